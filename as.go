@@ -0,0 +1,404 @@
+package gojson
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// ConversionError is returned by As and Unmarshal when a parsed value cannot
+// be converted to the requested Go type.
+type ConversionError struct {
+	Path   pointer.Pointer // location of the value in the converted one
+	Found  string          // JSON type of the value: "object", "array", "string", "number", "boolean" or "null"
+	Value  interface{}     // the value, for scalars
+	Type   reflect.Type    // Go type the value could not be converted to
+	Reason string          // why a number or a string option value could not be converted
+}
+
+func (e *ConversionError) Error() string {
+	found := e.Found
+	switch e.Value.(type) {
+	case int64, float64, bool, parser.Number:
+		found += " " + fmt.Sprint(e.Value)
+	}
+	location := ""
+	if len(e.Path) > 0 {
+		location = " at " + e.Path.String()
+	}
+	message := "gojson: cannot convert " + found + location + " to " + e.Type.String()
+	if e.Reason != "" {
+		message += ": " + e.Reason
+	}
+	return message
+}
+
+// As converts a parsed value to T, which can be bool, string, any integer or
+// floating-point type, interface{}, the parser's own types, and pointers,
+// slices, arrays, string-keyed maps and structs of those:
+//
+//	age, err := gojson.As[int64](user["age"])
+//	tags, err := gojson.As[[]string](user["tags"])
+//
+// Numbers are converted to any numeric type that holds them exactly, an
+// integer type only accepting integral floats such as 3.0, and to big.Int,
+// big.Float and parser.Number values, the Number values of parsers set to
+// UseNumber keeping all of their digits. Object members fill the struct
+// fields Marshal encodes under their key, matched case-insensitively when no
+// field has the exact key, and members without a field are ignored. Values
+// that do not fit T return a *ConversionError mentioning the JSON type found
+// and, inside arrays and objects, where it was found.
+func As[T any](v interface{}) (T, error) {
+	var result T
+	target := reflect.ValueOf(&result).Elem()
+	if err := (converter{}).convertValue(pointer.Pointer{}, v, target); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// converter converts parsed values to Go values.
+type converter struct {
+	// plainNumbers makes the Number values stored as they are, into
+	// interfaces and the parser's own types, int64 and float64 values, as
+	// parsers not set to UseNumber build them.
+	plainNumbers bool
+}
+
+// convertValue stores v, found at path, into target.
+func (c converter) convertValue(path pointer.Pointer, v interface{}, target reflect.Value) error {
+	t := target.Type()
+	if v != nil && (t.Kind() != reflect.String || t == numberType) && reflect.TypeOf(v).AssignableTo(t) {
+		if c.plainNumbers && t != numberType {
+			plain, err := plainNumbers(path, v, t)
+			if err != nil {
+				return err
+			}
+			if reflect.TypeOf(plain).AssignableTo(t) {
+				v = plain
+			}
+		}
+		target.Set(reflect.ValueOf(v))
+		return nil
+	}
+	fail := func(reason string) error {
+		return &ConversionError{Path: path, Found: parser.TypeName(v), Value: v, Type: t, Reason: reason}
+	}
+
+	switch t {
+	case bigIntType:
+		if n, ok := bigInt(v); ok {
+			target.Addr().Interface().(*big.Int).Set(n)
+			return nil
+		}
+		switch v.(type) {
+		case float64, parser.Number:
+			return fail("not an integer")
+		}
+	case bigFloatType:
+		if f, ok := bigFloat(v); ok {
+			target.Addr().Interface().(*big.Float).Set(f)
+			return nil
+		}
+	case numberType:
+		switch v.(type) {
+		case int64, float64:
+			target.SetString(fmt.Sprint(v))
+			return nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if v == nil {
+			target.SetZero()
+			return nil
+		}
+	case reflect.Pointer:
+		if v == nil {
+			target.SetZero()
+			return nil
+		}
+		elem := reflect.New(t.Elem())
+		if err := c.convertValue(path, v, elem.Elem()); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	case reflect.String:
+		if s, ok := v.(string); ok {
+			target.SetString(s)
+			return nil
+		}
+	case reflect.Bool:
+		if b, ok := v.(bool); ok {
+			target.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, reason, ok := integer(v)
+		if reason != "" {
+			return fail(reason)
+		}
+		if ok {
+			if target.OverflowInt(n) {
+				return fail("out of range")
+			}
+			target.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := v.(parser.Number); ok {
+			// Beyond the range of int64 values.
+			if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+				if target.OverflowUint(u) {
+					return fail("out of range")
+				}
+				target.SetUint(u)
+				return nil
+			}
+		}
+		n, reason, ok := integer(v)
+		if reason != "" {
+			return fail(reason)
+		}
+		if ok {
+			if n < 0 || target.OverflowUint(uint64(n)) {
+				return fail("out of range")
+			}
+			target.SetUint(uint64(n))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := v.(parser.Number); ok {
+			f, err := n.Float64()
+			if err != nil || target.OverflowFloat(f) {
+				return fail("out of range")
+			}
+			target.SetFloat(f)
+			return nil
+		}
+		switch n := v.(type) {
+		case int64:
+			f := float64(n)
+			if t.Kind() == reflect.Float32 {
+				f = float64(float32(f))
+			}
+			if f >= 1<<63 || int64(f) != n {
+				return fail("not exactly representable")
+			}
+			target.SetFloat(f)
+			return nil
+		case float64:
+			if target.OverflowFloat(n) {
+				return fail("out of range")
+			}
+			target.SetFloat(n)
+			return nil
+		}
+	case reflect.Slice:
+		if arr, ok := v.(parser.JsonArray); ok {
+			slice := reflect.MakeSlice(t, len(arr), len(arr))
+			for i, e := range arr {
+				if err := c.convertValue(path.Append(strconv.Itoa(i)), e, slice.Index(i)); err != nil {
+					return err
+				}
+			}
+			target.Set(slice)
+			return nil
+		}
+		if v == nil {
+			target.SetZero()
+			return nil
+		}
+	case reflect.Array:
+		if arr, ok := v.(parser.JsonArray); ok {
+			if len(arr) != t.Len() {
+				return fail(fmt.Sprintf("%d elements instead of %d", len(arr), t.Len()))
+			}
+			for i, e := range arr {
+				if err := c.convertValue(path.Append(strconv.Itoa(i)), e, target.Index(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		if keys, members, ok := parser.Members(v); ok {
+			m := reflect.MakeMapWithSize(t, len(keys))
+			for _, k := range keys {
+				elem := reflect.New(t.Elem()).Elem()
+				if err := c.convertValue(path.Append(k), members[k], elem); err != nil {
+					return err
+				}
+				m.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+			}
+			target.Set(m)
+			return nil
+		}
+		if v == nil {
+			target.SetZero()
+			return nil
+		}
+	case reflect.Struct:
+		if keys, members, ok := parser.Members(v); ok {
+			fields := cachedTypeFields(t)
+			for _, k := range keys {
+				f, ok := fieldByName(fields, k)
+				if !ok {
+					continue
+				}
+				fv, ok := settableField(target, f)
+				if !ok {
+					continue
+				}
+				member := members[k]
+				if f.asString && member != nil {
+					var err error
+					if member, err = c.quotedValue(path.Append(k), member, fv.Type()); err != nil {
+						return err
+					}
+				}
+				if err := c.convertValue(path.Append(k), member, fv); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return fail("")
+}
+
+// plainNumbers returns v, found at path and stored into a value of type t,
+// with its Number values replaced by int64 and float64 values, integers
+// beyond the range of int64 becoming float64 values.
+func plainNumbers(path pointer.Pointer, v interface{}, t reflect.Type) (interface{}, error) {
+	switch x := v.(type) {
+	case parser.Number:
+		if n, err := parser.ParseNumber(string(x)); err == nil {
+			return n, nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return nil, &ConversionError{Path: path, Found: "number", Value: x, Type: t, Reason: "out of range"}
+		}
+		return f, nil
+	case parser.JsonArray:
+		arr := make(parser.JsonArray, len(x))
+		for i, e := range x {
+			var err error
+			if arr[i], err = plainNumbers(path.Append(strconv.Itoa(i)), e, t); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case parser.JsonObject:
+		obj := make(parser.JsonObject, len(x))
+		for k, e := range x {
+			var err error
+			if obj[k], err = plainNumbers(path.Append(k), e, t); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case *parser.OrderedObject:
+		obj := parser.NewOrderedObject()
+		for _, k := range x.Keys {
+			e, err := plainNumbers(path.Append(k), x.Values[k], t)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(k, e)
+		}
+		return obj, nil
+	}
+	return v, nil
+}
+
+// fieldByName returns the field of fields encoded under key, preferring an
+// exact match to a case-insensitive one as encoding/json does.
+func fieldByName(fields []field, key string) (field, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// integer returns the parsed number v as an int64. It returns false if v is
+// not a number, or the reason why the number is not an integer.
+func integer(v interface{}) (int64, string, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, "", true
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, "not an integer", false
+		}
+		if n < -(1<<63) || n >= 1<<63 {
+			return 0, "out of range", false
+		}
+		return int64(n), "", true
+	case parser.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, "", true
+		}
+		if !strings.ContainsAny(string(n), ".eE") {
+			return 0, "out of range", false
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return 0, "out of range", false
+		}
+		return integer(f)
+	}
+	return 0, "", false
+}
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// bigInt returns the parsed number v as a big.Int, or false if v is not an
+// integer.
+func bigInt(v interface{}) (*big.Int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return big.NewInt(n), true
+	case parser.Number:
+		i, err := n.BigInt()
+		return i, err == nil
+	}
+	return nil, false
+}
+
+// bigFloat returns the parsed number v as a big.Float, or false if v is not
+// a number.
+func bigFloat(v interface{}) (*big.Float, bool) {
+	switch n := v.(type) {
+	case int64:
+		return new(big.Float).SetInt64(n), true
+	case float64:
+		return big.NewFloat(n), true
+	case parser.Number:
+		f, err := n.BigFloat()
+		return f, err == nil
+	}
+	return nil, false
+}
@@ -0,0 +1,158 @@
+package gojson
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func TestAs(t *testing.T) {
+	doc := parseValue(t, `{
+		"age": 31, "ratio": 0.5, "count": 3.0, "name": "café \"bar\"", "admin": true,
+		"tags": ["a", "b"], "matrix": [[1, 2], [3]], "scores": {"x": 1, "y\n": 2.5}, "missing": null
+	}`).(parser.JsonObject)
+
+	check := func(got, expected interface{}, err error) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+
+	i64, err := As[int64](doc["age"])
+	check(i64, int64(31), err)
+	u8, err := As[uint8](doc["count"])
+	check(u8, uint8(3), err)
+	f, err := As[float64](doc["age"])
+	check(f, 31.0, err)
+	f32, err := As[float32](doc["ratio"])
+	check(f32, float32(0.5), err)
+	s, err := As[string](doc["name"])
+	check(s, `café "bar"`, err)
+	b, err := As[bool](doc["admin"])
+	check(b, true, err)
+	tags, err := As[[]string](doc["tags"])
+	check(tags, []string{"a", "b"}, err)
+	matrix, err := As[[][]int](doc["matrix"])
+	check(matrix, [][]int{{1, 2}, {3}}, err)
+	pair, err := As[[2]string](doc["tags"])
+	check(pair, [2]string{"a", "b"}, err)
+	scores, err := As[map[string]float64](doc["scores"])
+	check(scores, map[string]float64{"x": 1, "y\n": 2.5}, err)
+	ptr, err := As[*int](doc["age"])
+	check(*ptr, 31, err)
+	nilPtr, err := As[*int](doc["missing"])
+	check(nilPtr, (*int)(nil), err)
+	nilSlice, err := As[[]string](doc["missing"])
+	check(nilSlice, []string(nil), err)
+	arr, err := As[parser.JsonArray](doc["tags"])
+	check(arr, parser.JsonArray{"a", "b"}, err)
+	raw, err := As[interface{}](doc["name"])
+	check(raw, `café "bar"`, err)
+
+	type user struct {
+		Name    string
+		Age     int  `json:"age"`
+		IsAdmin bool `json:"admin"`
+		Tags    []string
+		Missing *int
+		Ratio   float64 `json:"-"`
+		scores  map[string]float64
+	}
+	u, err := As[user](doc)
+	check(u, user{Name: `café "bar"`, Age: 31, IsAdmin: true, Tags: []string{"a", "b"}}, err)
+}
+
+func TestAsNumber(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`[123456789012345678901234567890, 2.5, 3.0, 1e400, 4]`))
+	p.UseNumber(true)
+	arr := p.ParseValue().(parser.JsonArray)
+
+	if n, err := As[*big.Int](arr[0]); err != nil || n.String() != "123456789012345678901234567890" {
+		t.Errorf("expected the exact integer, got %v (%v)", n, err)
+	}
+	if f, err := As[big.Float](arr[1]); err != nil || f.String() != "2.5" {
+		t.Errorf("expected 2.5, got %v (%v)", f.String(), err)
+	}
+	if n, err := As[int](arr[2]); err != nil || n != 3 {
+		t.Errorf("expected 3, got %d (%v)", n, err)
+	}
+	if f, err := As[float64](arr[1]); err != nil || f != 2.5 {
+		t.Errorf("expected 2.5, got %v (%v)", f, err)
+	}
+	if n, err := As[parser.Number](arr[4]); err != nil || n != "4" {
+		t.Errorf("expected the number as parsed, got %q (%v)", n, err)
+	}
+	if n, err := As[parser.Number](int64(5)); err != nil || n != "5" {
+		t.Errorf("expected int64 values to convert, got %q (%v)", n, err)
+	}
+
+	for i, typ := range []string{"int64", "float64", "*big.Int"} {
+		var err error
+		switch typ {
+		case "int64":
+			_, err = As[int64](arr[0])
+		case "float64":
+			_, err = As[float64](arr[3])
+		case "*big.Int":
+			_, err = As[*big.Int](arr[1])
+		}
+		var conversion *ConversionError
+		if !errors.As(err, &conversion) || conversion.Found != "number" {
+			t.Errorf("%d: expected a conversion error to %s, got %v", i, typ, err)
+		}
+	}
+
+	out, err := Marshal(arr)
+	if err != nil || string(out) != `[123456789012345678901234567890,2.5,3.0,1e400,4]` {
+		t.Errorf("expected the numbers to round-trip, got %s (%v)", out, err)
+	}
+	if _, err := Marshal(parser.Number("1.")); err == nil {
+		t.Errorf("expected invalid numbers to be rejected")
+	}
+}
+
+func TestAsErrors(t *testing.T) {
+	doc := parseValue(t, `{"ratio": 0.5, "big": 300, "negative": -1, "huge": 1e300, "name": "x", "tags": ["a", 2], "exact": 9007199254740993}`).(parser.JsonObject)
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{second(As[int64](doc["ratio"])), "gojson: cannot convert number 0.5 to int64: not an integer"},
+		{second(As[uint8](doc["big"])), "gojson: cannot convert number 300 to uint8: out of range"},
+		{second(As[uint](doc["negative"])), "gojson: cannot convert number -1 to uint: out of range"},
+		{second(As[float32](doc["huge"])), "gojson: cannot convert number 1e+300 to float32: out of range"},
+		{second(As[float64](doc["exact"])), "gojson: cannot convert number 9007199254740993 to float64: not exactly representable"},
+		{second(As[int](doc["name"])), "gojson: cannot convert string to int"},
+		{second(As[string](doc["missing"])), "gojson: cannot convert null to string"},
+		{second(As[[]string](doc["tags"])), "gojson: cannot convert number 2 at /1 to string"},
+		{second(As[[3]string](doc["tags"])), "gojson: cannot convert array to [3]string: 2 elements instead of 3"},
+		{second(As[map[string]bool](doc)), "gojson: cannot convert number 300 at /big to bool"},
+		{second(As[map[int]bool](doc)), "gojson: cannot convert object to map[int]bool"},
+		{second(As[bool](doc)), "gojson: cannot convert object to bool"},
+		{second(As[struct{ Big int8 }](doc)), "gojson: cannot convert number 300 at /big to int8: out of range"},
+		{second(As[struct{ Name string }](doc["tags"])), "gojson: cannot convert array to struct { Name string }"},
+	}
+	for _, test := range tests {
+		if test.err == nil || test.err.Error() != test.expected {
+			t.Errorf("expected error %q, got %v", test.expected, test.err)
+		}
+	}
+
+	_, err := As[[]int](parser.JsonArray{int64(1), "x"})
+	var conversion *ConversionError
+	if !errors.As(err, &conversion) || conversion.Found != "string" || conversion.Path.String() != "/1" || conversion.Type != reflect.TypeOf(0) {
+		t.Errorf("expected a *ConversionError about /1, got %#v", err)
+	}
+}
+
+// second returns the error of a call returning a value and an error.
+func second[T any](_ T, err error) error {
+	return err
+}
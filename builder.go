@@ -0,0 +1,143 @@
+package gojson
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// ObjectBuilder builds an object member by member:
+//
+//	user := gojson.Obj().
+//		Set("name", "John").
+//		Set("tags", gojson.Arr("a", "b")).
+//		Build()
+//
+// Values are Go booleans, strings, integers and floats, nil, parsed values
+// and other builders. Strings are stored as they are, as the parser stores
+// them. Setting a value of another type panics, as it is a programming error.
+type ObjectBuilder struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// Obj returns a builder of an empty object.
+func Obj() *ObjectBuilder {
+	return &ObjectBuilder{values: map[string]interface{}{}}
+}
+
+// Set sets the member key to value and returns the builder. Setting an
+// existing member replaces its value but keeps its position.
+func (b *ObjectBuilder) Set(key string, value interface{}) *ObjectBuilder {
+	if _, ok := b.values[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.values[key] = builderValue(value)
+	return b
+}
+
+// Build returns the object, nested builders being built too.
+func (b *ObjectBuilder) Build() parser.JsonObject {
+	obj := make(parser.JsonObject, len(b.keys))
+	for _, k := range b.keys {
+		obj[k] = build(b.values[k], false)
+	}
+	return obj
+}
+
+// BuildOrdered returns the object as a *parser.OrderedObject keeping the
+// order in which members were first set, nested builders being built as
+// ordered objects too.
+func (b *ObjectBuilder) BuildOrdered() *parser.OrderedObject {
+	obj := parser.NewOrderedObject()
+	for _, k := range b.keys {
+		obj.Set(k, build(b.values[k], true))
+	}
+	return obj
+}
+
+// ArrayBuilder builds an array element by element. It accepts the same
+// values as ObjectBuilder.
+type ArrayBuilder struct {
+	elements []interface{}
+}
+
+// Arr returns a builder of an array holding the given elements.
+func Arr(elements ...interface{}) *ArrayBuilder {
+	return (&ArrayBuilder{elements: []interface{}{}}).Append(elements...)
+}
+
+// Append adds elements at the end of the array and returns the builder.
+func (b *ArrayBuilder) Append(elements ...interface{}) *ArrayBuilder {
+	for _, e := range elements {
+		b.elements = append(b.elements, builderValue(e))
+	}
+	return b
+}
+
+// Build returns the array, nested builders being built too.
+func (b *ArrayBuilder) Build() parser.JsonArray {
+	return build(b, false).(parser.JsonArray)
+}
+
+// BuildOrdered returns the array, nested object builders being built as
+// ordered objects.
+func (b *ArrayBuilder) BuildOrdered() parser.JsonArray {
+	return build(b, true).(parser.JsonArray)
+}
+
+// build returns the value of v, building builders.
+func build(v interface{}, ordered bool) interface{} {
+	switch x := v.(type) {
+	case *ObjectBuilder:
+		if ordered {
+			return x.BuildOrdered()
+		}
+		return x.Build()
+	case *ArrayBuilder:
+		arr := make(parser.JsonArray, len(x.elements))
+		for i, e := range x.elements {
+			arr[i] = build(e, ordered)
+		}
+		return arr
+	}
+	return v
+}
+
+// builderValue converts a value given to a builder into a parsed value, or a
+// builder.
+func builderValue(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, bool, int64, string, parser.JsonObject, parser.JsonArray, *parser.OrderedObject, *ObjectBuilder, *ArrayBuilder:
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := rv.Uint(); n <= math.MaxInt64 {
+			return int64(n)
+		}
+		panic(fmt.Sprintf("gojson: builder value %v overflows int64", v))
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			panic(fmt.Sprintf("gojson: builder value %v is not a valid JSON number", v))
+		}
+		if rv.Kind() == reflect.Float32 {
+			// Keep the shortest decimal form of the float32, 0.1 rather than 0.10000000149011612.
+			f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', -1, 32), 64)
+		}
+		return f
+	}
+	panic(fmt.Sprintf("gojson: unsupported builder value of type %T", v))
+}
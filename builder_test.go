@@ -0,0 +1,49 @@
+package gojson
+
+import (
+	"testing"
+
+	"github.com/oabrivard/gojson/linter"
+)
+
+func TestBuilder(t *testing.T) {
+	type level int
+	obj := Obj().
+		Set("name", "John \"Jo\"").
+		Set("tags", Arr("a", "b")).
+		Set("age", 42).
+		Set("score", float32(0.1)).
+		Set("level", level(3)).
+		Set("admin", false).
+		Set("manager", nil).
+		Set("address", Obj().Set("city", "Paris")).
+		Set("raw", parseValue(t, `[1, {"x": true}]`))
+
+	expected := `{"address": {"city": "Paris"}, "admin": false, "age": 42, "level": 3, "manager": null, ` +
+		`"name": "John \"Jo\"", "raw": [1, {"x": true}], "score": 0.1, "tags": ["a", "b"]}`
+	if got := linter.Format(obj.Build(), linter.Options{InlineWidth: 1 << 30}); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	expected = `{"name": "John \"Jo\"", "tags": ["a", "b"], "age": 42, "score": 0.1, "level": 3, "admin": false, ` +
+		`"manager": null, "address": {"city": "Paris"}, "raw": [1, {"x": true}]}`
+	if got := linter.Format(obj.Set("age", 43).Set("age", 42).BuildOrdered(), linter.Options{InlineWidth: 1 << 30}); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	arr := Arr(1, Obj().Set("b", 2).Set("a", 1)).Append(Arr(), "x").BuildOrdered()
+	if got := linter.Format(arr, linter.Options{InlineWidth: 1 << 30}); got != `[1, {"b": 2, "a": 1}, [], "x"]` {
+		t.Errorf("unexpected array: %s", got)
+	}
+
+	for _, v := range []interface{}{struct{}{}, []int{1}, uint64(1 << 63)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Set(%v): expected a panic", v)
+				}
+			}()
+			Obj().Set("x", v)
+		}()
+	}
+}
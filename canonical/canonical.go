@@ -0,0 +1,200 @@
+// Package canonical serializes documents produced by the parser package in
+// the JSON Canonicalization Scheme (RFC 8785), a unique form suitable for
+// hashing and signing: no white space, object members sorted by the UTF-16
+// code units of their keys, numbers formatted as ECMAScript does and strings
+// escaped minimally. Hash computes digests of that form, and Sign and Verify
+// produce and check detached JSON Web Signatures over it.
+package canonical
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Marshal returns the canonical form of v. Numbers are IEEE 754 doubles in
+// JCS, so integers beyond 2^53 are rounded, parser.Number values included.
+// Strings and keys holding invalid UTF-8 are reported as errors; the lone
+// surrogates I-JSON forbids cannot reach Marshal from parsed documents, the
+// lexer replacing them by U+FFFD.
+func Marshal(v interface{}) ([]byte, error) {
+	return appendValue(nil, v)
+}
+
+// appendValue appends the canonical form of v to b.
+func appendValue(b []byte, v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, x), nil
+	case int64:
+		return appendNumber(b, float64(x))
+	case float64:
+		return appendNumber(b, x)
+	case parser.Number:
+		f, err := x.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("canonical: %s is out of the range of JSON numbers", x)
+		}
+		return appendNumber(b, f)
+	case string:
+		if err := validate(x); err != nil {
+			return nil, err
+		}
+		return appendString(b, x), nil
+	case parser.JsonArray:
+		b = append(b, '[')
+		for i, e := range x {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendValue(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case *parser.OrderedObject:
+		return appendObject(b, x.Keys, x.Values)
+	case parser.JsonObject:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		return appendObject(b, keys, x)
+	}
+	return nil, fmt.Errorf("canonical: unsupported value of type %T", v)
+}
+
+// member is an object member with the UTF-16 code units of its key.
+type member struct {
+	key   string
+	units []uint16
+	value interface{}
+}
+
+// appendObject appends an object holding the given members, sorted by the
+// UTF-16 code units of their keys.
+func appendObject(b []byte, keys []string, values map[string]interface{}) ([]byte, error) {
+	members := make([]member, len(keys))
+	for i, k := range keys {
+		if err := validate(k); err != nil {
+			return nil, err
+		}
+		members[i] = member{key: k, units: utf16.Encode([]rune(k)), value: values[k]}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return lessUnits(members[i].units, members[j].units)
+	})
+
+	b = append(b, '{')
+	for i, m := range members {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(appendString(b, m.key), ':')
+		var err error
+		if b, err = appendValue(b, m.value); err != nil {
+			return nil, err
+		}
+	}
+	return append(b, '}'), nil
+}
+
+// lessUnits reports whether a sorts before b.
+func lessUnits(a, b []uint16) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+// appendNumber appends f formatted as ECMAScript Number.prototype.toString
+// does.
+func appendNumber(b []byte, f float64) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("canonical: %v is not a valid JSON number", f)
+	}
+	if f == 0 {
+		return append(b, '0'), nil // also for -0
+	}
+	if f < 0 {
+		b = append(b, '-')
+		f = -f
+	}
+
+	// The shortest digits reading back as f, and the position n of the
+	// decimal point relative to them.
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	k, n := len(digits), x+1
+
+	switch {
+	case k <= n && n <= 21:
+		b = append(b, digits...)
+		return append(b, strings.Repeat("0", n-k)...), nil
+	case 0 < n && n <= 21:
+		return append(append(append(b, digits[:n]...), '.'), digits[n:]...), nil
+	case -6 < n && n <= 0:
+		return append(append(append(b, "0."...), strings.Repeat("0", -n)...), digits...), nil
+	}
+	b = append(b, digits[0])
+	if k > 1 {
+		b = append(append(b, '.'), digits[1:]...)
+	}
+	b = append(b, 'e')
+	if n-1 >= 0 {
+		b = append(b, '+')
+	}
+	return strconv.AppendInt(b, int64(n-1), 10), nil
+}
+
+// appendString appends s as a JSON string, escaping only quotation marks,
+// backslashes and control characters.
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"', '\\':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, `\b`...)
+		case '\f':
+			b = append(b, `\f`...)
+		case '\n':
+			b = append(b, `\n`...)
+		case '\r':
+			b = append(b, `\r`...)
+		case '\t':
+			b = append(b, `\t`...)
+		default:
+			if c < 0x20 {
+				b = append(b, `\u00`...)
+				b = append(b, "0123456789abcdef"[c>>4], "0123456789abcdef"[c&0xf])
+			} else {
+				b = append(b, c)
+			}
+		}
+	}
+	return append(b, '"')
+}
+
+// validate reports an error if s is not valid UTF-8.
+func validate(s string) error {
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("canonical: invalid UTF-8 in string %q", s)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package canonical
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func TestMarshal(t *testing.T) {
+	// The examples of RFC 8785 sections 3.2.2 and 3.2.3.
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		   "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		   "literals": [null, true, false]}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		{`{"€": "Euro Sign", "\r": "Carriage Return", "דּ": "Hebrew Letter Dalet With Dagesh", "1": "One",
+		   "😀": "Emoji: Grinning Face", "\u0080": "Control", "ö": "Latin Small Letter O With Diaeresis"}`,
+			`{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","ö":"Latin Small Letter O With Diaeresis",` +
+				`"€":"Euro Sign","😀":"Emoji: Grinning Face","דּ":"Hebrew Letter Dalet With Dagesh"}`},
+		{`[{}, [], "", 0, -0, 10, -1.5, {"b": {"d": 1, "c": 2}, "a": 3}]`, `[{},[],"",0,0,10,-1.5,{"a":3,"b":{"c":2,"d":1}}]`},
+	}
+
+	for _, tt := range tests {
+		data, err := Marshal(parse(t, tt.input))
+		if err != nil {
+			t.Fatalf("Marshal(%s): unexpected error: %v", tt.input, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("Marshal(%s):\nexpected %s\ngot      %s", tt.input, tt.expected, data)
+		}
+	}
+
+	obj := parser.NewOrderedObject()
+	obj.Set("z", int64(1))
+	obj.Set("a", parser.JsonArray{"x"})
+	if data, _ := Marshal(obj); string(data) != `{"a":["x"],"z":1}` {
+		t.Errorf("unexpected canonical form of an ordered object: %s", data)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	// Values from RFC 8785 appendix B.
+	tests := []struct {
+		f        float64
+		expected string
+	}{
+		{0, "0"},
+		{math.Copysign(0, -1), "0"},
+		{5e-324, "5e-324"},
+		{-5e-324, "-5e-324"},
+		{1.7976931348623157e308, "1.7976931348623157e+308"},
+		{9007199254740992, "9007199254740992"},
+		{9007199254740994, "9007199254740994"},
+		{295147905179352830000, "295147905179352830000"},
+		{1e21, "1e+21"},
+		{1e23, "1e+23"},
+		{999999999999999700000, "999999999999999700000"},
+		{0.000001, "0.000001"},
+		{1e-7, "1e-7"},
+		{0.0000012345, "0.0000012345"},
+		{333333333.3333333, "333333333.3333333"},
+		{-1.5, "-1.5"},
+	}
+
+	for _, tt := range tests {
+		data, err := Marshal(tt.f)
+		if err != nil {
+			t.Fatalf("Marshal(%v): unexpected error: %v", tt.f, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("Marshal(%v): expected %s, got %s", tt.f, tt.expected, data)
+		}
+	}
+}
+
+func TestMarshalNumber(t *testing.T) {
+	data, err := Marshal(parser.JsonArray{parser.Number("4.50"), parser.Number("123456789012345678901")})
+	if err != nil || string(data) != "[4.5,123456789012345680000]" {
+		t.Errorf("expected Number values formatted as doubles, got %s (%v)", data, err)
+	}
+	if _, err := Marshal(parser.Number("1e400")); err == nil {
+		t.Errorf("expected an error for a number out of range")
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	for _, v := range []interface{}{math.Inf(1), math.NaN(), "\xff", "\xed\xa0\x80", parser.JsonObject{"\xff": int64(1)}, struct{}{}} {
+		if _, err := Marshal(v); err == nil {
+			t.Errorf("Marshal(%v): expected an error", v)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, err := Hash(parse(t, `{"b": [1, 2.50, "é"], "a": null}`), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Hash: unexpected error: %v", err)
+	}
+	b, err := Hash(parse(t, `{ "a" : null , "b" : [1.0, 25e-1, "é"] }`), crypto.SHA256)
+	if err != nil {
+		t.Fatalf("Hash: unexpected error: %v", err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("expected equal digests, got %x and %x", a, b)
+	}
+	if expected := sha256.Sum256([]byte(`{"a":null,"b":[1,2.5,"é"]}`)); !bytes.Equal(a, expected[:]) {
+		t.Errorf("expected %x, got %x", expected, a)
+	}
+
+	c, _ := Hash(parse(t, `{"a": null, "b": [2.5, 1, "é"]}`), crypto.SHA256)
+	if bytes.Equal(a, c) {
+		t.Errorf("expected different digests for different documents")
+	}
+	if d, _ := Hash(parse(t, `{}`), crypto.SHA512); len(d) != 64 {
+		t.Errorf("expected a SHA-512 digest, got %x", d)
+	}
+
+	if _, err := Hash(parse(t, `{}`), crypto.MD4); err == nil {
+		t.Errorf("expected an error for an unavailable algorithm")
+	}
+	if _, err := Hash(math.NaN(), crypto.SHA256); err == nil {
+		t.Errorf("expected an error for an invalid value")
+	}
+}
+
+func TestSign(t *testing.T) {
+	doc := parse(t, `{"b": [1, 2.50], "a": "x"}`)
+	reformatted := parse(t, `{ "a" : "\u0078", "b" : [1.0, 25e-1] }`)
+
+	jws, err := Sign(doc, []byte("secret"), SignOptions{KeyID: "k1"})
+	if err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	if expected := "eyJhbGciOiJIUzI1NiIsImtpZCI6ImsxIn0..25XBcGU_YvKanB-0xsmY7X89x3dl2XDGHKS9u2l_zw0"; jws != expected {
+		t.Errorf("Sign: expected %s, got %s", expected, jws)
+	}
+	if err := Verify(reformatted, jws, []byte("secret")); err != nil {
+		t.Errorf("Verify: unexpected error: %v", err)
+	}
+	attached := strings.Replace(jws, "..", ".eyJhIjoieCIsImIiOlsxLDIuNV19.", 1)
+	if err := Verify(doc, attached, []byte("secret")); err != nil {
+		t.Errorf("Verify(attached): unexpected error: %v", err)
+	}
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	keys := []struct {
+		alg          string
+		private, pub interface{}
+	}{
+		{"HS512", []byte("secret"), []byte("secret")},
+		{"", rsaKey, &rsaKey.PublicKey},
+		{"PS256", rsaKey, &rsaKey.PublicKey},
+		{"", ecKey, &ecKey.PublicKey},
+		{"", edKey, edKey.Public()},
+	}
+	for _, k := range keys {
+		jws, err := Sign(doc, k.private, SignOptions{Algorithm: k.alg})
+		if err != nil {
+			t.Fatalf("Sign(%T, %q): unexpected error: %v", k.private, k.alg, err)
+		}
+		if err := Verify(reformatted, jws, k.pub); err != nil {
+			t.Errorf("Verify(%T, %q): unexpected error: %v", k.private, k.alg, err)
+		}
+		if err := Verify(reformatted, jws, k.private); err != nil {
+			t.Errorf("Verify(%T, %q) with the private key: unexpected error: %v", k.private, k.alg, err)
+		}
+		if err := Verify(parse(t, `{"a": "y", "b": [1, 2.5]}`), jws, k.pub); !errors.Is(err, ErrSignature) {
+			t.Errorf("Verify(%T, %q) of another document: expected ErrSignature, got %v", k.private, k.alg, err)
+		}
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	doc := parse(t, `{"a": 1}`)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jws, _ := Sign(doc, []byte("secret"), SignOptions{})
+
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{second(Sign(doc, "secret", SignOptions{})), "canonical: unsupported signing key of type string"},
+		{second(Sign(doc, []byte("secret"), SignOptions{Algorithm: "none"})), `canonical: unsupported JWS algorithm "none"`},
+		{second(Sign(doc, ecKey, SignOptions{Algorithm: "ES384"})), "canonical: a key of type *ecdsa.PrivateKey cannot sign with ES384"},
+		{second(Sign(math.NaN(), []byte("secret"), SignOptions{})), "canonical: NaN is not a valid JSON number"},
+		{Verify(doc, jws, &ecKey.PublicKey), "canonical: a key of type *ecdsa.PublicKey cannot verify HS256 signatures"},
+		{Verify(doc, "a.b", []byte("secret")), "canonical: a JWS must be made of 3 parts separated by dots"},
+		{Verify(doc, "eyJhbGciOiJub25lIn0..", []byte("secret")), `canonical: unsupported JWS algorithm "none"`},
+		{Verify(doc, "eyJhbGciOiJIUzI1NiIsImNyaXQiOlsiYjY0Il19..", []byte("secret")), "canonical: unsupported critical JWS header parameters"},
+		{Verify(doc, "e30..", []byte("secret")), `canonical: invalid JWS header: missing "alg"`},
+		{Verify(doc, jws, []byte("other")), ErrSignature.Error()},
+	}
+	for _, tt := range tests {
+		if tt.err == nil || tt.err.Error() != tt.expected {
+			t.Errorf("expected error %q, got %v", tt.expected, tt.err)
+		}
+	}
+}
+
+// second returns the error of a call returning a value and an error.
+func second[T any](_ T, err error) error {
+	return err
+}
@@ -0,0 +1,26 @@
+package canonical
+
+import (
+	"crypto"
+	_ "crypto/sha256" // register SHA-224 and SHA-256
+	_ "crypto/sha512" // register SHA-384 and SHA-512
+	"fmt"
+)
+
+// Hash returns the digest of the canonical form of v computed with
+// algorithm, so that documents differing only by white space, member order,
+// number notation or string escaping hash equally. SHA-2 algorithms are
+// always available; others must be linked into the binary, for instance by
+// importing crypto/sha3.
+func Hash(v interface{}, algorithm crypto.Hash) ([]byte, error) {
+	if !algorithm.Available() {
+		return nil, fmt.Errorf("canonical: hash algorithm %v is not available", algorithm)
+	}
+	data, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	h := algorithm.New()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
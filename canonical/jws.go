@@ -0,0 +1,275 @@
+package canonical
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// ErrSignature is returned by Verify for signatures that do not match the
+// document.
+var ErrSignature = errors.New("canonical: signature does not match the document")
+
+// SignOptions controls how Sign signs documents.
+type SignOptions struct {
+	// Algorithm is the JWS algorithm, such as HS256, RS256, PS256, ES256 or
+	// EdDSA. When empty, it is derived from the key: HS256 for []byte keys,
+	// RS256 for RSA keys, ES256, ES384 or ES512 according to the curve of
+	// ECDSA keys and EdDSA for Ed25519 keys.
+	Algorithm string
+	// KeyID is the "kid" header parameter, omitted when empty.
+	KeyID string
+}
+
+// Sign returns a detached JSON Web Signature (RFC 7515, appendix F) of the
+// canonical form of v, in the compact serialization with an empty payload:
+// "header..signature". The receiver verifies it against the document it got
+// with Verify, so that the document can travel as is, formatted in any way.
+// key is an HMAC secret as a []byte, or an *rsa.PrivateKey, an
+// *ecdsa.PrivateKey or an ed25519.PrivateKey.
+func Sign(v interface{}, key interface{}, opts SignOptions) (string, error) {
+	alg := opts.Algorithm
+	if alg == "" {
+		var err error
+		if alg, err = defaultAlgorithm(key); err != nil {
+			return "", err
+		}
+	}
+	payload, err := Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	header := append([]byte(`{"alg":`), appendString(nil, alg)...)
+	if opts.KeyID != "" {
+		header = append(append(header, `,"kid":`...), appendString(nil, opts.KeyID)...)
+	}
+	header = append(header, '}')
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+
+	signature, err := sign(alg, key, encodedHeader+"."+base64.RawURLEncoding.EncodeToString(payload))
+	if err != nil {
+		return "", err
+	}
+	return encodedHeader + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks that jws, a detached JSON Web Signature produced by Sign or
+// any JWS implementation signing the canonical form of documents, is a
+// signature of v by key. A JWS holding its payload is accepted if the
+// payload is the canonical form of v. key is an HMAC secret as a []byte, or
+// an RSA, ECDSA or Ed25519 public key or private key; the algorithm of the
+// signature must suit it. Signatures that do not match return ErrSignature.
+func Verify(v interface{}, jws string, key interface{}) error {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		return errors.New("canonical: a JWS must be made of 3 parts separated by dots")
+	}
+	alg, err := headerAlgorithm(parts[0])
+	if err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("canonical: invalid JWS signature: %v", err)
+	}
+	payload, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	if parts[1] != "" && parts[1] != encodedPayload {
+		return ErrSignature
+	}
+	return verify(alg, key, parts[0]+"."+encodedPayload, signature)
+}
+
+// headerAlgorithm returns the algorithm of the encoded JWS header. Headers
+// with critical parameters are rejected, none being supported.
+func headerAlgorithm(encoded string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("canonical: invalid JWS header: %v", err)
+	}
+	p := parser.NewParser(lexer.NewLexer(string(data)))
+	header, ok := p.ParseValue().(parser.JsonObject)
+	if len(p.Errors()) > 0 || !ok {
+		return "", errors.New("canonical: invalid JWS header: not a JSON object")
+	}
+	if _, ok := header["crit"]; ok {
+		return "", errors.New("canonical: unsupported critical JWS header parameters")
+	}
+	alg, ok := header["alg"].(string)
+	if !ok {
+		return "", errors.New(`canonical: invalid JWS header: missing "alg"`)
+	}
+	return alg, validate(alg)
+}
+
+// defaultAlgorithm returns the algorithm Sign uses for key.
+func defaultAlgorithm(key interface{}) (string, error) {
+	switch k := key.(type) {
+	case []byte:
+		return "HS256", nil
+	case *rsa.PrivateKey:
+		return "RS256", nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return "ES256", nil
+		case 384:
+			return "ES384", nil
+		case 521:
+			return "ES512", nil
+		}
+		return "", fmt.Errorf("canonical: unsupported ECDSA curve %s", k.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return "EdDSA", nil
+	}
+	return "", fmt.Errorf("canonical: unsupported signing key of type %T", key)
+}
+
+// algorithms maps the supported JWS algorithms to their hash function,
+// EdDSA hashing its input itself.
+var algorithms = map[string]crypto.Hash{
+	"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+	"EdDSA": 0,
+}
+
+// ecdsaBits returns the size in bits of the curve of an ES algorithm.
+func ecdsaBits(alg string) int {
+	switch alg {
+	case "ES256":
+		return 256
+	case "ES384":
+		return 384
+	}
+	return 521
+}
+
+// digest returns the digest of input computed with hash.
+func digest(hash crypto.Hash, input string) []byte {
+	h := hash.New()
+	h.Write([]byte(input))
+	return h.Sum(nil)
+}
+
+// sign returns the signature of input with key by the JWS algorithm alg.
+func sign(alg string, key interface{}, input string) ([]byte, error) {
+	hash, ok := algorithms[alg]
+	if !ok {
+		return nil, fmt.Errorf("canonical: unsupported JWS algorithm %q", alg)
+	}
+	mismatch := fmt.Errorf("canonical: a key of type %T cannot sign with %s", key, alg)
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, mismatch
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(input))
+		return mac.Sum(nil), nil
+	case "RS", "PS":
+		k, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, mismatch
+		}
+		if alg[0] == 'P' {
+			return rsa.SignPSS(rand.Reader, k, hash, digest(hash, input), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(rand.Reader, k, hash, digest(hash, input))
+	case "ES":
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok || k.Curve.Params().BitSize != ecdsaBits(alg) {
+			return nil, mismatch
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest(hash, input))
+		if err != nil {
+			return nil, fmt.Errorf("canonical: %v", err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	case "Ed":
+		k, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, mismatch
+		}
+		return ed25519.Sign(k, []byte(input)), nil
+	}
+	return nil, mismatch
+}
+
+// verify checks that signature is the signature of input by key with the JWS
+// algorithm alg.
+func verify(alg string, key interface{}, input string, signature []byte) error {
+	hash, ok := algorithms[alg]
+	if !ok {
+		return fmt.Errorf("canonical: unsupported JWS algorithm %q", alg)
+	}
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	mismatch := fmt.Errorf("canonical: a key of type %T cannot verify %s signatures", key, alg)
+
+	valid := false
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return mismatch
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(input))
+		valid = hmac.Equal(signature, mac.Sum(nil))
+	case "RS", "PS":
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return mismatch
+		}
+		if alg[0] == 'P' {
+			valid = rsa.VerifyPSS(k, hash, digest(hash, input), signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(k, hash, digest(hash, input), signature) == nil
+		}
+	case "ES":
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve.Params().BitSize != ecdsaBits(alg) {
+			return mismatch
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(k, digest(hash, input), r, s)
+		}
+	case "Ed":
+		k, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return mismatch
+		}
+		valid = ed25519.Verify(k, []byte(input), signature)
+	}
+	if !valid {
+		return ErrSignature
+	}
+	return nil
+}
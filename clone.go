@@ -0,0 +1,43 @@
+package gojson
+
+import "github.com/oabrivard/gojson/parser"
+
+// Clone returns a deep copy of a parsed value: objects, ordered objects and
+// arrays are copied down to their scalars, so the copy can be modified, or
+// the original one from another goroutine, without affecting the other.
+// Scalars and values of other types are returned as is.
+func Clone(v interface{}) interface{} {
+	switch x := v.(type) {
+	case parser.JsonObject:
+		if x == nil {
+			return x
+		}
+		obj := make(parser.JsonObject, len(x))
+		for k, e := range x {
+			obj[k] = Clone(e)
+		}
+		return obj
+	case *parser.OrderedObject:
+		if x == nil {
+			return x
+		}
+		obj := &parser.OrderedObject{
+			Keys:   append(make([]string, 0, len(x.Keys)), x.Keys...),
+			Values: make(parser.JsonObject, len(x.Values)),
+		}
+		for k, e := range x.Values {
+			obj.Values[k] = Clone(e)
+		}
+		return obj
+	case parser.JsonArray:
+		if x == nil {
+			return x
+		}
+		arr := make(parser.JsonArray, len(x))
+		for i, e := range x {
+			arr[i] = Clone(e)
+		}
+		return arr
+	}
+	return v
+}
@@ -0,0 +1,44 @@
+package gojson
+
+import (
+	"testing"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+func TestClone(t *testing.T) {
+	original := parseValue(t, `{"a": [1, {"b": "x"}, [true]], "c": {"d": null}}`)
+	clone := Clone(original)
+	if !Equal(original, clone) {
+		t.Fatalf("expected the clone to equal the original")
+	}
+
+	obj := clone.(parser.JsonObject)
+	arr := obj["a"].(parser.JsonArray)
+	arr[0] = int64(2)
+	arr[1].(parser.JsonObject)["b"] = "y"
+	arr[2].(parser.JsonArray)[0] = false
+	obj["c"].(parser.JsonObject)["e"] = int64(1)
+	delete(obj, "c")
+
+	if !Equal(original, parseValue(t, `{"a": [1, {"b": "x"}, [true]], "c": {"d": null}}`)) {
+		t.Errorf("modifying the clone changed the original")
+	}
+
+	ordered := parser.NewOrderedObject()
+	ordered.Set("z", parser.JsonArray{"a"})
+	ordered.Set("y", int64(1))
+	copied := Clone(ordered).(*parser.OrderedObject)
+	copied.Set("x", true)
+	copied.Values["z"].(parser.JsonArray)[0] = "b"
+	copied.Delete("y")
+	if ordered.Len() != 2 || ordered.Keys[0] != "z" || ordered.Values["z"].(parser.JsonArray)[0] != "a" {
+		t.Errorf("modifying the clone changed the original: %v", ordered)
+	}
+
+	for _, v := range []interface{}{nil, true, int64(1), 1.5, "s"} {
+		if Clone(v) != v {
+			t.Errorf("expected Clone(%v) to return the scalar", v)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/oabrivard/gojson/diff"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// strategies maps the values of the -conflict flag to merge strategies.
+var strategies = map[string]diff.Strategy{
+	"last":  diff.KeepLast,
+	"first": diff.KeepFirst,
+	"error": diff.Fail,
+}
+
+// runCombine combines several files into a single document, an array holding
+// one element per file or the deep merge of their contents, and returns the
+// exit code.
+func runCombine(args []string) int {
+	var merge bool
+	var conflict string
+	var opts diff.MergeOptions
+
+	flags := flag.NewFlagSet("combine", flag.ExitOnError)
+	flags.BoolVar(&merge, "merge", false, "deep-merge the files into a single document instead of an array")
+	flags.StringVar(&conflict, "conflict", "last", "value kept when merged files disagree: last, first or error")
+	flags.BoolVar(&opts.AppendArrays, "append", false, "concatenate the arrays of merged files instead of resolving them as conflicts")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson combine [-merge [-conflict last|first|error] [-append]] filename...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	strategy, ok := strategies[conflict]
+	if flags.NArg() == 0 || !ok {
+		flags.Usage()
+		return 1
+	}
+	opts.Strategy = strategy
+
+	docs := make(parser.JsonArray, flags.NArg())
+	for i, name := range flags.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		docs[i], err = parseDocument(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			return 1
+		}
+	}
+
+	var result interface{} = docs
+	if merge {
+		var err error
+		if result, err = diff.MergeAll(docs, opts); err != nil {
+			var conflict *diff.ConflictError
+			if errors.As(err, &conflict) {
+				fmt.Fprintf(os.Stderr, "error: %s: %v\n", flags.Arg(conflict.Index), err)
+			} else {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			}
+			return 1
+		}
+	}
+	fmt.Println(linter.Format(result, linter.DefaultOptions()))
+	return 0
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/oabrivard/gojson/convert"
+	"github.com/oabrivard/gojson/hjson"
+	"github.com/oabrivard/gojson/json5"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// convertOptions holds the flags of the convert command that apply to some
+// formats only.
+type convertOptions struct {
+	columns []string // columns of CSV and TSV output
+}
+
+// format reads and writes documents in one data format.
+type format struct {
+	extensions []string // file extensions identifying the format
+	decode     func(data []byte) (interface{}, error)
+	encode     func(v interface{}, opts convertOptions) ([]byte, error)
+}
+
+// formats lists the data formats of the convert command by name.
+var formats = map[string]format{
+	"json": {extensions: []string{".json"}, decode: decodeJSON, encode: encodeJSON},
+	// strict JSON being valid JSON5 and Hjson, their output is that of the
+	// json format
+	"json5": {extensions: []string{".json5"}, decode: json5.Parse, encode: encodeJSON},
+	"hjson": {extensions: []string{".hjson"}, decode: hjson.Parse, encode: encodeJSON},
+	"toml": {
+		extensions: []string{".toml"},
+		decode:     convert.FromTOML,
+		encode:     func(v interface{}, _ convertOptions) ([]byte, error) { return convert.ToTOML(v) },
+	},
+	"yaml": {
+		extensions: []string{".yaml", ".yml"},
+		decode:     convert.FromYAML,
+		encode:     func(v interface{}, _ convertOptions) ([]byte, error) { return convert.ToYAML(v) },
+	},
+	"xml": {
+		extensions: []string{".xml"},
+		decode:     convert.FromXML,
+		encode:     func(v interface{}, _ convertOptions) ([]byte, error) { return convert.ToXML(v) },
+	},
+	"bson": {extensions: []string{".bson"}, decode: decodeBSON, encode: encodeBSON},
+	"cbor": {
+		extensions: []string{".cbor"},
+		decode:     convert.FromCBOR,
+		encode:     func(v interface{}, _ convertOptions) ([]byte, error) { return convert.ToCBOR(v) },
+	},
+	"msgpack": {
+		extensions: []string{".msgpack", ".mpk"},
+		decode:     convert.FromMessagePack,
+		encode:     func(v interface{}, _ convertOptions) ([]byte, error) { return convert.ToMessagePack(v) },
+	},
+	// query strings have no file extension and end with a newline so that
+	// they print well
+	"query": {
+		decode: convert.FromQuery,
+		encode: func(v interface{}, _ convertOptions) ([]byte, error) {
+			data, err := convert.ToQuery(v)
+			return append(data, '\n'), err
+		},
+	},
+	"csv": csvFormat(',', ".csv"),
+	"tsv": csvFormat('\t', ".tsv"),
+}
+
+// csvFormat returns a CSV format using the given delimiter. Fields that look
+// like numbers or booleans are read as such.
+func csvFormat(comma rune, extension string) format {
+	return format{
+		extensions: []string{extension},
+		decode: func(data []byte) (interface{}, error) {
+			return convert.FromCSV(data, convert.CSVOptions{Comma: comma, InferTypes: true})
+		},
+		encode: func(v interface{}, opts convertOptions) ([]byte, error) {
+			return convert.ToCSV(v, convert.CSVOptions{Comma: comma, Columns: opts.columns})
+		},
+	}
+}
+
+// decodeJSON parses a JSON document.
+func decodeJSON(data []byte) (interface{}, error) {
+	return parseDocument(bytes.NewReader(data))
+}
+
+// encodeJSON formats a JSON document the way the fmt command does.
+func encodeJSON(v interface{}, _ convertOptions) ([]byte, error) {
+	return []byte(linter.Format(v, linter.DefaultOptions()) + "\n"), nil
+}
+
+// decodeBSON converts a BSON document, or an array of the documents of a
+// mongodump file holding several.
+func decodeBSON(data []byte) (interface{}, error) {
+	docs, err := convert.FromBSONDocuments(data)
+	if err != nil || len(docs) != 1 {
+		return docs, err
+	}
+	return docs[0], nil
+}
+
+// encodeBSON converts an object into a BSON document, or an array of objects
+// into a sequence of documents as written by mongodump.
+func encodeBSON(v interface{}, _ convertOptions) ([]byte, error) {
+	docs, ok := v.(parser.JsonArray)
+	if !ok {
+		return convert.ToBSON(v)
+	}
+	var result []byte
+	for _, doc := range docs {
+		data, err := convert.ToBSON(doc)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, data...)
+	}
+	return result, nil
+}
+
+// formatNames returns the names of the supported formats, sorted.
+func formatNames() string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// formatOf returns the name of the format identified by the extension of
+// fileName, or json.
+func formatOf(fileName string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for name, f := range formats {
+		for _, e := range f.extensions {
+			if e == ext {
+				return name
+			}
+		}
+	}
+	return "json"
+}
+
+// runConvert converts a file, or standard input, from one data format into
+// another and returns the exit code.
+func runConvert(args []string) int {
+	var from, to, columns string
+	var opts convertOptions
+
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	flags.StringVar(&from, "from", "", "format of the input, guessed from the file extension when empty ("+formatNames()+")")
+	flags.StringVar(&to, "to", "json", "format of the output ("+formatNames()+")")
+	flags.StringVar(&columns, "columns", "", "comma-separated columns of csv and tsv output, in order; all the keys found when empty")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson convert [-from format] [-to format] [-columns list] [filename]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if columns != "" {
+		opts.columns = strings.Split(columns, ",")
+	}
+
+	if flags.NArg() > 1 || (flags.NArg() == 0 && !isInputFromPipe()) {
+		flags.Usage()
+		return 1
+	}
+
+	name, r := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		name, r = flags.Arg(0), f
+		if from == "" {
+			from = formatOf(name)
+		}
+	}
+	if from == "" {
+		from = "json"
+	}
+
+	input, ok := formats[from]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown input format %q, expected one of %s\n", from, formatNames())
+		return 1
+	}
+	output, ok := formats[to]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: unknown output format %q, expected one of %s\n", to, formatNames())
+		return 1
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	doc, err := input.decode(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+	result, err := output.encode(doc, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+	os.Stdout.Write(result)
+	return 0
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// diffOpKind tells whether a line is kept, removed or added by a diff.
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffDelete
+	diffInsert
+)
+
+// diffOp is a single line of an edit script. aLine and bLine are the number of
+// lines of a and b that come before the operation.
+type diffOp struct {
+	kind  diffOpKind
+	line  string
+	aLine int
+	bLine int
+}
+
+// unifiedDiff returns a unified diff turning a into b, using aName and bName as
+// file labels. It returns the empty string when both texts are identical.
+func unifiedDiff(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var result strings.Builder
+	result.WriteString("--- " + aName + "\n")
+	result.WriteString("+++ " + bName + "\n")
+
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are close enough
+		// for their contexts to overlap.
+		first := start
+		for first < len(ops) && ops[first].kind == diffEqual {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != diffEqual {
+				if i-last > 2*diffContext {
+					break
+				}
+				last = i
+			}
+		}
+
+		from := max(first-diffContext, start)
+		to := min(last+diffContext+1, len(ops))
+		writeHunk(&result, ops[from:to])
+		start = to
+	}
+
+	return result.String()
+}
+
+// writeHunk writes the header and the lines of one hunk.
+func writeHunk(result *strings.Builder, ops []diffOp) {
+	aCount, bCount := 0, 0
+	for _, op := range ops {
+		if op.kind != diffInsert {
+			aCount++
+		}
+		if op.kind != diffDelete {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(result, "@@ -%s +%s @@\n", hunkRange(ops[0].aLine, aCount), hunkRange(ops[0].bLine, bCount))
+	for _, op := range ops {
+		switch op.kind {
+		case diffEqual:
+			result.WriteString(" ")
+		case diffDelete:
+			result.WriteString("-")
+		case diffInsert:
+			result.WriteString("+")
+		}
+		result.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			result.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the start,count pair of a hunk header.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits text into lines, each keeping its trailing newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes the shortest edit script turning a into b using Myers'
+// algorithm.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the edit script.
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: diffEqual, line: a[x], aLine: x, bLine: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, diffOp{kind: diffInsert, line: b[y], aLine: x, bLine: y})
+			} else {
+				x--
+				ops = append(ops, diffOp{kind: diffDelete, line: a[x], aLine: x, bLine: y})
+			}
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/schema"
+)
+
+// runFake prints random documents satisfying a JSON Schema read from a file,
+// or standard input, and returns the exit code.
+func runFake(args []string) int {
+	var opts schema.GenerateOptions
+	var count int
+	var seed int64
+
+	flags := flag.NewFlagSet("fake", flag.ExitOnError)
+	flags.IntVar(&count, "n", 1, "number of documents, printed one per line when more than one")
+	flags.Int64Var(&seed, "seed", 0, "seed of the random documents, a different one each run when 0")
+	flags.IntVar(&opts.MaxItems, "items", 0, "maximum number of optional array elements, 3 when 0")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson fake [-n count] [-seed n] [-items n] [schema.json]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 || (flags.NArg() == 0 && !isInputFromPipe()) {
+		flags.Usage()
+		return 1
+	}
+	if seed != 0 {
+		opts.Rand = rand.New(rand.NewPCG(uint64(seed), 0))
+	}
+
+	name, r := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		name, r = flags.Arg(0), f
+	}
+
+	doc, err := parseDocument(r)
+	var s *schema.Schema
+	if err == nil {
+		s, err = schema.Compile(doc)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+
+	format := linter.DefaultOptions()
+	if count > 1 {
+		format = linter.Options{InlineWidth: 1 << 30}
+	}
+	for i := 0; i < count; i++ {
+		v, err := s.Generate(opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			return 1
+		}
+		fmt.Println(linter.Format(v, format))
+	}
+	return 0
+}
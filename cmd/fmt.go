@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oabrivard/gojson/json5"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/mmap"
+)
+
+// fmtOptions holds the flags of the fmt command.
+type fmtOptions struct {
+	check     bool
+	diff      bool
+	timing    bool
+	stream    bool
+	mmap      bool
+	write     bool
+	json5     bool
+	lines     bool
+	canonical bool
+	compact   bool
+	indent    int
+	tab       bool
+	prefix    string
+	sort      bool
+	color     bool // resolved from the -color flag
+}
+
+// layout returns the layout options the flags select.
+func (opts fmtOptions) layout() linter.Options {
+	layout := linter.Options{Prefix: opts.prefix, Indent: strings.Repeat(" ", opts.indent), SortKeys: opts.sort, Compact: opts.compact, Color: opts.color}
+	if opts.tab {
+		layout.Indent = "\t"
+	}
+	return layout
+}
+
+// useColor reports whether output is highlighted in the given -color mode:
+// auto highlights it when standard output is a terminal, unless the NO_COLOR
+// environment variable is set.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("unknown -color mode %q, expected auto, always or never", mode)
+}
+
+// runFmt formats the files named in args, or standard input, and returns the
+// exit code.
+func runFmt(args []string) int {
+	var opts fmtOptions
+
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	flags.BoolVar(&opts.check, "check", false, "report files whose formatting differs from gojson's output instead of printing it")
+	flags.BoolVar(&opts.diff, "diff", false, "with -check, print a unified diff of the changes gojson would make")
+	flags.BoolVar(&opts.timing, "timing", false, "print parse and format times, throughput and allocations for each file to stderr")
+	flags.BoolVar(&opts.stream, "stream", false, "format while reading, using constant memory even for huge inputs")
+	flags.BoolVar(&opts.mmap, "mmap", false, "map files into memory and parse them in place instead of reading them")
+	flags.BoolVar(&opts.write, "write", false, "replace the content of the files with their formatted version")
+	flags.BoolVar(&opts.json5, "json5", false, "read JSON5 documents, with comments, unquoted keys and trailing commas, and print them as strict JSON")
+	flags.BoolVar(&opts.lines, "lines", false, "read newline-delimited JSON (JSON Lines), printing each record formatted, or only reporting bad lines with -check")
+	flags.BoolVar(&opts.canonical, "canonical", false, "print the canonical form of documents (RFC 8785), without a final newline, as signed payloads need")
+	flags.BoolVar(&opts.compact, "compact", false, "print documents without any insignificant white space")
+	flags.IntVar(&opts.indent, "indent", 2, "indent nested values by this number of spaces")
+	flags.BoolVar(&opts.tab, "tab", false, "indent nested values with tabs instead of spaces")
+	flags.StringVar(&opts.prefix, "prefix", "", "begin every line but the first with this prefix")
+	flags.BoolVar(&opts.sort, "sort", false, "print object members sorted by key, so that the output does not depend on the order of the input")
+	colorMode := flags.String("color", "auto", "highlight the output printed: auto, when it is a terminal, always or never")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson [fmt] [-check [-diff] | -write] [-stream | -mmap] [-timing] [-json5 | -lines] [-canonical | -compact | -indent n | -tab] [-prefix p] [-sort] [-color mode] filename...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if opts.diff && !opts.check {
+		fmt.Fprintf(os.Stderr, "error: -diff requires -check\n")
+		return 1
+	}
+	if opts.stream && opts.check {
+		fmt.Fprintf(os.Stderr, "error: -stream cannot be combined with -check\n")
+		return 1
+	}
+	if opts.mmap && opts.stream {
+		fmt.Fprintf(os.Stderr, "error: -mmap cannot be combined with -stream\n")
+		return 1
+	}
+	if opts.write && (opts.check || opts.stream || opts.mmap || opts.timing) {
+		fmt.Fprintf(os.Stderr, "error: -write cannot be combined with -check, -stream, -mmap or -timing\n")
+		return 1
+	}
+
+	if opts.json5 && (opts.stream || opts.mmap) {
+		fmt.Fprintf(os.Stderr, "error: -json5 cannot be combined with -stream or -mmap\n")
+		return 1
+	}
+
+	if opts.lines && (opts.diff || opts.write || opts.stream || opts.mmap || opts.timing || opts.json5) {
+		fmt.Fprintf(os.Stderr, "error: -lines cannot be combined with -diff, -write, -stream, -mmap, -timing or -json5\n")
+		return 1
+	}
+
+	if opts.canonical && (opts.stream || opts.lines) {
+		fmt.Fprintf(os.Stderr, "error: -canonical cannot be combined with -stream or -lines\n")
+		return 1
+	}
+
+	if opts.compact && opts.canonical {
+		fmt.Fprintf(os.Stderr, "error: -compact cannot be combined with -canonical\n")
+		return 1
+	}
+	if opts.sort && opts.stream {
+		fmt.Fprintf(os.Stderr, "error: -sort cannot be combined with -stream\n")
+		return 1
+	}
+	var err error
+	if opts.color, err = useColor(*colorMode); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	// Files written or compared must not hold escape sequences.
+	opts.color = opts.color && !opts.check && !opts.write && !opts.canonical
+
+	if opts.indent < 0 {
+		fmt.Fprintf(os.Stderr, "error: -indent must not be negative\n")
+		return 1
+	}
+
+	if isInputFromPipe() && flags.NArg() == 0 {
+		if opts.mmap {
+			fmt.Fprintf(os.Stderr, "error: -mmap requires file names\n")
+			return 1
+		}
+		return processFile("<stdin>", os.Stdin, opts)
+	}
+
+	if flags.NArg() == 0 || (!opts.check && !opts.write && flags.NArg() != 1) {
+		flags.Usage()
+		return 1
+	}
+	if opts.write {
+		return writeFiles(flags.Args(), opts)
+	}
+
+	exitCode := 0
+	for _, fileName := range flags.Args() {
+		if opts.mmap {
+			exitCode = max(exitCode, processMappedFile(fileName, opts))
+			continue
+		}
+		f, err := os.Open(fileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		if code := processFile(fileName, f, opts); code > exitCode {
+			exitCode = code
+		}
+		f.Close()
+	}
+	return exitCode
+}
+
+// processFile lints the content of r and either prints the result or, in check
+// mode, compares it with the original content. It returns the exit code.
+func processFile(name string, r io.Reader, opts fmtOptions) int {
+	var report *resourceReport
+	if opts.timing {
+		report = newResourceReport(name)
+		defer report.print(os.Stderr)
+	}
+
+	if opts.stream {
+		return streamFile(name, r, opts, report)
+	}
+	if opts.lines {
+		return processLines(name, r, opts)
+	}
+
+	start := time.Now()
+	bytes, err := io.ReadAll(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if report != nil {
+		report.sample()
+	}
+
+	strict, err := strictJSON(bytes, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+	return lintInput(name, bytes, linter.NewJsonLinterWithOptions(string(strict), opts.layout()), start, opts, report)
+}
+
+// strictJSON returns input, converted from JSON5 to strict JSON with -json5,
+// syntax errors being located in input.
+func strictJSON(input []byte, opts fmtOptions) ([]byte, error) {
+	if !opts.json5 {
+		return input, nil
+	}
+	return json5.ToJSON(input, linter.DefaultOptions())
+}
+
+// processMappedFile is like processFile for the named file, mapped into memory
+// and parsed in place.
+func processMappedFile(name string, opts fmtOptions) int {
+	var report *resourceReport
+	if opts.timing {
+		report = newResourceReport(name)
+		defer report.print(os.Stderr)
+	}
+
+	start := time.Now()
+	f, err := mmap.Open(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer f.Close()
+	if report != nil {
+		report.sample()
+	}
+
+	return lintInput(name, f.Bytes(), linter.NewJsonLinterFromBytes(f.Bytes(), opts.layout()), start, opts, report)
+}
+
+// lintInput lints input, read since start, with jl and either prints the
+// result as it is laid out or, in check mode, compares it with input. It returns the exit code.
+func lintInput(name string, input []byte, jl *linter.JsonLinter, start time.Time, opts fmtOptions, report *resourceReport) int {
+	var formatted string
+	var err error
+	if opts.check || opts.canonical {
+		formatted, err = output(jl, opts)
+	} else {
+		err = printDocument(jl)
+	}
+	if report != nil {
+		report.bytes = len(input)
+		report.stages = jl.Timings()
+		report.read = time.Since(start) - report.stages.Parse - report.stages.Format
+		report.sample()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+
+	if !opts.check {
+		fmt.Print(formatted) // empty unless canonical, printDocument having written the document
+		return 0
+	}
+
+	if string(input) == formatted {
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: not formatted\n", name)
+	if opts.diff {
+		fmt.Print(unifiedDiff(name, name+" (formatted)", string(input), formatted))
+	}
+	return 1
+}
+
+// output returns what gojson prints for the document of jl: its formatted
+// version followed by a newline, or its canonical form with -canonical,
+// which ends without one so that it can be hashed as is.
+func output(jl *linter.JsonLinter, opts fmtOptions) (string, error) {
+	if opts.canonical {
+		return jl.Canonicalize()
+	}
+	result, err := jl.Lint()
+	if err != nil {
+		return "", err
+	}
+	return result + "\n", nil
+}
+
+// printDocument writes the formatted document of jl to standard output as it is laid
+// out, followed by a newline, as output returns it.
+func printDocument(jl *linter.JsonLinter) error {
+	out := bufio.NewWriter(os.Stdout)
+	if err := jl.LintTo(out); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	return out.Flush()
+}
+
+// streamFile formats r to standard output while reading it. Reading, parsing
+// and formatting happen in a single pass, so the report only has a format time.
+func streamFile(name string, r io.Reader, opts fmtOptions, report *resourceReport) int {
+	counter := &countingReader{r: r}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	start := time.Now()
+	err := linter.LintStreamWithOptions(counter, out, opts.layout())
+	if report != nil {
+		report.bytes = counter.n
+		report.stages.Format = time.Since(start)
+		report.sample()
+	}
+	if err != nil {
+		out.Flush()
+		fmt.Fprintf(os.Stderr, "\nerror: %s: %v\n", name, err)
+		return 1
+	}
+
+	fmt.Fprintln(out)
+	return 0
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
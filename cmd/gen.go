@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oabrivard/gojson/codegen"
+)
+
+// runGen prints Go types matching the sample documents named in args, or read
+// from standard input, or described by a JSON Schema, or a Go literal holding
+// a document, and returns the exit code.
+func runGen(args []string) int {
+	var opts codegen.Options
+	var fromSchema, literal, value bool
+
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	flags.StringVar(&opts.Package, "package", "main", "name of the generated package")
+	flags.StringVar(&opts.TypeName, "type", "Root", "name of the top-level type")
+	flags.BoolVar(&fromSchema, "schema", false, "read a single JSON Schema describing the types instead of samples")
+	flags.BoolVar(&literal, "literal", false, "print a single document as a map[string]interface{} literal")
+	flags.BoolVar(&value, "value", false, "print the types of a single document and a variable holding it")
+	flags.StringVar(&opts.VarName, "var", "value", "name of the variable printed with -value")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson gen [-package name] [-type name] [-schema | -literal | -value [-var name]] [filename...]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var samples []interface{}
+	read := func(name string, r io.Reader) bool {
+		doc, err := parseDocument(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			return false
+		}
+		samples = append(samples, doc)
+		return true
+	}
+
+	if flags.NArg() == 0 {
+		if !isInputFromPipe() {
+			flags.Usage()
+			return 1
+		}
+		if !read("<stdin>", os.Stdin) {
+			return 1
+		}
+	}
+	for _, fileName := range flags.Args() {
+		f, err := os.Open(fileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		ok := read(fileName, f)
+		f.Close()
+		if !ok {
+			return 1
+		}
+	}
+
+	var src string
+	var err error
+	switch {
+	case (fromSchema || literal || value) && len(samples) != 1:
+		fmt.Fprintf(os.Stderr, "error: -schema, -literal and -value require a single document\n")
+		return 1
+	case fromSchema:
+		src, err = codegen.FromSchema(opts, samples[0])
+	case literal:
+		src, err = codegen.Literal(samples[0])
+		src += "\n"
+	case value:
+		src, err = codegen.ValueLiteral(opts, samples[0])
+	default:
+		src, err = codegen.FromSamples(opts, samples...)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	fmt.Print(src)
+	return 0
+}
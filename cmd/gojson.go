@@ -5,7 +5,8 @@ import (
 	"io"
 	"os"
 
-	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
 )
 
 func isInputFromPipe() bool {
@@ -13,43 +14,55 @@ func isInputFromPipe() bool {
 	return fileInfo.Mode()&os.ModeCharDevice == 0
 }
 
-func main() {
-	var f *os.File
-
-	if isInputFromPipe() {
-		f = os.Stdin
-
-	} else {
-		fileName := ""
+// parseDocument parses the JSON value read from r, its objects keeping their
+// keys in source order so that commands print them as they were written.
+func parseDocument(r io.Reader) (interface{}, error) {
+	l := lexer.NewReaderLexer(r)
+	p := parser.NewParser(l)
+	doc := p.ParseOrderedValue()
+	if err := l.Err(); err != nil {
+		return nil, err
+	}
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("parsing errors: %v", p.Errors())
+	}
+	return doc, nil
+}
 
-		if len(os.Args) != 2 {
-			fmt.Fprintf(os.Stderr, "gojson filename\n")
-			os.Exit(1)
-		} else {
-			fileName = os.Args[1]
-		}
+func main() {
+	args := os.Args[1:]
 
-		var err error
-		f, err = os.Open(fileName)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
-		}
-		defer f.Close()
+	if len(args) > 0 && args[0] == "query" {
+		os.Exit(runQuery(args[1:]))
 	}
-
-	bytes, err := io.ReadAll(f)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	if len(args) > 0 && args[0] == "convert" {
+		os.Exit(runConvert(args[1:]))
 	}
-
-	jl := linter.NewJsonLinter(string(bytes))
-	result, err := jl.Lint()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	if len(args) > 0 && args[0] == "gen" {
+		os.Exit(runGen(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "gron" {
+		os.Exit(runGron(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "split" {
+		os.Exit(runSplit(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "lsp" {
+		os.Exit(runLSP(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "fake" {
+		os.Exit(runFake(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "template" {
+		os.Exit(runTemplate(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "combine" {
+		os.Exit(runCombine(args[1:]))
 	}
 
-	fmt.Println(result)
+	// fmt is the default command, so "gojson file.json" keeps working.
+	if len(args) > 0 && args[0] == "fmt" {
+		args = args[1:]
+	}
+	os.Exit(runFmt(args))
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// capture runs command with args, and returns its exit code and what it
+// printed to standard output.
+func capture(t *testing.T, command func(args []string) int, args ...string) (int, string) {
+	t.Helper()
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	code := command(args)
+	os.Stdout = stdout
+
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	printed, err := io.ReadAll(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(printed)
+}
+
+func TestCommandsKeepKeyOrder(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "doc.json")
+	if err := os.WriteFile(name, []byte(`{"zeta": {"b": 1, "a": 2}, "alpha": [{"y": true, "x": false}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command  func(args []string) int
+		args     []string
+		expected string
+	}{
+		{runQuery, []string{".", name}, "{\n  \"zeta\": {\n    \"b\": 1,\n    \"a\": 2\n  },\n  \"alpha\": [\n    {\n      \"y\": true,\n      \"x\": false\n    }\n  ]\n}\n"},
+		{runQuery, []string{".zeta", name}, "{\n  \"b\": 1,\n  \"a\": 2\n}\n"},
+		{runGron, []string{name}, "json = {};\njson.zeta = {};\njson.zeta.b = 1;\njson.zeta.a = 2;\njson.alpha = [];\njson.alpha[0] = {};\njson.alpha[0].y = true;\njson.alpha[0].x = false;\n"},
+	}
+	for _, test := range tests {
+		code, printed := capture(t, test.command, test.args...)
+		if code != 0 || printed != test.expected {
+			t.Errorf("%s: expected %q, got %q (exit code %d)", strings.Join(test.args, " "), test.expected, printed, code)
+		}
+	}
+}
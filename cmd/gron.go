@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oabrivard/gojson/gron"
+	"github.com/oabrivard/gojson/linter"
+)
+
+// runGron prints a file, or standard input, as one assignment per line, or
+// turns such assignments back into a document with -u, and returns the exit
+// code.
+func runGron(args []string) int {
+	var ungron bool
+
+	flags := flag.NewFlagSet("gron", flag.ExitOnError)
+	flags.BoolVar(&ungron, "u", false, "turn assignments back into a formatted document")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson gron [-u] [filename]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 || (flags.NArg() == 0 && !isInputFromPipe()) {
+		flags.Usage()
+		return 1
+	}
+
+	name, r := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		name, r = flags.Arg(0), f
+	}
+
+	if ungron {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		doc, err := gron.Ungron(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			return 1
+		}
+		fmt.Println(linter.Format(doc, linter.DefaultOptions()))
+		return 0
+	}
+
+	doc, err := parseDocument(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+	fmt.Print(gron.Format(doc))
+	return 0
+}
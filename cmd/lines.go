@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/ndjson"
+)
+
+// processLines reads the newline-delimited JSON held by r record by record,
+// printing each of them formatted unless in check mode, and reporting every
+// bad line with its number. It returns the exit code.
+func processLines(name string, r io.Reader, opts fmtOptions) int {
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	exitCode := 0
+	reader := ndjson.NewReaderWithOptions(r, ndjson.Options{
+		PreserveOrder: true,
+		OnError: func(err *ndjson.LineError) error {
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "error: %s:%d: %v\n", name, err.Line, err.Err)
+			exitCode = 1
+			return nil
+		},
+	})
+	for {
+		v, err := reader.Next()
+		if err == io.EOF {
+			return exitCode
+		}
+		if err != nil {
+			out.Flush()
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			return 1
+		}
+		if !opts.check {
+			fmt.Fprintln(out, linter.Format(v, opts.layout()))
+		}
+	}
+}
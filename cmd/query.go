@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/query"
+)
+
+// runQuery evaluates the expression in args against a file, or standard
+// input, prints every output and returns the exit code. With -sql, the
+// expression is a SQL-like statement and the array of selected rows is
+// printed.
+func runQuery(args []string) int {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson query [-sql] expression [filename]\n")
+		flags.PrintDefaults()
+	}
+	sql := flags.Bool("sql", false, "the expression is a SELECT statement")
+	flags.Parse(args)
+
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 1
+	}
+
+	var run func(doc interface{}) ([]interface{}, error)
+	if *sql {
+		s, err := query.CompileSQL(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		run = func(doc interface{}) ([]interface{}, error) {
+			rows, err := s.Run(doc)
+			return []interface{}{rows}, err
+		}
+	} else {
+		q, err := query.Compile(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		run = q.Run
+	}
+
+	name, r := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 2 {
+		f, err := os.Open(flags.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		name, r = flags.Arg(1), f
+	} else if !isInputFromPipe() {
+		flags.Usage()
+		return 1
+	}
+
+	doc, err := parseDocument(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+
+	outputs, err := run(doc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+	for _, v := range outputs {
+		fmt.Println(linter.Format(v, linter.DefaultOptions()))
+	}
+	return 0
+}
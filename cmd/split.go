@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oabrivard/gojson/stream"
+)
+
+// runSplit writes the elements of the array held by a file, or standard
+// input, into several files and returns the exit code.
+func runSplit(args []string) int {
+	var opts stream.SplitOptions
+	var prefix string
+
+	flags := flag.NewFlagSet("split", flag.ExitOnError)
+	flags.IntVar(&opts.Shards, "n", 0, "number of files receiving the elements in turn")
+	flags.Int64Var(&opts.MaxSize, "size", 0, "maximum size of each file in bytes, consecutive elements going to the same file")
+	flags.StringVar(&prefix, "o", "shard-", "prefix of the names of the files, followed by their number and .json")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson split (-n count | -size bytes) [-o prefix] [filename]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 || (flags.NArg() == 0 && !isInputFromPipe()) || (opts.Shards > 0) == (opts.MaxSize > 0) {
+		flags.Usage()
+		return 1
+	}
+
+	name, r := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		name, r = flags.Arg(0), f
+	}
+
+	create := func(shard int) (io.WriteCloser, error) {
+		f, err := os.Create(fmt.Sprintf("%s%04d.json", prefix, shard))
+		if err != nil {
+			return nil, err
+		}
+		return &bufferedFile{Writer: bufio.NewWriter(f), file: f}, nil
+	}
+	if _, err := stream.Split(r, create, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+// bufferedFile is a file written through a buffer, flushed when the file is
+// closed.
+type bufferedFile struct {
+	*bufio.Writer
+	file *os.File
+}
+
+func (f *bufferedFile) Close() error {
+	err := f.Flush()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/oabrivard/gojson/interpolate"
+	"github.com/oabrivard/gojson/linter"
+)
+
+// runTemplate prints a template read from a file, or standard input, with
+// its placeholders replaced by environment variables and the fields of a data
+// file, and returns the exit code.
+func runTemplate(args []string) int {
+	var opts interpolate.Options
+	var dataFile string
+
+	flags := flag.NewFlagSet("template", flag.ExitOnError)
+	flags.StringVar(&dataFile, "data", "", "JSON file holding the fields of {{.field}} placeholders")
+	flags.BoolVar(&opts.WholeValues, "values", false, "replace strings made of a single placeholder by the value itself, such as a number")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "gojson template [-data file.json] [-values] [filename]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() > 1 || (flags.NArg() == 0 && !isInputFromPipe()) {
+		flags.Usage()
+		return 1
+	}
+
+	if dataFile != "" {
+		f, err := os.Open(dataFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		if opts.Data, err = parseDocument(f); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", dataFile, err)
+			return 1
+		}
+	}
+
+	name, r := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		name, r = flags.Arg(0), f
+	}
+
+	doc, err := parseDocument(r)
+	if err == nil {
+		doc, err = interpolate.Interpolate(doc, opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+		return 1
+	}
+	fmt.Println(linter.Format(doc, linter.DefaultOptions()))
+	return 0
+}
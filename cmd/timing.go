@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+
+	"github.com/oabrivard/gojson/linter"
+)
+
+// resourceReport collects the measurements printed by the -timing flag for one
+// file.
+type resourceReport struct {
+	name    string
+	bytes   int
+	read    time.Duration
+	stages  linter.Timings
+	allocs  uint64 // number of heap allocations
+	alloced uint64 // bytes allocated on the heap
+	maxHeap uint64 // largest live heap observed between stages
+
+	before runtime.MemStats
+}
+
+// newResourceReport starts measuring resources for the named file.
+func newResourceReport(name string) *resourceReport {
+	r := &resourceReport{name: name}
+	runtime.ReadMemStats(&r.before)
+	return r
+}
+
+// sample records the current live heap size, keeping the largest value seen.
+func (r *resourceReport) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	r.maxHeap = max(r.maxHeap, m.HeapAlloc)
+	r.allocs = m.Mallocs - r.before.Mallocs
+	r.alloced = m.TotalAlloc - r.before.TotalAlloc
+}
+
+// print writes the report as a single line to w.
+func (r *resourceReport) print(w io.Writer) {
+	total := r.read + r.stages.Parse + r.stages.Format
+	throughput := 0.0
+	if total > 0 {
+		throughput = float64(r.bytes) / total.Seconds() / (1 << 20)
+	}
+
+	fmt.Fprintf(w, "%s: read %v, parse %v, format %v, %d bytes, %.2f MiB/s, %d allocs (%s), max heap %s\n",
+		r.name, r.read, r.stages.Parse, r.stages.Format, r.bytes, throughput, r.allocs, formatBytes(r.alloced), formatBytes(r.maxHeap))
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/oabrivard/gojson/linter"
+)
+
+// errInterrupted is returned by writeFile when a signal arrives before the
+// file is replaced.
+var errInterrupted = errors.New("interrupted")
+
+// writeFiles formats the named files in place and returns the exit code.
+// SIGINT and SIGTERM are caught while it runs: the file being written is left
+// untouched, its temporary file removed, and the files done and pending are
+// listed before exiting.
+func writeFiles(names []string, opts fmtOptions) int {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var caught os.Signal
+	interrupted := func() bool {
+		if caught == nil {
+			select {
+			case caught = <-signals:
+			default:
+			}
+		}
+		return caught != nil
+	}
+
+	exitCode := 0
+	for i, name := range names {
+		err := formatInPlace(name, opts, interrupted)
+		if err == errInterrupted || err == nil && interrupted() {
+			if err == nil {
+				i++ // the file was written before the signal was noticed
+			}
+			fmt.Fprintf(os.Stderr, "interrupted by %v: %d of %d files done\n", caught, i, len(names))
+			if i < len(names) {
+				fmt.Fprintf(os.Stderr, "pending: %s\n", strings.Join(names[i:], " "))
+			}
+			return 128 + int(caught.(syscall.Signal))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", name, err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// formatInPlace replaces the content of the named file with its formatted
+// version, unless it is already formatted.
+func formatInPlace(name string, opts fmtOptions, interrupted func() bool) error {
+	input, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	strict, err := strictJSON(input, opts)
+	if err != nil {
+		return err
+	}
+	formatted, err := output(linter.NewJsonLinterWithOptions(string(strict), opts.layout()), opts)
+	if err != nil {
+		return err
+	}
+	if string(input) == formatted {
+		return nil
+	}
+	return writeFile(name, []byte(formatted), interrupted)
+}
+
+// writeFile atomically replaces the content of the named file with data: data
+// is written to a temporary file of the same directory, which is then renamed
+// over the original, so that readers and interruptions never see a partially
+// written file. The temporary file is removed on errors and when interrupted
+// reports a signal before the rename, which leaves the original unchanged.
+func writeFile(name string, data []byte, interrupted func() bool) (err error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if interrupted() {
+		return errInterrupted
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,384 @@
+// Package codegen generates Go type definitions, with json struct tags, that
+// match JSON documents produced by the parser package, and Go literals holding
+// such documents.
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Options controls the generated code.
+type Options struct {
+	Package  string // name of the generated package, "main" when empty
+	TypeName string // name of the top-level type, "Root" when empty
+	VarName  string // name of the variable declared by ValueLiteral, "value" when empty
+}
+
+// kind distinguishes the Go types a JSON location can map to.
+type kind int
+
+const (
+	kindNull kind = iota // only null was seen
+	kindBool
+	kindInt
+	kindFloat
+	kindString
+	kindTime
+	kindSlice
+	kindStruct
+	kindAny
+)
+
+// goType is the Go type inferred for one location of the samples.
+type goType struct {
+	kind     kind
+	seen     bool // a value has been observed, even null
+	nullable bool // null has been observed along with other values
+
+	elem *goType // element type of a slice
+
+	name    string   // name of a struct type
+	hint    string   // name suggested by the location, before deduplication
+	fields  []*field // fields of a struct, in the order they were first seen
+	objects int      // number of objects merged into a struct
+}
+
+// field is a field of a generated struct.
+type field struct {
+	key   string // JSON name
+	name  string // Go name
+	typ   *goType
+	count int // number of objects holding the field
+}
+
+// FromSamples returns the Go source of types matching every sample document.
+// Objects become structs named after the key holding them, arrays become
+// slices of the merged type of their elements, integral numbers become int64
+// and others float64, and strings holding RFC 3339 timestamps become
+// time.Time. Fields missing from some objects are tagged omitempty, and
+// fields that are sometimes null become pointers.
+func FromSamples(opts Options, samples ...interface{}) (string, error) {
+	root := &goType{}
+	for _, v := range samples {
+		root.merge(v)
+	}
+
+	g := newGenerator(opts)
+	g.name(root)
+	return g.render(root)
+}
+
+// merge records the sample value v.
+func (t *goType) merge(v interface{}) {
+	observed := kindOf(v)
+	switch {
+	case observed == kindNull:
+		if t.seen && t.kind != kindNull {
+			t.nullable = true
+		}
+		t.seen = true
+		return
+	case !t.seen || t.kind == kindNull:
+		t.nullable = t.seen
+		t.kind = observed
+	case t.kind == kindTime && observed == kindString, t.kind == kindString && observed == kindTime:
+		t.kind = kindString
+	case t.kind == kindInt && observed == kindFloat, t.kind == kindFloat && observed == kindInt:
+		t.kind = kindFloat
+	case t.kind != observed:
+		t.kind = kindAny
+	}
+	t.seen = true
+
+	switch t.kind {
+	case kindSlice:
+		if t.elem == nil {
+			t.elem = &goType{}
+		}
+		for _, e := range v.(parser.JsonArray) {
+			t.elem.merge(e)
+		}
+	case kindStruct:
+		t.mergeObject(v)
+	}
+}
+
+// mergeObject records the members of an object.
+func (t *goType) mergeObject(v interface{}) {
+	t.objects++
+	keys, members, _ := parser.Members(v)
+	for _, k := range keys {
+		var f *field
+		for _, existing := range t.fields {
+			if existing.key == k {
+				f = existing
+				break
+			}
+		}
+		if f == nil {
+			f = &field{key: k, typ: &goType{}}
+			t.fields = append(t.fields, f)
+		}
+		f.typ.merge(members[k])
+		f.count++
+	}
+}
+
+// kindOf returns the kind of a parsed value.
+func kindOf(v interface{}) kind {
+	switch x := v.(type) {
+	case nil:
+		return kindNull
+	case bool:
+		return kindBool
+	case int64:
+		return kindInt
+	case float64:
+		return kindFloat
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, x); err == nil {
+			return kindTime
+		}
+		return kindString
+	case parser.JsonArray:
+		return kindSlice
+	case parser.JsonObject, *parser.OrderedObject:
+		return kindStruct
+	}
+	return kindAny
+}
+
+// generator names and renders the inferred types.
+type generator struct {
+	pkg      string
+	typeName string
+	used     map[string]bool // type names already taken
+	structs  []*goType       // structs to declare, in naming order
+	imports  map[string]bool
+	usesPtr  bool // a value literal calls the ptr helper
+}
+
+func newGenerator(opts Options) *generator {
+	g := &generator{pkg: opts.Package, typeName: opts.TypeName, used: map[string]bool{}, imports: map[string]bool{}}
+	if g.pkg == "" {
+		g.pkg = "main"
+	}
+	if g.typeName == "" {
+		g.typeName = "Root"
+	}
+	return g
+}
+
+// name assigns unique names to the structs found in t, breadth first so that
+// shallow types get the shortest names.
+func (g *generator) name(t *goType) {
+	g.used[g.typeName] = true
+	t.hint = g.typeName
+	if t.kind == kindStruct {
+		t.name = g.typeName
+	}
+	queue := []*goType{t}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		switch t.kind {
+		case kindSlice:
+			if t.elem != nil {
+				t.elem.hint = elementName(t.hint)
+				queue = append(queue, t.elem)
+			}
+		case kindStruct:
+			if t.name == "" {
+				t.name = uniqueName(t.hint, g.used)
+			}
+			g.structs = append(g.structs, t)
+			names := map[string]bool{}
+			for _, f := range t.fields {
+				f.name = uniqueName(identifier(f.key), names)
+				f.typ.hint = f.name
+				queue = append(queue, f.typ)
+			}
+		}
+	}
+}
+
+// uniqueName returns name, or name followed by the smallest number making it
+// absent from used, and marks the result as used.
+func uniqueName(name string, used map[string]bool) string {
+	result := name
+	for i := 2; used[result]; i++ {
+		result = name + strconv.Itoa(i)
+	}
+	used[result] = true
+	return result
+}
+
+// render returns the formatted source of the declarations.
+func (g *generator) render(root *goType) (string, error) {
+	return g.source(g.declarations(root))
+}
+
+// declarations returns the unformatted declarations of root and the structs
+// it holds.
+func (g *generator) declarations(root *goType) string {
+	var body strings.Builder
+	if root.kind != kindStruct {
+		fmt.Fprintf(&body, "type %s %s\n\n", g.typeName, g.typeExpr(root))
+	}
+	for _, s := range g.structs {
+		fmt.Fprintf(&body, "type %s struct {\n", s.name)
+		for _, f := range s.fields {
+			optional := f.count < s.objects
+			typ := g.typeExpr(f.typ)
+			if optional && f.typ.kind == kindStruct && !f.typ.nullable {
+				typ = "*" + typ // omitempty has no effect on struct values
+			}
+			tag := f.key
+			if optional {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&body, "%s %s `json:%s`\n", f.name, typ, strconv.Quote(tag))
+		}
+		body.WriteString("}\n\n")
+	}
+	return body.String()
+}
+
+// source returns the formatted file made of the package clause, the imports
+// and the declarations in body.
+func (g *generator) source(body string) (string, error) {
+	var src strings.Builder
+	fmt.Fprintf(&src, "package %s\n\n", g.pkg)
+	if g.imports["time"] {
+		src.WriteString("import \"time\"\n\n")
+	}
+	src.WriteString(body)
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return "", fmt.Errorf("codegen: formatting generated code: %v", err)
+	}
+	return string(formatted), nil
+}
+
+// typeExpr returns the Go expression of t.
+func (g *generator) typeExpr(t *goType) string {
+	var expr string
+	switch t.kind {
+	case kindBool:
+		expr = "bool"
+	case kindInt:
+		expr = "int64"
+	case kindFloat:
+		expr = "float64"
+	case kindString:
+		expr = "string"
+	case kindTime:
+		g.imports["time"] = true
+		expr = "time.Time"
+	case kindSlice:
+		if t.elem == nil || !t.elem.seen {
+			return "[]interface{}"
+		}
+		return "[]" + g.typeExpr(t.elem)
+	case kindStruct:
+		expr = t.name
+	default:
+		return "interface{}"
+	}
+	if t.nullable {
+		return "*" + expr
+	}
+	return expr
+}
+
+// initialisms are written in upper case in Go identifiers.
+var initialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true, "HTML": true, "HTTP": true,
+	"HTTPS": true, "ID": true, "IP": true, "JSON": true, "SQL": true, "TCP": true, "TLS": true, "TTL": true,
+	"UDP": true, "UI": true, "URI": true, "URL": true, "UTF8": true, "UUID": true, "XML": true,
+}
+
+// identifier converts a JSON key such as "user_id" or "createdAt" into an
+// exported Go identifier such as UserID or CreatedAt.
+func identifier(key string) string {
+	name := camelCase(key)
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "Field" + name
+	}
+	return name
+}
+
+// camelCase joins the words of text, capitalized, initialisms being written in
+// upper case.
+func camelCase(text string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	prev := rune(0)
+	for _, r := range text {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && prev != 0 && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+		prev = r
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			prev = 0
+		}
+	}
+	flush()
+
+	var result strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			result.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		result.WriteRune(unicode.ToUpper(r[0]))
+		result.WriteString(string(r[1:]))
+	}
+	return result.String()
+}
+
+// elementName returns the name suggested for the elements of a slice named
+// name: its singular, or name followed by Item.
+func elementName(name string) string {
+	if elem := singular(name); elem != name {
+		return elem
+	}
+	return name + "Item"
+}
+
+// singular returns the singular form of a plural type name such as Users or
+// Categories, or name itself.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
@@ -0,0 +1,358 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func generate(t *testing.T, opts Options, inputs ...string) string {
+	var samples []interface{}
+	for _, input := range inputs {
+		samples = append(samples, parse(t, input))
+	}
+	src, err := FromSamples(opts, samples...)
+	if err != nil {
+		t.Fatalf("FromSamples: unexpected error: %v", err)
+	}
+	return src
+}
+
+func TestFromSamples(t *testing.T) {
+	got := generate(t, Options{Package: "api", TypeName: "Repository"},
+		`{
+			"id": 1,
+			"full_name": "oabrivard/gojson",
+			"html_url": "https://github.com/oabrivard/gojson",
+			"stars": 12,
+			"score": 4,
+			"created_at": "2023-12-01T10:00:00Z",
+			"owner": {"login": "oabrivard", "id": 7},
+			"topics": ["json", "go"],
+			"contributors": [{"login": "a", "commits": 3}, {"login": "b", "commits": 1, "admin": true}],
+			"license": null,
+			"extra": {}
+		}`,
+		`{
+			"id": 2,
+			"full_name": "other/repo",
+			"html_url": "https://github.com/other/repo",
+			"score": 3.5,
+			"created_at": "2024-01-01T00:00:00.5+02:00",
+			"owner": {"login": "other", "id": 8},
+			"topics": [],
+			"contributors": [],
+			"license": {"key": "mit"},
+			"extra": {}
+		}`)
+
+	expected := "package api\n\nimport \"time\"\n\n" +
+		"type Repository struct {\n" +
+		"\tContributors []Contributor `json:\"contributors\"`\n" +
+		"\tCreatedAt    time.Time     `json:\"created_at\"`\n" +
+		"\tExtra        Extra         `json:\"extra\"`\n" +
+		"\tFullName     string        `json:\"full_name\"`\n" +
+		"\tHTMLURL      string        `json:\"html_url\"`\n" +
+		"\tID           int64         `json:\"id\"`\n" +
+		"\tLicense      *License      `json:\"license\"`\n" +
+		"\tOwner        Owner         `json:\"owner\"`\n" +
+		"\tScore        float64       `json:\"score\"`\n" +
+		"\tStars        int64         `json:\"stars,omitempty\"`\n" +
+		"\tTopics       []string      `json:\"topics\"`\n" +
+		"}\n\n" +
+		"type Extra struct {\n" +
+		"}\n\n" +
+		"type License struct {\n" +
+		"\tKey string `json:\"key\"`\n" +
+		"}\n\n" +
+		"type Owner struct {\n" +
+		"\tID    int64  `json:\"id\"`\n" +
+		"\tLogin string `json:\"login\"`\n" +
+		"}\n\n" +
+		"type Contributor struct {\n" +
+		"\tCommits int64  `json:\"commits\"`\n" +
+		"\tLogin   string `json:\"login\"`\n" +
+		"\tAdmin   bool   `json:\"admin,omitempty\"`\n" +
+		"}\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestFromSamplesTopLevelValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"text"`, "package main\n\ntype Root string\n"},
+		{`[1, 2.5, null]`, "package main\n\ntype Root []*float64\n"},
+		{`[1, "a"]`, "package main\n\ntype Root []interface{}\n"},
+		{`[]`, "package main\n\ntype Root []interface{}\n"},
+		{`[{"a": 1}]`, "package main\n\ntype Root []RootItem\n\ntype RootItem struct {\n\tA int64 `json:\"a\"`\n}\n"},
+	}
+
+	for _, tt := range tests {
+		if got := generate(t, Options{}, tt.input); got != tt.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFromSamplesNaming(t *testing.T) {
+	got := generate(t, Options{TypeName: "Config"}, `{
+		"2fa": true,
+		"user-id": "u1",
+		"userId": "u2",
+		"categories": [{"name": "a", "config": {"debug": true}}],
+		"config": {"url": "x"}
+	}`)
+
+	expected := "package main\n\n" +
+		"type Config struct {\n" +
+		"\tField2fa   bool       `json:\"2fa\"`\n" +
+		"\tCategories []Category `json:\"categories\"`\n" +
+		"\tConfig     Config2    `json:\"config\"`\n" +
+		"\tUserID     string     `json:\"user-id\"`\n" +
+		"\tUserID2    string     `json:\"userId\"`\n" +
+		"}\n\n" +
+		"type Config2 struct {\n" +
+		"\tURL string `json:\"url\"`\n" +
+		"}\n\n" +
+		"type Category struct {\n" +
+		"\tConfig Config3 `json:\"config\"`\n" +
+		"\tName   string  `json:\"name\"`\n" +
+		"}\n\n" +
+		"type Config3 struct {\n" +
+		"\tDebug bool `json:\"debug\"`\n" +
+		"}\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"name":         "Name",
+		"first_name":   "FirstName",
+		"createdAt":    "CreatedAt",
+		"api_key":      "APIKey",
+		"HTTPServer":   "HTTPServer",
+		"item-count2":  "ItemCount2",
+		"":             "Field",
+		"123":          "Field123",
+		"élève":        "Élève",
+		"image_url_v2": "ImageURLV2",
+	}
+
+	for key, expected := range tests {
+		if got := identifier(key); got != expected {
+			t.Errorf("identifier(%q): expected %s, got %s", key, expected, got)
+		}
+	}
+}
+
+const petSchema = `{
+	"$defs": {
+		"tag": {
+			"type": "object",
+			"properties": {
+				"id": {"type": "integer"},
+				"label": {"type": "string"}
+			},
+			"required": ["id"]
+		}
+	},
+	"description": "Pet is an animal of the store.",
+	"type": "object",
+	"properties": {
+		"id": {"type": "integer", "description": "Unique identifier."},
+		"name": {"type": "string"},
+		"status": {"enum": ["available", "pending", "sold-out"]},
+		"size": {"enum": [1, 2, 3]},
+		"weight": {"type": ["number", "null"]},
+		"born_at": {"type": "string", "format": "date-time"},
+		"owner": {
+			"type": "object",
+			"properties": {"name": {"type": "string"}},
+			"required": ["name"]
+		},
+		"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}},
+		"main_tag": {"$ref": "#/$defs/tag"},
+		"attributes": {"type": "object", "additionalProperties": {"type": "string"}},
+		"children": {"type": "array", "items": {"$ref": "#"}},
+		"extra": {}
+	},
+	"required": ["id", "name", "status", "weight", "tags"]
+}`
+
+func TestFromSchema(t *testing.T) {
+	src, err := FromSchema(Options{Package: "store", TypeName: "Pet"}, parse(t, petSchema))
+	if err != nil {
+		t.Fatalf("FromSchema: unexpected error: %v", err)
+	}
+
+	expected := "package store\n\nimport \"time\"\n\n" +
+		"// Pet is an animal of the store.\n" +
+		"type Pet struct {\n" +
+		"\tAttributes map[string]string `json:\"attributes,omitempty\"`\n" +
+		"\tBornAt     *time.Time        `json:\"born_at,omitempty\"`\n" +
+		"\tChildren   []Pet             `json:\"children,omitempty\"`\n" +
+		"\tExtra      interface{}       `json:\"extra,omitempty\"`\n" +
+		"\t// Unique identifier.\n" +
+		"\tID      int64    `json:\"id\"`\n" +
+		"\tMainTag *Tag     `json:\"main_tag,omitempty\"`\n" +
+		"\tName    string   `json:\"name\"`\n" +
+		"\tOwner   *Owner   `json:\"owner,omitempty\"`\n" +
+		"\tSize    *Size    `json:\"size,omitempty\"`\n" +
+		"\tStatus  Status   `json:\"status\"`\n" +
+		"\tTags    []Tag    `json:\"tags\"`\n" +
+		"\tWeight  *float64 `json:\"weight\"`\n" +
+		"}\n\n" +
+		"type Tag struct {\n" +
+		"\tID    int64   `json:\"id\"`\n" +
+		"\tLabel *string `json:\"label,omitempty\"`\n" +
+		"}\n\n" +
+		"type Owner struct {\n" +
+		"\tName string `json:\"name\"`\n" +
+		"}\n\n" +
+		"type Size int64\n\n" +
+		"const (\n" +
+		"\tSize1 Size = 1\n" +
+		"\tSize2 Size = 2\n" +
+		"\tSize3 Size = 3\n" +
+		")\n\n" +
+		"type Status string\n\n" +
+		"const (\n" +
+		"\tStatusAvailable Status = \"available\"\n" +
+		"\tStatusPending   Status = \"pending\"\n" +
+		"\tStatusSoldOut   Status = \"sold-out\"\n" +
+		")\n"
+	if src != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, src)
+	}
+}
+
+func TestFromSchemaTopLevelValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"type": "string"}`, "package main\n\ntype Root string\n"},
+		{`{"type": "array", "items": {"type": "integer"}}`, "package main\n\ntype Root []int64\n"},
+		{`{"type": "array", "items": {"properties": {"a": {"type": "boolean"}}, "required": ["a"]}}`,
+			"package main\n\ntype Root []RootItem\n\ntype RootItem struct {\n\tA bool `json:\"a\"`\n}\n"},
+		{`{"enum": ["a", "b"]}`, "package main\n\ntype Root string\n\nconst (\n\tRootA Root = \"a\"\n\tRootB Root = \"b\"\n)\n"},
+		{`true`, "package main\n\ntype Root interface{}\n"},
+	}
+
+	for _, tt := range tests {
+		src, err := FromSchema(Options{}, parse(t, tt.input))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.input, err)
+		}
+		if src != tt.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tt.input, tt.expected, src)
+		}
+	}
+}
+
+func TestFromSchemaErrors(t *testing.T) {
+	tests := []string{
+		`"object"`,
+		`{"enum": []}`,
+		`{"enum": ["a", 1]}`,
+		`{"properties": {"a": {"$ref": "#/$defs/missing"}}}`,
+		`{"properties": {"a": {"$ref": "other.json"}}}`,
+		`{"properties": {"a": false}}`,
+		`{"type": 1}`,
+	}
+
+	for _, input := range tests {
+		if _, err := FromSchema(Options{}, parse(t, input)); err == nil {
+			t.Errorf("%s: expected an error", input)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	got, err := Literal(parse(t, `{"id": 7, "name": "café \"x\"", "tags": ["a", "b"], "score": 2.5, "owner": {"login": "x"}, "none": null, "empty": []}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "map[string]interface{}{\n" +
+		"\t\"empty\": []interface{}{},\n" +
+		"\t\"id\":    int64(7),\n" +
+		"\t\"name\":  \"café \\\"x\\\"\",\n" +
+		"\t\"none\":  nil,\n" +
+		"\t\"owner\": map[string]interface{}{\n" +
+		"\t\t\"login\": \"x\",\n" +
+		"\t},\n" +
+		"\t\"score\": float64(2.5),\n" +
+		"\t\"tags\":  []interface{}{\"a\", \"b\"},\n" +
+		"}"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestValueLiteral(t *testing.T) {
+	got, err := ValueLiteral(Options{Package: "api", VarName: "sample"}, parse(t, `{
+		"id": 7,
+		"at": "2024-01-01T00:00:00.5+02:00",
+		"items": [{"n": 1, "opt": {"a": true}}, {"n": null}],
+		"mixed": [1, "a"]
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "package api\n\nimport \"time\"\n\n" +
+		"type Root struct {\n" +
+		"\tAt    time.Time     `json:\"at\"`\n" +
+		"\tID    int64         `json:\"id\"`\n" +
+		"\tItems []Item        `json:\"items\"`\n" +
+		"\tMixed []interface{} `json:\"mixed\"`\n" +
+		"}\n\n" +
+		"type Item struct {\n" +
+		"\tN   *int64 `json:\"n\"`\n" +
+		"\tOpt *Opt   `json:\"opt,omitempty\"`\n" +
+		"}\n\n" +
+		"type Opt struct {\n" +
+		"\tA bool `json:\"a\"`\n" +
+		"}\n\n" +
+		"var sample = Root{\n" +
+		"\tAt: time.Date(2024, time.January, 1, 0, 0, 0, 500000000, time.FixedZone(\"\", 7200)),\n" +
+		"\tID: 7,\n" +
+		"\tItems: []Item{\n" +
+		"\t\t{\n" +
+		"\t\t\tN: ptr(int64(1)),\n" +
+		"\t\t\tOpt: &Opt{\n" +
+		"\t\t\t\tA: true,\n" +
+		"\t\t\t},\n" +
+		"\t\t},\n" +
+		"\t\t{},\n" +
+		"\t},\n" +
+		"\tMixed: []interface{}{int64(1), \"a\"},\n" +
+		"}\n\n" +
+		"func ptr[T any](v T) *T { return &v }\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	got, err = ValueLiteral(Options{}, parse(t, `[1, 2.5, null]`))
+	expected = "package main\n\ntype Root []*float64\n\n" +
+		"var value Root = []*float64{ptr(float64(1)), ptr(float64(2.5)), nil}\n\n" +
+		"func ptr[T any](v T) *T { return &v }\n"
+	if err != nil || got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s (%v)", expected, got, err)
+	}
+}
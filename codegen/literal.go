@@ -0,0 +1,186 @@
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Literal returns the Go expression of a parsed document, built from
+// map[string]interface{}, []interface{} and scalars, so that a real payload
+// can be pasted into a test. Integral numbers are int64 and others float64,
+// as the parser produces them, and strings hold their characters rather than
+// their escape sequences.
+func Literal(v interface{}) (string, error) {
+	return formatExpr(anyExpr(v))
+}
+
+// ValueLiteral returns the Go source of the types matching the document v,
+// as FromSamples generates them, followed by a variable named after
+// opts.VarName holding v as a value of the top-level type. A generic ptr
+// helper is declared when fields that are sometimes null hold a value.
+func ValueLiteral(opts Options, v interface{}) (string, error) {
+	root := &goType{}
+	root.merge(v)
+
+	g := newGenerator(opts)
+	g.name(root)
+
+	name := opts.VarName
+	if name == "" {
+		name = "value"
+	}
+	var body strings.Builder
+	body.WriteString(g.declarations(root))
+	if root.kind == kindStruct {
+		fmt.Fprintf(&body, "var %s = %s\n", name, g.valueExpr(root, v, false))
+	} else {
+		fmt.Fprintf(&body, "var %s %s = %s\n", name, g.typeName, g.valueExpr(root, v, false))
+	}
+	if g.usesPtr {
+		body.WriteString("\nfunc ptr[T any](v T) *T { return &v }\n")
+	}
+	return g.source(body.String())
+}
+
+// formatExpr returns the expression expr formatted by gofmt.
+func formatExpr(expr string) (string, error) {
+	const prefix = "package p\n\nvar _ = "
+	formatted, err := format.Source([]byte(prefix + expr + "\n"))
+	if err != nil {
+		return "", fmt.Errorf("codegen: formatting generated code: %v", err)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(formatted), prefix), "\n"), nil
+}
+
+// anyExpr returns the expression of v as a value of an interface{}.
+func anyExpr(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(x)
+	case int64:
+		return "int64(" + strconv.FormatInt(x, 10) + ")"
+	case float64:
+		return "float64(" + strconv.FormatFloat(x, 'g', -1, 64) + ")"
+	case string:
+		return strconv.Quote(x)
+	case parser.JsonArray:
+		elems := make([]string, len(x))
+		for i, e := range x {
+			elems[i] = anyExpr(e)
+		}
+		return composite("[]interface{}", elems, isScalars(x))
+	case parser.JsonObject, *parser.OrderedObject:
+		keys, members, _ := parser.Members(x)
+		elems := make([]string, len(keys))
+		for i, k := range keys {
+			elems[i] = strconv.Quote(k) + ": " + anyExpr(members[k])
+		}
+		return composite("map[string]interface{}", elems, false)
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// valueExpr returns the expression of v as a value of the type t, or of a
+// pointer to it if pointer is set.
+func (g *generator) valueExpr(t *goType, v interface{}, pointer bool) string {
+	if v == nil {
+		return "nil"
+	}
+
+	var expr string
+	switch t.kind {
+	case kindBool, kindInt, kindFloat:
+		expr = fmt.Sprint(v)
+	case kindString:
+		expr = strconv.Quote(v.(string))
+	case kindTime:
+		g.imports["time"] = true
+		expr = timeExpr(v.(string))
+	case kindSlice:
+		arr := v.(parser.JsonArray)
+		if t.elem == nil || !t.elem.seen {
+			return anyExpr(arr)
+		}
+		elems := make([]string, len(arr))
+		for i, e := range arr {
+			elems[i] = g.valueExpr(t.elem, e, t.elem.nullable)
+			if t.elem.kind == kindStruct {
+				// The type of elements goes without saying, as gofmt -s has it.
+				elems[i] = strings.TrimPrefix(strings.TrimPrefix(elems[i], "&"), t.elem.name)
+			}
+		}
+		return composite(g.typeExpr(t), elems, isScalars(arr))
+	case kindStruct:
+		_, members, _ := parser.Members(v)
+		var elems []string
+		for _, f := range t.fields {
+			e := members[f.key]
+			if e == nil {
+				continue // missing and null members are left to the zero value
+			}
+			optional := f.count < t.objects
+			elems = append(elems, f.name+": "+g.valueExpr(f.typ, e, f.typ.nullable || optional && f.typ.kind == kindStruct))
+		}
+		expr = composite(t.name, elems, false)
+		if pointer || t.nullable {
+			return "&" + expr
+		}
+		return expr
+	default:
+		return anyExpr(v)
+	}
+
+	if pointer || t.nullable {
+		switch t.kind {
+		case kindInt:
+			expr = "int64(" + expr + ")" // ptr(1) would be a *int
+		case kindFloat:
+			expr = "float64(" + expr + ")"
+		}
+		g.usesPtr = true
+		return "ptr(" + expr + ")"
+	}
+	return expr
+}
+
+// composite returns the composite literal of type typ holding elems, on one
+// line if inline is set and each on its own line otherwise.
+func composite(typ string, elems []string, inline bool) string {
+	if len(elems) == 0 {
+		return typ + "{}"
+	}
+	if inline {
+		return typ + "{" + strings.Join(elems, ", ") + "}"
+	}
+	return typ + "{\n" + strings.Join(elems, ",\n") + ",\n}"
+}
+
+// isScalars reports whether the elements of arr are all scalars, which are
+// written on one line.
+func isScalars(arr parser.JsonArray) bool {
+	for _, e := range arr {
+		switch e.(type) {
+		case parser.JsonArray, parser.JsonObject, *parser.OrderedObject:
+			return false
+		}
+	}
+	return true
+}
+
+// timeExpr returns the time.Date call building the RFC 3339 timestamp raw.
+func timeExpr(raw string) string {
+	t, _ := time.Parse(time.RFC3339Nano, raw)
+	location := "time.UTC"
+	if _, offset := t.Zone(); offset != 0 {
+		location = fmt.Sprintf("time.FixedZone(\"\", %d)", offset)
+	}
+	return fmt.Sprintf("time.Date(%d, time.%s, %d, %d, %d, %d, %d, %s)",
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), location)
+}
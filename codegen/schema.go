@@ -0,0 +1,303 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// schemaGenerator generates the declarations of the types described by a JSON
+// Schema document.
+type schemaGenerator struct {
+	*generator
+	root  interface{}
+	refs  map[string]string // names of the types declared for each $ref
+	decls []string          // declarations, in the order the types were found
+}
+
+// FromSchema returns the Go source of the types described by a parsed JSON
+// Schema document. Objects with properties become structs, whose optional
+// properties, those not listed in required, are pointers tagged omitempty.
+// Enums become named types with one typed constant per value, $ref targets
+// become named types shared by every reference, and strings with the
+// date-time format become time.Time. The description of a schema or property
+// is kept as a doc comment.
+func FromSchema(opts Options, schema interface{}) (string, error) {
+	g := &schemaGenerator{generator: newGenerator(opts), root: schema, refs: map[string]string{}}
+	g.used[g.typeName] = true
+	if err := g.define(g.typeName, schema); err != nil {
+		return "", err
+	}
+	return g.source(strings.Join(g.decls, "\n"))
+}
+
+// define declares the type name, already reserved, for the schema s.
+func (g *schemaGenerator) define(name string, s interface{}) error {
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var decl strings.Builder
+	_, members, _ := parser.Members(s)
+	if description, ok := members["description"].(string); ok {
+		writeComment(&decl, "", description)
+	}
+
+	switch {
+	case members["enum"] != nil:
+		if err := g.defineEnum(&decl, name, members["enum"]); err != nil {
+			return err
+		}
+	case g.isStruct(members):
+		if err := g.defineStruct(&decl, name, members); err != nil {
+			return err
+		}
+	default:
+		expr, err := g.typeFor(s, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&decl, "type %s %s\n", name, expr)
+	}
+
+	g.decls[slot] = decl.String()
+	return nil
+}
+
+// isStruct reports whether a schema describes an object with properties.
+func (g *schemaGenerator) isStruct(members map[string]interface{}) bool {
+	if _, ok := members["$ref"]; ok {
+		return false
+	}
+	_, hasProperties := members["properties"]
+	return hasProperties && (members["type"] == nil || members["type"] == "object")
+}
+
+// defineEnum writes a named type and one constant per value of an enum. The
+// values must all be strings or all be numbers.
+func (g *schemaGenerator) defineEnum(decl *strings.Builder, name string, enum interface{}) error {
+	values, ok := enum.(parser.JsonArray)
+	if !ok || len(values) == 0 {
+		return fmt.Errorf("codegen: %s: enum must be a non-empty array", name)
+	}
+
+	var base string
+	for _, v := range values {
+		var t string
+		switch v.(type) {
+		case string:
+			t = "string"
+		case int64:
+			t = "int64"
+		case float64:
+			t = "float64"
+		default:
+			return fmt.Errorf("codegen: %s: unsupported enum value %v", name, v)
+		}
+		if base == "int64" && t == "float64" || base == "float64" && t == "int64" {
+			t = "float64"
+		} else if base != "" && base != t {
+			return fmt.Errorf("codegen: %s: enum values must all be strings or all be numbers", name)
+		}
+		base = t
+	}
+
+	fmt.Fprintf(decl, "type %s %s\n\nconst (\n", name, base)
+	for _, v := range values {
+		text := fmt.Sprint(v)
+		literal := text
+		if _, ok := v.(string); ok {
+			literal = strconv.Quote(text)
+		}
+		constant := uniqueName(name+camelCase(text), g.used)
+		fmt.Fprintf(decl, "%s %s = %s\n", constant, name, literal)
+	}
+	decl.WriteString(")\n")
+	return nil
+}
+
+// defineStruct writes a struct with one field per property.
+func (g *schemaGenerator) defineStruct(decl *strings.Builder, name string, members map[string]interface{}) error {
+	keys, properties, _ := parser.Members(members["properties"])
+	if properties == nil {
+		return fmt.Errorf("codegen: %s: properties must be an object", name)
+	}
+	required := map[string]bool{}
+	if list, ok := members["required"].(parser.JsonArray); ok {
+		for _, r := range list {
+			if k, ok := r.(string); ok {
+				required[k] = true
+			}
+		}
+	}
+
+	fmt.Fprintf(decl, "type %s struct {\n", name)
+	names := map[string]bool{}
+	for _, k := range keys {
+		field := uniqueName(identifier(k), names)
+		expr, err := g.typeFor(properties[k], field)
+		if err != nil {
+			return err
+		}
+		tag := k
+		if !required[k] {
+			tag += ",omitempty"
+			if !strings.HasPrefix(expr, "*") && !strings.HasPrefix(expr, "[]") && !strings.HasPrefix(expr, "map[") && expr != "interface{}" {
+				expr = "*" + expr
+			}
+		}
+		_, property, _ := parser.Members(properties[k])
+		if description, ok := property["description"].(string); ok {
+			writeComment(decl, "\t", description)
+		}
+		fmt.Fprintf(decl, "%s %s `json:%s`\n", field, expr, strconv.Quote(tag))
+	}
+	decl.WriteString("}\n")
+	return nil
+}
+
+// typeFor returns the Go expression of the type described by the schema s,
+// declaring the named types it needs. hint names a struct or enum found in s.
+func (g *schemaGenerator) typeFor(s interface{}, hint string) (string, error) {
+	if b, ok := s.(bool); ok {
+		if !b {
+			return "", fmt.Errorf("codegen: %s: the false schema has no Go type", hint)
+		}
+		return "interface{}", nil
+	}
+	_, members, _ := parser.Members(s)
+	if members == nil {
+		return "", fmt.Errorf("codegen: %s: a schema must be an object or a boolean", hint)
+	}
+
+	if ref, ok := members["$ref"]; ok {
+		return g.reference(ref, hint)
+	}
+
+	types, nullable, err := schemaTypes(members, hint)
+	if err != nil {
+		return "", err
+	}
+
+	var expr string
+	switch {
+	case members["enum"] != nil || g.isStruct(members):
+		expr = uniqueName(hint, g.used)
+		if err := g.define(expr, s); err != nil {
+			return "", err
+		}
+	case len(types) != 1:
+		return "interface{}", nil
+	case types[0] == "array":
+		elem := "interface{}"
+		if items, ok := members["items"]; ok {
+			if elem, err = g.typeFor(items, elementName(hint)); err != nil {
+				return "", err
+			}
+		}
+		return "[]" + elem, nil
+	case types[0] == "object":
+		value := "interface{}"
+		if additional, ok := members["additionalProperties"]; ok && additional != true && additional != false {
+			if value, err = g.typeFor(additional, hint+"Value"); err != nil {
+				return "", err
+			}
+		}
+		return "map[string]" + value, nil
+	case types[0] == "string" && members["format"] == "date-time":
+		g.imports["time"] = true
+		expr = "time.Time"
+	case types[0] == "string":
+		expr = "string"
+	case types[0] == "integer":
+		expr = "int64"
+	case types[0] == "number":
+		expr = "float64"
+	case types[0] == "boolean":
+		expr = "bool"
+	default:
+		return "interface{}", nil
+	}
+
+	if nullable {
+		return "*" + expr, nil
+	}
+	return expr, nil
+}
+
+// schemaTypes returns the non-null types allowed by a schema and whether null
+// is allowed too. A schema without a type keyword is an object when it has
+// properties and an array when it has items.
+func schemaTypes(members map[string]interface{}, hint string) ([]string, bool, error) {
+	var types []string
+	switch t := members["type"].(type) {
+	case nil:
+		if _, ok := members["properties"]; ok {
+			types = []string{"object"}
+		} else if _, ok := members["items"]; ok {
+			types = []string{"array"}
+		}
+	case string:
+		types = []string{t}
+	case parser.JsonArray:
+		for _, e := range t {
+			name, ok := e.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("codegen: %s: type must be a string or an array of strings", hint)
+			}
+			types = append(types, name)
+		}
+	default:
+		return nil, false, fmt.Errorf("codegen: %s: type must be a string or an array of strings", hint)
+	}
+
+	nonNull := types[:0:0]
+	for _, t := range types {
+		if t != "null" {
+			nonNull = append(nonNull, t)
+		}
+	}
+	return nonNull, len(nonNull) != len(types), nil
+}
+
+// reference returns the name of the type declared for the target of a $ref
+// inside the schema document, declaring it on first use. The type is named
+// after the last token of the reference, such as Address for
+// "#/$defs/address", the reference "#" designating the top-level type.
+func (g *schemaGenerator) reference(v interface{}, hint string) (string, error) {
+	ref, ok := v.(string)
+	if !ok || !strings.HasPrefix(ref, "#") {
+		return "", fmt.Errorf("codegen: %s: unsupported reference %v, only references inside the document are supported", hint, v)
+	}
+	if ref == "#" {
+		return g.typeName, nil
+	}
+	if name, ok := g.refs[ref]; ok {
+		return name, nil
+	}
+
+	p, err := pointer.Parse(ref[1:])
+	if err != nil {
+		return "", fmt.Errorf("codegen: %s: invalid reference %q: %v", hint, ref, err)
+	}
+	target, err := p.Get(g.root)
+	if err != nil {
+		return "", fmt.Errorf("codegen: %s: unresolved reference %q", hint, ref)
+	}
+
+	name := uniqueName(identifier(p[len(p)-1]), g.used)
+	g.refs[ref] = name
+	if err := g.define(name, target); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// writeComment writes text as a doc comment, each line starting with indent.
+func writeComment(w *strings.Builder, indent, text string) {
+	for _, line := range strings.Split(strings.ReplaceAll(text, `\n`, "\n"), "\n") {
+		w.WriteString(strings.TrimRight(indent+"// "+line, " ") + "\n")
+	}
+}
@@ -0,0 +1,185 @@
+// Package config loads configuration files written in JSON, or JSON with
+// comments and trailing commas, into Go values:
+//
+//	type Config struct {
+//		Port  int    `json:"port"`
+//		Level string `json:"level"`
+//	}
+//	cfg, err := config.Load[Config]("config.json", config.Options{
+//		Overlays: []string{"config.production.json"},
+//	})
+//
+// Objects can include other files with a "$include" member holding a path,
+// or an array of paths, relative to the including file:
+//
+//	{"$include": "defaults.json", "port": "${PORT:-8080}"}
+//
+// The members of the included files are merged in order, then those of the
+// including object over them. Overlays are merged over the loaded file the
+// same way, following RFC 7386: objects are merged member by member, null
+// removes a member and other values replace the previous ones. Environment
+// variables are finally expanded in strings as the interpolate package does,
+// a string made of a single ${NAME} or ${NAME:-default} placeholder holding
+// the JSON value of the variable, such as the number 8080.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/interpolate"
+	"github.com/oabrivard/gojson/jsonc"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// IncludeKey is the key of the members listing the files an object includes.
+const IncludeKey = "$include"
+
+// Options controls how Load reads a configuration.
+type Options struct {
+	// Overlays are files merged over the loaded one, in order.
+	Overlays []string
+	// Env looks environment variables up, os.LookupEnv when nil.
+	Env func(name string) (string, bool)
+}
+
+// Load reads the configuration file path, with its includes and overlays,
+// expands its environment variables and converts the result to T with
+// gojson.As, members without a struct field being ignored.
+func Load[T any](path string, opts Options) (T, error) {
+	v, err := LoadValue(path, opts)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return gojson.As[T](v)
+}
+
+// LoadValue reads the configuration file path like Load and returns it as a
+// parsed value, objects being *parser.OrderedObject values.
+func LoadValue(path string, opts Options) (interface{}, error) {
+	v, err := loadFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, overlay := range opts.Overlays {
+		o, err := loadFile(overlay, nil)
+		if err != nil {
+			return nil, err
+		}
+		v = merge(v, o)
+	}
+	return interpolate.Interpolate(v, interpolate.Options{Env: opts.Env, WholeValues: true})
+}
+
+// loadFile reads the file path and resolves its includes. including holds
+// the absolute paths of the files including it, to detect cycles.
+func loadFile(path string, including []string) (interface{}, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	if slices.Contains(including, abs) {
+		return nil, fmt.Errorf("config: %s includes itself", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	doc, err := jsonc.Parse(data)
+	if err != nil {
+		var syntax *jsonc.SyntaxError
+		if errors.As(err, &syntax) {
+			return nil, fmt.Errorf("config: %s: %s at line %d, column %d", path, syntax.Msg, syntax.Line, syntax.Column)
+		}
+		return nil, fmt.Errorf("config: %s: %v", path, err)
+	}
+	return resolveIncludes(doc.Root.Value(), path, append(including, abs))
+}
+
+// resolveIncludes returns v, read from the file path, with the includes of
+// its objects merged into them.
+func resolveIncludes(v interface{}, path string, including []string) (interface{}, error) {
+	switch x := v.(type) {
+	case parser.JsonArray:
+		for i, e := range x {
+			var err error
+			if x[i], err = resolveIncludes(e, path, including); err != nil {
+				return nil, err
+			}
+		}
+		return x, nil
+	case *parser.OrderedObject:
+		var files []string
+		if include, ok := x.Get(IncludeKey); ok {
+			switch inc := include.(type) {
+			case string:
+				files = []string{inc}
+			case parser.JsonArray:
+				for _, e := range inc {
+					s, ok := e.(string)
+					if !ok {
+						return nil, fmt.Errorf("config: %s: %s must hold a path or an array of paths", path, IncludeKey)
+					}
+					files = append(files, s)
+				}
+			default:
+				return nil, fmt.Errorf("config: %s: %s must hold a path or an array of paths", path, IncludeKey)
+			}
+			x.Delete(IncludeKey)
+		}
+
+		for _, k := range x.Keys {
+			e, err := resolveIncludes(x.Values[k], path, including)
+			if err != nil {
+				return nil, err
+			}
+			x.Values[k] = e
+		}
+		if files == nil {
+			return x, nil
+		}
+
+		var result interface{} = parser.NewOrderedObject()
+		for _, file := range files {
+			file, _ = gojson.As[string](file)
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(filepath.Dir(path), file)
+			}
+			included, err := loadFile(file, including)
+			if err != nil {
+				return nil, err
+			}
+			result = merge(result, included)
+		}
+		return merge(result, x), nil
+	}
+	return v, nil
+}
+
+// merge returns the result of merging overlay over base following RFC 7386.
+// Objects of base are modified in place.
+func merge(base, overlay interface{}) interface{} {
+	o, ok := overlay.(*parser.OrderedObject)
+	if !ok {
+		return overlay
+	}
+	b, ok := base.(*parser.OrderedObject)
+	if !ok {
+		b = parser.NewOrderedObject()
+	}
+	for _, k := range o.Keys {
+		v := o.Values[k]
+		if v == nil {
+			b.Delete(k)
+			continue
+		}
+		previous, _ := b.Get(k)
+		b.Set(k, merge(previous, v))
+	}
+	return b
+}
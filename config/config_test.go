@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/linter"
+)
+
+// writeFiles writes files, mapping names to contents, into a temporary
+// directory and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// env returns an environment lookup function serving vars.
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+type serverConfig struct {
+	Host    string            `json:"host"`
+	Port    int               `json:"port"`
+	Debug   bool              `json:"debug"`
+	Tags    []string          `json:"tags"`
+	Limits  map[string]int    `json:"limits"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Timeout *float64          `json:"timeout"`
+}
+
+type appConfig struct {
+	Name   string       `json:"name"`
+	Server serverConfig `json:"server"`
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"config.json": `{
+			// the service
+			"$include": "common/defaults.json",
+			"name": "api",
+			"server": {
+				"port": "${PORT:-8080}",
+				"tags": ["${REGION}", "web"],
+			},
+		}`,
+		"common/defaults.json": `{"server": {"$include": "server.json", "host": "localhost", "debug": true}}`,
+		"common/server.json":   `{"limits": {"connections": 100, "requests": 10}, "timeout": 1.5}`,
+		"production.json":      `{"server": {"debug": false, "limits": {"requests": null, "connections": 1000}, "timeout": null}}`,
+	})
+
+	cfg, err := Load[appConfig](filepath.Join(dir, "config.json"), Options{Env: env(map[string]string{"REGION": "eu"})})
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	timeout := 1.5
+	expected := appConfig{Name: "api", Server: serverConfig{
+		Host: "localhost", Port: 8080, Debug: true, Tags: []string{"eu", "web"},
+		Limits: map[string]int{"connections": 100, "requests": 10}, Timeout: &timeout,
+	}}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load:\nexpected %+v\ngot      %+v", expected, cfg)
+	}
+
+	opts := Options{
+		Overlays: []string{filepath.Join(dir, "production.json")},
+		Env:      env(map[string]string{"REGION": "us", "PORT": "9000"}),
+	}
+	cfg, err = Load[appConfig](filepath.Join(dir, "config.json"), opts)
+	if err != nil {
+		t.Fatalf("Load(overlay): unexpected error: %v", err)
+	}
+	expected = appConfig{Name: "api", Server: serverConfig{
+		Host: "localhost", Port: 9000, Tags: []string{"us", "web"}, Limits: map[string]int{"connections": 1000},
+	}}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Load(overlay):\nexpected %+v\ngot      %+v", expected, cfg)
+	}
+
+	v, err := LoadValue(filepath.Join(dir, "config.json"), opts)
+	if err != nil {
+		t.Fatalf("LoadValue: unexpected error: %v", err)
+	}
+	got := linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+	want := `{"server": {"limits": {"connections": 1000}, "host": "localhost", "debug": false, "port": 9000, "tags": ["us", "web"]}, "name": "api"}`
+	if got != want {
+		t.Errorf("LoadValue:\nexpected %s\ngot      %s", want, got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"syntax.json":  "{\n  \"a\": 1,\n  \"b\" 2\n}",
+		"cycle.json":   `{"$include": ["other.json"]}`,
+		"other.json":   `{"nested": {"$include": "cycle.json"}}`,
+		"include.json": `{"$include": 1}`,
+		"missing.json": `{"$include": "nowhere.json"}`,
+		"env.json":     `{"port": "${PORT}"}`,
+		"type.json":    `{"port": "http"}`,
+	})
+
+	tests := []struct {
+		file, expected string
+	}{
+		{"syntax.json", "config: " + filepath.Join(dir, "syntax.json") + ": expected ':', got '2' at line 3, column 7"},
+		{"cycle.json", "config: " + filepath.Join(dir, "cycle.json") + " includes itself"},
+		{"include.json", "config: " + filepath.Join(dir, "include.json") + ": $include must hold a path or an array of paths"},
+		{"missing.json", "config: open " + filepath.Join(dir, "nowhere.json") + ": no such file or directory"},
+		{"env.json", "interpolate: /port: undefined variable PORT"},
+		{"type.json", "gojson: cannot convert string at /port to int"},
+	}
+	for _, tt := range tests {
+		_, err := Load[struct{ Port int }](filepath.Join(dir, tt.file), Options{Env: env(nil)})
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("Load(%s): expected error %q, got %v", tt.file, tt.expected, err)
+		}
+	}
+
+	_, err := Load[struct{}](filepath.Join(dir, "env.json"), Options{Overlays: []string{filepath.Join(dir, "none.json")}})
+	if err == nil || !strings.Contains(err.Error(), "none.json") {
+		t.Errorf("Load: expected an error about the missing overlay, got %v", err)
+	}
+}
@@ -0,0 +1,695 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// BSON element types.
+const (
+	bsonDouble        = 0x01
+	bsonString        = 0x02
+	bsonDocument      = 0x03
+	bsonArray         = 0x04
+	bsonBinary        = 0x05
+	bsonUndefined     = 0x06
+	bsonObjectID      = 0x07
+	bsonBoolean       = 0x08
+	bsonDateTime      = 0x09
+	bsonNull          = 0x0a
+	bsonRegex         = 0x0b
+	bsonDBPointer     = 0x0c
+	bsonCode          = 0x0d
+	bsonSymbol        = 0x0e
+	bsonCodeWithScope = 0x0f
+	bsonInt32         = 0x10
+	bsonTimestamp     = 0x11
+	bsonInt64         = 0x12
+	bsonDecimal128    = 0x13
+	bsonMinKey        = 0xff
+	bsonMaxKey        = 0x7f
+)
+
+// FromBSON converts a single BSON document into a *parser.OrderedObject
+// keeping its element order. Values without a JSON equivalent are written in
+// the relaxed form of MongoDB Extended JSON v2: ObjectIds become {"$oid": hex},
+// dates {"$date": RFC 3339 text}, or {"$date": {"$numberLong": text}} for
+// those before 1970 or after 9999, binary data {"$binary": {"base64": text,
+// "subType": hex}}, and so on. Infinities and NaN become {"$numberDouble":
+// text}.
+func FromBSON(data []byte) (interface{}, error) {
+	docs, err := FromBSONDocuments(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) != 1 {
+		return nil, fmt.Errorf("convert: expected a single BSON document, got %d", len(docs))
+	}
+	return docs[0], nil
+}
+
+// FromBSONDocuments converts a sequence of BSON documents, like the .bson
+// files written by mongodump, into an array of objects converted as FromBSON
+// does.
+func FromBSONDocuments(data []byte) (parser.JsonArray, error) {
+	d := &bsonDecoder{data: data}
+	docs := parser.JsonArray{}
+	for d.pos < len(d.data) {
+		doc, err := d.document(0, false)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// bsonDecoder reads BSON documents from data.
+type bsonDecoder struct {
+	data []byte
+	pos  int
+}
+
+// read returns the next n bytes.
+func (d *bsonDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, fmt.Errorf("convert: unexpected end of BSON data at offset %d", len(d.data))
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// int32 reads a little-endian 32 bit integer.
+func (d *bsonDecoder) int32() (int32, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.LittleEndian.Uint32(b)), nil
+}
+
+// uint64 reads a little-endian 64 bit integer.
+func (d *bsonDecoder) uint64() (uint64, error) {
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// cstring reads a NUL-terminated string.
+func (d *bsonDecoder) cstring() (string, error) {
+	end := bytes.IndexByte(d.data[d.pos:], 0)
+	if end < 0 {
+		return "", fmt.Errorf("convert: unterminated BSON string at offset %d", d.pos)
+	}
+	s := d.data[d.pos : d.pos+end]
+	if !utf8.Valid(s) {
+		return "", fmt.Errorf("convert: invalid UTF-8 in BSON string at offset %d", d.pos)
+	}
+	d.pos += end + 1
+	return string(s), nil
+}
+
+// string reads a length-prefixed string.
+func (d *bsonDecoder) string() (string, error) {
+	at := d.pos
+	n, err := d.int32()
+	if err != nil {
+		return "", err
+	}
+	b, err := d.read(int(n))
+	if err != nil {
+		return "", err
+	}
+	if n < 1 || b[n-1] != 0 || !utf8.Valid(b[:n-1]) {
+		return "", fmt.Errorf("convert: invalid BSON string at offset %d", at)
+	}
+	return string(b[:n-1]), nil
+}
+
+// document reads a document, or an array when isArray is set.
+func (d *bsonDecoder) document(depth int, isArray bool) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("convert: BSON data nested too deeply")
+	}
+	start := d.pos
+	n, err := d.int32()
+	if err != nil {
+		return nil, err
+	}
+	if n < 5 || int(n) > len(d.data)-start {
+		return nil, fmt.Errorf("convert: invalid BSON document length %d at offset %d", n, start)
+	}
+	end := start + int(n)
+
+	obj, arr := parser.NewOrderedObject(), parser.JsonArray{}
+	for {
+		if d.pos >= end {
+			return nil, fmt.Errorf("convert: unterminated BSON document at offset %d", start)
+		}
+		kind := d.data[d.pos]
+		d.pos++
+		if kind == 0 {
+			break
+		}
+		name, err := d.cstring()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.element(kind, depth)
+		if err != nil {
+			return nil, err
+		}
+		if isArray {
+			arr = append(arr, v)
+		} else {
+			obj.Set(name, v)
+		}
+	}
+	if d.pos != end {
+		return nil, fmt.Errorf("convert: invalid BSON document length %d at offset %d", n, start)
+	}
+	if isArray {
+		return arr, nil
+	}
+	return obj, nil
+}
+
+// element reads the value of an element of the given type.
+func (d *bsonDecoder) element(kind byte, depth int) (interface{}, error) {
+	at := d.pos
+	switch kind {
+	case bsonDouble:
+		n, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		f := math.Float64frombits(n)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return extended("$numberDouble", formatSpecialFloat(f)), nil
+		}
+		return f, nil
+	case bsonString:
+		s, err := d.string()
+		return s, err
+	case bsonDocument:
+		return d.document(depth+1, false)
+	case bsonArray:
+		return d.document(depth+1, true)
+	case bsonBinary:
+		n, err := d.int32()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("convert: invalid BSON binary length %d at offset %d", n, at)
+		}
+		b, err := d.read(int(n) + 1)
+		if err != nil {
+			return nil, err
+		}
+		subType, b := b[0], b[1:]
+		if subType == 0x02 { // old binary, holding its own length
+			if len(b) < 4 || int(binary.LittleEndian.Uint32(b)) != len(b)-4 {
+				return nil, fmt.Errorf("convert: invalid BSON binary at offset %d", at)
+			}
+			b = b[4:]
+		}
+		bin := parser.NewOrderedObject()
+		bin.Set("base64", base64.StdEncoding.EncodeToString(b))
+		bin.Set("subType", fmt.Sprintf("%02x", subType))
+		return extended("$binary", bin), nil
+	case bsonUndefined:
+		return extended("$undefined", true), nil
+	case bsonObjectID:
+		b, err := d.read(12)
+		if err != nil {
+			return nil, err
+		}
+		return extended("$oid", hex.EncodeToString(b)), nil
+	case bsonBoolean:
+		b, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] > 1 {
+			return nil, fmt.Errorf("convert: invalid BSON boolean at offset %d", at)
+		}
+		return b[0] == 1, nil
+	case bsonDateTime:
+		n, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		ms := int64(n)
+		if t := time.UnixMilli(ms).UTC(); t.Year() >= 1970 && t.Year() <= 9999 {
+			return extended("$date", t.Format("2006-01-02T15:04:05.000Z07:00")), nil
+		}
+		return extended("$date", extended("$numberLong", strconv.FormatInt(ms, 10))), nil
+	case bsonNull:
+		return nil, nil
+	case bsonRegex:
+		pattern, err := d.cstring()
+		if err != nil {
+			return nil, err
+		}
+		options, err := d.cstring()
+		if err != nil {
+			return nil, err
+		}
+		re := parser.NewOrderedObject()
+		re.Set("pattern", pattern)
+		re.Set("options", options)
+		return extended("$regularExpression", re), nil
+	case bsonDBPointer:
+		ns, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(12)
+		if err != nil {
+			return nil, err
+		}
+		ptr := parser.NewOrderedObject()
+		ptr.Set("$ref", ns)
+		ptr.Set("$id", extended("$oid", hex.EncodeToString(b)))
+		return extended("$dbPointer", ptr), nil
+	case bsonCode:
+		s, err := d.string()
+		return extended("$code", s), err
+	case bsonSymbol:
+		s, err := d.string()
+		return extended("$symbol", s), err
+	case bsonCodeWithScope:
+		n, err := d.int32()
+		if err != nil {
+			return nil, err
+		}
+		code, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		scope, err := d.document(depth+1, false)
+		if err != nil {
+			return nil, err
+		}
+		if d.pos-at != int(n) {
+			return nil, fmt.Errorf("convert: invalid BSON code with scope length %d at offset %d", n, at)
+		}
+		obj := extended("$code", code)
+		obj.Set("$scope", scope)
+		return obj, nil
+	case bsonInt32:
+		n, err := d.int32()
+		return int64(n), err
+	case bsonTimestamp:
+		n, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		ts := parser.NewOrderedObject()
+		ts.Set("t", int64(n>>32))
+		ts.Set("i", int64(n&math.MaxUint32))
+		return extended("$timestamp", ts), nil
+	case bsonInt64:
+		n, err := d.uint64()
+		return int64(n), err
+	case bsonDecimal128:
+		lo, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		hi, err := d.uint64()
+		if err != nil {
+			return nil, err
+		}
+		return extended("$numberDecimal", formatDecimal128(hi, lo)), nil
+	case bsonMinKey:
+		return extended("$minKey", int64(1)), nil
+	case bsonMaxKey:
+		return extended("$maxKey", int64(1)), nil
+	}
+	return nil, fmt.Errorf("convert: invalid BSON element type 0x%02x at offset %d", kind, at-1)
+}
+
+// extended returns the Extended JSON object {key: v}.
+func extended(key string, v interface{}) *parser.OrderedObject {
+	obj := parser.NewOrderedObject()
+	obj.Set(key, v)
+	return obj
+}
+
+// formatSpecialFloat returns the Extended JSON text of an infinity or NaN.
+func formatSpecialFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f > 0:
+		return "Infinity"
+	}
+	return "-Infinity"
+}
+
+// ToBSON converts a parsed JSON object into a BSON document. Objects in the
+// canonical or relaxed form of MongoDB Extended JSON v2, like {"$oid": hex}
+// or {"$date": text}, become the BSON values they describe. Integers are
+// written as 32 bit integers when they fit, as 64 bit integers otherwise.
+// Members of plain JsonObject maps are written in sorted key order.
+func ToBSON(v interface{}) ([]byte, error) {
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("convert: a BSON document must be an object, got %s", parser.TypeName(v))
+	}
+	return appendBSONDocument(nil, keys, members)
+}
+
+// appendBSONDocument appends a document holding the given members.
+func appendBSONDocument(b []byte, keys []string, members map[string]interface{}) ([]byte, error) {
+	start := len(b)
+	b = append(b, 0, 0, 0, 0)
+	for _, k := range keys {
+		var err error
+		if b, err = appendBSONElement(b, k, members[k]); err != nil {
+			return nil, err
+		}
+	}
+	b = append(b, 0)
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(b)-start))
+	return b, nil
+}
+
+// appendBSONElement appends the element name holding v.
+func appendBSONElement(b []byte, name string, v interface{}) ([]byte, error) {
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, fmt.Errorf("convert: BSON element names cannot hold NUL characters")
+	}
+	element := func(kind byte) []byte {
+		return append(append(append(b, kind), name...), 0)
+	}
+
+	switch x := v.(type) {
+	case nil:
+		return element(bsonNull), nil
+	case bool:
+		if x {
+			return append(element(bsonBoolean), 1), nil
+		}
+		return append(element(bsonBoolean), 0), nil
+	case int64:
+		if x >= math.MinInt32 && x <= math.MaxInt32 {
+			return binary.LittleEndian.AppendUint32(element(bsonInt32), uint32(x)), nil
+		}
+		return binary.LittleEndian.AppendUint64(element(bsonInt64), uint64(x)), nil
+	case float64:
+		return binary.LittleEndian.AppendUint64(element(bsonDouble), math.Float64bits(x)), nil
+	case string:
+		return appendBSONString(element(bsonString), x), nil
+	case parser.JsonArray:
+		keys := make([]string, len(x))
+		members := make(map[string]interface{}, len(x))
+		for i, e := range x {
+			keys[i] = strconv.Itoa(i)
+			members[keys[i]] = e
+		}
+		return appendBSONDocument(element(bsonArray), keys, members)
+	}
+
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("convert: unsupported value of type %T", v)
+	}
+	if len(keys) > 0 && strings.HasPrefix(keys[0], "$") {
+		kind, payload, ok, err := extendedValue(keys, members)
+		if err != nil {
+			return nil, fmt.Errorf("convert: %s: %v", name, err)
+		}
+		if ok {
+			return append(element(kind), payload...), nil
+		}
+	}
+	return appendBSONDocument(element(bsonDocument), keys, members)
+}
+
+// appendBSONString appends a length-prefixed string.
+func appendBSONString(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)+1))
+	return append(append(b, s...), 0)
+}
+
+// extendedValue returns the type and content of the BSON value described by
+// an Extended JSON object, or false when the object is not one.
+func extendedValue(keys []string, members map[string]interface{}) (byte, []byte, bool, error) {
+	str := func(v interface{}) (string, bool) {
+		s, ok := v.(string)
+		return s, ok
+	}
+	if len(keys) == 2 && keys[0] == "$code" && keys[1] == "$scope" {
+		code, ok := str(members["$code"])
+		scopeKeys, scope, isObject := parser.Members(members["$scope"])
+		if !ok || !isObject {
+			return 0, nil, false, fmt.Errorf("invalid $code value")
+		}
+		b := appendBSONString([]byte{0, 0, 0, 0}, code)
+		b, err := appendBSONDocument(b, scopeKeys, scope)
+		if err != nil {
+			return 0, nil, false, err
+		}
+		binary.LittleEndian.PutUint32(b, uint32(len(b)))
+		return bsonCodeWithScope, b, true, nil
+	}
+	if len(keys) != 1 {
+		return 0, nil, false, nil
+	}
+
+	key, v := keys[0], members[keys[0]]
+	invalid := fmt.Errorf("invalid %s value", key)
+	switch key {
+	case "$oid":
+		s, _ := str(v)
+		id, err := hex.DecodeString(s)
+		if err != nil || len(id) != 12 {
+			return 0, nil, false, invalid
+		}
+		return bsonObjectID, id, true, nil
+	case "$date":
+		var ms int64
+		switch x := v.(type) {
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, x)
+			if err != nil {
+				return 0, nil, false, invalid
+			}
+			ms = t.UnixMilli()
+		case int64:
+			ms = x
+		default:
+			long, ok := extendedLong(v)
+			if !ok {
+				return 0, nil, false, invalid
+			}
+			ms = long
+		}
+		return bsonDateTime, binary.LittleEndian.AppendUint64(nil, uint64(ms)), true, nil
+	case "$numberInt":
+		s, _ := str(v)
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return 0, nil, false, invalid
+		}
+		return bsonInt32, binary.LittleEndian.AppendUint32(nil, uint32(n)), true, nil
+	case "$numberLong":
+		s, _ := str(v)
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, nil, false, invalid
+		}
+		return bsonInt64, binary.LittleEndian.AppendUint64(nil, uint64(n)), true, nil
+	case "$numberDouble":
+		s, _ := str(v)
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, nil, false, invalid
+		}
+		return bsonDouble, binary.LittleEndian.AppendUint64(nil, math.Float64bits(f)), true, nil
+	case "$numberDecimal":
+		s, _ := str(v)
+		hi, lo, ok := parseDecimal128(s)
+		if !ok {
+			return 0, nil, false, invalid
+		}
+		return bsonDecimal128, binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, lo), hi), true, nil
+	case "$binary":
+		_, bin, ok := parser.Members(v)
+		data, isData := str(bin["base64"])
+		subTypeText, isSubType := str(bin["subType"])
+		if !ok || !isData || !isSubType {
+			return 0, nil, false, invalid
+		}
+		b, err := base64.StdEncoding.DecodeString(data)
+		subType, subTypeErr := strconv.ParseUint(subTypeText, 16, 8)
+		if err != nil || subTypeErr != nil {
+			return 0, nil, false, invalid
+		}
+		if subType == 0x02 {
+			b = append(binary.LittleEndian.AppendUint32(nil, uint32(len(b))), b...)
+		}
+		payload := binary.LittleEndian.AppendUint32(nil, uint32(len(b)))
+		return bsonBinary, append(append(payload, byte(subType)), b...), true, nil
+	case "$timestamp":
+		_, ts, ok := parser.Members(v)
+		t, isT := ts["t"].(int64)
+		i, isI := ts["i"].(int64)
+		if !ok || !isT || !isI || t < 0 || t > math.MaxUint32 || i < 0 || i > math.MaxUint32 {
+			return 0, nil, false, invalid
+		}
+		return bsonTimestamp, binary.LittleEndian.AppendUint64(nil, uint64(t)<<32|uint64(i)), true, nil
+	case "$regularExpression":
+		_, re, ok := parser.Members(v)
+		pattern, isPattern := str(re["pattern"])
+		options, isOptions := str(re["options"])
+		if !ok || !isPattern || !isOptions || strings.IndexByte(pattern+options, 0) >= 0 {
+			return 0, nil, false, invalid
+		}
+		return bsonRegex, append(append(append([]byte(pattern), 0), options...), 0), true, nil
+	case "$symbol", "$code":
+		s, ok := str(v)
+		if !ok {
+			return 0, nil, false, invalid
+		}
+		if key == "$symbol" {
+			return bsonSymbol, appendBSONString(nil, s), true, nil
+		}
+		return bsonCode, appendBSONString(nil, s), true, nil
+	case "$undefined":
+		return bsonUndefined, nil, true, nil
+	case "$minKey":
+		return bsonMinKey, nil, true, nil
+	case "$maxKey":
+		return bsonMaxKey, nil, true, nil
+	}
+	return 0, nil, false, nil
+}
+
+// extendedLong returns the value of a {"$numberLong": text} object.
+func extendedLong(v interface{}) (int64, bool) {
+	keys, members, ok := parser.Members(v)
+	if !ok || len(keys) != 1 || keys[0] != "$numberLong" {
+		return 0, false
+	}
+	s, _ := members["$numberLong"].(string)
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// Decimal128 limits: the largest coefficient, and the bias and range of the
+// exponent.
+var maxDecimal128Coefficient = new(big.Int).Sub(new(big.Int).Exp(big.NewInt(10), big.NewInt(34), nil), big.NewInt(1))
+
+const (
+	decimal128Bias        = 6176
+	decimal128MaxExponent = 6111
+)
+
+// formatDecimal128 returns the text of an IEEE 754 decimal128 value held in
+// its binary integer decimal encoding, as specified for BSON.
+func formatDecimal128(hi, lo uint64) string {
+	sign := ""
+	if hi>>63 == 1 {
+		sign = "-"
+	}
+	switch hi >> 58 & 0x1f {
+	case 0x1f:
+		return "NaN"
+	case 0x1e:
+		return sign + "Infinity"
+	}
+
+	var exp int
+	coefficient := new(big.Int)
+	if hi>>61&3 == 3 {
+		// The implicit 100 prefix makes the coefficient too large, so zero.
+		exp = int(hi>>47&0x3fff) - decimal128Bias
+	} else {
+		exp = int(hi>>49&0x3fff) - decimal128Bias
+		coefficient.SetUint64(hi & (1<<49 - 1))
+		coefficient.Lsh(coefficient, 64).Or(coefficient, new(big.Int).SetUint64(lo))
+		if coefficient.Cmp(maxDecimal128Coefficient) > 0 {
+			coefficient.SetInt64(0)
+		}
+	}
+
+	digits := coefficient.String()
+	adjusted := exp + len(digits) - 1
+	switch {
+	case exp == 0:
+		return sign + digits
+	case exp < 0 && adjusted >= -6:
+		if point := len(digits) + exp; point > 0 {
+			return sign + digits[:point] + "." + digits[point:]
+		}
+		return sign + "0." + strings.Repeat("0", -len(digits)-exp) + digits
+	}
+	s := sign + digits[:1]
+	if len(digits) > 1 {
+		s += "." + digits[1:]
+	}
+	if adjusted >= 0 {
+		return s + "E+" + strconv.Itoa(adjusted)
+	}
+	return s + "E" + strconv.Itoa(adjusted)
+}
+
+// parseDecimal128 returns the decimal128 encoding of a decimal number, which
+// must be exactly representable.
+func parseDecimal128(s string) (hi, lo uint64, ok bool) {
+	var sign uint64
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = 1 << 63
+		}
+		s = s[1:]
+	}
+	switch strings.ToLower(s) {
+	case "nan":
+		return 0x1f << 58, 0, true
+	case "inf", "infinity":
+		return sign | 0x1e<<58, 0, true
+	}
+
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0, 0, false
+		}
+		exp, s = e, s[:i]
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		exp -= len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	coefficient, ok := new(big.Int).SetString(s, 10)
+	if !ok || s == "" || strings.ContainsAny(s, "+-") || coefficient.Cmp(maxDecimal128Coefficient) > 0 {
+		return 0, 0, false
+	}
+	if exp < -decimal128Bias || exp > decimal128MaxExponent {
+		return 0, 0, false
+	}
+
+	lo = new(big.Int).And(coefficient, new(big.Int).SetUint64(math.MaxUint64)).Uint64()
+	hi = sign | uint64(exp+decimal128Bias)<<49 | new(big.Int).Rsh(coefficient, 64).Uint64()
+	return hi, lo, true
+}
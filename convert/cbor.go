@@ -0,0 +1,391 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// CBOR major types.
+const (
+	cborUnsigned = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// ToCBOR encodes a parsed JSON value as CBOR (RFC 8949). Lengths and integers
+// use their shortest form and floats are written as single precision when
+// that keeps their value. Members of plain JsonObject maps are written in
+// sorted key order.
+func ToCBOR(v interface{}) ([]byte, error) {
+	return appendCBOR(nil, v)
+}
+
+// appendCBOR appends the encoding of v to b.
+func appendCBOR(b []byte, v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xf6), nil
+	case bool:
+		if x {
+			return append(b, 0xf5), nil
+		}
+		return append(b, 0xf4), nil
+	case int64:
+		if x < 0 {
+			return appendCBORHead(b, cborNegative, uint64(-1-x)), nil
+		}
+		return appendCBORHead(b, cborUnsigned, uint64(x)), nil
+	case float64:
+		if f := float32(x); float64(f) == x || math.IsNaN(x) {
+			return binary.BigEndian.AppendUint32(append(b, 0xfa), math.Float32bits(f)), nil
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(x)), nil
+	case string:
+		s := x
+		return append(appendCBORHead(b, cborText, uint64(len(s))), s...), nil
+	case parser.JsonArray:
+		b = appendCBORHead(b, cborArray, uint64(len(x)))
+		for _, e := range x {
+			var err error
+			if b, err = appendCBOR(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("convert: unsupported value of type %T", v)
+	}
+	b = appendCBORHead(b, cborMap, uint64(len(keys)))
+	for _, k := range keys {
+		s := k
+		b = append(appendCBORHead(b, cborText, uint64(len(s))), s...)
+		var err error
+		if b, err = appendCBOR(b, members[k]); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendCBORHead appends the head of a data item of the given major type,
+// holding n in its shortest form.
+func appendCBORHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+// FromCBOR decodes a single CBOR data item following the conversion to JSON
+// of RFC 8949 section 6.1. Maps become *parser.OrderedObject values keeping
+// their key order, non-string keys being converted to their text. Byte
+// strings become base64url strings without padding, or base64 or base16
+// strings under the expected conversion tags 22 and 23. Integers beyond int64
+// and bignums become strings of their digits, epoch dates RFC 3339 strings,
+// and undefined, infinities, NaN and unassigned simple values become null.
+// Other tags are ignored.
+func FromCBOR(data []byte) (interface{}, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.value(0, 21)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("convert: unexpected data after the CBOR data item at offset %d", d.pos)
+	}
+	return v, nil
+}
+
+// cborBreak is the stop code ending indefinite-length items.
+const cborBreak = 0xff
+
+// cborDecoder reads CBOR data items from data.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// read returns the next n bytes.
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("convert: unexpected end of CBOR data at offset %d", len(d.data))
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// head reads the head of a data item and returns its major type, additional
+// information and argument. The argument is zero for indefinite lengths.
+func (d *cborDecoder) head() (major, info byte, n uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		arg, err := d.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, c := range arg {
+			n = n<<8 | uint64(c)
+		}
+		return major, info, n, nil
+	case info == 31 && major >= cborBytes && major <= cborMap || info == 31 && major == cborSimple:
+		return major, info, 0, nil
+	}
+	return 0, 0, 0, fmt.Errorf("convert: invalid CBOR additional information %d at offset %d", info, d.pos-1)
+}
+
+// atBreak reports whether the next byte is the break stop code, consuming it.
+func (d *cborDecoder) atBreak() (bool, error) {
+	if d.pos >= len(d.data) {
+		return false, fmt.Errorf("convert: unexpected end of CBOR data at offset %d", len(d.data))
+	}
+	if d.data[d.pos] == cborBreak {
+		d.pos++
+		return true, nil
+	}
+	return false, nil
+}
+
+// value decodes the next data item, encoding byte strings as the expected
+// conversion tag hint (21, 22 or 23) says.
+func (d *cborDecoder) value(depth int, hint uint64) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("convert: CBOR data nested too deeply")
+	}
+	start := d.pos
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if n > math.MaxInt64 {
+			return strconv.FormatUint(n, 10), nil
+		}
+		return int64(n), nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			return "-" + new(big.Int).Add(new(big.Int).SetUint64(n), big.NewInt(1)).String(), nil
+		}
+		return -1 - int64(n), nil
+	case cborBytes:
+		b, err := d.chunks(cborBytes, info, n)
+		if err != nil {
+			return nil, err
+		}
+		return encodeBytes(b, hint), nil
+	case cborText:
+		b, err := d.chunks(cborText, info, n)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(b) {
+			return nil, fmt.Errorf("convert: invalid UTF-8 in CBOR text string at offset %d", start)
+		}
+		return string(b), nil
+	case cborArray:
+		arr := parser.JsonArray{}
+		for i := uint64(0); info == 31 || i < n; i++ {
+			if info == 31 {
+				if end, err := d.atBreak(); err != nil || end {
+					return arr, err
+				}
+			}
+			v, err := d.value(depth+1, hint)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case cborMap:
+		obj := parser.NewOrderedObject()
+		for i := uint64(0); info == 31 || i < n; i++ {
+			if info == 31 {
+				if end, err := d.atBreak(); err != nil || end {
+					return obj, err
+				}
+			}
+			at := d.pos
+			k, err := d.value(depth+1, hint)
+			if err != nil {
+				return nil, err
+			}
+			key, err := keyText(k)
+			if err != nil {
+				return nil, fmt.Errorf("convert: %v at offset %d", err, at)
+			}
+			v, err := d.value(depth+1, hint)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, v)
+		}
+		return obj, nil
+	case cborTag:
+		return d.tagged(n, depth, hint)
+	}
+
+	switch {
+	case info == 20:
+		return false, nil
+	case info == 21:
+		return true, nil
+	case info == 25:
+		return finite(halfToFloat(uint16(n))), nil
+	case info == 26:
+		return finite(float64(math.Float32frombits(uint32(n)))), nil
+	case info == 27:
+		return finite(math.Float64frombits(n)), nil
+	case info == 31:
+		return nil, fmt.Errorf("convert: unexpected CBOR break at offset %d", start)
+	case info == 24 && n < 32:
+		return nil, fmt.Errorf("convert: invalid CBOR simple value at offset %d", start)
+	}
+	return nil, nil // null, undefined and unassigned simple values
+}
+
+// chunks reads the content of a byte or text string of n bytes, or of an
+// indefinite-length string made of definite-length chunks of the same type.
+func (d *cborDecoder) chunks(major, info byte, n uint64) ([]byte, error) {
+	if info != 31 {
+		return d.read(n)
+	}
+	var result []byte
+	for {
+		if end, err := d.atBreak(); err != nil || end {
+			return result, err
+		}
+		at := d.pos
+		m, chunkInfo, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || chunkInfo == 31 {
+			return nil, fmt.Errorf("convert: invalid CBOR string chunk at offset %d", at)
+		}
+		b, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, b...)
+	}
+}
+
+// tagged decodes the content of a data item with tag number.
+func (d *cborDecoder) tagged(number uint64, depth int, hint uint64) (interface{}, error) {
+	if number >= 21 && number <= 23 {
+		hint = number
+	}
+	start := d.pos
+	// Bignums hold the magnitude of their value in a byte string.
+	if (number == 2 || number == 3) && d.pos < len(d.data) && d.data[d.pos]>>5 == cborBytes {
+		_, info, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.chunks(cborBytes, info, n)
+		if err != nil {
+			return nil, err
+		}
+		i := new(big.Int).SetBytes(b)
+		if number == 3 {
+			i.Sub(big.NewInt(-1), i)
+		}
+		return i.String(), nil
+	}
+	v, err := d.value(depth+1, hint)
+	if err != nil {
+		return nil, err
+	}
+
+	if number == 1 { // epoch-based date/time
+		var t time.Time
+		switch x := v.(type) {
+		case int64:
+			t = time.Unix(x, 0)
+		case float64:
+			sec, frac := math.Modf(x)
+			t = time.Unix(int64(sec), int64(frac*1e9))
+		default:
+			return nil, fmt.Errorf("convert: invalid CBOR epoch date at offset %d", start)
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	}
+	return v, nil
+}
+
+// encodeBytes returns the text of a byte string for the expected conversion
+// tag hint.
+func encodeBytes(b []byte, hint uint64) string {
+	switch hint {
+	case 22:
+		return base64.StdEncoding.EncodeToString(b)
+	case 23:
+		return hex.EncodeToString(b)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// finite returns f, or nil for infinities and NaN, which JSON cannot hold.
+func finite(f float64) interface{} {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil
+	}
+	return f
+}
+
+// halfToFloat converts an IEEE 754 half-precision float.
+func halfToFloat(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
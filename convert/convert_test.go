@@ -0,0 +1,735 @@
+package convert
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+// compact lays v out on a single line.
+func compact(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+// unordered replaces the ordered objects of v by JsonObject maps, which are
+// formatted with sorted keys.
+func unordered(v interface{}) interface{} {
+	switch x := v.(type) {
+	case *parser.OrderedObject:
+		obj := parser.JsonObject{}
+		for k, e := range x.Values {
+			obj[k] = unordered(e)
+		}
+		return obj
+	case parser.JsonArray:
+		arr := make(parser.JsonArray, len(x))
+		for i, e := range x {
+			arr[i] = unordered(e)
+		}
+		return arr
+	}
+	return v
+}
+
+func TestFromYAML(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", "null"},
+		{"name: gojson\nversion: 2\nratio: 0.5\nstable: true\nlicense: ~\n", `{"name": "gojson", "version": 2, "ratio": 0.5, "stable": true, "license": null}`},
+		{"zebra: 1\napple: 2\n", `{"zebra": 1, "apple": 2}`},
+		{"- a\n- [1, 2]\n- {k: v}\n", `["a", [1, 2], {"k": "v"}]`},
+		{"quote: 'say \"hi\"'\npath: C:\\dir\nmulti: |\n  line 1\n  line 2\n", `{"quote": "say \"hi\"", "path": "C:\\dir", "multi": "line 1\nline 2\n"}`},
+		{"date: 2001-12-14\nbig: 123456789012345678901234\ninf: .inf\nbin: !!binary aGk=\n", `{"date": "2001-12-14", "big": "123456789012345678901234", "inf": ".inf", "bin": "aGk="}`},
+		{"1: one\ntrue: yes\n", `{"1": "one", "true": "yes"}`},
+		{"base: &base {a: 1, b: 2}\nderived:\n  <<: *base\n  b: 3\nalias: *base\n", `{"base": {"a": 1, "b": 2}, "derived": {"a": 1, "b": 3}, "alias": {"a": 1, "b": 2}}`},
+		{"hex: 0x1F\nexp: 1e3\n", `{"hex": 31, "exp": 1000}`},
+	}
+
+	for _, tt := range tests {
+		v, err := FromYAML([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("FromYAML(%q): unexpected error: %v", tt.input, err)
+		}
+		if got := compact(v); got != tt.expected {
+			t.Errorf("FromYAML(%q): expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFromYAMLErrors(t *testing.T) {
+	tests := []string{
+		"a: [1, 2\n",
+		"a: 1\n a: 2\n",
+		"? [1, 2]\n: value\n",
+		"a: &x 1\nb:\n  <<: *x\n",
+	}
+
+	for _, input := range tests {
+		if _, err := FromYAML([]byte(input)); err == nil {
+			t.Errorf("FromYAML(%q): expected an error", input)
+		}
+	}
+}
+
+func TestToYAML(t *testing.T) {
+	doc := parser.NewOrderedObject()
+	doc.Set("name", "gojson")
+	doc.Set("version", int64(2))
+	doc.Set("ratio", 1.0)
+	doc.Set("tags", parser.JsonArray{"json", "true", "12"})
+	doc.Set("owner", parser.JsonObject{"login": `a"b`, "id": nil})
+	doc.Set("empty", parser.JsonArray{})
+	doc.Set("text", "line 1\nline 2")
+
+	expected := `name: gojson
+version: 2
+ratio: 1.0
+tags:
+  - json
+  - "true"
+  - "12"
+owner:
+  id: null
+  login: a"b
+empty: []
+text: |-
+  line 1
+  line 2
+`
+	data, err := ToYAML(doc)
+	if err != nil {
+		t.Fatalf("ToYAML: unexpected error: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	input := `{"a": [1, 2.5, "x", null, true], "b": {"c": "d\"e", "f": {}}, "g": "\u00e9"}`
+	data, err := ToYAML(parse(t, input))
+	if err != nil {
+		t.Fatalf("ToYAML: unexpected error: %v", err)
+	}
+	v, err := FromYAML(data)
+	if err != nil {
+		t.Fatalf("FromYAML: unexpected error: %v", err)
+	}
+	if got, expected := compact(v), `{"a": [1, 2.5, "x", null, true], "b": {"c": "d\"e", "f": {}}, "g": "é"}`; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+const tomlDocument = `title = "TOML \"example\""
+version = 2
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00-08:00
+birthday = 1979-05-27
+alarm = 07:32:00
+meeting = 1979-05-27T07:32:00
+
+[database]
+ports = [8000, 8001]
+ratio = 0.5
+enabled = true
+limits = { max = 10, min = 1 }
+
+[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]
+
+[[products]]
+name = "Nail"
+color = "gray"
+
+[products.size]
+unit = "mm"
+`
+
+func TestFromTOML(t *testing.T) {
+	v, err := FromTOML([]byte(tomlDocument))
+	if err != nil {
+		t.Fatalf("FromTOML: unexpected error: %v", err)
+	}
+	expected := `{"title": "TOML \"example\"", "version": 2, ` +
+		`"owner": {"name": "Tom", "dob": "1979-05-27T07:32:00-08:00", "birthday": "1979-05-27", "alarm": "07:32:00", "meeting": "1979-05-27T07:32:00"}, ` +
+		`"database": {"ports": [8000, 8001], "ratio": 0.5, "enabled": true, "limits": {"max": 10, "min": 1}}, ` +
+		`"products": [{"name": "Hammer", "sku": 738594937}, {}, {"name": "Nail", "color": "gray", "size": {"unit": "mm"}}]}`
+	if got := compact(v); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if _, err := FromTOML([]byte("a = \n")); err == nil {
+		t.Errorf("FromTOML: expected an error for an invalid document")
+	}
+}
+
+func TestToTOML(t *testing.T) {
+	doc := parse(t, `{
+		"title": "say \"hi\"\n",
+		"ratio": 1.0,
+		"tags": ["a", 1, [true]],
+		"owner": {"name": "Tom", "address": {"city": "Paris"}},
+		"points": [{"x": 1, "meta": {"k": "v"}}, {"x": 2}],
+		"mixed": [{"a": 1}, 2],
+		"empty": {},
+		"odd key": 1
+	}`)
+
+	expected := `mixed = [{ a = 1 }, 2]
+"odd key" = 1
+ratio = 1.0
+tags = ["a", 1, [true]]
+title = "say \"hi\"\n"
+
+[empty]
+
+[owner]
+name = "Tom"
+
+[owner.address]
+city = "Paris"
+
+[[points]]
+x = 1
+
+[points.meta]
+k = "v"
+
+[[points]]
+x = 2
+`
+	data, err := ToTOML(doc)
+	if err != nil {
+		t.Fatalf("ToTOML: unexpected error: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+
+	back, err := FromTOML(data)
+	if err != nil {
+		t.Fatalf("FromTOML: the generated document does not parse: %v", err)
+	}
+	if got := compact(unordered(back)); got != compact(doc) {
+		t.Errorf("round trip: expected %s, got %s", compact(doc), got)
+	}
+}
+
+func TestToTOMLErrors(t *testing.T) {
+	tests := []string{`[1, 2]`, `"text"`, `{"a": {"b": null}}`, `{"a": [1, null]}`}
+	for _, input := range tests {
+		if _, err := ToTOML(parse(t, input)); err == nil {
+			t.Errorf("ToTOML(%s): expected an error", input)
+		}
+	}
+}
+
+func TestFromCSV(t *testing.T) {
+	input := "name,age,score,admin,note\nalice,31,4.5,true,\"says \"\"hi\"\"\"\nbob,017,-1e3,no,\n"
+	tests := []struct {
+		opts     CSVOptions
+		expected string
+	}{
+		{CSVOptions{}, `[{"name": "alice", "age": "31", "score": "4.5", "admin": "true", "note": "says \"hi\""}, {"name": "bob", "age": "017", "score": "-1e3", "admin": "no", "note": ""}]`},
+		{CSVOptions{InferTypes: true, EmptyAsNull: true}, `[{"name": "alice", "age": 31, "score": 4.5, "admin": true, "note": "says \"hi\""}, {"name": "bob", "age": "017", "score": -1000, "admin": "no", "note": null}]`},
+	}
+
+	for _, tt := range tests {
+		v, err := FromCSV([]byte(input), tt.opts)
+		if err != nil {
+			t.Fatalf("FromCSV(%+v): unexpected error: %v", tt.opts, err)
+		}
+		if got := compact(v); got != tt.expected {
+			t.Errorf("FromCSV(%+v):\nexpected %s\ngot      %s", tt.opts, tt.expected, got)
+		}
+	}
+
+	v, err := FromCSV([]byte("a\tb\n1\t\"x\ty\"\n"), CSVOptions{Comma: '\t', InferTypes: true})
+	if err != nil {
+		t.Fatalf("FromCSV(TSV): unexpected error: %v", err)
+	}
+	if got, expected := compact(v), `[{"a": 1, "b": "x\ty"}]`; got != expected {
+		t.Errorf("FromCSV(TSV): expected %s, got %s", expected, got)
+	}
+
+	if v, err := FromCSV(nil, CSVOptions{}); err != nil || len(v) != 0 {
+		t.Errorf("FromCSV(empty): expected an empty array, got %v, %v", v, err)
+	}
+	if _, err := FromCSV([]byte("a,b\n1\n"), CSVOptions{}); err == nil {
+		t.Errorf("FromCSV: expected an error for a short record")
+	}
+}
+
+func TestToCSV(t *testing.T) {
+	doc := parse(t, `[
+		{"name": "alice", "age": 31, "tags": ["a", "b"]},
+		{"name": "bob, jr", "admin": true, "age": null},
+		{"name": "say \"hi\"", "extra": {"k": 1}}
+	]`)
+
+	tests := []struct {
+		opts     CSVOptions
+		expected string
+	}{
+		{CSVOptions{}, "age,name,tags,admin,extra\n31,alice,\"[\"\"a\"\", \"\"b\"\"]\",,\n,\"bob, jr\",,true,\n,\"say \"\"hi\"\"\",,,\"{\"\"k\"\": 1}\"\n"},
+		{CSVOptions{Comma: '\t', Columns: []string{"name", "admin"}}, "name\tadmin\nalice\t\nbob, jr\ttrue\n\"say \"\"hi\"\"\"\t\n"},
+	}
+
+	for _, tt := range tests {
+		data, err := ToCSV(doc, tt.opts)
+		if err != nil {
+			t.Fatalf("ToCSV(%+v): unexpected error: %v", tt.opts, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("ToCSV(%+v):\nexpected %q\ngot      %q", tt.opts, tt.expected, data)
+		}
+	}
+
+	for _, input := range []string{`{"a": 1}`, `[{"a": 1}, 2]`} {
+		if _, err := ToCSV(parse(t, input), CSVOptions{}); err == nil {
+			t.Errorf("ToCSV(%s): expected an error", input)
+		}
+	}
+}
+
+const xmlDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- an order -->
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <order id="42" status="new">
+      <customer>Alice &amp; Bob</customer>
+      <item sku="A1">Hammer</item>
+      <item sku="B2"/>
+      <item>Nail</item>
+      <note></note>
+      <total currency="EUR">12.50</total>
+    </order>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestFromXML(t *testing.T) {
+	v, err := FromXML([]byte(xmlDocument))
+	if err != nil {
+		t.Fatalf("FromXML: unexpected error: %v", err)
+	}
+	expected := `{"Envelope": {"Body": {"order": {"@id": "42", "@status": "new", "customer": "Alice & Bob", ` +
+		`"item": [{"@sku": "A1", "#text": "Hammer"}, {"@sku": "B2"}, "Nail"], "note": null, "total": {"@currency": "EUR", "#text": "12.50"}}}}}`
+	if got := compact(v); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	for _, input := range []string{"", "<a>", "<a></b>", "<a/><b/>", "text<a/>"} {
+		if _, err := FromXML([]byte(input)); err == nil {
+			t.Errorf("FromXML(%q): expected an error", input)
+		}
+	}
+}
+
+func TestToXML(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"order": {"@id": 42, "customer": "Alice & \"Bob\"", "item": [{"@sku": "A1", "#text": "Hammer"}, "Nail"], "note": null, "tags": [[1, 2]]}}`,
+			"<order id=\"42\">\n  <customer>Alice &amp; &#34;Bob&#34;</customer>\n  <item sku=\"A1\">Hammer</item>\n  <item>Nail</item>\n  <note></note>\n  <tags>[1, 2]</tags>\n</order>\n"},
+		{`{"a": 1, "b": true}`, "<root>\n  <a>1</a>\n  <b>true</b>\n</root>\n"},
+		{`[1, 2]`, "<root>[1, 2]</root>\n"},
+		{`{"list": [1, 2]}`, "<root>\n  <list>1</list>\n  <list>2</list>\n</root>\n"},
+	}
+
+	for _, tt := range tests {
+		data, err := ToXML(parse(t, tt.input))
+		if err != nil {
+			t.Fatalf("ToXML(%s): unexpected error: %v", tt.input, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("ToXML(%s):\nexpected %q\ngot      %q", tt.input, tt.expected, data)
+		}
+	}
+
+	for _, input := range []string{`{"a b": 1}`, `{"1a": 1}`, `{"x": {"@a:b": 1}}`, `{"xmlns": 1}`} {
+		if _, err := ToXML(parse(t, input)); err == nil {
+			t.Errorf("ToXML(%s): expected an error", input)
+		}
+	}
+}
+
+func TestToMessagePack(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`null`, "c0"},
+		{`[true, false]`, "92c3c2"},
+		{`[0, 127, 128, 256, 65536, 4294967296]`, "96007fcc80cd0100ce00010000cf0000000100000000"},
+		{`[-1, -32, -33, -129, -32769, -2147483649]`, "96ffe0d0dfd1ff7fd2ffff7fffd3ffffffff7fffffff"},
+		{`1.5`, "cb3ff8000000000000"},
+		{`"a\"é"`, "a461" + "22c3a9"},
+		{`{"b": 1, "a": [2]}`, "82a1619102a16201"},
+	}
+
+	for _, tt := range tests {
+		data, err := ToMessagePack(parse(t, tt.input))
+		if err != nil {
+			t.Fatalf("ToMessagePack(%s): unexpected error: %v", tt.input, err)
+		}
+		if got := hex.EncodeToString(data); got != tt.expected {
+			t.Errorf("ToMessagePack(%s): expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+
+	long, err := ToMessagePack(strings.Repeat("x", 300))
+	if err != nil {
+		t.Fatalf("ToMessagePack: unexpected error: %v", err)
+	}
+	if got := hex.EncodeToString(long[:3]); got != "da012c" || len(long) != 303 {
+		t.Errorf("expected a str 16 header, got %s and %d bytes", got, len(long))
+	}
+}
+
+func TestFromMessagePack(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"c0", `null`},
+		{"93c3c27f", `[true, false, 127]`},
+		{"94e0d080d1ff7fcfffffffffffffffff", `[-32, -128, -129, "18446744073709551615"]`},
+		{"92ca3fc00000cb3ff8000000000000", `[1.5, 1.5]`},
+		{"d90361225c", `"a\"\\"`},
+		{"83a162010181a3796573c3a161c0", `{"b": 1, "1": {"yes": true}, "a": null}`},
+		{"c403010203", `"AQID"`},
+		{"d6ff00000000", `"1970-01-01T00:00:00Z"`},
+		{"d40507", `{"type": 5, "data": "Bw=="}`},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.input)
+		v, err := FromMessagePack(data)
+		if err != nil {
+			t.Fatalf("FromMessagePack(%s): unexpected error: %v", tt.input, err)
+		}
+		if got := compact(v); got != tt.expected {
+			t.Errorf("FromMessagePack(%s): expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"", "c1", "92c0", "a3616263c0", "dfffffffff", "81c0", "8191c0c0"} {
+		data, _ := hex.DecodeString(input)
+		if _, err := FromMessagePack(data); err == nil {
+			t.Errorf("FromMessagePack(%s): expected an error", input)
+		}
+	}
+}
+
+func TestMessagePackRoundTrip(t *testing.T) {
+	input := `{"name": "gojson", "tags": ["a", "b\n"], "nested": {"pi": 3.14, "n": -7, "ok": null}}`
+	data, err := ToMessagePack(parse(t, input))
+	if err != nil {
+		t.Fatalf("ToMessagePack: unexpected error: %v", err)
+	}
+	v, err := FromMessagePack(data)
+	if err != nil {
+		t.Fatalf("FromMessagePack: unexpected error: %v", err)
+	}
+	if got, expected := compact(v), compact(parse(t, input)); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestToCBOR(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[null, true, false]`, "83f6f5f4"},
+		{`[0, 23, 24, 1000, 1000000, 1000000000000]`, "86001718181903e81a000f42401b000000e8d4a51000"},
+		{`[-1, -100, -1000]`, "832038633903e7"},
+		{`[1.5, 1.1]`, "82fa3fc00000fb3ff199999999999a"},
+		{`"ü"`, "62c3bc"},
+		{`{"b": 1, "a": [2]}`, "a2616181026162" + "01"},
+	}
+
+	for _, tt := range tests {
+		data, err := ToCBOR(parse(t, tt.input))
+		if err != nil {
+			t.Fatalf("ToCBOR(%s): unexpected error: %v", tt.input, err)
+		}
+		if got := hex.EncodeToString(data); got != tt.expected {
+			t.Errorf("ToCBOR(%s): expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFromCBOR(t *testing.T) {
+	// Most inputs come from the examples of RFC 8949 appendix A.
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1bffffffffffffffff", `"18446744073709551615"`},
+		{"3bffffffffffffffff", `"-18446744073709551616"`},
+		{"c249010000000000000000", `"18446744073709551616"`},
+		{"c349010000000000000000", `"-18446744073709551617"`},
+		{"83f93c00f97bfffa47c35000", `[1, 65504, 100000]`},
+		{"83f97c00fa7fc00000f7", `[null, null, null]`},
+		{"c11a514b67b0", `"2013-03-21T20:04:00Z"`},
+		{"c074323031332d30332d32315432303a30343a30305a", `"2013-03-21T20:04:00Z"`},
+		{"824401020304" + "42fbff", `["AQIDBA", "-_8"]`},
+		{"a2616101191f40f6", `{"a": 1, "8000": null}`},
+		{"5f42010243030405ff", `"AQIDBAU"`},
+		{"d6824401020304a1616144fbff0102", `["AQIDBA==", {"a": "+/8BAg=="}]`},
+		{"d74401020304", `"01020304"`},
+		{"7f657374726561646d696e67ff", `"streaming"`},
+		{"9f018202039f0405ffff", `[1, [2, 3], [4, 5]]`},
+		{"bf61610161629f0203ffff", `{"a": 1, "b": [2, 3]}`},
+		{"6322c2b1", `"\"±"`},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.input)
+		v, err := FromCBOR(data)
+		if err != nil {
+			t.Fatalf("FromCBOR(%s): unexpected error: %v", tt.input, err)
+		}
+		if got := compact(v); got != tt.expected {
+			t.Errorf("FromCBOR(%s): expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"", "1c", "ff", "62c3", "62c328", "5f6161ff", "9f01", "a180f6", "f818", "0101"} {
+		data, _ := hex.DecodeString(input)
+		if _, err := FromCBOR(data); err == nil {
+			t.Errorf("FromCBOR(%s): expected an error", input)
+		}
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	input := `{"name": "gojson", "tags": ["a", "b\n"], "nested": {"pi": 3.14, "n": -7, "ok": null}}`
+	data, err := ToCBOR(parse(t, input))
+	if err != nil {
+		t.Fatalf("ToCBOR: unexpected error: %v", err)
+	}
+	v, err := FromCBOR(data)
+	if err != nil {
+		t.Fatalf("FromCBOR: unexpected error: %v", err)
+	}
+	if got, expected := compact(v), compact(parse(t, input)); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestToBSON(t *testing.T) {
+	data, err := ToBSON(parse(t, `{"hello": "world"}`))
+	if err != nil {
+		t.Fatalf("ToBSON: unexpected error: %v", err)
+	}
+	if got, expected := hex.EncodeToString(data), "160000000268656c6c6f0006000000776f726c640000"; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	data, err = ToBSON(parse(t, `{"id": {"$oid": "5f1b2c3d4e5f601718191a1b"}, "n": 1, "big": 4294967296}`))
+	if err != nil {
+		t.Fatalf("ToBSON: unexpected error: %v", err)
+	}
+	expected := "2900000012626967000000000001000000" + "07696400" + "5f1b2c3d4e5f601718191a1b" + "106e000100000000"
+	if got := hex.EncodeToString(data); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	for _, input := range []string{`[1]`, `{"a\u0000": 1}`, `{"id": {"$oid": "xyz"}}`, `{"d": {"$date": "yesterday"}}`,
+		`{"n": {"$numberInt": "3000000000"}}`, `{"b": {"$binary": {"base64": "AQ=="}}}`, `{"x": {"$numberDecimal": "1.2.3"}}`} {
+		if _, err := ToBSON(parse(t, input)); err == nil {
+			t.Errorf("ToBSON(%s): expected an error", input)
+		}
+	}
+}
+
+func TestBSONRoundTrip(t *testing.T) {
+	input := `{"_id": {"$oid": "5f1b2c3d4e5f601718191a1b"}, "name": "café \"au lait\"", "n": 42, "big": 9007199254740993, ` +
+		`"pi": 3.14, "ok": true, "none": null, "tags": ["a", {"b": [1]}], ` +
+		`"at": {"$date": "2020-07-24T18:30:00.123Z"}, "old": {"$date": {"$numberLong": "-86400000"}}, ` +
+		`"bin": {"$binary": {"base64": "AQID", "subType": "00"}}, "legacy": {"$binary": {"base64": "AQID", "subType": "02"}}, ` +
+		`"re": {"$regularExpression": {"pattern": "^a", "options": "i"}}, "ts": {"$timestamp": {"t": 1595615400, "i": 3}}, ` +
+		`"price": {"$numberDecimal": "12.50"}, "inf": {"$numberDouble": "-Infinity"}, "code": {"$code": "f()", "$scope": {"x": 1}}, ` +
+		`"min": {"$minKey": 1}, "max": {"$maxKey": 1}, "sym": {"$symbol": "s"}, "u": {"$undefined": true}, "embedded": {"$other": 1}}`
+	data, err := ToBSON(parse(t, input))
+	if err != nil {
+		t.Fatalf("ToBSON: unexpected error: %v", err)
+	}
+	v, err := FromBSON(data)
+	if err != nil {
+		t.Fatalf("FromBSON: unexpected error: %v", err)
+	}
+	if got, expected := compact(unordered(v)), compact(parse(t, input)); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	docs, err := FromBSONDocuments(append(data, data...))
+	if err != nil || len(docs) != 2 {
+		t.Fatalf("FromBSONDocuments: expected 2 documents, got %d, %v", len(docs), err)
+	}
+	if _, err := FromBSON(append(data, data...)); err == nil {
+		t.Errorf("FromBSON: expected an error for two documents")
+	}
+}
+
+func TestFromBSONErrors(t *testing.T) {
+	for _, input := range []string{
+		"0500000001",                         // truncated double
+		"0600000000",                         // length beyond the data
+		"050000000000",                       // trailing byte
+		"0c000000026100ffffffff0000",         // negative string length
+		"0d0000000261000200000062000000",     // unterminated string
+		"0c0000002061000100000000",           // unknown element type
+		"0c0000000261000200000080000000"[:8], // truncated document
+		"09000000086100020000",               // invalid boolean
+	} {
+		data, _ := hex.DecodeString(input)
+		if _, err := FromBSON(data); err == nil {
+			t.Errorf("FromBSON(%s): expected an error", input)
+		}
+	}
+}
+
+func TestDecimal128(t *testing.T) {
+	for _, s := range []string{"1", "-1", "0", "-0", "12.50", "0.001234", "1E+3", "1.23E-7",
+		"1234567890123456789012345678901234", "9.999999999999999999999999999999999E+6144", "Infinity", "-Infinity", "NaN"} {
+		hi, lo, ok := parseDecimal128(s)
+		if !ok {
+			t.Errorf("parseDecimal128(%s): unexpected failure", s)
+			continue
+		}
+		if got := formatDecimal128(hi, lo); got != s {
+			t.Errorf("expected %s, got %s", s, got)
+		}
+	}
+	if hi, lo, _ := parseDecimal128("1"); hi != 0x3040000000000000 || lo != 1 {
+		t.Errorf("parseDecimal128(1): got %016x %016x", hi, lo)
+	}
+	for _, s := range []string{"", "1.2.3", "12345678901234567890123456789012345", "1E+7000", "--1"} {
+		if _, _, ok := parseDecimal128(s); ok {
+			t.Errorf("parseDecimal128(%s): expected a failure", s)
+		}
+	}
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	input := `{"name": "café \"au lait\"", "n": 42, "pi": 3.14, "ok": true, "none": null, "tags": ["a", {"b": [1, -2.5]}], "empty": {}}`
+	s, err := ToStruct(parse(t, input))
+	if err != nil {
+		t.Fatalf("ToStruct: unexpected error: %v", err)
+	}
+	if got := s.Fields["name"].GetStringValue(); got != `café "au lait"` {
+		t.Errorf("expected an unescaped string, got %q", got)
+	}
+	if got := s.Fields["tags"].GetListValue().GetValues()[1].GetStructValue().Fields["b"].GetListValue().GetValues()[1].GetNumberValue(); got != -2.5 {
+		t.Errorf("expected -2.5, got %v", got)
+	}
+	if got, expected := compact(FromStruct(s)), compact(parse(t, input)); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if _, err := ToStruct(parse(t, `[1]`)); err == nil {
+		t.Errorf("ToStruct([1]): expected an error")
+	}
+	if v := FromValue(structpb.NewNumberValue(math.Inf(1))); v != nil {
+		t.Errorf("expected null for an infinity, got %v", v)
+	}
+	if v := FromValue(structpb.NewNumberValue(1e300)); v != 1e300 {
+		t.Errorf("expected a float for a large number, got %v", v)
+	}
+}
+
+func TestFromQuery(t *testing.T) {
+	tests := []struct {
+		input, expected string
+	}{
+		{"", `{}`},
+		{"a=1&b=x+y&c=%C3%A9%26", `{"a": "1", "b": "x y", "c": "é&"}`},
+		{"tag=a&tag=b&tag=c", `{"tag": ["a", "b", "c"]}`},
+		{"user[name]=alice&user[tags][]=a&user[tags][]=b", `{"user": {"name": "alice", "tags": ["a", "b"]}}`},
+		{"a[0][b]=1&a[0][c]=2&a[1][b]=3", `{"a": [{"b": "1", "c": "2"}, {"b": "3"}]}`},
+		{"a%5B0%5D=x&flag&q=say+%22hi%22", `{"a": ["x"], "flag": "", "q": "say \"hi\""}`},
+		{"a[b=1&c]=2", `{"a[b": "1", "c]": "2"}`},
+		{"a[x]=1;a[0]=2", `{"a": {"x": "1", "0": "2"}}`},
+	}
+
+	for _, tt := range tests {
+		v, err := FromQuery([]byte(tt.input))
+		if err != nil {
+			t.Fatalf("FromQuery(%q): unexpected error: %v", tt.input, err)
+		}
+		if got := compact(v); got != tt.expected {
+			t.Errorf("FromQuery(%q):\nexpected %s\ngot      %s", tt.input, tt.expected, got)
+		}
+	}
+
+	errors := map[string]string{
+		"a=1&a[b]=2":   "convert: a[b]: a string cannot hold members",
+		"a[]=1&a[x]=2": `convert: a[x]: cannot set the member "x" of an array`,
+		"a[1]=x":       "convert: a[1]: index 1 skips elements of an array of 0",
+		"a[b]=1&a=2":   "convert: a: a is an object, not a string",
+		"a=%zz":        `convert: invalid query value "%zz": invalid URL escape "%zz"`,
+	}
+	for input, expected := range errors {
+		if _, err := FromQuery([]byte(input)); err == nil || err.Error() != expected {
+			t.Errorf("FromQuery(%q): expected error %q, got %v", input, expected, err)
+		}
+	}
+}
+
+func TestToQuery(t *testing.T) {
+	doc := parse(t, `{"q": "a b&c", "page": 2, "exact": true, "none": null, "filter": {"tags": ["x", "y"], "range": [1.5, {"max": 3}]}, "empty": [], "é": "é"}`)
+	data, err := ToQuery(doc)
+	if err != nil {
+		t.Fatalf("ToQuery: unexpected error: %v", err)
+	}
+	expected := "exact=true&filter[range][0]=1.5&filter[range][1][max]=3&filter[tags][0]=x&filter[tags][1]=y&none=&page=2&q=a+b%26c&%C3%A9=%C3%A9"
+	if string(data) != expected {
+		t.Errorf("ToQuery:\nexpected %s\ngot      %s", expected, data)
+	}
+
+	if _, err := ToQuery(parse(t, `[1]`)); err == nil {
+		t.Errorf("ToQuery([1]): expected an error")
+	}
+}
+
+func TestQueryRoundTrip(t *testing.T) {
+	input := "user[name]=alice&user[tags][0]=a&user[tags][1]=b&q=x+y%2Fz"
+	v, err := FromQuery([]byte(input))
+	if err != nil {
+		t.Fatalf("FromQuery: unexpected error: %v", err)
+	}
+	data, err := ToQuery(v)
+	if err != nil {
+		t.Fatalf("ToQuery: unexpected error: %v", err)
+	}
+	if string(data) != input {
+		t.Errorf("round trip:\nexpected %s\ngot      %s", input, data)
+	}
+}
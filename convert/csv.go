@@ -0,0 +1,139 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// CSVOptions controls the conversion between CSV and arrays of objects.
+type CSVOptions struct {
+	Comma       rune     // field delimiter, ',' when zero; use '\t' for TSV, quoted like CSV
+	InferTypes  bool     // read fields that look like numbers or booleans as such, instead of strings
+	EmptyAsNull bool     // read empty fields as null instead of empty strings
+	Columns     []string // columns to write, in order; all the keys found in the objects when empty
+}
+
+// jsonNumber matches the fields read as numbers when inferring types.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// FromCSV converts CSV data into an array of *parser.OrderedObject values,
+// one per record, keyed by the names of the header row. Every record must
+// have as many fields as the header.
+func FromCSV(data []byte, opts CSVOptions) (parser.JsonArray, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	if opts.Comma != 0 {
+		r.Comma = opts.Comma
+	}
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return parser.JsonArray{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("convert: %v", err)
+	}
+
+	records := parser.JsonArray{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("convert: %v", err)
+		}
+
+		obj := parser.NewOrderedObject()
+		for i, name := range header {
+			obj.Set(name, csvValue(record[i], opts))
+		}
+		records = append(records, obj)
+	}
+}
+
+// csvValue converts a CSV field.
+func csvValue(field string, opts CSVOptions) interface{} {
+	switch {
+	case field == "" && opts.EmptyAsNull:
+		return nil
+	case !opts.InferTypes:
+		return field
+	case field == "true":
+		return true
+	case field == "false":
+		return false
+	case jsonNumber.MatchString(field):
+		if n, err := parser.ParseNumber(field); err == nil {
+			return n
+		}
+	}
+	return field
+}
+
+// ToCSV converts an array of objects into CSV data, starting with a header
+// row. Without opts.Columns, the columns are all the keys of the objects in
+// the order they are first found, plain JsonObject maps contributing theirs
+// in sorted order. Missing members and null values are written as empty
+// fields, and arrays and objects as single-line JSON.
+func ToCSV(v interface{}, opts CSVOptions) ([]byte, error) {
+	arr, ok := v.(parser.JsonArray)
+	if !ok {
+		return nil, fmt.Errorf("convert: CSV data must be an array of objects, got %s", parser.TypeName(v))
+	}
+	for i, e := range arr {
+		if _, _, ok := parser.Members(e); !ok {
+			return nil, fmt.Errorf("convert: CSV data must be an array of objects, got %s at index %d", parser.TypeName(e), i)
+		}
+	}
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		seen := map[string]bool{}
+		for _, e := range arr {
+			keys, _, _ := parser.Members(e)
+			for _, k := range keys {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if opts.Comma != 0 {
+		w.Comma = opts.Comma
+	}
+	w.Write(columns)
+	for _, e := range arr {
+		_, members, _ := parser.Members(e)
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = csvField(members[c])
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("convert: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// csvField returns the text of a CSV field holding v.
+func csvField(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	}
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
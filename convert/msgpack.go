@@ -0,0 +1,355 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// ToMessagePack encodes a parsed JSON value as MessagePack, using the
+// smallest representation of each integer, string, array and map length.
+// Members of plain JsonObject maps are written in sorted key order.
+func ToMessagePack(v interface{}) ([]byte, error) {
+	return appendMessagePack(nil, v)
+}
+
+// appendMessagePack appends the encoding of v to b.
+func appendMessagePack(b []byte, v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if x {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int64:
+		return appendMessagePackInt(b, x), nil
+	case float64:
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(x)), nil
+	case string:
+		s := x
+		b = appendMessagePackLength(b, len(s), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		return append(b, s...), nil
+	case parser.JsonArray:
+		b = appendMessagePackLength(b, len(x), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, e := range x {
+			var err error
+			if b, err = appendMessagePack(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("convert: unsupported value of type %T", v)
+	}
+	b = appendMessagePackLength(b, len(keys), 0x80, 15, 0, 0xde, 0xdf)
+	for _, k := range keys {
+		s := k
+		b = appendMessagePackLength(b, len(s), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		b = append(b, s...)
+		var err error
+		if b, err = appendMessagePack(b, members[k]); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendMessagePackInt appends the shortest encoding of an integer.
+func appendMessagePackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return append(b, byte(i))
+	case i >= -32 && i < 0:
+		return append(b, byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+// appendMessagePackLength appends the header of a string, array or map of n
+// elements: the fix code ored with n when n <= fixMax, or the 8, 16 or 32 bit
+// code followed by n. A zero code8 means the type has no 8 bit form.
+func appendMessagePackLength(b []byte, n int, fix byte, fixMax int, code8, code16, code32 byte) []byte {
+	switch {
+	case n <= fixMax:
+		return append(b, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(b, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, code32), uint32(n))
+}
+
+// FromMessagePack decodes a single MessagePack value. Maps become
+// *parser.OrderedObject values keeping their key order, non-string keys being
+// converted to their text. Binary data becomes base64 strings, timestamps
+// RFC 3339 strings, unsigned integers beyond int64 strings of their digits,
+// and other extension types objects with "type" and base64 "data" members.
+func FromMessagePack(data []byte) (interface{}, error) {
+	d := &msgpackDecoder{data: data}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("convert: unexpected data after the MessagePack value at offset %d", d.pos)
+	}
+	return v, nil
+}
+
+// maxBinaryDepth bounds the nesting of decoded binary values.
+const maxBinaryDepth = 10000
+
+// msgpackDecoder reads MessagePack values from data.
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+// read returns the next n bytes.
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, fmt.Errorf("convert: unexpected end of MessagePack data at offset %d", len(d.data))
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+// value decodes the next value.
+func (d *msgpackDecoder) value(depth int) (interface{}, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("convert: MessagePack data nested too deeply")
+	}
+	start := d.pos
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapValue(int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.array(int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		bin, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(bin), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(int(n))
+	case 0xca:
+		n, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.uint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return strconv.FormatUint(n, 10), nil
+		}
+		return int64(n), nil
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n), depth)
+	}
+	return nil, fmt.Errorf("convert: invalid MessagePack type 0x%02x at offset %d", c, start)
+}
+
+// str decodes a string of n bytes.
+func (d *msgpackDecoder) str(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// array decodes an array of n elements.
+func (d *msgpackDecoder) array(n int, depth int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, fmt.Errorf("convert: unexpected end of MessagePack data at offset %d", len(d.data))
+	}
+	arr := make(parser.JsonArray, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+// mapValue decodes a map of n key/value pairs.
+func (d *msgpackDecoder) mapValue(n int, depth int) (interface{}, error) {
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, fmt.Errorf("convert: unexpected end of MessagePack data at offset %d", len(d.data))
+	}
+	obj := parser.NewOrderedObject()
+	for i := 0; i < n; i++ {
+		at := d.pos
+		k, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		key, err := keyText(k)
+		if err != nil {
+			return nil, fmt.Errorf("convert: %v at offset %d", err, at)
+		}
+		v, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(key, v)
+	}
+	return obj, nil
+}
+
+// ext decodes an extension value of n bytes, after its type.
+func (d *msgpackDecoder) ext(n int) (interface{}, error) {
+	t, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if int8(t[0]) == -1 { // timestamp
+		var sec int64
+		var nsec uint32
+		switch n {
+		case 4:
+			sec = int64(binary.BigEndian.Uint32(b))
+		case 8:
+			u := binary.BigEndian.Uint64(b)
+			sec, nsec = int64(u&(1<<34-1)), uint32(u>>34)
+		case 12:
+			nsec, sec = binary.BigEndian.Uint32(b), int64(binary.BigEndian.Uint64(b[4:]))
+		default:
+			return nil, fmt.Errorf("convert: invalid MessagePack timestamp of %d bytes", n)
+		}
+		return time.Unix(sec, int64(nsec)).UTC().Format(time.RFC3339Nano), nil
+	}
+
+	obj := parser.NewOrderedObject()
+	obj.Set("type", int64(int8(t[0])))
+	obj.Set("data", base64.StdEncoding.EncodeToString(b))
+	return obj, nil
+}
+
+// keyText returns the object key for a decoded map key: strings are kept,
+// numbers and booleans converted to their text.
+func keyText(k interface{}) (string, error) {
+	switch x := k.(type) {
+	case string:
+		return x, nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case nil:
+		return "null", nil
+	}
+	return "", fmt.Errorf("unsupported %s map key", parser.TypeName(k))
+}
@@ -0,0 +1,197 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// querySegment is a part of the key of a URL query parameter: a member name,
+// an array index, or [] appending to an array.
+type querySegment struct {
+	name   string
+	index  int  // for array indexes, -1 for []
+	member bool // whether the segment is a member name
+}
+
+// FromQuery converts a URL-encoded query string or form, such as
+// "user[name]=alice&user[tags][]=a&user[tags][]=b", into a
+// *parser.OrderedObject. Keys use the bracket syntax of web frameworks:
+// "a[b]" is the member b of the object a, "a[0]" the first element of the
+// array a and "a[]" an element appended to it. Repeated keys without brackets
+// form arrays. Values are strings. Indexes must not skip elements, so that
+// "a[1]=x" alone is an error.
+func FromQuery(data []byte) (interface{}, error) {
+	root := parser.NewOrderedObject()
+	for _, pair := range strings.FieldsFunc(strings.TrimSpace(string(data)), func(r rune) bool { return r == '&' || r == ';' }) {
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("convert: invalid query key %q: %v", rawKey, err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("convert: invalid query value %q: %v", rawValue, err)
+		}
+		if key == "" {
+			continue
+		}
+		segments := parseQueryKey(key)
+		if _, err := setQueryValue(root, segments, value); err != nil {
+			return nil, fmt.Errorf("convert: %s: %v", key, err)
+		}
+	}
+	return root, nil
+}
+
+// parseQueryKey splits a key into its segments. Brackets that are not closed
+// are part of the name they follow.
+func parseQueryKey(key string) []querySegment {
+	base, rest := key, ""
+	if i := strings.IndexByte(key, '['); i > 0 {
+		base, rest = key[:i], key[i:]
+	}
+	segments := []querySegment{{name: base, member: true}}
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			segments[len(segments)-1].name += rest
+			break
+		}
+		inner := rest[1:end]
+		rest = rest[end+1:]
+		if inner == "" {
+			segments = append(segments, querySegment{index: -1})
+		} else if i, err := strconv.Atoi(inner); err == nil && i >= 0 && strconv.Itoa(i) == inner {
+			segments = append(segments, querySegment{index: i})
+		} else {
+			segments = append(segments, querySegment{name: inner, member: true})
+		}
+	}
+	return segments
+}
+
+// setQueryValue stores value at the location designated by segments in
+// container, which is nil when it does not exist yet, and returns the
+// possibly created or reallocated container.
+func setQueryValue(container interface{}, segments []querySegment, value interface{}) (interface{}, error) {
+	s := segments[0]
+	if container == nil {
+		if s.member {
+			container = parser.NewOrderedObject()
+		} else {
+			container = parser.JsonArray{}
+		}
+	}
+
+	switch c := container.(type) {
+	case *parser.OrderedObject:
+		name := s.name
+		if !s.member {
+			if s.index < 0 {
+				return nil, fmt.Errorf("cannot append to an object")
+			}
+			name = strconv.Itoa(s.index)
+		}
+		existing, ok := c.Get(name)
+		if len(segments) == 1 {
+			switch e := existing.(type) {
+			case parser.JsonArray:
+				value = append(e, value)
+			case string:
+				value = parser.JsonArray{e, value}
+			default:
+				if ok {
+					return nil, fmt.Errorf("%s is an %s, not a string", s.name, parser.TypeName(existing))
+				}
+			}
+			c.Set(name, value)
+			return c, nil
+		}
+		child, err := setQueryValue(existing, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		c.Set(name, child)
+		return c, nil
+	case parser.JsonArray:
+		if s.member {
+			return nil, fmt.Errorf("cannot set the member %q of an array", s.name)
+		}
+		index := s.index
+		switch {
+		case index < 0:
+			index = len(c)
+		case index > len(c):
+			return nil, fmt.Errorf("index %d skips elements of an array of %d", index, len(c))
+		}
+		if index == len(c) {
+			c = append(c, nil)
+		}
+		if len(segments) == 1 {
+			c[index] = value
+			return c, nil
+		}
+		child, err := setQueryValue(c[index], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		c[index] = child
+		return c, nil
+	}
+	return nil, fmt.Errorf("a %s cannot hold members", parser.TypeName(container))
+}
+
+// ToQuery converts an object into a URL-encoded query string, using the
+// bracket syntax FromQuery reads: nested members are written as "a[b]=" and
+// array elements as "a[0]=". Strings are written without quotes, numbers and
+// booleans as in JSON and null as an empty value, while empty arrays and
+// objects, which cannot be represented, are left out. Members of plain
+// JsonObject maps are written in sorted key order.
+func ToQuery(v interface{}) ([]byte, error) {
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("convert: a query string must be built from an object, got %s", parser.TypeName(v))
+	}
+	var buf bytes.Buffer
+	for _, k := range keys {
+		appendQuery(&buf, url.QueryEscape(k), members[k])
+	}
+	return buf.Bytes(), nil
+}
+
+// appendQuery appends the parameters of v, found at key, to buf.
+func appendQuery(buf *bytes.Buffer, key string, v interface{}) {
+	if keys, members, ok := parser.Members(v); ok {
+		for _, k := range keys {
+			appendQuery(buf, key+"["+url.QueryEscape(k)+"]", members[k])
+		}
+		return
+	}
+	if arr, ok := v.(parser.JsonArray); ok {
+		for i, e := range arr {
+			appendQuery(buf, key+"["+strconv.Itoa(i)+"]", e)
+		}
+		return
+	}
+
+	var value string
+	switch x := v.(type) {
+	case string:
+		value = x
+	case int64:
+		value = strconv.FormatInt(x, 10)
+	case float64:
+		value = strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		value = strconv.FormatBool(x)
+	}
+	if buf.Len() > 0 {
+		buf.WriteByte('&')
+	}
+	buf.WriteString(key + "=" + url.QueryEscape(value))
+}
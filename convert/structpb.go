@@ -0,0 +1,106 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// ToStruct converts a parsed JSON object into a google.protobuf.Struct, as
+// used by gRPC services for free-form fields.
+func ToStruct(v interface{}) (*structpb.Struct, error) {
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("convert: a Struct must be an object, got %s", parser.TypeName(v))
+	}
+	s := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(keys))}
+	for _, k := range keys {
+		value, err := ToValue(members[k])
+		if err != nil {
+			return nil, err
+		}
+		s.Fields[k] = value
+	}
+	return s, nil
+}
+
+// ToValue converts a parsed JSON value into a google.protobuf.Value. Struct
+// numbers are doubles, so integers beyond 2^53 lose precision.
+func ToValue(v interface{}) (*structpb.Value, error) {
+	switch x := v.(type) {
+	case nil:
+		return structpb.NewNullValue(), nil
+	case bool:
+		return structpb.NewBoolValue(x), nil
+	case int64:
+		return structpb.NewNumberValue(float64(x)), nil
+	case float64:
+		return structpb.NewNumberValue(x), nil
+	case string:
+		return structpb.NewStringValue(x), nil
+	case parser.JsonArray:
+		list := &structpb.ListValue{Values: make([]*structpb.Value, len(x))}
+		for i, e := range x {
+			value, err := ToValue(e)
+			if err != nil {
+				return nil, err
+			}
+			list.Values[i] = value
+		}
+		return structpb.NewListValue(list), nil
+	}
+
+	if _, _, ok := parser.Members(v); !ok {
+		return nil, fmt.Errorf("convert: unsupported value of type %T", v)
+	}
+	s, err := ToStruct(v)
+	if err != nil {
+		return nil, err
+	}
+	return structpb.NewStructValue(s), nil
+}
+
+// FromStruct converts a google.protobuf.Struct into a parser.JsonObject, its
+// fields having no order, converted as FromValue does.
+func FromStruct(s *structpb.Struct) parser.JsonObject {
+	obj := make(parser.JsonObject, len(s.GetFields()))
+	for k, v := range s.GetFields() {
+		obj[k] = FromValue(v)
+	}
+	return obj
+}
+
+// FromValue converts a google.protobuf.Value into a parsed JSON value.
+// Numbers holding integers exactly representable as doubles become int64
+// values, others float64 values. Unset values, infinities and NaN, which JSON
+// cannot hold, become null.
+func FromValue(v *structpb.Value) interface{} {
+	switch k := v.GetKind().(type) {
+	case *structpb.Value_BoolValue:
+		return k.BoolValue
+	case *structpb.Value_NumberValue:
+		f := k.NumberValue
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil
+		}
+		if f == math.Trunc(f) && math.Abs(f) <= 1<<53 {
+			return int64(f)
+		}
+		return f
+	case *structpb.Value_StringValue:
+		return k.StringValue
+	case *structpb.Value_ListValue:
+		values := k.ListValue.GetValues()
+		arr := make(parser.JsonArray, len(values))
+		for i, e := range values {
+			arr[i] = FromValue(e)
+		}
+		return arr
+	case *structpb.Value_StructValue:
+		return FromStruct(k.StructValue)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package convert
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// FromTOML converts a TOML document into a *parser.OrderedObject keeping the
+// key order of the document. Dates and times become strings in their TOML
+// form, arrays of tables become arrays of objects, and infinities and NaN
+// become strings.
+func FromTOML(data []byte) (interface{}, error) {
+	var doc map[string]interface{}
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return nil, fmt.Errorf("convert: %v", err)
+	}
+
+	// Keys lists every key path in document order, array table elements
+	// sharing the path of their array.
+	order := map[string]int{}
+	for i, k := range md.Keys() {
+		path := k.String()
+		if _, ok := order[path]; !ok {
+			order[path] = i
+		}
+	}
+	return fromTOMLValue(doc, "", order), nil
+}
+
+// fromTOMLValue converts a decoded TOML value found at path.
+func fromTOMLValue(v interface{}, path string, order map[string]int) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			return order[tomlPath(path, keys[i])] < order[tomlPath(path, keys[j])]
+		})
+		obj := parser.NewOrderedObject()
+		for _, k := range keys {
+			obj.Set(k, fromTOMLValue(x[k], tomlPath(path, k), order))
+		}
+		return obj
+	case []map[string]interface{}:
+		arr := make(parser.JsonArray, len(x))
+		for i, e := range x {
+			arr[i] = fromTOMLValue(e, path, order)
+		}
+		return arr
+	case []interface{}:
+		arr := make(parser.JsonArray, len(x))
+		for i, e := range x {
+			arr[i] = fromTOMLValue(e, path, order)
+		}
+		return arr
+	case string:
+		return x
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return strconv.FormatFloat(x, 'g', -1, 64)
+		}
+		return x
+	case time.Time:
+		return formatTOMLTime(x)
+	}
+	return v // int64 and bool
+}
+
+// tomlPath returns the key path of key inside the table at path, in the form
+// used by toml.Key.String.
+func tomlPath(path, key string) string {
+	k := toml.Key{key}.String()
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
+
+// formatTOMLTime returns a TOML date or time in the form it was written,
+// local dates and times having no offset.
+func formatTOMLTime(t time.Time) string {
+	switch t.Location().String() {
+	case "date-local":
+		return t.Format("2006-01-02")
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// ToTOML converts a parsed JSON object into a TOML document. Nested objects
+// become tables and arrays of objects become arrays of tables. Members of
+// plain JsonObject maps are written in sorted key order. TOML has no null,
+// so null values are reported as errors.
+func ToTOML(v interface{}) ([]byte, error) {
+	if _, _, ok := parser.Members(v); !ok {
+		return nil, fmt.Errorf("convert: a TOML document must be an object, got %s", parser.TypeName(v))
+	}
+	var result strings.Builder
+	if err := writeTOMLTable(&result, nil, v); err != nil {
+		return nil, err
+	}
+	return []byte(result.String()), nil
+}
+
+// writeTOMLTable writes the members of the table obj found at path: first its
+// key/value pairs, then its subtables and arrays of tables.
+func writeTOMLTable(w *strings.Builder, path []string, obj interface{}) error {
+	keys, members, _ := parser.Members(obj)
+	for _, k := range keys {
+		if isTOMLTable(members[k]) || isTOMLTableArray(members[k]) {
+			continue
+		}
+		w.WriteString(tomlKey(k) + " = ")
+		if err := writeTOMLValue(w, append(path, k), members[k]); err != nil {
+			return err
+		}
+		w.WriteByte('\n')
+	}
+
+	for _, k := range keys {
+		sub := append(append([]string{}, path...), k)
+		switch {
+		case isTOMLTable(members[k]):
+			writeTOMLHeader(w, "[", sub, "]")
+			if err := writeTOMLTable(w, sub, members[k]); err != nil {
+				return err
+			}
+		case isTOMLTableArray(members[k]):
+			for _, e := range members[k].(parser.JsonArray) {
+				writeTOMLHeader(w, "[[", sub, "]]")
+				if err := writeTOMLTable(w, sub, e); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeTOMLHeader writes a table or array of tables header, separated from
+// what precedes it by an empty line.
+func writeTOMLHeader(w *strings.Builder, open string, path []string, close string) {
+	if w.Len() > 0 {
+		w.WriteByte('\n')
+	}
+	quoted := make([]string, len(path))
+	for i, k := range path {
+		quoted[i] = tomlKey(k)
+	}
+	w.WriteString(open + strings.Join(quoted, ".") + close + "\n")
+}
+
+// writeTOMLValue writes v as an inline TOML value.
+func writeTOMLValue(w *strings.Builder, path []string, v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		return fmt.Errorf("convert: %s: TOML cannot represent null", strings.Join(path, "."))
+	case bool:
+		w.WriteString(strconv.FormatBool(x))
+	case int64:
+		w.WriteString(strconv.FormatInt(x, 10))
+	case float64:
+		w.WriteString(formatFloat(x))
+	case string:
+		w.WriteString(tomlString(x))
+	case parser.JsonArray:
+		w.WriteByte('[')
+		for i, e := range x {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			if err := writeTOMLValue(w, append(path, strconv.Itoa(i)), e); err != nil {
+				return err
+			}
+		}
+		w.WriteByte(']')
+	default:
+		keys, members, ok := parser.Members(v)
+		if !ok {
+			return fmt.Errorf("convert: unsupported value of type %T", v)
+		}
+		if len(keys) == 0 {
+			w.WriteString("{}")
+			return nil
+		}
+		w.WriteString("{ ")
+		for i, k := range keys {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(tomlKey(k) + " = ")
+			if err := writeTOMLValue(w, append(path, k), members[k]); err != nil {
+				return err
+			}
+		}
+		w.WriteString(" }")
+	}
+	return nil
+}
+
+// isTOMLTable reports whether v is written as a table.
+func isTOMLTable(v interface{}) bool {
+	_, _, ok := parser.Members(v)
+	return ok
+}
+
+// isTOMLTableArray reports whether v is a non-empty array of objects, written
+// as an array of tables.
+func isTOMLTableArray(v interface{}) bool {
+	arr, ok := v.(parser.JsonArray)
+	if !ok || len(arr) == 0 {
+		return false
+	}
+	for _, e := range arr {
+		if !isTOMLTable(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlKey returns k as a bare key when possible, or as a quoted key.
+func tomlKey(k string) string {
+	if k == "" {
+		return `""`
+	}
+	for _, c := range k {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return tomlString(k)
+		}
+	}
+	return k
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var result strings.Builder
+	result.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			result.WriteByte('\\')
+			result.WriteRune(r)
+		case r == '\n':
+			result.WriteString(`\n`)
+		case r == '\r':
+			result.WriteString(`\r`)
+		case r == '\t':
+			result.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&result, `\u%04x`, r)
+		default:
+			result.WriteRune(r)
+		}
+	}
+	result.WriteByte('"')
+	return result.String()
+}
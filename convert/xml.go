@@ -0,0 +1,221 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// FromXML converts an XML document into an object holding its root element.
+// Each element becomes an object whose attributes are members named "@" plus
+// the attribute name and whose text is the "#text" member; elements with
+// neither attributes nor children become their text, or null when empty.
+// Repeated child elements become arrays. Text is trimmed of surrounding white
+// space and kept as a string. Namespaces, comments and processing instructions
+// are dropped.
+func FromXML(data []byte) (interface{}, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var root *parser.OrderedObject
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("convert: %v", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil {
+				return nil, fmt.Errorf("convert: unexpected element <%s> after the root element", t.Name.Local)
+			}
+			v, err := readXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			root = parser.NewOrderedObject()
+			root.Set(t.Name.Local, v)
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return nil, fmt.Errorf("convert: unexpected text outside of the root element")
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("convert: no root element")
+	}
+	return root, nil
+}
+
+// readXMLElement converts the element opened by start, reading up to its end.
+func readXMLElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	obj := parser.NewOrderedObject()
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" && a.Name.Space == "" {
+			continue
+		}
+		obj.Set("@"+a.Name.Local, a.Value)
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("convert: %v", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			if existing, ok := obj.Values[name]; ok {
+				// Elements are never arrays, so an array holds repeated elements.
+				if arr, ok := existing.(parser.JsonArray); ok {
+					obj.Set(name, append(arr, child))
+				} else {
+					obj.Set(name, parser.JsonArray{existing, child})
+				}
+			} else {
+				obj.Set(name, child)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if obj.Len() == 0 {
+				if content == "" {
+					return nil, nil
+				}
+				return content, nil
+			}
+			if content != "" {
+				obj.Set("#text", content)
+			}
+			return obj, nil
+		}
+	}
+}
+
+// ToXML converts a parsed JSON value into an indented XML document, following
+// the conventions of FromXML. An object with a single member is the root
+// element, other values are wrapped in a <root> element. Arrays become
+// repeated elements, and numbers, booleans and nested arrays become text.
+func ToXML(v interface{}) ([]byte, error) {
+	name, value := "root", v
+	if keys, members, ok := parser.Members(v); ok && len(keys) == 1 && !strings.HasPrefix(keys[0], "@") && keys[0] != "#text" {
+		if _, isArray := members[keys[0]].(parser.JsonArray); !isArray {
+			name, value = keys[0], members[keys[0]]
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := writeXMLElement(enc, name, value); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, fmt.Errorf("convert: %v", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// writeXMLElement writes the element name holding v.
+func writeXMLElement(enc *xml.Encoder, name string, v interface{}) error {
+	if !isXMLName(name) {
+		return fmt.Errorf("convert: %q is not a valid XML element name", name)
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	keys, members, isObject := parser.Members(v)
+	if !isObject {
+		if err := encodeXMLToken(enc, start); err != nil {
+			return err
+		}
+		if v != nil {
+			if err := encodeXMLToken(enc, xml.CharData(xmlText(v))); err != nil {
+				return err
+			}
+		}
+		return encodeXMLToken(enc, start.End())
+	}
+
+	for _, k := range keys {
+		if attr := k; strings.HasPrefix(attr, "@") {
+			if !isXMLName(attr[1:]) {
+				return fmt.Errorf("convert: %q is not a valid XML attribute name", attr[1:])
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attr[1:]}, Value: xmlText(members[k])})
+		}
+	}
+	if err := encodeXMLToken(enc, start); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		child := k
+		switch {
+		case strings.HasPrefix(child, "@"):
+		case child == "#text":
+			if err := encodeXMLToken(enc, xml.CharData(xmlText(members[k]))); err != nil {
+				return err
+			}
+		default:
+			elements, ok := members[k].(parser.JsonArray)
+			if !ok {
+				elements = parser.JsonArray{members[k]}
+			}
+			for _, e := range elements {
+				if err := writeXMLElement(enc, child, e); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return encodeXMLToken(enc, start.End())
+}
+
+// encodeXMLToken writes a token, wrapping the error.
+func encodeXMLToken(enc *xml.Encoder, t xml.Token) error {
+	if err := enc.EncodeToken(t); err != nil {
+		return fmt.Errorf("convert: %v", err)
+	}
+	return nil
+}
+
+// xmlText returns the text of a scalar, arrays and objects being written as
+// single-line JSON.
+func xmlText(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	}
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+// isXMLName reports whether name is usable as an element or attribute name.
+// Names may not contain colons, since namespaces are not supported.
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		letter := r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r > 0x7f
+		if i == 0 && !letter || !letter && !(r == '-' || r == '.' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+// Package convert translates between documents of the parser package and
+// other data formats.
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// FromYAML converts the first YAML document of data into a parsed JSON value.
+// Mappings become *parser.OrderedObject values keeping their key order,
+// sequences become parser.JsonArray values and aliases are expanded. Scalars
+// that JSON cannot represent as such, like timestamps, binary data, infinities
+// or integers beyond int64, become strings, as do non-string mapping keys.
+func FromYAML(data []byte) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("convert: %v", err)
+	}
+	if doc.Kind == 0 {
+		return nil, nil // empty document
+	}
+	return fromYAMLNode(&doc, 0)
+}
+
+// maxYAMLDepth bounds the nesting of expanded aliases, which could otherwise
+// grow exponentially or loop.
+const maxYAMLDepth = 10000
+
+// fromYAMLNode converts a YAML node.
+func fromYAMLNode(n *yaml.Node, depth int) (interface{}, error) {
+	if depth > maxYAMLDepth {
+		return nil, fmt.Errorf("convert: line %d: document nested too deeply", n.Line)
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		return fromYAMLNode(n.Content[0], depth+1)
+	case yaml.AliasNode:
+		return fromYAMLNode(n.Alias, depth+1)
+	case yaml.SequenceNode:
+		arr := make(parser.JsonArray, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := fromYAMLNode(c, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case yaml.MappingNode:
+		obj := parser.NewOrderedObject()
+		if err := mergeYAMLMapping(obj, n, depth); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	}
+	return fromYAMLScalar(n)
+}
+
+// mergeYAMLMapping sets the members of the mapping n in obj. Members of maps
+// merged with the "<<" key are set first, so that the mapping overrides them.
+func mergeYAMLMapping(obj *parser.OrderedObject, n *yaml.Node, depth int) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Tag != "!!merge" {
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, s := range sources {
+			for s.Kind == yaml.AliasNode {
+				s = s.Alias
+			}
+			if s.Kind != yaml.MappingNode {
+				return fmt.Errorf("convert: line %d: only mappings can be merged", s.Line)
+			}
+			if err := mergeYAMLMapping(obj, s, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Tag == "!!merge" {
+			continue
+		}
+		for key.Kind == yaml.AliasNode {
+			key = key.Alias
+		}
+		if key.Kind != yaml.ScalarNode {
+			return fmt.Errorf("convert: line %d: mapping keys must be scalars", key.Line)
+		}
+		v, err := fromYAMLNode(value, depth+1)
+		if err != nil {
+			return err
+		}
+		obj.Set(key.Value, v)
+	}
+	return nil
+}
+
+// fromYAMLScalar converts a YAML scalar according to its resolved tag.
+func fromYAMLScalar(n *yaml.Node) (interface{}, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, fmt.Errorf("convert: line %d: %v", n.Line, err)
+		}
+		return b, nil
+	case "!!int":
+		var i int64
+		if err := n.Decode(&i); err == nil {
+			return i, nil
+		}
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, fmt.Errorf("convert: line %d: %v", n.Line, err)
+		}
+		// Integers too large for int64 resolve as floats, keep their digits.
+		if !math.IsInf(f, 0) && !math.IsNaN(f) && strings.ContainsAny(n.Value, ".eE") {
+			return f, nil
+		}
+	}
+	return n.Value, nil
+}
+
+// ToYAML converts a parsed JSON value into a YAML document. Members of plain
+// JsonObject maps are written in sorted key order.
+func ToYAML(v interface{}) ([]byte, error) {
+	n, err := toYAMLNode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, fmt.Errorf("convert: %v", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("convert: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// toYAMLNode converts a parsed JSON value into a YAML node.
+func toYAMLNode(v interface{}) (*yaml.Node, error) {
+	switch x := v.(type) {
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(x)}, nil
+	case int64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatInt(x, 10)}, nil
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: formatFloat(x)}, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: x}, nil
+	case parser.JsonArray:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range x {
+			c, err := toYAMLNode(e)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, c)
+		}
+		return n, nil
+	}
+
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("convert: unsupported value of type %T", v)
+	}
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, k := range keys {
+		value, err := toYAMLNode(members[k])
+		if err != nil {
+			return nil, err
+		}
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, value)
+	}
+	return n, nil
+}
+
+// formatFloat returns the shortest text of f that reads back as a float.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,63 @@
+package gojson
+
+import (
+	"io"
+
+	"github.com/oabrivard/gojson/stream"
+)
+
+// Delim is one of the JSON delimiters '{', '}', '[' and ']'.
+type Delim rune
+
+func (d Delim) String() string {
+	return string(d)
+}
+
+// Token is a token of a document returned by Decoder.Token: a Delim for the
+// starts and ends of objects and arrays, a string for keys and strings, an
+// int64 or a float64 for numbers, a bool for true and false, or nil for
+// null. Commas and colons are checked but not returned.
+type Token interface{}
+
+// A Decoder reads the tokens of a JSON document from an input stream, one at
+// a time, like encoding/json's Decoder.Token, so that tools can process
+// documents their own way without holding them in memory.
+type Decoder struct {
+	r            *stream.Reader // reader of the events of the document
+	line, column int            // position of the last token returned
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: stream.NewReader(r)}
+}
+
+// Token returns the next token of the document, strings being decoded. It
+// returns io.EOF once the whole document has been read, which must be a
+// single JSON value, and a *stream.SyntaxError if it is invalid, after which
+// every call returns the same error.
+func (d *Decoder) Token() (Token, error) {
+	e, err := d.r.Next()
+	if err != nil {
+		return nil, err
+	}
+	d.line, d.column = e.Line, e.Column
+
+	switch e.Kind {
+	case stream.ObjectStart:
+		return Delim('{'), nil
+	case stream.ObjectEnd:
+		return Delim('}'), nil
+	case stream.ArrayStart:
+		return Delim('['), nil
+	case stream.ArrayEnd:
+		return Delim(']'), nil
+	}
+	return e.Value, nil
+}
+
+// Position returns the line and the column of the last token returned by
+// Token, as the lexer locates tokens, or 0, 0 before the first one.
+func (d *Decoder) Position() (line, column int) {
+	return d.line, d.column
+}
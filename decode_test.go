@@ -0,0 +1,50 @@
+package gojson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/stream"
+)
+
+func TestDecoderToken(t *testing.T) {
+	d := NewDecoder(strings.NewReader("{\"a\\nb\": [1, 2.5, \"x\\u00e9\"],\n \"c\": {}, \"d\": true, \"e\": null}"))
+	expected := []Token{
+		Delim('{'), "a\nb", Delim('['), int64(1), 2.5, "xé", Delim(']'),
+		"c", Delim('{'), Delim('}'), "d", true, "e", nil, Delim('}'),
+	}
+	var got []Token
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, tok)
+		if tok == "c" {
+			if line, _ := d.Position(); line != 2 {
+				t.Errorf("expected \"c\" on line 2, got %d", line)
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if s := fmt.Sprint(Delim('[')); s != "[" {
+		t.Errorf("expected [, got %s", s)
+	}
+
+	d = NewDecoder(strings.NewReader(`[1 2]`))
+	d.Token()
+	d.Token()
+	var syntax *stream.SyntaxError
+	if _, err := d.Token(); !errors.As(err, &syntax) {
+		t.Errorf("expected a *stream.SyntaxError, got %v", err)
+	}
+}
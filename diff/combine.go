@@ -0,0 +1,110 @@
+package diff
+
+import (
+	"fmt"
+
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Strategy tells MergeAll which value to keep when documents hold different
+// values at the same location.
+type Strategy int
+
+const (
+	KeepLast  Strategy = iota // the values of later documents replace those of earlier ones
+	KeepFirst                 // the values of earlier documents are kept
+	Fail                      // different values are reported as a *ConflictError
+)
+
+// MergeOptions controls how MergeAll combines documents.
+type MergeOptions struct {
+	Strategy Strategy
+	// AppendArrays concatenates the arrays found at the same location
+	// instead of resolving them by Strategy.
+	AppendArrays bool
+}
+
+// ConflictError is returned by MergeAll, with the Fail strategy, for a value
+// of a document differing from the value earlier documents hold.
+type ConflictError struct {
+	Path  pointer.Pointer
+	Index int         // index of the document holding New
+	Old   interface{} // value merged from the earlier documents
+	New   interface{}
+}
+
+func (e *ConflictError) Error() string {
+	path := e.Path.String()
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("diff: conflicting values at %s: %s and %s", path, linter.Format(e.Old, inline), linter.Format(e.New, inline))
+}
+
+// MergeAll deep-merges docs, in order, into a single document, as when
+// aggregating configuration fragments: objects are merged member by member,
+// the merged object listing the members in the order they first appear, and
+// other values found at the same location are resolved according to opts
+// unless they are equal. The merged document shares no values with docs, and
+// is nil when docs is empty.
+func MergeAll(docs []interface{}, opts MergeOptions) (interface{}, error) {
+	var merged interface{}
+	for i, doc := range docs {
+		if i == 0 {
+			merged = clone(doc)
+			continue
+		}
+		var err error
+		if merged, err = mergeInto(pointer.Pointer{}, i, merged, doc, opts); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+// mergeInto returns the merge of v, found at path in the document of the
+// given index, into merged, the value of the earlier documents.
+func mergeInto(path pointer.Pointer, index int, merged, v interface{}, opts MergeOptions) (interface{}, error) {
+	mergedKeys, mergedMembers, isObject := parser.Members(merged)
+	keys, values, ok := parser.Members(v)
+	if isObject && ok {
+		obj := parser.NewOrderedObject()
+		for _, k := range mergedKeys {
+			obj.Set(k, mergedMembers[k])
+		}
+		for _, k := range keys {
+			previous, ok := obj.Get(k)
+			if !ok {
+				obj.Set(k, clone(values[k]))
+				continue
+			}
+			member, err := mergeInto(path.Append(k), index, previous, values[k], opts)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(k, member)
+		}
+		if _, ok := merged.(parser.JsonObject); ok {
+			return obj.Values, nil
+		}
+		return obj, nil
+	}
+
+	if a, ok := merged.(parser.JsonArray); ok && opts.AppendArrays {
+		if b, ok := v.(parser.JsonArray); ok {
+			return append(a, clone(b).(parser.JsonArray)...), nil
+		}
+	}
+	if equal(merged, v) {
+		return merged, nil
+	}
+	switch opts.Strategy {
+	case KeepFirst:
+		return merged, nil
+	case Fail:
+		return nil, &ConflictError{Path: path, Index: index, Old: merged, New: clone(v)}
+	}
+	return clone(v), nil
+}
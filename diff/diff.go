@@ -0,0 +1,256 @@
+// Package diff computes the structural differences between two documents
+// produced by the parser package and renders them for humans or as a JSON
+// Patch (RFC 6902). It also merges the changes two documents made to a common
+// base, reporting the conflicting ones, and deep-merges any number of
+// documents into one.
+package diff
+
+import (
+	"strconv"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Kind is the kind of a Change.
+type Kind int
+
+const (
+	Added   Kind = iota // the value only exists in the new document
+	Removed             // the value only exists in the old document
+	Changed             // the value exists in both documents with different contents
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change is a single difference between two documents. Old is nil for
+// additions and New is nil for removals.
+type Change struct {
+	Path pointer.Pointer
+	Kind Kind
+	Old  interface{}
+	New  interface{}
+}
+
+// Options controls how documents are compared.
+type Options struct {
+	IgnoreArrayOrder bool              // compare arrays as multisets of elements
+	IgnorePaths      []pointer.Pointer // values at these paths, and below, are not compared
+}
+
+// Compare returns the changes turning old into new, comparing arrays element
+// by element.
+func Compare(old, new interface{}) []Change {
+	return CompareWithOptions(old, new, Options{})
+}
+
+// CompareWithOptions returns the changes turning old into new according to
+// opts. Object members are reported in the order of the old document, followed
+// by the members only found in the new one.
+func CompareWithOptions(old, new interface{}, opts Options) []Change {
+	c := &comparer{opts: opts}
+	c.compare(pointer.Pointer{}, old, new)
+	return c.changes
+}
+
+// comparer accumulates the changes found while walking two documents.
+type comparer struct {
+	opts    Options
+	changes []Change
+}
+
+// add records a change unless its path is ignored.
+func (c *comparer) add(path pointer.Pointer, kind Kind, old, new interface{}) {
+	if c.ignored(path) {
+		return
+	}
+	c.changes = append(c.changes, Change{Path: path, Kind: kind, Old: old, New: new})
+}
+
+// ignored reports whether path is, or is below, one of the ignored paths.
+func (c *comparer) ignored(path pointer.Pointer) bool {
+	for _, p := range c.opts.IgnorePaths {
+		if len(p) > len(path) {
+			continue
+		}
+		match := true
+		for i := range p {
+			if p[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// compare records the changes between two values found at path.
+func (c *comparer) compare(path pointer.Pointer, old, new interface{}) {
+	if c.ignored(path) {
+		return
+	}
+
+	if oldKeys, oldMembers, ok := parser.Members(old); ok {
+		if newKeys, newMembers, ok := parser.Members(new); ok {
+			c.compareObjects(path, oldKeys, oldMembers, newKeys, newMembers)
+			return
+		}
+	}
+	if oldArray, ok := old.(parser.JsonArray); ok {
+		if newArray, ok := new.(parser.JsonArray); ok {
+			if c.opts.IgnoreArrayOrder {
+				c.compareUnordered(path, oldArray, newArray)
+			} else {
+				c.compareArrays(path, oldArray, newArray)
+			}
+			return
+		}
+	}
+	if !equal(old, new) {
+		c.add(path, Changed, old, new)
+	}
+}
+
+// compareObjects records the changes between the members of two objects.
+func (c *comparer) compareObjects(path pointer.Pointer, oldKeys []string, oldMembers map[string]interface{}, newKeys []string, newMembers map[string]interface{}) {
+	for _, k := range oldKeys {
+		if v, ok := newMembers[k]; ok {
+			c.compare(path.Append(k), oldMembers[k], v)
+		} else {
+			c.add(path.Append(k), Removed, oldMembers[k], nil)
+		}
+	}
+	for _, k := range newKeys {
+		if _, ok := oldMembers[k]; !ok {
+			c.add(path.Append(k), Added, nil, newMembers[k])
+		}
+	}
+}
+
+// compareArrays records the changes between two arrays compared index by
+// index. Trailing removals are listed from the last index down, so that
+// applying the changes in order never shifts an index still to be removed.
+func (c *comparer) compareArrays(path pointer.Pointer, old, new parser.JsonArray) {
+	common := min(len(old), len(new))
+	for i := 0; i < common; i++ {
+		c.compare(path.Append(strconv.Itoa(i)), old[i], new[i])
+	}
+	for i := common; i < len(new); i++ {
+		c.add(path.Append(strconv.Itoa(i)), Added, nil, new[i])
+	}
+	for i := len(old) - 1; i >= common; i-- {
+		c.add(path.Append(strconv.Itoa(i)), Removed, old[i], nil)
+	}
+}
+
+// compareUnordered records the elements of old missing from new, at their
+// index in old, and the elements of new missing from old, at their index in
+// new. Each element of one array matches at most one equal element of the
+// other.
+func (c *comparer) compareUnordered(path pointer.Pointer, old, new parser.JsonArray) {
+	matched := make([]bool, len(new))
+	var removed []int
+	for i, o := range old {
+		found := false
+		for j, n := range new {
+			if !matched[j] && equal(o, n) {
+				matched[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, i)
+		}
+	}
+
+	for i := len(removed) - 1; i >= 0; i-- {
+		c.add(path.Append(strconv.Itoa(removed[i])), Removed, old[removed[i]], nil)
+	}
+	for j, n := range new {
+		if !matched[j] {
+			c.add(path.Append(strconv.Itoa(j)), Added, nil, n)
+		}
+	}
+}
+
+// equal reports whether two parsed values are deeply equal. Numbers compare
+// by value, so 1 equals 1.0, and object member order is not significant.
+func equal(a, b interface{}) bool {
+	if x, ok := a.(parser.Number); ok {
+		if y, ok := b.(parser.Number); ok {
+			return sameNumber(x, y)
+		}
+	}
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+
+	if ka, ma, ok := parser.Members(a); ok {
+		_, mb, ok := parser.Members(b)
+		if !ok || len(ka) != len(mb) {
+			return false
+		}
+		for _, k := range ka {
+			v, ok := mb[k]
+			if !ok || !equal(ma[k], v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if x, ok := a.(parser.JsonArray); ok {
+		y, ok := b.(parser.JsonArray)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
+
+// number converts a parsed number into a float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case parser.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// sameNumber reports whether two Number values are equal, exactly rather than
+// as float64 values.
+func sameNumber(x, y parser.Number) bool {
+	fx, err := x.BigFloat()
+	if err != nil {
+		return false
+	}
+	fy, err := y.BigFloat()
+	return err == nil && fx.Cmp(fy) == 0
+}
@@ -0,0 +1,260 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+const oldDocument = `{
+	"name": "gojson",
+	"version": 1,
+	"tags": ["json", "parser", "linter", "cli"],
+	"owner": {"login": "alice", "id": 7},
+	"license": "MIT"
+}`
+
+const newDocument = `{
+	"name": "gojson",
+	"version": 2,
+	"tags": ["json", "lexer"],
+	"owner": {"login": "alice", "id": 7.0, "admin": true},
+	"stars": 12
+}`
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func TestCompare(t *testing.T) {
+	changes := Compare(parse(t, oldDocument), parse(t, newDocument))
+	expected := []Change{
+		{Path: pointer.MustParse("/license"), Kind: Removed, Old: "MIT"},
+		{Path: pointer.MustParse("/owner/admin"), Kind: Added, New: true},
+		{Path: pointer.MustParse("/tags/1"), Kind: Changed, Old: "parser", New: "lexer"},
+		{Path: pointer.MustParse("/tags/3"), Kind: Removed, Old: "cli"},
+		{Path: pointer.MustParse("/tags/2"), Kind: Removed, Old: "linter"},
+		{Path: pointer.MustParse("/version"), Kind: Changed, Old: int64(1), New: int64(2)},
+		{Path: pointer.MustParse("/stars"), Kind: Added, New: int64(12)},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestCompareEqualDocuments(t *testing.T) {
+	if changes := Compare(parse(t, oldDocument), parse(t, oldDocument)); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+	if changes := Compare(int64(1), 1.0); len(changes) != 0 {
+		t.Errorf("expected 1 and 1.0 to be equal, got %v", changes)
+	}
+}
+
+func TestCompareNumbers(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`{"a": 1.0, "b": 123456789012345678901, "c": 0.10}`))
+	p.UseNumber(true)
+	old := p.ParseValue()
+	p = parser.NewParser(lexer.NewLexer(`{"a": 1, "b": 123456789012345678902, "c": 1e-1}`))
+	p.UseNumber(true)
+	changes := Compare(old, p.ParseValue())
+	expected := []Change{
+		{Path: pointer.MustParse("/b"), Kind: Changed, Old: parser.Number("123456789012345678901"), New: parser.Number("123456789012345678902")},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+	if changes := Compare(parser.Number("2.50"), 2.5); len(changes) != 0 {
+		t.Errorf("expected a Number to equal a float with the same value, got %v", changes)
+	}
+}
+
+func TestCompareDifferentTypes(t *testing.T) {
+	changes := Compare(parse(t, `{"a": [1]}`), parse(t, `{"a": {"0": 1}}`))
+	expected := []Change{
+		{Path: pointer.MustParse("/a"), Kind: Changed, Old: parser.JsonArray{int64(1)}, New: parser.JsonObject{"0": int64(1)}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestIgnoreArrayOrder(t *testing.T) {
+	old := parse(t, `{"tags": ["a", "b", "c", "b"]}`)
+	new := parse(t, `{"tags": ["c", "b", "a", "d"]}`)
+
+	changes := CompareWithOptions(old, new, Options{IgnoreArrayOrder: true})
+	expected := []Change{
+		{Path: pointer.MustParse("/tags/3"), Kind: Removed, Old: "b"},
+		{Path: pointer.MustParse("/tags/3"), Kind: Added, New: "d"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+
+	if changes := CompareWithOptions(parse(t, `[1, 2, 3]`), parse(t, `[3, 1, 2]`), Options{IgnoreArrayOrder: true}); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestIgnorePaths(t *testing.T) {
+	opts := Options{IgnorePaths: []pointer.Pointer{pointer.MustParse("/tags"), pointer.MustParse("/owner/admin"), pointer.MustParse("/version")}}
+	changes := CompareWithOptions(parse(t, oldDocument), parse(t, newDocument), opts)
+	expected := []Change{
+		{Path: pointer.MustParse("/license"), Kind: Removed, Old: "MIT"},
+		{Path: pointer.MustParse("/stars"), Kind: Added, New: int64(12)},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	changes := Compare(parse(t, oldDocument), parse(t, newDocument))
+	expected := `- /license: "MIT"
++ /owner/admin: true
+~ /tags/1: "parser" -> "lexer"
+- /tags/3: "cli"
+- /tags/2: "linter"
+~ /version: 1 -> 2
++ /stars: 12
+`
+	if got := FormatText(changes); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	if got := FormatText(Compare(parse(t, `[1]`), parse(t, `{"a": [1, 2]}`))); got != "~ /: [1] -> {\"a\": [1, 2]}\n" {
+		t.Errorf("unexpected root change: %q", got)
+	}
+}
+
+func TestFormatPatch(t *testing.T) {
+	changes := Compare(parse(t, `{"a": 1, "b": [1, 2]}`), parse(t, `{"a": 2, "b": [1], "c": null}`))
+	expected := `[{"op": "replace", "path": "/a", "value": 2}, {"op": "remove", "path": "/b/1"}, {"op": "add", "path": "/c", "value": null}]`
+	if got := FormatPatch(changes, linter.Options{InlineWidth: 200}); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+// TestPatchApplies checks that applying the patch to the old document gives the
+// new one.
+func TestPatchApplies(t *testing.T) {
+	old, new := parse(t, oldDocument), parse(t, newDocument)
+
+	doc := old
+	for _, op := range Patch(Compare(old, new)) {
+		o := op.(*parser.OrderedObject)
+		path := pointer.MustParse(o.Values["path"].(string))
+		var err error
+		switch o.Values["op"] {
+		case "add":
+			doc, err = path.Add(doc, o.Values["value"])
+		case "remove":
+			doc, err = path.Delete(doc)
+		case "replace":
+			doc, err = path.Set(doc, o.Values["value"])
+		}
+		if err != nil {
+			t.Fatalf("applying %v: unexpected error: %v", o.Values, err)
+		}
+	}
+
+	if changes := Compare(doc, new); len(changes) != 0 {
+		t.Errorf("patched document differs from the new one: %v", changes)
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := parse(t, `{"name": "gojson", "version": 1, "tags": ["json", "cli"], "owner": {"login": "alice", "id": 7}, "license": "MIT", "old": true}`)
+	ours := parse(t, `{"name": "gojson", "version": 2, "tags": ["json", "tool"], "owner": {"login": "alice", "id": 7, "admin": true}, "license": "BSD"}`)
+	theirs := parse(t, `{"name": "gojson-cli", "version": 2, "tags": ["yaml", "cli"], "owner": {"login": "bob", "id": 7}, "stars": 12}`)
+
+	merged, conflicts := Merge3(base, ours, theirs)
+	expected := `{"license": "BSD", "name": "gojson-cli", "owner": {"admin": true, "id": 7, "login": "bob"}, "stars": 12, "tags": ["yaml", "tool"], "version": 2}`
+	if got := linter.Format(merged, inline); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+	if got := FormatConflicts(conflicts); got != "! /license: ours changed \"MIT\" to \"BSD\", theirs removed \"MIT\"\n" {
+		t.Errorf("unexpected conflicts:\n%s", got)
+	}
+	if c := conflicts[0]; c.Path.String() != "/license" || c.Ours.Kind != Changed || c.Theirs.Kind != Removed {
+		t.Errorf("unexpected conflict %+v", c)
+	}
+}
+
+func TestMerge3Conflicts(t *testing.T) {
+	tests := []struct {
+		base, ours, theirs string
+		merged             string
+		conflicts          string
+	}{
+		{`{"a": 1}`, `{"a": 2}`, `{"a": 3}`, `{"a": 2}`, "! /a: ours changed 1 to 2, theirs changed 1 to 3\n"},
+		{`{}`, `{"a": {"x": 1, "y": 1}}`, `{"a": {"x": 2, "y": 1}}`, `{"a": {"x": 1, "y": 1}}`, "! /a/x: ours added 1, theirs added 2\n"},
+		{`[1, 2]`, `[1, 2, 3]`, `[0, 2]`, `[1, 2, 3]`, "! /: ours changed [1, 2] to [1, 2, 3], theirs changed [1, 2] to [0, 2]\n"},
+		{`[1, 2]`, `[0, 2]`, `[1, 3]`, `[0, 3]`, ""},
+		{`{"a": null}`, `{}`, `{"a": null}`, `{}`, ""},
+	}
+
+	for _, test := range tests {
+		merged, conflicts := Merge3(parse(t, test.base), parse(t, test.ours), parse(t, test.theirs))
+		if got := linter.Format(merged, inline); got != test.merged {
+			t.Errorf("%s %s %s: expected %s, got %s", test.base, test.ours, test.theirs, test.merged, got)
+		}
+		if got := FormatConflicts(conflicts); got != test.conflicts {
+			t.Errorf("%s %s %s: expected conflicts %q, got %q", test.base, test.ours, test.theirs, test.conflicts, got)
+		}
+	}
+}
+
+func TestMergeAll(t *testing.T) {
+	docs := []interface{}{
+		parse(t, `{"name": "api", "server": {"port": 80, "hosts": ["a"]}, "debug": false}`),
+		parse(t, `{"server": {"port": 8080, "hosts": ["b"], "tls": true}, "debug": false}`),
+		parse(t, `{"server": {"port": 9090}, "owner": "ops"}`),
+	}
+
+	tests := []struct {
+		opts     MergeOptions
+		expected string
+	}{
+		{MergeOptions{}, `{"debug": false, "name": "api", "owner": "ops", "server": {"hosts": ["b"], "port": 9090, "tls": true}}`},
+		{MergeOptions{Strategy: KeepFirst}, `{"debug": false, "name": "api", "owner": "ops", "server": {"hosts": ["a"], "port": 80, "tls": true}}`},
+		{MergeOptions{AppendArrays: true}, `{"debug": false, "name": "api", "owner": "ops", "server": {"hosts": ["a", "b"], "port": 9090, "tls": true}}`},
+	}
+	for _, tt := range tests {
+		merged, err := MergeAll(docs, tt.opts)
+		if err != nil {
+			t.Fatalf("MergeAll(%+v): unexpected error: %v", tt.opts, err)
+		}
+		if got := linter.Format(merged, inline); got != tt.expected {
+			t.Errorf("MergeAll(%+v):\nexpected %s\ngot      %s", tt.opts, tt.expected, got)
+		}
+	}
+	if got := linter.Format(docs[0], inline); got != `{"debug": false, "name": "api", "server": {"hosts": ["a"], "port": 80}}` {
+		t.Errorf("MergeAll modified its input: %s", got)
+	}
+
+	_, err := MergeAll(docs, MergeOptions{Strategy: Fail})
+	conflict, ok := err.(*ConflictError)
+	if !ok || conflict.Index != 1 || conflict.Path.String() != "/server/hosts" {
+		t.Fatalf("MergeAll(Fail): expected a conflict at /server/hosts, got %v", err)
+	}
+	if expected := `diff: conflicting values at /server/hosts: ["a"] and ["b"]`; err.Error() != expected {
+		t.Errorf("expected error %q, got %q", expected, err)
+	}
+	if _, err := MergeAll(docs, MergeOptions{Strategy: Fail, AppendArrays: true}); err == nil || err.Error() != "diff: conflicting values at /server/port: 80 and 8080" {
+		t.Errorf("MergeAll(Fail, AppendArrays): unexpected error %v", err)
+	}
+	if merged, err := MergeAll([]interface{}{parse(t, `{"a": 1}`), parse(t, `[1]`)}, MergeOptions{}); err != nil || linter.Format(merged, inline) != "[1]" {
+		t.Errorf("MergeAll(object, array): expected [1], got %v, %v", merged, err)
+	}
+}
@@ -0,0 +1,175 @@
+package diff
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Conflict is a location that two documents derived from the same base both
+// changed, in different ways. Ours and Theirs are the changes each side made
+// to the base value, which is their Old value unless both sides added it.
+type Conflict struct {
+	Path   pointer.Pointer
+	Ours   Change
+	Theirs Change
+}
+
+// String returns the conflict as "path: ours <change>, theirs <change>", the
+// whole document being designated by "/".
+func (c Conflict) String() string {
+	path := c.Path.String()
+	if path == "" {
+		path = "/"
+	}
+	return path + ": ours " + describeChange(c.Ours) + ", theirs " + describeChange(c.Theirs)
+}
+
+// describeChange returns how a side of a conflict changed the base value.
+func describeChange(c Change) string {
+	switch c.Kind {
+	case Added:
+		return "added " + linter.Format(c.New, inline)
+	case Removed:
+		return "removed " + linter.Format(c.Old, inline)
+	}
+	return "changed " + linter.Format(c.Old, inline) + " to " + linter.Format(c.New, inline)
+}
+
+// FormatConflicts renders conflicts for humans, one per line prefixed by "! ".
+func FormatConflicts(conflicts []Conflict) string {
+	var result strings.Builder
+	for _, c := range conflicts {
+		result.WriteString("! " + c.String() + "\n")
+	}
+	return result.String()
+}
+
+// absent is a value missing from a document, as opposed to null.
+type absent struct{}
+
+// Merge3 merges the changes that ours and theirs, two documents derived from
+// base, made to it. A value changed by one side only takes that side's
+// version, one changed by both in the same way is kept, and objects changed by
+// both, like arrays of the same length as in base, are merged member by
+// member, or element by element. Other values changed by both sides in
+// different ways are conflicts, where the merged document holds the version of
+// ours. Any value can be nil, and the members of merged objects follow the
+// order of ours, those only added by theirs coming last. The merged document
+// shares no values with its inputs.
+func Merge3(base, ours, theirs interface{}) (interface{}, []Conflict) {
+	m := &merger{}
+	merged := m.merge(pointer.Pointer{}, base, ours, theirs)
+	return merged, m.conflicts
+}
+
+// merger accumulates the conflicts found while merging documents.
+type merger struct {
+	conflicts []Conflict
+}
+
+// merge returns the merge of the values found at path, any of which may be
+// absent.
+func (m *merger) merge(path pointer.Pointer, base, ours, theirs interface{}) interface{} {
+	switch {
+	case same(ours, theirs), same(base, theirs):
+		return clone(ours)
+	case same(base, ours):
+		return clone(theirs)
+	}
+
+	_, isBaseAbsent := base.(absent)
+	if _, _, ok := parser.Members(ours); ok {
+		if _, _, ok := parser.Members(theirs); ok {
+			if _, _, ok := parser.Members(base); ok || isBaseAbsent {
+				return m.mergeObjects(path, base, ours, theirs)
+			}
+		}
+	}
+	if o, ok := ours.(parser.JsonArray); ok {
+		if t, ok := theirs.(parser.JsonArray); ok {
+			if b, ok := base.(parser.JsonArray); ok && len(b) == len(o) && len(b) == len(t) {
+				merged := make(parser.JsonArray, len(b))
+				for i := range b {
+					merged[i] = m.merge(path.Append(strconv.Itoa(i)), b[i], o[i], t[i])
+				}
+				return merged
+			}
+		}
+	}
+
+	m.conflicts = append(m.conflicts, Conflict{
+		Path:   path,
+		Ours:   sideChange(path, base, ours),
+		Theirs: sideChange(path, base, theirs),
+	})
+	return clone(ours)
+}
+
+// mergeObjects returns the merge of objects, base being absent when both
+// sides added an object at path.
+func (m *merger) mergeObjects(path pointer.Pointer, base, ours, theirs interface{}) interface{} {
+	_, baseMembers, _ := parser.Members(base)
+	ourKeys, ourMembers, _ := parser.Members(ours)
+	theirKeys, theirMembers, _ := parser.Members(theirs)
+
+	keys := append([]string{}, ourKeys...)
+	for _, k := range theirKeys {
+		if _, ok := ourMembers[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	lookup := func(values map[string]interface{}, k string) interface{} {
+		if v, ok := values[k]; ok {
+			return v
+		}
+		return absent{}
+	}
+	obj := parser.NewOrderedObject()
+	for _, k := range keys { // members removed by both sides are in neither
+		v := m.merge(path.Append(k), lookup(baseMembers, k), lookup(ourMembers, k), lookup(theirMembers, k))
+		if _, ok := v.(absent); !ok {
+			obj.Set(k, v)
+		}
+	}
+	if _, ok := ours.(parser.JsonObject); ok {
+		return obj.Values
+	}
+	return obj
+}
+
+// sideChange returns the change a side made to the base value at path.
+func sideChange(path pointer.Pointer, base, side interface{}) Change {
+	_, isBaseAbsent := base.(absent)
+	_, isSideAbsent := side.(absent)
+	switch {
+	case isBaseAbsent:
+		return Change{Path: path, Kind: Added, New: clone(side)}
+	case isSideAbsent:
+		return Change{Path: path, Kind: Removed, Old: clone(base)}
+	}
+	return Change{Path: path, Kind: Changed, Old: clone(base), New: clone(side)}
+}
+
+// same reports whether two values, any of which may be absent, are equal.
+func same(a, b interface{}) bool {
+	_, isAAbsent := a.(absent)
+	_, isBAbsent := b.(absent)
+	if isAAbsent || isBAbsent {
+		return isAAbsent && isBAbsent
+	}
+	return equal(a, b)
+}
+
+// clone returns a deep copy of v, which may be absent.
+func clone(v interface{}) interface{} {
+	if _, ok := v.(absent); ok {
+		return v
+	}
+	return gojson.Clone(v)
+}
@@ -0,0 +1,65 @@
+package diff
+
+import (
+	"math"
+	"strings"
+
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// inline lays values out on a single line in rendered text.
+var inline = linter.Options{InlineWidth: math.MaxInt}
+
+// FormatText renders changes for humans, one per line: "+ path: value" for
+// additions, "- path: value" for removals and "~ path: old -> new" for
+// changes. The whole document is designated by the path "/".
+func FormatText(changes []Change) string {
+	var result strings.Builder
+	for _, c := range changes {
+		path := c.Path.String()
+		if path == "" {
+			path = "/"
+		}
+		switch c.Kind {
+		case Added:
+			result.WriteString("+ " + path + ": " + linter.Format(c.New, inline))
+		case Removed:
+			result.WriteString("- " + path + ": " + linter.Format(c.Old, inline))
+		default:
+			result.WriteString("~ " + path + ": " + linter.Format(c.Old, inline) + " -> " + linter.Format(c.New, inline))
+		}
+		result.WriteByte('\n')
+	}
+	return result.String()
+}
+
+// Patch converts changes into a JSON Patch document (RFC 6902) made of "add",
+// "remove" and "replace" operations. Applying the patch of a comparison done
+// without IgnoreArrayOrder to the old document yields the new one.
+func Patch(changes []Change) parser.JsonArray {
+	patch := make(parser.JsonArray, 0, len(changes))
+	for _, c := range changes {
+		op := parser.NewOrderedObject()
+		switch c.Kind {
+		case Added:
+			op.Set("op", "add")
+			op.Set("path", c.Path.String())
+			op.Set("value", c.New)
+		case Removed:
+			op.Set("op", "remove")
+			op.Set("path", c.Path.String())
+		default:
+			op.Set("op", "replace")
+			op.Set("path", c.Path.String())
+			op.Set("value", c.New)
+		}
+		patch = append(patch, op)
+	}
+	return patch
+}
+
+// FormatPatch renders changes as a JSON Patch document laid out with opts.
+func FormatPatch(changes []Change, opts linter.Options) string {
+	return linter.Format(Patch(changes), opts)
+}
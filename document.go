@@ -0,0 +1,105 @@
+package gojson
+
+import (
+	"strconv"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Document is an immutable handle on a parsed document, safe for concurrent
+// use. Its update methods return new documents that share the subtrees they
+// leave unchanged with the original one, copying only the containers along
+// the updated path, so that readers of a snapshot are never affected by
+// updates.
+type Document struct {
+	root interface{}
+}
+
+// NewDocument returns a document holding a deep copy of v, which the caller
+// remains free to modify.
+func NewDocument(v interface{}) *Document {
+	return &Document{root: Clone(v)}
+}
+
+// Value returns the whole document. It is shared with other documents, so it
+// must not be modified; use Clone to get a modifiable copy.
+func (d *Document) Value() interface{} {
+	return d.root
+}
+
+// Get returns the value designated by p. Like Value, it must not be modified.
+func (d *Document) Get(p pointer.Pointer) (interface{}, error) {
+	return p.Get(d.root)
+}
+
+// Set returns a new document where the value designated by p is replaced by
+// a deep copy of value, following the semantics of pointer.Pointer.Set.
+func (d *Document) Set(p pointer.Pointer, value interface{}) (*Document, error) {
+	root, err := p.Set(copyPath(d.root, p), Clone(value))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{root: root}, nil
+}
+
+// Add returns a new document where a deep copy of value is inserted at p,
+// following the semantics of pointer.Pointer.Add.
+func (d *Document) Add(p pointer.Pointer, value interface{}) (*Document, error) {
+	root, err := p.Add(copyPath(d.root, p), Clone(value))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{root: root}, nil
+}
+
+// Delete returns a new document without the value designated by p.
+func (d *Document) Delete(p pointer.Pointer) (*Document, error) {
+	root, err := p.Delete(copyPath(d.root, p))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{root: root}, nil
+}
+
+// copyPath returns v with the containers along p copied, one level deep
+// each, so that updating the copy along p leaves v unchanged. The walk stops
+// where p does not resolve.
+func copyPath(v interface{}, p pointer.Pointer) interface{} {
+	switch x := v.(type) {
+	case parser.JsonObject:
+		obj := make(parser.JsonObject, len(x)+1)
+		for k, e := range x {
+			obj[k] = e
+		}
+		if len(p) > 0 {
+			if child, ok := obj[p[0]]; ok {
+				obj[p[0]] = copyPath(child, p[1:])
+			}
+		}
+		return obj
+	case *parser.OrderedObject:
+		obj := &parser.OrderedObject{
+			Keys:   append(make([]string, 0, len(x.Keys)+1), x.Keys...),
+			Values: make(parser.JsonObject, len(x.Values)+1),
+		}
+		for k, e := range x.Values {
+			obj.Values[k] = e
+		}
+		if len(p) > 0 {
+			if child, ok := obj.Values[p[0]]; ok {
+				obj.Values[p[0]] = copyPath(child, p[1:])
+			}
+		}
+		return obj
+	case parser.JsonArray:
+		arr := append(make(parser.JsonArray, 0, len(x)+1), x...)
+		if len(p) > 0 {
+			if i, err := strconv.Atoi(p[0]); err == nil && i >= 0 && i < len(arr) {
+				arr[i] = copyPath(arr[i], p[1:])
+			}
+		}
+		return arr
+	}
+	return v
+}
@@ -0,0 +1,79 @@
+package gojson
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+func TestDocument(t *testing.T) {
+	input := parseValue(t, `{"server": {"host": "localhost", "ports": [80, 443]}, "users": [{"name": "a"}], "debug": false}`)
+	v1 := NewDocument(input)
+	input.(parser.JsonObject)["debug"] = true
+	if got, _ := v1.Get(pointer.MustParse("/debug")); got != false {
+		t.Errorf("modifying the input changed the document")
+	}
+
+	v2, err := v1.Set(pointer.MustParse("/server/ports/0"), int64(8080))
+	if err != nil {
+		t.Fatalf("Set: unexpected error: %v", err)
+	}
+	v3, err := v2.Add(pointer.MustParse("/users/0"), Obj().Set("name", "b").Build())
+	if err != nil {
+		t.Fatalf("Add: unexpected error: %v", err)
+	}
+	v4, err := v3.Delete(pointer.MustParse("/debug"))
+	if err != nil {
+		t.Fatalf("Delete: unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`{"debug": false, "server": {"host": "localhost", "ports": [80, 443]}, "users": [{"name": "a"}]}`,
+		`{"debug": false, "server": {"host": "localhost", "ports": [8080, 443]}, "users": [{"name": "a"}]}`,
+		`{"debug": false, "server": {"host": "localhost", "ports": [8080, 443]}, "users": [{"name": "b"}, {"name": "a"}]}`,
+		`{"server": {"host": "localhost", "ports": [8080, 443]}, "users": [{"name": "b"}, {"name": "a"}]}`,
+	}
+	for i, d := range []*Document{v1, v2, v3, v4} {
+		if !Equal(d.Value(), parseValue(t, expected[i])) {
+			t.Errorf("version %d: expected %s, got %v", i+1, expected[i], d.Value())
+		}
+	}
+
+	// Unchanged subtrees are shared.
+	users1, _ := v1.Get(pointer.MustParse("/users/0"))
+	users2, _ := v2.Get(pointer.MustParse("/users/0"))
+	if reflect.ValueOf(users1).Pointer() != reflect.ValueOf(users2).Pointer() {
+		t.Errorf("expected the unchanged users to be shared")
+	}
+
+	if _, err := v1.Set(pointer.MustParse("/missing/x"), int64(1)); err == nil {
+		t.Errorf("Set: expected an error for a missing parent")
+	}
+	if _, err := v1.Delete(pointer.MustParse("/server/ports/5")); err == nil {
+		t.Errorf("Delete: expected an error for a missing element")
+	}
+}
+
+func TestDocumentOrdered(t *testing.T) {
+	obj := parser.NewOrderedObject()
+	obj.Set("b", parser.JsonArray{int64(1)})
+	obj.Set("a", int64(2))
+	v1 := NewDocument(obj)
+	v2, err := v1.Set(pointer.MustParse("/b/-"), int64(3))
+	if err != nil {
+		t.Fatalf("Set: unexpected error: %v", err)
+	}
+	v3, err := v2.Set(pointer.MustParse("/c"), "x")
+	if err != nil {
+		t.Fatalf("Set: unexpected error: %v", err)
+	}
+
+	if got := v1.Value().(*parser.OrderedObject); got.Len() != 2 || len(got.Values["b"].(parser.JsonArray)) != 1 {
+		t.Errorf("updates changed the first version: %v", got)
+	}
+	if got := v3.Value().(*parser.OrderedObject); !reflect.DeepEqual(got.Keys, []string{"b", "a", "c"}) || len(got.Values["b"].(parser.JsonArray)) != 2 {
+		t.Errorf("unexpected last version: %v", got)
+	}
+}
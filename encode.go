@@ -0,0 +1,587 @@
+// Package gojson provides functions to encode Go values as JSON and to decode
+// JSON into them, mirroring the API of encoding/json on top of gojson's lexer
+// and parser, and helpers working on the documents produced by the parser
+// package, such as Equal, Clone and the Obj and Arr builders.
+package gojson
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"iter"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// An Encoder writes JSON values to an output stream.
+type Encoder struct {
+	w          io.Writer // the destination of the encoded values
+	prefix     string    // prefix of every line when indenting
+	indent     string    // indentation of each nesting level, no indentation if empty
+	escapeHTML bool      // whether <, > and & are escaped inside strings
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, escapeHTML: true}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline.
+// Nothing is written if v cannot be encoded.
+func (enc *Encoder) Encode(v interface{}) error {
+	e := &encodeState{prefix: enc.prefix, indent: enc.indent, escapeHTML: enc.escapeHTML}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return err
+	}
+	e.WriteByte('\n')
+
+	_, err := enc.w.Write(e.Bytes())
+	return err
+}
+
+// EncodeArray writes a JSON array of the values of seq to the stream,
+// followed by a newline. Every value is written, and the stream flushed if it
+// has a Flush method, as an http.ResponseWriter or a bufio.Writer do, as soon
+// as seq yields it, so that producers can emit unbounded result sets without
+// buffering them. If a value cannot be encoded, the array is left
+// unterminated, so that readers do not take it for complete, and the error
+// returned.
+func (enc *Encoder) EncodeArray(seq iter.Seq[interface{}]) error {
+	e := &encodeState{prefix: enc.prefix, indent: enc.indent, escapeHTML: enc.escapeHTML}
+	e.beginContainer('[')
+	n := 0
+	for v := range seq {
+		e.beginElement(n)
+		if err := e.encode(reflect.ValueOf(v)); err != nil {
+			return err
+		}
+		n++
+		if err := enc.flush(e); err != nil {
+			return err
+		}
+	}
+	e.endContainer(']', n)
+	e.WriteByte('\n')
+	return enc.flush(e)
+}
+
+// EncodeLines writes the values of seq to the stream as newline-delimited
+// JSON, each value on its own line whatever the indentation set, flushing the
+// stream as EncodeArray does. It stops at the first value that cannot be
+// encoded and returns its error.
+func (enc *Encoder) EncodeLines(seq iter.Seq[interface{}]) error {
+	e := &encodeState{escapeHTML: enc.escapeHTML}
+	for v := range seq {
+		if err := e.encode(reflect.ValueOf(v)); err != nil {
+			return err
+		}
+		e.WriteByte('\n')
+		if err := enc.flush(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush writes the encoding accumulated by e to the stream, and flushes the
+// stream if it can be.
+func (enc *Encoder) flush(e *encodeState) error {
+	_, err := enc.w.Write(e.Bytes())
+	e.Reset()
+	if err != nil {
+		return err
+	}
+	switch w := enc.w.(type) {
+	case interface{ Flush() error }:
+		return w.Flush()
+	case interface{ Flush() }:
+		w.Flush()
+	}
+	return nil
+}
+
+// Values returns a sequence of the values received from ch until it is
+// closed, to be encoded by EncodeArray or EncodeLines.
+func Values[T any](ch <-chan T) iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// SetIndent instructs the encoder to start every element of subsequently
+// encoded objects and arrays on a new line, made of prefix followed by one copy
+// of indent per nesting level. Calling SetIndent("", "") disables indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix = prefix
+	enc.indent = indent
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be escaped
+// inside JSON quoted strings. The default behavior is to escape &, <, and > to
+// \u0026, \u003c, and \u003e to avoid certain safety problems that can
+// arise when embedding JSON in HTML.
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.escapeHTML = on
+}
+
+// UnsupportedTypeError is returned when attempting to encode a value of a type
+// that has no JSON representation.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "gojson: unsupported type: " + e.Type.String()
+}
+
+// UnsupportedValueError is returned when attempting to encode a value that has
+// no JSON representation, such as NaN, an infinite float or a value holding
+// itself.
+type UnsupportedValueError struct {
+	Str string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "gojson: unsupported value: " + e.Str
+}
+
+// encodeState accumulates the JSON encoding of a value.
+type encodeState struct {
+	bytes.Buffer
+	prefix     string                // prefix of every line when indenting
+	indent     string                // indentation of each nesting level, no indentation if empty
+	escapeHTML bool                  // whether <, > and & are escaped inside strings
+	depth      int                   // current nesting level
+	ptrLevel   int                   // number of nested pointers, maps and slices being encoded
+	ptrSeen    map[cycleKey]struct{} // those being encoded, once ptrLevel passes startDetectingCyclesAfter
+}
+
+// startDetectingCyclesAfter is the number of nested pointers, maps and slices
+// past which the encoder records those it goes through, as encoding/json
+// does, so that shallow values pay nothing for the detection of cycles.
+const startDetectingCyclesAfter = 1000
+
+// cycleKey identifies a pointer, map or slice being encoded. Slices are told
+// apart by their length too, since a slice shares its address with its
+// shorter reslices.
+type cycleKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// enter records that v, a non-nil pointer, map or slice, is being encoded,
+// and returns an *UnsupportedValueError if it already is: v is part of a
+// cycle. leave must be called once v is encoded.
+func (e *encodeState) enter(v reflect.Value) error {
+	e.ptrLevel++
+	if e.ptrLevel <= startDetectingCyclesAfter {
+		return nil
+	}
+	key := cycleKeyOf(v)
+	if _, ok := e.ptrSeen[key]; ok {
+		return &UnsupportedValueError{Str: "encountered a cycle via " + v.Type().String()}
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = map[cycleKey]struct{}{}
+	}
+	e.ptrSeen[key] = struct{}{}
+	return nil
+}
+
+// leave undoes enter once v is encoded.
+func (e *encodeState) leave(v reflect.Value) {
+	if e.ptrLevel > startDetectingCyclesAfter {
+		delete(e.ptrSeen, cycleKeyOf(v))
+	}
+	e.ptrLevel--
+}
+
+// cycleKeyOf returns the key of v in encodeState.ptrSeen.
+func cycleKeyOf(v reflect.Value) cycleKey {
+	key := cycleKey{typ: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	return key
+}
+
+var (
+	orderedObjectType = reflect.TypeOf((*parser.OrderedObject)(nil))
+	numberType        = reflect.TypeOf(parser.Number(""))
+)
+
+// encode writes the JSON encoding of v.
+func (e *encodeState) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.WriteString("null")
+		return nil
+	}
+
+	if mv, ok := marshalerValue(v); ok {
+		return e.encodeMarshaler(mv)
+	}
+
+	if v.Type() == orderedObjectType {
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		return e.encodeOrderedObject(v.Interface().(*parser.OrderedObject))
+	}
+
+	if v.Type() == numberType {
+		// Numbers are written as they were parsed, keeping their precision.
+		n := parser.Number(v.String())
+		if !n.Valid() {
+			return &UnsupportedValueError{Str: strconv.Quote(v.String())}
+		}
+		e.WriteString(v.String())
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.WriteString("true")
+		} else {
+			e.WriteString("false")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return e.encodeFloat(v.Float(), v.Type().Bits())
+	case reflect.String:
+		e.encodeString(v.String())
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		if v.Kind() == reflect.Pointer {
+			if err := e.enter(v); err != nil {
+				return err
+			}
+			defer e.leave(v)
+		}
+		return e.encode(v.Elem())
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte slices are encoded as base64 strings, like encoding/json does.
+			e.encodeString(base64.StdEncoding.EncodeToString(v.Bytes()))
+			return nil
+		}
+		if err := e.enter(v); err != nil {
+			return err
+		}
+		defer e.leave(v)
+		return e.encodeArray(v)
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+	return nil
+}
+
+// encodeFloat writes f using the shortest representation that round-trips,
+// switching to exponent notation for very large or very small values.
+func (e *encodeState) encodeFloat(f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return &UnsupportedValueError{Str: strconv.FormatFloat(f, 'g', -1, bits)}
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b := strconv.AppendFloat(nil, f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		if n := len(b); n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	e.Write(b)
+	return nil
+}
+
+// encodeMap writes a map as a JSON object, sorting the keys so that the
+// output is deterministic.
+func (e *encodeState) encodeMap(v reflect.Value) error {
+	if v.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	if err := e.enter(v); err != nil {
+		return err
+	}
+	defer e.leave(v)
+	keys, err := sortedMapKeys(v)
+	if err != nil {
+		return err
+	}
+
+	e.beginContainer('{')
+	for i, k := range keys {
+		e.beginElement(i)
+		e.encodeKey(k.name)
+		if err := e.encode(v.MapIndex(k.value)); err != nil {
+			return err
+		}
+	}
+	e.endContainer('}', len(keys))
+	return nil
+}
+
+// mapKey is a key of a map with the name it is encoded under.
+type mapKey struct {
+	name  string
+	value reflect.Value
+}
+
+// sortedMapKeys returns the keys of a map sorted by their names, which are,
+// as encoding/json names them, the text of encoding.TextMarshaler keys,
+// strings as they are and integers in decimal. Maps with other keys
+// return an *UnsupportedTypeError.
+func sortedMapKeys(v reflect.Value) ([]mapKey, error) {
+	t := v.Type().Key()
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if !t.Implements(textMarshalerType) {
+			return nil, &UnsupportedTypeError{Type: v.Type()}
+		}
+	}
+
+	keys := make([]mapKey, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		k := iter.Key()
+		name, err := mapKeyName(k)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, mapKey{name: name, value: k})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].name < keys[j].name })
+	return keys, nil
+}
+
+// mapKeyName returns the name of the map key k.
+func mapKeyName(k reflect.Value) (string, error) {
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		b, err := m.MarshalText()
+		if err != nil {
+			return "", &MarshalerError{Type: k.Type(), Err: err, sourceFunc: "MarshalText"}
+		}
+		return string(b), nil
+	}
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	default:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+}
+
+// encodeOrderedObject writes an OrderedObject, keeping the order of its keys.
+func (e *encodeState) encodeOrderedObject(obj *parser.OrderedObject) error {
+	e.beginContainer('{')
+	for i, k := range obj.Keys {
+		e.beginElement(i)
+		e.encodeKey(k)
+		if err := e.encode(reflect.ValueOf(obj.Values[k])); err != nil {
+			return err
+		}
+	}
+	e.endContainer('}', len(obj.Keys))
+	return nil
+}
+
+// encodeArray writes a slice or an array as a JSON array.
+func (e *encodeState) encodeArray(v reflect.Value) error {
+	e.beginContainer('[')
+	n := v.Len()
+	for i := 0; i < n; i++ {
+		e.beginElement(i)
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	e.endContainer(']', n)
+	return nil
+}
+
+// encodeStruct writes the exported fields of a struct as a JSON object, using
+// the names and options given by their json tags.
+func (e *encodeState) encodeStruct(v reflect.Value) error {
+	e.beginContainer('{')
+	n := 0
+	for _, f := range cachedTypeFields(v.Type()) {
+		fv, ok := fieldValue(v, f)
+		if !ok || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		e.beginElement(n)
+		e.encodeKey(f.name)
+		if err := e.encodeField(fv, f); err != nil {
+			return err
+		}
+		n++
+	}
+	e.endContainer('}', n)
+	return nil
+}
+
+// encodeField writes the value of a struct field. Fields with the string option
+// are written as a JSON string holding their JSON encoding.
+func (e *encodeState) encodeField(v reflect.Value, f field) error {
+	if !f.asString {
+		return e.encode(v)
+	}
+
+	inner := &encodeState{escapeHTML: e.escapeHTML}
+	if err := inner.encode(v); err != nil {
+		return err
+	}
+	e.encodeString(inner.String())
+	return nil
+}
+
+// beginContainer opens an object or an array.
+func (e *encodeState) beginContainer(open byte) {
+	e.WriteByte(open)
+	e.depth++
+}
+
+// beginElement writes the separator and the indentation preceding the i-th
+// element of a container.
+func (e *encodeState) beginElement(i int) {
+	if i > 0 {
+		e.WriteByte(',')
+	}
+	e.newline()
+}
+
+// endContainer closes an object or an array holding n elements.
+func (e *encodeState) endContainer(close byte, n int) {
+	e.depth--
+	if n > 0 {
+		e.newline()
+	}
+	e.WriteByte(close)
+}
+
+// newline starts a new indented line when indentation is enabled.
+func (e *encodeState) newline() {
+	if e.indent == "" && e.prefix == "" {
+		return
+	}
+	e.WriteByte('\n')
+	e.WriteString(e.prefix)
+	for i := 0; i < e.depth; i++ {
+		e.WriteString(e.indent)
+	}
+}
+
+// encodeKey writes an object key followed by the name separator.
+func (e *encodeState) encodeKey(key string) {
+	e.encodeString(key)
+	e.WriteByte(':')
+	if e.indent != "" || e.prefix != "" {
+		e.WriteByte(' ')
+	}
+}
+
+const hex = "0123456789abcdef"
+
+// encodeString writes s as a quoted JSON string, escaping the characters that
+// RFC 8259 requires plus, optionally, the HTML-sensitive ones.
+func (e *encodeState) encodeString(s string) {
+	e.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && (!e.escapeHTML || (b != '<' && b != '>' && b != '&')) {
+				i++
+				continue
+			}
+			e.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				e.WriteByte('\\')
+				e.WriteByte(b)
+			case '\b':
+				e.WriteString(`\b`)
+			case '\f':
+				e.WriteString(`\f`)
+			case '\n':
+				e.WriteString(`\n`)
+			case '\r':
+				e.WriteString(`\r`)
+			case '\t':
+				e.WriteString(`\t`)
+			default:
+				e.WriteString(`\u00`)
+				e.WriteByte(hex[b>>4])
+				e.WriteByte(hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			// Invalid UTF-8 is replaced by the replacement character, written
+			// as it is like encoding/json does.
+			e.WriteString(s[start:i])
+			e.WriteString("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			// U+2028 and U+2029 are valid JSON but break JavaScript parsers.
+			e.WriteString(s[start:i])
+			fmt.Fprintf(&e.Buffer, `\u%04x`, r)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	e.WriteString(s[start:])
+	e.WriteByte('"')
+}
@@ -0,0 +1,259 @@
+package gojson
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+func TestEncodeValues(t *testing.T) {
+	type point struct {
+		X, Y   int
+		Label  string
+		hidden bool
+	}
+
+	ordered := parser.NewOrderedObject()
+	ordered.Set("z", int64(1))
+	ordered.Set("a", parser.JsonArray{true, nil})
+
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{int8(-5), "-5"},
+		{uint64(18446744073709551615), "18446744073709551615"},
+		{3.0, "3"},
+		{1.5e-7, "1.5e-7"},
+		{1e21, "1e+21"},
+		{float32(0.1), "0.1"},
+		{"a\"b\\c\n", `"a\"b\\c\n"`},
+		{"<b>&</b>", `"\u003cb\u003e\u0026\u003c/b\u003e"`},
+		{"\u2028\x01", `"\u2028\u0001"`},
+		{[]byte("hi"), `"aGk="`},
+		{[]int(nil), "null"},
+		{[]string{}, "[]"},
+		{[2]bool{true, false}, "[true,false]"},
+		{map[string]int{"b": 2, "a": 1}, `{"a":1,"b":2}`},
+		{parser.JsonObject{"k": parser.JsonArray{int64(1), "x"}}, `{"k":[1,"x"]}`},
+		{ordered, `{"z":1,"a":[true,null]}`},
+		{point{X: 1, Y: 2, Label: "p"}, `{"X":1,"Y":2,"Label":"p"}`},
+		{&point{}, `{"X":0,"Y":0,"Label":""}`},
+		{(*point)(nil), "null"},
+	}
+
+	for i, tt := range tests {
+		var out strings.Builder
+		if err := NewEncoder(&out).Encode(tt.value); err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %v", i, err)
+		}
+		if out.String() != tt.expected+"\n" {
+			t.Errorf("tests[%d] - encoding wrong. expected=%q, got=%q", i, tt.expected+"\n", out.String())
+		}
+	}
+}
+
+func TestEncodeIndentAndHTML(t *testing.T) {
+	var out strings.Builder
+	enc := NewEncoder(&out)
+	enc.SetIndent(">", "  ")
+	enc.SetEscapeHTML(false)
+
+	value := map[string]interface{}{"a": []int{1, 2}, "b": map[string]int{}, "c": "<&>"}
+	if err := enc.Encode(value); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := "{\n>  \"a\": [\n>    1,\n>    2\n>  ],\n>  \"b\": {},\n>  \"c\": \"<&>\"\n>}\n"
+	if out.String() != expected {
+		t.Errorf("indented encoding wrong. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	values := []interface{}{
+		math.NaN(),
+		math.Inf(-1),
+		make(chan int),
+		map[[2]int]string{{1, 2}: "a"},
+		map[textKey]int{"fail": 1},
+		[]interface{}{func() {}},
+	}
+
+	for i, v := range values {
+		var out strings.Builder
+		if err := NewEncoder(&out).Encode(v); err == nil {
+			t.Errorf("values[%d] - expected an error", i)
+		}
+		if out.Len() != 0 {
+			t.Errorf("values[%d] - expected nothing to be written, got %q", i, out.String())
+		}
+	}
+}
+
+// textKey is a map key named by its MarshalText method, which fails for
+// "fail".
+type textKey string
+
+func (k textKey) MarshalText() ([]byte, error) {
+	if k == "fail" {
+		return nil, errors.New("cannot name the key")
+	}
+	return []byte("key-" + k), nil
+}
+
+// pointKey is a map key of kind struct named by its MarshalText method.
+type pointKey struct{ X, Y int }
+
+func (p pointKey) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(p.X) + "," + strconv.Itoa(p.Y)), nil
+}
+
+func TestEncodeLikeEncodingJSON(t *testing.T) {
+	values := []interface{}{
+		map[int]string{10: "a", 9: "b", -1: "c"},
+		map[uint8]bool{2: true, 10: false},
+		map[textKey]int{"b": 1, "a": 2},
+		map[pointKey]string{{1, 2}: "p", {0, 5}: "q"},
+		map[string]interface{}{"nested": map[int64]int{3: 3}},
+		"invalid \xff UTF-8 \xe2\x82",
+		map[string]int{"\xffkey": 1},
+	}
+	for _, v := range values {
+		expected, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Marshal(v)
+		if err != nil || string(got) != string(expected) {
+			t.Errorf("%#v: expected %s, got %s (%v)", v, expected, got, err)
+		}
+
+		expected, _ = json.MarshalIndent(v, "", "  ")
+		got, err = MarshalIndent(v, "", "  ")
+		if err != nil || string(got) != string(expected) {
+			t.Errorf("%#v: expected %s indented, got %s (%v)", v, expected, got, err)
+		}
+	}
+}
+
+func TestEncodeStructTags(t *testing.T) {
+	type inner struct {
+		Value int `json:"value"`
+	}
+	type tagged struct {
+		Name     string            `json:"name"`
+		Nick     string            `json:"nick,omitempty"`
+		Age      int               `json:",omitempty"`
+		ID       int64             `json:"id,string"`
+		Ratio    float64           `json:"ratio,string"`
+		Flag     bool              `json:"flag,string"`
+		Quoted   string            `json:"quoted,string"`
+		Skipped  string            `json:"-"`
+		Dash     string            `json:"-,"`
+		Tags     []string          `json:"tags,omitempty"`
+		Attrs    map[string]string `json:"attrs,omitempty"`
+		Inner    *inner            `json:"inner,omitempty"`
+		Nested   inner             `json:"nested"`
+		Invalid  int               `json:"a\"b"`
+		NotValid []int             `json:"list,string"`
+	}
+
+	value := tagged{Name: "John", ID: 42, Ratio: 0.5, Flag: true, Quoted: "x", Skipped: "no", Dash: "yes", Nested: inner{1}, NotValid: []int{1}}
+
+	var out strings.Builder
+	if err := NewEncoder(&out).Encode(value); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := `{"name":"John","id":"42","ratio":"0.5","flag":"true","quoted":"\"x\"","-":"yes","nested":{"value":1},"Invalid":0,"list":[1]}` + "\n"
+	if out.String() != expected {
+		t.Errorf("tagged struct encoding wrong. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+// flushRecorder records what was written to it when it was flushed.
+type flushRecorder struct {
+	strings.Builder
+	flushed []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.String())
+}
+
+func TestEncodeArray(t *testing.T) {
+	var out flushRecorder
+	enc := NewEncoder(&out)
+	values := []interface{}{parser.JsonObject{"a": int64(1)}, "b", nil}
+	if err := enc.EncodeArray(slices.Values(values)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`[{"a":1}`, `[{"a":1},"b"`, `[{"a":1},"b",null`, "[{\"a\":1},\"b\",null]\n"}
+	if !reflect.DeepEqual(out.flushed, expected) {
+		t.Errorf("expected the stream to be flushed after every value, got %q", out.flushed)
+	}
+
+	var indented strings.Builder
+	enc = NewEncoder(&indented)
+	enc.SetIndent("", "  ")
+	if err := enc.EncodeArray(slices.Values([]interface{}{1, []int{2}})); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[\n  1,\n  [\n    2\n  ]\n]\n"; indented.String() != expected {
+		t.Errorf("indented array wrong. expected=%q, got=%q", expected, indented.String())
+	}
+
+	var empty strings.Builder
+	if err := NewEncoder(&empty).EncodeArray(slices.Values([]interface{}{})); err != nil || empty.String() != "[]\n" {
+		t.Errorf("expected an empty array, got %q, %v", empty.String(), err)
+	}
+
+	var invalid strings.Builder
+	if err := NewEncoder(&invalid).EncodeArray(slices.Values([]interface{}{1, math.NaN(), 3})); err == nil || invalid.String() != "[1" {
+		t.Errorf("expected an error and an unterminated array, got %q, %v", invalid.String(), err)
+	}
+}
+
+func TestEncodeLines(t *testing.T) {
+	ch := make(chan map[string]int)
+	go func() {
+		ch <- map[string]int{"a": 1, "b": 2}
+		ch <- map[string]int{}
+		close(ch)
+	}()
+
+	var out strings.Builder
+	enc := NewEncoder(&out)
+	enc.SetIndent(">", "  ")
+	if err := enc.EncodeLines(Values(ch)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "{\"a\":1,\"b\":2}\n{}\n"; out.String() != expected {
+		t.Errorf("lines wrong. expected=%q, got=%q", expected, out.String())
+	}
+
+	// the sequence stops being consumed at the first invalid value
+	consumed := 0
+	seq := func(yield func(interface{}) bool) {
+		for _, v := range []interface{}{"a", make(chan int), "c"} {
+			consumed++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	out.Reset()
+	if err := NewEncoder(&out).EncodeLines(seq); err == nil || out.String() != "\"a\"\n" || consumed != 2 {
+		t.Errorf("expected an error after one line, got %q, %v with %d values consumed", out.String(), err, consumed)
+	}
+}
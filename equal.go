@@ -0,0 +1,192 @@
+package gojson
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// EqualOptions controls how parsed values are compared by EqualWithOptions.
+type EqualOptions struct {
+	Tolerance        float64           // numbers differing by at most this much are equal
+	IgnoreArrayOrder bool              // compare arrays as multisets of elements
+	IgnorePaths      []pointer.Pointer // values at these paths, and below, are not compared
+}
+
+// Equal reports whether two parsed values are the same JSON value. Unlike
+// reflect.DeepEqual, it considers numbers equal whatever their
+// representation, so int64(1) equals float64(1) and parser.Number("1.0"), and
+// compares plain JsonObject maps and ordered objects by their members,
+// regardless of order.
+func Equal(a, b interface{}) bool {
+	return EqualWithOptions(a, b, EqualOptions{})
+}
+
+// EqualWithOptions reports whether two parsed values are the same JSON value
+// according to opts. When array order is ignored, each element of a is
+// matched with the first unmatched equal element of b, and paths below
+// arrays designate the elements of a.
+func EqualWithOptions(a, b interface{}, opts EqualOptions) bool {
+	e := equaler{opts: opts}
+	return e.equal(nil, a, b)
+}
+
+// equaler compares parsed values.
+type equaler struct {
+	opts EqualOptions
+}
+
+// equal reports whether a and b, found at path, are equal.
+func (e *equaler) equal(path pointer.Pointer, a, b interface{}) bool {
+	if e.ignored(path) {
+		return true
+	}
+
+	if n, ok := a.(parser.Number); ok {
+		return e.equalNumber(n, b)
+	}
+	if n, ok := b.(parser.Number); ok {
+		return e.equalNumber(n, a)
+	}
+
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok && e.opts.Tolerance == 0 {
+			return x == y // exact beyond the precision of doubles
+		}
+		return e.equalNumbers(float64(x), b)
+	case float64:
+		return e.equalNumbers(x, b)
+	case parser.JsonArray:
+		y, ok := b.(parser.JsonArray)
+		if !ok {
+			return false
+		}
+		if e.opts.IgnoreArrayOrder {
+			return e.equalUnordered(path, x, y)
+		}
+		if len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !e.equal(path.Append(strconv.Itoa(i)), x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if ka, ma, ok := parser.Members(a); ok {
+		kb, mb, ok := parser.Members(b)
+		if !ok {
+			return false
+		}
+		for _, k := range ka {
+			v, ok := mb[k]
+			if !ok && !e.ignored(path.Append(k)) || ok && !e.equal(path.Append(k), ma[k], v) {
+				return false
+			}
+		}
+		for _, k := range kb {
+			if _, ok := ma[k]; !ok && !e.ignored(path.Append(k)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch a.(type) {
+	case nil, bool, string:
+		return a == b
+	}
+	return false
+}
+
+// equalNumber reports whether b is a number equal to n. Without a tolerance,
+// n is compared exactly with integers and other Number values, and as the
+// nearest float64 with floats.
+func (e *equaler) equalNumber(n parser.Number, b interface{}) bool {
+	if e.opts.Tolerance == 0 {
+		var y *big.Float
+		switch m := b.(type) {
+		case int64:
+			y = new(big.Float).SetInt64(m)
+		case parser.Number:
+			var err error
+			if y, err = m.BigFloat(); err != nil {
+				return false
+			}
+		}
+		if y != nil {
+			x, err := n.BigFloat()
+			return err == nil && x.Cmp(y) == 0
+		}
+	}
+	f, err := n.Float64()
+	return err == nil && e.equalNumbers(f, b)
+}
+
+// equalNumbers reports whether b is a number equal to f.
+func (e *equaler) equalNumbers(f float64, b interface{}) bool {
+	var g float64
+	switch y := b.(type) {
+	case int64:
+		g = float64(y)
+	case float64:
+		g = y
+	case parser.Number:
+		var err error
+		if g, err = y.Float64(); err != nil {
+			return false
+		}
+	default:
+		return false
+	}
+	return f == g || math.Abs(f-g) <= e.opts.Tolerance
+}
+
+// equalUnordered reports whether arrays a and b hold equal elements, in any
+// order.
+func (e *equaler) equalUnordered(path pointer.Pointer, a, b parser.JsonArray) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	used := make([]bool, len(b))
+	for i, x := range a {
+		elementPath := path.Append(strconv.Itoa(i))
+		found := false
+		for j, y := range b {
+			if !used[j] && e.equal(elementPath, x, y) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ignored reports whether path is one of the ignored paths, or below one.
+func (e *equaler) ignored(path pointer.Pointer) bool {
+	for _, p := range e.opts.IgnorePaths {
+		if len(p) <= len(path) && equalTokens(p, path[:len(p)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalTokens reports whether two pointers have the same tokens.
+func equalTokens(a, b pointer.Pointer) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
@@ -0,0 +1,130 @@
+package gojson
+
+import (
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+func parseValue(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{`{"a": 1, "b": [true, null, "x"]}`, `{"b": [true, null, "x"], "a": 1.0}`, true},
+		{`1`, `1e0`, true},
+		{`9007199254740993`, `9007199254740992`, false},
+		{`{"a": 1}`, `{"a": 1, "b": 2}`, false},
+		{`{"a": 1, "b": 2}`, `{"a": 1}`, false},
+		{`[1, 2]`, `[2, 1]`, false},
+		{`[1]`, `{"0": 1}`, false},
+		{`"1"`, `1`, false},
+		{`null`, `false`, false},
+		{`{}`, `[]`, false},
+		{`{"caf\u00e9": "\u0041\/"}`, `{"café": "A/"}`, true},
+		{`["a\"b", "\ud83d\ude00"]`, `["a\u0022b", "😀"]`, true},
+		{`{"a\nb": 1}`, `{"a\\nb": 1}`, false},
+	}
+
+	for _, tt := range tests {
+		if got := Equal(parseValue(t, tt.a), parseValue(t, tt.b)); got != tt.expected {
+			t.Errorf("Equal(%s, %s): expected %v, got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+
+	ordered := parser.NewOrderedObject()
+	ordered.Set("b", parser.JsonArray{int64(1)})
+	ordered.Set("a", "x")
+	if !Equal(ordered, parseValue(t, `{"a": "x", "b": [1.0]}`)) {
+		t.Errorf("expected an ordered object to equal a map with the same members")
+	}
+}
+
+func TestEqualNumbers(t *testing.T) {
+	parseNumbers := func(input string) interface{} {
+		p := parser.NewParser(lexer.NewLexer(input))
+		p.UseNumber(true)
+		return p.ParseValue()
+	}
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{`1.0`, `1`, true},
+		{`1e2`, `100`, true},
+		{`0.1`, `0.1`, true},
+		{`9007199254740993`, `9007199254740993`, true},
+		{`9007199254740993`, `9007199254740992`, false},
+		{`123456789012345678901234567890`, `1.2345678901234567890123456789e29`, true},
+		{`1e400`, `1e400`, true},
+		{`1e400`, `1e401`, false},
+		{`1`, `"1"`, false},
+	}
+
+	for _, tt := range tests {
+		if got := Equal(parseNumbers(tt.a), parseNumbers(tt.b)); got != tt.expected {
+			t.Errorf("Equal(%s, %s) of Number values: expected %v, got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+
+	mixed := []struct {
+		number, value string
+		expected      bool
+	}{
+		{`1.0`, `1`, true},
+		{`0.1`, `0.1`, true},
+		{`9007199254740993`, `9007199254740993`, true},
+		{`9007199254740993`, `9007199254740992`, false},
+		{`[1, {"a": 2.5}]`, `[1.0, {"a": 25e-1}]`, true},
+		{`1`, `true`, false},
+	}
+	for _, tt := range mixed {
+		n, v := parseNumbers(tt.number), parseValue(t, tt.value)
+		if got := Equal(n, v); got != tt.expected {
+			t.Errorf("Equal(%s, %s) of a Number and a parsed value: expected %v, got %v", tt.number, tt.value, tt.expected, got)
+		}
+		if got := Equal(v, n); got != tt.expected {
+			t.Errorf("Equal(%s, %s) of a parsed value and a Number: expected %v, got %v", tt.value, tt.number, tt.expected, got)
+		}
+	}
+
+	if !EqualWithOptions(parseNumbers(`[0.1]`), parseValue(t, `[0.1000001]`), EqualOptions{Tolerance: 1e-6}) {
+		t.Errorf("expected a Number within the tolerance to be equal")
+	}
+}
+
+func TestEqualWithOptions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		opts     EqualOptions
+		expected bool
+	}{
+		{`[0.1, 0.2]`, `[0.1000001, 0.2]`, EqualOptions{Tolerance: 1e-6}, true},
+		{`[0.1, 0.2]`, `[0.11, 0.2]`, EqualOptions{Tolerance: 1e-6}, false},
+		{`[1, [2, 3], {"a": 4}]`, `[{"a": 4}, 1, [2, 3]]`, EqualOptions{IgnoreArrayOrder: true}, true},
+		{`[[2, 3]]`, `[[3, 2]]`, EqualOptions{IgnoreArrayOrder: true}, true},
+		{`[1, 1, 2]`, `[1, 2, 2]`, EqualOptions{IgnoreArrayOrder: true}, false},
+		{`{"id": 1, "meta": {"at": "now"}}`, `{"id": 1, "meta": {"at": "later"}}`, EqualOptions{IgnorePaths: []pointer.Pointer{pointer.MustParse("/meta/at")}}, true},
+		{`{"id": 1, "meta": {"at": "now"}}`, `{"id": 1}`, EqualOptions{IgnorePaths: []pointer.Pointer{pointer.MustParse("/meta")}}, true},
+		{`{"id": 1, "meta": {"at": "now"}}`, `{"id": 2}`, EqualOptions{IgnorePaths: []pointer.Pointer{pointer.MustParse("/meta")}}, false},
+		{`[{"id": 1, "at": 1}]`, `[{"id": 1, "at": 2}]`, EqualOptions{IgnorePaths: []pointer.Pointer{pointer.MustParse("/0/at")}}, true},
+		{`1`, `2`, EqualOptions{IgnorePaths: []pointer.Pointer{pointer.MustParse("")}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := EqualWithOptions(parseValue(t, tt.a), parseValue(t, tt.b), tt.opts); got != tt.expected {
+			t.Errorf("EqualWithOptions(%s, %s, %+v): expected %v, got %v", tt.a, tt.b, tt.opts, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package gojson
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// field describes how a struct field is encoded.
+type field struct {
+	name      string // key of the field in the JSON object
+	index     []int  // index sequence of the field in the struct, see reflect.Value.FieldByIndex
+	tagged    bool   // whether the name comes from a json tag
+	omitEmpty bool   // skip the field when it holds an empty value
+	asString  bool   // encode a scalar field inside a JSON string
+}
+
+// fieldCache maps a struct type to its []field.
+var fieldCache sync.Map
+
+// cachedTypeFields returns the encoded fields of struct type t, computing them
+// once per type.
+func cachedTypeFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.([]field)
+}
+
+// typeFields lists the fields of struct type t that are encoded, honoring the
+// `json:"name,omitempty,string"` tag syntax of encoding/json. Unexported
+// fields and fields tagged `json:"-"` are skipped. The fields of embedded
+// structs without a tag name are promoted as encoding/json does: when several
+// fields have the same name, the least nested one is kept, or the one with a
+// tag among those nested as deep, and none of them if that is ambiguous.
+func typeFields(t reflect.Type) []field {
+	var candidates []field
+	collectFields(t, nil, map[reflect.Type]bool{t: true}, &candidates)
+
+	byName := make(map[string][]field)
+	for _, f := range candidates {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var fields []field
+	for _, f := range candidates {
+		if dominant, ok := dominantField(byName[f.name]); ok && slices.Equal(dominant.index, f.index) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// collectFields appends the fields of struct type t, found at index inside
+// the struct being described, to fields, in the order of their index
+// sequences. visiting holds the embedded struct types being walked, whose
+// fields are not promoted again.
+func collectFields(t reflect.Type, index []int, visiting map[reflect.Type]bool, fields *[]field) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		if !isValidTag(name) {
+			name = ""
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+
+		if sf.Anonymous {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if name == "" && ft.Kind() == reflect.Struct {
+				// embedded structs, exported or not, have their fields promoted
+				if !visiting[ft] {
+					visiting[ft] = true
+					collectFields(ft, fieldIndex, visiting, fields)
+					delete(visiting, ft)
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := field{name: name, index: fieldIndex, tagged: name != "", omitEmpty: opts.contains("omitempty")}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		if opts.contains("string") {
+			// The string option only applies to fields of scalar types.
+			switch sf.Type.Kind() {
+			case reflect.Bool,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64,
+				reflect.String:
+				f.asString = true
+			}
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+// dominantField returns the field encoded among fields, which have the same
+// name, or false if none is.
+func dominantField(fields []field) (field, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields {
+		depth = min(depth, len(f.index))
+	}
+	var shallowest []field
+	for _, f := range fields {
+		if len(f.index) == depth {
+			shallowest = append(shallowest, f)
+		}
+	}
+	if len(shallowest) == 1 {
+		return shallowest[0], true
+	}
+	var tagged []field
+	for _, f := range shallowest {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+// fieldValue returns the field f of struct v, or false if f belongs to an
+// embedded struct v points to with a nil pointer.
+func fieldValue(v reflect.Value, f field) (reflect.Value, bool) {
+	for i, x := range f.index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// settableField returns the field f of struct v, allocating the embedded
+// structs v points to with a nil pointer on the way. It returns false if one
+// of them cannot be allocated, its type being unexported.
+func settableField(v reflect.Value, f field) (reflect.Value, bool) {
+	for i, x := range f.index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// tagOptions is the part of a struct tag following the name.
+type tagOptions string
+
+// parseTag splits a struct field's json tag into its name and its
+// comma-separated options.
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, tagOptions(opts)
+}
+
+// contains reports whether a comma-separated list of options contains the
+// given option.
+func (o tagOptions) contains(option string) bool {
+	s := string(o)
+	for s != "" {
+		var name string
+		name, s, _ = strings.Cut(s, ",")
+		if name == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidTag reports whether name can be used as a JSON key taken from a
+// struct tag.
+func isValidTag(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but otherwise any
+			// punctuation chars are allowed in a tag name.
+		case !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') && c < 0x80:
+			return false
+		}
+	}
+	return true
+}
+
+// isEmptyValue reports whether v is the zero value omitted by omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+// Package flatten turns documents produced by the parser package into flat
+// maps from path keys, such as "server.ports[0]", to scalar values, and
+// rebuilds documents from such maps, for interoperability with environment
+// variables, spreadsheets and other flat key/value stores.
+package flatten
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// ArrayStyle is the way array indices are written in path keys.
+type ArrayStyle int
+
+const (
+	DottedIndex  ArrayStyle = iota // indices are path segments, as in "ports.0"
+	BracketIndex                   // indices follow their array in brackets, as in "ports[0]"
+)
+
+// Options controls the syntax of path keys.
+type Options struct {
+	Separator string     // separator of object keys, "." when empty
+	Arrays    ArrayStyle // syntax of array indices
+}
+
+// separator returns the separator of object keys.
+func (o Options) separator() string {
+	if o.Separator == "" {
+		return "."
+	}
+	return o.Separator
+}
+
+// Flatten returns the scalar values of v keyed by their dotted path, array
+// indices being path segments, like {"server.ports.0": 80}.
+func Flatten(v interface{}) parser.JsonObject {
+	return FlattenWithOptions(v, Options{})
+}
+
+// FlattenWithOptions returns the scalar values of v keyed by their path,
+// written as opts says. Empty objects and arrays are kept as values so that
+// Unflatten restores them, and a scalar v is keyed by the empty string. Keys
+// are used as they are; keys holding the separator, or brackets with the
+// BracketIndex style, cannot be told apart from nested paths.
+func FlattenWithOptions(v interface{}, opts Options) parser.JsonObject {
+	flat := parser.JsonObject{}
+	flatten(flat, "", v, opts)
+	return flat
+}
+
+// flatten adds the values of v, found at path, to flat.
+func flatten(flat parser.JsonObject, path string, v interface{}, opts Options) {
+	switch x := v.(type) {
+	case parser.JsonArray:
+		if len(x) == 0 {
+			flat[path] = parser.JsonArray{}
+		}
+		for i, e := range x {
+			index := strconv.Itoa(i)
+			if opts.Arrays == BracketIndex {
+				flatten(flat, path+"["+index+"]", e, opts)
+			} else {
+				flatten(flat, join(path, index, opts), e, opts)
+			}
+		}
+		return
+	case *parser.OrderedObject:
+		if x.Len() == 0 {
+			flat[path] = parser.JsonObject{}
+		}
+		for _, k := range x.Keys {
+			flatten(flat, join(path, k, opts), x.Values[k], opts)
+		}
+		return
+	case parser.JsonObject:
+		if len(x) == 0 {
+			flat[path] = parser.JsonObject{}
+		}
+		for k, e := range x {
+			flatten(flat, join(path, k, opts), e, opts)
+		}
+		return
+	}
+	flat[path] = v
+}
+
+// join returns the path of the member key of the value at path.
+func join(path, key string, opts Options) string {
+	if path == "" {
+		return key
+	}
+	return path + opts.separator() + key
+}
+
+// Unflatten rebuilds the document described by a map of dotted path keys, as
+// returned by Flatten.
+func Unflatten(flat map[string]interface{}) (interface{}, error) {
+	return UnflattenWithOptions(flat, Options{})
+}
+
+// UnflattenWithOptions rebuilds the document described by a map of path keys
+// written as opts says. Objects are rebuilt as parser.JsonObject maps. With the
+// DottedIndex style, segments made of digits only are array indices. Array
+// elements missing from the map are null. Keys assigning both a value and
+// members to the same path are reported as errors.
+func UnflattenWithOptions(flat map[string]interface{}, opts Options) (interface{}, error) {
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var root interface{}
+	for _, k := range keys {
+		path, err := parsePath(k, opts)
+		if err != nil {
+			return nil, err
+		}
+		if root, err = assign(root, path, flat[k]); err != nil {
+			return nil, fmt.Errorf("flatten: key %q: %v", k, err)
+		}
+	}
+	return root, nil
+}
+
+// step is an element of a path: an object key or an array index.
+type step struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// parsePath splits a path key into its steps.
+func parsePath(k string, opts Options) ([]step, error) {
+	if k == "" {
+		return nil, nil
+	}
+
+	var path []step
+	for i, segment := range strings.Split(k, opts.separator()) {
+		if opts.Arrays == DottedIndex {
+			if index, err := strconv.Atoi(segment); err == nil && index >= 0 && strings.Trim(segment, "0123456789") == "" {
+				path = append(path, step{index: index})
+			} else {
+				path = append(path, step{key: segment, isKey: true})
+			}
+			continue
+		}
+
+		key := segment
+		if open := strings.IndexByte(segment, '['); open >= 0 {
+			key = segment[:open]
+		}
+		if key != "" || i > 0 || len(key) == len(segment) {
+			path = append(path, step{key: key, isKey: true})
+		}
+		for rest := segment[len(key):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("flatten: key %q: invalid index", k)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("flatten: key %q: invalid index %q", k, rest[1:end])
+			}
+			path = append(path, step{index: index})
+			rest = rest[end+1:]
+		}
+	}
+	return path, nil
+}
+
+// assign sets value at path inside node and returns the updated node.
+func assign(node interface{}, path []step, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		if node != nil {
+			return nil, fmt.Errorf("conflicting values")
+		}
+		// Copy empty containers, which members of other keys may be added to.
+		switch x := value.(type) {
+		case parser.JsonObject:
+			if len(x) == 0 {
+				return parser.JsonObject{}, nil
+			}
+		case parser.JsonArray:
+			if len(x) == 0 {
+				return parser.JsonArray{}, nil
+			}
+		}
+		return value, nil
+	}
+
+	s := path[0]
+	if s.isKey {
+		obj, ok := node.(parser.JsonObject)
+		if !ok && node != nil {
+			return nil, fmt.Errorf("cannot set member %q of a non-object value", s.key)
+		}
+		if obj == nil {
+			obj = parser.JsonObject{}
+		}
+		child, err := assign(obj[s.key], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		obj[s.key] = child
+		return obj, nil
+	}
+
+	arr, ok := node.(parser.JsonArray)
+	if !ok && node != nil {
+		return nil, fmt.Errorf("cannot set index %d of a non-array value", s.index)
+	}
+	for len(arr) <= s.index {
+		arr = append(arr, nil)
+	}
+	child, err := assign(arr[s.index], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	arr[s.index] = child
+	return arr, nil
+}
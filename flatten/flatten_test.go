@@ -0,0 +1,103 @@
+package flatten
+
+import (
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+// compact lays v out on a single line.
+func compact(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+const document = `{"server": {"host": "localhost", "ports": [80, 443], "tls": {}}, "tags": [], "debug": false, "users": [{"name": "a"}, null]}`
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{},
+			`{"debug": false, "server.host": "localhost", "server.ports.0": 80, "server.ports.1": 443, "server.tls": {}, "tags": [], "users.0.name": "a", "users.1": null}`},
+		{Options{Separator: "__", Arrays: BracketIndex},
+			`{"debug": false, "server__host": "localhost", "server__ports[0]": 80, "server__ports[1]": 443, "server__tls": {}, "tags": [], "users[0]__name": "a", "users[1]": null}`},
+	}
+
+	for _, tt := range tests {
+		flat := FlattenWithOptions(parse(t, document), tt.opts)
+		if got := compact(flat); got != tt.expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, got)
+		}
+		v, err := UnflattenWithOptions(flat, tt.opts)
+		if err != nil {
+			t.Fatalf("UnflattenWithOptions: unexpected error: %v", err)
+		}
+		if got, expected := compact(v), compact(parse(t, document)); got != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+		}
+	}
+
+	if got := compact(Flatten(parse(t, `[[1], 2]`))); got != `{"0.0": 1, "1": 2}` {
+		t.Errorf("unexpected flattening of an array: %s", got)
+	}
+	if got := compact(FlattenWithOptions(parse(t, `[[1], 2]`), Options{Arrays: BracketIndex})); got != `{"[0][0]": 1, "[1]": 2}` {
+		t.Errorf("unexpected flattening of an array: %s", got)
+	}
+	if got := compact(Flatten(int64(1))); got != `{"": 1}` {
+		t.Errorf("unexpected flattening of a scalar: %s", got)
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	tests := []struct {
+		flat     map[string]interface{}
+		opts     Options
+		expected string
+	}{
+		{map[string]interface{}{"a.b": int64(1), "a.c.2": true, "a.d": parser.JsonObject{}, "a.d.e": "x"}, Options{},
+			`{"a": {"b": 1, "c": [null, null, true], "d": {"e": "x"}}}`},
+		{map[string]interface{}{"[1][0]": int64(1), "[0].a[1]": int64(2)}, Options{Arrays: BracketIndex},
+			`[{"a": [null, 2]}, [1]]`},
+		{map[string]interface{}{"x/0": "kept", "x/y[0]": "z"}, Options{Separator: "/", Arrays: BracketIndex},
+			`{"x": {"0": "kept", "y": ["z"]}}`},
+		{map[string]interface{}{"": "scalar"}, Options{}, `"scalar"`},
+	}
+
+	for _, tt := range tests {
+		v, err := UnflattenWithOptions(tt.flat, tt.opts)
+		if err != nil {
+			t.Fatalf("UnflattenWithOptions(%v): unexpected error: %v", tt.flat, err)
+		}
+		if got := compact(v); got != tt.expected {
+			t.Errorf("UnflattenWithOptions(%v): expected %s, got %s", tt.flat, tt.expected, got)
+		}
+	}
+
+	errors := []struct {
+		flat map[string]interface{}
+		opts Options
+	}{
+		{map[string]interface{}{"a": int64(1), "a.b": int64(2)}, Options{}},
+		{map[string]interface{}{"a.0": int64(1), "a.b": int64(2)}, Options{}},
+		{map[string]interface{}{"a[x]": int64(1)}, Options{Arrays: BracketIndex}},
+		{map[string]interface{}{"a[1": int64(1)}, Options{Arrays: BracketIndex}},
+		{map[string]interface{}{"a[0]b": int64(1)}, Options{Arrays: BracketIndex}},
+	}
+	for _, tt := range errors {
+		if _, err := UnflattenWithOptions(tt.flat, tt.opts); err == nil {
+			t.Errorf("UnflattenWithOptions(%v): expected an error", tt.flat)
+		}
+	}
+}
@@ -1,3 +1,9 @@
 module github.com/oabrivard/gojson
 
-go 1.21.4
+go 1.23
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	google.golang.org/protobuf v1.36.12
+	gopkg.in/yaml.v3 v3.0.1
+)
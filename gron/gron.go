@@ -0,0 +1,230 @@
+// Package gron turns documents produced by the parser package into discrete
+// assignments, one per line, that can be filtered with grep and turned back
+// into a document:
+//
+//	json = {};
+//	json.users = [];
+//	json.users[0] = {};
+//	json.users[0].name = "Alice";
+//	json.users[0]["e-mail"] = "alice@example.com";
+//
+// Ungron accepts any subset of such lines in any order, creating the objects
+// and arrays they go through.
+package gron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Root is the name of the variable holding the whole document.
+const Root = "json"
+
+// Statements returns the assignments describing v, containers being assigned
+// before their members. Members of ordered objects keep their order, those of
+// plain JsonObject maps are sorted by key.
+func Statements(v interface{}) []string {
+	var statements []string
+	walk(Root, v, &statements)
+	return statements
+}
+
+// Format returns the statements describing v, each ending with a newline.
+func Format(v interface{}) string {
+	var result strings.Builder
+	for _, s := range Statements(v) {
+		result.WriteString(s)
+		result.WriteByte('\n')
+	}
+	return result.String()
+}
+
+// walk appends the statements of v, found at path.
+func walk(path string, v interface{}, statements *[]string) {
+	switch x := v.(type) {
+	case parser.JsonArray:
+		*statements = append(*statements, path+" = [];")
+		for i, e := range x {
+			walk(path+"["+strconv.Itoa(i)+"]", e, statements)
+		}
+		return
+	}
+
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		*statements = append(*statements, path+" = "+linter.Format(v, linter.DefaultOptions())+";")
+		return
+	}
+	*statements = append(*statements, path+" = {};")
+	for _, k := range keys {
+		walk(path+member(k), members[k], statements)
+	}
+}
+
+// member returns the accessor of an object member: ".key" for keys that are
+// identifiers, `["key"]` for others, escaped as JSON strings.
+func member(key string) string {
+	if isIdentifier(key) {
+		return "." + key
+	}
+	return `["` + lexer.Escape(key) + `"]`
+}
+
+// isIdentifier reports whether s is a JavaScript identifier made of ASCII
+// characters.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		letter := c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Ungron rebuilds a document from statements, one per line, as written by
+// Format. Empty lines are ignored. Objects and arrays are created as needed,
+// objects being *parser.OrderedObject values with members in the order they
+// are first assigned, and array elements never assigned are null.
+func Ungron(text string) (interface{}, error) {
+	var doc interface{}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		path, value, err := parseStatement(line)
+		if err != nil {
+			return nil, fmt.Errorf("gron: line %d: %v", i+1, err)
+		}
+		doc = assign(doc, path, value)
+	}
+	return doc, nil
+}
+
+// step is an element of the path of a statement: an object key or an array
+// index.
+type step struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// parseStatement splits a statement into the path it assigns, without the
+// root variable, and the value it assigns.
+func parseStatement(line string) ([]step, interface{}, error) {
+	i := 0
+	for i < len(line) && line[i] != '.' && line[i] != '[' && line[i] != ' ' && line[i] != '=' {
+		i++
+	}
+	if !isIdentifier(line[:i]) {
+		return nil, nil, fmt.Errorf("expected a variable name")
+	}
+
+	var path []step
+	for i < len(line) && (line[i] == '.' || line[i] == '[') {
+		switch {
+		case line[i] == '.':
+			start := i + 1
+			for i++; i < len(line) && line[i] != '.' && line[i] != '[' && line[i] != ' ' && line[i] != '='; i++ {
+			}
+			if !isIdentifier(line[start:i]) {
+				return nil, nil, fmt.Errorf("invalid member name %q", line[start:i])
+			}
+			path = append(path, step{key: line[start:i], isKey: true})
+		case strings.HasPrefix(line[i:], `["`):
+			start := i + 2
+			for i = start; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if !strings.HasPrefix(line[min(i, len(line)):], `"]`) {
+				return nil, nil, fmt.Errorf("unterminated member name")
+			}
+			key, ok := lexer.Unescape(line[start:i])
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid member name %q", line[start:i])
+			}
+			path = append(path, step{key: key, isKey: true})
+			i += 2
+		default:
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				return nil, nil, fmt.Errorf("unterminated index")
+			}
+			index, err := strconv.Atoi(line[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, nil, fmt.Errorf("invalid index %q", line[i+1:i+end])
+			}
+			path = append(path, step{index: index})
+			i += end + 1
+		}
+	}
+
+	rest := strings.TrimSpace(line[i:])
+	if !strings.HasPrefix(rest, "=") {
+		return nil, nil, fmt.Errorf("expected \"=\"")
+	}
+	rest = strings.TrimSuffix(strings.TrimSpace(rest[1:]), ";")
+
+	p := parser.NewParser(lexer.NewLexer(rest))
+	value := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		return nil, nil, fmt.Errorf("invalid value: %v", p.Errors())
+	}
+	return path, value, nil
+}
+
+// assign sets value at path inside node and returns the updated node.
+// Assigning an empty object or array to an existing object or array keeps
+// it, so that statements can come in any order.
+func assign(node interface{}, path []step, value interface{}) interface{} {
+	if len(path) == 0 {
+		switch x := value.(type) {
+		case parser.JsonObject:
+			if _, _, ok := parser.Members(node); ok && len(x) == 0 {
+				return node
+			}
+			if len(x) == 0 {
+				return parser.NewOrderedObject()
+			}
+		case parser.JsonArray:
+			if _, ok := node.(parser.JsonArray); ok && len(x) == 0 {
+				return node
+			}
+		}
+		return value
+	}
+
+	s := path[0]
+	if s.isKey {
+		switch obj := node.(type) {
+		case *parser.OrderedObject:
+			child, _ := obj.Get(s.key)
+			obj.Set(s.key, assign(child, path[1:], value))
+			return obj
+		case parser.JsonObject:
+			obj[s.key] = assign(obj[s.key], path[1:], value)
+			return obj
+		}
+		obj := parser.NewOrderedObject()
+		obj.Set(s.key, assign(nil, path[1:], value))
+		return obj
+	}
+
+	arr, _ := node.(parser.JsonArray)
+	for len(arr) <= s.index {
+		arr = append(arr, nil)
+	}
+	arr[s.index] = assign(arr[s.index], path[1:], value)
+	return arr
+}
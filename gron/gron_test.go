@@ -0,0 +1,97 @@
+package gron
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+// compact lays v out on a single line.
+func compact(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+const document = `{"users": [{"name": "Alice", "e-mail": "a@example.com", "tags": []}, null], "count": 2.5, "ok": true, "say \"hi\"": {}}`
+
+const statements = `json = {};
+json.count = 2.5;
+json.ok = true;
+json["say \"hi\""] = {};
+json.users = [];
+json.users[0] = {};
+json.users[0]["e-mail"] = "a@example.com";
+json.users[0].name = "Alice";
+json.users[0].tags = [];
+json.users[1] = null;
+`
+
+func TestFormat(t *testing.T) {
+	if got := Format(parse(t, document)); got != statements {
+		t.Errorf("expected:\n%s\ngot:\n%s", statements, got)
+	}
+
+	obj := parser.NewOrderedObject()
+	obj.Set("b", int64(1))
+	obj.Set("a", parser.JsonArray{"x"})
+	expected := []string{`json = {};`, `json.b = 1;`, `json.a = [];`, `json.a[0] = "x";`}
+	if got := Statements(obj); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	if got := Format(int64(3)); got != "json = 3;\n" {
+		t.Errorf("expected a single statement, got %q", got)
+	}
+}
+
+func TestUngron(t *testing.T) {
+	v, err := Ungron(statements)
+	if err != nil {
+		t.Fatalf("Ungron: unexpected error: %v", err)
+	}
+	expected := `{"count": 2.5, "ok": true, "say \"hi\"": {}, "users": [{"e-mail": "a@example.com", "name": "Alice", "tags": []}, null]}`
+	if got := compact(v); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// Filtered lines, in any order, still make a document.
+	v, err = Ungron("json.users[2].name = \"Carol\";\n\njson.users[0].name = \"Alice\"\njson.users = [];\n")
+	if err != nil {
+		t.Fatalf("Ungron: unexpected error: %v", err)
+	}
+	expected = `{"users": [{"name": "Alice"}, null, {"name": "Carol"}]}`
+	if got := compact(v); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestUngronErrors(t *testing.T) {
+	for _, input := range []string{
+		`json.a`,
+		`json.a = `,
+		`json.a = {"b"`,
+		`json[-1] = 1;`,
+		`json[1 = 1;`,
+		`json["a\q"] = 1;`,
+		`json["a = 1;`,
+		`json.1a = 1;`,
+		`= 1;`,
+	} {
+		if _, err := Ungron(input); err == nil {
+			t.Errorf("Ungron(%s): expected an error", input)
+		} else if !strings.HasPrefix(err.Error(), "gron: line 1: ") {
+			t.Errorf("Ungron(%s): unexpected error %v", input, err)
+		}
+	}
+}
@@ -0,0 +1,527 @@
+// Package hjson reads Hjson documents, a syntax for configuration files
+// edited by hand, into the values the parser package produces, so that they
+// can be validated and converted to strict JSON. On top of JSON, Hjson allows
+// #, // and /* */ comments, keys and strings without quotes, strings between
+// single quotes, multi-line strings between triple single quotes, commas left
+// out at the end of lines, trailing commas and the braces of the root object
+// left out. See https://hjson.github.io/syntax.html.
+package hjson
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Parse parses the Hjson document held by data into the values used by the
+// parser package: objects are *parser.OrderedObject keeping their keys in
+// document order, strings hold their decoded characters. Syntax errors are
+// reported by a *SyntaxError.
+func Parse(data []byte) (interface{}, error) {
+	d := &decoder{data: data, line: 1, column: 1}
+	return d.document()
+}
+
+// ToJSON converts the Hjson document held by data to strict JSON laid out
+// according to opts. Comments are dropped.
+func ToJSON(data []byte, opts linter.Options) ([]byte, error) {
+	v, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(linter.Format(v, opts) + "\n"), nil
+}
+
+// SyntaxError is an error of an Hjson document.
+type SyntaxError struct {
+	Msg    string // description of the error
+	Line   int    // line of the error
+	Column int    // column of the error, in bytes
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("hjson: %s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
+
+// decoder parses an Hjson document.
+type decoder struct {
+	data   []byte
+	pos    int // offset of the next character
+	line   int // line of the next character
+	column int // column of the next character
+}
+
+// errorf returns an error located at the next character.
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Msg: fmt.Sprintf(format, args...), Line: d.line, Column: d.column}
+}
+
+// peek returns the next byte, or 0 at the end of the input.
+func (d *decoder) peek() byte {
+	if d.pos >= len(d.data) {
+		return 0
+	}
+	return d.data[d.pos]
+}
+
+// peekAt returns the byte n bytes after the next one, or 0.
+func (d *decoder) peekAt(n int) byte {
+	if d.pos+n >= len(d.data) {
+		return 0
+	}
+	return d.data[d.pos+n]
+}
+
+// next consumes and returns the next byte.
+func (d *decoder) next() byte {
+	c := d.peek()
+	d.pos++
+	if c == '\n' {
+		d.line++
+		d.column = 1
+	} else {
+		d.column++
+	}
+	return c
+}
+
+// atEOF reports whether the whole input has been consumed.
+func (d *decoder) atEOF() bool {
+	return d.pos >= len(d.data)
+}
+
+// describe returns how the next character appears in error messages.
+func (d *decoder) describe() string {
+	if d.atEOF() {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRune(d.data[d.pos:])
+	return fmt.Sprintf("%q", r)
+}
+
+// document parses the document, a single value or the members of an object
+// without braces.
+func (d *decoder) document() (interface{}, error) {
+	if err := d.skipSpace(); err != nil {
+		return nil, err
+	}
+	if c := d.peek(); c != '{' && c != '[' {
+		start := *d
+		obj, err := d.members(nil)
+		if err == nil {
+			return obj, nil
+		}
+		// not an object without braces, maybe a lone value
+		objErr := err
+		*d = start
+		v, err := d.rootValue()
+		if err != nil {
+			return nil, objErr
+		}
+		return v, nil
+	}
+	return d.rootValue()
+}
+
+// rootValue parses the single value of the document.
+func (d *decoder) rootValue() (interface{}, error) {
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.skipSpace(); err != nil {
+		return nil, err
+	}
+	if !d.atEOF() {
+		return nil, d.errorf("unexpected %s after the end of the document", d.describe())
+	}
+	return v, nil
+}
+
+// isPunctuator reports whether c is one of the characters structuring a
+// document, which quoteless keys and strings cannot start with.
+func isPunctuator(c byte) bool {
+	return strings.IndexByte("{}[],:", c) >= 0
+}
+
+// isSpace reports whether c is white space.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// atComment reports whether a comment starts at the next character.
+func (d *decoder) atComment() bool {
+	c := d.peek()
+	return c == '#' || c == '/' && (d.peekAt(1) == '/' || d.peekAt(1) == '*')
+}
+
+// skipSpace skips white space, line breaks and comments.
+func (d *decoder) skipSpace() error {
+	for !d.atEOF() {
+		switch {
+		case isSpace(d.peek()):
+			d.next()
+		case d.peek() == '#' || d.peek() == '/' && d.peekAt(1) == '/':
+			for !d.atEOF() && d.peek() != '\n' {
+				d.next()
+			}
+		case d.peek() == '/' && d.peekAt(1) == '*':
+			start := *d
+			d.next()
+			d.next()
+			for !(d.peek() == '*' && d.peekAt(1) == '/') {
+				if d.atEOF() {
+					return start.errorf("unterminated comment")
+				}
+				d.next()
+			}
+			d.next()
+			d.next()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// value parses a value.
+func (d *decoder) value() (interface{}, error) {
+	switch c := d.peek(); {
+	case c == '{':
+		return d.object()
+	case c == '[':
+		return d.array()
+	case c == '\'' && d.peekAt(1) == '\'' && d.peekAt(2) == '\'':
+		return d.multilineString()
+	case c == '"' || c == '\'':
+		return d.quotedString()
+	case d.atEOF():
+		return nil, d.errorf("unexpected end of input")
+	case isPunctuator(c):
+		return nil, d.errorf("unexpected %s", d.describe())
+	}
+	return d.quoteless()
+}
+
+// object parses an object, the next character being {.
+func (d *decoder) object() (interface{}, error) {
+	open := *d
+	d.next()
+	return d.members(&open)
+}
+
+// members parses the members of an object up to its closing brace, already
+// past its opening one found at open, or up to the end of the input for the
+// root object without braces, open being nil.
+func (d *decoder) members(open *decoder) (interface{}, error) {
+	obj := parser.NewOrderedObject()
+	for {
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.atEOF() {
+			if open == nil {
+				return obj, nil
+			}
+			return nil, unterminated(*open, "object")
+		}
+		if open != nil && d.peek() == '}' {
+			d.next()
+			return obj, nil
+		}
+
+		key, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.peek() != ':' {
+			return nil, d.errorf("expected ':', got %s", d.describe())
+		}
+		d.next()
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(key, v)
+
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.peek() == ',' {
+			d.next()
+		}
+	}
+}
+
+// unterminated returns the error of an object or array opened at start and
+// never closed.
+func unterminated(start decoder, container string) error {
+	return start.errorf("unterminated %s, note that strings without quotes run to the end of their line", container)
+}
+
+// key parses an object key, quoted or not.
+func (d *decoder) key() (string, error) {
+	switch c := d.peek(); {
+	case c == '"' || c == '\'':
+		return d.quotedString()
+	case d.atEOF():
+		return "", d.errorf("expected a key, got end of input")
+	case isPunctuator(c):
+		return "", d.errorf("expected a key, got %s", d.describe())
+	}
+
+	var result strings.Builder
+	for !d.atEOF() && d.peek() != ':' {
+		c := d.peek()
+		if isSpace(c) {
+			next := *d
+			next.skipSpace()
+			if next.peek() == ':' {
+				break
+			}
+			return "", d.errorf("white space in a key, quote the key to include it")
+		}
+		if isPunctuator(c) {
+			return "", d.errorf("unexpected %s in a key, quote the key to include it", d.describe())
+		}
+		if err := d.writeRaw(&result); err != nil {
+			return "", err
+		}
+	}
+	return result.String(), nil
+}
+
+// array parses an array, the next character being [.
+func (d *decoder) array() (interface{}, error) {
+	start := *d
+	arr := parser.JsonArray{}
+	d.next()
+	for {
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.atEOF() {
+			return nil, unterminated(start, "array")
+		}
+		if d.peek() == ']' {
+			d.next()
+			return arr, nil
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+
+		if err := d.skipSpace(); err != nil {
+			return nil, err
+		}
+		if d.peek() == ',' {
+			d.next()
+		}
+	}
+}
+
+// quoteless parses a value without quotes: true, false, null or a number
+// when followed by the end of the line, a comma, a closing bracket or a
+// comment, or else a string running to the end of the line, trailing white
+// space excluded.
+func (d *decoder) quoteless() (interface{}, error) {
+	var text strings.Builder
+	for {
+		c := d.peek()
+		if d.atEOF() || c == '\n' || c == '\r' || c == ',' || c == '}' || c == ']' || d.atComment() {
+			literal := strings.TrimRight(text.String(), " \t")
+			switch literal {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			case "null":
+				return nil, nil
+			}
+			if isNumber(literal) {
+				return number(literal), nil
+			}
+			if d.atEOF() || c == '\n' || c == '\r' {
+				return literal, nil
+			}
+		}
+		if err := d.writeRaw(&text); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeRaw consumes the next character and writes it to result.
+func (d *decoder) writeRaw(result *strings.Builder) error {
+	r, size := utf8.DecodeRune(d.data[d.pos:])
+	if r == utf8.RuneError && size == 1 {
+		return d.errorf("invalid UTF-8")
+	}
+	for i := 0; i < size; i++ {
+		d.next()
+	}
+	result.WriteRune(r)
+	return nil
+}
+
+// isNumber reports whether s is a number in the JSON syntax.
+func isNumber(s string) bool {
+	i := 0
+	digits := func() int {
+		start := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if n := digits(); n == 0 || n > 1 && s[i-n] == '0' {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// number returns the value of the number s, an int64, or a float64 if it has
+// a fraction or an exponent or does not fit in an int64.
+func number(s string) interface{} {
+	n, err := parser.ParseNumber(s)
+	if err != nil && !strings.ContainsAny(s, ".eE") {
+		n, err = parser.ParseNumber(s + ".0")
+	}
+	if err != nil {
+		// out of the range of a float64, kept as written
+		return s
+	}
+	return n
+}
+
+// quotedString parses a string between double or single quotes and returns
+// its characters. Escape sequences are copied in their strict JSON form and
+// decoded by lexer.Unescape once the string ends, surrogate pairs included.
+func (d *decoder) quotedString() (string, error) {
+	start := *d
+	quote := d.next()
+	var result strings.Builder
+	for {
+		switch c := d.peek(); {
+		case d.atEOF():
+			return "", start.errorf("unterminated string")
+		case c == quote:
+			d.next()
+			s, _ := lexer.Unescape(result.String())
+			return s, nil
+		case c == '\n' || c == '\r':
+			return "", d.errorf("line break in a string, use a multi-line string between ''' instead")
+		case c == '\\':
+			d.next()
+			switch e := d.peek(); e {
+			case 'b', 'f', 'n', 'r', 't', '"', '\\', '/':
+				d.next()
+				result.WriteByte('\\')
+				result.WriteByte(e)
+			case '\'':
+				d.next()
+				result.WriteByte('\'')
+			case 'u':
+				d.next()
+				begin := d.pos
+				for i := 0; i < 4; i++ {
+					if !isHexDigit(d.peek()) {
+						return "", d.errorf("expected a hexadecimal digit, got %s", d.describe())
+					}
+					d.next()
+				}
+				result.WriteString(`\u`)
+				result.Write(d.data[begin:d.pos])
+			default:
+				return "", d.errorf("invalid escape sequence '\\%s'", strings.Trim(d.describe(), `'"`))
+			}
+		default:
+			if err := d.writeRaw(&result); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// isHexDigit reports whether c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// multilineString parses a string between triple single quotes, the next
+// characters being the opening quotes. Its lines lose as many leading spaces as there
+// are characters before the opening quotes on their line; the line breaks
+// following the opening quotes and preceding the closing ones are dropped.
+// Escape sequences are not interpreted.
+func (d *decoder) multilineString() (string, error) {
+	start := *d
+	indent := d.column - 1
+	d.next()
+	d.next()
+	d.next()
+	skipIndent := func() {
+		for i := 0; i < indent && (d.peek() == ' ' || d.peek() == '\t'); i++ {
+			d.next()
+		}
+	}
+
+	for d.peek() == ' ' || d.peek() == '\t' || d.peek() == '\r' {
+		d.next()
+	}
+	if d.peek() == '\n' {
+		d.next()
+		skipIndent()
+	}
+
+	var text strings.Builder
+	for {
+		switch {
+		case d.atEOF():
+			return "", start.errorf("unterminated multi-line string")
+		case d.peek() == '\'' && d.peekAt(1) == '\'' && d.peekAt(2) == '\'':
+			d.next()
+			d.next()
+			d.next()
+			return strings.TrimSuffix(text.String(), "\n"), nil
+		case d.peek() == '\n':
+			d.next()
+			text.WriteByte('\n')
+			skipIndent()
+		case d.peek() == '\r':
+			d.next()
+		default:
+			text.WriteByte(d.next())
+		}
+	}
+}
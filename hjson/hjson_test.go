@@ -0,0 +1,86 @@
+package hjson
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oabrivard/gojson/linter"
+)
+
+func compact(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1, "b": [true, null]}`, `{"a": 1, "b": [true, null]}`},
+		{"{\n  # comment\n  name: John Smith\n  age: 42 // years\n  tags: [\n    a\n    b\n  ]\n}", `{"name": "John Smith", "age": 42, "tags": ["a", "b"]}`},
+		{"name: gojson\nversion: 1.5\n", `{"name": "gojson", "version": 1.5}`},
+		{"{a: hello, world # not a comment\n}", `{"a": "hello, world # not a comment"}`},
+		{"[1, 2,]", `[1, 2]`},
+		{"{\n  a: true1\n  b: 'single \"quoted\"'\n  c: \"tab\\tand \\u00e9\"\n}", `{"a": "true1", "b": "single \"quoted\"", "c": "tab\tand é"}`},
+		{"{b: 'it\\'s', c: \"x\"}", `{"b": "it's", "c": "x"}`},
+		{"{\n  text:\n    '''\n    first\n      second\n    '''\n}", `{"text": "first\n  second"}`},
+		{"{quote: '''a \"b\" \\n'''}", `{"quote": "a \"b\" \\n"}`},
+		{"{\n  n: 007\n  e: 1e3\n  neg: -2\n  big: 12345678901234567890\n}", `{"n": "007", "e": 1000, "neg": -2, "big": 1.2345678901234567e+19}`},
+		{"/* header */ [x\n]", `["x"]`},
+		{"", `{}`},
+		{"42", `42`},
+		{"just a string", `"just a string"`},
+	}
+
+	for _, test := range tests {
+		v, err := Parse([]byte(test.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+			continue
+		}
+		if compact(v) != test.expected {
+			t.Errorf("%q: got %s, want %s", test.input, compact(v), test.expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{\n  a: 1\n", "hjson: unterminated object, note that strings without quotes run to the end of their line at line 1, column 1"},
+		{"[a, b]", "hjson: unterminated array, note that strings without quotes run to the end of their line at line 1, column 1"},
+		{"{a b: 1}", "hjson: white space in a key, quote the key to include it at line 1, column 3"},
+		{"{a: 'x\n'}", "hjson: line break in a string, use a multi-line string between ''' instead at line 1, column 7"},
+		{"{a: '''x}", "hjson: unterminated multi-line string at line 1, column 5"},
+		{"{a: ,}", "hjson: unexpected ',' at line 1, column 5"},
+		{"{a: 1} x", "hjson: unexpected 'x' after the end of the document at line 1, column 8"},
+		{"/* open", "hjson: unterminated comment at line 1, column 1"},
+		{`{a: "\x"}`, `hjson: invalid escape sequence '\x' at line 1, column 7`},
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", test.input, err)
+			continue
+		}
+		if err.Error() != test.expected {
+			t.Errorf("%q: got %q, want %q", test.input, err.Error(), test.expected)
+		}
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	input := "# server settings\nhost: localhost\nport: 8080\n"
+	expected := "{\n  \"host\": \"localhost\",\n  \"port\": 8080\n}\n"
+	got, err := ToJSON([]byte(input), linter.DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
@@ -0,0 +1,146 @@
+package httpjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/stream"
+)
+
+// Default limits of DecodeResponse.
+const (
+	DefaultMaxBodySize = 10 << 20 // 10 MiB
+	DefaultMaxDepth    = 1000
+)
+
+// ErrBodyTooLarge is returned, wrapped, by DecodeResponse for bodies larger
+// than DecodeOptions.MaxBodySize.
+var ErrBodyTooLarge = errors.New("httpjson: response body too large")
+
+// DecodeOptions controls what DecodeResponse accepts.
+type DecodeOptions struct {
+	// ContentTypes are the accepted media types, application/json and the
+	// types with a +json suffix when empty.
+	ContentTypes []string
+	// MaxBodySize is the maximum size of the body in bytes,
+	// DefaultMaxBodySize when 0, and unlimited when negative.
+	MaxBodySize int64
+	// MaxDepth is the maximum number of containers nested inside each other,
+	// DefaultMaxDepth when 0, and unlimited when negative.
+	MaxDepth int
+}
+
+// ResponseError is a failure to decode a response, with the request it
+// answered.
+type ResponseError struct {
+	Method     string // method of the request, if known
+	URL        string // URL of the request, without its password, if known
+	StatusCode int
+	Err        error
+}
+
+func (e *ResponseError) Error() string {
+	if e.URL == "" {
+		return fmt.Sprintf("httpjson: response with status %d: %v", e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("httpjson: %s %s: response with status %d: %v", e.Method, e.URL, e.StatusCode, e.Err)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeResponse decodes the JSON value held by the body of resp into the
+// value v points to, and closes the body. A *interface{} is set to the value
+// as the parser builds it, objects being decoded as *parser.OrderedObject
+// values, while other targets, such as structs, are filled by
+// gojson.Unmarshal. Responses whose content type is not accepted, or which
+// exceed the limits of opts, are rejected without being decoded. Errors are
+// returned as *ResponseError values.
+func DecodeResponse(resp *http.Response, v interface{}, opts DecodeOptions) error {
+	defer resp.Body.Close()
+
+	data, value, err := decodeBody(resp, opts)
+	if err == nil {
+		if p, ok := v.(*interface{}); ok && p != nil {
+			*p = value
+		} else {
+			err = gojson.Unmarshal(data, v)
+		}
+	}
+	if err != nil {
+		respErr := &ResponseError{StatusCode: resp.StatusCode, Err: err}
+		if req := resp.Request; req != nil && req.URL != nil {
+			respErr.Method, respErr.URL = req.Method, req.URL.Redacted()
+		}
+		return respErr
+	}
+	return nil
+}
+
+// decodeBody reads the body of resp and decodes it according to opts,
+// returning its bytes and its value.
+func decodeBody(resp *http.Response, opts DecodeOptions) ([]byte, interface{}, error) {
+	if err := checkContentType(resp.Header.Get("Content-Type"), opts.ContentTypes); err != nil {
+		return nil, nil, err
+	}
+
+	body := io.Reader(resp.Body)
+	maxBodySize := opts.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	if maxBodySize > 0 {
+		body = io.LimitReader(body, maxBodySize+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading the body: %w", err)
+	}
+	if maxBodySize > 0 && int64(len(data)) > maxBodySize {
+		return nil, nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, maxBodySize)
+	}
+
+	r := stream.NewReader(bytes.NewReader(data))
+	switch {
+	case opts.MaxDepth == 0:
+		r.SetMaxDepth(DefaultMaxDepth)
+	case opts.MaxDepth > 0:
+		r.SetMaxDepth(opts.MaxDepth)
+	}
+	e, err := r.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := r.ReadValue(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := r.Next(); err != io.EOF {
+		return nil, nil, err
+	}
+	return data, value, nil
+}
+
+// checkContentType reports an error if contentType is not one of accepted,
+// or not a JSON type when accepted is empty.
+func checkContentType(contentType string, accepted []string) error {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", contentType)
+	}
+	if len(accepted) == 0 && isJSON(contentType) {
+		return nil
+	}
+	for _, a := range accepted {
+		if t == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected content type %q", t)
+}
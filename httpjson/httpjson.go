@@ -0,0 +1,185 @@
+// Package httpjson provides net/http helpers for JSON APIs: ValidateRequests
+// rejects requests whose body is not valid JSON, FormatResponses lays JSON
+// responses out as clients ask, and DecodeResponse safely decodes the
+// responses received by clients.
+package httpjson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/stream"
+)
+
+// ErrorResponse is the body of the error responses of ValidateRequests.
+type ErrorResponse struct {
+	Error  string `json:"error"`
+	Line   int    `json:"line,omitempty"`   // line of the syntax error in the request body
+	Column int    `json:"column,omitempty"` // column of the syntax error in the request body
+}
+
+// ValidateRequests returns a handler passing requests on to next once their
+// body has been checked to hold a single valid JSON value. Other requests
+// get a 400 Bad Request response holding an ErrorResponse which locates the
+// syntax error, or a 413 Request Entity Too Large response when the body
+// exceeds the limit set by http.MaxBytesReader. Requests without a body are
+// passed on unchanged.
+func ValidateRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: err.Error()})
+			} else {
+				writeError(w, http.StatusBadRequest, ErrorResponse{Error: "reading the request body: " + err.Error()})
+			}
+			return
+		}
+		if len(body) > 0 {
+			if resp, ok := validate(body); !ok {
+				writeError(w, http.StatusBadRequest, resp)
+				return
+			}
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validate reports whether body holds a valid JSON value, as gojson.Valid
+// checks it, or why not. Syntax errors are located by reading the body
+// again with a stream.Reader, when it detects them.
+func validate(body []byte) (ErrorResponse, bool) {
+	if gojson.Valid(body) {
+		return ErrorResponse{}, true
+	}
+	r := stream.NewReader(bytes.NewReader(body))
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			return ErrorResponse{Error: "invalid JSON"}, false
+		}
+		var syntaxErr *stream.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return ErrorResponse{Error: "invalid JSON: " + syntaxErr.Msg, Line: syntaxErr.Line, Column: syntaxErr.Column}, false
+		}
+		if err != nil {
+			return ErrorResponse{Error: "invalid JSON: " + err.Error()}, false
+		}
+	}
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, resp ErrorResponse) {
+	body, _ := gojson.Marshal(resp) // cannot fail for an ErrorResponse
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// The layouts of FormatResponses.
+const (
+	Pretty  = "pretty"  // indented as by the linter
+	Compact = "compact" // without any white space
+)
+
+// FormatResponses returns a handler laying out the JSON responses of next
+// according to the query parameter param of requests, Pretty or Compact.
+// When the parameter is set, responses are buffered until next returns,
+// then those with a JSON content type, application/json or a +json suffix,
+// are reformatted. Responses that are not valid JSON are sent unchanged.
+func FormatResponses(next http.Handler, param string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		layout := r.URL.Query().Get(param)
+		if layout != Pretty && layout != Compact {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buffered := &bufferedResponse{ResponseWriter: w}
+		next.ServeHTTP(buffered, r)
+		buffered.send(layout)
+	})
+}
+
+// bufferedResponse holds a response until it is sent.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status int // 0 until the status is written
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// send writes the response, laid out if it is a JSON one.
+func (b *bufferedResponse) send(layout string) {
+	b.WriteHeader(http.StatusOK)
+	body := b.body.Bytes()
+	if isJSON(b.Header().Get("Content-Type")) {
+		if formatted, ok := format(body, layout); ok {
+			body = formatted
+			b.Header().Del("Content-Length")
+		}
+	}
+	b.ResponseWriter.WriteHeader(b.status)
+	b.ResponseWriter.Write(body)
+}
+
+// isJSON reports whether contentType designates JSON.
+func isJSON(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (t == "application/json" || strings.HasSuffix(t, "+json"))
+}
+
+// format lays out body, reporting false if it is not a valid JSON value.
+func format(body []byte, layout string) ([]byte, bool) {
+	if !gojson.Valid(body) {
+		return nil, false
+	}
+	r := stream.NewReader(bytes.NewReader(body))
+	e, err := r.Next()
+	if err != nil {
+		return nil, false
+	}
+	v, err := r.ReadValue(e)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := r.Next(); err != io.EOF {
+		return nil, false
+	}
+
+	if layout == Pretty {
+		return []byte(linter.Format(v, linter.DefaultOptions()) + "\n"), true
+	}
+	var out bytes.Buffer
+	w := stream.NewWriter(&out)
+	w.WriteValue(v)
+	w.Flush()
+	out.WriteByte('\n')
+	return out.Bytes(), true
+}
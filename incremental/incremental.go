@@ -0,0 +1,455 @@
+// Package incremental keeps the syntax tree of a JSON text up to date as it
+// is edited, for editor integrations working on large files. Applying an
+// edit reparses the smallest value enclosing it and shares every other node
+// with the previous tree, so that typing in a string of a large document
+// costs about as much as parsing that string.
+//
+//	tree, err := incremental.Parse(text)
+//	tree, err = tree.Edit(start, end, replacement)
+//	value := tree.Value()
+//
+// Nodes only record their width and the width of the text preceding them in
+// their parent, not their offsets, so that the nodes following an edit stay
+// valid without being updated. Trees are immutable and safe for concurrent
+// use.
+package incremental
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Kind is the JSON type of a node.
+type Kind uint8
+
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+var kindNames = [...]string{"null", "boolean", "number", "string", "array", "object"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Node is a value of a tree. The children of an object are its keys and
+// values in turn, like the elements of an array. Nodes are never modified.
+type Node struct {
+	kind     Kind
+	lead     int    // width of the text between the previous child, or the opening bracket, and the node
+	width    int    // width of the text of the node
+	text     string // text of scalars, strings keeping their quotes
+	children []*Node
+	trail    int // width of the text between the last child and the closing bracket
+}
+
+// Kind returns the JSON type of the node.
+func (n *Node) Kind() Kind {
+	return n.kind
+}
+
+// Width returns the length of the text of the node.
+func (n *Node) Width() int {
+	return n.width
+}
+
+// Text returns the text of a scalar, strings keeping their quotes, or the
+// empty string for arrays and objects.
+func (n *Node) Text() string {
+	return n.text
+}
+
+// Children returns the elements of an array or the keys and values of an
+// object in turn. The slice must not be modified.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// Value converts the node to the types of the parser package: a
+// *parser.OrderedObject, parser.JsonArray, string, int64, float64, bool or
+// nil.
+func (n *Node) Value() interface{} {
+	switch n.kind {
+	case Bool:
+		return n.text == "true"
+	case Number:
+		v, _ := parser.ParseNumber(n.text)
+		return v
+	case String:
+		s, _ := lexer.Unescape(n.text[1 : len(n.text)-1])
+		return s
+	case Array:
+		arr := make(parser.JsonArray, len(n.children))
+		for i, c := range n.children {
+			arr[i] = c.Value()
+		}
+		return arr
+	case Object:
+		obj := parser.NewOrderedObject()
+		for i := 0; i < len(n.children); i += 2 {
+			obj.Set(n.children[i].Value().(string), n.children[i+1].Value())
+		}
+		return obj
+	}
+	return nil
+}
+
+// Tree is the syntax tree of a JSON text holding one value.
+type Tree struct {
+	text        string
+	root        *Node
+	lead, trail int // widths of the whitespace around the root
+}
+
+// Parse parses text.
+func Parse(text string) (*Tree, error) {
+	p := &treeParser{src: text}
+	p.skipSpace()
+	lead := p.pos
+	root, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	root.lead = lead
+	end := p.pos
+	p.skipSpace()
+	if p.pos < len(text) {
+		return nil, p.errorf("unexpected %q after the end of the document", text[p.pos])
+	}
+	return &Tree{text: text, root: root, lead: lead, trail: len(text) - end}, nil
+}
+
+// Text returns the text of the tree.
+func (t *Tree) Text() string {
+	return t.text
+}
+
+// Root returns the node of the whole document.
+func (t *Tree) Root() *Node {
+	return t.root
+}
+
+// Value converts the document to the types of the parser package, as
+// Node.Value does.
+func (t *Tree) Value() interface{} {
+	return t.root.Value()
+}
+
+// frame is a node on the path from the root to an edit.
+type frame struct {
+	node  *Node
+	start int // offset of the node in the text
+	index int // index of the node among the children of its parent
+}
+
+// Edit returns the tree of the text where the bytes from start to end are
+// replaced by replacement. The innermost value enclosing the edit whose new
+// text is still a value of the same role, such as a key remaining a string,
+// is reparsed, and the nodes outside it are shared with t. An error is
+// returned, and t is left as it was, if the edited text is not valid JSON.
+func (t *Tree) Edit(start, end int, replacement string) (*Tree, error) {
+	if start < 0 || end < start || end > len(t.text) {
+		return nil, fmt.Errorf("incremental: invalid edit range [%d, %d] of a text of %d bytes", start, end, len(t.text))
+	}
+	text := t.text[:start] + replacement + t.text[end:]
+	delta := len(replacement) - (end - start)
+
+	path := []frame{{node: t.root, start: t.lead}}
+	if start < t.lead || end > t.lead+t.root.width {
+		path = nil // the edit touches the whitespace around the root
+	}
+	for len(path) > 0 {
+		parent := path[len(path)-1]
+		found := false
+		offset := parent.start + 1
+		for i, c := range parent.node.children {
+			offset += c.lead
+			if offset <= start && end <= offset+c.width {
+				path = append(path, frame{node: c, start: offset, index: i})
+				found = true
+				break
+			}
+			offset += c.width
+		}
+		if !found {
+			break
+		}
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		f := path[i]
+		newEnd := f.start + f.node.width + delta
+		if newEnd <= f.start {
+			continue
+		}
+		p := &treeParser{src: text[:newEnd], pos: f.start}
+		n, err := p.parseValue()
+		if err != nil || p.pos != newEnd {
+			continue
+		}
+		isKey := i > 0 && path[i-1].node.kind == Object && f.index%2 == 0
+		if isKey && n.kind != String {
+			continue
+		}
+		n.lead = f.node.lead
+		for j := i - 1; j >= 0; j-- {
+			parent := *path[j].node
+			parent.children = append([]*Node{}, parent.children...)
+			parent.children[path[j+1].index] = n
+			parent.width += delta
+			n = &parent
+		}
+		return &Tree{text: text, root: n, lead: t.lead, trail: t.trail}, nil
+	}
+	return Parse(text)
+}
+
+// Span returns the offsets where the value designated by p starts and ends,
+// or false if there is none.
+func (t *Tree) Span(p pointer.Pointer) (int, int, bool) {
+	n, start := t.root, t.lead
+	for _, token := range p {
+		offset := start + 1
+		var next *Node
+		switch n.kind {
+		case Array:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n.children) || strconv.Itoa(i) != token {
+				return 0, 0, false
+			}
+			for _, c := range n.children[:i] {
+				offset += c.lead + c.width
+			}
+			next = n.children[i]
+			offset += next.lead
+		case Object:
+			// The last of duplicate keys wins, as with the parser.
+			valueStart := 0
+			for j := 0; j < len(n.children); j += 2 {
+				key, value := n.children[j], n.children[j+1]
+				offset += key.lead + key.width + value.lead
+				if key.text[1:len(key.text)-1] == token {
+					next, valueStart = value, offset
+				}
+				offset += value.width
+			}
+			if next == nil {
+				return 0, 0, false
+			}
+			offset = valueStart
+		default:
+			return 0, 0, false
+		}
+		n, start = next, offset
+	}
+	return start, start + n.width, true
+}
+
+// treeParser builds the nodes of a text.
+type treeParser struct {
+	src string
+	pos int
+}
+
+// errorf returns a syntax error located at the current offset.
+func (p *treeParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("incremental: offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// found returns the error of a missing token.
+func (p *treeParser) found(expected string) error {
+	if p.pos == len(p.src) {
+		return p.errorf("expected %s, got end of input", expected)
+	}
+	return p.errorf("expected %s, got %q", expected, p.src[p.pos])
+}
+
+// skipSpace skips the whitespace allowed between tokens.
+func (p *treeParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// parseValue parses the value at the current offset.
+func (p *treeParser) parseValue() (*Node, error) {
+	if p.pos == len(p.src) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	start := p.pos
+	var n *Node
+	var err error
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		n, err = p.parseContainer(Object, '}')
+	case c == '[':
+		n, err = p.parseContainer(Array, ']')
+	case c == '"':
+		n, err = p.parseString()
+	case c == '-' || '0' <= c && c <= '9':
+		n, err = p.parseNumber()
+	default:
+		n, err = p.parseLiteral()
+	}
+	if err != nil {
+		return nil, err
+	}
+	n.width = p.pos - start
+	return n, nil
+}
+
+// parseLiteral parses true, false or null.
+func (p *treeParser) parseLiteral() (*Node, error) {
+	for _, literal := range []struct {
+		text string
+		kind Kind
+	}{{"true", Bool}, {"false", Bool}, {"null", Null}} {
+		if len(p.src)-p.pos >= len(literal.text) && p.src[p.pos:p.pos+len(literal.text)] == literal.text {
+			p.pos += len(literal.text)
+			return &Node{kind: literal.kind, text: literal.text}, nil
+		}
+	}
+	return nil, p.errorf("unexpected %q", p.src[p.pos])
+}
+
+// parseContainer parses an object or an array, the current byte being its
+// opening bracket.
+func (p *treeParser) parseContainer(kind Kind, closing byte) (*Node, error) {
+	n := &Node{kind: kind}
+	p.pos++
+	mark := p.pos // end of the previous child
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == closing {
+		n.trail = p.pos - mark
+		p.pos++
+		return n, nil
+	}
+	for {
+		if kind == Object {
+			if p.pos == len(p.src) || p.src[p.pos] != '"' {
+				return nil, p.found("a string for key")
+			}
+			key, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			key.lead = p.pos - key.width - mark
+			n.children = append(n.children, key)
+			mark = p.pos
+			p.skipSpace()
+			if p.pos == len(p.src) || p.src[p.pos] != ':' {
+				return nil, p.found("':' after key")
+			}
+			p.pos++
+			p.skipSpace()
+		}
+		child, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		child.lead = p.pos - child.width - mark
+		n.children = append(n.children, child)
+		mark = p.pos
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == closing {
+			n.trail = p.pos - mark
+			p.pos++
+			return n, nil
+		}
+		if p.pos == len(p.src) || p.src[p.pos] != ',' {
+			return nil, p.found(fmt.Sprintf("',' or '%c'", closing))
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+// parseString parses a string, the current byte being its opening quote,
+// checking its escape sequences.
+func (p *treeParser) parseString() (*Node, error) {
+	start := p.pos
+	for i := start + 1; i < len(p.src); i++ {
+		switch c := p.src[i]; {
+		case c == '"':
+			p.pos = i + 1
+			return &Node{kind: String, text: p.src[start:p.pos]}, nil
+		case c == '\\':
+			if i+1 == len(p.src) {
+				break
+			}
+			switch p.src[i+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				i++
+			case 'u':
+				if i+6 > len(p.src) {
+					p.pos = i
+					return nil, p.errorf("invalid escape sequence")
+				}
+				if _, err := strconv.ParseUint(p.src[i+2:i+6], 16, 16); err != nil {
+					p.pos = i
+					return nil, p.errorf("invalid escape sequence %q", p.src[i:i+6])
+				}
+				i += 5
+			default:
+				p.pos = i
+				return nil, p.errorf("invalid escape sequence %q", p.src[i:i+2])
+			}
+		case c < 0x20:
+			p.pos = i
+			return nil, p.errorf("control character %q in string", c)
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// parseNumber parses a number, following the grammar of RFC 8259.
+func (p *treeParser) parseNumber() (*Node, error) {
+	start := p.pos
+	digits := func() int {
+		n := 0
+		for p.pos < len(p.src) && '0' <= p.src[p.pos] && p.src[p.pos] <= '9' {
+			p.pos++
+			n++
+		}
+		return n
+	}
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	switch {
+	case p.pos < len(p.src) && p.src[p.pos] == '0':
+		p.pos++
+	case digits() == 0:
+		return nil, p.found("a digit")
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		if digits() == 0 {
+			return nil, p.found("a digit after '.'")
+		}
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		if digits() == 0 {
+			return nil, p.found("a digit in the exponent")
+		}
+	}
+	return &Node{kind: Number, text: p.src[start:p.pos]}, nil
+}
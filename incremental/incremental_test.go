@@ -0,0 +1,147 @@
+package incremental
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+const document = ` {
+	"name": "gojson",
+	"tags": ["json", 12, true],
+	"owner": {"login": "alice", "id": null}
+}
+`
+
+func format(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+// reparse parses text from scratch, for comparison with incremental results.
+func reparse(t *testing.T, text string) string {
+	p := parser.NewParser(lexer.NewLexer(text))
+	doc := p.ParseOrdered()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return format(doc)
+}
+
+func TestParse(t *testing.T) {
+	tree, err := Parse(document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, expected := format(tree.Value()), reparse(t, document); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	root := tree.Root()
+	if root.Kind() != Object || len(root.Children()) != 6 || root.Width() != len(strings.TrimSpace(document)) {
+		t.Errorf("unexpected root: %s of %d children and width %d", root.Kind(), len(root.Children()), root.Width())
+	}
+	for _, test := range []struct {
+		ptr, text string
+	}{
+		{"", strings.TrimSpace(document)},
+		{"/name", `"gojson"`},
+		{"/tags/1", "12"},
+		{"/tags/2", "true"},
+		{"/owner", `{"login": "alice", "id": null}`},
+		{"/owner/id", "null"},
+	} {
+		start, end, ok := tree.Span(pointer.MustParse(test.ptr))
+		if !ok || document[start:end] != test.text {
+			t.Errorf("%s: expected %q, got %q (%v)", test.ptr, test.text, document[start:end], ok)
+		}
+	}
+	for _, ptr := range []string{"/missing", "/tags/3", "/tags/01", "/name/0"} {
+		if _, _, ok := tree.Span(pointer.MustParse(ptr)); ok {
+			t.Errorf("%s: expected no value", ptr)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tree, err := Parse(document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	text := document
+
+	edits := []struct {
+		old, new string
+		shared   []int // children of the root still shared with the previous tree
+	}{
+		{`"gojson"`, `"gojson2"`, []int{0, 2, 3, 4, 5}},
+		{`12`, `125`, []int{0, 1, 2, 4, 5}},
+		{`true`, `{"a": [1]}`, []int{0, 1, 2, 4, 5}},
+		{`"login"`, `"user"`, []int{0, 1, 2, 3, 4}},
+		{`, "id": null`, ``, []int{0, 1, 2, 3, 4}},
+		{`"tags"`, `"labels"`, []int{0, 1, 3, 4, 5}},
+		{`}`, `, "x": []}`, nil},
+	}
+	for _, edit := range edits {
+		start := strings.LastIndex(text, edit.old)
+		if edit.old == `}` {
+			start = strings.LastIndex(text, "}")
+		}
+		edited, err := tree.Edit(start, start+len(edit.old), edit.new)
+		if err != nil {
+			t.Fatalf("%s -> %s: unexpected error: %v", edit.old, edit.new, err)
+		}
+		text = text[:start] + edit.new + text[start+len(edit.old):]
+		if edited.Text() != text {
+			t.Fatalf("expected the text %q, got %q", text, edited.Text())
+		}
+		if got, expected := format(edited.Value()), reparse(t, text); got != expected {
+			t.Errorf("%s -> %s: expected %s, got %s", edit.old, edit.new, expected, got)
+		}
+		for _, i := range edit.shared {
+			if edited.Root().Children()[i] != tree.Root().Children()[i] {
+				t.Errorf("%s -> %s: expected child %d to be shared", edit.old, edit.new, i)
+			}
+		}
+		tree = edited
+	}
+
+	if start, end, ok := tree.Span(pointer.MustParse("/labels/2/a/0")); !ok || text[start:end] != "1" {
+		t.Errorf("expected the span of a reparsed value, got %q", text[start:end])
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	tree, err := Parse(`{"a": [1, 2]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, test := range []struct {
+		start, end  int
+		replacement string
+		expected    string
+	}{
+		{7, 8, "", "incremental: offset 7: unexpected ','"},
+		{1, 4, "1", "incremental: offset 1: expected a string for key, got '1'"},
+		{12, 13, "", "incremental: offset 12: expected ',' or '}', got end of input"},
+		{5, 20, "", "incremental: invalid edit range [5, 20] of a text of 13 bytes"},
+	} {
+		if _, err := tree.Edit(test.start, test.end, test.replacement); err == nil || err.Error() != test.expected {
+			t.Errorf("expected error %q, got %v", test.expected, err)
+		}
+	}
+	if tree.Text() != `{"a": [1, 2]}` {
+		t.Errorf("expected failed edits to leave the tree unchanged")
+	}
+
+	edited, err := tree.Edit(0, 0, "\n  ")
+	if err != nil || edited.Text() != "\n  {\"a\": [1, 2]}" {
+		t.Fatalf("unexpected result: %v", err)
+	}
+	if start, _, _ := edited.Span(pointer.Pointer{}); start != 3 {
+		t.Errorf("expected the document to start at 3, got %d", start)
+	}
+}
@@ -0,0 +1,112 @@
+// Package index answers repeated lookups against a document produced by the
+// parser package. Building an index walks the document once; afterwards the
+// value at a JSON Pointer, the members named by a key, and the values under a
+// path are found without walking the document again, which suits servers
+// answering many field queries against the same large document.
+//
+// An index describes the document as it was built and shares its values:
+// documents modified afterwards must be indexed again.
+package index
+
+import (
+	"strconv"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Entry is a value of an indexed document and the pointer designating it.
+type Entry struct {
+	Path  pointer.Pointer
+	Value interface{}
+}
+
+// Index is the index of a document, safe for concurrent use since it is never
+// modified once built.
+type Index struct {
+	entries []Entry          // every value, in document order, parents first
+	ends    []int            // entries[ends[i]] follows the values under entries[i]
+	paths   map[string]int   // positions of entries, by pointer text
+	keys    map[string][]int // positions of object members, by key
+}
+
+// Build indexes doc. Keys and pointer tokens are decoded, as the parser
+// stores keys and the pointer package designates them. The members of plain
+// parser.JsonObject maps, which have no order, are indexed in sorted key
+// order.
+func Build(doc interface{}) *Index {
+	idx := &Index{paths: map[string]int{}, keys: map[string][]int{}}
+	idx.add(pointer.Pointer{}, "", doc)
+	return idx
+}
+
+// add indexes v, found at path whose text is ptr, and the values under it.
+func (idx *Index) add(path pointer.Pointer, ptr string, v interface{}) {
+	i := len(idx.entries)
+	idx.entries = append(idx.entries, Entry{Path: path, Value: v})
+	idx.ends = append(idx.ends, 0)
+	idx.paths[ptr] = i
+
+	switch x := v.(type) {
+	case parser.JsonObject, *parser.OrderedObject:
+		keys, members, _ := parser.Members(x)
+		for _, k := range keys {
+			idx.keys[k] = append(idx.keys[k], len(idx.entries))
+			idx.add(path.Append(k), ptr+pointer.Pointer{k}.String(), members[k])
+		}
+	case parser.JsonArray:
+		for j, e := range x {
+			token := strconv.Itoa(j)
+			idx.add(path.Append(token), ptr+"/"+token, e)
+		}
+	}
+	idx.ends[i] = len(idx.entries)
+}
+
+// Len returns the number of values in the document, the document itself
+// included.
+func (idx *Index) Len() int {
+	return len(idx.entries)
+}
+
+// Get returns the value designated by p, or false if there is none.
+func (idx *Index) Get(p pointer.Pointer) (interface{}, bool) {
+	return idx.Lookup(p.String())
+}
+
+// Lookup returns the value designated by the JSON Pointer ptr, such as
+// "/users/0/name", or false if there is none or ptr is invalid.
+func (idx *Index) Lookup(ptr string) (interface{}, bool) {
+	i, ok := idx.paths[ptr]
+	if !ok {
+		return nil, false
+	}
+	return idx.entries[i].Value, true
+}
+
+// Key returns the object members named key anywhere in the document, in
+// document order.
+func (idx *Index) Key(key string) []Entry {
+	positions := idx.keys[key]
+	entries := make([]Entry, len(positions))
+	for i, pos := range positions {
+		entries[i] = idx.entries[pos]
+	}
+	return entries
+}
+
+// Under returns the values nested in the value designated by p, at any depth
+// and in document order, or nil if p designates nothing.
+func (idx *Index) Under(p pointer.Pointer) []Entry {
+	i, ok := idx.paths[p.String()]
+	if !ok {
+		return nil
+	}
+	return idx.entries[i+1 : idx.ends[i] : idx.ends[i]]
+}
+
+// Entries returns every value of the document in document order, parents
+// before the values they hold, starting with the document itself.
+func (idx *Index) Entries() []Entry {
+	return idx.entries[:len(idx.entries):len(idx.entries)]
+}
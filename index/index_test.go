@@ -0,0 +1,103 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+const document = `{
+	"name": "gojson",
+	"owner": {"name": "alice", "id": 7},
+	"tags": ["json", {"name": "lexer"}],
+	"a/b": {"m~n": true}
+}`
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseOrdered()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func paths(entries []Entry) []string {
+	result := []string{}
+	for _, e := range entries {
+		result = append(result, e.Path.String())
+	}
+	return result
+}
+
+func TestLookup(t *testing.T) {
+	idx := Build(parse(t, document))
+	if idx.Len() != 11 {
+		t.Errorf("expected 11 values, got %d", idx.Len())
+	}
+
+	for _, test := range []struct {
+		ptr      string
+		expected interface{}
+	}{
+		{"/name", "gojson"},
+		{"/owner/id", int64(7)},
+		{"/tags/1/name", "lexer"},
+		{"/a~1b/m~0n", true},
+	} {
+		if v, ok := idx.Lookup(test.ptr); !ok || !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%s: expected %v, got %v (%v)", test.ptr, test.expected, v, ok)
+		}
+		if v, ok := idx.Get(pointer.MustParse(test.ptr)); !ok || !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%s: expected Get to return %v, got %v (%v)", test.ptr, test.expected, v, ok)
+		}
+	}
+	if v, ok := idx.Get(pointer.Pointer{}); !ok || v == nil {
+		t.Errorf("expected the empty pointer to designate the document, got %v (%v)", v, ok)
+	}
+	for _, ptr := range []string{"/missing", "/tags/2", "/tags/01", "name", "/a/b"} {
+		if v, ok := idx.Lookup(ptr); ok {
+			t.Errorf("%s: expected no value, got %v", ptr, v)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	idx := Build(parse(t, document))
+	if got, expected := paths(idx.Key("name")), []string{"/name", "/owner/name", "/tags/1/name"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if entries := idx.Key("missing"); len(entries) != 0 {
+		t.Errorf("expected no members, got %v", entries)
+	}
+}
+
+func TestUnder(t *testing.T) {
+	idx := Build(parse(t, document))
+	if got, expected := paths(idx.Under(pointer.MustParse("/tags"))), []string{"/tags/0", "/tags/1", "/tags/1/name"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if entries := idx.Under(pointer.MustParse("/owner/id")); len(entries) != 0 {
+		t.Errorf("expected nothing under a scalar, got %v", entries)
+	}
+	if entries := idx.Under(pointer.MustParse("/missing")); entries != nil {
+		t.Errorf("expected nil for a missing value, got %v", entries)
+	}
+	if got := len(idx.Under(pointer.Pointer{})); got != idx.Len()-1 {
+		t.Errorf("expected every value but the document under it, got %d", got)
+	}
+	if got := paths(idx.Entries()[:3]); !reflect.DeepEqual(got, []string{"", "/name", "/owner"}) {
+		t.Errorf("expected entries in document order, got %v", got)
+	}
+}
+
+func TestBuildUnordered(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`{"b": 1, "a": [true]}`))
+	idx := Build(p.ParseValue())
+	if got, expected := paths(idx.Entries()), []string{"", "/a", "/a/0", "/b"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
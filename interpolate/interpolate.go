@@ -0,0 +1,220 @@
+// Package interpolate turns documents produced by the parser package into
+// templates: placeholders inside their string values are replaced by
+// environment variables, written ${NAME} or ${NAME:-default}, or by fields of
+// a data document, written {{.field}} or {{.items.0.name}}, the result
+// remaining valid JSON. Writing $${ produces a literal ${.
+package interpolate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Options controls how Interpolate replaces placeholders.
+type Options struct {
+	// Env looks environment variables up, os.LookupEnv when nil.
+	Env func(name string) (string, bool)
+	// Data is the parsed document whose fields {{.field}} placeholders
+	// designate.
+	Data interface{}
+	// WholeValues replaces the strings made of a single placeholder by the
+	// value itself rather than its text, so that "{{.port}}" becomes the
+	// number 8080 and "${DEBUG}" the boolean true when DEBUG holds true.
+	// Environment variables not holding valid JSON remain strings.
+	WholeValues bool
+}
+
+// Interpolate returns a copy of v where the placeholders of string values
+// are replaced according to opts. Object keys are left unchanged. Undefined
+// variables and fields, and fields holding objects or arrays inside longer
+// strings, are reported as errors, located by a JSON Pointer.
+func Interpolate(v interface{}, opts Options) (interface{}, error) {
+	if opts.Env == nil {
+		opts.Env = os.LookupEnv
+	}
+	in := &interpolator{opts: opts}
+	return in.value(pointer.Pointer{}, v)
+}
+
+// interpolator replaces placeholders.
+type interpolator struct {
+	opts Options
+}
+
+// value returns a copy of v, found at path, with its placeholders replaced.
+func (in *interpolator) value(path pointer.Pointer, v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return in.string(path, x)
+	case parser.JsonArray:
+		arr := make(parser.JsonArray, len(x))
+		for i, e := range x {
+			var err error
+			if arr[i], err = in.value(path.Append(strconv.Itoa(i)), e); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case parser.JsonObject:
+		obj := make(parser.JsonObject, len(x))
+		for k, e := range x {
+			var err error
+			if obj[k], err = in.value(path.Append(k), e); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case *parser.OrderedObject:
+		obj := parser.NewOrderedObject()
+		for _, k := range x.Keys {
+			e, err := in.value(path.Append(k), x.Values[k])
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(k, e)
+		}
+		return obj, nil
+	}
+	return v, nil
+}
+
+// string returns s, a string found at path, with its placeholders replaced.
+func (in *interpolator) string(path pointer.Pointer, s string) (interface{}, error) {
+	var result strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			result.WriteString("${")
+			i += 3
+
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("interpolate: %s: unterminated placeholder in %q", path, s)
+			}
+			text, err := in.env(s[i+2 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("interpolate: %s: %v", path, err)
+			}
+			if in.opts.WholeValues && i == 0 && end == len(s)-1 {
+				return envValue(text), nil
+			}
+			result.WriteString(text)
+			i += end + 1
+
+		case strings.HasPrefix(s[i:], "{{"):
+			end := strings.Index(s[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("interpolate: %s: unterminated placeholder in %q", path, s)
+			}
+			v, err := in.field(strings.TrimSpace(s[i+2 : i+end]))
+			if err != nil {
+				return nil, fmt.Errorf("interpolate: %s: %v", path, err)
+			}
+			if in.opts.WholeValues && i == 0 && end == len(s)-2 {
+				return gojson.Clone(v), nil
+			}
+			text, err := scalarText(v)
+			if err != nil {
+				return nil, fmt.Errorf("interpolate: %s: %v", path, err)
+			}
+			result.WriteString(text)
+			i += end + 2
+
+		default:
+			result.WriteByte(s[i])
+			i++
+		}
+	}
+	return result.String(), nil
+}
+
+// env returns the value of the environment variable placeholder expr, NAME
+// or NAME:-default.
+func (in *interpolator) env(expr string) (string, error) {
+	name, def, hasDefault := strings.Cut(expr, ":-")
+	if !isName(name) {
+		return "", fmt.Errorf("invalid variable name %q", name)
+	}
+	if value, ok := in.opts.Env(name); ok {
+		return value, nil
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return "", fmt.Errorf("undefined variable %s", name)
+}
+
+// isName reports whether s is a valid environment variable name.
+func isName(s string) bool {
+	for i, c := range s {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// envValue returns the JSON value held by an environment variable, or its
+// text as a string.
+func envValue(text string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(text))
+	v := p.ParseValue()
+	if len(p.Errors()) > 0 || strings.TrimSpace(text) == "" {
+		return text
+	}
+	return v
+}
+
+// field returns the field of the data document designated by the field
+// placeholder expr, such as .users.0.name, or . for the whole document.
+func (in *interpolator) field(expr string) (interface{}, error) {
+	if !strings.HasPrefix(expr, ".") {
+		return nil, fmt.Errorf("invalid field %q", expr)
+	}
+	current := in.opts.Data
+	if expr == "." {
+		return current, nil
+	}
+
+	for _, name := range strings.Split(expr[1:], ".") {
+		var ok bool
+		switch x := current.(type) {
+		case parser.JsonObject:
+			current, ok = x[name]
+		case *parser.OrderedObject:
+			current, ok = x.Get(name)
+		case parser.JsonArray:
+			if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(x) {
+				current, ok = x[i], true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("undefined field %s", expr)
+		}
+	}
+	return current, nil
+}
+
+// scalarText returns the text of a scalar inserted into a string.
+func scalarText(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case nil:
+		return "null", nil
+	case bool, int64, float64:
+		return fmt.Sprintf("%v", x), nil
+	case parser.JsonArray:
+		return "", errors.New("cannot insert an array into a string")
+	}
+	return "", errors.New("cannot insert an object into a string")
+}
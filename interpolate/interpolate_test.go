@@ -0,0 +1,119 @@
+package interpolate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func parse(t *testing.T, input string) interface{} {
+	t.Helper()
+	p := parser.NewParser(lexer.NewLexer(input))
+	v := p.ParseValue()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parsing %s: %v", input, p.Errors())
+	}
+	return v
+}
+
+func compact(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+var env = map[string]string{
+	"HOST":  "db.example.com",
+	"PORT":  "5432",
+	"DEBUG": "true",
+	"QUOTE": "say \"hi\"\n",
+}
+
+func lookup(name string) (string, bool) {
+	v, ok := env[name]
+	return v, ok
+}
+
+func TestInterpolate(t *testing.T) {
+	doc := parse(t, `{
+		"url": "postgres://${HOST}:${PORT}/{{.db.name}}",
+		"user": "{{ .users.0 }}",
+		"level": "${LEVEL:-info}",
+		"quote": "${QUOTE}",
+		"port": "${PORT}",
+		"replicas": "{{.replicas}}",
+		"literal": "$${HOST} costs $5",
+		"list": ["{{.db.name}}-${PORT}", 1, null]
+	}`)
+	data := parse(t, `{"db": {"name": "app"}, "users": ["admin"], "replicas": 3}`)
+
+	got, err := Interpolate(doc, Options{Env: lookup, Data: data})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"level": "info", "list": ["app-5432", 1, null], "literal": "${HOST} costs $5", "port": "5432", "quote": "say \"hi\"\n", "replicas": "3", "url": "postgres://db.example.com:5432/app", "user": "admin"}`
+	if compact(got) != expected {
+		t.Errorf("got %s, want %s", compact(got), expected)
+	}
+	if compact(doc) == compact(got) {
+		t.Errorf("the input was modified")
+	}
+}
+
+func TestInterpolateWholeValues(t *testing.T) {
+	doc := parse(t, `["${PORT}", "${DEBUG}", "${HOST}", "{{.db}}", "{{.replicas}}", "x{{.replicas}}", "${EMPTY:-}"]`)
+	data := parse(t, `{"db": {"name": "app"}, "replicas": 3}`)
+
+	got, err := Interpolate(doc, Options{Env: lookup, Data: data, WholeValues: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `[5432, true, "db.example.com", {"name": "app"}, 3, "x3", ""]`
+	if compact(got) != expected {
+		t.Errorf("got %s, want %s", compact(got), expected)
+	}
+
+	got.(parser.JsonArray)[3].(parser.JsonObject)["name"] = "changed"
+	if data.(parser.JsonObject)["db"].(parser.JsonObject)["name"] != "app" {
+		t.Errorf("the data document was modified")
+	}
+}
+
+func TestInterpolateKeepsOrder(t *testing.T) {
+	doc := parser.NewOrderedObject()
+	doc.Set("b", "${HOST}")
+	doc.Set("a", "x")
+	got, err := Interpolate(doc, Options{Env: lookup})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if compact(got) != `{"b": "db.example.com", "a": "x"}` {
+		t.Errorf("got %s", compact(got))
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	data := parse(t, `{"db": {"name": "app"}, "list": [1]}`)
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": "${MISSING}"}`, "/a: undefined variable MISSING"},
+		{`{"a": ["${1X}"]}`, `/a/0: invalid variable name "1X"`},
+		{`{"a": "${HOST"}`, "/a: unterminated placeholder"},
+		{`{"a": "{{.db"}`, "/a: unterminated placeholder"},
+		{`{"a": "{{.db.missing}}"}`, "/a: undefined field .db.missing"},
+		{`{"a": "{{.list.1}}"}`, "/a: undefined field .list.1"},
+		{`{"a": "{{db}}"}`, `/a: invalid field "db"`},
+		{`{"a": "x{{.db}}"}`, "/a: cannot insert an object into a string"},
+		{`{"a": "x{{.list}}"}`, "/a: cannot insert an array into a string"},
+	}
+
+	for _, test := range tests {
+		_, err := Interpolate(parse(t, test.input), Options{Env: lookup, Data: data})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%s: got error %v, want %q", test.input, err, test.expected)
+		}
+	}
+}
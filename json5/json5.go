@@ -0,0 +1,69 @@
+// Package json5 reads JSON5 documents, the superset of JSON meant for
+// configuration files written by hand, into the values the parser package
+// produces, and converts them to strict JSON. On top of JSON, JSON5 allows
+// // and /* */ comments, object keys written as identifiers, strings between
+// single quotes which may span lines by escaping their line breaks, trailing
+// commas, hexadecimal numbers, numbers with a leading plus sign or a leading
+// or trailing decimal point, and Infinity and NaN. See https://spec.json5.org.
+//
+// Documents are read by the parser package, from a lexer set to the JSON5
+// dialect, so that errors are located and limits and options apply as they
+// do for JSON documents.
+package json5
+
+import (
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// NewLexer returns a lexer scanning the JSON5 document held by data in place,
+// as lexer.NewBytesLexer does, set to the JSON5 dialect. Limits are set on it,
+// and options such as UseNumber on the parser created on it, as for JSON.
+func NewLexer(data []byte) *lexer.Lexer {
+	l := lexer.NewBytesLexer(data)
+	l.JSON5(true)
+	return l
+}
+
+// Parse parses the JSON5 document held by data into the values used by the
+// parser package: objects are *parser.OrderedObject keeping their keys in
+// document order, strings hold their decoded characters. Infinity and NaN,
+// which have no strict JSON equivalent, are reported as errors, as is any
+// syntax error, by a *SyntaxError.
+func Parse(data []byte) (interface{}, error) {
+	return parse(parser.NewParser(NewLexer(data)))
+}
+
+// ToJSON converts the JSON5 document held by data to strict JSON laid out
+// according to opts. Comments are dropped, and numbers are written as they
+// are in the document, in strict JSON form: hexadecimal numbers in decimal,
+// large ones included.
+func ToJSON(data []byte, opts linter.Options) ([]byte, error) {
+	p := parser.NewParser(NewLexer(data))
+	p.UseNumber(true)
+	v, err := parse(p)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(linter.Format(v, opts) + "\n"), nil
+}
+
+// parse parses the document of p, keeping the key order of its objects.
+func parse(p *parser.Parser) (interface{}, error) {
+	v := p.ParseOrderedValue()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, &SyntaxError{errs[0]}
+	}
+	return v, nil
+}
+
+// SyntaxError is an error of a JSON5 document, the first error the parser
+// reports for it.
+type SyntaxError struct {
+	parser.ParseError
+}
+
+func (e *SyntaxError) Error() string {
+	return "json5: " + e.ParseError.Error()
+}
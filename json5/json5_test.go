@@ -0,0 +1,128 @@
+package json5
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func compact(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1}`, `{"a": 1}`},
+		{"// config\n{unquoted: 'single', $id_2: \"double\", /* inline */ 'quoted key': null,}", `{"unquoted": "single", "$id_2": "double", "quoted key": null}`},
+		{`[1, 2, 3,]`, `[1, 2, 3]`},
+		{`[0x1F, -0XfF, +5, .5, 5., 1e3, 5.e-1, -0]`, `[31, -255, 5, 0.5, 5, 1000, 0.5, 0]`},
+		{`'it\'s "quoted"'`, `"it's \"quoted\""`},
+		{"'multi\\\nline \\\r\nstring'", `"multiline string"`},
+		{`'\x41\v\0é\a\/'`, `"A\u000b\u0000éa/"`},
+		{"'tab\there'", `"tab\there"`},
+		{`{ab: true}`, `{"ab": true}`},
+		{"\ufeff{\u00a0a\u2028: [] }", `{"a": []}`},
+		{`{café: 1, ключ: 2}`, `{"café": 1, "ключ": 2}`},
+	}
+
+	for _, test := range tests {
+		v, err := Parse([]byte(test.input))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.input, err)
+			continue
+		}
+		if compact(v) != test.expected {
+			t.Errorf("%q: got %s, want %s", test.input, compact(v), test.expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[Infinity]", "json5: Infinity has no strict JSON equivalent at line 1, column 10"},
+		{"-NaN", "json5: -NaN has no strict JSON equivalent at line 1, column 5"},
+		{"'line\nbreak'", "json5: unexpected token 'line\nbreak' at line 2, column 6"},
+		{"[1,,2]", "json5: unexpected token ',' at line 1, column 4"},
+		{"/* open", "json5: unexpected token '/*' at line 1, column 8"},
+		{"{a: 'x}", "json5: unexpected token ''x}' at line 1, column 8"},
+		{"{1: 2}", "json5: expected string for key at line 1, column 3, got '1'"},
+		{"[undefined]", "json5: unexpected token 'undefined' at line 1, column 11"},
+		{"'\\1'", "json5: unexpected token '\\1' at line 1, column 4"},
+		{"1 2", "json5: unexpected token '2' after the end of the document at line 1, column 4"},
+		{"0x", "json5: unexpected token '0x' at line 1, column 3"},
+		{"", "json5: unexpected token '' at line 1, column 1"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", test.input, err)
+			continue
+		}
+		if err.Error() != test.expected {
+			t.Errorf("%q: got %q, want %q", test.input, err.Error(), test.expected)
+		}
+	}
+}
+
+func TestSameAsJSON(t *testing.T) {
+	// Errors are located as in the equivalent JSON document.
+	inputs := [][2]string{
+		{"{\n  'a': [1, 2,, 3],\n}", "{\n  \"a\": [1, 2,, 3]\n}"},
+		{"{'a': 'x', 'a': 1e400}", `{"a": "x", "a": 1e400}`},
+	}
+	for _, in := range inputs {
+		_, err := Parse([]byte(in[0]))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("%q: expected a *SyntaxError, got %v", in[0], err)
+		}
+		p := parser.NewParser(lexer.NewLexer(in[1]))
+		p.ParseValue()
+		if errs := p.Errors(); len(errs) == 0 || syntaxErr.ParseError != errs[0] {
+			t.Errorf("%q: got %v, want the error of %q, %v", in[0], syntaxErr.ParseError, in[1], errs)
+		}
+	}
+
+	l := NewLexer([]byte(`{a: [1, 2, 3, 4]}`))
+	l.SetLimits(lexer.Limits{MaxTokens: 5})
+	p := parser.NewParser(l)
+	if p.ParseValue(); len(p.Errors()) == 0 || p.Errors()[0].Code != parser.LimitExceeded {
+		t.Errorf("expected the token limit to be exceeded, got %v", p.Errors())
+	}
+
+	p = parser.NewParser(NewLexer([]byte(`[0x10000000000000000, .5]`)))
+	p.UseNumber(true)
+	if v := p.ParseValue(); !reflect.DeepEqual(v, parser.JsonArray{parser.Number("18446744073709551616"), parser.Number("0.5")}) {
+		t.Errorf("expected Number values in strict JSON form, got %#v, errors %v", v, p.Errors())
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	input := `{
+  // the name of the package
+  name: 'gojson',
+  version: 0x2,
+  id: 0x10000000000000000,
+  keywords: ['json', 'json5',],
+}`
+	expected := "{\n  \"name\": \"gojson\",\n  \"version\": 2,\n  \"id\": 18446744073709551616,\n  \"keywords\": [\n    \"json\",\n    \"json5\"\n  ]\n}\n"
+	got, err := ToJSON([]byte(input), linter.DefaultOptions())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
@@ -0,0 +1,178 @@
+package jsonc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/oabrivard/gojson/linter"
+)
+
+// Format lays doc out according to opts, the way the linter does for
+// documents without comments. Comments are written on their own lines before
+// the node they precede, or at the end of the line of the node they follow.
+// Objects and arrays holding comments are never printed on a single line,
+// and only one blank line is kept wherever the document has some, except
+// after opening brackets.
+func Format(doc *Document, opts linter.Options) string {
+	f := &formatter{opts: opts}
+	f.comments(doc.Root.Before, 0)
+	f.line(0, doc.Root.BlankBefore)
+	f.node(doc.Root, 0)
+	f.after(doc.Root.After)
+	f.comments(doc.End, 0)
+	return f.result.String()
+}
+
+// formatter writes the layout of a document.
+type formatter struct {
+	opts   linter.Options
+	result strings.Builder
+	opened bool // whether the last line written ends with an opening bracket
+}
+
+// line starts a new line indented for the given depth, preceded by a blank
+// line if blank is set and the previous line is not an opening one. Nothing
+// is written at the beginning of the document.
+func (f *formatter) line(depth int, blank bool) {
+	if f.result.Len() == 0 {
+		return
+	}
+	if blank && !f.opened {
+		f.result.WriteString("\n" + strings.TrimRight(f.opts.Prefix, " \t"))
+	}
+	f.opened = false
+	f.result.WriteString("\n" + f.opts.Prefix)
+	for i := 0; i < depth; i++ {
+		f.result.WriteString(f.opts.Indent)
+	}
+}
+
+// comments writes comments on their own lines at the given depth.
+func (f *formatter) comments(comments []Comment, depth int) {
+	for _, c := range comments {
+		f.line(depth, c.BlankBefore)
+		f.result.WriteString(c.Text)
+	}
+}
+
+// after writes the comments following a node on its line.
+func (f *formatter) after(comments []Comment) {
+	for _, c := range comments {
+		f.result.WriteString(" " + c.Text)
+	}
+}
+
+// node writes the value of n, its key aside, at the given depth.
+func (f *formatter) node(n *Node, depth int) {
+	if n.Kind != Object && n.Kind != Array {
+		f.result.WriteString(n.Text)
+		return
+	}
+
+	open, close := "[", "]"
+	if n.Kind == Object {
+		open, close = "{", "}"
+	}
+	if len(n.Children) == 0 && len(n.End) == 0 {
+		f.result.WriteString(open + close)
+		return
+	}
+	if f.opts.InlineWidth > 0 && !hasComments(n) && f.inlineWidth(n, f.opts.InlineWidth) <= f.opts.InlineWidth {
+		f.inline(n)
+		return
+	}
+
+	f.result.WriteString(open)
+	f.opened = true
+	children := f.order(n)
+	for i, c := range children {
+		f.comments(c.Before, depth+1)
+		f.line(depth+1, c.BlankBefore)
+		if c.Key != "" {
+			f.result.WriteString(c.Key + ": ")
+		}
+		f.node(c, depth+1)
+		if i < len(children)-1 {
+			f.result.WriteString(",")
+		}
+		f.after(c.After)
+	}
+	f.comments(n.End, depth+1)
+	f.line(depth, false)
+	f.result.WriteString(close)
+}
+
+// hasComments reports whether comments appear inside n, whose own comments
+// are written outside of its brackets.
+func hasComments(n *Node) bool {
+	if len(n.End) > 0 {
+		return true
+	}
+	for _, c := range n.Children {
+		if len(c.Before) > 0 || len(c.After) > 0 || hasComments(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// inline writes n on a single line.
+func (f *formatter) inline(n *Node) {
+	if n.Kind != Object && n.Kind != Array {
+		f.result.WriteString(n.Text)
+		return
+	}
+
+	open, close := "[", "]"
+	if n.Kind == Object {
+		open, close = "{", "}"
+	}
+	f.result.WriteString(open)
+	for i, c := range f.order(n) {
+		if i > 0 {
+			f.result.WriteString(", ")
+		}
+		if c.Key != "" {
+			f.result.WriteString(c.Key + ": ")
+		}
+		f.inline(c)
+	}
+	f.result.WriteString(close)
+}
+
+// inlineWidth returns the length of n printed on a single line, or a value
+// greater than limit as soon as it is known to exceed it.
+func (f *formatter) inlineWidth(n *Node, limit int) int {
+	if n.Kind != Object && n.Kind != Array {
+		return len(n.Text)
+	}
+
+	width := 2 // brackets
+	for i, c := range n.Children {
+		if i > 0 {
+			width += 2 // ", "
+		}
+		if c.Key != "" {
+			width += len(c.Key) + 2 // ": "
+		}
+		if width > limit {
+			return width
+		}
+		width += f.inlineWidth(c, limit-width)
+		if width > limit {
+			return width
+		}
+	}
+	return width
+}
+
+// order returns the children of n in the order they are printed, their
+// comments moving with them when keys are sorted.
+func (f *formatter) order(n *Node) []*Node {
+	if !f.opts.SortKeys || n.Kind != Object {
+		return n.Children
+	}
+	children := append([]*Node(nil), n.Children...)
+	sort.SliceStable(children, func(i, j int) bool { return children[i].Key < children[j].Key })
+	return children
+}
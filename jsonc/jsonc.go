@@ -0,0 +1,271 @@
+// Package jsonc parses JSON with comments, as found in VS Code settings and
+// tsconfig.json files, into a syntax tree where each comment is attached to
+// the node it precedes or follows on the same line, so that Format can lay
+// such files out again without losing their comments. Blank lines between
+// members are kept as well, and trailing commas are accepted.
+package jsonc
+
+import (
+	"fmt"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Kind is the kind of a Node.
+type Kind int
+
+const (
+	Object Kind = iota
+	Array
+	String
+	Number
+	Bool
+	Null
+)
+
+// Comment is a // or /* */ comment.
+type Comment struct {
+	Text        string // the comment as written, delimiters included
+	BlankBefore bool   // whether a blank line precedes the comment
+}
+
+// Node is a value of a document, with the comments around it. Members of
+// objects are nodes with a key.
+type Node struct {
+	Kind     Kind
+	Key      string  // JSON text of the key of an object member, quotes included
+	Text     string  // JSON text of a scalar as written
+	Children []*Node // members of an object or elements of an array
+
+	Before      []Comment // comments on the lines preceding the node
+	After       []Comment // comments following the node on its last line
+	End         []Comment // comments preceding the closing bracket of an object or array
+	BlankBefore bool      // whether a blank line directly precedes the node
+}
+
+// Document is a parsed document: its root value and the comments following
+// it.
+type Document struct {
+	Root *Node
+	End  []Comment // comments on the lines following the root value
+}
+
+// Value returns the value held by n in the form the parser produces: objects
+// are *parser.OrderedObject and strings and keys are decoded. The comments are
+// dropped.
+func (n *Node) Value() interface{} {
+	switch n.Kind {
+	case Object:
+		obj := parser.NewOrderedObject()
+		for _, c := range n.Children {
+			obj.Set(unquote(c.Key), c.Value())
+		}
+		return obj
+	case Array:
+		arr := make(parser.JsonArray, len(n.Children))
+		for i, c := range n.Children {
+			arr[i] = c.Value()
+		}
+		return arr
+	case String:
+		return unquote(n.Text)
+	case Number:
+		v, err := parser.ParseNumber(n.Text)
+		if err != nil {
+			// integers too large for an int64 are read as floats
+			v, _ = parser.ParseNumber(n.Text + ".0")
+		}
+		return v
+	case Bool:
+		return n.Text == "true"
+	}
+	return nil
+}
+
+// unquote returns the characters of the string literal text, whose escape
+// sequences the scanner has checked.
+func unquote(text string) string {
+	s, _ := lexer.Unescape(text[1 : len(text)-1])
+	return s
+}
+
+// SyntaxError is an error of a document.
+type SyntaxError struct {
+	Msg    string // description of the error
+	Line   int    // line of the offending token
+	Column int    // column of the offending token
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("jsonc: %s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
+
+// Parse parses the document held by data, reporting syntax errors by a
+// *SyntaxError.
+func Parse(data []byte) (*Document, error) {
+	p := &docParser{s: scanner{data: data, line: 1, column: 1}}
+	p.advance()
+
+	root := &Node{}
+	p.attachBefore(root)
+	if err := p.value(root); err != nil {
+		return nil, err
+	}
+	p.attachAfter(root)
+	if p.tok.kind == tokenError {
+		return nil, p.errorf("%s", p.tok.text)
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.errorf("unexpected %s after the end of the document", p.tok.describe())
+	}
+	return &Document{Root: root, End: p.takeComments()}, nil
+}
+
+// docParser builds the tree of a document.
+type docParser struct {
+	s        scanner
+	tok      tok       // the next token
+	comments []comment // comments read before tok
+	lastLine int       // line the last consumed token ends on
+}
+
+// advance consumes the next token, gathering the comments before the
+// following one.
+func (p *docParser) advance() {
+	if p.tok.kind != tokenNone {
+		p.lastLine = p.tok.line
+	}
+	for {
+		p.tok = p.s.next()
+		if p.tok.kind != tokenComment {
+			return
+		}
+		p.comments = append(p.comments, comment{Comment: Comment{Text: p.tok.text, BlankBefore: p.tok.blankBefore}, line: p.tok.line})
+	}
+}
+
+// comment is a comment and the line it starts on.
+type comment struct {
+	Comment
+	line int
+}
+
+// takeComments returns the pending comments, which are then no longer
+// pending.
+func (p *docParser) takeComments() []Comment {
+	var result []Comment
+	for _, c := range p.comments {
+		result = append(result, c.Comment)
+	}
+	p.comments = nil
+	return result
+}
+
+// attachBefore attaches the pending comments to n, which starts at the next
+// token.
+func (p *docParser) attachBefore(n *Node) {
+	n.Before = append(n.Before, p.takeComments()...)
+	n.BlankBefore = p.tok.blankBefore
+}
+
+// attachAfter attaches to n, which ends with the last consumed token, the
+// pending comments starting on the same line.
+func (p *docParser) attachAfter(n *Node) {
+	i := 0
+	for i < len(p.comments) && p.comments[i].line == p.lastLine {
+		n.After = append(n.After, p.comments[i].Comment)
+		i++
+	}
+	p.comments = p.comments[i:]
+}
+
+// errorf returns an error located at the next token.
+func (p *docParser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Msg: fmt.Sprintf(format, args...), Line: p.tok.line, Column: p.tok.column}
+}
+
+// expected returns the error of a next token other than the one expected.
+func (p *docParser) expected(what string) error {
+	if p.tok.kind == tokenError {
+		return p.errorf("%s", p.tok.text)
+	}
+	return p.errorf("expected %s, got %s", what, p.tok.describe())
+}
+
+// value parses the value starting at the next token into n.
+func (p *docParser) value(n *Node) error {
+	n.Before = append(n.Before, p.takeComments()...)
+	switch p.tok.kind {
+	case tokenError:
+		return p.errorf("%s", p.tok.text)
+	case tokenString, tokenNumber:
+		n.Kind = String
+		if p.tok.kind == tokenNumber {
+			n.Kind = Number
+		}
+	case tokenLiteral:
+		n.Kind = Null
+		if p.tok.text != "null" {
+			n.Kind = Bool
+		}
+	case tokenBeginObject:
+		n.Kind = Object
+		return p.container(n, tokenEndObject)
+	case tokenBeginArray:
+		n.Kind = Array
+		return p.container(n, tokenEndArray)
+	default:
+		return p.errorf("unexpected %s", p.tok.describe())
+	}
+	n.Text = p.tok.text
+	p.advance()
+	return nil
+}
+
+// container parses the members or elements of n up to the closing token end,
+// the next token being the opening one.
+func (p *docParser) container(n *Node, end tokenKind) error {
+	p.advance()
+	var prev *Node
+	for {
+		if prev != nil {
+			p.attachAfter(prev)
+		}
+		if p.tok.kind == end {
+			n.End = p.takeComments()
+			p.advance()
+			return nil
+		}
+		if prev != nil {
+			if p.tok.kind != tokenValueSeparator {
+				return p.expected(fmt.Sprintf("',' or '%s'", end))
+			}
+			p.advance()
+			p.attachAfter(prev)
+			if p.tok.kind == end {
+				// trailing comma
+				continue
+			}
+		}
+
+		child := &Node{}
+		p.attachBefore(child)
+		if end == tokenEndObject {
+			if p.tok.kind != tokenString {
+				return p.expected("string for key")
+			}
+			child.Key = p.tok.text
+			p.advance()
+			if p.tok.kind != tokenNameSeparator {
+				return p.expected("':'")
+			}
+			p.advance()
+		}
+		if err := p.value(child); err != nil {
+			return err
+		}
+		n.Children = append(n.Children, child)
+		prev = child
+	}
+}
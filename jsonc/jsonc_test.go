@@ -0,0 +1,161 @@
+package jsonc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oabrivard/gojson/linter"
+)
+
+const settings = `// Editor settings
+{
+    // Font
+    "editor.fontSize": 14, // points
+    "editor.fontFamily": "Fira Code",
+
+    /* Files */
+    "files.exclude": {"**/.git": true, "**/node_modules": true,},
+    "files.associations": [ /* none yet */ ],
+    "search.exclude": {
+        "dist": true // build output
+        // more to come
+    }
+}
+// end of file`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(settings))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	root := doc.Root
+	if root.Kind != Object || len(root.Children) != 5 {
+		t.Fatalf("got %+v, want an object with 5 members", root)
+	}
+	if len(root.Before) != 1 || root.Before[0].Text != "// Editor settings" {
+		t.Errorf("got comments %v before the root", root.Before)
+	}
+	if len(doc.End) != 1 || doc.End[0].Text != "// end of file" {
+		t.Errorf("got comments %v after the root", doc.End)
+	}
+
+	fontSize := root.Children[0]
+	if fontSize.Key != `"editor.fontSize"` || fontSize.Text != "14" {
+		t.Errorf("got member %+v", fontSize)
+	}
+	if len(fontSize.Before) != 1 || fontSize.Before[0].Text != "// Font" {
+		t.Errorf("got comments %v before the first member", fontSize.Before)
+	}
+	if len(fontSize.After) != 1 || fontSize.After[0].Text != "// points" {
+		t.Errorf("got comments %v after the first member", fontSize.After)
+	}
+
+	exclude := root.Children[2]
+	if !exclude.Before[0].BlankBefore || exclude.Before[0].Text != "/* Files */" || exclude.BlankBefore {
+		t.Errorf("got %+v, want a blank line before the comment of files.exclude", exclude)
+	}
+	if associations := root.Children[3]; len(associations.End) != 1 || associations.End[0].Text != "/* none yet */" {
+		t.Errorf("got %+v, want the comment inside the empty array", associations)
+	}
+	search := root.Children[4]
+	if len(search.Children[0].After) != 1 || len(search.End) != 1 || search.End[0].Text != "// more to come" {
+		t.Errorf("got %+v, want the comments inside search.exclude", search)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	doc, err := Parse([]byte(settings))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Editor settings
+{
+  // Font
+  "editor.fontSize": 14, // points
+  "editor.fontFamily": "Fira Code",
+
+  /* Files */
+  "files.exclude": {
+    "**/.git": true,
+    "**/node_modules": true
+  },
+  "files.associations": [
+    /* none yet */
+  ],
+  "search.exclude": {
+    "dist": true // build output
+    // more to come
+  }
+}
+// end of file`
+	if got := Format(doc, linter.DefaultOptions()); got != expected {
+		t.Errorf("got\n%s\nwant\n%s", got, expected)
+	}
+	again, err := Parse([]byte(expected))
+	if err != nil || Format(again, linter.DefaultOptions()) != expected {
+		t.Errorf("formatting is not stable: %v\n%s", err, Format(again, linter.DefaultOptions()))
+	}
+
+	opts := linter.Options{Indent: "\t", SortKeys: true, InlineWidth: 60}
+	expected = `// Editor settings
+{
+	"editor.fontFamily": "Fira Code",
+	// Font
+	"editor.fontSize": 14, // points
+	"files.associations": [
+		/* none yet */
+	],
+
+	/* Files */
+	"files.exclude": {"**/.git": true, "**/node_modules": true},
+	"search.exclude": {
+		"dist": true // build output
+		// more to come
+	}
+}
+// end of file`
+	if got := Format(doc, opts); got != expected {
+		t.Errorf("got\n%s\nwant\n%s", got, expected)
+	}
+}
+
+func TestValue(t *testing.T) {
+	doc, err := Parse([]byte("[1, 2.5, \"a\\n\", true, null, {\"k\": false}, 12345678901234567890] // done"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := linter.Format(doc.Root.Value(), linter.Options{InlineWidth: 1 << 30})
+	if expected := `[1, 2.5, "a\n", true, null, {"k": false}, 1.2345678901234567e+19]`; got != expected {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{\n  \"a\": 1\n  \"b\": 2\n}", "jsonc: expected ',' or '}', got '\"b\"' at line 3, column 3"},
+		{"[1, /* open", "jsonc: unterminated comment at line 1, column 5"},
+		{"{a: 1}", "jsonc: unexpected 'a' at line 1, column 2"},
+		{"[\"a\" true]", "jsonc: expected ',' or ']', got 'true' at line 1, column 6"},
+		{"[01]", "jsonc: invalid number '01' at line 1, column 2"},
+		{"[1,,]", "jsonc: unexpected ',' at line 1, column 4"},
+		{"{} // x\n{}", "jsonc: unexpected '{' after the end of the document at line 2, column 1"},
+		{"", "jsonc: unexpected end of input at line 1, column 1"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a *SyntaxError, got %v", test.input, err)
+			continue
+		}
+		if err.Error() != test.expected {
+			t.Errorf("%q: got %q, want %q", test.input, err.Error(), test.expected)
+		}
+	}
+}
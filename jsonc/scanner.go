@@ -0,0 +1,245 @@
+package jsonc
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a token.
+type tokenKind int
+
+const (
+	tokenNone tokenKind = iota
+	tokenBeginObject
+	tokenEndObject
+	tokenBeginArray
+	tokenEndArray
+	tokenNameSeparator
+	tokenValueSeparator
+	tokenString
+	tokenNumber
+	tokenLiteral // true, false or null
+	tokenComment
+	tokenEOF
+	tokenError // text holds the description of the error
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEndObject:
+		return "}"
+	case tokenEndArray:
+		return "]"
+	}
+	return fmt.Sprintf("token %d", int(k))
+}
+
+// tok is a token of a document.
+type tok struct {
+	kind        tokenKind
+	text        string // text of the token as written
+	line        int    // line of the first character of the token
+	column      int    // column of the first character of the token
+	blankBefore bool   // whether a blank line precedes the token
+}
+
+// describe returns how the token appears in error messages.
+func (t tok) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of input"
+	}
+	return "'" + t.text + "'"
+}
+
+// scanner splits a document into tokens, comments included.
+type scanner struct {
+	data   []byte
+	pos    int // offset of the next character
+	line   int // line of the next character
+	column int // column of the next character
+}
+
+// next returns the next token.
+func (s *scanner) next() tok {
+	blank := s.skipSpace()
+	t := tok{line: s.line, column: s.column, blankBefore: blank}
+	start := s.pos
+	if s.pos >= len(s.data) {
+		t.kind = tokenEOF
+		return t
+	}
+
+	switch c := s.data[s.pos]; {
+	case c == '{':
+		t.kind = tokenBeginObject
+		s.advance(1)
+	case c == '}':
+		t.kind = tokenEndObject
+		s.advance(1)
+	case c == '[':
+		t.kind = tokenBeginArray
+		s.advance(1)
+	case c == ']':
+		t.kind = tokenEndArray
+		s.advance(1)
+	case c == ':':
+		t.kind = tokenNameSeparator
+		s.advance(1)
+	case c == ',':
+		t.kind = tokenValueSeparator
+		s.advance(1)
+	case c == '"':
+		t.kind = tokenString
+		if msg := s.scanString(); msg != "" {
+			return tok{kind: tokenError, text: msg, line: t.line, column: t.column}
+		}
+	case c == '/' && s.peekAt(1) == '/':
+		t.kind = tokenComment
+		for s.pos < len(s.data) && s.data[s.pos] != '\n' && s.data[s.pos] != '\r' {
+			s.advance(1)
+		}
+	case c == '/' && s.peekAt(1) == '*':
+		t.kind = tokenComment
+		s.advance(2)
+		for !(s.peekAt(0) == '*' && s.peekAt(1) == '/') {
+			if s.pos >= len(s.data) {
+				return tok{kind: tokenError, text: "unterminated comment", line: t.line, column: t.column}
+			}
+			s.advance(1)
+		}
+		s.advance(2)
+	case c == '-' || '0' <= c && c <= '9':
+		t.kind = tokenNumber
+		if !s.scanNumber() {
+			return tok{kind: tokenError, text: fmt.Sprintf("invalid number '%s'", s.data[start:s.pos]), line: t.line, column: t.column}
+		}
+	case 'a' <= c && c <= 'z':
+		for s.pos < len(s.data) && 'a' <= s.data[s.pos] && s.data[s.pos] <= 'z' {
+			s.advance(1)
+		}
+		t.kind = tokenLiteral
+		if word := string(s.data[start:s.pos]); word != "true" && word != "false" && word != "null" {
+			return tok{kind: tokenError, text: fmt.Sprintf("unexpected '%s'", word), line: t.line, column: t.column}
+		}
+	default:
+		r, _ := utf8.DecodeRune(s.data[s.pos:])
+		return tok{kind: tokenError, text: fmt.Sprintf("unexpected %q", r), line: t.line, column: t.column}
+	}
+	t.text = string(s.data[start:s.pos])
+	return t
+}
+
+// peekAt returns the character n bytes after the next one, or 0.
+func (s *scanner) peekAt(n int) byte {
+	if s.pos+n >= len(s.data) {
+		return 0
+	}
+	return s.data[s.pos+n]
+}
+
+// advance consumes n characters.
+func (s *scanner) advance(n int) {
+	for ; n > 0; n-- {
+		if s.data[s.pos] == '\n' {
+			s.line++
+			s.column = 1
+		} else {
+			s.column++
+		}
+		s.pos++
+	}
+}
+
+// skipSpace skips white space and reports whether it holds a blank line.
+func (s *scanner) skipSpace() bool {
+	newlines := 0
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\n':
+			newlines++
+		case ' ', '\t', '\r':
+		default:
+			return newlines > 1
+		}
+		s.advance(1)
+	}
+	return newlines > 1
+}
+
+// scanString consumes a string, the next character being its opening quote,
+// and returns the description of its error if it is invalid.
+func (s *scanner) scanString() string {
+	s.advance(1)
+	for {
+		if s.pos >= len(s.data) {
+			return "unterminated string"
+		}
+		switch c := s.data[s.pos]; {
+		case c == '"':
+			s.advance(1)
+			return ""
+		case c < 0x20:
+			return "control character in a string"
+		case c == '\\':
+			s.advance(1)
+			switch s.peekAt(0) {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				s.advance(1)
+			case 'u':
+				s.advance(1)
+				for i := 0; i < 4; i++ {
+					if !isHexDigit(s.peekAt(0)) {
+						return "invalid escape sequence in a string"
+					}
+					s.advance(1)
+				}
+			default:
+				return "invalid escape sequence in a string"
+			}
+		default:
+			s.advance(1)
+		}
+	}
+}
+
+// isHexDigit reports whether c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// scanNumber consumes a number and reports whether it is valid.
+func (s *scanner) scanNumber() bool {
+	digits := func() int {
+		n := 0
+		for '0' <= s.peekAt(0) && s.peekAt(0) <= '9' {
+			s.advance(1)
+			n++
+		}
+		return n
+	}
+
+	if s.peekAt(0) == '-' {
+		s.advance(1)
+	}
+	first := s.peekAt(0)
+	if n := digits(); n == 0 || n > 1 && first == '0' {
+		return false
+	}
+	if s.peekAt(0) == '.' {
+		s.advance(1)
+		if digits() == 0 {
+			return false
+		}
+	}
+	if c := s.peekAt(0); c == 'e' || c == 'E' {
+		s.advance(1)
+		if c := s.peekAt(0); c == '+' || c == '-' {
+			s.advance(1)
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return true
+}
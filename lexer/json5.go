@@ -0,0 +1,205 @@
+package lexer
+
+import (
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/token"
+)
+
+// currentRune returns the character starting at the current byte, and its
+// size in bytes.
+func (l *Lexer) currentRune() (rune, int) {
+	if l.ch < utf8.RuneSelf {
+		return rune(l.ch), 1
+	}
+	if l.reader == nil {
+		return utf8.DecodeRuneInString(l.input[l.position:])
+	}
+	next, _ := l.reader.Peek(utf8.UTFMax - 1)
+	return utf8.DecodeRune(append([]byte{l.ch}, next...))
+}
+
+// json5Space returns the size in bytes of the JSON5 whitespace character at
+// the current position, or 0 if there is none.
+func (l *Lexer) json5Space() int {
+	switch l.ch {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return 1
+	}
+	if l.ch < utf8.RuneSelf {
+		return 0
+	}
+	r, size := l.currentRune()
+	switch {
+	case r == '\u00a0', r == '\u2028', r == '\u2029', r == '\ufeff', unicode.Is(unicode.Zs, r):
+		return size
+	}
+	return 0
+}
+
+// atIdentifierStart reports whether a JSON5 identifier starts at the current
+// character, possibly with a \u escape sequence.
+func (l *Lexer) atIdentifierStart() bool {
+	r, _ := l.currentRune()
+	return r == '\\' || identifierStart(r)
+}
+
+// readJSON5Identifier reads a JSON5 identifier, returned as a TRUE, FALSE or
+// NULL token for the literals, as an IDENTIFIER token holding its characters
+// otherwise, and as an ILLEGAL token if it is invalid.
+func (l *Lexer) readJSON5Identifier() token.Token {
+	l.mark()
+	for r, size := l.currentRune(); r == '\\' || identifierPart(r); r, size = l.currentRune() {
+		for ; size > 0; size-- {
+			l.readChar()
+		}
+	}
+	raw := l.text()
+	tok := token.NewTokenWithValue(token.LookupIdent(raw), raw, l.line, l.column)
+	if tok.Type == token.ILLEGAL {
+		if name, ok := identifierName(raw); ok {
+			tok.Type, tok.Value = token.IDENTIFIER, name
+		}
+	}
+	return tok
+}
+
+// identifierName returns the characters of the JSON5 identifier raw, as
+// written in a document, decoding its \u escape sequences. It reports false
+// if raw is not a valid identifier.
+func identifierName(raw string) (string, bool) {
+	var name strings.Builder
+	for i := 0; i < len(raw); {
+		r, size := utf8.DecodeRuneInString(raw[i:])
+		if r == '\\' {
+			var ok bool
+			if r, ok = hexRune(strings.TrimPrefix(raw[i+1:], "u")); !ok || raw[i+1] != 'u' {
+				return "", false
+			}
+			size = 6
+		}
+		if !identifierPart(r) || i == 0 && !identifierStart(r) {
+			return "", false
+		}
+		name.WriteRune(r)
+		i += size
+	}
+	return name.String(), true
+}
+
+// identifierStart reports whether r may start a JSON5 identifier.
+func identifierStart(r rune) bool {
+	return r == '$' || r == '_' || unicode.In(r, unicode.L, unicode.Nl)
+}
+
+// identifierPart reports whether r may appear in a JSON5 identifier.
+func identifierPart(r rune) bool {
+	return identifierStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) ||
+		r == '\u200c' || r == '\u200d'
+}
+
+// strictNumber returns the strict JSON form of the JSON5 number text:
+// hexadecimal numbers are written in decimal, a leading plus sign is dropped
+// and a leading or trailing decimal point gets a 0. Other errors, such as
+// leading zeros, are left for parsers to report as they do for JSON. It
+// reports false if text is not a number, as for Infinity and NaN.
+func strictNumber(text string) (string, bool) {
+	sign := ""
+	switch {
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	case strings.HasPrefix(text, "-"):
+		sign, text = "-", text[1:]
+	}
+
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		digits := text[2:]
+		if digits == "" || strings.TrimLeft(digits, "0123456789abcdefABCDEF") != "" {
+			return "", false
+		}
+		n, _ := new(big.Int).SetString(digits, 16)
+		return sign + n.String(), true
+	}
+
+	switch {
+	case text == "":
+		return "", false
+	case text[0] == '.':
+		if len(text) == 1 || !isDigit(text[1]) {
+			return "", false
+		}
+		text = "0" + text
+	case !isDigit(text[0]):
+		return "", false
+	}
+	if i := strings.IndexByte(text, '.'); i >= 0 && (i+1 == len(text) || text[i+1] == 'e' || text[i+1] == 'E') {
+		text = text[:i+1] + "0" + text[i+1:]
+	}
+	return sign + text, true
+}
+
+// unescapeJSON5 returns the characters of a JSON5 string whose body, between
+// its quotes, is raw: on top of those of JSON, the escape sequences \' \v \0
+// and \xHH are decoded, backslashes followed by a line break continue the
+// string on the next line, and those followed by any other character stand
+// for that character. It reports false if raw holds an invalid escape
+// sequence, such as \1, or a line break that is not escaped.
+func unescapeJSON5(raw string) (string, bool) {
+	if !strings.ContainsAny(raw, "\\\n\r") {
+		return raw, true
+	}
+
+	// raw is rewritten as the body of a JSON string, decoded by Unescape, so
+	// that surrogate pairs written as two \u sequences remain pairs.
+	var body strings.Builder
+	body.Grow(len(raw))
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case '\n', '\r':
+			return "", false
+		case '"':
+			body.WriteString(`\"`)
+		case '\\':
+			if i+1 == len(raw) {
+				return "", false
+			}
+			i++
+			switch c := raw[i]; {
+			case strings.IndexByte(`"\/bfnrtu`, c) >= 0:
+				body.WriteByte('\\')
+				body.WriteByte(c)
+			case c == 'v':
+				body.WriteString(`\u000b`)
+			case c == '0':
+				if i+1 < len(raw) && isDigit(raw[i+1]) {
+					return "", false // octal escape sequence
+				}
+				body.WriteString(`\u0000`)
+			case c == 'x':
+				if _, ok := hexRune("00" + raw[i+1:min(i+3, len(raw))]); !ok {
+					return "", false
+				}
+				body.WriteString(`\u00`)
+				body.WriteString(raw[i+1 : i+3])
+				i += 2
+			case isDigit(c):
+				return "", false
+			case c == '\r':
+				if i+1 < len(raw) && raw[i+1] == '\n' {
+					i++
+				}
+			case c == '\n':
+			case strings.HasPrefix(raw[i:], "\u2028"), strings.HasPrefix(raw[i:], "\u2029"):
+				i += len("\u2028") - 1
+			default:
+				body.WriteByte(c)
+			}
+		default:
+			body.WriteByte(c)
+		}
+	}
+	return Unescape(body.String())
+}
@@ -2,6 +2,12 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+
 	"github.com/oabrivard/gojson/token"
 )
 
@@ -14,6 +20,22 @@ type Lexer struct {
 	ch           byte   // current char under examination
 	line         int    // current line number
 	column       int    // current column number
+
+	reader    *bufio.Reader // source of characters when reading from an io.Reader
+	start     int           // position where the text of the current token starts
+	capturing bool          // whether characters read from reader are kept in capture
+	capture   []byte        // text of the current token when reading from reader
+	eof       bool          // whether reader has been exhausted
+	read      int           // number of bytes read from reader
+	err       error         // error other than io.EOF returned by reader, or *LimitError, if any
+
+	raw         bool // whether STRING tokens keep the escape sequences of their text as written
+	comments    bool // whether // and /* */ comments are skipped like whitespace
+	openComment bool // whether a /* comment runs to the end of the input
+	json5       bool // whether the input is read as JSON5, see JSON5
+
+	limits Limits // caps on the input, see SetLimits
+	tokens int    // number of tokens returned, counted with limits only
 }
 
 // NewLexer creates and initializes a new Lexer with the given input string.
@@ -23,11 +45,177 @@ func NewLexer(input string) *Lexer {
 	return l
 }
 
-// NextToken reads the next token from the input and returns it.
+// NewBytesLexer creates and initializes a new Lexer scanning input in place,
+// without copying it: the text of tokens, and so the strings of values parsed
+// from them, reference input. It must therefore not be modified, nor unmapped
+// when it is a memory mapping, as long as they are in use.
+func NewBytesLexer(input []byte) *Lexer {
+	return NewLexer(unsafe.String(unsafe.SliceData(input), len(input)))
+}
+
+// NewReaderLexer creates and initializes a new Lexer reading its input from r.
+// Input is buffered internally and only the text of the token being scanned is
+// retained, so arbitrarily large documents can be tokenized in constant memory.
+// A read error ends the input as its end would, and is then reported by Err.
+func NewReaderLexer(r io.Reader) *Lexer {
+	l := &Lexer{reader: bufio.NewReader(r), line: 1, column: 0}
+	l.readChar() // Initialize the first character
+	return l
+}
+
+// Reset makes the lexer scan input from its start, as a lexer returned by
+// NewLexer would, reusing the memory it holds and keeping the settings of
+// DecodeStrings, SkipComments, JSON5 and SetLimits. Lexers can so be kept and
+// reused across documents, as by services handling many requests.
+func (l *Lexer) Reset(input string) {
+	*l = Lexer{input: input, line: 1, column: 0, capture: l.capture[:0], raw: l.raw, comments: l.comments, json5: l.json5, limits: l.limits}
+	l.readChar() // Initialize the first character
+}
+
+// DecodeStrings sets whether the escape sequences of strings are decoded, as
+// Unescape does, in the text of STRING tokens, and so in the strings and keys
+// parsed from them, which then hold the characters they stand for: "a\"b"
+// is read as a"b. Strings holding invalid escape sequences are returned as
+// ILLEGAL tokens. Strings are decoded by default; when off, the text of STRING
+// tokens is their body as written, escape sequences included.
+func (l *Lexer) DecodeStrings(on bool) {
+	l.raw = !on
+}
+
+// SkipComments sets whether // and /* */ comments, as found in JSONC files
+// such as VS Code settings and tsconfig.json, are skipped like whitespace. A
+// /* comment left open at the end of the input is returned as an ILLEGAL
+// token. Lexers must be given the setting before tokens are read from them,
+// and so before a parser is created on them. By default, a slash is an
+// ILLEGAL token.
+func (l *Lexer) SkipComments(on bool) {
+	l.comments = on
+}
+
+// JSON5 sets whether the input is read as JSON5, the superset of JSON meant
+// for configuration files written by hand, see https://spec.json5.org. On top
+// of JSON, comments are skipped as with SkipComments, as are the whitespace
+// characters of JSON5, strings may be written between single quotes and hold
+// the escape sequences of JSON5, line continuations included, numbers may be
+// hexadecimal, start with a plus sign or a decimal point or end with one, and
+// object keys may be identifiers, returned as IDENTIFIER tokens holding their
+// characters. The text of NUMBER tokens is the strict JSON form of the number,
+// as 0x1F is read as 31 and .5 as 0.5; Infinity and NaN, which have none, are
+// ILLEGAL tokens, as is any other identifier preceded by a sign. Parsers
+// created on the lexer accept trailing commas in objects and identifiers as
+// keys. Lexers must be given the setting before a parser is created on them.
+func (l *Lexer) JSON5(on bool) {
+	l.json5 = on
+}
+
+// IsJSON5 reports whether the input is read as JSON5, see JSON5.
+func (l *Lexer) IsJSON5() bool {
+	return l.json5
+}
+
+// Limits caps what a lexer accepts from untrusted input, so that a malicious
+// payload results in an error rather than unbounded memory growth. A limit of
+// 0 is no limit.
+type Limits struct {
+	MaxBytes        int // size of the input in bytes
+	MaxStringLength int // length in bytes of the text of a string token
+	MaxTokens       int // number of tokens, the final EOF excluded
+}
+
+// SetLimits sets the limits of the input. Exceeding one ends the input as its
+// end would, before the token exceeding it, Err then returning a *LimitError,
+// and reading the size limit past the last byte allowed: a lexer reading from an
+// io.Reader stops reading at the limit, so that neither the input nor a
+// string is ever buffered past it. Lexers must be given their limits before
+// a parser is created on them, parsers reporting the error as a LimitExceeded
+// ParseError.
+func (l *Lexer) SetLimits(limits Limits) {
+	l.limits = limits
+}
+
+// LimitError is the error of a lexer whose input exceeds one of its limits.
+type LimitError struct {
+	Limit        string // the limit exceeded: "document size", "string length" or "token count"
+	Max          int    // the value of the limit
+	Line, Column int    // position where the limit was exceeded
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s exceeds the limit of %d at line %d, column %d", e.Limit, e.Max, e.Line, e.Column)
+}
+
+// Err returns the error, other than io.EOF, that ended the input of a lexer
+// reading from an io.Reader, or the *LimitError of a lexer whose input
+// exceeds its limits, or nil. The tokens returned past such an error
+// describe a truncated document, so callers reporting syntax errors should
+// report it instead.
+func (l *Lexer) Err() error {
+	return l.err
+}
+
+// Offset returns the number of bytes of input read so far, the whole input
+// once the end has been reached.
+func (l *Lexer) Offset() int {
+	if l.reader != nil {
+		return l.read
+	}
+	return min(l.position, len(l.input))
+}
+
+// NextToken reads the next token from the input and returns it. A string
+// running to the end of the input is returned as an ILLEGAL token holding
+// its opening quote and its text.
 func (l *Lexer) NextToken() token.Token {
+	if l.limits == (Limits{}) {
+		return l.nextToken()
+	}
+	if l.err != nil {
+		return token.Token{Type: token.EOF, Line: l.line, Column: l.column, Offset: l.position}
+	}
+
+	tok := l.nextToken()
+	if tok.Type != token.EOF {
+		l.tokens++
+	}
+	switch max := l.limits; {
+	case l.err != nil:
+	case max.MaxBytes > 0 && l.reader == nil && l.position >= max.MaxBytes && len(l.input) > max.MaxBytes:
+		// The input has been read up to or past the limit, where a reader
+		// stops: report the first character past it, as a reader does.
+		line, column := locate(l.input[:max.MaxBytes])
+		l.err = &LimitError{Limit: "document size", Max: max.MaxBytes, Line: line, Column: column}
+	case max.MaxStringLength > 0 && tok.Type == token.STRING && len(tok.Value) > max.MaxStringLength:
+		l.fail("string length", max.MaxStringLength, tok)
+	case max.MaxTokens > 0 && l.tokens > max.MaxTokens:
+		l.fail("token count", max.MaxTokens, tok)
+	}
+	if l.err != nil {
+		return token.Token{Type: token.EOF, Line: tok.Line, Column: tok.Column, Offset: tok.Offset}
+	}
+	return tok
+}
+
+// locate returns the line and the column of the character following text,
+// the start of the input.
+func locate(text string) (line, column int) {
+	line = 1 + strings.Count(text, "\n")
+	return line, len(text) - strings.LastIndexByte(text, '\n')
+}
+
+// fail ends the input with the *LimitError of the given limit, exceeded by
+// tok, unless it has already ended with an error.
+func (l *Lexer) fail(limit string, max int, tok token.Token) {
+	if l.err == nil {
+		l.err = &LimitError{Limit: limit, Max: max, Line: tok.Line, Column: tok.Column}
+	}
+}
+
+// nextToken reads the next token from the input, regardless of limits.
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace() // Skip any whitespace before the next token
+	offset := l.position
 
 	// Switch on the current character to determine the token type
 	switch l.ch {
@@ -44,29 +232,79 @@ func (l *Lexer) NextToken() token.Token {
 	case ',':
 		tok = token.NewToken(token.VALUE_SEPARATOR, l.ch, l.line, l.column)
 	case '"':
-		tok = token.NewTokenWithValue(token.STRING, l.readString(), l.line, l.column)
+		tok = l.readStringToken()
 	case 0:
 		tok = token.NewTokenWithValue(token.EOF, "", l.line, l.column)
+		if l.openComment {
+			l.openComment = false
+			tok.Type, tok.Value = token.ILLEGAL, "/*"
+		}
 	default:
 		// Handle numbers and identifiers or mark as illegal
-		if isDigit(l.ch) || l.ch == '-' {
-			return token.NewTokenWithValue(token.NUMBER, l.readNumber(), l.line, l.column)
+		if l.json5 && l.ch == '\'' {
+			tok = l.readStringToken()
+		} else if isDigit(l.ch) || l.ch == '-' || l.json5 && (l.ch == '+' || l.ch == '.') {
+			tok = token.NewTokenWithValue(token.NUMBER, l.readNumber(), l.line, l.column)
+			if l.json5 {
+				if n, ok := strictNumber(tok.Value); ok {
+					tok.Value = n
+				} else {
+					tok.Type = token.ILLEGAL
+				}
+			}
+			tok.Offset = offset
+			return tok
+		} else if l.json5 && l.atIdentifierStart() {
+			tok = l.readJSON5Identifier()
+			tok.Offset = offset
+			return tok
 		} else if isLetter(l.ch) {
 			s := l.readIdentifier()
 			t := token.LookupIdent(s)
-			return token.NewTokenWithValue(t, s, l.line, l.column)
+			tok = token.NewTokenWithValue(t, s, l.line, l.column)
+			tok.Offset = offset
+			return tok
 		} else {
 			tok = token.NewToken(token.ILLEGAL, l.ch, l.line, l.column)
 		}
 	}
 
+	tok.Offset = offset
 	l.readChar() // Move to the next character
 	return tok
 }
 
+// readStringToken reads the string token starting at the current character,
+// a quotation mark, up to its closing quote.
+func (l *Lexer) readStringToken() token.Token {
+	quote := l.ch
+	tok := token.NewTokenWithValue(token.STRING, l.readString(quote), l.line, l.column)
+	switch {
+	case l.ch != quote:
+		// The string runs to the end of the input.
+		tok.Type, tok.Value = token.ILLEGAL, string(quote)+tok.Value
+	case l.raw:
+	case l.json5:
+		if s, ok := unescapeJSON5(tok.Value); ok {
+			tok.Value = s
+		} else {
+			tok.Type = token.ILLEGAL
+		}
+	default:
+		if s, ok := Unescape(tok.Value); ok {
+			tok.Value = s
+		} else {
+			tok.Type = token.ILLEGAL
+		}
+	}
+	return tok
+}
+
 // readChar advances to the next character in the input.
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
+	if l.reader != nil {
+		l.readCharFromReader()
+	} else if l.readPosition >= len(l.input) {
 		l.ch = 0 // End of input
 	} else {
 		l.ch = l.input[l.readPosition]
@@ -84,20 +322,173 @@ func (l *Lexer) readChar() {
 	l.readPosition++
 }
 
-// skipWhitespace skips over any whitespace characters in the input.
+// readCharFromReader sets the current character to the next byte of the
+// reader, or to 0 at the end of the input, on a read error or past the size
+// limit.
+func (l *Lexer) readCharFromReader() {
+	if l.limits.MaxBytes > 0 && l.read == l.limits.MaxBytes && l.err == nil {
+		if _, err := l.reader.Peek(1); err == nil {
+			l.err = &LimitError{Limit: "document size", Max: l.limits.MaxBytes, Line: l.line, Column: l.column + 1}
+		}
+	}
+	if l.err != nil {
+		l.ch = 0 // End of input
+		l.eof = true
+		return
+	}
+	ch, err := l.reader.ReadByte()
+	if err != nil {
+		l.ch = 0 // End of input
+		l.eof = true
+		if err != io.EOF {
+			l.err = err
+		}
+		return
+	}
+	l.ch = ch
+	l.read++
+	if l.capturing {
+		l.capture = append(l.capture, ch)
+	}
+}
+
+// mark records the current character as the start of a token's text.
+func (l *Lexer) mark() {
+	l.start = l.position
+	if l.reader != nil {
+		l.capturing = true
+		l.capture = l.capture[:0]
+		if !l.eof {
+			l.capture = append(l.capture, l.ch)
+		}
+	}
+}
+
+// text returns the input read since the last mark, excluding the current
+// character.
+func (l *Lexer) text() string {
+	if l.reader == nil {
+		return l.input[l.start:l.position]
+	}
+	l.capturing = false
+	if l.eof {
+		return string(l.capture) // the end of input was not captured
+	}
+	return string(l.capture[:len(l.capture)-1])
+}
+
+// advanceTo moves to the character at pos, past the current one, as many
+// calls to readChar would, counting the lines of the skipped input at once.
+// It only applies to string input.
+func (l *Lexer) advanceTo(pos int) {
+	if pos <= l.position {
+		return
+	}
+	skipped := l.input[l.position+1 : min(pos+1, len(l.input))]
+	if n := strings.Count(skipped, "\n"); n > 0 {
+		l.line += n
+		l.column = pos - (l.position + 1 + strings.LastIndexByte(skipped, '\n'))
+	} else {
+		l.column += pos - l.position
+	}
+
+	l.position = pos
+	l.readPosition = pos + 1
+	if pos < len(l.input) {
+		l.ch = l.input[pos]
+	} else {
+		l.ch = 0 // End of input
+	}
+}
+
+// skipWhitespace skips over any whitespace characters in the input, and the
+// comments between them with SkipComments or JSON5.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	l.skipSpaces()
+	for (l.comments || l.json5) && l.skipComment() {
+		l.skipSpaces()
+	}
+}
+
+// skipSpaces skips over the whitespace characters at the current position.
+func (l *Lexer) skipSpaces() {
+	if l.json5 {
+		for size := l.json5Space(); size > 0; size = l.json5Space() {
+			for ; size > 0; size-- {
+				l.readChar()
+			}
+		}
+		return
+	}
+	if l.reader == nil {
+		i := l.position
+		for i < len(l.input) && isWhitespace(l.input[i]) {
+			i++
+		}
+		l.advanceTo(i)
+		return
+	}
+	for isWhitespace(l.ch) {
 		l.readChar()
 	}
 }
 
-// readNumber reads a number (integer or floating point) from the input.
+// skipComment moves past the comment starting at the current character, if
+// any, and reports whether there was one.
+func (l *Lexer) skipComment() bool {
+	next := l.peekChar()
+	if l.ch != '/' || next != '/' && next != '*' {
+		return false
+	}
+	l.readChar()
+	l.readChar()
+	if next == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		return true
+	}
+	for l.ch != 0 && (l.ch != '*' || l.peekChar() != '/') {
+		l.readChar()
+	}
+	if l.ch == 0 {
+		l.openComment = true
+		return true
+	}
+	l.readChar()
+	l.readChar()
+	return true
+}
+
+// peekChar returns the character following the current one, or 0 at the end
+// of the input.
+func (l *Lexer) peekChar() byte {
+	if l.reader != nil {
+		next, err := l.reader.Peek(1)
+		if err != nil {
+			return 0
+		}
+		return next[0]
+	}
+	if l.readPosition < len(l.input) {
+		return l.input[l.readPosition]
+	}
+	return 0
+}
+
+// isWhitespace checks if a character is JSON whitespace.
+func isWhitespace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+// readNumber reads a number (integer or floating point) from the input, and
+// the letters of hexadecimal numbers, Infinity and NaN with JSON5.
 func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) || l.ch == '.' || l.ch == '-' || l.ch == '+' || l.ch == 'e' || l.ch == 'E' {
+	l.mark()
+	for isDigit(l.ch) || l.ch == '.' || l.ch == '-' || l.ch == '+' || l.ch == 'e' || l.ch == 'E' || l.json5 && isLetter(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.text()
 }
 
 // isDigit checks if a character is a digit.
@@ -105,25 +496,67 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-// readString reads a string from the input, handling escaped quotes.
-func (l *Lexer) readString() string {
-	position := l.position + 1
-	for {
+// readString reads a string ending with quote from the input, handling
+// escaped quotes.
+func (l *Lexer) readString(quote byte) string {
+	l.readChar() // Skip the opening quote
+	l.mark()
+	if l.reader == nil {
+		l.skipStringBody(quote)
+		return l.text()
+	}
+	for l.ch != quote && l.ch != 0 {
+		if l.limits.MaxStringLength > 0 && len(l.capture) > l.limits.MaxStringLength+1 {
+			l.err = &LimitError{Limit: "string length", Max: l.limits.MaxStringLength, Line: l.line, Column: l.column}
+			break
+		}
+		if l.ch == '\\' {
+			l.readChar() // An escaped character never ends the string
+			if l.ch == 0 {
+				break
+			}
+		}
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+	}
+	return l.text()
+}
+
+// skipStringBody moves to quote, ending the string whose body starts at the
+// current character, or to the end of the input, jumping from one quote,
+// backslash or NUL character to the next rather than reading every character.
+// A NUL character ends the input for readChar, and so ends the string.
+func (l *Lexer) skipStringBody(quote byte) {
+	stops := "\"\\\x00"
+	if quote != '"' {
+		stops = string(quote) + "\\\x00"
+	}
+	i := l.position
+	for i < len(l.input) {
+		k := strings.IndexAny(l.input[i:], stops)
+		if k < 0 {
 			break
 		}
+		i += k
+		if l.input[i] != '\\' {
+			l.advanceTo(i)
+			return
+		}
+		if i+1 < len(l.input) && l.input[i+1] == 0 {
+			l.advanceTo(i + 1)
+			return
+		}
+		i += 2 // An escaped character never ends the string
 	}
-	return l.input[position:l.position]
+	l.advanceTo(len(l.input))
 }
 
 // readIdentifier reads an identifier from the input.
 func (l *Lexer) readIdentifier() string {
-	position := l.position
+	l.mark()
 	for isLetter(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.text()
 }
 
 // isLetter checks if a character is a letter or underscore.
@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/oabrivard/gojson/token"
 )
@@ -43,3 +47,430 @@ func TestTokenizeSimpleObject(t *testing.T) {
 		}
 	}
 }
+
+func TestReaderLexerMatchesStringLexer(t *testing.T) {
+	input := `{"name": "John", "tags": ["a", "b"], "ok": true, "none": null, "value": -3.5e+5}`
+
+	expected := NewLexer(input)
+	l := NewReaderLexer(iotest.OneByteReader(strings.NewReader(input)))
+
+	for i := 0; ; i++ {
+		want := expected.NextToken()
+		tok := l.NextToken()
+
+		if tok != want {
+			t.Fatalf("tokens[%d] - expected=%+v, got=%+v", i, want, tok)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+}
+
+func TestStringLexerMatchesReaderLexer(t *testing.T) {
+	// The string lexer scans white space and strings in chunks, and must
+	// report the same tokens and positions as the reader one, which reads
+	// every character.
+	inputs := []string{
+		"{\n  \"name\": \"John\",\r\n\t\"tags\": [\"a\", \"b\"]\n}\n\n",
+		`"a\"b\\" : "\\" "x\\\"y"`,
+		"\"line one\nline two\" \n 1",
+		"  \"\"  \"\\u00e9\\n\" ",
+		`"unterminated`,
+		`"ends with a backslash\`,
+		"\"nul\x00inside\" 2",
+		"\"escaped nul\\\x00\" 3",
+		"\n\n   ",
+		"",
+	}
+
+	for _, input := range inputs {
+		expected := NewReaderLexer(iotest.OneByteReader(strings.NewReader(input)))
+		l := NewLexer(input)
+
+		for i := 0; i < 100; i++ {
+			want := expected.NextToken()
+			tok := l.NextToken()
+
+			if tok != want {
+				t.Fatalf("%q: tokens[%d] - expected=%+v, got=%+v", input, i, want, tok)
+			}
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+	}
+}
+
+func TestBytesLexerMatchesStringLexer(t *testing.T) {
+	input := `{"name": "John", "tags": ["a", "b"], "ok": true, "none": null, "value": -3.5e+5}`
+
+	expected := NewLexer(input)
+	l := NewBytesLexer([]byte(input))
+
+	for i := 0; ; i++ {
+		want := expected.NextToken()
+		tok := l.NextToken()
+
+		if tok != want {
+			t.Fatalf("tokens[%d] - expected=%+v, got=%+v", i, want, tok)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	if tok := NewBytesLexer(nil).NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF for empty input, got %+v", tok)
+	}
+}
+
+func TestReaderLexerTokenAtEndOfInput(t *testing.T) {
+	l := NewReaderLexer(strings.NewReader(`-12`))
+
+	tok := l.NextToken()
+	if tok.Type != token.NUMBER || tok.Value != "-12" {
+		t.Fatalf("expected number -12, got %+v", tok)
+	}
+	if tok := l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF, got %+v", tok)
+	}
+}
+
+func TestTokenizeEscapedQuote(t *testing.T) {
+	l := NewLexer(`"a\"b\\" :`)
+
+	tok := l.NextToken()
+	if tok.Type != token.STRING || tok.Value != `a"b\` {
+		t.Fatalf("expected the escaped quote decoded in the string, got %+v", tok)
+	}
+	if tok := l.NextToken(); tok.Type != token.NAME_SEPARATOR {
+		t.Fatalf("expected ':' after the string, got %+v", tok)
+	}
+}
+
+func TestTokenizeUnterminatedString(t *testing.T) {
+	for _, input := range []string{`"abc`, `"ab\"`, `"ab\`} {
+		for _, l := range []*Lexer{NewLexer(input), NewReaderLexer(strings.NewReader(input))} {
+			if tok := l.NextToken(); tok.Type != token.ILLEGAL || tok.Value != input {
+				t.Errorf("%s: expected an illegal token holding the string, got %+v", input, tok)
+			}
+			if tok := l.NextToken(); tok.Type != token.EOF {
+				t.Errorf("%s: expected EOF after the string, got %+v", input, tok)
+			}
+		}
+	}
+}
+
+func TestResetLexer(t *testing.T) {
+	// a lexer interrupted in the middle of a document, reader-based or not,
+	// scans the next one from its start once reset
+	inputs := []string{"{\n  \"a\": [1, true]\n}", `"b" null`, ""}
+	lexers := []*Lexer{NewLexer(`{"first": "doc`), NewReaderLexer(strings.NewReader("[\n[\n1"))}
+	for _, l := range lexers {
+		l.NextToken()
+		l.NextToken()
+
+		for _, input := range inputs {
+			l.Reset(input)
+			expected := NewLexer(input)
+			for i := 0; i < 100; i++ {
+				want := expected.NextToken()
+				tok := l.NextToken()
+
+				if tok != want {
+					t.Fatalf("%q: tokens[%d] - expected=%+v, got=%+v", input, i, want, tok)
+				}
+				if tok.Type == token.EOF {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestReaderLexerReadError(t *testing.T) {
+	failure := errors.New("connection reset")
+	l := NewReaderLexer(io.MultiReader(strings.NewReader(`{"a": 1`), iotest.ErrReader(failure)))
+
+	for _, expected := range []token.TokenType{token.BEGIN_OBJECT, token.STRING, token.NAME_SEPARATOR, token.NUMBER, token.EOF} {
+		if tok := l.NextToken(); tok.Type != expected {
+			t.Fatalf("expected a token of type %v, got %+v", expected, tok)
+		}
+	}
+	if l.Err() != failure {
+		t.Errorf("expected the read error, got %v", l.Err())
+	}
+
+	if l := NewReaderLexer(strings.NewReader(`1`)); l.NextToken().Type != token.NUMBER || l.NextToken().Type != token.EOF || l.Err() != nil {
+		t.Errorf("expected no error at the end of the input, got %v", l.Err())
+	}
+	l.Reset(`1`)
+	if l.Err() != nil {
+		t.Errorf("expected no error after a reset, got %v", l.Err())
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{`plain`, "plain"},
+		{`a\"b`, `a"b`},
+		{`\\ \/ \b\f\n\r\t`, "\\ / \b\f\n\r\t"},
+		{`caf\u00e9 \u00E9`, "café é"},
+		{`\ud83d\ude00!`, "😀!"},
+		{`\ud83d alone`, "� alone"},
+		{`\ude00\ud83d`, "��"},
+		{`\ud83dA`, "�A"},
+		{``, ""},
+	}
+	for _, tt := range tests {
+		if s, ok := Unescape(tt.raw); !ok || s != tt.expected {
+			t.Errorf("%q: expected %q, got %q, %v", tt.raw, tt.expected, s, ok)
+		}
+	}
+
+	for _, raw := range []string{`\q`, `end\`, `\u12`, `\u12g4`} {
+		if s, ok := Unescape(raw); ok {
+			t.Errorf("%q: expected an invalid escape sequence, got %q", raw, s)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		raw, text string
+	}{
+		{`plain`, "plain"},
+		{`a\"b\\c`, "a\"b\\c"},
+		{`line\nbreak\ttab\r`, "line\nbreak\ttab\r"},
+		{`\u0001\u001f`, "\x01\x1f"},
+		{`été`, "été"},
+	}
+	for _, tt := range tests {
+		if got := Escape(tt.text); got != tt.raw {
+			t.Errorf("Escape(%q): expected %q, got %q", tt.text, tt.raw, got)
+		}
+		if got, ok := Unescape(tt.raw); !ok || got != tt.text {
+			t.Errorf("Unescape(%q): expected %q, got %q", tt.raw, tt.text, got)
+		}
+	}
+	if got := Escape("a\xffb"); got != "a\uFFFDb" {
+		t.Errorf("expected invalid UTF-8 to be replaced, got %q", got)
+	}
+}
+
+func TestDecodeStrings(t *testing.T) {
+	input := `{"k\u00e9y": "a\"b\n"} "bad\q"`
+	for _, l := range []*Lexer{NewLexer(input), NewReaderLexer(strings.NewReader(input))} {
+		expected := []token.Token{
+			{Type: token.BEGIN_OBJECT, Value: "{", Line: 1, Column: 1},
+			{Type: token.STRING, Value: "kéy", Line: 1, Column: 11, Offset: 1},
+			{Type: token.NAME_SEPARATOR, Value: ":", Line: 1, Column: 12, Offset: 11},
+			{Type: token.STRING, Value: "a\"b\n", Line: 1, Column: 21, Offset: 13},
+			{Type: token.END_OBJECT, Value: "}", Line: 1, Column: 22, Offset: 21},
+			{Type: token.ILLEGAL, Value: `bad\q`, Line: 1, Column: 30, Offset: 23},
+			{Type: token.EOF, Value: "", Line: 1, Column: 31, Offset: 30},
+		}
+		for i, want := range expected {
+			if tok := l.NextToken(); tok != want {
+				t.Fatalf("tokens[%d] - expected=%+v, got=%+v", i, want, tok)
+			}
+		}
+
+		l.Reset(`"\t"`)
+		if tok := l.NextToken(); tok.Value != "\t" {
+			t.Errorf("expected strings decoded after a reset, got %+v", tok)
+		}
+
+		l.DecodeStrings(false)
+		l.Reset(`"a\"b" "bad\q"`)
+		if tok := l.NextToken(); tok.Type != token.STRING || tok.Value != `a\"b` {
+			t.Errorf("expected the escape sequences kept as written, got %+v", tok)
+		}
+		if tok := l.NextToken(); tok.Type != token.STRING || tok.Value != `bad\q` {
+			t.Errorf("expected invalid escape sequences kept as written, got %+v", tok)
+		}
+	}
+}
+
+func TestSkipComments(t *testing.T) {
+	input := "// settings\n{\"a\": /* one */ 1, // end\n\"b\": [true /**/]}/* last */"
+	expected := []token.Token{
+		{Type: token.BEGIN_OBJECT, Value: "{"},
+		{Type: token.STRING, Value: "a"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.NUMBER, Value: "1"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.STRING, Value: "b"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.BEGIN_ARRAY, Value: "["},
+		{Type: token.TRUE, Value: "true"},
+		{Type: token.END_ARRAY, Value: "]"},
+		{Type: token.END_OBJECT, Value: "}"},
+		{Type: token.EOF, Value: ""},
+	}
+	for _, l := range []*Lexer{NewLexer(input), NewReaderLexer(strings.NewReader(input))} {
+		l.SkipComments(true)
+		for i, want := range expected {
+			if tok := l.NextToken(); tok.Type != want.Type || tok.Value != want.Value {
+				t.Fatalf("tokens[%d] - expected=%+v, got=%+v", i, want, tok)
+			}
+		}
+
+		l.Reset("1 /* open")
+		if tok := l.NextToken(); tok.Type != token.NUMBER {
+			t.Fatalf("expected a number, got %+v", tok)
+		}
+		if tok := l.NextToken(); tok.Type != token.ILLEGAL || tok.Value != "/*" {
+			t.Errorf("expected an unterminated comment to be illegal, got %+v", tok)
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("expected EOF after an unterminated comment, got %+v", tok)
+		}
+	}
+
+	if tok := NewLexer("// x").NextToken(); tok.Type != token.ILLEGAL {
+		t.Errorf("expected comments to be illegal by default, got %+v", tok)
+	}
+}
+
+func TestJSON5(t *testing.T) {
+	input := "// settings\n{unquoted: 'it\\'s', \"x\u0061\": 0x1F, $_b: +.5, c: 5., d: [-0X10, 1e3,],\n" +
+		"'e': 'line\\\ntwo', f: \"\\x41\\v\", \u0061b: null,}"
+	expected := []token.Token{
+		{Type: token.BEGIN_OBJECT, Value: "{"},
+		{Type: token.IDENTIFIER, Value: "unquoted"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.STRING, Value: "it's"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.STRING, Value: "xa"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.NUMBER, Value: "31"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.IDENTIFIER, Value: "$_b"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.NUMBER, Value: "0.5"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.IDENTIFIER, Value: "c"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.NUMBER, Value: "5.0"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.IDENTIFIER, Value: "d"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.BEGIN_ARRAY, Value: "["},
+		{Type: token.NUMBER, Value: "-16"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.NUMBER, Value: "1e3"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.END_ARRAY, Value: "]"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.STRING, Value: "e"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.STRING, Value: "linetwo"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.IDENTIFIER, Value: "f"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.STRING, Value: "A\v"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.IDENTIFIER, Value: "ab"},
+		{Type: token.NAME_SEPARATOR, Value: ":"},
+		{Type: token.NULL, Value: "null"},
+		{Type: token.VALUE_SEPARATOR, Value: ","},
+		{Type: token.END_OBJECT, Value: "}"},
+		{Type: token.EOF, Value: ""},
+	}
+	for _, l := range []*Lexer{NewLexer(input), NewReaderLexer(strings.NewReader(input))} {
+		l.JSON5(true)
+		for i, want := range expected {
+			if tok := l.NextToken(); tok.Type != want.Type || tok.Value != want.Value {
+				t.Fatalf("tokens[%d] - expected=%+v, got=%+v", i, want, tok)
+			}
+		}
+
+		l.Reset("[1,\u00a0\u2028\ufeff\v\f2]")
+		for _, want := range []token.TokenType{token.BEGIN_ARRAY, token.NUMBER, token.VALUE_SEPARATOR, token.NUMBER, token.END_ARRAY, token.EOF} {
+			if tok := l.NextToken(); tok.Type != want {
+				t.Fatalf("expected JSON5 whitespace to be skipped, got %+v", tok)
+			}
+		}
+
+		for _, bad := range []string{"-Infinity", "+NaN", "0x", "0x-1", "+", ".", `'a\1'`, `'a\0' '\08'`, "'a\nb'", `"a\x4"`, "'open", `a-`, `\x61`} {
+			l.Reset(bad)
+			tok := l.NextToken()
+			for tok.Type != token.ILLEGAL && tok.Type != token.EOF {
+				tok = l.NextToken()
+			}
+			if tok.Type != token.ILLEGAL {
+				t.Errorf("%s: expected an illegal token", bad)
+			}
+		}
+	}
+
+	if tok := NewLexer("'a'").NextToken(); tok.Type != token.ILLEGAL || tok.Value != "'" {
+		t.Errorf("expected single quotes to be illegal by default, got %+v", tok)
+	}
+	if tok := NewLexer("+1").NextToken(); tok.Type != token.ILLEGAL {
+		t.Errorf("expected a plus sign to be illegal by default, got %+v", tok)
+	}
+}
+
+// endless is a reader of an endless repetition of a byte.
+type endless byte
+
+func (e endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(e)
+	}
+	return len(p), nil
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits Limits
+		tokens int // tokens returned before the input ends
+		err    string
+	}{
+		{`{"a": "bc"}`, Limits{MaxBytes: 11, MaxStringLength: 2, MaxTokens: 5}, 5, ""},
+		{`{"a": "bc"} `, Limits{MaxBytes: 11}, 4, "document size exceeds the limit of 11 at line 1, column 12"},
+		{`"` + strings.Repeat("a", 100) + `"`, Limits{MaxBytes: 10}, 0, "document size exceeds the limit of 10 at line 1, column 11"},
+		{"[1,\n \"abcdef\"]", Limits{MaxBytes: 8}, 3, "document size exceeds the limit of 8 at line 2, column 5"},
+		{`{"a": "bcd"}`, Limits{MaxStringLength: 2}, 3, "string length exceeds the limit of 2"},
+		{`[1, 2, 3]`, Limits{MaxTokens: 4}, 4, "token count exceeds the limit of 4 at line 1, column 6"},
+	}
+	for _, test := range tests {
+		for _, l := range []*Lexer{NewLexer(test.input), NewBytesLexer([]byte(test.input)), NewReaderLexer(strings.NewReader(test.input))} {
+			l.SetLimits(test.limits)
+			n := 0
+			for l.NextToken().Type != token.EOF {
+				n++
+			}
+			if n != test.tokens {
+				t.Errorf("%s: expected %d tokens, got %d", test.input, test.tokens, n)
+			}
+			err := l.Err()
+			var limitErr *LimitError
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("%s: unexpected error: %v", test.input, err)
+			case test.err != "" && (!errors.As(err, &limitErr) || !strings.HasPrefix(err.Error(), test.err)):
+				t.Errorf("%s: expected %q, got %v", test.input, test.err, err)
+			}
+			if tok := l.NextToken(); tok.Type != token.EOF {
+				t.Errorf("%s: expected the input to stay ended, got %+v", test.input, tok)
+			}
+		}
+	}
+
+	// Reading stops at the limits rather than buffering endless input.
+	for _, limits := range []Limits{{MaxBytes: 1 << 16}, {MaxStringLength: 1 << 16}} {
+		l := NewReaderLexer(io.MultiReader(strings.NewReader(`"`), endless('a')))
+		l.SetLimits(limits)
+		if tok := l.NextToken(); tok.Type != token.EOF || l.Err() == nil {
+			t.Errorf("%+v: expected the input to end with an error, got %+v", limits, tok)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package lexer
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Unescape returns the characters of a JSON string whose body, between its
+// quotation marks, is raw, as written in a document: the escape sequences of
+// RFC 8259, \" \\ \/ \b \f \n \r \t and \uXXXX, are decoded, surrogate
+// pairs written as two \u sequences included, and lone surrogates replaced by
+// U+FFFD. It reports false if raw holds an invalid escape sequence.
+func Unescape(raw string) (string, bool) {
+	i := strings.IndexByte(raw, '\\')
+	if i < 0 {
+		return raw, true
+	}
+
+	var result strings.Builder
+	result.Grow(len(raw))
+	for i >= 0 {
+		result.WriteString(raw[:i])
+		if i+1 == len(raw) {
+			return "", false
+		}
+		n := 2
+		switch c := raw[i+1]; c {
+		case '"', '\\', '/':
+			result.WriteByte(c)
+		case 'b':
+			result.WriteByte('\b')
+		case 'f':
+			result.WriteByte('\f')
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 't':
+			result.WriteByte('\t')
+		case 'u':
+			r, ok := hexRune(raw[i+2:])
+			if !ok {
+				return "", false
+			}
+			n = 6
+			if utf16.IsSurrogate(r) {
+				low, ok := hexRune(strings.TrimPrefix(raw[i+6:], `\u`))
+				if pair := utf16.DecodeRune(r, low); ok && len(raw) >= i+8 && raw[i+6:i+8] == `\u` && pair != utf8.RuneError {
+					r, n = pair, 12
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			result.WriteRune(r)
+		default:
+			return "", false
+		}
+		raw = raw[i+n:]
+		i = strings.IndexByte(raw, '\\')
+	}
+	result.WriteString(raw)
+	return result.String(), true
+}
+
+// Escape returns the body of the JSON string literal holding s, the inverse
+// of Unescape: quotation marks, backslashes and control characters are
+// escaped, and the bytes of s that are not valid UTF-8 are replaced by U+FFFD.
+func Escape(s string) string {
+	var result strings.Builder
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == '"' || r == '\\':
+			result.WriteByte('\\')
+			result.WriteRune(r)
+		case r == '\b':
+			result.WriteString(`\b`)
+		case r == '\f':
+			result.WriteString(`\f`)
+		case r == '\n':
+			result.WriteString(`\n`)
+		case r == '\r':
+			result.WriteString(`\r`)
+		case r == '\t':
+			result.WriteString(`\t`)
+		case r < 0x20:
+			result.WriteString(`\u00`)
+			result.WriteByte("0123456789abcdef"[r>>4])
+			result.WriteByte("0123456789abcdef"[r&0xf])
+		default:
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
+
+// hexRune returns the rune written with the 4 hexadecimal digits starting s.
+func hexRune(s string) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range []byte(s[:4]) {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c -= 'a' - 10
+		case 'A' <= c && c <= 'F':
+			c -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
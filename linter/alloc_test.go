@@ -0,0 +1,29 @@
+// The race detector makes sync.Pool drop items at random, so that the
+// buffers are not always reused.
+
+//go:build !race
+
+package linter
+
+import "testing"
+
+func TestPrintReusesBuffers(t *testing.T) {
+	node := NewObjectNode()
+	for _, key := range []string{`"b"`, `"a"`} {
+		arr := NewArrayNode()
+		arr.AddElement(NewScalarNode("1"))
+		arr.AddElement(NewScalarNode(`"text"`))
+		node.AddMember(key, arr)
+	}
+
+	for _, opts := range []Options{DefaultOptions(), {Prefix: "# ", Indent: "\t", InlineWidth: 12}} {
+		expected := opts.Print(node)
+		// only the returned string is allocated
+		if allocs := testing.AllocsPerRun(100, func() { opts.Print(node) }); allocs > 1 {
+			t.Errorf("Print(%+v): expected 1 allocation, got %v", opts, allocs)
+		}
+		if got := opts.Print(node); got != expected {
+			t.Errorf("Print(%+v): expected %q, got %q", opts, expected, got)
+		}
+	}
+}
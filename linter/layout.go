@@ -0,0 +1,325 @@
+package linter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/oabrivard/gojson/metrics"
+)
+
+// Options controls the layout of formatted JSON.
+type Options struct {
+	Prefix      string // written at the beginning of every line but the first
+	Indent      string // written once per nesting level at the beginning of a line
+	SortKeys    bool   // emit object members sorted by key instead of in their original order
+	InlineWidth int    // print objects and arrays no wider than this on a single line, 0 disables
+	Compact     bool   // print without any insignificant white space, ignoring the other options but SortKeys
+	Color       bool   // highlight keys, strings, numbers and literals with ANSI escape sequences, for terminals
+}
+
+// DefaultOptions returns the options used by NewJsonLinter: two-space
+// indentation, keys in source order and every element on its own line.
+func DefaultOptions() Options {
+	return Options{Indent: "  "}
+}
+
+// nodeKind distinguishes the kinds of Node.
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	objectNode
+	arrayNode
+)
+
+// Node is a JSON value prepared for layout. Scalars carry their final JSON
+// text, so that parsed documents and encoded Go values share a single
+// formatting pipeline.
+type Node struct {
+	kind     nodeKind
+	text     string   // JSON text of a scalar
+	keys     []string // JSON text of the keys of an object, quotes included
+	children []*Node  // members of an object or elements of an array
+}
+
+// NewScalarNode creates a Node for a string, number, boolean or null, given
+// its JSON text.
+func NewScalarNode(text string) *Node {
+	return &Node{kind: scalarNode, text: text}
+}
+
+// NewObjectNode creates a Node for an empty JSON object.
+func NewObjectNode() *Node {
+	return &Node{kind: objectNode}
+}
+
+// NewArrayNode creates a Node for an empty JSON array.
+func NewArrayNode() *Node {
+	return &Node{kind: arrayNode}
+}
+
+// AddMember appends a member to an object node. key is the JSON text of the
+// key, quotes included.
+func (n *Node) AddMember(key string, value *Node) {
+	n.keys = append(n.keys, key)
+	n.children = append(n.children, value)
+}
+
+// AddElement appends an element to an array node.
+func (n *Node) AddElement(value *Node) {
+	n.children = append(n.children, value)
+}
+
+// maxPooledBuffer is the capacity beyond which buffers are not returned to
+// bufferPool, so that formatting one huge document does not pin its memory.
+const maxPooledBuffer = 1 << 20
+
+// bufferPool holds the buffers Print lays documents out in, reused across
+// calls so that services formatting many documents do not churn the garbage
+// collector.
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// Print lays out n according to the options and returns the JSON text.
+func (o Options) Print(n *Node) string {
+	r := metrics.Active()
+	var start time.Time
+	if r != nil {
+		start = time.Now()
+	}
+
+	result := bufferPool.Get().(*bytes.Buffer)
+	result.Reset()
+	o.print(result, n, 0)
+	text := result.String()
+	if result.Cap() <= maxPooledBuffer {
+		bufferPool.Put(result)
+	}
+
+	if r != nil {
+		r.Formatted(len(text), time.Since(start))
+	}
+	return text
+}
+
+// Fprint lays out n according to the options and writes the JSON text to w
+// as it is produced, so that the text of a huge document is never held in
+// memory.
+func (o Options) Fprint(w io.Writer, n *Node) error {
+	r := metrics.Active()
+	var start time.Time
+	if r != nil {
+		start = time.Now()
+	}
+
+	counter := &countingWriter{w: w}
+	out := bufio.NewWriter(counter)
+	o.print(out, n, 0)
+	err := out.Flush()
+
+	if r != nil {
+		r.Formatted(counter.n, time.Since(start))
+	}
+	return err
+}
+
+// countingWriter counts the bytes written to w, for the metrics of Fprint.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
+
+// writer is the destination of a layout: the buffer of Print, or the
+// buffered writer of Fprint.
+type writer interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// print writes n at the given nesting depth.
+func (o Options) print(result writer, n *Node, depth int) {
+	if n.kind == scalarNode {
+		o.writeScalar(result, n.text)
+		return
+	}
+
+	open, close := "[", "]"
+	if n.kind == objectNode {
+		open, close = "{", "}"
+	}
+
+	if len(n.children) == 0 {
+		result.WriteString(open)
+		result.WriteString(close)
+		return
+	}
+	if o.Compact || o.InlineWidth > 0 && o.inlineWidth(n, o.InlineWidth) <= o.InlineWidth {
+		o.printInline(result, n)
+		return
+	}
+
+	result.WriteString(open)
+	indexes := o.order(n)
+	for i := range n.children {
+		c := i
+		if indexes != nil {
+			c = indexes[i]
+		}
+		if i > 0 {
+			result.WriteByte(',')
+		}
+		o.newline(result, depth+1)
+		if n.kind == objectNode {
+			o.writeKey(result, n.keys[c])
+			result.WriteString(": ")
+		}
+		o.print(result, n.children[c], depth+1)
+	}
+	o.newline(result, depth)
+	result.WriteString(close)
+}
+
+// printInline writes n on a single line, with a space after commas and
+// colons unless compacting.
+func (o Options) printInline(result writer, n *Node) {
+	if n.kind == scalarNode {
+		o.writeScalar(result, n.text)
+		return
+	}
+
+	open, close := "[", "]"
+	if n.kind == objectNode {
+		open, close = "{", "}"
+	}
+
+	result.WriteString(open)
+	indexes := o.order(n)
+	for i := range n.children {
+		c := i
+		if indexes != nil {
+			c = indexes[i]
+		}
+		if i > 0 {
+			result.WriteByte(',')
+			if !o.Compact {
+				result.WriteByte(' ')
+			}
+		}
+		if n.kind == objectNode {
+			o.writeKey(result, n.keys[c])
+			result.WriteByte(':')
+			if !o.Compact {
+				result.WriteByte(' ')
+			}
+		}
+		o.printInline(result, n.children[c])
+	}
+	result.WriteString(close)
+}
+
+// inlineWidth returns the length of n printed on a single line, or a value
+// greater than limit as soon as it is known to exceed it.
+func (o Options) inlineWidth(n *Node, limit int) int {
+	if n.kind == scalarNode {
+		return len(n.text)
+	}
+
+	width := 2 // brackets
+	for i, c := range n.children {
+		if i > 0 {
+			width += 2 // ", "
+		}
+		if n.kind == objectNode {
+			width += len(n.keys[i]) + 2 // ": "
+		}
+		if width > limit {
+			return width
+		}
+		width += o.inlineWidth(c, limit-width)
+		if width > limit {
+			return width
+		}
+	}
+	return width
+}
+
+// order returns the indexes of the children of n in the order they are
+// printed, or nil when they are printed in their own order.
+func (o Options) order(n *Node) []int {
+	if !o.SortKeys || n.kind != objectNode {
+		return nil
+	}
+	indexes := make([]int, len(n.children))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	// Keys are compared without their quotes, so that "a" sorts before "a b".
+	sort.SliceStable(indexes, func(i, j int) bool { return unquoted(n.keys[indexes[i]]) < unquoted(n.keys[indexes[j]]) })
+	return indexes
+}
+
+// unquoted returns the JSON text of a key without its quotes.
+func unquoted(key string) string {
+	if len(key) >= 2 {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+// newline starts a new line indented for the given depth.
+func (o Options) newline(result writer, depth int) {
+	result.WriteByte('\n')
+	result.WriteString(o.Prefix)
+	for i := 0; i < depth; i++ {
+		result.WriteString(o.Indent)
+	}
+}
+
+// ANSI escape sequences of the colors of highlighted output, chosen after
+// jq's.
+const (
+	keyColor     = "\x1b[34;1m" // bold blue
+	stringColor  = "\x1b[32m"   // green
+	numberColor  = "\x1b[36m"   // cyan
+	literalColor = "\x1b[35m"   // magenta, for true, false and null
+	resetColor   = "\x1b[0m"
+)
+
+// writeScalar writes the JSON text of a scalar, highlighted with Color.
+func (o Options) writeScalar(w io.StringWriter, text string) {
+	if !o.Color || text == "" {
+		w.WriteString(text)
+		return
+	}
+	color := literalColor
+	switch c := text[0]; {
+	case c == '"':
+		color = stringColor
+	case c == '-' || '0' <= c && c <= '9':
+		color = numberColor
+	}
+	w.WriteString(color)
+	w.WriteString(text)
+	w.WriteString(resetColor)
+}
+
+// writeKey writes the JSON text of a key, highlighted with Color.
+func (o Options) writeKey(w io.StringWriter, key string) {
+	if !o.Color {
+		w.WriteString(key)
+		return
+	}
+	w.WriteString(keyColor)
+	w.WriteString(key)
+	w.WriteString(resetColor)
+}
@@ -3,8 +3,12 @@ package linter
 
 import (
 	"fmt"
-	"strings"
+	"io"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/oabrivard/gojson/canonical"
 	"github.com/oabrivard/gojson/lexer"
 	"github.com/oabrivard/gojson/parser"
 )
@@ -13,82 +17,218 @@ import (
 type JsonLinter struct {
 	lexer  *lexer.Lexer   // The lexer to tokenize the input
 	parser *parser.Parser // The parser to parse the tokenized input
+
+	options Options // layout of the formatted output
+	timings Timings // durations measured by the last call to Lint
+}
+
+// Timings holds the wall time spent in each stage of a Lint or LintTo call.
+type Timings struct {
+	Parse  time.Duration // tokenizing and parsing the input
+	Format time.Duration // formatting the parsed value
 }
 
-// NewJsonLinter creates and initializes a new JsonLinter with the given input string.
+// NewJsonLinter creates and initializes a new JsonLinter with the given input
+// string, formatting with DefaultOptions.
 func NewJsonLinter(input string) *JsonLinter {
+	return NewJsonLinterWithOptions(input, DefaultOptions())
+}
+
+// NewJsonLinterWithOptions creates and initializes a new JsonLinter with the
+// given input string and layout options.
+func NewJsonLinterWithOptions(input string, opts Options) *JsonLinter {
 	l := lexer.NewLexer(input)
 	p := parser.NewParser(l)
-	return &JsonLinter{lexer: l, parser: p}
+	p.RecoverErrors(true)
+	return &JsonLinter{lexer: l, parser: p, options: opts}
 }
 
-// Lint performs the linting process on the input JSON.
+// NewJsonLinterFromBytes creates and initializes a new JsonLinter scanning
+// input in place with a bytes lexer, which input must outlive until Lint
+// returns.
+func NewJsonLinterFromBytes(input []byte, opts Options) *JsonLinter {
+	l := lexer.NewBytesLexer(input)
+	p := parser.NewParser(l)
+	p.RecoverErrors(true)
+	return &JsonLinter{lexer: l, parser: p, options: opts}
+}
+
+// NewJsoncLinter creates and initializes a new JsonLinter for JSON with
+// comments, such as VS Code settings and tsconfig.json files, with the given
+// input string and layout options. Comments are dropped from the output; the
+// jsonc package formats such files keeping them.
+func NewJsoncLinter(input string, opts Options) *JsonLinter {
+	l := lexer.NewLexer(input)
+	l.SkipComments(true)
+	p := parser.NewParser(l)
+	p.RecoverErrors(true)
+	return &JsonLinter{lexer: l, parser: p, options: opts}
+}
+
+// Lint performs the linting process on the input JSON, which may be any JSON
+// value, not only an object.
 // It parses the input and then formats it into a nicely structured JSON string.
+// Object keys are emitted in the order they appear in the input, so linting the
+// same document always produces the same output, and numbers are written as
+// they are spelled in the input, such as 1e100. The parser resumes after
+// the errors it finds, so that the *ParseErrors returned for an invalid
+// document lists all of its errors.
 func (jl *JsonLinter) Lint() (string, error) {
-	parsedObject := jl.parser.Parse()
-
-	// If parsing errors are present, return an aggregated error message.
-	if len(jl.parser.Errors()) > 0 {
-		return "", fmt.Errorf("parsing errors: %v", jl.parser.Errors())
+	parsedObject, err := jl.parse()
+	if err != nil {
+		return "", err
 	}
 
-	// Use the custom formatJSON function to format the parsed JSON object.
-	formattedJson := formatJSON(parsedObject, "")
+	// Lay out the parsed JSON object according to the linter's options.
+	start := time.Now()
+	formattedJson := Format(parsedObject, jl.options)
+	jl.timings.Format = time.Since(start)
 	return string(formattedJson), nil
 }
 
-// formatJSON formats any JSON value into a nicely indented string.
-func formatJSON(obj interface{}, indent string) string {
+// LintTo parses the input like Lint, and writes the formatted document to w
+// as it is laid out instead of returning it, so that formatting a huge
+// document does not hold its whole text in memory next to the input. Nothing
+// is written for invalid documents, for which the *ParseErrors is returned;
+// otherwise the error is the first one returned by w.
+func (jl *JsonLinter) LintTo(w io.Writer) error {
+	parsedObject, err := jl.parse()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = FormatTo(w, parsedObject, jl.options)
+	jl.timings.Format = time.Since(start)
+	return err
+}
+
+// parse parses the input for Lint and LintTo, returning the *ParseErrors of
+// invalid documents.
+func (jl *JsonLinter) parse() (interface{}, error) {
+	start := time.Now()
+	jl.parser.UseNumber(true) // numbers are printed as they are written
+	parsedObject := jl.parser.ParseOrderedValue()
+	jl.timings.Parse = time.Since(start)
+
+	// If parsing errors are present, return them all.
+	if errs := jl.parser.Errors(); len(errs) > 0 {
+		return nil, &ParseErrors{Errors: errs}
+	}
+	return parsedObject, nil
+}
+
+// Compact parses the input like Lint, and returns it without any
+// insignificant white space, the smallest form of the document for sending
+// it over the wire. Only the SortKeys layout option applies.
+func (jl *JsonLinter) Compact() (string, error) {
+	jl.options.Compact = true
+	return jl.Lint()
+}
+
+// Canonicalize parses the input like Lint, and returns its canonical form as
+// defined by the JSON Canonicalization Scheme (RFC 8785), as needed to sign
+// payloads: no white space, members sorted by their keys, numbers formatted
+// as ECMAScript does and strings escaped minimally. The layout options are
+// ignored. Objects holding a key more than once are reported as errors, the
+// scheme requiring unique keys.
+func (jl *JsonLinter) Canonicalize() (string, error) {
+	start := time.Now()
+	jl.parser.SetDuplicateKeys(parser.RejectDuplicates)
+	parsedObject := jl.parser.ParseValue()
+	jl.timings.Parse = time.Since(start)
+
+	if errs := jl.parser.Errors(); len(errs) > 0 {
+		return "", &ParseErrors{Errors: errs}
+	}
+
+	start = time.Now()
+	canonicalJson, err := canonical.Marshal(parsedObject)
+	jl.timings.Format = time.Since(start)
+	if err != nil {
+		return "", err
+	}
+	return string(canonicalJson), nil
+}
+
+// ParseErrors is the error returned by Lint, LintTo and Canonicalize for invalid documents, holding
+// all the errors the parser found in them.
+type ParseErrors struct {
+	Errors parser.ErrorList
+}
+
+func (e *ParseErrors) Error() string {
+	return fmt.Sprintf("parsing errors: %v", []parser.ParseError(e.Errors))
+}
+
+// Unwrap returns the errors of the parser, for errors.As to find them.
+func (e *ParseErrors) Unwrap() []error {
+	return e.Errors.Unwrap()
+}
+
+// Timings returns the durations measured by the last call to Lint.
+func (jl *JsonLinter) Timings() Timings {
+	return jl.timings
+}
+
+// Format lays out a parsed JSON value according to opts. Objects parsed with
+// ParseOrdered keep their key order, while plain JsonObject maps are emitted
+// with sorted keys since they carry no order. Strings and keys are escaped as
+// RFC 8259 requires.
+func Format(value interface{}, opts Options) string {
+	return opts.Print(valueNode(value))
+}
+
+// FormatTo lays out a parsed JSON value like Format, writing the text to w
+// as it is produced. It returns the first error returned by w.
+func FormatTo(w io.Writer, value interface{}, opts Options) error {
+	return opts.Fprint(w, valueNode(value))
+}
+
+// valueNode converts a parsed JSON value into a Node.
+func valueNode(obj interface{}) *Node {
 	// Type switch to handle different types of JSON values.
 	switch v := obj.(type) {
+	case *parser.OrderedObject:
+		node := NewObjectNode()
+		for _, k := range v.Keys {
+			node.AddMember(quote(k), valueNode(v.Values[k]))
+		}
+		return node
 	case parser.JsonObject:
-		return formatObject(v, indent) // Format a JSON object
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		node := NewObjectNode()
+		for _, k := range keys {
+			node.AddMember(quote(k), valueNode(v[k]))
+		}
+		return node
 	case parser.JsonArray:
-		return formatArray(v, indent) // Format a JSON array
+		node := NewArrayNode()
+		for _, e := range v {
+			node.AddElement(valueNode(e))
+		}
+		return node
 	case string:
-		return fmt.Sprintf("\"%s\"", v) // Format a JSON string
+		return NewScalarNode(quote(v)) // Format a JSON string
 	case nil:
-		return "null" // Format a JSON null
+		return NewScalarNode("null") // Format a JSON null
 	case bool:
 		if v {
-			return "true"
-		}
-		return "false"
-	default: // For numbers and other types, use default formatting
-		return fmt.Sprintf("%v", v)
-	}
-}
-
-// formatObject formats a JSON object into a string with proper indentation.
-func formatObject(obj map[string]interface{}, indent string) string {
-	var result strings.Builder
-	result.WriteString("{\n")
-	i := 0
-	for k, v := range obj {
-		// Format each key-value pair in the object.
-		result.WriteString(indent + "  \"" + k + "\": " + formatJSON(v, indent+"  "))
-		if i < len(obj)-1 {
-			result.WriteString(",")
-		}
-		result.WriteString("\n")
-		i++
-	}
-	result.WriteString(indent + "}")
-	return result.String()
-}
-
-// formatArray formats a JSON array into a string with proper indentation.
-func formatArray(array []interface{}, indent string) string {
-	var result strings.Builder
-	result.WriteString("[\n")
-	for i, v := range array {
-		// Format each value in the array.
-		result.WriteString(indent + "  " + formatJSON(v, indent+"  "))
-		if i < len(array)-1 {
-			result.WriteString(",")
+			return NewScalarNode("true")
 		}
-		result.WriteString("\n")
+		return NewScalarNode("false")
+	case parser.Number:
+		return NewScalarNode(string(v))
+	case int64:
+		return NewScalarNode(strconv.FormatInt(v, 10))
+	case float64:
+		return NewScalarNode(strconv.FormatFloat(v, 'g', -1, 64)) // as %v does
+	default: // For other types, use default formatting
+		return NewScalarNode(fmt.Sprintf("%v", v))
 	}
-	result.WriteString(indent + "]")
-	return result.String()
 }
@@ -1,7 +1,11 @@
 package linter
 
 import (
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/oabrivard/gojson/parser"
 )
 
 func TestLintSimpleObject(t *testing.T) {
@@ -62,3 +66,333 @@ func TestLintInvalidJson(t *testing.T) {
 		t.Errorf("Expected error(s) during linting")
 	}
 }
+
+func TestLintRecordsTimings(t *testing.T) {
+	jl := NewJsonLinter(`{"key": [1, 2, 3]}`)
+
+	if _, err := jl.Lint(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	timings := jl.Timings()
+	if timings.Parse <= 0 || timings.Format <= 0 {
+		t.Errorf("expected positive stage durations, got %+v", timings)
+	}
+}
+
+func TestLintFromBytesMatchesLint(t *testing.T) {
+	input := `{"b": [1, 2.5, {"c": null}], "a": "x\"y"}`
+
+	expected, err := NewJsonLinter(input).Lint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	linted, err := NewJsonLinterFromBytes([]byte(input), DefaultOptions()).Lint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if linted != expected {
+		t.Errorf("got %s, want %s", linted, expected)
+	}
+}
+
+func TestLintStreamMatchesLint(t *testing.T) {
+	corpus := []string{
+		`{
+			"key": "value",
+			"key-n": 101,
+			"key-f": 1.5e3,
+			"key-o": {
+				"inner key": "inner value",
+				"empty": {}
+			},
+			"key-l": ["list value", [], [true, false, null]]
+		}`,
+		`[]`,
+		`{}`,
+		`"text"`,
+		`-0.5e-3`,
+		`null`,
+		`[[[]], {"a": [{}]}, [1, [2, [3]]]]`,
+		`{"esc\"aped": "tab\t \u00e9 \/ \\", "pair 😀": "lone \ud800"}`,
+		"{\"a\":1,\n\t\"b\" :\r\n[ true ,false ]}",
+		`[12345678901234567890, 1E+2, 0.30000000000000004]`,
+	}
+	for _, input := range corpus {
+		expected, err := NewJsonLinter(input).Lint()
+		if err != nil {
+			t.Fatalf("%s: %v", input, err)
+		}
+		var out strings.Builder
+		if err := LintStream(strings.NewReader(input), &out); err != nil || out.String() != expected {
+			t.Errorf("%s: expected %q streamed, got %q (%v)", input, expected, out.String(), err)
+		}
+	}
+
+	// Lint accepts arrays without all of their commas, LintStream does not.
+	for _, input := range []string{`[1,]`, `[1 2]`, `{"a": [true false,]}`} {
+		if _, err := NewJsonLinter(input).Lint(); err != nil {
+			t.Errorf("%s: expected Lint to accept it, got %v", input, err)
+		}
+		var out strings.Builder
+		if err := LintStream(strings.NewReader(input), &out); err == nil {
+			t.Errorf("%s: expected an error while streaming", input)
+		}
+	}
+
+	// Lint keeps the last value of a duplicate key, LintStream every member.
+	input := `{"b": 2, "a": 1, "b": 3}`
+	expected := "{\n  \"b\": 2,\n  \"a\": 1,\n  \"b\": 3\n}"
+	var out strings.Builder
+	if err := LintStream(strings.NewReader(input), &out); err != nil || out.String() != expected {
+		t.Errorf("%s: expected every member streamed, got %q (%v)", input, out.String(), err)
+	}
+}
+
+func TestLintStreamWithOptions(t *testing.T) {
+	input := `{"a": [1, {"b": null}], "c": {}, "d": "e"}`
+	for _, opts := range []Options{{Indent: "\t"}, {Prefix: "// ", Indent: "    "}, {Indent: ""}, {Indent: "  ", Compact: true}} {
+		expected, err := NewJsonLinterWithOptions(input, opts).Lint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		if err := LintStreamWithOptions(strings.NewReader(input), &out, opts); err != nil || out.String() != expected {
+			t.Errorf("%+v: expected %q streamed, got %q, %v", opts, expected, out.String(), err)
+		}
+	}
+}
+
+func TestLintKeepsNumbers(t *testing.T) {
+	input := `[1e100, 0.30000000000000004, 12345678901234567890, -0.0, 1E+2, 1.50]`
+	expected := `[1e100, 0.30000000000000004, 12345678901234567890, -0.0, 1E+2, 1.50]`
+	opts := Options{InlineWidth: 80}
+	if linted, err := NewJsonLinterWithOptions(input, opts).Lint(); err != nil || linted != expected {
+		t.Errorf("expected %s, got %s (%v)", expected, linted, err)
+	}
+	var out strings.Builder
+	if err := LintStreamWithOptions(strings.NewReader(input), &out, Options{Compact: true}); err != nil || out.String() != strings.ReplaceAll(expected, " ", "") {
+		t.Errorf("expected the numbers kept when streaming, got %s (%v)", out.String(), err)
+	}
+
+	for _, input := range []string{`[01]`, `[1.]`, `[1e+]`} {
+		if _, err := NewJsonLinter(input).Lint(); err == nil {
+			t.Errorf("%s: expected an invalid number error", input)
+		}
+		if err := LintStream(strings.NewReader(input), &out); err == nil {
+			t.Errorf("%s: expected an invalid number error when streaming", input)
+		}
+	}
+}
+
+func TestLintStreamInvalidJson(t *testing.T) {
+	inputs := []string{
+		`{"key": ['list value']}`,
+		`{"key": "value",}`,
+		`{"key": [1, 2,]}`,
+		`{"key" "value"}`,
+		`{"a": 1 "b": 2}`,
+		`{"a": 1}}`,
+		`[1, 2] 3`,
+	}
+
+	for _, input := range inputs {
+		var out strings.Builder
+		if err := LintStream(strings.NewReader(input), &out); err == nil {
+			t.Errorf("expected an error while streaming %s", input)
+		}
+	}
+}
+
+func TestLintTopLevelValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[1, {"b": 2, "a": []}]`, "[\n  1,\n  {\n    \"b\": 2,\n    \"a\": []\n  }\n]"},
+		{` "hello" `, `"hello"`},
+		{`42`, `42`},
+		{`true`, `true`},
+		{`null`, `null`},
+	}
+
+	for _, tt := range tests {
+		result, err := NewJsonLinter(tt.input).Lint()
+		if err != nil || result != tt.expected {
+			t.Errorf("%s: expected %q, got %q, %v", tt.input, tt.expected, result, err)
+		}
+		var out strings.Builder
+		if err := LintStream(strings.NewReader(tt.input), &out); err != nil || out.String() != tt.expected {
+			t.Errorf("%s: expected %q streamed, got %q, %v", tt.input, tt.expected, out.String(), err)
+		}
+	}
+}
+
+func TestLintReportsAllErrors(t *testing.T) {
+	_, err := NewJsonLinter(`{"a": x, "b": [1, , 2], "c" 3}`).Lint()
+	expected := "parsing errors: [unexpected token 'x' at line 1, column 8 unexpected token ',' at line 1, column 19 expected next token to be 6, got 10 instead, at line 1, column 27]"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+
+	var errs *ParseErrors
+	if !errors.As(err, &errs) || len(errs.Errors) != 3 || errs.Errors[2].Line != 1 || errs.Errors[2].Column != 27 {
+		t.Fatalf("expected the 3 errors of the parser, got %#v", err)
+	}
+	var first parser.ParseError
+	if !errors.As(err, &first) || first.Code != parser.UnexpectedToken {
+		t.Errorf("expected errors.As to find the first parse error, got %+v", first)
+	}
+}
+
+func TestLintJsonc(t *testing.T) {
+	input := "{\n  // Editor settings.\n  \"editor.tabSize\": 2, /* spaces */\n  \"files.exclude\": [\"out\"]\n}\n"
+	got, err := NewJsoncLinter(input, DefaultOptions()).Lint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected, _ := NewJsonLinter(`{"editor.tabSize": 2, "files.exclude": ["out"]}`).Lint()
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	if _, err := NewJsonLinter(input).Lint(); err == nil {
+		t.Error("expected comments to be rejected by NewJsonLinter")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	input := "{\n  \"b\": [1, 2, {\"c\": \"x y\"}],\n  \"a\": {}\n}"
+	got, err := NewJsonLinter(input).Compact()
+	expected := `{"b":[1,2,{"c":"x y"}],"a":{}}`
+	if err != nil || got != expected {
+		t.Errorf("expected %s, got %s (%v)", expected, got, err)
+	}
+
+	got = Format(parser.JsonObject{"b": int64(1), "a": parser.JsonArray{true, nil}}, Options{Indent: "\t", SortKeys: true, Compact: true})
+	if expected := `{"a":[true,null],"b":1}`; got != expected {
+		t.Errorf("expected %s with sorted keys, got %s", expected, got)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	got, err := NewJsonLinter(`{"b": [1.50, 1E3, "\u00e9"], "a": {"d": null, "c": true}}`).Canonicalize()
+	expected := `{"a":{"c":true,"d":null},"b":[1.5,1000,"é"]}`
+	if err != nil || got != expected {
+		t.Errorf("expected %s, got %s (%v)", expected, got, err)
+	}
+
+	var errs *ParseErrors
+	if _, err := NewJsonLinter(`{"a": 1, "a": 2}`).Canonicalize(); !errors.As(err, &errs) || errs.Errors[0].Code != parser.DuplicateKey {
+		t.Errorf("expected a duplicate key error, got %v", err)
+	}
+}
+
+func TestLintWithOptions(t *testing.T) {
+	input := `{"b": [1, 2], "a": {"z": "long value here", "y": null}, "c": []}`
+
+	tests := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{Indent: "\t"}, "{\n\t\"b\": [\n\t\t1,\n\t\t2\n\t],\n\t\"a\": {\n\t\t\"z\": \"long value here\",\n\t\t\"y\": null\n\t},\n\t\"c\": []\n}"},
+		{Options{Prefix: "# ", Indent: " ", SortKeys: true}, "{\n#  \"a\": {\n#   \"y\": null,\n#   \"z\": \"long value here\"\n#  },\n#  \"b\": [\n#   1,\n#   2\n#  ],\n#  \"c\": []\n# }"},
+		{Options{Indent: "  ", InlineWidth: 10}, "{\n  \"b\": [1, 2],\n  \"a\": {\n    \"z\": \"long value here\",\n    \"y\": null\n  },\n  \"c\": []\n}"},
+		{Options{Indent: "  ", InlineWidth: 80}, "{\"b\": [1, 2], \"a\": {\"z\": \"long value here\", \"y\": null}, \"c\": []}"},
+	}
+
+	for i, tt := range tests {
+		linted, err := NewJsonLinterWithOptions(input, tt.opts).Lint()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if linted != tt.expected {
+			t.Errorf("tests[%d] - linted object is not as expected. Got %q, want %q", i, linted, tt.expected)
+		}
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	input := `{"a b": 1, "B": 2, "a": {"y": 3, "x": 4}, "ab": 5}`
+	expected := `{"B": 2, "a": {"x": 4, "y": 3}, "a b": 1, "ab": 5}`
+	for i := 0; i < 3; i++ {
+		linted, err := NewJsonLinterWithOptions(input, Options{SortKeys: true, InlineWidth: 80}).Lint()
+		if err != nil || linted != expected {
+			t.Fatalf("expected %s, got %s (%v)", expected, linted, err)
+		}
+	}
+}
+
+func TestFormatEscapesStrings(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{"a\"b\u00e9\n", `"a\"bé\n"`},
+		{"say \"hi\"\n\tand\x01go", `"say \"hi\"\n\tand\u0001go"`},
+		{`C:\dir\x \u12 end\`, `"C:\\dir\\x \\u12 end\\"`},
+		{parser.JsonObject{"k\"\n": "v"}, `{"k\"\n": "v"}`},
+	}
+	for _, tt := range tests {
+		if got := Format(tt.value, Options{InlineWidth: 80}); got != tt.expected {
+			t.Errorf("Format(%q): expected %s, got %s", tt.value, tt.expected, got)
+		}
+	}
+
+	var out strings.Builder
+	if err := LintStream(strings.NewReader("[\"a\tb\"]"), &out); err != nil || out.String() != "[\n  \"a\\tb\"\n]" {
+		t.Errorf("expected the tab escaped when streaming, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestLintColor(t *testing.T) {
+	input := `{"a": ["x", -1, true, null]}`
+	expected := "{\x1b[34;1m\"a\"\x1b[0m: [\x1b[32m\"x\"\x1b[0m, \x1b[36m-1\x1b[0m, \x1b[35mtrue\x1b[0m, \x1b[35mnull\x1b[0m]}"
+	opts := Options{InlineWidth: 30, Color: true}
+	if linted, err := NewJsonLinterWithOptions(input, opts).Lint(); err != nil || linted != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, linted, err)
+	}
+
+	// Escape sequences do not count in the width of inline values.
+	opts.Color = false
+	plain, _ := NewJsonLinterWithOptions(input, opts).Lint()
+	if plain != `{"a": ["x", -1, true, null]}` {
+		t.Errorf("expected the same layout without colors, got %q", plain)
+	}
+
+	var out strings.Builder
+	compact := strings.NewReplacer(", ", ",", ": ", ":").Replace(expected)
+	if err := LintStreamWithOptions(strings.NewReader(input), &out, Options{Compact: true, Color: true}); err != nil || out.String() != compact {
+		t.Errorf("expected the colors when streaming, got %q (%v)", out.String(), err)
+	}
+}
+
+// failingWriter fails every write, as a full disk does.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestLintTo(t *testing.T) {
+	input := `{"b": [1, 2.50, {"c": "x\ty"}], "a": {}, "d": "` + strings.Repeat("z", 8192) + `"}`
+	for _, opts := range []Options{DefaultOptions(), {Prefix: "# ", Indent: "\t", InlineWidth: 20}, {Compact: true, SortKeys: true}} {
+		expected, err := NewJsonLinterWithOptions(input, opts).Lint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		if err := NewJsonLinterWithOptions(input, opts).LintTo(&out); err != nil || out.String() != expected {
+			t.Errorf("LintTo(%+v): expected %q, got %q (%v)", opts, expected, out.String(), err)
+		}
+	}
+
+	var out strings.Builder
+	var errs *ParseErrors
+	if err := NewJsonLinter(`{"a": }`).LintTo(&out); !errors.As(err, &errs) || out.Len() != 0 {
+		t.Errorf("expected parse errors and no output, got %q (%v)", out.String(), err)
+	}
+	if err := NewJsonLinter(input).LintTo(failingWriter{}); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the error of the writer, got %v", err)
+	}
+}
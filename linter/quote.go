@@ -0,0 +1,10 @@
+package linter
+
+import "github.com/oabrivard/gojson/lexer"
+
+// quote returns the JSON text of the string s, its quotation marks,
+// backslashes and control characters being escaped as RFC 8259 requires, so
+// that the output is always valid JSON.
+func quote(s string) string {
+	return "\"" + lexer.Escape(s) + "\""
+}
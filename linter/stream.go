@@ -0,0 +1,190 @@
+package linter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/token"
+)
+
+// streamFormatter formats a JSON document token by token, writing the output
+// as soon as each token has been read. Only the current token and the nesting
+// of the document are kept in memory.
+type streamFormatter struct {
+	lexer    *lexer.Lexer  // the lexer producing the tokens to format
+	out      *bufio.Writer // the buffered destination of the formatted output
+	curToken token.Token   // current token under examination
+	opts     Options       // layout of the output
+}
+
+// LintStream reads a JSON document from r and writes it to w formatted with
+// DefaultOptions, without holding the document in memory. Valid documents are
+// formatted exactly like Lint formats them, with two exceptions: LintStream
+// rejects arrays missing the commas between their elements, or ending with
+// one, as in [1 2,], which Lint accepts, and it writes every member of an
+// object holding a key more than once, where Lint keeps the last value at
+// the position of the first. Since the output is produced while the input is
+// being read, part of it may already have been written when a syntax error
+// is found.
+func LintStream(r io.Reader, w io.Writer) error {
+	return LintStreamWithOptions(r, w, DefaultOptions())
+}
+
+// LintStreamWithOptions is like LintStream, laying the document out
+// according to the Prefix, Indent, Compact and Color options. Members are written in
+// their original order and every element on its own line unless compacting,
+// since SortKeys and InlineWidth need whole objects and arrays.
+func LintStreamWithOptions(r io.Reader, w io.Writer, opts Options) error {
+	f := &streamFormatter{lexer: lexer.NewReaderLexer(r), out: bufio.NewWriter(w), opts: opts}
+	f.nextToken()
+
+	if err := f.formatValue(0); err != nil {
+		return err
+	}
+
+	f.nextToken()
+	if f.curToken.Type != token.EOF {
+		return f.errorf("unexpected token '%s' after the end of the document at line %d, column %d", f.curToken.Value, f.curToken.Line, f.curToken.Column)
+	}
+	return f.out.Flush()
+}
+
+// nextToken advances to the next token of the input.
+func (f *streamFormatter) nextToken() {
+	f.curToken = f.lexer.NextToken()
+}
+
+// errorf flushes what has been formatted so far and returns a parsing error.
+func (f *streamFormatter) errorf(format string, args ...interface{}) error {
+	f.out.Flush()
+	if err := f.lexer.Err(); err != nil {
+		return err // the input was truncated by a read error
+	}
+	return fmt.Errorf("parsing error: "+format, args...)
+}
+
+// newline starts a new line indented for the given depth, or does nothing
+// when compacting.
+func (f *streamFormatter) newline(depth int) {
+	if f.opts.Compact {
+		return
+	}
+	f.out.WriteByte('\n')
+	f.out.WriteString(f.opts.Prefix)
+	for i := 0; i < depth; i++ {
+		f.out.WriteString(f.opts.Indent)
+	}
+}
+
+// formatValue writes the JSON value starting at the current token, found at
+// the given depth.
+func (f *streamFormatter) formatValue(depth int) error {
+	switch f.curToken.Type {
+	case token.STRING:
+		f.opts.writeScalar(f.out, quote(f.curToken.Value))
+	case token.NUMBER:
+		// Numbers are written as they are, as Lint does.
+		if !parser.Number(f.curToken.Value).Valid() {
+			kind := "integer"
+			if strings.ContainsAny(f.curToken.Value, ".eE") {
+				kind = "float"
+			}
+			return f.errorf("could not parse %q as %s at line %d, column %d", f.curToken.Value, kind, f.curToken.Line, f.curToken.Column)
+		}
+		f.opts.writeScalar(f.out, f.curToken.Value)
+	case token.TRUE, token.FALSE, token.NULL:
+		f.opts.writeScalar(f.out, f.curToken.Value)
+	case token.BEGIN_OBJECT:
+		return f.formatObject(depth)
+	case token.BEGIN_ARRAY:
+		return f.formatArray(depth)
+	default:
+		return f.errorf("unexpected token '%s' at line %d, column %d", f.curToken.Value, f.curToken.Line, f.curToken.Column)
+	}
+	return nil
+}
+
+// formatObject writes the JSON object starting at the current token.
+func (f *streamFormatter) formatObject(depth int) error {
+	f.nextToken()
+	if f.curToken.Type == token.END_OBJECT {
+		f.out.WriteString("{}")
+		return nil
+	}
+	f.out.WriteByte('{')
+
+	for {
+		if f.curToken.Type != token.STRING {
+			return f.errorf("expected string for key at line %d, column %d, got '%s'", f.curToken.Line, f.curToken.Column, f.curToken.Value)
+		}
+		f.newline(depth + 1)
+		f.opts.writeKey(f.out, quote(f.curToken.Value))
+		f.out.WriteByte(':')
+		if !f.opts.Compact {
+			f.out.WriteByte(' ')
+		}
+
+		f.nextToken()
+		if f.curToken.Type != token.NAME_SEPARATOR {
+			return f.errorf("expected ':' at line %d, column %d, got '%s'", f.curToken.Line, f.curToken.Column, f.curToken.Value)
+		}
+
+		f.nextToken()
+		if err := f.formatValue(depth + 1); err != nil {
+			return err
+		}
+
+		f.nextToken()
+		switch f.curToken.Type {
+		case token.END_OBJECT:
+			f.newline(depth)
+			f.out.WriteByte('}')
+			return nil
+		case token.VALUE_SEPARATOR:
+			f.out.WriteByte(',')
+			f.nextToken()
+			if f.curToken.Type == token.END_OBJECT { // No comma just before the end of the object
+				return f.errorf("No ',' before '}' at line %d, column %d", f.curToken.Line, f.curToken.Column)
+			}
+		default:
+			return f.errorf("expected ',' or '}' at line %d, column %d, got '%s'", f.curToken.Line, f.curToken.Column, f.curToken.Value)
+		}
+	}
+}
+
+// formatArray writes the JSON array starting at the current token.
+func (f *streamFormatter) formatArray(depth int) error {
+	f.nextToken()
+	if f.curToken.Type == token.END_ARRAY {
+		f.out.WriteString("[]")
+		return nil
+	}
+	f.out.WriteByte('[')
+
+	for {
+		f.newline(depth + 1)
+		if err := f.formatValue(depth + 1); err != nil {
+			return err
+		}
+
+		f.nextToken()
+		switch f.curToken.Type {
+		case token.END_ARRAY:
+			f.newline(depth)
+			f.out.WriteByte(']')
+			return nil
+		case token.VALUE_SEPARATOR:
+			f.out.WriteByte(',')
+			f.nextToken()
+			if f.curToken.Type == token.END_ARRAY { // No comma just before the end of the array
+				return f.errorf("No ',' before ']' at line %d, column %d", f.curToken.Line, f.curToken.Column)
+			}
+		default:
+			return f.errorf("expected ',' or ']' at line %d, column %d, got '%s'", f.curToken.Line, f.curToken.Column, f.curToken.Value)
+		}
+	}
+}
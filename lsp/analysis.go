@@ -0,0 +1,361 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/token"
+)
+
+// Severities of diagnostics.
+const (
+	severityError   = 1
+	severityWarning = 2
+)
+
+// Kinds of document symbols, as numbered by the protocol.
+const (
+	symbolString  = 15
+	symbolNumber  = 16
+	symbolBoolean = 17
+	symbolArray   = 18
+	symbolObject  = 19
+	symbolNull    = 21
+)
+
+// position is a location in a document: a line and the UTF-16 code units
+// preceding it on that line, both starting at 0.
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// textRange is the span of a document between two positions, end excluded.
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+// diagnostic is an error or warning about a document.
+type diagnostic struct {
+	Range    textRange `json:"range"`
+	Severity int       `json:"severity"`
+	Source   string    `json:"source"`
+	Message  string    `json:"message"`
+}
+
+// documentSymbol is a member or element of a document, shown in outlines.
+type documentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          textRange        `json:"range"`          // from the key to the end of the value
+	SelectionRange textRange        `json:"selectionRange"` // the key, or the value of elements
+	Children       []documentSymbol `json:"children,omitempty"`
+}
+
+// analysis is what is known of the text of a document.
+type analysis struct {
+	text        string
+	value       interface{} // parsed document, objects being ordered, or nil if invalid
+	valid       bool
+	symbols     []documentSymbol
+	diagnostics []diagnostic
+}
+
+// tok is a token with the offsets where it starts and ends.
+type tok struct {
+	token.Token
+	start, end   int
+	unterminated bool // a string that the end of the input interrupts
+}
+
+// analyzer parses a document, recording symbols and diagnostics.
+type analyzer struct {
+	*analysis
+	lines  []int // offsets where lines start
+	tokens []tok
+	pos    int // index of the current token
+}
+
+// analyze parses text, reporting syntax errors, which stop parsing, and
+// duplicate keys as diagnostics.
+func analyze(text string) *analysis {
+	a := &analyzer{analysis: &analysis{text: text}, lines: lineStarts(text)}
+	a.tokenize()
+
+	value, children, ok := a.parseValue()
+	if ok && a.current().Type != token.EOF {
+		a.errorf(a.current(), "unexpected %s after the end of the document", describe(a.current()))
+		ok = false
+	}
+	if ok {
+		a.value, a.valid, a.symbols = value, true, children
+	}
+	return a.analysis
+}
+
+// tokenize splits the text into tokens. The lexer reports the position of
+// single characters, the closing quote of strings, and the character
+// following numbers and literals, from which the offsets of their ends are
+// derived. Strings are kept as written, so that their lengths give their
+// offsets, and decoded by parseValue.
+func (a *analyzer) tokenize() {
+	l := lexer.NewLexer(a.text)
+	l.DecodeStrings(false)
+	for {
+		t := tok{Token: l.NextToken()}
+		at := a.offset(t.Line, t.Column)
+		switch {
+		case t.Type == token.ILLEGAL && strings.HasPrefix(t.Value, `"`):
+			// A string running to the end of the text.
+			t.Type, t.Value, t.unterminated = token.STRING, t.Value[1:], true
+			t.start, t.end = at-len(t.Value)-1, at+1
+		case t.Type == token.STRING:
+			t.start, t.end = at-len(t.Value)-1, at+1
+		case t.Type == token.EOF:
+			t.start, t.end = len(a.text), len(a.text)
+		case t.Type == token.NUMBER || t.Type == token.TRUE || t.Type == token.FALSE || t.Type == token.NULL,
+			t.Type == token.ILLEGAL && len(t.Value) > 0 && isLetter(t.Value[0]):
+			t.start, t.end = at-len(t.Value), at
+		default:
+			t.start, t.end = at, at+1
+		}
+		t.end = min(t.end, len(a.text))
+		a.tokens = append(a.tokens, t)
+		if t.Type == token.EOF {
+			return
+		}
+	}
+}
+
+// offset returns the offset of the character at the 1-based line and column
+// the lexer reports, column 0 designating the end of the previous line.
+func (a *analyzer) offset(line, column int) int {
+	if line < 1 || line > len(a.lines) {
+		return len(a.text)
+	}
+	return max(0, min(a.lines[line-1]+column-1, len(a.text)))
+}
+
+// position returns the protocol position of the character at offset.
+func (a *analyzer) position(offset int) position {
+	line := sort.Search(len(a.lines), func(i int) bool { return a.lines[i] > offset }) - 1
+	character := 0
+	for s := a.text[a.lines[line]:offset]; len(s) > 0; {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		if r >= 0x10000 {
+			character += 2 // a surrogate pair
+		} else {
+			character++
+		}
+	}
+	return position{Line: line, Character: character}
+}
+
+// span returns the range from the start of from to the end of to.
+func (a *analyzer) span(from, to tok) textRange {
+	return textRange{Start: a.position(from.start), End: a.position(to.end)}
+}
+
+// current returns the current token.
+func (a *analyzer) current() tok {
+	return a.tokens[a.pos]
+}
+
+// advance moves to the next token and returns the one it leaves.
+func (a *analyzer) advance() tok {
+	t := a.tokens[a.pos]
+	if t.Type != token.EOF {
+		a.pos++
+	}
+	return t
+}
+
+// errorf records a syntax error at t.
+func (a *analyzer) errorf(t tok, format string, args ...interface{}) {
+	a.report(a.span(t, t), severityError, fmt.Sprintf(format, args...))
+}
+
+// report records a diagnostic.
+func (a *analyzer) report(r textRange, severity int, message string) {
+	a.diagnostics = append(a.diagnostics, diagnostic{Range: r, Severity: severity, Source: "gojson", Message: message})
+}
+
+// describe returns how t appears in messages.
+func describe(t tok) string {
+	if t.Type == token.EOF {
+		return "end of input"
+	}
+	if t.Type == token.STRING {
+		return "'\"" + t.Value + "\"'"
+	}
+	return "'" + t.Value + "'"
+}
+
+// parseValue parses the value starting at the current token, returning it
+// with the symbols of its members or elements, or false after recording a
+// syntax error.
+func (a *analyzer) parseValue() (interface{}, []documentSymbol, bool) {
+	t := a.current()
+	switch t.Type {
+	case token.BEGIN_OBJECT:
+		return a.parseObject()
+	case token.BEGIN_ARRAY:
+		return a.parseArray()
+	case token.STRING:
+		a.advance()
+		if t.unterminated {
+			a.errorf(t, "unterminated string")
+			return nil, nil, false
+		}
+		s, ok := lexer.Unescape(t.Value)
+		if !ok {
+			a.errorf(t, "invalid escape sequence in %s", describe(t))
+			return nil, nil, false
+		}
+		return s, nil, true
+	case token.NUMBER:
+		a.advance()
+		n, err := parser.ParseNumber(t.Value)
+		if err != nil {
+			a.errorf(t, "invalid number '%s'", t.Value)
+			return nil, nil, false
+		}
+		return n, nil, true
+	case token.TRUE, token.FALSE:
+		a.advance()
+		return t.Type == token.TRUE, nil, true
+	case token.NULL:
+		a.advance()
+		return nil, nil, true
+	}
+	a.errorf(t, "unexpected %s", describe(t))
+	return nil, nil, false
+}
+
+// parseObject parses an object, the current token being its opening brace.
+func (a *analyzer) parseObject() (interface{}, []documentSymbol, bool) {
+	a.advance()
+	obj := parser.NewOrderedObject()
+	var symbols []documentSymbol
+	keys := map[string]tok{}
+	if a.current().Type == token.END_OBJECT {
+		a.advance()
+		return obj, symbols, true
+	}
+
+	for {
+		key := a.current()
+		if key.Type != token.STRING {
+			a.errorf(key, "expected string for key, got %s", describe(key))
+			return nil, nil, false
+		}
+		decoded, _, ok := a.parseValue() // consumes the key, checking that it is terminated
+		if !ok {
+			return nil, nil, false
+		}
+		name := decoded.(string)
+		if previous, ok := keys[name]; ok {
+			a.report(a.span(key, key), severityWarning, fmt.Sprintf("duplicate key \"%s\", first defined on line %d", key.Value, a.position(previous.start).Line+1))
+		} else {
+			keys[name] = key
+		}
+		if sep := a.current(); sep.Type != token.NAME_SEPARATOR {
+			a.errorf(sep, "expected ':' after key, got %s", describe(sep))
+			return nil, nil, false
+		}
+		a.advance()
+
+		first := a.current()
+		value, children, ok := a.parseValue()
+		if !ok {
+			return nil, nil, false
+		}
+		last := a.tokens[a.pos-1]
+		obj.Set(name, value)
+		symbol := newSymbol(name, first, value, children)
+		symbol.Range, symbol.SelectionRange = a.span(key, last), a.span(key, key)
+		symbols = append(symbols, symbol)
+
+		switch sep := a.advance(); {
+		case sep.Type == token.END_OBJECT:
+			return obj, symbols, true
+		case sep.Type != token.VALUE_SEPARATOR:
+			a.errorf(sep, "expected ',' or '}', got %s", describe(sep))
+			return nil, nil, false
+		case a.current().Type == token.END_OBJECT:
+			a.errorf(sep, "trailing comma before '}'")
+			return nil, nil, false
+		}
+	}
+}
+
+// parseArray parses an array, the current token being its opening bracket.
+func (a *analyzer) parseArray() (interface{}, []documentSymbol, bool) {
+	a.advance()
+	arr := parser.JsonArray{}
+	var symbols []documentSymbol
+	if a.current().Type == token.END_ARRAY {
+		a.advance()
+		return arr, symbols, true
+	}
+
+	for {
+		first := a.current()
+		value, children, ok := a.parseValue()
+		if !ok {
+			return nil, nil, false
+		}
+		last := a.tokens[a.pos-1]
+		symbol := newSymbol(strconv.Itoa(len(arr)), first, value, children)
+		symbol.Range = a.span(first, last)
+		symbol.SelectionRange = symbol.Range
+		symbols = append(symbols, symbol)
+		arr = append(arr, value)
+
+		switch sep := a.advance(); {
+		case sep.Type == token.END_ARRAY:
+			return arr, symbols, true
+		case sep.Type != token.VALUE_SEPARATOR:
+			a.errorf(sep, "expected ',' or ']', got %s", describe(sep))
+			return nil, nil, false
+		case a.current().Type == token.END_ARRAY:
+			a.errorf(sep, "trailing comma before ']'")
+			return nil, nil, false
+		}
+	}
+}
+
+// newSymbol returns the symbol named name of value, whose first token is
+// first.
+func newSymbol(name string, first tok, value interface{}, children []documentSymbol) documentSymbol {
+	s := documentSymbol{Name: name, Children: children}
+	switch value.(type) {
+	case *parser.OrderedObject:
+		s.Kind = symbolObject
+	case parser.JsonArray:
+		s.Kind = symbolArray
+		s.Detail = fmt.Sprintf("%d elements", len(children))
+	case string:
+		s.Kind, s.Detail = symbolString, `"`+first.Value+`"`
+	case bool:
+		s.Kind, s.Detail = symbolBoolean, first.Value
+	case nil:
+		s.Kind, s.Detail = symbolNull, "null"
+	default:
+		s.Kind, s.Detail = symbolNumber, first.Value
+	}
+	return s
+}
+
+// isLetter reports whether c starts the words the lexer reads as literals.
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
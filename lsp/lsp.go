@@ -0,0 +1,357 @@
+// Package lsp implements a Language Server Protocol server for JSON
+// documents, speaking JSON-RPC over a pair of streams such as the standard
+// input and output of an editor's child process. It publishes diagnostics for
+// syntax errors and duplicate keys, formats documents with the linter, and
+// lists their members and elements as document symbols.
+package lsp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// errExitBeforeShutdown is returned by Serve when the client asks the server
+// to exit without shutting it down first.
+var errExitBeforeShutdown = errors.New("lsp: exit notification before shutdown")
+
+// Server is a language server handling the JSON documents an editor opens.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*analysis // open documents, by URI
+	shutdown bool                 // a shutdown request was received
+	err      error                // first failure to write a notification
+}
+
+// NewServer returns a server reading messages from r and writing its
+// responses and notifications to w.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{in: bufio.NewReader(r), out: w, docs: map[string]*analysis{}}
+}
+
+// Serve handles messages until the client sends the exit notification or
+// closes the input. It returns an error if the input cannot be read, the
+// output cannot be written, or the client exits without a shutdown request.
+func (s *Server) Serve() error {
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		p := parser.NewParser(lexer.NewLexer(body))
+		msg := p.ParseOrdered()
+		if len(p.Errors()) > 0 {
+			if err := s.send(errorResponse{JSONRPC: "2.0", Error: responseError{Code: codeParseError, Message: parser.ErrorList(p.Errors()).Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		method, _ := msg.Get("method")
+		if method == "exit" {
+			if !s.shutdown {
+				return errExitBeforeShutdown
+			}
+			return nil
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+		if s.err != nil {
+			return s.err
+		}
+	}
+}
+
+// read returns the body of the next message, whose headers give its length.
+func (s *Server) read() (string, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return "", io.EOF
+		}
+		return "", fmt.Errorf("lsp: reading message headers: %v", err)
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("lsp: invalid Content-Length %q", headers.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return "", fmt.Errorf("lsp: reading message body: %v", err)
+	}
+	return string(body), nil
+}
+
+// send writes a message, preceded by its length.
+func (s *Server) send(msg interface{}) error {
+	body, err := gojson.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("lsp: encoding message: %v", err)
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		return fmt.Errorf("lsp: writing message: %v", err)
+	}
+	return nil
+}
+
+// response is the successful result of a request.
+type response struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Result  interface{} `json:"result"`
+}
+
+// errorResponse is the failure of a request.
+type errorResponse struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      interface{}   `json:"id"`
+	Error   responseError `json:"error"`
+}
+
+// responseError describes why a request failed.
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// notification is a message that expects no response.
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// handle handles a request or notification, responding to requests.
+func (s *Server) handle(msg *parser.OrderedObject) error {
+	id, isRequest := msg.Get("id")
+	if text, ok := id.(string); ok {
+		id = text
+	}
+	method, ok := msg.Get("method")
+	if _, isString := method.(string); !ok || !isString {
+		return s.send(errorResponse{JSONRPC: "2.0", ID: id, Error: responseError{Code: codeInvalidRequest, Message: "missing method"}})
+	}
+	params, _ := msg.Get("params")
+	result, err := s.dispatch(method.(string), params)
+
+	switch {
+	case !isRequest:
+		return nil
+	case err != nil:
+		var rpcErr *responseError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.send(errorResponse{JSONRPC: "2.0", ID: id, Error: *rpcErr})
+	}
+	return s.send(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// dispatch runs method and returns its result, or the error to respond with.
+func (s *Server) dispatch(method string, params interface{}) (interface{}, error) {
+	switch method {
+	case "initialize":
+		var result initializeResult
+		result.Capabilities.TextDocumentSync = 1 // the whole text is sent on changes
+		result.Capabilities.DocumentFormattingProvider = true
+		result.Capabilities.DocumentSymbolProvider = true
+		result.ServerInfo.Name = "gojson"
+		return result, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		uri, err := documentURI(params)
+		if err != nil {
+			return nil, err
+		}
+		text, ok := member(member(params, "textDocument"), "text").(string)
+		if !ok {
+			return nil, fmt.Errorf("missing textDocument text")
+		}
+		s.update(uri, text)
+		return nil, nil
+	case "textDocument/didChange":
+		uri, err := documentURI(params)
+		if err != nil {
+			return nil, err
+		}
+		changes, _ := member(params, "contentChanges").(parser.JsonArray)
+		if len(changes) == 0 {
+			return nil, nil
+		}
+		text, ok := member(changes[len(changes)-1], "text").(string)
+		if !ok {
+			return nil, fmt.Errorf("missing contentChanges text")
+		}
+		s.update(uri, text)
+		return nil, nil
+	case "textDocument/didClose":
+		uri, err := documentURI(params)
+		if err != nil {
+			return nil, err
+		}
+		delete(s.docs, uri)
+		s.publish(uri, nil)
+		return nil, nil
+	case "textDocument/formatting":
+		doc, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		return formatEdits(doc, formattingOptions(member(params, "options"))), nil
+	case "textDocument/documentSymbol":
+		doc, err := s.document(params)
+		if err != nil {
+			return nil, err
+		}
+		if doc.symbols == nil {
+			return []documentSymbol{}, nil
+		}
+		return doc.symbols, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+// update analyzes the new text of a document and publishes its diagnostics.
+func (s *Server) update(uri, text string) {
+	doc := analyze(text)
+	s.docs[uri] = doc
+	s.publish(uri, doc.diagnostics)
+}
+
+// publish sends the diagnostics of a document, recording the failure to do
+// so for Serve to return.
+func (s *Server) publish(uri string, diagnostics []diagnostic) {
+	if diagnostics == nil {
+		diagnostics = []diagnostic{}
+	}
+	err := s.send(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// initializeResult describes the capabilities of the server.
+type initializeResult struct {
+	Capabilities struct {
+		TextDocumentSync           int  `json:"textDocumentSync"`
+		DocumentFormattingProvider bool `json:"documentFormattingProvider"`
+		DocumentSymbolProvider     bool `json:"documentSymbolProvider"`
+	} `json:"capabilities"`
+	ServerInfo struct {
+		Name string `json:"name"`
+	} `json:"serverInfo"`
+}
+
+// publishDiagnosticsParams holds the diagnostics of a document.
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// document returns the open document designated by params.
+func (s *Server) document(params interface{}) (*analysis, error) {
+	uri, err := documentURI(params)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, fmt.Errorf("document %s is not open", uri)
+	}
+	return doc, nil
+}
+
+// textEdit replaces a range of a document.
+type textEdit struct {
+	Range   textRange `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+// formatEdits returns the edits formatting doc, none if it is formatted or
+// invalid.
+func formatEdits(doc *analysis, opts linter.Options) []textEdit {
+	if !doc.valid {
+		return []textEdit{}
+	}
+	formatted := linter.Format(doc.value, opts) + "\n"
+	if formatted == doc.text {
+		return []textEdit{}
+	}
+	end := (&analyzer{analysis: doc, lines: lineStarts(doc.text)}).position(len(doc.text))
+	return []textEdit{{Range: textRange{End: end}, NewText: formatted}}
+}
+
+// formattingOptions returns the layout requested by the options of a
+// formatting request.
+func formattingOptions(options interface{}) linter.Options {
+	opts := linter.DefaultOptions()
+	if spaces, ok := member(options, "insertSpaces").(bool); ok && !spaces {
+		opts.Indent = "\t"
+	} else if size, ok := member(options, "tabSize").(int64); ok && size > 0 {
+		opts.Indent = strings.Repeat(" ", int(size))
+	}
+	return opts
+}
+
+// documentURI returns the URI of the document designated by params.
+func documentURI(params interface{}) (string, error) {
+	uri, ok := member(member(params, "textDocument"), "uri").(string)
+	if !ok {
+		return "", fmt.Errorf("missing textDocument uri")
+	}
+	return uri, nil
+}
+
+// member returns the member key of v, or nil if v is not an object holding
+// it.
+func member(v interface{}, key string) interface{} {
+	if obj, ok := v.(*parser.OrderedObject); ok {
+		value, _ := obj.Get(key)
+		return value
+	}
+	return nil
+}
+
+// lineStarts returns the offsets where the lines of text start.
+func lineStarts(text string) []int {
+	lines := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
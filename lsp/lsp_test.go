@@ -0,0 +1,106 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// serve runs a server on the given messages and returns the messages it
+// wrote, in compact form.
+func serve(t *testing.T, messages ...string) ([]string, error) {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, m := range messages {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	err := NewServer(&in, &out).Serve()
+
+	var result []string
+	for out.Len() > 0 {
+		var length int
+		if _, err := fmt.Fscanf(&out, "Content-Length: %d\r\n\r\n", &length); err != nil {
+			t.Fatalf("reading output: %v", err)
+		}
+		body := string(out.Next(length))
+		p := parser.NewParser(lexer.NewLexer(body))
+		msg := p.ParseOrdered()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("invalid output %s: %v", body, p.Errors())
+		}
+		result = append(result, linter.Format(msg, linter.Options{InlineWidth: 1 << 30}))
+	}
+	return result, err
+}
+
+func TestServe(t *testing.T) {
+	doc := `{\n  \"name\": \"gojson\",\n  \"tags\": [\"json\", 1]\n}\n`
+	got, err := serve(t,
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"capabilities": {}}}`,
+		`{"jsonrpc": "2.0", "method": "initialized", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///a.json", "languageId": "json", "version": 1, "text": "`+doc+`"}}}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "textDocument/documentSymbol", "params": {"textDocument": {"uri": "file:///a.json"}}}`,
+		`{"jsonrpc": "2.0", "id": "f", "method": "textDocument/formatting", "params": {"textDocument": {"uri": "file:///a.json"}, "options": {"tabSize": 4, "insertSpaces": true}}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "textDocument/hover", "params": {}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "shutdown"}`,
+		`{"jsonrpc": "2.0", "method": "exit"}`,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		`{"jsonrpc": "2.0", "id": 1, "result": {"capabilities": {"textDocumentSync": 1, "documentFormattingProvider": true, "documentSymbolProvider": true}, "serverInfo": {"name": "gojson"}}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/publishDiagnostics", "params": {"uri": "file:///a.json", "diagnostics": []}}`,
+		`{"jsonrpc": "2.0", "id": 2, "result": [` +
+			`{"name": "name", "detail": "\"gojson\"", "kind": 15, "range": {"start": {"line": 1, "character": 2}, "end": {"line": 1, "character": 18}}, "selectionRange": {"start": {"line": 1, "character": 2}, "end": {"line": 1, "character": 8}}}, ` +
+			`{"name": "tags", "detail": "2 elements", "kind": 18, "range": {"start": {"line": 2, "character": 2}, "end": {"line": 2, "character": 21}}, "selectionRange": {"start": {"line": 2, "character": 2}, "end": {"line": 2, "character": 8}}, "children": [` +
+			`{"name": "0", "detail": "\"json\"", "kind": 15, "range": {"start": {"line": 2, "character": 11}, "end": {"line": 2, "character": 17}}, "selectionRange": {"start": {"line": 2, "character": 11}, "end": {"line": 2, "character": 17}}}, ` +
+			`{"name": "1", "detail": "1", "kind": 16, "range": {"start": {"line": 2, "character": 19}, "end": {"line": 2, "character": 20}}, "selectionRange": {"start": {"line": 2, "character": 19}, "end": {"line": 2, "character": 20}}}]}]}`,
+		`{"jsonrpc": "2.0", "id": "f", "result": [{"range": {"start": {"line": 0, "character": 0}, "end": {"line": 4, "character": 0}}, "newText": "{\n    \"name\": \"gojson\",\n    \"tags\": [\n        \"json\",\n        1\n    ]\n}\n"}]}`,
+		`{"jsonrpc": "2.0", "id": 3, "error": {"code": -32601, "message": "method not found: textDocument/hover"}}`,
+		`{"jsonrpc": "2.0", "id": 4, "result": null}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{`{"a": 1, "b": 2, "a": 3}`, `[{"range": {"start": {"line": 0, "character": 17}, "end": {"line": 0, "character": 20}}, "severity": 2, "source": "gojson", "message": "duplicate key \"a\", first defined on line 1"}]`},
+		{"{\n  \"a\": 1\n  \"b\": 2\n}", `[{"range": {"start": {"line": 2, "character": 2}, "end": {"line": 2, "character": 5}}, "severity": 1, "source": "gojson", "message": "expected ',' or '}', got '\"b\"'"}]`},
+		{`["été", tru]`, `[{"range": {"start": {"line": 0, "character": 8}, "end": {"line": 0, "character": 11}}, "severity": 1, "source": "gojson", "message": "unexpected 'tru'"}]`},
+		{"[1,\n 2,]", `[{"range": {"start": {"line": 1, "character": 2}, "end": {"line": 1, "character": 3}}, "severity": 1, "source": "gojson", "message": "trailing comma before ']'"}]`},
+		{`{"a": 1, "\u0061": 2}`, `[{"range": {"start": {"line": 0, "character": 9}, "end": {"line": 0, "character": 17}}, "severity": 2, "source": "gojson", "message": "duplicate key \"\\u0061\", first defined on line 1"}]`},
+		{`["a\"b", "\q"]`, `[{"range": {"start": {"line": 0, "character": 9}, "end": {"line": 0, "character": 13}}, "severity": 1, "source": "gojson", "message": "invalid escape sequence in '\"\\q\"'"}]`},
+		{`{"a": "open`, `[{"range": {"start": {"line": 0, "character": 6}, "end": {"line": 0, "character": 11}}, "severity": 1, "source": "gojson", "message": "unterminated string"}]`},
+		{`{} 1`, `[{"range": {"start": {"line": 0, "character": 3}, "end": {"line": 0, "character": 4}}, "severity": 1, "source": "gojson", "message": "unexpected '1' after the end of the document"}]`},
+		{``, `[{"range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 0}}, "severity": 1, "source": "gojson", "message": "unexpected end of input"}]`},
+	}
+
+	for _, test := range tests {
+		text := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(test.text)
+		got, err := serve(t, `{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "x", "text": "`+text+`"}}}`)
+		if err != nil || len(got) != 1 {
+			t.Fatalf("%q: got %v, %v", test.text, got, err)
+		}
+		if expected := `{"jsonrpc": "2.0", "method": "textDocument/publishDiagnostics", "params": {"uri": "x", "diagnostics": ` + test.expected + `}}`; got[0] != expected {
+			t.Errorf("%q: got\n%s\nwant\n%s", test.text, got[0], expected)
+		}
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	if _, err := serve(t, `{"jsonrpc": "2.0", "method": "exit"}`); err == nil {
+		t.Errorf("expected an error")
+	}
+}
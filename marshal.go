@@ -0,0 +1,168 @@
+package gojson
+
+import (
+	"reflect"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Marshal returns the compact JSON encoding of v.
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encodeState{escapeHTML: true}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// MarshalIndent is like Marshal but applies the given prefix and indentation
+// to format the output, starting every element of objects and arrays on a new
+// line.
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return MarshalWithOptions(v, linter.Options{Prefix: prefix, Indent: indent})
+}
+
+// MarshalWithOptions encodes v and lays it out with the same formatter, and
+// the same options, as the linter uses for parsed documents. The objects and
+// arrays returned by MarshalJSON methods are laid out as well.
+func MarshalWithOptions(v interface{}, opts linter.Options) ([]byte, error) {
+	e := &encodeState{escapeHTML: true}
+	node, err := e.node(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(opts.Print(node)), nil
+}
+
+// node converts v into a layout node. Scalars are encoded exactly as encode
+// writes them.
+func (e *encodeState) node(v reflect.Value) (*linter.Node, error) {
+	if _, ok := marshalerValue(v); ok {
+		return e.marshalerNode(v)
+	}
+
+	if v.IsValid() && v.Type() == orderedObjectType && !v.IsNil() {
+		obj := v.Interface().(*parser.OrderedObject)
+		node := linter.NewObjectNode()
+		for _, k := range obj.Keys {
+			child, err := e.node(reflect.ValueOf(obj.Values[k]))
+			if err != nil {
+				return nil, err
+			}
+			node.AddMember(e.scalarText(func() { e.encodeString(k) }), child)
+		}
+		return node, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if !v.IsNil() {
+			if v.Kind() == reflect.Pointer {
+				if err := e.enter(v); err != nil {
+					return nil, err
+				}
+				defer e.leave(v)
+			}
+			return e.node(v.Elem())
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			if err := e.enter(v); err != nil {
+				return nil, err
+			}
+			defer e.leave(v)
+			keys, err := sortedMapKeys(v)
+			if err != nil {
+				return nil, err
+			}
+			node := linter.NewObjectNode()
+			for _, k := range keys {
+				child, err := e.node(v.MapIndex(k.value))
+				if err != nil {
+					return nil, err
+				}
+				node.AddMember(e.scalarText(func() { e.encodeString(k.name) }), child)
+			}
+			return node, nil
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Array || !v.IsNil() && v.Type().Elem().Kind() != reflect.Uint8 {
+			if v.Kind() == reflect.Slice {
+				if err := e.enter(v); err != nil {
+					return nil, err
+				}
+				defer e.leave(v)
+			}
+			node := linter.NewArrayNode()
+			for i := 0; i < v.Len(); i++ {
+				child, err := e.node(v.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				node.AddElement(child)
+			}
+			return node, nil
+		}
+	case reflect.Struct:
+		node := linter.NewObjectNode()
+		for _, f := range cachedTypeFields(v.Type()) {
+			fv, ok := fieldValue(v, f)
+			if !ok || f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			var child *linter.Node
+			var err error
+			if f.asString {
+				child = linter.NewScalarNode(e.scalarText(func() { err = e.encodeField(fv, f) }))
+			} else {
+				child, err = e.node(fv)
+			}
+			if err != nil {
+				return nil, err
+			}
+			node.AddMember(e.scalarText(func() { e.encodeString(f.name) }), child)
+		}
+		return node, nil
+	}
+
+	// Everything else is a scalar, or a nil or erroneous value that encode
+	// already knows how to handle.
+	return e.scalarNode(v)
+}
+
+// marshalerNode encodes v, whose type has a MarshalJSON or MarshalText
+// method, and converts the objects and arrays it returns into nodes, keeping
+// their key order and the text of their numbers, so that they are laid out
+// like the rest of the value.
+func (e *encodeState) marshalerNode(v reflect.Value) (*linter.Node, error) {
+	var err error
+	text := e.scalarText(func() { err = e.encode(v) })
+	if err != nil {
+		return nil, err
+	}
+	if text == "" || text[0] != '{' && text[0] != '[' {
+		return linter.NewScalarNode(text), nil
+	}
+	p := parser.NewParser(lexer.NewLexer(text))
+	p.UseNumber(true)
+	return e.node(reflect.ValueOf(p.ParseOrderedValue())) // valid, as encode checked it
+}
+
+// scalarNode encodes v on a single line and wraps the result in a node.
+func (e *encodeState) scalarNode(v reflect.Value) (*linter.Node, error) {
+	var err error
+	text := e.scalarText(func() { err = e.encode(v) })
+	if err != nil {
+		return nil, err
+	}
+	return linter.NewScalarNode(text), nil
+}
+
+// scalarText runs write against an empty buffer and returns what it wrote.
+func (e *encodeState) scalarText(write func()) string {
+	e.Reset()
+	write()
+	return e.String()
+}
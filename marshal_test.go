@@ -0,0 +1,229 @@
+package gojson
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func TestMarshal(t *testing.T) {
+	type user struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Admin bool     `json:"admin,omitempty"`
+	}
+
+	b, err := Marshal(user{Name: "John", Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := `{"name":"John","tags":["a","b"]}`
+	if string(b) != expected {
+		t.Errorf("marshaled value is not as expected. Got %s, want %s", b, expected)
+	}
+}
+
+func TestMarshalIndent(t *testing.T) {
+	value := map[string]interface{}{
+		"b": []interface{}{1, "two", nil},
+		"a": map[string]int{},
+		"c": struct {
+			ID int64 `json:"id,string"`
+		}{7},
+	}
+
+	b, err := MarshalIndent(value, "", "\t")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := "{\n\t\"a\": {},\n\t\"b\": [\n\t\t1,\n\t\t\"two\",\n\t\tnull\n\t],\n\t\"c\": {\n\t\t\"id\": \"7\"\n\t}\n}"
+	if string(b) != expected {
+		t.Errorf("marshaled value is not as expected. Got %q, want %q", b, expected)
+	}
+}
+
+func TestMarshalWithOptionsMatchesLinter(t *testing.T) {
+	type point struct {
+		Y int `json:"y"`
+		X int `json:"x"`
+	}
+	type shape struct {
+		Name   string  `json:"name"`
+		Points []point `json:"points"`
+	}
+
+	opts := linter.Options{Indent: "  ", SortKeys: true, InlineWidth: 20}
+	value := shape{Name: "line", Points: []point{{1, 2}, {3, 4}}}
+
+	b, err := MarshalWithOptions(value, opts)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	input := `{"points": [{"y": 1, "x": 2}, {"y": 3, "x": 4}], "name": "line"}`
+	linted, err := linter.NewJsonLinterWithOptions(input, opts).Lint()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if string(b) != linted {
+		t.Errorf("marshaled value differs from linted document. Got %q, want %q", b, linted)
+	}
+}
+
+func TestMarshalIndentOrderedObject(t *testing.T) {
+	obj := parser.NewOrderedObject()
+	obj.Set("z", int64(1))
+	obj.Set("a", parser.JsonArray{})
+
+	b, err := MarshalIndent(obj, "", "  ")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := "{\n  \"z\": 1,\n  \"a\": []\n}"
+	if string(b) != expected {
+		t.Errorf("marshaled value is not as expected. Got %q, want %q", b, expected)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	if _, err := MarshalIndent([]interface{}{make(chan int)}, "", "  "); err == nil {
+		t.Errorf("expected an error for an unsupported type")
+	}
+	if _, err := Marshal(map[string]float64{"x": 0}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMarshalParsedRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		`{"a\"b": "c\\d", "tab": "x\ty", "é": "\u00e9\ud83d\ude00", "": ["\/", "\u0000"]}`,
+		`["line\nbreak", {"\u0022": null}]`,
+	} {
+		p := parser.NewParser(lexer.NewLexer(input))
+		doc := p.ParseValue()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%s: unexpected parsing errors: %v", input, p.Errors())
+		}
+		b, err := Marshal(doc)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", input, err)
+		}
+		p = parser.NewParser(lexer.NewLexer(string(b)))
+		if again := p.ParseValue(); len(p.Errors()) != 0 || !reflect.DeepEqual(again, doc) {
+			t.Errorf("%s: marshaled as %s, which parses as %v (%v)", input, b, again, p.Errors())
+		}
+	}
+}
+
+// cyclic is a list whose elements can refer to themselves.
+type cyclic struct {
+	Name  string      `json:"name"`
+	Next  *cyclic     `json:"next"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func TestMarshalCycles(t *testing.T) {
+	viaPointer := &cyclic{Name: "a"}
+	viaPointer.Next = viaPointer
+	viaInterface := &cyclic{Name: "b"}
+	viaInterface.Value = viaInterface
+
+	for _, v := range []*cyclic{viaPointer, viaInterface} {
+		_, err := Marshal(v)
+		var unsupported *UnsupportedValueError
+		if !errors.As(err, &unsupported) || !strings.Contains(unsupported.Error(), "cycle via *gojson.cyclic") {
+			t.Errorf("Marshal(%s): expected a cycle to be reported, got %v", v.Name, err)
+		}
+		if _, err := MarshalIndent(v, "", "  "); !errors.As(err, &unsupported) {
+			t.Errorf("MarshalIndent(%s): expected a cycle to be reported, got %v", v.Name, err)
+		}
+	}
+
+	viaMap := map[string]interface{}{"name": "e"}
+	viaMap["self"] = viaMap
+	viaSlice := []interface{}{"f", nil}
+	viaSlice[1] = viaSlice
+	for _, v := range []interface{}{viaMap, viaSlice} {
+		_, err := Marshal(v)
+		var unsupported *UnsupportedValueError
+		if !errors.As(err, &unsupported) || !strings.Contains(unsupported.Error(), "cycle via") {
+			t.Errorf("Marshal(%T): expected a cycle to be reported, got %v", v, err)
+		}
+		if _, err := MarshalIndent(v, "", "  "); !errors.As(err, &unsupported) {
+			t.Errorf("MarshalIndent(%T): expected a cycle to be reported, got %v", v, err)
+		}
+	}
+
+	// Values shared by several members, however deep, are not cycles.
+	shared := &cyclic{Name: "c"}
+	deep := shared
+	for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+		deep = &cyclic{Name: "d", Next: deep, Value: []interface{}{shared, map[string]*cyclic{"s": shared}}}
+	}
+	if _, err := Marshal(deep); err != nil {
+		t.Errorf("unexpected error for shared values: %v", err)
+	}
+}
+
+// Types embedded by TestMarshalEmbeddedStructs.
+type (
+	Base struct {
+		ID   int    `json:"id"`
+		Kind string `json:"kind"`
+	}
+	Audit struct {
+		Kind    string `json:"kind"` // conflicts with Base.Kind at the same depth
+		Created string `json:"created,omitempty"`
+	}
+	Deep struct {
+		ID    int `json:"id"` // hidden by Base.ID, which is less nested
+		Depth int
+	}
+	Mid struct {
+		Deep
+	}
+	private struct {
+		Secret int `json:"secret"`
+	}
+)
+
+func TestMarshalEmbeddedStructs(t *testing.T) {
+	type record struct {
+		Base
+		*Audit
+		Mid
+		private
+		Named Base `json:"named"`
+		Name  string
+	}
+
+	values := []record{
+		{Base: Base{ID: 1, Kind: "a"}, Audit: &Audit{Kind: "b", Created: "today"}, Mid: Mid{Deep{ID: 2, Depth: 3}}, private: private{4}, Named: Base{ID: 5}, Name: "x"},
+		{Base: Base{ID: 1}}, // the fields of a nil embedded pointer are left out
+	}
+	for i, v := range values {
+		expected, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := Marshal(v)
+		if err != nil || string(b) != string(expected) {
+			t.Errorf("values[%d] - expected %s, got %s, %v", i, expected, b, err)
+		}
+
+		expected, _ = json.MarshalIndent(v, "", "  ")
+		if b, err := MarshalIndent(v, "", "  "); err != nil || string(b) != string(expected) {
+			t.Errorf("values[%d] - expected %s, got %s, %v", i, expected, b, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gojson
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Marshaler is the interface implemented by types that can marshal themselves
+// into valid JSON. It has the same method as encoding/json's Marshaler, so
+// existing implementations work unchanged.
+type Marshaler interface {
+	MarshalJSON() ([]byte, error)
+}
+
+// MarshalerError represents an error from calling a MarshalJSON or MarshalText
+// method, or invalid JSON returned by MarshalJSON.
+type MarshalerError struct {
+	Type       reflect.Type
+	Err        error
+	sourceFunc string
+}
+
+func (e *MarshalerError) Error() string {
+	return "gojson: error calling " + e.sourceFunc + " for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *MarshalerError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// marshalerValue returns the value whose MarshalJSON or MarshalText method
+// should be used to encode v, if any. Methods with pointer receivers are found
+// for addressable values, like encoding/json does.
+func marshalerValue(v reflect.Value) (reflect.Value, bool) {
+	if !v.IsValid() {
+		return v, false
+	}
+	t := v.Type()
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		return v, true
+	}
+	if t.Kind() != reflect.Pointer && v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		if pt.Implements(marshalerType) || pt.Implements(textMarshalerType) {
+			return v.Addr(), true
+		}
+	}
+	return v, false
+}
+
+// encodeMarshaler writes the output of the MarshalJSON or MarshalText method of
+// v. MarshalJSON output is validated with gojson's parser and compacted.
+func (e *encodeState) encodeMarshaler(v reflect.Value) error {
+	if v.Kind() == reflect.Pointer && v.IsNil() || v.Kind() == reflect.Interface && v.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+
+	if m, ok := v.Interface().(Marshaler); ok {
+		b, err := m.MarshalJSON()
+		if err != nil {
+			return &MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalJSON"}
+		}
+		if err := validateJSON(b); err != nil {
+			return &MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalJSON"}
+		}
+		compact(&e.Buffer, b)
+		return nil
+	}
+
+	b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return &MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalText"}
+	}
+	e.encodeString(string(b))
+	return nil
+}
+
+// validateJSON reports whether b holds exactly one valid JSON value.
+func validateJSON(b []byte) error {
+	p := parser.NewParser(lexer.NewLexer(string(b)))
+	p.ParseValue()
+	if len(p.Errors()) > 0 {
+		return fmt.Errorf("invalid JSON %q: %s", b, parser.ErrorList(p.Errors()))
+	}
+	return nil
+}
+
+// compact appends src to dst with all insignificant whitespace removed.
+func compact(dst *bytes.Buffer, src []byte) {
+	inString := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		if inString {
+			dst.WriteByte(c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				dst.WriteByte(src[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '"':
+			inString = true
+		}
+		dst.WriteByte(c)
+	}
+}
@@ -0,0 +1,125 @@
+package gojson
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type celsius float64
+
+func (c celsius) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "value" : ` + strconv.FormatFloat(float64(c), 'f', -1, 64) + `, "unit": "C \"deg\"" }`), nil
+}
+
+type broken struct{}
+
+func (broken) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a": }`), nil
+}
+
+type failing struct{}
+
+func (failing) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("boom")
+}
+
+type level int
+
+func (l *level) MarshalText() ([]byte, error) {
+	return []byte(strings.Repeat("*", int(*l))), nil
+}
+
+func TestEncodeMarshalers(t *testing.T) {
+	type reading struct {
+		Temp  celsius  `json:"temp"`
+		Level level    `json:"level"`
+		Ptr   *celsius `json:"ptr"`
+	}
+
+	b, err := Marshal(&reading{Temp: 21.5, Level: 3})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected := `{"temp":{"value":21.5,"unit":"C \"deg\""},"level":"***","ptr":null}`
+	if string(b) != expected {
+		t.Errorf("marshaled value is not as expected. Got %s, want %s", b, expected)
+	}
+
+	b, err = MarshalIndent([]interface{}{celsius(1)}, "", "  ")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	expected = "[\n  {\n    \"value\": 1,\n    \"unit\": \"C \\\"deg\\\"\"\n  }\n]"
+	if string(b) != expected {
+		t.Errorf("marshaled value is not as expected. Got %q, want %q", b, expected)
+	}
+}
+
+// nested is a Marshaler returning indented JSON holding another Marshaler.
+type nested struct {
+	Inner celsius
+}
+
+func (n nested) MarshalJSON() ([]byte, error) {
+	inner, err := Marshal(n.Inner)
+	if err != nil {
+		return nil, err
+	}
+	return []byte("{\n\t\"z\": 1.50,\n\t\"inner\": " + string(inner) + ",\n\t\"list\": [ ]\n}"), nil
+}
+
+func TestMarshalIndentMarshalers(t *testing.T) {
+	lvl := level(2)
+	value := map[string]interface{}{
+		"n":   nested{Inner: 2},
+		"raw": json.RawMessage(`[1e2, {"b": "\u00e9", "a": true}]`),
+		"lvl": &lvl,
+	}
+	b, err := MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{
+  "lvl": "**",
+  "n": {
+    "z": 1.50,
+    "inner": {
+      "value": 2,
+      "unit": "C \"deg\""
+    },
+    "list": []
+  },
+  "raw": [
+    1e2,
+    {
+      "b": "é",
+      "a": true
+    }
+  ]
+}`
+	if string(b) != expected {
+		t.Errorf("expected %s, got %s", expected, b)
+	}
+}
+
+func TestEncodeMarshalerErrors(t *testing.T) {
+	values := []interface{}{broken{}, []interface{}{failing{}}}
+
+	for i, v := range values {
+		_, err := Marshal(v)
+		var merr *MarshalerError
+		if !errors.As(err, &merr) {
+			t.Errorf("values[%d] - expected a MarshalerError, got %v", i, err)
+		}
+	}
+
+	_, err := Marshal(broken{})
+	if !strings.Contains(err.Error(), "MarshalJSON") || !strings.Contains(err.Error(), "unexpected token '}'") {
+		t.Errorf("error does not explain the invalid output: %v", err)
+	}
+}
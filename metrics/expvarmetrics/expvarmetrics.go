@@ -0,0 +1,118 @@
+// Package expvarmetrics provides counters of the documents parsed and
+// formatted by gojson, to install as their metrics.Recorder, and publishes
+// them as an expvar variable and in the Prometheus text exposition format.
+// Importing it registers nothing: the expvar variable, served at /debug/vars
+// by the http.DefaultServeMux, only exists once Enable is called.
+package expvarmetrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oabrivard/gojson/metrics"
+)
+
+// Default holds the counters installed by Enable.
+var Default = &Counters{}
+
+var publishOnce sync.Once
+
+// Enable installs Default as the recorder, and publishes it as the "gojson"
+// expvar variable, served with the other expvar variables at /debug/vars. It
+// returns Default, which can also be exposed to Prometheus by registering it
+// as an HTTP handler at /metrics.
+func Enable() *Counters {
+	publishOnce.Do(func() {
+		expvar.Publish("gojson", expvar.Func(func() interface{} { return Default.Snapshot() }))
+	})
+	metrics.SetRecorder(Default)
+	return Default
+}
+
+// Counters is a metrics.Recorder summing the measurements it receives. The zero value
+// is ready to use.
+type Counters struct {
+	documentsParsed    atomic.Int64
+	bytesParsed        atomic.Int64
+	parseErrors        atomic.Int64
+	parseNanos         atomic.Int64
+	documentsFormatted atomic.Int64
+	bytesFormatted     atomic.Int64
+	formatNanos        atomic.Int64
+}
+
+// Snapshot is the value of Counters at a point in time.
+type Snapshot struct {
+	DocumentsParsed    int64
+	BytesParsed        int64
+	ParseErrors        int64 // documents parsed with errors
+	ParseTime          time.Duration
+	DocumentsFormatted int64
+	BytesFormatted     int64
+	FormatTime         time.Duration
+}
+
+// Parsed adds a parsed document to the counters.
+func (c *Counters) Parsed(bytes int, d time.Duration, failed bool) {
+	c.documentsParsed.Add(1)
+	c.bytesParsed.Add(int64(bytes))
+	if failed {
+		c.parseErrors.Add(1)
+	}
+	c.parseNanos.Add(int64(d))
+}
+
+// Formatted adds a formatted document to the counters.
+func (c *Counters) Formatted(bytes int, d time.Duration) {
+	c.documentsFormatted.Add(1)
+	c.bytesFormatted.Add(int64(bytes))
+	c.formatNanos.Add(int64(d))
+}
+
+// Snapshot returns the current values of the counters.
+func (c *Counters) Snapshot() Snapshot {
+	return Snapshot{
+		DocumentsParsed:    c.documentsParsed.Load(),
+		BytesParsed:        c.bytesParsed.Load(),
+		ParseErrors:        c.parseErrors.Load(),
+		ParseTime:          time.Duration(c.parseNanos.Load()),
+		DocumentsFormatted: c.documentsFormatted.Load(),
+		BytesFormatted:     c.bytesFormatted.Load(),
+		FormatTime:         time.Duration(c.formatNanos.Load()),
+	}
+}
+
+// WritePrometheus writes the counters to w in the Prometheus text exposition
+// format, durations in seconds.
+func (c *Counters) WritePrometheus(w io.Writer) error {
+	s := c.Snapshot()
+	metrics := []struct {
+		name, help string
+		value      interface{}
+	}{
+		{"gojson_documents_parsed_total", "Documents parsed.", s.DocumentsParsed},
+		{"gojson_parsed_bytes_total", "Bytes of input parsed.", s.BytesParsed},
+		{"gojson_parse_errors_total", "Documents parsed with errors.", s.ParseErrors},
+		{"gojson_parse_seconds_total", "Time spent parsing.", s.ParseTime.Seconds()},
+		{"gojson_documents_formatted_total", "Documents formatted.", s.DocumentsFormatted},
+		{"gojson_formatted_bytes_total", "Bytes of output formatted.", s.BytesFormatted},
+		{"gojson_format_seconds_total", "Time spent formatting.", s.FormatTime.Seconds()},
+	}
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %v\n", m.name, m.help, m.name, m.name, m.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the counters in the Prometheus text exposition format.
+func (c *Counters) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WritePrometheus(w)
+}
@@ -0,0 +1,71 @@
+package expvarmetrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oabrivard/gojson/metrics"
+)
+
+func TestCounters(t *testing.T) {
+	var c Counters
+	c.Parsed(100, 2*time.Millisecond, false)
+	c.Parsed(20, time.Millisecond, true)
+	c.Formatted(150, 500*time.Millisecond)
+
+	expected := Snapshot{
+		DocumentsParsed:    2,
+		BytesParsed:        120,
+		ParseErrors:        1,
+		ParseTime:          3 * time.Millisecond,
+		DocumentsFormatted: 1,
+		BytesFormatted:     150,
+		FormatTime:         500 * time.Millisecond,
+	}
+	if s := c.Snapshot(); s != expected {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE gojson_documents_parsed_total counter\ngojson_documents_parsed_total 2\n",
+		"gojson_parse_errors_total 1\n",
+		"gojson_parse_seconds_total 0.003\n",
+		"gojson_formatted_bytes_total 150\n",
+		"gojson_format_seconds_total 0.5\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected %q in the exposition, got:\n%s", line, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
+
+func TestEnable(t *testing.T) {
+	defer metrics.SetRecorder(nil)
+	if metrics.Active() != nil {
+		t.Fatalf("expected no recorder by default")
+	}
+
+	if Enable() != Default || Enable() != Default || metrics.Active() != metrics.Recorder(Default) {
+		t.Fatalf("expected Enable to install the default counters")
+	}
+	Default.Parsed(10, time.Second, false)
+	var s Snapshot
+	if err := json.Unmarshal([]byte(expvar.Get("gojson").String()), &s); err != nil || s.BytesParsed < 10 {
+		t.Errorf("expected the counters published as an expvar variable, got %+v, %v", s, err)
+	}
+
+	metrics.SetRecorder(nil)
+	if metrics.Active() != nil {
+		t.Errorf("expected no recorder once removed")
+	}
+}
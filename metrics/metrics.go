@@ -0,0 +1,45 @@
+// Package metrics collects statistics on the documents parsed and formatted
+// by gojson, so that operators can monitor its use inside long-running
+// services. Nothing is measured until a Recorder is installed with
+// SetRecorder. The package only defines the hooks, so that the parser and
+// the linter do not depend on net/http; the expvarmetrics package provides
+// counters to install, published with expvar and for Prometheus.
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Recorder receives the measurements of parse and format operations. Its
+// methods are called from the goroutines doing the operations, possibly
+// concurrently, and must be quick.
+type Recorder interface {
+	// Parsed is called for every document parsed, with the number of bytes
+	// of input read, the time taken and whether the document has errors.
+	Parsed(bytes int, d time.Duration, failed bool)
+	// Formatted is called for every document formatted, with the number of
+	// bytes of output and the time taken.
+	Formatted(bytes int, d time.Duration)
+}
+
+// holder holds the installed recorder, atomic.Value requiring values of a
+// single concrete type.
+type holder struct {
+	r Recorder
+}
+
+var installed atomic.Value // of holder
+
+// SetRecorder installs r as the recorder of all operations, replacing the
+// previous one. A nil r stops measuring.
+func SetRecorder(r Recorder) {
+	installed.Store(holder{r})
+}
+
+// Active returns the installed recorder, or nil if there is none. Parse and
+// format operations only measure themselves when it is not nil.
+func Active() Recorder {
+	h, _ := installed.Load().(holder)
+	return h.r
+}
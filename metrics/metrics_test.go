@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+// recorder counts the measurements it receives.
+type recorder struct {
+	parsed, formatted int
+}
+
+func (r *recorder) Parsed(bytes int, d time.Duration, failed bool) { r.parsed++ }
+func (r *recorder) Formatted(bytes int, d time.Duration)           { r.formatted++ }
+
+func TestSetRecorder(t *testing.T) {
+	defer SetRecorder(nil)
+	if Active() != nil {
+		t.Fatalf("expected no recorder by default")
+	}
+
+	r := &recorder{}
+	SetRecorder(r)
+	if Active() != Recorder(r) {
+		t.Fatalf("expected the installed recorder, got %v", Active())
+	}
+
+	SetRecorder(nil)
+	if Active() != nil {
+		t.Errorf("expected no recorder once removed")
+	}
+}
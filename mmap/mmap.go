@@ -0,0 +1,31 @@
+// Package mmap maps files into memory, so that huge documents can be lexed in
+// place with lexer.NewBytesLexer: the operating system pages their content in
+// on demand instead of it being read into the Go heap.
+package mmap
+
+// File is a read-only memory mapping of a whole file.
+type File struct {
+	data  []byte
+	unmap func() error // releases data, nil once released
+}
+
+// Bytes returns the content of the file. It must not be modified, nor used
+// after Close, which includes the strings of values parsed from it in place.
+func (f *File) Bytes() []byte {
+	return f.data
+}
+
+// Len returns the size of the file.
+func (f *File) Len() int {
+	return len(f.data)
+}
+
+// Close releases the mapping.
+func (f *File) Close() error {
+	if f.unmap == nil {
+		return nil
+	}
+	err := f.unmap()
+	f.data, f.unmap = nil, nil
+	return err
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package mmap
+
+import "os"
+
+// Open reads the named file into memory, memory mappings being only
+// supported on Unix systems.
+func Open(name string) (*File, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &File{data: data, unmap: func() error { return nil }}, nil
+}
@@ -0,0 +1,63 @@
+package mmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+func TestOpen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "doc.json")
+	if err := os.WriteFile(name, []byte(`{"name": "John", "tags": ["a", "b"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	p := parser.NewParser(lexer.NewBytesLexer(f.Bytes()))
+	doc := p.ParseValue()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	if obj := doc.(parser.JsonObject); obj["name"] != "John" || len(obj["tags"].(parser.JsonArray)) != 2 {
+		t.Errorf("unexpected document %v", doc)
+	}
+	if f.Len() != 36 {
+		t.Errorf("got length %d, want 36", f.Len())
+	}
+
+	if err := f.Close(); err != nil {
+		t.Errorf("unexpected error closing: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("unexpected error closing twice: %v", err)
+	}
+}
+
+func TestOpenEmptyFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(name, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Len() != 0 {
+		t.Errorf("got length %d, want 0", f.Len())
+	}
+	f.Close()
+}
+
+func TestOpenMissingFile(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
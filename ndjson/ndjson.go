@@ -0,0 +1,225 @@
+// Package ndjson reads and writes newline-delimited JSON, also known as JSON
+// Lines: streams holding one JSON value per line, as produced by log shippers
+// and database exports.
+package ndjson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/stream"
+)
+
+// LineError is the failure to decode a line.
+type LineError struct {
+	Line int    // number of the line, starting at 1
+	Text string // content of the line, without its end of line
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("ndjson: line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Options controls how a Reader decodes lines.
+type Options struct {
+	// OnError is called for each line that cannot be decoded. When it returns
+	// nil the line is skipped, otherwise reading stops with the error it
+	// returns. When OnError is nil, reading stops with the LineError of the
+	// first bad line.
+	OnError func(err *LineError) error
+
+	// Workers, when above 1, is the number of goroutines decoding chunks of
+	// lines in parallel. Values are still returned in order, and OnError is
+	// still called in order by Next.
+	Workers int
+
+	// PreserveOrder makes objects *parser.OrderedObject values keeping their
+	// keys in the order of the line, instead of parser.JsonObject values.
+	PreserveOrder bool
+}
+
+// Skip is an OnError handler skipping every bad line.
+func Skip(*LineError) error {
+	return nil
+}
+
+// Reader decodes the values of a newline-delimited JSON stream one line at a
+// time. Blank lines are ignored.
+type Reader struct {
+	in       *bufio.Reader
+	opts     Options
+	read     int       // number of lines read from in
+	line     int       // number of the line of the last value returned
+	err      error     // the error that stopped reading, if any
+	parallel *pipeline // the workers decoding lines, when decoding in parallel
+}
+
+// NewReader returns a Reader of the values held by r, stopping at the first
+// bad line.
+func NewReader(r io.Reader) *Reader {
+	return NewReaderWithOptions(r, Options{})
+}
+
+// NewReaderWithOptions returns a Reader of the values held by r decoding
+// lines according to opts. A reader decoding in parallel starts reading r
+// ahead of calls to Next, and must be closed if it is abandoned before Next
+// returns an error.
+func NewReaderWithOptions(r io.Reader, opts Options) *Reader {
+	reader := &Reader{in: bufio.NewReader(r), opts: opts}
+	if opts.Workers > 1 {
+		reader.parallel = startPipeline(reader, opts.Workers)
+	}
+	return reader
+}
+
+// Next returns the value of the next line. It returns io.EOF at the end of the
+// stream, after which, like after any other error, every call returns the
+// same error.
+func (r *Reader) Next() (interface{}, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	v, err := r.next()
+	if err != nil {
+		r.err = err
+		if r.parallel != nil {
+			r.parallel.stop()
+		}
+	}
+	return v, err
+}
+
+// Close stops the workers of a reader decoding in parallel; every later call
+// to Next fails. It does nothing for other readers, or once Next has
+// returned an error.
+func (r *Reader) Close() error {
+	if r.parallel != nil && r.err == nil {
+		r.err = errors.New("ndjson: reader closed")
+		r.parallel.stop()
+	}
+	return nil
+}
+
+// record is a decoded line.
+type record struct {
+	value interface{}
+	err   *LineError // the failure to decode the line, if any
+	line  int
+}
+
+// next returns the value of the next line, handling bad lines.
+func (r *Reader) next() (interface{}, error) {
+	for {
+		rec, err := r.nextRecord()
+		if err != nil {
+			return nil, err
+		}
+		r.line = rec.line
+		if rec.err == nil {
+			return rec.value, nil
+		}
+		if r.opts.OnError == nil {
+			return nil, rec.err
+		}
+		if err := r.opts.OnError(rec.err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// nextRecord returns the next non-blank line, decoded.
+func (r *Reader) nextRecord() (record, error) {
+	if r.parallel != nil {
+		return r.parallel.next()
+	}
+	for {
+		text, err := r.readLine()
+		if err != nil {
+			return record{}, err
+		}
+		if rec, ok := decodeLine(r.read, text, r.opts.PreserveOrder); ok {
+			return rec, nil
+		}
+	}
+}
+
+// readLine returns the next line of the input, without its end of line.
+func (r *Reader) readLine() (string, error) {
+	text, err := r.in.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if text == "" && err == io.EOF {
+		return "", io.EOF
+	}
+	r.read++
+	return strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r"), nil
+}
+
+// decodeLine decodes the text of the given line, keeping the order of object
+// keys if ordered is set, and reports false for blank lines.
+func decodeLine(line int, text string, ordered bool) (record, bool) {
+	if strings.TrimSpace(text) == "" {
+		return record{}, false
+	}
+	p := parser.NewParser(lexer.NewLexer(text))
+	p.PreserveOrder(ordered)
+	v := p.ParseValue()
+	if len(p.Errors()) > 0 {
+		err := parser.ErrorList(p.Errors())
+		return record{err: &LineError{Line: line, Text: text, Err: err}, line: line}, true
+	}
+	return record{value: v, line: line}, true
+}
+
+// Line returns the number of the line holding the value last returned by
+// Next, or of the bad line whose error it returned.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Writer encodes parsed values one per line, compacted. Output is buffered,
+// so Flush must be called once all values have been written.
+type Writer struct {
+	out     *bufio.Writer // the buffered destination of the lines
+	line    bytes.Buffer  // the line being encoded
+	encoder *bufio.Writer // the buffered writer of line given to stream writers
+}
+
+// NewWriter returns a Writer of values to w.
+func NewWriter(w io.Writer) *Writer {
+	nw := &Writer{out: bufio.NewWriter(w)}
+	nw.encoder = bufio.NewWriter(&nw.line)
+	return nw
+}
+
+// Encode writes v, a parsed value, followed by a newline. Nothing is written
+// if v cannot be encoded.
+func (w *Writer) Encode(v interface{}) error {
+	w.line.Reset()
+	// The stream writer reuses w.encoder rather than allocating its own buffer.
+	if err := stream.NewWriter(w.encoder).WriteValue(v); err != nil {
+		w.encoder.Reset(&w.line)
+		return err
+	}
+	w.encoder.Flush()
+	w.line.WriteByte('\n')
+	_, err := w.out.Write(w.line.Bytes())
+	return err
+}
+
+// Flush writes any buffered output to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.out.Flush()
+}
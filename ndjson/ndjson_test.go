@@ -0,0 +1,220 @@
+package ndjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+func TestReader(t *testing.T) {
+	input := "{\"a\": 1}\r\n\n  \n[true, null]\n\"x\\ty\"\n3.5"
+	r := NewReader(strings.NewReader(input))
+
+	expected := []struct {
+		value string
+		line  int
+	}{
+		{`{"a":1}`, 1},
+		{`[true,null]`, 4},
+		{`"x\ty"`, 5},
+		{`3.5`, 6},
+	}
+	for _, want := range expected {
+		v, err := r.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := encode(t, v); got != want.value+"\n" {
+			t.Errorf("got %s, want %s", got, want.value)
+		}
+		if r.Line() != want.line {
+			t.Errorf("got line %d, want %d", r.Line(), want.line)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReaderStopsAtBadLine(t *testing.T) {
+	r := NewReader(strings.NewReader("1\n{\"a\": }\n2\n"))
+	r.Next()
+
+	_, err := r.Next()
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) {
+		t.Fatalf("expected a *LineError, got %v", err)
+	}
+	if lineErr.Line != 2 || lineErr.Text != `{"a": }` {
+		t.Errorf("got line %d %q, want line 2", lineErr.Line, lineErr.Text)
+	}
+	if _, again := r.Next(); again != err {
+		t.Errorf("expected the error to be returned again, got %v", again)
+	}
+}
+
+func TestReaderSkipsBadLines(t *testing.T) {
+	var bad []int
+	r := NewReaderWithOptions(strings.NewReader("1\nnope\n2\n[1,\n3\n"), Options{
+		OnError: func(err *LineError) error {
+			bad = append(bad, err.Line)
+			return Skip(err)
+		},
+	})
+
+	var values []interface{}
+	for {
+		v, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		values = append(values, v)
+	}
+	if len(values) != 3 || values[2] != int64(3) {
+		t.Errorf("got values %v, want [1 2 3]", values)
+	}
+	if len(bad) != 2 || bad[0] != 2 || bad[1] != 4 {
+		t.Errorf("got bad lines %v, want [2 4]", bad)
+	}
+}
+
+func TestReaderHandlerError(t *testing.T) {
+	stop := errors.New("too many errors")
+	r := NewReaderWithOptions(strings.NewReader("nope\n1\n"), Options{
+		OnError: func(*LineError) error { return stop },
+	})
+	if _, err := r.Next(); err != stop {
+		t.Errorf("expected the error of the handler, got %v", err)
+	}
+}
+
+func TestReaderPreserveOrder(t *testing.T) {
+	input := strings.Repeat("{\"b\": 1, \"a\": {\"z\": 2, \"y\": 3}}\n", 3)
+	for _, workers := range []int{0, 2} {
+		r := NewReaderWithOptions(strings.NewReader(input), Options{PreserveOrder: true, Workers: workers})
+		for i := 0; i < 3; i++ {
+			v, err := r.Next()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			obj, ok := v.(*parser.OrderedObject)
+			if !ok || fmt.Sprint(obj.Keys) != "[b a]" {
+				t.Fatalf("workers %d: expected an ordered object with keys [b a], got %#v", workers, v)
+			}
+			if inner := obj.Values["a"].(*parser.OrderedObject); fmt.Sprint(inner.Keys) != "[z y]" {
+				t.Errorf("workers %d: expected nested keys [z y], got %v", workers, inner.Keys)
+			}
+		}
+		r.Close()
+	}
+}
+
+func encode(t *testing.T, values ...interface{}) string {
+	t.Helper()
+	var out strings.Builder
+	w := NewWriter(&out)
+	for _, v := range values {
+		if err := w.Encode(v); err != nil {
+			t.Fatalf("Encode(%v): unexpected error: %v", v, err)
+		}
+	}
+	w.Flush()
+	return out.String()
+}
+
+func TestWriter(t *testing.T) {
+	obj := parser.NewOrderedObject()
+	obj.Set("b", int64(1))
+	obj.Set("a", parser.JsonArray{"x", nil})
+	got := encode(t, obj, "s", parser.JsonObject{})
+	if expected := "{\"b\":1,\"a\":[\"x\",null]}\n\"s\"\n{}\n"; got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+func TestWriterSkipsInvalidValues(t *testing.T) {
+	var out strings.Builder
+	w := NewWriter(&out)
+	w.Encode(int64(1))
+	if err := w.Encode(parser.JsonArray{int64(2), struct{}{}}); err == nil {
+		t.Errorf("expected an error for an unsupported value")
+	}
+	w.Encode(int64(3))
+	w.Flush()
+	if out.String() != "1\n3\n" {
+		t.Errorf("got %q, want %q", out.String(), "1\n3\n")
+	}
+}
+
+func TestParallelReader(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 3000; i++ {
+		switch {
+		case i%1000 == 999:
+			input.WriteString("{bad\n")
+		case i%7 == 0:
+			input.WriteString("\n")
+		default:
+			fmt.Fprintf(&input, "{\"n\": %d}\n", i)
+		}
+	}
+
+	var bad []int
+	r := NewReaderWithOptions(strings.NewReader(input.String()), Options{
+		Workers: 4,
+		OnError: func(err *LineError) error {
+			bad = append(bad, err.Line)
+			return nil
+		},
+	})
+	defer r.Close()
+
+	count := 0
+	for {
+		v, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n := v.(parser.JsonObject)["n"].(int64)
+		if int(n) != r.Line()-1 {
+			t.Fatalf("got value %d at line %d, values are out of order", n, r.Line())
+		}
+		count++
+	}
+	if count != 3000-3-429 {
+		t.Errorf("got %d values, want %d", count, 3000-3-429)
+	}
+	if len(bad) != 3 || bad[0] != 1000 || bad[1] != 2000 || bad[2] != 3000 {
+		t.Errorf("got bad lines %v, want [1000 2000 3000]", bad)
+	}
+}
+
+func TestParallelReaderStops(t *testing.T) {
+	input := strings.Repeat("1\n", 10000) + "nope\n" + strings.Repeat("2\n", 10000)
+	r := NewReaderWithOptions(strings.NewReader(input), Options{Workers: 3})
+	var err error
+	for err == nil {
+		_, err = r.Next()
+	}
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 10001 || r.Line() != 10001 {
+		t.Errorf("expected an error at line 10001, got %v", err)
+	}
+
+	r = NewReaderWithOptions(strings.NewReader(input), Options{Workers: 3})
+	r.Next()
+	r.Close()
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("expected an error after Close, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package ndjson
+
+// chunkLines is the number of lines decoded by a worker at a time.
+const chunkLines = 512
+
+// pipeline decodes the lines of a Reader in parallel: a producer reads
+// chunks of lines and hands them to workers, while their results are queued
+// in input order for Next.
+type pipeline struct {
+	results chan chan chunk // the results of the chunks, in input order
+	done    chan struct{}   // closed to stop the producer
+	stopped bool
+	batch   []record // the records of the current chunk not returned yet
+	err     error    // the error following the records of the current chunk
+}
+
+// job is a chunk of lines to decode.
+type job struct {
+	first  int      // number of the first line
+	lines  []string // the lines, without their end of line
+	err    error    // the error that stopped reading after the lines, if any
+	result chan<- chunk
+}
+
+// chunk is a decoded job.
+type chunk struct {
+	records []record
+	err     error
+}
+
+// startPipeline starts decoding the lines of r with the given number of
+// workers. Only the producer reads r.in from then on.
+func startPipeline(r *Reader, workers int) *pipeline {
+	p := &pipeline{results: make(chan chan chunk, 2*workers), done: make(chan struct{})}
+	jobs := make(chan job, workers)
+	for i := 0; i < workers; i++ {
+		go work(jobs, r.opts.PreserveOrder)
+	}
+	go p.produce(r, jobs)
+	return p
+}
+
+// produce reads chunks of lines until the end of the input, an error or
+// stop.
+func (p *pipeline) produce(r *Reader, jobs chan<- job) {
+	defer close(jobs)
+	for {
+		j := job{first: r.read + 1}
+		for len(j.lines) < chunkLines && j.err == nil {
+			text, err := r.readLine()
+			if err != nil {
+				j.err = err
+				break
+			}
+			j.lines = append(j.lines, text)
+		}
+
+		result := make(chan chunk, 1)
+		j.result = result
+		select {
+		case p.results <- result:
+		case <-p.done:
+			return
+		}
+		select {
+		case jobs <- j:
+		case <-p.done:
+			return
+		}
+		if j.err != nil {
+			return
+		}
+	}
+}
+
+// work decodes jobs until there are no more, keeping the order of object
+// keys if ordered is set.
+func work(jobs <-chan job, ordered bool) {
+	for j := range jobs {
+		c := chunk{err: j.err}
+		for i, text := range j.lines {
+			if rec, ok := decodeLine(j.first+i, text, ordered); ok {
+				c.records = append(c.records, rec)
+			}
+		}
+		j.result <- c
+	}
+}
+
+// next returns the next record, in input order.
+func (p *pipeline) next() (record, error) {
+	for len(p.batch) == 0 {
+		if p.err != nil {
+			return record{}, p.err
+		}
+		c := <-<-p.results
+		p.batch, p.err = c.records, c.err
+	}
+	rec := p.batch[0]
+	p.batch = p.batch[1:]
+	return rec, nil
+}
+
+// stop stops the producer, and so the workers once they have decoded the
+// jobs already handed to them.
+func (p *pipeline) stop() {
+	if !p.stopped {
+		p.stopped = true
+		close(p.done)
+	}
+}
@@ -0,0 +1,52 @@
+package parser
+
+// arenaChunk is the number of values of the chunks of an Arena.
+const arenaChunk = 4096
+
+// Arena allocates the arrays of the documents parsed by parsers using it, see
+// Parser.UseArena, in large chunks shared by many arrays instead of one
+// allocation each, and frees them all at once. Workloads that parse many large
+// documents, inspect them briefly and discard them so allocate far fewer
+// objects, and the chunks are reused from one document to the next instead of
+// being left to the garbage collector.
+//
+// The zero value is an empty arena ready to use. An Arena must not be used by
+// several parsers at the same time.
+type Arena struct {
+	chunks  [][]interface{}
+	current int // index of the chunk being filled
+	used    int // number of values of the current chunk in use
+}
+
+// array returns an array of the arena holding a copy of values. Arrays too
+// large to share a chunk with others are allocated on their own.
+func (a *Arena) array(values []interface{}) JsonArray {
+	n := len(values)
+	if n > arenaChunk/4 {
+		return append(make(JsonArray, 0, n), values...)
+	}
+
+	if a.current < len(a.chunks) && a.used+n > arenaChunk {
+		a.current++
+		a.used = 0
+	}
+	if a.current == len(a.chunks) {
+		a.chunks = append(a.chunks, make([]interface{}, arenaChunk))
+	}
+	// the capacity is limited so that appending to the array does not
+	// overwrite the arrays following it
+	array := a.chunks[a.current][a.used : a.used+n : a.used+n]
+	copy(array, values)
+	a.used += n
+	return array
+}
+
+// Free frees all the arrays allocated from the arena, which reuses their
+// memory for the next documents: neither they nor the values holding them
+// must be used afterwards.
+func (a *Arena) Free() {
+	for i := 0; i <= a.current && i < len(a.chunks); i++ {
+		clear(a.chunks[i])
+	}
+	a.current, a.used = 0, 0
+}
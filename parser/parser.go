@@ -4,10 +4,16 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"math/big"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unsafe"
 
 	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/metrics"
 	"github.com/oabrivard/gojson/token"
 )
 
@@ -19,7 +25,56 @@ type Parser struct {
 	curToken  token.Token // current token under examination
 	peekToken token.Token // next token in the input
 
-	errors []string // slice to store errors encountered during parsing
+	errors   []parseError // errors encountered during parsing
+	exported []ParseError // errors as Errors returns them, built when it is called
+
+	maxErrors int // number of distinct errors kept, unlimited if 0
+	recorded  int // number of errors encountered, kept or not
+	formatted int // value of recorded when messages were formatted
+	dropped   int // number of distinct errors beyond maxErrors
+
+	ordered    bool          // build objects as OrderedObject values instead of JsonObject
+	duplicates DuplicateKeys // what to do with the keys an object holds more than once
+	recovering bool          // resume parsing after errors, see RecoverErrors
+	keepOrder  bool          // set ordered for every document, see PreserveOrder
+	numbers    bool          // return numbers as Number values, see UseNumber
+
+	// depth is the number of containers enclosing the current token, and
+	// objectSizes and arraySizes the sizes of the last object and array
+	// parsed at each depth, used to preallocate their siblings, which tend
+	// to have the same shape, as the records of an array do.
+	depth       int
+	objectSizes []int
+	arraySizes  []int
+
+	arena   *Arena        // allocator of arrays, if any
+	scratch []interface{} // elements of the arrays being parsed into arena
+
+	maxMemory int64 // budget of the values of a document in bytes, unlimited if 0
+	memory    int64 // approximate size of the values parsed so far
+	limitErr  error // set once the budget or a limit of the lexer is exceeded, which aborts parsing
+}
+
+// maxSizeHint bounds the capacity containers are preallocated with, so that
+// one large container does not inflate its small siblings.
+const maxSizeHint = 1024
+
+// sizeHint returns the size recorded in sizes for the current depth.
+func (p *Parser) sizeHint(sizes []int) int {
+	if p.depth < len(sizes) {
+		return sizes[p.depth]
+	}
+	return 0
+}
+
+// recordSize records n as the size of the container parsed at the current
+// depth in sizes, and returns sizes.
+func (p *Parser) recordSize(sizes []int, n int) []int {
+	for len(sizes) <= p.depth {
+		sizes = append(sizes, 0)
+	}
+	sizes[p.depth] = min(n, maxSizeHint)
+	return sizes
 }
 
 // NewParser creates and initializes a new Parser with the given lexer.
@@ -31,65 +86,491 @@ func NewParser(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// nextToken advances both curToken and peekToken.
+// Reset makes the parser parse input from its start, as a parser returned by
+// NewParser for lexer.NewLexer(input) would, reusing the memory held by the
+// parser and its lexer, and keeping the arena it uses. The slices returned by
+// Errors before are left as they were.
+func (p *Parser) Reset(input string) {
+	clear(p.objectSizes)
+	clear(p.arraySizes)
+	p.objectSizes = p.objectSizes[:0]
+	p.arraySizes = p.arraySizes[:0]
+	p.reset(input)
+}
+
+// reset is Reset keeping the sizes of the containers parsed before, which
+// suit documents of the same shape as hints.
+func (p *Parser) reset(input string) {
+	if p.lexer == nil {
+		p.lexer = lexer.NewLexer(input)
+	} else {
+		p.lexer.Reset(input)
+	}
+	clear(p.errors)
+	*p = Parser{
+		lexer:       p.lexer,
+		errors:      p.errors[:0],
+		objectSizes: p.objectSizes,
+		arraySizes:  p.arraySizes,
+		arena:       p.arena,
+		scratch:     p.scratch,
+		maxMemory:   p.maxMemory,
+		maxErrors:   p.maxErrors,
+		duplicates:  p.duplicates,
+		recovering:  p.recovering,
+		ordered:     p.keepOrder,
+		keepOrder:   p.keepOrder,
+		numbers:     p.numbers,
+	}
+	p.nextToken()
+	p.nextToken()
+}
+
+// Result is the outcome of parsing one of the documents given to ParseAll.
+type Result struct {
+	Value  interface{}  // the parsed value, nil if the document is invalid
+	Errors []ParseError // the errors found in the document, if any
+}
+
+// ParseAll parses each of docs as ParseValue does and returns their results
+// in order. A single parser is used for all of them, reusing its state from
+// one document to the next and preallocating containers with the sizes found
+// in the previous documents, which suits pipelines parsing many small records
+// of the same shape, such as logs. Documents given as []byte are scanned in
+// place, without copying them: as with lexer.NewBytesLexer, they must not be
+// modified as long as the values parsed from them are in use.
+func ParseAll[D string | []byte](docs []D) []Result {
+	results := make([]Result, len(docs))
+	p := &Parser{}
+	for i, doc := range docs {
+		var input string
+		switch d := any(doc).(type) {
+		case string:
+			input = d
+		case []byte:
+			input = unsafe.String(unsafe.SliceData(d), len(d))
+		}
+		p.reset(input)
+		results[i].Value = p.ParseValue()
+		results[i].Errors = p.Errors()
+	}
+	return results
+}
+
+// Pool is a set of parsers that can be reused across documents, so that
+// request-handling services do not allocate fresh parser and lexer state for
+// every request. The zero value is ready to use, and a Pool is safe for
+// concurrent use.
+//
+//	var parsers parser.Pool
+//
+//	func handle(body string) (interface{}, error) {
+//		p := parsers.Get(body)
+//		defer parsers.Put(p)
+//		v := p.ParseValue()
+//		if errs := p.Errors(); len(errs) > 0 {
+//			return nil, errs[0]
+//		}
+//		return v, nil
+//	}
+//
+// The values parsed remain valid after the parser is put back.
+type Pool struct {
+	parsers sync.Pool
+}
+
+// Get returns a parser of the pool, or a new one if it is empty, ready to
+// parse input.
+func (pool *Pool) Get(input string) *Parser {
+	if p, ok := pool.parsers.Get().(*Parser); ok {
+		p.Reset(input)
+		return p
+	}
+	return NewParser(lexer.NewLexer(input))
+}
+
+// Put returns p to the pool. It must not be used afterwards.
+func (pool *Pool) Put(p *Parser) {
+	p.Reset("") // drop the references to the last input
+	p.arena = nil
+	p.maxMemory = 0
+	p.maxErrors = 0
+	p.duplicates = LastKeyWins
+	p.recovering = false
+	p.ordered, p.keepOrder = false, false
+	p.numbers = false
+	pool.parsers.Put(p)
+}
+
+// UseArena makes the parser allocate the arrays of the documents it parses
+// from arena, or from the heap as by default if arena is nil. They are only
+// valid until arena is freed.
+func (p *Parser) UseArena(arena *Arena) {
+	p.arena = arena
+}
+
+// PreserveOrder makes the parser build every object of the documents it
+// parses as an OrderedObject remembering the order of its keys, as
+// ParseOrdered does, when on is true, including after Reset: ParseValue then
+// returns documents that the linter formats with their keys in source order,
+// as needed to round-trip configuration files. Parse still returns a
+// JsonObject, the objects nested in it being ordered.
+func (p *Parser) PreserveOrder(on bool) {
+	p.ordered, p.keepOrder = on, on
+}
+
+// UseNumber makes the parser return numbers as Number values holding their
+// text when on is true, including after Reset, instead of int64 and float64
+// values, so that integers beyond the range of int64 and decimals with more
+// digits than a float64 holds are kept exactly, as financial and crypto
+// payloads need.
+func (p *Parser) UseNumber(on bool) {
+	p.numbers = on
+}
+
+// DuplicateKeys tells a parser what to do with the keys an object holds more
+// than once, such as "a" in {"a": 1, "a": 2}.
+type DuplicateKeys int
+
+const (
+	LastKeyWins       DuplicateKeys = iota // the last value of the key is kept, as by default
+	FirstKeyWins                           // the first value of the key is kept
+	RejectDuplicates                       // each repeated key is reported as an error naming it
+	CollectDuplicates                      // the values of the key are kept, in order, in a JsonArray
+)
+
+// SetDuplicateKeys sets what the parser does with the keys an object holds
+// more than once. With RejectDuplicates, parsing goes on after a repeated key
+// so that all of them are reported, as `duplicate key "a" at line 3, column
+// 7`, the position being the one of the repetition, and ParseValue returns
+// nil. With CollectDuplicates, only the values of repeated keys are gathered
+// in a JsonArray, which callers cannot tell from an array value.
+func (p *Parser) SetDuplicateKeys(policy DuplicateKeys) {
+	p.duplicates = policy
+}
+
+// RecoverErrors makes the parser resume parsing after the errors it finds in
+// a container when on is true, so that a single run reports all the errors
+// of a document: the tokens following an error are skipped up to the next
+// comma or the end of the container at its level, and parsing goes on from
+// there. ParseValue still returns nil for documents with errors. Exceeding
+// the memory budget aborts parsing all the same.
+func (p *Parser) RecoverErrors(on bool) {
+	p.recovering = on
+}
+
+// SetMaxMemory sets the budget, in bytes, of the values of the documents the
+// parser parses. Parsing is aborted as soon as the approximate size of the
+// values parsed so far exceeds it, Err then returning a *MemoryLimitError,
+// which protects services from inputs built to expand into huge trees. A
+// budget of 0, the default, is unlimited.
+func (p *Parser) SetMaxMemory(bytes int64) {
+	p.maxMemory = bytes
+}
+
+// MemoryLimitError is the error of a parser whose budget, set with
+// SetMaxMemory, is exceeded by a document.
+type MemoryLimitError struct {
+	Limit        int64 // the budget in bytes
+	Line, Column int   // position of the value exceeding it
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("document exceeds the memory budget of %d bytes at line %d, column %d", e.Limit, e.Line, e.Column)
+}
+
+// Err returns a *MemoryLimitError if parsing was aborted because the budget
+// of the parser was exceeded, a *lexer.LimitError if it was because the input
+// exceeds a limit set with the SetLimits method of its lexer, and nil
+// otherwise. Syntax errors are reported by Errors, which also lists the
+// message of this error.
+func (p *Parser) Err() error {
+	return p.limitErr
+}
+
+// Approximate sizes in bytes of parsed values, counted against the budget
+// set with SetMaxMemory.
+const (
+	valueSize     = 16 // an interface value, the size of the string or number it holds excluded
+	numberSize    = 8  // the int64 or float64 held by an interface value
+	containerSize = 48 // a map or a slice, and the allocation holding its contents
+	memberSize    = 32 // the key and the slot of an object member, the bytes of the key excluded
+)
+
+// charge counts n bytes against the budget of the parser, and reports whether
+// parsing can go on.
+func (p *Parser) charge(n int) bool {
+	if p.maxMemory <= 0 {
+		return true
+	}
+	if p.limitErr == nil {
+		p.memory += int64(n)
+		if p.memory <= p.maxMemory {
+			return true
+		}
+		limitErr := &MemoryLimitError{Limit: p.maxMemory, Line: p.curToken.Line, Column: p.curToken.Column}
+		p.record(parseError{code: MemoryLimit, tok: p.curToken, err: limitErr})
+		p.limitErr = limitErr
+	}
+	return false
+}
+
+// nextToken advances both curToken and peekToken. The EOF token of a lexer
+// whose limits are exceeded aborts parsing.
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.lexer.NextToken()
+	if p.peekToken.Type == token.EOF && p.limitErr == nil {
+		if err, ok := p.lexer.Err().(*lexer.LimitError); ok {
+			p.record(parseError{code: LimitExceeded, tok: token.Token{Line: err.Line, Column: err.Column, Offset: p.peekToken.Offset}, err: err})
+			p.limitErr = err
+		}
+	}
 }
 
 // JsonObject and JsonArray are types to represent JSON objects and arrays, respectively.
 type JsonObject map[string]interface{}
 type JsonArray []interface{}
 
+// OrderedObject represents a JSON object that remembers the order in which its
+// keys appeared in the input.
+type OrderedObject struct {
+	Keys   []string   // keys in the order they were first seen
+	Values JsonObject // values indexed by key
+}
+
+// NewOrderedObject creates an empty OrderedObject.
+func NewOrderedObject() *OrderedObject {
+	return &OrderedObject{Values: make(JsonObject)}
+}
+
+// Get returns the value stored for key and whether it was present.
+func (o *OrderedObject) Get(key string) (interface{}, bool) {
+	v, ok := o.Values[key]
+	return v, ok
+}
+
+// Set stores value for key. A new key is appended after the existing ones,
+// while an existing key keeps its position.
+func (o *OrderedObject) Set(key string, value interface{}) {
+	if _, ok := o.Values[key]; !ok {
+		o.Keys = append(o.Keys, key)
+	}
+	o.Values[key] = value
+}
+
+// Delete removes key and its value from the object, if present.
+func (o *OrderedObject) Delete(key string) {
+	if _, ok := o.Values[key]; !ok {
+		return
+	}
+	delete(o.Values, key)
+	for i, k := range o.Keys {
+		if k == key {
+			o.Keys = append(o.Keys[:i], o.Keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Len returns the number of keys in the object.
+func (o *OrderedObject) Len() int {
+	return len(o.Keys)
+}
+
+// Members returns the keys, in document order, and the members of a parsed
+// object, either an *OrderedObject or a JsonObject, whose keys carry no order
+// and are returned sorted. It reports false if v is not an object.
+func Members(v interface{}) (keys []string, values map[string]interface{}, ok bool) {
+	switch o := v.(type) {
+	case *OrderedObject:
+		return o.Keys, o.Values, true
+	case JsonObject:
+		keys := make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys, o, true
+	}
+	return nil, nil, false
+}
+
+// TypeName returns the name of the JSON type of a parsed value: "object",
+// "array", "string", "number", "boolean" or "null". Values of other Go types
+// are named after their type.
+func TypeName(v interface{}) string {
+	switch v.(type) {
+	case JsonObject, *OrderedObject:
+		return "object"
+	case JsonArray:
+		return "array"
+	case string:
+		return "string"
+	case int64, float64, Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
 // Parse starts the parsing process and returns the top-level JSON object.
 func (p *Parser) Parse() JsonObject {
+	if r := metrics.Active(); r != nil {
+		defer p.report(r, time.Now())
+	}
 	return p.parseObject()
 }
 
+// ParseValue parses a document made of any single JSON value, not only an
+// object, and reports an error if anything but whitespace follows it.
+func (p *Parser) ParseValue() interface{} {
+	if r := metrics.Active(); r != nil {
+		defer p.report(r, time.Now())
+	}
+	value, err := p.parseValue()
+	if err != nil || len(p.errors) > 0 {
+		return nil
+	}
+
+	if !p.peekTokenIs(token.EOF) {
+		p.addError(TrailingToken, p.peekToken)
+		return nil
+	}
+	return value
+}
+
+// ParseOrdered parses the top-level JSON object like Parse, but every object in
+// the document, nested ones included, is returned as an OrderedObject so that
+// callers can reproduce the source key order.
+func (p *Parser) ParseOrdered() *OrderedObject {
+	if r := metrics.Active(); r != nil {
+		defer p.report(r, time.Now())
+	}
+	p.ordered = true
+	return p.parseOrderedObject()
+}
+
+// ParseOrderedValue parses a document made of any single JSON value like
+// ParseValue, every object in the document being returned as an
+// OrderedObject as with ParseOrdered.
+func (p *Parser) ParseOrderedValue() interface{} {
+	p.ordered = true
+	return p.ParseValue()
+}
+
+// report hands the measurements of the document parsed since start to r.
+func (p *Parser) report(r metrics.Recorder, start time.Time) {
+	r.Parsed(p.lexer.Offset(), time.Since(start), len(p.errors) > 0)
+}
+
 // parseObject parses a JSON object from the token stream.
 func (p *Parser) parseObject() JsonObject {
-	object := make(JsonObject)
+	object := make(JsonObject, p.sizeHint(p.objectSizes))
+	get := func(key string) (interface{}, bool) {
+		value, ok := object[key]
+		return value, ok
+	}
+	if !p.parseMembers(get, func(key string, value interface{}) { object[key] = value }) {
+		return nil
+	}
+	p.objectSizes = p.recordSize(p.objectSizes, len(object))
+	return object
+}
 
+// parseOrderedObject parses a JSON object from the token stream, keeping the
+// order of its keys.
+func (p *Parser) parseOrderedObject() *OrderedObject {
+	hint := p.sizeHint(p.objectSizes)
+	object := &OrderedObject{Keys: make([]string, 0, hint), Values: make(JsonObject, hint)}
+	if !p.parseMembers(object.Get, object.Set) {
+		return nil
+	}
+	p.objectSizes = p.recordSize(p.objectSizes, object.Len())
+	return object
+}
+
+// parseMembers parses the members of a JSON object, handing each key-value pair
+// to set, get returning the value set for a key, if any, so that repeated
+// keys are handled according to the duplicate key policy. It returns false if
+// the object is malformed.
+func (p *Parser) parseMembers(get func(key string) (interface{}, bool), set func(key string, value interface{})) bool {
 	// Ensure the current token is the beginning of an object
 	if !p.curTokenIs(token.BEGIN_OBJECT) {
-		p.addError(fmt.Sprintf("expected '{' at line %d, column %d, got '%s'", p.curToken.Line, p.curToken.Column, p.curToken.Value))
-		return nil
+		p.addError(ExpectedObject, p.curToken)
+		return false
 	}
 
 	// Move to the next token
 	p.nextToken()
 
+	var collected map[string]bool // keys whose values are collected, with CollectDuplicates
+
 	// Loop until the end of the object is reached
 	for !p.curTokenIs(token.END_OBJECT) && !p.curTokenIs(token.EOF) {
-		key := p.parseObjectKey()
-		if key == "" {
-			return nil
+		keyToken := p.curToken
+		key, ok := p.parseObjectKey()
+		if ok && !p.charge(memberSize+len(key)) {
+			return false
 		}
 
 		// Ensure a name separator (:) follows the key
-		if !p.expectPeek(token.NAME_SEPARATOR) {
-			return nil
+		if !ok || !p.expectPeek(token.NAME_SEPARATOR) {
+			if p.resume(token.END_OBJECT) {
+				continue
+			}
+			return false
 		}
 
 		// Move to the value token
 		p.nextToken()
 
 		// Parse the value
+		p.depth++
 		value, err := p.parseValue()
+		p.depth--
 		if err != nil {
-			return nil
+			if p.resume(token.END_OBJECT) {
+				continue
+			}
+			return false
 		}
 
-		object[key] = value
+		var previous interface{}
+		repeated := false
+		if p.duplicates != LastKeyWins {
+			previous, repeated = get(key)
+		}
+		switch {
+		case !repeated:
+			set(key, value)
+		case p.duplicates == RejectDuplicates:
+			p.addError(DuplicateKey, keyToken)
+		case p.duplicates == CollectDuplicates:
+			if collected[key] {
+				set(key, append(previous.(JsonArray), value))
+			} else {
+				if collected == nil {
+					collected = make(map[string]bool)
+				}
+				collected[key] = true
+				set(key, JsonArray{previous, value})
+			}
+		}
 
 		// Move past the value
 		p.nextToken()
 
 		// Handle comma separation for multiple key-value pairs
 		if p.curTokenIs(token.VALUE_SEPARATOR) {
-			if p.peekToken.Type == token.END_OBJECT { // No comma just before the end of the object
-				p.addError(fmt.Sprintf("No ',' before '}' at line %d, column %d", p.curToken.Line, p.curToken.Column))
-				return nil
+			if p.peekToken.Type == token.END_OBJECT && !p.lexer.IsJSON5() { // No comma just before the end of the object, but with JSON5
+				p.addError(TrailingComma, p.curToken)
+				if !p.recovering {
+					return false
+				}
 			}
 
 			p.nextToken()
@@ -98,21 +579,51 @@ func (p *Parser) parseObject() JsonObject {
 
 	// Ensure the end of the object is reached
 	if !p.curTokenIs(token.END_OBJECT) {
-		p.addError(fmt.Sprintf("expected '}' at line %d, column %d, got '%s'", p.curToken.Line, p.curToken.Column, p.curToken.Value))
-		return nil
+		p.addError(ExpectedObjectEnd, p.curToken)
+		return false
 	}
 
-	return object
+	return true
 }
 
 // parseArray parses a JSON array from the token stream.
 func (p *Parser) parseArray() JsonArray {
-	array := JsonArray{}
+	if p.arena != nil {
+		return p.parseArenaArray()
+	}
+
+	array := make(JsonArray, 0, p.sizeHint(p.arraySizes))
+	if !p.parseElements(func(value interface{}) { array = append(array, value) }) {
+		return nil
+	}
+	p.arraySizes = p.recordSize(p.arraySizes, len(array))
+	return array
+}
+
+// parseArenaArray parses a JSON array from the token stream into the arena of
+// the parser. The elements are collected at the end of the scratch space of
+// the parser, nested arrays using the space after them, then copied into the
+// arena once their number is known.
+func (p *Parser) parseArenaArray() JsonArray {
+	base := len(p.scratch)
+	defer func() {
+		clear(p.scratch[base:])
+		p.scratch = p.scratch[:base]
+	}()
+
+	if !p.parseElements(func(value interface{}) { p.scratch = append(p.scratch, value) }) {
+		return nil
+	}
+	return p.arena.array(p.scratch[base:])
+}
 
+// parseElements parses the elements of a JSON array, handing each of them to
+// add. It returns false if the array is malformed.
+func (p *Parser) parseElements(add func(value interface{})) bool {
 	// Ensure the current token is the beginning of an array
 	if !p.curTokenIs(token.BEGIN_ARRAY) {
-		p.addError(fmt.Sprintf("expected '[' at line %d, column %d, got '%s'", p.curToken.Line, p.curToken.Column, p.curToken.Value))
-		return nil
+		p.addError(ExpectedArray, p.curToken)
+		return false
 	}
 
 	// Move to the next token
@@ -121,12 +632,17 @@ func (p *Parser) parseArray() JsonArray {
 	// Loop until the end of the array is reached
 	for !p.curTokenIs(token.END_ARRAY) {
 		// Parse the value
+		p.depth++
 		value, err := p.parseValue()
+		p.depth--
 		if err != nil {
-			return nil
+			if p.resume(token.END_ARRAY) {
+				continue
+			}
+			return false
 		}
 
-		array = append(array, value)
+		add(value)
 
 		// Move past the value
 		p.nextToken()
@@ -138,29 +654,250 @@ func (p *Parser) parseArray() JsonArray {
 	}
 
 	// Ensure the end of the array is reached
-	if !p.curTokenIs(token.END_ARRAY) {
-		return nil
+	return p.curTokenIs(token.END_ARRAY)
+}
+
+// resume skips, in recovery mode, the tokens following an error in a
+// container whose end is close, nested containers included, up to the next
+// comma, which it moves past, or to the end of the container. It reports
+// whether parsing can go on there, which is not the case at the end of the
+// input or at the end of an enclosing container, left for the latter to
+// resume at.
+func (p *Parser) resume(close token.TokenType) bool {
+	if !p.recovering || p.limitErr != nil {
+		return false
+	}
+	depth := 0
+	for ; !p.curTokenIs(token.EOF); p.nextToken() {
+		switch p.curToken.Type {
+		case token.BEGIN_OBJECT, token.BEGIN_ARRAY:
+			depth++
+		case token.END_OBJECT, token.END_ARRAY:
+			if depth == 0 {
+				return p.curTokenIs(close)
+			}
+			depth--
+		case token.VALUE_SEPARATOR:
+			if depth == 0 {
+				p.nextToken()
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	return array
+// errContainer is returned by parseValue, in recovery mode, for malformed
+// objects and arrays, whose errors are already recorded.
+var errContainer = errors.New("malformed container")
+
+// ErrorCode identifies the kind of a ParseError.
+type ErrorCode int
+
+const (
+	TrailingToken     ErrorCode = iota // a token follows the end of the document
+	ExpectedObject                     // an object was expected
+	TrailingComma                      // a comma is followed by '}'
+	ExpectedObjectEnd                  // an object is not closed
+	ExpectedArray                      // an array was expected
+	ExpectedKey                        // a member does not start with a string
+	UnexpectedToken                    // a value was expected
+	InvalidNumber                      // a number does not fit its Go type
+	ExpectedToken                      // the next token is not the expected one, such as ':' after a key
+	MemoryLimit                        // the budget of the parser is exceeded
+	DuplicateKey                       // an object holds a key more than once, see SetDuplicateKeys
+	TooManyErrors                      // errors beyond the limit set with SetMaxErrors
+	LimitExceeded                      // the input exceeds a limit of the lexer, see lexer.Lexer.SetLimits
+)
+
+// ParseError is an error found in a document by a Parser.
+type ParseError struct {
+	Code         ErrorCode
+	Message      string // description of the error, without its position
+	Line, Column int    // position of the token the error is about, 0 for TooManyErrors
+	ByteOffset   int    // offset in bytes of that token in the input
+	// Repeats is the number of errors identical to this one but for their
+	// position, which are only counted with SetMaxErrors.
+	Repeats int
 }
 
-// addError appends an error message to the parser's errors slice.
-func (p *Parser) addError(msg string) {
-	p.errors = append(p.errors, msg)
+// Error returns the message of the error with its position, followed by the
+// number of its repeats, if any. The position is inserted before the token
+// found, as in "expected '}' at line 1, column 7, got ']'".
+func (e ParseError) Error() string {
+	if e.Code == TooManyErrors {
+		return e.Message
+	}
+	head, tail := e.Message, ""
+	switch e.Code {
+	case ExpectedObject, ExpectedObjectEnd, ExpectedArray, ExpectedKey:
+		if i := strings.Index(e.Message, gotPrefix); i >= 0 {
+			head, tail = e.Message[:i], e.Message[i:]
+		}
+	}
+	at := " at"
+	if e.Code == ExpectedToken {
+		at = ", at"
+	}
+	text := fmt.Sprintf("%s%s line %d, column %d%s", head, at, e.Line, e.Column, tail)
+	if e.Repeats > 0 {
+		text = fmt.Sprintf("%s (repeated %d more times)", text, e.Repeats)
+	}
+	return text
+}
+
+// ErrorList is a list of parse errors, such as the errors of a parser, as an
+// error whose message lists theirs separated by semicolons.
+type ErrorList []ParseError
+
+func (l ErrorList) Error() string {
+	messages := make([]string, len(l))
+	for i, e := range l {
+		messages[i] = e.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors of the list, for errors.As to find them.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// parseError records what is needed to format the message of an error, which
+// is only done when Errors is called: documents being rejected are often
+// simply dropped, as by validity checks.
+type parseError struct {
+	code     ErrorCode
+	tok      token.Token     // token the error is about, which locates it
+	expected token.TokenType // for ExpectedToken, with got
+	got      token.TokenType
+	err      error // for InvalidNumber, MemoryLimit and LimitExceeded
+	repeats  int   // number of errors identical to this one but for their position
+}
+
+// sameAs reports whether e and other are the same error, at any position.
+func (e parseError) sameAs(other parseError) bool {
+	return e.code == other.code && e.tok.Type == other.tok.Type && e.tok.Value == other.tok.Value &&
+		e.expected == other.expected && e.got == other.got
 }
 
-// parseObjectKey parses and returns the key of an object field.
-func (p *Parser) parseObjectKey() string {
-	if p.curToken.Type != token.STRING {
-		p.addError(fmt.Sprintf("expected string for key at line %d, column %d, got '%s'", p.curToken.Line, p.curToken.Column, p.curToken.Value))
-		return ""
+// export returns the ParseError describing e.
+func (e parseError) export() ParseError {
+	return ParseError{
+		Code:       e.code,
+		Message:    e.describe(),
+		Line:       e.tok.Line,
+		Column:     e.tok.Column,
+		ByteOffset: e.tok.Offset,
+		Repeats:    e.repeats,
 	}
-	return p.curToken.Value
+}
+
+// gotPrefix introduces the token found in the messages of the errors
+// expecting another one, before which ParseError.Error inserts the position.
+const gotPrefix = ", got '"
+
+// describe returns the description of the error, without its position.
+func (e parseError) describe() string {
+	t := e.tok
+	switch e.code {
+	case TrailingToken:
+		return fmt.Sprintf("unexpected token '%s' after the end of the document", t.Value)
+	case ExpectedObject:
+		return fmt.Sprintf("expected '{'"+gotPrefix+"%s'", t.Value)
+	case TrailingComma:
+		return "No ',' before '}'"
+	case ExpectedObjectEnd:
+		return fmt.Sprintf("expected '}'"+gotPrefix+"%s'", t.Value)
+	case ExpectedArray:
+		return fmt.Sprintf("expected '['"+gotPrefix+"%s'", t.Value)
+	case ExpectedKey:
+		return fmt.Sprintf("expected string for key"+gotPrefix+"%s'", t.Value)
+	case UnexpectedToken:
+		if name := strings.TrimLeft(t.Value, "+-"); name == "Infinity" || name == "NaN" {
+			return fmt.Sprintf("%s has no strict JSON equivalent", t.Value)
+		}
+		return fmt.Sprintf("unexpected token '%s'", t.Value)
+	case InvalidNumber:
+		return e.err.Error()
+	case MemoryLimit:
+		return fmt.Sprintf("document exceeds the memory budget of %d bytes", e.err.(*MemoryLimitError).Limit)
+	case LimitExceeded:
+		err := e.err.(*lexer.LimitError)
+		return fmt.Sprintf("%s exceeds the limit of %d", err.Limit, err.Max)
+	case DuplicateKey:
+		return fmt.Sprintf("duplicate key \"%s\"", t.Value)
+	}
+	return fmt.Sprintf("expected next token to be %v, got %v instead", e.expected, e.got)
+}
+
+// addError records an error of the given kind about tok.
+func (p *Parser) addError(code ErrorCode, tok token.Token) {
+	p.record(parseError{code: code, tok: tok})
+}
+
+// record records e, unless parsing was aborted: the errors following the
+// abort are only due to it.
+func (p *Parser) record(e parseError) {
+	if p.limitErr != nil {
+		return
+	}
+	p.recorded++
+	if p.maxErrors > 0 {
+		for i := range p.errors {
+			if p.errors[i].sameAs(e) {
+				p.errors[i].repeats++
+				return
+			}
+		}
+		if len(p.errors) == p.maxErrors {
+			p.dropped++
+			return
+		}
+	}
+	p.errors = append(p.errors, e)
+}
+
+// SetMaxErrors limits the errors the parser keeps to max distinct ones, so
+// that a badly broken document does not produce thousands of near-identical
+// errors: an error repeating one already kept, about the same token at
+// another position, is only counted with it, and the errors beyond max are
+// summarized by a last message, "and N more errors". A max of 0, the default,
+// keeps all the errors.
+func (p *Parser) SetMaxErrors(max int) {
+	p.maxErrors = max
+}
+
+// parseObjectKey parses and returns the key of an object field, a string or,
+// from a JSON5 lexer, an identifier. The empty string is a valid key, so
+// success is reported separately.
+func (p *Parser) parseObjectKey() (string, bool) {
+	if p.curToken.Type != token.STRING && p.curToken.Type != token.IDENTIFIER {
+		p.addError(ExpectedKey, p.curToken)
+		return "", false
+	}
+	return p.curToken.Value, true
 }
 
 // parseValue parses a JSON value based on the current token type.
 func (p *Parser) parseValue() (interface{}, error) {
+	size := valueSize
+	switch p.curToken.Type {
+	case token.STRING:
+		size += len(p.curToken.Value)
+	case token.NUMBER:
+		size += numberSize
+	case token.BEGIN_OBJECT, token.BEGIN_ARRAY:
+		size += containerSize
+	}
+	if !p.charge(size) {
+		return nil, p.limitErr
+	}
+
 	switch p.curToken.Type {
 	case token.STRING:
 		return p.curToken.Value, nil
@@ -170,38 +907,167 @@ func (p *Parser) parseValue() (interface{}, error) {
 		return p.parseBoolean(), nil
 	case token.NULL:
 		return nil, nil
-	case token.BEGIN_OBJECT:
-		return p.parseObject(), nil
-	case token.BEGIN_ARRAY:
-		return p.parseArray(), nil
+	case token.BEGIN_OBJECT, token.BEGIN_ARRAY:
+		return p.parseContainer()
 	default:
-		p.addError(fmt.Sprintf("unexpected token '%s' at line %d, column %d", p.curToken.Value, p.curToken.Line, p.curToken.Column))
+		p.addError(UnexpectedToken, p.curToken)
 		return nil, errors.New("unexpected token")
 	}
 }
 
-// parseNumber parses a number token into an appropriate Go numeric type.
+// parseContainer parses an object or an array. Malformed ones are reported
+// as errContainer in recovery mode, for the enclosing container to resume
+// after them, and as nil values otherwise.
+func (p *Parser) parseContainer() (interface{}, error) {
+	var value interface{}
+	malformed := false
+	switch {
+	case p.curTokenIs(token.BEGIN_ARRAY):
+		array := p.parseArray()
+		value, malformed = array, array == nil
+	case p.ordered:
+		object := p.parseOrderedObject()
+		value, malformed = object, object == nil
+	default:
+		object := p.parseObject()
+		value, malformed = object, object == nil
+	}
+	if malformed && p.recovering {
+		return nil, errContainer
+	}
+	return value, nil
+}
+
+// parseNumber parses a number token into an appropriate Go numeric type, or
+// into a Number with UseNumber.
 func (p *Parser) parseNumber() interface{} {
-	numStr := p.curToken.Value
+	if p.numbers {
+		n := Number(p.curToken.Value)
+		if !n.Valid() {
+			p.record(parseError{code: InvalidNumber, tok: p.curToken, err: &numberError{text: string(n), float: strings.ContainsAny(string(n), ".eE")}})
+			return nil
+		}
+		return n
+	}
+	val, err := ParseNumber(p.curToken.Value)
+	if err != nil {
+		p.record(parseError{code: InvalidNumber, tok: p.curToken, err: err})
+		return nil
+	}
+	return val
+}
 
+// ParseNumber converts the text of a number token into an int64, or into a
+// float64 if it has a fraction or an exponent.
+func ParseNumber(numStr string) (interface{}, error) {
 	// Check for float or integer representation
 	if strings.Contains(numStr, ".") || strings.ContainsAny(numStr, "eE") {
 		// Parse as float
 		val, err := strconv.ParseFloat(numStr, 64)
 		if err != nil {
-			p.addError(fmt.Sprintf("could not parse %q as float at line %d, column %d", numStr, p.curToken.Line, p.curToken.Column))
-			return nil
+			return nil, &numberError{text: numStr, float: true}
 		}
-		return val
+		return val, nil
 	}
 
 	// Parse as integer
 	val, err := strconv.ParseInt(numStr, 10, 64)
 	if err != nil {
-		p.addError(fmt.Sprintf("could not parse %q as integer at line %d, column %d", numStr, p.curToken.Line, p.curToken.Column))
-		return nil
+		return nil, &numberError{text: numStr}
 	}
-	return val
+	return val, nil
+}
+
+// Number is the text of a JSON number, as parsers set to UseNumber return
+// numbers, converted on demand to the Go type that holds it.
+type Number string
+
+// String returns the text of n.
+func (n Number) String() string {
+	return string(n)
+}
+
+// Valid reports whether n follows the grammar of JSON numbers, whatever its
+// magnitude.
+func (n Number) Valid() bool {
+	s := string(n)
+	s = strings.TrimPrefix(s, "-")
+	digits := func() bool {
+		i := 0
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		s = s[i:]
+		return i > 0
+	}
+	switch {
+	case strings.HasPrefix(s, "0"):
+		s = s[1:]
+	case !digits():
+		return false
+	}
+	if strings.HasPrefix(s, ".") {
+		s = s[1:]
+		if !digits() {
+			return false
+		}
+	}
+	if strings.HasPrefix(s, "e") || strings.HasPrefix(s, "E") {
+		s = s[1:]
+		if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+			s = s[1:]
+		}
+		if !digits() {
+			return false
+		}
+	}
+	return s == ""
+}
+
+// Int64 returns n as an int64, failing if it is not an integer or does not
+// fit in one.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// Float64 returns n as the nearest float64.
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// BigInt returns n as a big.Int, failing if it has a fraction or an exponent.
+func (n Number) BigInt() (*big.Int, error) {
+	i, ok := new(big.Int).SetString(string(n), 10)
+	if !ok {
+		return nil, &numberError{text: string(n)}
+	}
+	return i, nil
+}
+
+// BigFloat returns n as a big.Float with enough precision for all of its
+// digits to be significant.
+func (n Number) BigFloat() (*big.Float, error) {
+	// Each decimal digit takes less than 4 bits.
+	prec := max(uint(4*len(n)), 64)
+	f, _, err := big.ParseFloat(string(n), 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, &numberError{text: string(n), float: true}
+	}
+	return f, nil
+}
+
+// numberError is returned by ParseNumber for text that is not a number or
+// does not fit in its type. Its message is formatted by Error.
+type numberError struct {
+	text  string
+	float bool
+}
+
+func (e *numberError) Error() string {
+	if e.float {
+		return fmt.Sprintf("could not parse %q as float", e.text)
+	}
+	return fmt.Sprintf("could not parse %q as integer", e.text)
 }
 
 // parseBoolean returns a boolean value based on the current token.
@@ -215,13 +1081,36 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 		p.nextToken()
 		return true
 	} else {
-		p.addError(fmt.Sprintf("expected next token to be %v, got %v instead, at line %d, column %d", t, p.peekToken.Type, p.curToken.Line, p.curToken.Column))
+		p.record(parseError{code: ExpectedToken, tok: p.curToken, expected: t, got: p.peekToken.Type})
 		return false
 	}
 }
 
-func (p *Parser) Errors() []string {
-	return p.errors
+// Errors returns the errors encountered so far. Their messages are formatted
+// by the first call that needs them, so that callers only checking whether a
+// document is valid do not pay for them.
+func (p *Parser) Errors() []ParseError {
+	if p.formatted == p.recorded {
+		return p.exported
+	}
+	if p.maxErrors > 0 {
+		// the repeats of the errors kept may have changed
+		p.exported = make([]ParseError, 0, len(p.errors)+1)
+	}
+	for _, e := range p.errors[len(p.exported):] {
+		p.exported = append(p.exported, e.export())
+	}
+	if p.dropped > 0 {
+		message := fmt.Sprintf("and %d more errors", p.dropped)
+		p.exported = append(p.exported, ParseError{Code: TooManyErrors, Message: message})
+	}
+	p.formatted = p.recorded
+	return p.exported
+}
+
+// peekTokenIs checks if the next token is of a specific type.
+func (p *Parser) peekTokenIs(t token.TokenType) bool {
+	return p.peekToken.Type == t
 }
 
 // curTokenIs checks if the current token is of a specific type.
@@ -1,10 +1,15 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/metrics"
+	"github.com/oabrivard/gojson/metrics/expvarmetrics"
 )
 
 func TestParseSimpleObject(t *testing.T) {
@@ -14,10 +19,10 @@ func TestParseSimpleObject(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 0 {
+	if len(p.Errors()) != 0 {
 		errMsg := ""
-		for _, s := range p.errors {
-			errMsg += s + "\n"
+		for _, s := range p.Errors() {
+			errMsg += s.Error() + "\n"
 		}
 		t.Fatalf(errMsg)
 	}
@@ -40,10 +45,10 @@ func TestParseStep1Valid(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 0 {
+	if len(p.Errors()) != 0 {
 		errMsg := ""
-		for _, s := range p.errors {
-			errMsg += s + "\n"
+		for _, s := range p.Errors() {
+			errMsg += s.Error() + "\n"
 		}
 		t.Fatalf(errMsg)
 	}
@@ -62,8 +67,8 @@ func TestParseStep1Invalid(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 1 || p.errors[0] != "expected '{' at line 1, column 1, got ''" {
-		t.Errorf("Not the expected error(s) during parsing, got %v", p.errors)
+	if len(p.Errors()) != 1 || p.Errors()[0].Error() != "expected '{' at line 1, column 1, got ''" {
+		t.Errorf("Not the expected error(s) during parsing, got %v", p.Errors())
 	}
 
 	if parsed != nil {
@@ -78,10 +83,10 @@ func TestParseStep2Valid1(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 0 {
+	if len(p.Errors()) != 0 {
 		errMsg := ""
-		for _, s := range p.errors {
-			errMsg += s + "\n"
+		for _, s := range p.Errors() {
+			errMsg += s.Error() + "\n"
 		}
 		t.Fatalf(errMsg)
 	}
@@ -105,10 +110,10 @@ func TestParseStep2Valid2(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 0 {
+	if len(p.Errors()) != 0 {
 		errMsg := ""
-		for _, s := range p.errors {
-			errMsg += s + "\n"
+		for _, s := range p.Errors() {
+			errMsg += s.Error() + "\n"
 		}
 		t.Fatalf(errMsg)
 	}
@@ -130,8 +135,8 @@ func TestParseStep2Invalid1(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 1 || p.errors[0] != "No ',' before '}' at line 1, column 16" {
-		t.Errorf("Not the expected error(s) during parsing, got %v", p.errors)
+	if len(p.Errors()) != 1 || p.Errors()[0].Error() != "No ',' before '}' at line 1, column 16" {
+		t.Errorf("Not the expected error(s) during parsing, got %v", p.Errors())
 	}
 
 	if parsed != nil {
@@ -149,8 +154,8 @@ func TestParseStep2Invalid2(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 1 || p.errors[0] != "expected string for key at line 3, column 6, got 'key'" {
-		t.Errorf("Not the expected error(s) during parsing, got %v", p.errors)
+	if len(p.Errors()) != 1 || p.Errors()[0].Error() != "expected string for key at line 3, column 6, got 'key'" {
+		t.Errorf("Not the expected error(s) during parsing, got %v", p.Errors())
 	}
 
 	if parsed != nil {
@@ -171,10 +176,10 @@ func TestParseStep3Valid(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 0 {
+	if len(p.Errors()) != 0 {
 		errMsg := ""
-		for _, s := range p.errors {
-			errMsg += s + "\n"
+		for _, s := range p.Errors() {
+			errMsg += s.Error() + "\n"
 		}
 		t.Fatalf(errMsg)
 	}
@@ -205,8 +210,8 @@ func TestParseStep3Invalid(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 1 || p.errors[0] != "unexpected token 'False' at line 3, column 16" {
-		t.Errorf("Not the expected error(s) during parsing, got %v", p.errors)
+	if len(p.Errors()) != 1 || p.Errors()[0].Error() != "unexpected token 'False' at line 3, column 16" {
+		t.Errorf("Not the expected error(s) during parsing, got %v", p.Errors())
 	}
 
 	if parsed != nil {
@@ -226,10 +231,10 @@ func TestParseStep4Valid1(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 0 {
+	if len(p.Errors()) != 0 {
 		errMsg := ""
-		for _, s := range p.errors {
-			errMsg += s + "\n"
+		for _, s := range p.Errors() {
+			errMsg += s.Error() + "\n"
 		}
 		t.Fatalf(errMsg)
 	}
@@ -260,10 +265,10 @@ func TestParseStep4Valid2(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 0 {
+	if len(p.Errors()) != 0 {
 		errMsg := ""
-		for _, s := range p.errors {
-			errMsg += s + "\n"
+		for _, s := range p.Errors() {
+			errMsg += s.Error() + "\n"
 		}
 		t.Fatalf(errMsg)
 	}
@@ -296,11 +301,720 @@ func TestParseStep4Invalid(t *testing.T) {
 	p := NewParser(l)
 	parsed := p.Parse()
 
-	if len(p.errors) != 2 || p.errors[0] != "unexpected token ''' at line 7, column 13" || p.errors[1] != "expected string for key at line 7, column 18, got 'list'" {
-		t.Errorf("Not the expected error(s) during parsing, got %v", p.errors)
+	if len(p.Errors()) != 2 || p.Errors()[0].Error() != "unexpected token ''' at line 7, column 13" || p.Errors()[1].Error() != "expected string for key at line 7, column 18, got 'list'" {
+		t.Errorf("Not the expected error(s) during parsing, got %v", p.Errors())
 	}
 
 	if parsed != nil {
 		t.Errorf("expected a nil result from parsing an empty input")
 	}
 }
+
+func TestParseOrderedKeepsKeyOrder(t *testing.T) {
+	input := `{"b": 1, "a": {"z": true, "y": false}, "c": [{"k2": 1, "k1": 2}]}`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	parsed := p.ParseOrdered()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected errors: %v", p.Errors())
+	}
+
+	if !reflect.DeepEqual(parsed.Keys, []string{"b", "a", "c"}) {
+		t.Errorf("top-level keys are not in source order, got %v", parsed.Keys)
+	}
+
+	inner, ok := parsed.Values["a"].(*OrderedObject)
+	if !ok || !reflect.DeepEqual(inner.Keys, []string{"z", "y"}) {
+		t.Errorf("nested object is not ordered, got %+v", parsed.Values["a"])
+	}
+
+	arr, ok := parsed.Values["c"].(JsonArray)
+	if !ok || len(arr) != 1 {
+		t.Fatalf("expected an array with one element, got %+v", parsed.Values["c"])
+	}
+	if elem, ok := arr[0].(*OrderedObject); !ok || !reflect.DeepEqual(elem.Keys, []string{"k2", "k1"}) {
+		t.Errorf("object inside array is not ordered, got %+v", arr[0])
+	}
+}
+
+func TestMembers(t *testing.T) {
+	ordered := NewOrderedObject()
+	ordered.Set("b", int64(1))
+	ordered.Set("a", int64(2))
+
+	tests := []struct {
+		value interface{}
+		keys  []string
+		ok    bool
+	}{
+		{ordered, []string{"b", "a"}, true},
+		{JsonObject{"b": int64(1), "a": int64(2), "c": nil}, []string{"a", "b", "c"}, true},
+		{JsonObject{}, []string{}, true},
+		{JsonArray{int64(1)}, nil, false},
+		{"text", nil, false},
+	}
+	for _, tt := range tests {
+		keys, values, ok := Members(tt.value)
+		if ok != tt.ok || !reflect.DeepEqual(keys, tt.keys) {
+			t.Errorf("Members(%v): expected %v, %v, got %v, %v", tt.value, tt.keys, tt.ok, keys, ok)
+		}
+		if ok && len(values) != len(keys) {
+			t.Errorf("Members(%v): expected %d values, got %v", tt.value, len(keys), values)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{JsonObject{}, "object"},
+		{NewOrderedObject(), "object"},
+		{JsonArray{}, "array"},
+		{"", "string"},
+		{int64(1), "number"},
+		{1.5, "number"},
+		{Number("1e400"), "number"},
+		{false, "boolean"},
+		{nil, "null"},
+		{int32(1), "int32"},
+	}
+	for _, tt := range tests {
+		if got := TypeName(tt.value); got != tt.expected {
+			t.Errorf("TypeName(%#v): expected %s, got %s", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[1, "two", null]`, JsonArray{int64(1), "two", nil}},
+		{`"hello"`, "hello"},
+		{` 42 `, int64(42)},
+		{`true`, true},
+		{`null`, nil},
+		{`{"a": [false]}`, JsonObject{"a": JsonArray{false}}},
+	}
+
+	for i, tt := range tests {
+		p := NewParser(lexer.NewLexer(tt.input))
+		parsed := p.ParseValue()
+
+		if len(p.Errors()) != 0 {
+			t.Fatalf("tests[%d] - unexpected errors: %v", i, p.Errors())
+		}
+		if !reflect.DeepEqual(parsed, tt.expected) {
+			t.Errorf("tests[%d] - parsed value is not as expected. Got %+v, want %+v", i, parsed, tt.expected)
+		}
+	}
+}
+
+func TestParseValueInvalid(t *testing.T) {
+	p := NewParser(lexer.NewLexer(`{"a": 1} ]`))
+	parsed := p.ParseValue()
+
+	if len(p.Errors()) != 1 || p.Errors()[0].Error() != "unexpected token ']' after the end of the document at line 1, column 10" {
+		t.Errorf("Not the expected error(s) during parsing, got %v", p.Errors())
+	}
+	if parsed != nil {
+		t.Errorf("expected a nil result from parsing an invalid input")
+	}
+}
+
+func TestParseSiblingCapacity(t *testing.T) {
+	input := `[{"tags": [1, 2, 3], "id": 1}, {"tags": [4], "id": 2}, {"tags": [], "id": 3}]`
+	p := NewParser(lexer.NewLexer(input))
+	parsed, ok := p.ParseValue().(JsonArray)
+	if len(p.Errors()) != 0 || !ok || len(parsed) != 3 {
+		t.Fatalf("unexpected result %+v, errors %v", parsed, p.Errors())
+	}
+
+	// every array of tags after the first is preallocated with the size of
+	// the previous one
+	expected := []int{0, 3, 1}
+	for i := 1; i < len(parsed); i++ {
+		tags := parsed[i].(JsonObject)["tags"].(JsonArray)
+		if cap(tags) != expected[i] {
+			t.Errorf("element %d: expected tags with a capacity of %d, got %d", i, expected[i], cap(tags))
+		}
+	}
+	if !reflect.DeepEqual(parsed[2], JsonObject{"tags": JsonArray{}, "id": int64(3)}) {
+		t.Errorf("unexpected last element %+v", parsed[2])
+	}
+}
+
+// messages returns the messages of errs.
+func messages(errs []ParseError) []string {
+	result := make([]string, len(errs))
+	for i, e := range errs {
+		result[i] = e.Error()
+	}
+	return result
+}
+
+func TestParseErrorsFormattedLazily(t *testing.T) {
+	p := NewParser(lexer.NewLexer(`{"a": 99999999999999999999, "b" 1}`))
+	p.ParseValue()
+
+	if len(p.errors) != 2 || p.exported != nil {
+		t.Fatalf("expected 2 unformatted errors, got %d errors and messages %v", len(p.errors), p.exported)
+	}
+	expected := []string{
+		`could not parse "99999999999999999999" as integer at line 1, column 27`,
+		"expected next token to be 6, got 10 instead, at line 1, column 31",
+	}
+	if !reflect.DeepEqual(messages(p.Errors()), expected) {
+		t.Errorf("expected errors %q, got %q", expected, p.Errors())
+	}
+	if allocs := testing.AllocsPerRun(10, func() { p.Errors() }); allocs != 0 {
+		t.Errorf("expected the messages to be formatted once, got %v allocations per call", allocs)
+	}
+}
+
+func TestResetParser(t *testing.T) {
+	p := NewParser(lexer.NewLexer(`{"a": [1, 2`))
+	p.ParseValue()
+	errs := p.Errors()
+	if len(errs) == 0 {
+		t.Fatalf("expected errors parsing an unterminated document")
+	}
+
+	p.Reset(`{"b": {"c": null}}`)
+	parsed := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected errors after a reset: %v", p.Errors())
+	}
+	if !reflect.DeepEqual(parsed, JsonObject{"b": JsonObject{"c": nil}}) {
+		t.Errorf("unexpected result %+v", parsed)
+	}
+	if len(errs) == 0 || errs[0].Error() != "unexpected token '' at line 1, column 12" {
+		t.Errorf("expected the errors returned before the reset to be kept, got %q", errs)
+	}
+
+	p.Reset(`[1] 2`)
+	if p.ParseValue() != nil || len(p.Errors()) != 1 {
+		t.Errorf("expected an error for data after the document, got %v", p.Errors())
+	}
+}
+
+func TestPool(t *testing.T) {
+	var pool Pool
+	input := `{"id": 1, "tags": ["a", "b"]}`
+	expected := JsonObject{"id": int64(1), "tags": JsonArray{"a", "b"}}
+
+	for i := 0; i < 3; i++ {
+		p := pool.Get(input)
+		parsed := p.ParseValue()
+		if len(p.Errors()) != 0 || !reflect.DeepEqual(parsed, expected) {
+			t.Fatalf("run %d: unexpected result %+v, errors %v", i, parsed, p.Errors())
+		}
+		pool.Put(p)
+	}
+
+	// parsing from the pool only allocates the values
+	pooled := testing.AllocsPerRun(100, func() {
+		p := pool.Get(input)
+		p.ParseValue()
+		pool.Put(p)
+	})
+	fresh := testing.AllocsPerRun(100, func() {
+		NewParser(lexer.NewLexer(input)).ParseValue()
+	})
+	if pooled >= fresh {
+		t.Errorf("expected fewer allocations with a pool, got %v, against %v without", pooled, fresh)
+	}
+}
+
+func TestParseWithArena(t *testing.T) {
+	input := `[{"tags": ["a", "b"], "ids": [[1, 2], [3]]}, [], [true, [null, [false]]]]`
+	expected := NewParser(lexer.NewLexer(input)).ParseValue()
+
+	var arena Arena
+	p := NewParser(lexer.NewLexer(input))
+	p.UseArena(&arena)
+	parsed := p.ParseValue()
+	if len(p.Errors()) != 0 || !reflect.DeepEqual(parsed, expected) {
+		t.Fatalf("unexpected result %+v, errors %v", parsed, p.Errors())
+	}
+	if len(arena.chunks) != 1 || len(p.scratch) != 0 {
+		t.Errorf("expected the arrays in a single chunk and no scratch elements left, got %d chunks and %d elements", len(arena.chunks), len(p.scratch))
+	}
+
+	// arrays do not overwrite each other when appended to
+	tags := parsed.(JsonArray)[0].(JsonObject)["tags"].(JsonArray)
+	_ = append(tags, "c")
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("appending to an array modified the document: %+v", parsed)
+	}
+
+	// after a reset, the next document reuses the chunk
+	arena.Free()
+	p.Reset(`[1, [2]]`)
+	parsed = p.ParseValue()
+	if !reflect.DeepEqual(parsed, JsonArray{int64(1), JsonArray{int64(2)}}) || len(arena.chunks) != 1 {
+		t.Errorf("unexpected result %+v with %d chunks", parsed, len(arena.chunks))
+	}
+	if arena.chunks[0][3] != nil {
+		t.Errorf("expected the arena to drop the values of the freed arrays, got %v", arena.chunks[0][3])
+	}
+
+	p.Reset(`[1, [2, ]`)
+	if p.ParseValue() != nil || len(p.Errors()) == 0 || len(p.scratch) != 0 {
+		t.Errorf("expected an error and no scratch elements left, got %v and %d elements", p.Errors(), len(p.scratch))
+	}
+}
+
+func TestArenaLargeArrays(t *testing.T) {
+	var arena Arena
+	small := arena.array(make([]interface{}, arenaChunk/4))
+	large := arena.array(make([]interface{}, arenaChunk/4+1))
+	if len(small) != arenaChunk/4 || len(large) != arenaChunk/4+1 || len(arena.chunks) != 1 || arena.used != arenaChunk/4 {
+		t.Errorf("expected only the small array in the arena, got %d chunks and %d values used", len(arena.chunks), arena.used)
+	}
+	for i := 0; i < 4; i++ {
+		arena.array(make([]interface{}, arenaChunk/4))
+	}
+	if len(arena.chunks) != 2 || arena.current != 1 || arena.used != arenaChunk/4 {
+		t.Errorf("expected a second chunk, got %d chunks, current %d, %d values used", len(arena.chunks), arena.current, arena.used)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	docs := []string{`{"id": 1, "tags": ["a", "b", "c"]}`, `{"id": 2, "tags": ["d"]}`, `{"id": 3,`, `[true]`}
+	expected := []Result{
+		{Value: JsonObject{"id": int64(1), "tags": JsonArray{"a", "b", "c"}}},
+		{Value: JsonObject{"id": int64(2), "tags": JsonArray{"d"}}},
+		{Errors: []ParseError{{Code: ExpectedObjectEnd, Message: "expected '}', got ''", Line: 1, Column: 10, ByteOffset: 9}}},
+		{Value: JsonArray{true}},
+	}
+
+	results := ParseAll(docs)
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+	byteDocs := make([][]byte, len(docs))
+	for i, doc := range docs {
+		byteDocs[i] = []byte(doc)
+	}
+	if results := ParseAll(byteDocs); !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+
+	// the arrays of a document are preallocated with the size of those of
+	// the previous one
+	tags := results[1].Value.(JsonObject)["tags"].(JsonArray)
+	if cap(tags) != 3 {
+		t.Errorf("expected the tags of the second document to be preallocated, got a capacity of %d", cap(tags))
+	}
+}
+
+func TestMaxMemory(t *testing.T) {
+	input := `{"a": [1, 2, 3], "b": {"c": "some text"}, "d": [[], [], []]}`
+	p := NewParser(lexer.NewLexer(input))
+	p.SetMaxMemory(1 << 10)
+	if p.ParseValue() == nil || p.Err() != nil || len(p.Errors()) != 0 {
+		t.Fatalf("unexpected errors %v, %v within the budget", p.Err(), p.Errors())
+	}
+	used := p.memory
+
+	p.Reset(input)
+	p.SetMaxMemory(used - 1)
+	if p.ParseValue() != nil {
+		t.Errorf("expected no value when the budget is exceeded")
+	}
+	var limitErr *MemoryLimitError
+	if !errors.As(p.Err(), &limitErr) || limitErr.Limit != used-1 || limitErr.Line != 1 || limitErr.Column != 57 {
+		t.Fatalf("expected a memory limit error at the last array, got %v", p.Err())
+	}
+	expected := []string{fmt.Sprintf("document exceeds the memory budget of %d bytes at line 1, column 57", used-1)}
+	if !reflect.DeepEqual(messages(p.Errors()), expected) {
+		t.Errorf("expected errors %q, got %q", expected, p.Errors())
+	}
+
+	// an input expanding into many small values is stopped early
+	bomb := "[" + strings.Repeat("[],", 1<<16) + "[]]"
+	p.Reset(bomb)
+	p.SetMaxMemory(1 << 12)
+	if p.ParseValue() != nil || p.Err() == nil || len(p.Errors()) != 1 {
+		t.Errorf("expected only a memory limit error, got %v", p.Errors())
+	}
+	if tok := p.curToken; tok.Column > 1<<8 {
+		t.Errorf("expected parsing to stop at the limit, stopped at column %d", tok.Column)
+	}
+
+	p.Reset(input)
+	p.SetMaxMemory(0)
+	if p.ParseValue() == nil || p.Err() != nil {
+		t.Errorf("unexpected error %v without a budget", p.Err())
+	}
+}
+
+func TestLexerLimits(t *testing.T) {
+	l := lexer.NewLexer(`{"a": [1, 2, 3], "b": "too long"}`)
+	l.SetLimits(lexer.Limits{MaxStringLength: 4})
+	p := NewParser(l)
+	if p.ParseValue() != nil {
+		t.Errorf("expected no value when a limit is exceeded")
+	}
+	var limitErr *lexer.LimitError
+	if !errors.As(p.Err(), &limitErr) || limitErr.Limit != "string length" {
+		t.Fatalf("expected a string length error, got %v", p.Err())
+	}
+	expected := []string{"string length exceeds the limit of 4 at line 1, column 32"}
+	if !reflect.DeepEqual(messages(p.Errors()), expected) {
+		t.Errorf("expected errors %q, got %q", expected, p.Errors())
+	}
+	if errs := p.Errors(); errs[0].Code != LimitExceeded || errs[0].Message != "string length exceeds the limit of 4" {
+		t.Errorf("expected a LimitExceeded error, got %+v", errs[0])
+	}
+
+	// limits are kept by Reset, the token count starting over
+	l.SetLimits(lexer.Limits{MaxTokens: 9})
+	p.Reset(`[1, 2, 3, 4]`)
+	if p.ParseValue() == nil || p.Err() != nil {
+		t.Errorf("unexpected error %v within the limits", p.Err())
+	}
+	p.Reset(`[1, 2, 3, 4, 5]`)
+	if p.ParseValue() != nil || p.Err() == nil || len(p.Errors()) != 1 {
+		t.Errorf("expected only a token count error, got %v", p.Errors())
+	}
+}
+
+func TestParseMetrics(t *testing.T) {
+	var counters expvarmetrics.Counters
+	metrics.SetRecorder(&counters)
+	defer metrics.SetRecorder(nil)
+
+	NewParser(lexer.NewLexer(`{"a": [1, 2]}`)).ParseValue()
+	NewParser(lexer.NewReaderLexer(strings.NewReader(`{"a": 3}`))).Parse()
+	NewParser(lexer.NewLexer(`{"a" 1}`)).ParseOrdered()
+
+	// the last document is only read up to its error
+	s := counters.Snapshot()
+	if s.DocumentsParsed != 3 || s.BytesParsed != 13+8+6 || s.ParseErrors != 1 || s.ParseTime <= 0 {
+		t.Errorf("unexpected counters %+v", s)
+	}
+}
+
+func TestMaxErrors(t *testing.T) {
+	input := "[" + strings.Repeat("99999999999999999999, ", 50) + "1e999, 2e999,\n3e999]"
+
+	p := NewParser(lexer.NewLexer(input))
+	p.ParseValue()
+	if len(p.Errors()) != 53 {
+		t.Fatalf("expected all the errors without a limit, got %d", len(p.Errors()))
+	}
+
+	p.Reset(input)
+	p.SetMaxErrors(2)
+	p.ParseValue()
+	expected := []string{
+		`could not parse "99999999999999999999" as integer at line 1, column 22 (repeated 49 more times)`,
+		`could not parse "1e999" as float at line 1, column 1107`,
+		"and 2 more errors",
+	}
+	if !reflect.DeepEqual(messages(p.Errors()), expected) {
+		t.Errorf("expected errors %q, got %q", expected, p.Errors())
+	}
+
+	// the messages are formatted again when errors are added
+	p.Reset(`[1e999, 1e999`)
+	p.nextToken()
+	p.parseValue()
+	if errs := p.Errors(); len(errs) != 1 || strings.Contains(errs[0].Error(), "repeated") {
+		t.Fatalf("expected a single error, got %q", errs)
+	}
+	p.nextToken()
+	p.nextToken()
+	p.parseValue()
+	if errs := p.Errors(); len(errs) != 1 || !strings.HasSuffix(errs[0].Error(), "(repeated 1 more times)") {
+		t.Errorf("expected a repeated error, got %q", errs)
+	}
+}
+
+func TestParseOrderedValue(t *testing.T) {
+	p := NewParser(lexer.NewLexer(`[{"b": 1, "a": {"d": 2, "c": 3}}, "x"]`))
+	parsed, ok := p.ParseOrderedValue().(JsonArray)
+	if len(p.Errors()) != 0 || !ok || len(parsed) != 2 {
+		t.Fatalf("unexpected result %+v, errors %v", parsed, p.Errors())
+	}
+	obj := parsed[0].(*OrderedObject)
+	inner := obj.Values["a"].(*OrderedObject)
+	if !reflect.DeepEqual(obj.Keys, []string{"b", "a"}) || !reflect.DeepEqual(inner.Keys, []string{"d", "c"}) {
+		t.Errorf("expected the keys in source order, got %v and %v", obj.Keys, inner.Keys)
+	}
+
+	p = NewParser(lexer.NewLexer(`"x" 1`))
+	if p.ParseOrderedValue() != nil || len(p.Errors()) != 1 {
+		t.Errorf("expected an error for data after the document, got %v", p.Errors())
+	}
+}
+
+func TestPreserveOrder(t *testing.T) {
+	p := NewParser(lexer.NewLexer(`{"b": 1, "a": 2}`))
+	p.PreserveOrder(true)
+	for _, input := range []string{`{"k": 1, "j": 2}`, `{"z": [{"y": 1, "x": 2}]}`} {
+		obj, ok := p.ParseValue().(*OrderedObject)
+		if !ok || len(p.Errors()) != 0 {
+			t.Fatalf("expected an ordered object, got %v, errors %v", obj, p.Errors())
+		}
+		if len(obj.Keys) != 2 || obj.Keys[0] < obj.Keys[1] {
+			t.Errorf("expected the keys in source order, got %v", obj.Keys)
+		}
+		p.Reset(input)
+	}
+	obj := p.Parse()
+	if _, ok := obj["z"].(JsonArray)[0].(*OrderedObject); !ok {
+		t.Errorf("expected the nested objects to be ordered, got %#v", obj)
+	}
+
+	p.PreserveOrder(false)
+	p.Reset(`{"b": 1}`)
+	if _, ok := p.ParseValue().(JsonObject); !ok {
+		t.Errorf("expected a JsonObject once the option is off")
+	}
+
+	var pool Pool
+	p = pool.Get(`{}`)
+	p.PreserveOrder(true)
+	pool.Put(p)
+	if _, ok := pool.Get(`{}`).ParseValue().(JsonObject); !ok {
+		t.Errorf("expected the pool to clear the option")
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	input := `[123456789012345678901234567890, 0.1234567890123456789, -1e400, 7]`
+	p := NewParser(lexer.NewLexer(input))
+	p.UseNumber(true)
+	arr, ok := p.ParseValue().(JsonArray)
+	if !ok || len(p.Errors()) != 0 {
+		t.Fatalf("expected an array, got %v, errors %v", arr, p.Errors())
+	}
+	expected := JsonArray{Number("123456789012345678901234567890"), Number("0.1234567890123456789"), Number("-1e400"), Number("7")}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Errorf("expected %#v, got %#v", expected, arr)
+	}
+
+	if i, err := arr[0].(Number).BigInt(); err != nil || i.String() != "123456789012345678901234567890" {
+		t.Errorf("expected the exact integer, got %v (%v)", i, err)
+	}
+	if f, err := arr[1].(Number).BigFloat(); err != nil || f.Text('g', 19) != "0.1234567890123456789" {
+		t.Errorf("expected all the digits, got %v (%v)", f, err)
+	}
+	if _, err := arr[1].(Number).BigInt(); err == nil {
+		t.Errorf("expected an error converting a decimal to a big.Int")
+	}
+	if n, err := arr[3].(Number).Int64(); err != nil || n != 7 {
+		t.Errorf("expected 7, got %d (%v)", n, err)
+	}
+
+	for _, bad := range []string{"01", "1.", "-", "1e", "1e+-2", "1-2"} {
+		p.Reset(bad)
+		if v := p.ParseValue(); v != nil || len(p.Errors()) == 0 || p.Errors()[0].Code != InvalidNumber {
+			t.Errorf("%s: expected an invalid number, got %#v, errors %v", bad, v, p.Errors())
+		}
+	}
+
+	var pool Pool
+	p = pool.Get(`1`)
+	p.UseNumber(true)
+	pool.Put(p)
+	if v := pool.Get(`1`).ParseValue(); v != int64(1) {
+		t.Errorf("expected the pool to clear the option, got %#v", v)
+	}
+}
+
+func TestJSON5(t *testing.T) {
+	input := `// settings
+{
+	name: 'gojson',
+	"version": 2.,
+	hex: 0xFF,
+	list: [+1, .5, /* none */],
+	nested: {a: 'it\'s',},
+}`
+	l := lexer.NewLexer(input)
+	l.JSON5(true)
+	p := NewParser(l)
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected errors: %v", p.Errors())
+	}
+	expected := JsonObject{
+		"name":    "gojson",
+		"version": 2.0,
+		"hex":     int64(255),
+		"list":    JsonArray{int64(1), 0.5},
+		"nested":  JsonObject{"a": "it's"},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("expected %#v, got %#v", expected, doc)
+	}
+
+	p.UseNumber(true)
+	p.Reset(`[0x10000000000000000, -.5e3]`)
+	if v := p.ParseValue(); !reflect.DeepEqual(v, JsonArray{Number("18446744073709551616"), Number("-0.5e3")}) || len(p.Errors()) != 0 {
+		t.Errorf("expected numbers in strict JSON form, got %#v, errors %v", v, p.Errors())
+	}
+	p.UseNumber(false)
+
+	for _, tt := range []struct {
+		input    string
+		expected string
+	}{
+		{`{a: Infinity}`, "Infinity has no strict JSON equivalent at line 1, column 13"},
+		{`[1, -NaN]`, "-NaN has no strict JSON equivalent at line 1, column 9"},
+		{`{a: 1, 2: 3}`, "expected string for key at line 1, column 9, got '2'"},
+		{`{a: 'x\1'}`, `unexpected token 'x\1' at line 1, column 9`},
+	} {
+		p.Reset(tt.input)
+		if p.ParseValue(); len(p.Errors()) == 0 || p.Errors()[0].Error() != tt.expected {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.expected, p.Errors())
+		}
+	}
+
+	p = NewParser(lexer.NewLexer(`{"a": 1,}`))
+	if p.ParseValue(); len(p.Errors()) == 0 || p.Errors()[0].Code != TrailingComma {
+		t.Errorf("expected trailing commas to be rejected without JSON5, got %v", p.Errors())
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	input := `{"a": 1, "b": {"a": [0]}, "a": 2, "a": 3}`
+	tests := []struct {
+		policy   DuplicateKeys
+		expected interface{}
+	}{
+		{LastKeyWins, JsonObject{"a": int64(3), "b": JsonObject{"a": JsonArray{int64(0)}}}},
+		{FirstKeyWins, JsonObject{"a": int64(1), "b": JsonObject{"a": JsonArray{int64(0)}}}},
+		{CollectDuplicates, JsonObject{"a": JsonArray{int64(1), int64(2), int64(3)}, "b": JsonObject{"a": JsonArray{int64(0)}}}},
+	}
+	for _, test := range tests {
+		p := NewParser(lexer.NewLexer(input))
+		p.SetDuplicateKeys(test.policy)
+		if got := p.ParseValue(); len(p.Errors()) != 0 || !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("policy %d: expected %v, got %v (errors %v)", test.policy, test.expected, got, p.Errors())
+		}
+	}
+
+	p := NewParser(lexer.NewLexer(`{"b": 1, "a": 2, "b": 3, "a": 4}`))
+	p.SetDuplicateKeys(CollectDuplicates)
+	obj := p.ParseOrdered()
+	if !reflect.DeepEqual(obj.Keys, []string{"b", "a"}) || !reflect.DeepEqual(obj.Values["b"], JsonArray{int64(1), int64(3)}) {
+		t.Errorf("expected the collected values in key order, got %v %v", obj.Keys, obj.Values)
+	}
+
+	p = NewParser(lexer.NewLexer(input))
+	p.SetDuplicateKeys(RejectDuplicates)
+	if got := p.ParseValue(); got != nil {
+		t.Errorf("expected no value, got %v", got)
+	}
+	expected := []string{
+		`duplicate key "a" at line 1, column 29`,
+		`duplicate key "a" at line 1, column 37`,
+	}
+	if !reflect.DeepEqual(messages(p.Errors()), expected) {
+		t.Errorf("expected %q, got %q", expected, p.Errors())
+	}
+	p.Reset(`{"a": 1, "a": 2}`)
+	if p.ParseValue(); len(p.Errors()) != 1 {
+		t.Errorf("expected the policy to be kept by Reset, got %v", p.Errors())
+	}
+}
+
+func TestRecoverErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`{"a" 1, "b": x, "c": [1, , 2], "d": {"e" 3}, "f": 4,}`, []string{
+			"expected next token to be 6, got 10 instead, at line 1, column 4",
+			"unexpected token 'x' at line 1, column 15",
+			"unexpected token ',' at line 1, column 26",
+			"expected next token to be 6, got 10 instead, at line 1, column 40",
+			"No ',' before '}' at line 1, column 52",
+		}},
+		{`[{"a": [1, }, 2], 3]`, []string{
+			"unexpected token '}' at line 1, column 12",
+		}},
+		{`[1, [2, {"a": 3`, []string{
+			"expected '}' at line 1, column 16, got ''",
+		}},
+	}
+	for _, test := range tests {
+		p := NewParser(lexer.NewLexer(test.input))
+		p.RecoverErrors(true)
+		if v := p.ParseValue(); v != nil {
+			t.Errorf("%s: expected no value, got %v", test.input, v)
+		}
+		if !reflect.DeepEqual(messages(p.Errors()), test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.input, test.expected, p.Errors())
+		}
+	}
+
+	p := NewParser(lexer.NewLexer(`{"a" 1, "b": x}`))
+	if p.ParseValue(); len(p.Errors()) != 1 {
+		t.Errorf("expected parsing to stop at the first error by default, got %q", p.Errors())
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	p := NewParser(lexer.NewLexer("{\n  \"a\": x,\n  \"b\" 1}"))
+	p.RecoverErrors(true)
+	p.ParseValue()
+	expected := []ParseError{
+		{Code: UnexpectedToken, Message: "unexpected token 'x'", Line: 2, Column: 9, ByteOffset: 9},
+		{Code: ExpectedToken, Message: "expected next token to be 6, got 10 instead", Line: 3, Column: 5, ByteOffset: 14},
+	}
+	errs := p.Errors()
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i, e := range errs {
+		if e != expected[i] {
+			t.Errorf("errors[%d]: expected %#v, got %#v", i, expected[i], e)
+		}
+	}
+
+	err := error(ErrorList(errs))
+	if err.Error() != "unexpected token 'x' at line 2, column 9; expected next token to be 6, got 10 instead, at line 3, column 5" {
+		t.Errorf("unexpected message %q", err)
+	}
+	var first ParseError
+	if !errors.As(err, &first) || first.Code != UnexpectedToken {
+		t.Errorf("expected errors.As to find the first error, got %+v", first)
+	}
+
+	p = NewParser(lexer.NewLexer(`[1, , , ,]`))
+	p.SetMaxErrors(1)
+	p.RecoverErrors(true)
+	p.ParseValue()
+	errs = p.Errors()
+	if len(errs) != 1 || errs[0].Repeats != 2 || errs[0].Message != "unexpected token ','" {
+		t.Errorf("expected a single repeated error, got %+v", errs)
+	}
+
+	// errors built from their fields, as by callers, read as recorded ones
+	messages := []struct {
+		err      ParseError
+		expected string
+	}{
+		{ParseError{Code: UnexpectedToken, Message: "unexpected token 'x'", Line: 2, Column: 9}, "unexpected token 'x' at line 2, column 9"},
+		{ParseError{Code: ExpectedObjectEnd, Message: "expected '}', got ']'", Line: 1, Column: 7}, "expected '}' at line 1, column 7, got ']'"},
+		{ParseError{Code: ExpectedKey, Message: "expected string for key, got ', got ''", Line: 1, Column: 2}, "expected string for key at line 1, column 2, got ', got ''"},
+		{ParseError{Code: ExpectedToken, Message: "expected next token to be 6, got 10 instead", Line: 3, Column: 5, Repeats: 2}, "expected next token to be 6, got 10 instead, at line 3, column 5 (repeated 2 more times)"},
+		{ParseError{Code: TooManyErrors, Message: "and 3 more errors"}, "and 3 more errors"},
+	}
+	for _, tt := range messages {
+		if got := tt.err.Error(); got != tt.expected {
+			t.Errorf("%+v: expected %q, got %q", tt.err, tt.expected, got)
+		}
+	}
+}
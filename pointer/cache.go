@@ -0,0 +1,37 @@
+package pointer
+
+import "sync"
+
+// maxCachedPointers bounds the number of pointers kept by Compile, so that
+// programs compiling untrusted or generated pointers do not grow without
+// limit.
+const maxCachedPointers = 1024
+
+// cache holds the pointers parsed by Compile, keyed by their textual form.
+var cache = pointerCache{pointers: map[string]Pointer{}}
+
+// pointerCache is a bounded cache of parsed pointers. When full, it is
+// emptied before a new pointer is added, which keeps hot pointers cached at
+// the cost of parsing them once more.
+type pointerCache struct {
+	mu       sync.RWMutex
+	pointers map[string]Pointer
+}
+
+// get returns the cached pointer for s and whether it was found.
+func (c *pointerCache) get(s string) (Pointer, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	p, ok := c.pointers[s]
+	return p, ok
+}
+
+// put adds p to the cache under s.
+func (c *pointerCache) put(s string, p Pointer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pointers) >= maxCachedPointers {
+		c.pointers = map[string]Pointer{}
+	}
+	c.pointers[s] = p
+}
@@ -0,0 +1,329 @@
+// Package pointer implements JSON Pointer (RFC 6901) to address, read and
+// modify values inside documents produced by the parser package, whose keys
+// are decoded: "/a\"b" designates the member written "a\"b" as well as the
+// member written "a\u0022b".
+package pointer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// ErrNotFound is returned, wrapped, when a pointer does not resolve to a value.
+var ErrNotFound = errors.New("pointer: value not found")
+
+// Wildcard is a reference token matching any member or element when a
+// pointer is used as a pattern by Matches, so that "/users/*/password"
+// designates the password of every user.
+const Wildcard = "*"
+
+// Pointer is a parsed JSON Pointer: the list of its unescaped reference tokens.
+// The empty Pointer designates the whole document.
+type Pointer []string
+
+// Parse parses a JSON Pointer such as "/users/0/name", unescaping "~1" into
+// "/" and "~0" into "~" in each reference token.
+func Parse(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("pointer: %q does not start with '/'", s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		unescaped, err := unescape(t)
+		if err != nil {
+			return nil, fmt.Errorf("pointer: %q: %v", s, err)
+		}
+		tokens[i] = unescaped
+	}
+	return Pointer(tokens), nil
+}
+
+// Compile is like Parse, but pointers are cached by their textual form, so
+// compiling the same pointer again, for instance on a hot path, returns the
+// same Pointer without parsing it. The returned Pointer is shared and must not
+// be modified; Append returns a new pointer.
+func Compile(s string) (Pointer, error) {
+	if p, ok := cache.get(s); ok {
+		return p, nil
+	}
+	p, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	p = p[:len(p):len(p)]
+	cache.put(s, p)
+	return p, nil
+}
+
+// MustParse is like Parse but panics if s is not a valid pointer. It eases the
+// declaration of pointers known at compile time.
+func MustParse(s string) Pointer {
+	p, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// unescape decodes the "~0" and "~1" escape sequences of a reference token.
+func unescape(token string) (string, error) {
+	if !strings.Contains(token, "~") {
+		return token, nil
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(token); i++ {
+		if token[i] != '~' {
+			result.WriteByte(token[i])
+			continue
+		}
+		if i+1 == len(token) || (token[i+1] != '0' && token[i+1] != '1') {
+			return "", fmt.Errorf("invalid escape sequence in %q", token)
+		}
+		if token[i+1] == '0' {
+			result.WriteByte('~')
+		} else {
+			result.WriteByte('/')
+		}
+		i++
+	}
+	return result.String(), nil
+}
+
+// escaper escapes the characters that are special in reference tokens.
+var escaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// String returns the textual form of the pointer, escaping "~" and "/".
+func (p Pointer) String() string {
+	var result strings.Builder
+	for _, t := range p {
+		result.WriteByte('/')
+		result.WriteString(escaper.Replace(t))
+	}
+	return result.String()
+}
+
+// Append returns a new pointer made of p followed by the given tokens.
+func (p Pointer) Append(tokens ...string) Pointer {
+	return append(append(Pointer{}, p...), tokens...)
+}
+
+// Matches reports whether p, used as a pattern whose tokens may be
+// wildcards, designates path.
+func (p Pointer) Matches(path Pointer) bool {
+	if len(p) != len(path) {
+		return false
+	}
+	for i := range p {
+		if p[i] != Wildcard && p[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve returns the value designated by the JSON Pointer s inside doc, such
+// as the name of the first user for "/users/0/name". It is Compile followed by
+// Get.
+func Resolve(doc interface{}, s string) (interface{}, error) {
+	p, err := Compile(s)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(doc)
+}
+
+// Get returns the value designated by p inside doc.
+func (p Pointer) Get(doc interface{}) (interface{}, error) {
+	current := doc
+	for i, t := range p {
+		child, err := getChild(current, t)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrNotFound, p[:i+1], err)
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// Set replaces the value designated by p with value and returns the updated
+// document. A missing object member is created, and "-" appends to an array,
+// but the parent of the target must exist. Setting the empty pointer replaces
+// the whole document.
+func (p Pointer) Set(doc interface{}, value interface{}) (interface{}, error) {
+	return p.update(doc, value, func(container interface{}, token string) (interface{}, error) {
+		if arr, ok := container.(parser.JsonArray); ok && token != "-" {
+			index, err := arrayIndex(token, len(arr)-1)
+			if err != nil {
+				return nil, err
+			}
+			arr[index] = value
+			return arr, nil
+		}
+		return addChild(container, token, value)
+	})
+}
+
+// Add inserts value at the location designated by p, following the semantics
+// of the JSON Patch "add" operation: an object member is created or replaced,
+// while array elements at and after the index are shifted to make room. The
+// updated document is returned.
+func (p Pointer) Add(doc interface{}, value interface{}) (interface{}, error) {
+	return p.update(doc, value, func(container interface{}, token string) (interface{}, error) {
+		return addChild(container, token, value)
+	})
+}
+
+// Delete removes the value designated by p and returns the updated document.
+// The whole document cannot be deleted.
+func (p Pointer) Delete(doc interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		return nil, errors.New("pointer: cannot delete the whole document")
+	}
+	return p.update(doc, nil, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case parser.JsonObject:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			delete(c, token)
+			return c, nil
+		case *parser.OrderedObject:
+			if _, ok := c.Get(token); !ok {
+				return nil, fmt.Errorf("no member %q", token)
+			}
+			c.Delete(token)
+			return c, nil
+		case parser.JsonArray:
+			index, err := arrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:index], c[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot delete %q from a %s", token, parser.TypeName(container))
+		}
+	})
+}
+
+// update walks doc down to the parent of the target of p, lets apply modify
+// that parent, and stores the possibly reallocated containers back along the
+// way. The empty pointer replaces the document with root.
+func (p Pointer) update(doc interface{}, root interface{}, apply func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(p) == 0 {
+		return root, nil
+	}
+
+	var walk func(node interface{}, depth int) (interface{}, error)
+	walk = func(node interface{}, depth int) (interface{}, error) {
+		if depth == len(p)-1 {
+			updated, err := apply(node, p[depth])
+			if err != nil {
+				return nil, fmt.Errorf("pointer: %s: %v", p, err)
+			}
+			return updated, nil
+		}
+
+		child, err := getChild(node, p[depth])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrNotFound, p[:depth+1], err)
+		}
+		updated, err := walk(child, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return replaceChild(node, p[depth], updated), nil
+	}
+	return walk(doc, 0)
+}
+
+// getChild returns the member or element of container designated by token.
+func getChild(container interface{}, token string) (interface{}, error) {
+	switch c := container.(type) {
+	case parser.JsonObject:
+		v, ok := c[token]
+		if !ok {
+			return nil, fmt.Errorf("no member %q", token)
+		}
+		return v, nil
+	case *parser.OrderedObject:
+		v, ok := c.Get(token)
+		if !ok {
+			return nil, fmt.Errorf("no member %q", token)
+		}
+		return v, nil
+	case parser.JsonArray:
+		index, err := arrayIndex(token, len(c)-1)
+		if err != nil {
+			return nil, err
+		}
+		return c[index], nil
+	default:
+		return nil, fmt.Errorf("cannot index a %s with %q", parser.TypeName(container), token)
+	}
+}
+
+// replaceChild stores value as the existing child of container designated by
+// token, and returns the container.
+func replaceChild(container interface{}, token string, value interface{}) interface{} {
+	switch c := container.(type) {
+	case parser.JsonObject:
+		c[token] = value
+	case *parser.OrderedObject:
+		c.Set(token, value)
+	case parser.JsonArray:
+		index, _ := arrayIndex(token, len(c)-1)
+		c[index] = value
+	}
+	return container
+}
+
+// addChild adds value to container at token and returns the container, which
+// is reallocated when an array grows.
+func addChild(container interface{}, token string, value interface{}) (interface{}, error) {
+	switch c := container.(type) {
+	case parser.JsonObject:
+		c[token] = value
+		return c, nil
+	case *parser.OrderedObject:
+		c.Set(token, value)
+		return c, nil
+	case parser.JsonArray:
+		if token == "-" {
+			return append(c, value), nil
+		}
+		index, err := arrayIndex(token, len(c))
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, nil)
+		copy(c[index+1:], c[index:])
+		c[index] = value
+		return c, nil
+	default:
+		return nil, fmt.Errorf("cannot add %q to a %s", token, parser.TypeName(container))
+	}
+}
+
+// arrayIndex parses token as an array index no greater than max.
+func arrayIndex(token string, max int) (int, error) {
+	if token == "-" {
+		return 0, errors.New(`"-" designates the element after the end of the array`)
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index > max {
+		return 0, fmt.Errorf("array index %s out of range", token)
+	}
+	return index, nil
+}
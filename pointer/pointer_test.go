@@ -0,0 +1,316 @@
+package pointer
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// rfcDocument is the example document of RFC 6901, section 5.
+const rfcDocument = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8
+}`
+
+func parse(t *testing.T, input string) parser.JsonObject {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.Parse()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func TestParseAndString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Pointer
+	}{
+		{"", Pointer{}},
+		{"/", Pointer{""}},
+		{"/foo/0", Pointer{"foo", "0"}},
+		{"/a~1b", Pointer{"a/b"}},
+		{"/m~0n", Pointer{"m~n"}},
+		{"/~01", Pointer{"~1"}},
+	}
+
+	for i, tt := range tests {
+		p, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(p, tt.expected) {
+			t.Errorf("tests[%d] - pointer wrong. expected=%q, got=%q", i, tt.expected, p)
+		}
+		if p.String() != tt.input {
+			t.Errorf("tests[%d] - string wrong. expected=%q, got=%q", i, tt.input, p.String())
+		}
+	}
+
+	for _, input := range []string{"foo", "/a~", "/a~2"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("expected an error parsing %q", input)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	doc := parse(t, rfcDocument)
+
+	tests := []struct {
+		pointer  string
+		expected interface{}
+	}{
+		{"/foo", parser.JsonArray{"bar", "baz"}},
+		{"/foo/0", "bar"},
+		{"/", int64(0)},
+		{"/a~1b", int64(1)},
+		{"/c%d", int64(2)},
+		{"/e^f", int64(3)},
+		{"/g|h", int64(4)},
+		{`/i\j`, int64(5)},
+		{`/k"l`, int64(6)},
+		{"/ ", int64(7)},
+		{"/m~0n", int64(8)},
+	}
+
+	for i, tt := range tests {
+		value, err := MustParse(tt.pointer).Get(doc)
+		if err != nil {
+			t.Fatalf("tests[%d] - unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(value, tt.expected) {
+			t.Errorf("tests[%d] - value wrong. expected=%v, got=%v", i, tt.expected, value)
+		}
+	}
+
+	for _, ptr := range []string{"/missing", "/foo/2", "/foo/01", "/foo/-", "/foo/0/x"} {
+		if _, err := MustParse(ptr).Get(doc); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound for %q, got %v", ptr, err)
+		}
+	}
+}
+
+func TestSetAddDelete(t *testing.T) {
+	doc := parse(t, `{"users": [{"name": "John"}, {"name": "Jane"}], "count": 2}`)
+
+	steps := []struct {
+		op      string
+		pointer string
+		value   interface{}
+	}{
+		{"set", "/users/0/name", "Johnny"},
+		{"set", "/users/1/age", int64(30)},
+		{"add", "/users/1", parser.JsonObject{"name": "Jim"}},
+		{"add", "/users/-", "last"},
+		{"set", "/users/-", "very last"},
+		{"delete", "/count", nil},
+		{"delete", "/users/4", nil},
+	}
+
+	var result interface{} = doc
+	for i, s := range steps {
+		var err error
+		switch s.op {
+		case "set":
+			result, err = MustParse(s.pointer).Set(result, s.value)
+		case "add":
+			result, err = MustParse(s.pointer).Add(result, s.value)
+		case "delete":
+			result, err = MustParse(s.pointer).Delete(result)
+		}
+		if err != nil {
+			t.Fatalf("steps[%d] - unexpected error: %v", i, err)
+		}
+	}
+
+	expected := parser.JsonObject{
+		"users": parser.JsonArray{
+			parser.JsonObject{"name": "Johnny"},
+			parser.JsonObject{"name": "Jim"},
+			parser.JsonObject{"name": "Jane", "age": int64(30)},
+			"last",
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("document is not as expected. Got %+v, want %+v", result, expected)
+	}
+}
+
+func TestMutationErrors(t *testing.T) {
+	doc := parse(t, `{"a": [1], "s": "text"}`)
+
+	if _, err := MustParse("/a/5").Set(doc, 1); err == nil {
+		t.Errorf("expected an error setting an out of range index")
+	}
+	if _, err := MustParse("/a/2").Add(doc, 1); err == nil {
+		t.Errorf("expected an error adding past the end of an array")
+	}
+	if _, err := MustParse("/s/x").Add(doc, 1); err == nil {
+		t.Errorf("expected an error adding to a string")
+	}
+	if _, err := MustParse("/missing/x").Set(doc, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing parent, got %v", err)
+	}
+	if _, err := MustParse("").Delete(doc); err == nil {
+		t.Errorf("expected an error deleting the whole document")
+	}
+
+	replaced, err := MustParse("").Set(doc, "root")
+	if err != nil || replaced != "root" {
+		t.Errorf("expected the empty pointer to replace the document, got %v, %v", replaced, err)
+	}
+}
+
+func TestNumberValues(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`{"n": 123456789012345678901}`))
+	p.UseNumber(true)
+	doc := p.Parse()
+
+	if v, err := MustParse("/n").Get(doc); err != nil || v != parser.Number("123456789012345678901") {
+		t.Errorf("expected the Number value, got %v, %v", v, err)
+	}
+	if _, err := MustParse("/n/x").Add(doc, 1); err == nil || err.Error() != `pointer: /n/x: cannot add "x" to a number` {
+		t.Errorf("expected an error naming the number, got %v", err)
+	}
+	if _, err := MustParse("/n/0").Get(doc); err == nil || err.Error() != `pointer: value not found: /n/0: cannot index a number with "0"` {
+		t.Errorf("expected an error naming the number, got %v", err)
+	}
+}
+
+func TestOrderedObject(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`{"b": {"y": 1, "x": 2}, "a": 3}`))
+	doc := p.ParseOrdered()
+
+	if v, err := MustParse("/b/x").Get(doc); err != nil || v != int64(2) {
+		t.Errorf("expected 2, got %v, %v", v, err)
+	}
+	if _, err := MustParse("/b/y").Delete(doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := MustParse("/b/z").Add(doc, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inner := doc.Values["b"].(*parser.OrderedObject)
+	if !reflect.DeepEqual(inner.Keys, []string{"x", "z"}) {
+		t.Errorf("keys are not as expected, got %v", inner.Keys)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	doc := parse(t, `{"users": [{"name": "Ana"}], "caf\u00e9": {"a\/b": true}}`)
+
+	tests := []struct {
+		pointer  string
+		expected interface{}
+	}{
+		{"", doc},
+		{"/users/0/name", "Ana"},
+		{"/café/a~1b", true},
+	}
+	for _, tt := range tests {
+		value, err := Resolve(doc, tt.pointer)
+		if err != nil || !reflect.DeepEqual(value, tt.expected) {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.pointer, tt.expected, value, err)
+		}
+	}
+
+	if _, err := Resolve(doc, "users"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+	if _, err := Resolve(doc, "/users/1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	p1, err := Compile("/users/0/name")
+	if err != nil {
+		t.Fatalf("Compile: unexpected error: %v", err)
+	}
+	p2, err := Compile("/users/0/name")
+	if err != nil {
+		t.Fatalf("Compile: unexpected error: %v", err)
+	}
+	if &p1[0] != &p2[0] {
+		t.Errorf("Compile: expected the cached pointer to be returned")
+	}
+	if extended := p1.Append("first"); extended.String() != "/users/0/name/first" || p2.String() != "/users/0/name" {
+		t.Errorf("Append: got %q, cached pointer became %q", extended, p2)
+	}
+	if _, err := Compile("users"); err == nil {
+		t.Errorf("Compile: expected an error")
+	}
+
+	for i := 0; i <= maxCachedPointers; i++ {
+		if _, err := Compile("/" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("Compile: unexpected error: %v", err)
+		}
+	}
+	if n := len(cache.pointers); n > maxCachedPointers {
+		t.Errorf("cache holds %d pointers, expected at most %d", n, maxCachedPointers)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		expected      bool
+	}{
+		{"/users/*/password", "/users/0/password", true},
+		{"/users/*/password", "/users/alice/password", true},
+		{"/users/*/password", "/users/0/name", false},
+		{"/users/*", "/users/0/password", false},
+		{"/*", "/~1", true},
+		{"/a~1b", "/a~1b", true},
+		{"/a~1b", "/a/b", false},
+		{"", "", true},
+		{"", "/a", false},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.pattern).Matches(MustParse(tt.path)); got != tt.expected {
+			t.Errorf("%q.Matches(%q): expected %v, got %v", tt.pattern, tt.path, tt.expected, got)
+		}
+	}
+}
+
+func TestEscapedKeys(t *testing.T) {
+	doc := parse(t, `{"caf\u00e9": 1, "k\"l": [1]}`)
+
+	if _, err := MustParse("/café").Set(doc, int64(2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := MustParse(`/k"l/-`).Add(doc, int64(2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := MustParse("/a\tb").Set(doc, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := parser.JsonObject{"café": int64(2), `k"l`: parser.JsonArray{int64(1), int64(2)}, "a\tb": true}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("expected the decoded keys, got %+v", doc)
+	}
+
+	if _, err := MustParse("/café").Delete(doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := doc["café"]; ok {
+		t.Errorf("expected the member to be deleted, got %+v", doc)
+	}
+	if v, err := Resolve(parse(t, `{"a\u0022b": 1}`), `/a"b`); err != nil || v != int64(1) {
+		t.Errorf("expected the member written with a unicode escape, got %v (%v)", v, err)
+	}
+}
@@ -0,0 +1,555 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// builtin implements a function. Arguments are unevaluated expressions, so
+// that functions like map and select can run them against each element.
+type builtin func(input interface{}, args []expr) ([]interface{}, error)
+
+// builtinKey identifies a builtin by name and number of arguments.
+type builtinKey struct {
+	name  string
+	arity int
+}
+
+// builtins holds the functions available in expressions.
+var builtins = map[builtinKey]builtin{
+	{"empty", 0}:          func(interface{}, []expr) ([]interface{}, error) { return nil, nil },
+	{"not", 0}:            simple(func(v interface{}) (interface{}, error) { return !isTruthy(v), nil }),
+	{"type", 0}:           simple(func(v interface{}) (interface{}, error) { return parser.TypeName(v), nil }),
+	{"arrays", 0}:         typeFilter("array"),
+	{"objects", 0}:        typeFilter("object"),
+	{"strings", 0}:        typeFilter("string"),
+	{"numbers", 0}:        typeFilter("number"),
+	{"booleans", 0}:       typeFilter("boolean"),
+	{"nulls", 0}:          typeFilter("null"),
+	{"length", 0}:         simple(length),
+	{"keys", 0}:           simple(keys(true)),
+	{"keys_unsorted", 0}:  simple(keys(false)),
+	{"add", 0}:            simple(add),
+	{"any", 0}:            simple(anyAll(true)),
+	{"all", 0}:            simple(anyAll(false)),
+	{"flatten", 0}:        simple(flatten),
+	{"reverse", 0}:        simple(reverse),
+	{"sort", 0}:           simple(sortValues),
+	{"unique", 0}:         simple(unique),
+	{"min", 0}:            simple(extreme(-1)),
+	{"max", 0}:            simple(extreme(1)),
+	{"first", 0}:          simple(func(v interface{}) (interface{}, error) { return indexValue(v, int64(0)) }),
+	{"last", 0}:           simple(func(v interface{}) (interface{}, error) { return indexValue(v, int64(-1)) }),
+	{"floor", 0}:          simple(mathFunc("floor", math.Floor)),
+	{"ceil", 0}:           simple(mathFunc("ceil", math.Ceil)),
+	{"round", 0}:          simple(mathFunc("round", math.Round)),
+	{"sqrt", 0}:           simple(mathFunc("sqrt", math.Sqrt)),
+	{"tostring", 0}:       simple(tostring),
+	{"tonumber", 0}:       simple(tonumber),
+	{"ascii_downcase", 0}: simple(stringFunc("ascii_downcase", strings.ToLower)),
+	{"ascii_upcase", 0}:   simple(stringFunc("ascii_upcase", strings.ToUpper)),
+	{"to_entries", 0}:     simple(toEntries),
+	{"from_entries", 0}:   simple(fromEntries),
+	{"map", 1}:            mapFunc,
+	{"map_values", 1}:     mapValues,
+	{"select", 1}:         selectFunc,
+	{"sort_by", 1}:        sortBy,
+	{"range", 1}:          rangeFunc,
+	{"has", 1}:            withArg(has),
+	{"contains", 1}:       withArg(containsFunc),
+	{"split", 1}:          withArg(stringArg("split", func(s, sep string) (interface{}, error) { return stringsArray(strings.Split(s, sep)), nil })),
+	{"join", 1}:           withArg(join),
+	{"startswith", 1}:     withArg(stringArg("startswith", func(s, p string) (interface{}, error) { return strings.HasPrefix(s, p), nil })),
+	{"endswith", 1}:       withArg(stringArg("endswith", func(s, p string) (interface{}, error) { return strings.HasSuffix(s, p), nil })),
+	{"ltrimstr", 1}:       withArg(trim(strings.TrimPrefix)),
+	{"rtrimstr", 1}:       withArg(trim(strings.TrimSuffix)),
+	{"test", 1}:           withArg(stringArg("test", test)),
+}
+
+// simple adapts a function of the input alone into a builtin.
+func simple(f func(v interface{}) (interface{}, error)) builtin {
+	return func(input interface{}, args []expr) ([]interface{}, error) {
+		v, err := f(input)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{v}, nil
+	}
+}
+
+// withArg adapts a function of the input and of one argument value into a
+// builtin, called once per output of the argument.
+func withArg(f func(v, arg interface{}) (interface{}, error)) builtin {
+	return func(input interface{}, args []expr) ([]interface{}, error) {
+		values, err := args[0].eval(input)
+		if err != nil {
+			return nil, err
+		}
+		outputs := make([]interface{}, 0, len(values))
+		for _, arg := range values {
+			v, err := f(input, arg)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, v)
+		}
+		return outputs, nil
+	}
+}
+
+// typeFilter returns a builtin producing its input only when it is of the
+// given JSON type.
+func typeFilter(name string) builtin {
+	return func(input interface{}, args []expr) ([]interface{}, error) {
+		if parser.TypeName(input) != name {
+			return nil, nil
+		}
+		return []interface{}{input}, nil
+	}
+}
+
+// stringArg adapts a function of two strings, the input and the argument.
+func stringArg(name string, f func(s, arg string) (interface{}, error)) func(v, arg interface{}) (interface{}, error) {
+	return func(v, arg interface{}) (interface{}, error) {
+		s, ok1 := v.(string)
+		a, ok2 := arg.(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("query: %s cannot be applied to %s and %s", name, parser.TypeName(v), parser.TypeName(arg))
+		}
+		return f(s, a)
+	}
+}
+
+// stringFunc adapts a string transformation.
+func stringFunc(name string, f func(string) string) func(v interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("query: %s cannot be applied to %s", name, parser.TypeName(v))
+		}
+		return f(s), nil
+	}
+}
+
+// mathFunc adapts a numeric function.
+func mathFunc(name string, f func(float64) float64) func(v interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		n, ok := asNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("query: %s cannot be applied to %s", name, parser.TypeName(v))
+		}
+		return number(f(n)), nil
+	}
+}
+
+func length(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case nil:
+		return int64(0), nil
+	case bool:
+		return nil, fmt.Errorf("query: boolean has no length")
+	case int64:
+		if x < 0 {
+			return -x, nil
+		}
+		return x, nil
+	case float64:
+		return math.Abs(x), nil
+	case parser.Number:
+		f, _ := asNumber(x)
+		return number(math.Abs(f)), nil
+	case string:
+		return int64(utf8.RuneCountInString(x)), nil
+	case parser.JsonArray:
+		return int64(len(x)), nil
+	}
+	k, _, _ := parser.Members(v)
+	return int64(len(k)), nil
+}
+
+func keys(sorted bool) func(v interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		if arr, ok := v.(parser.JsonArray); ok {
+			indexes := make(parser.JsonArray, len(arr))
+			for i := range arr {
+				indexes[i] = int64(i)
+			}
+			return indexes, nil
+		}
+		k, _, ok := parser.Members(v)
+		if !ok {
+			return nil, fmt.Errorf("query: %s has no keys", parser.TypeName(v))
+		}
+		if sorted {
+			k = sortedCopy(k)
+		}
+		return stringsArray(k), nil
+	}
+}
+
+func add(v interface{}) (interface{}, error) {
+	values, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	var sum interface{}
+	for _, e := range values {
+		if sum, err = applyOperator("+", sum, e); err != nil {
+			return nil, err
+		}
+	}
+	return sum, nil
+}
+
+func anyAll(isAny bool) func(v interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		values, err := iterate(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range values {
+			if isTruthy(e) == isAny {
+				return isAny, nil
+			}
+		}
+		return !isAny, nil
+	}
+}
+
+func flatten(v interface{}) (interface{}, error) {
+	arr, ok := v.(parser.JsonArray)
+	if !ok {
+		return nil, fmt.Errorf("query: cannot flatten %s", parser.TypeName(v))
+	}
+	result := parser.JsonArray{}
+	for _, e := range arr {
+		if inner, ok := e.(parser.JsonArray); ok {
+			flat, _ := flatten(inner)
+			result = append(result, flat.(parser.JsonArray)...)
+		} else {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func reverse(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case nil:
+		return parser.JsonArray{}, nil
+	case string:
+		r := []rune(x)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r), nil
+	case parser.JsonArray:
+		result := make(parser.JsonArray, len(x))
+		for i, e := range x {
+			result[len(x)-1-i] = e
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("query: cannot reverse %s", parser.TypeName(v))
+}
+
+func sortValues(v interface{}) (interface{}, error) {
+	arr, ok := v.(parser.JsonArray)
+	if !ok {
+		return nil, fmt.Errorf("query: cannot sort %s", parser.TypeName(v))
+	}
+	result := append(parser.JsonArray{}, arr...)
+	sort.SliceStable(result, func(i, j int) bool { return compare(result[i], result[j]) < 0 })
+	return result, nil
+}
+
+func unique(v interface{}) (interface{}, error) {
+	sorted, err := sortValues(v)
+	if err != nil {
+		return nil, err
+	}
+	result := parser.JsonArray{}
+	for _, e := range sorted.(parser.JsonArray) {
+		if len(result) == 0 || !equal(result[len(result)-1], e) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func extreme(sign int) func(v interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		arr, ok := v.(parser.JsonArray)
+		if !ok {
+			return nil, fmt.Errorf("query: %s has no minimum or maximum", parser.TypeName(v))
+		}
+		var best interface{}
+		for i, e := range arr {
+			if i == 0 || compare(e, best)*sign > 0 {
+				best = e
+			}
+		}
+		return best, nil
+	}
+}
+
+func tostring(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), nil
+	case parser.Number:
+		return x.String(), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case nil:
+		return "null", nil
+	}
+	return nil, fmt.Errorf("query: cannot convert %s to string", parser.TypeName(v))
+}
+
+func tonumber(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case int64, float64, parser.Number:
+		return x, nil
+	case string:
+		n, err := parser.ParseNumber(x)
+		if err != nil {
+			return nil, fmt.Errorf("query: cannot convert %q to number", x)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("query: cannot convert %s to number", parser.TypeName(v))
+}
+
+func toEntries(v interface{}) (interface{}, error) {
+	k, members, ok := parser.Members(v)
+	if !ok {
+		return nil, fmt.Errorf("query: cannot convert %s to entries", parser.TypeName(v))
+	}
+	entries := make(parser.JsonArray, len(k))
+	for i, key := range k {
+		entry := parser.NewOrderedObject()
+		entry.Set("key", key)
+		entry.Set("value", members[key])
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+func fromEntries(v interface{}) (interface{}, error) {
+	arr, ok := v.(parser.JsonArray)
+	if !ok {
+		return nil, fmt.Errorf("query: cannot build an object from %s", parser.TypeName(v))
+	}
+	obj := parser.NewOrderedObject()
+	for _, e := range arr {
+		_, entry, ok := parser.Members(e)
+		if !ok {
+			return nil, fmt.Errorf("query: entries must be objects, got %s", parser.TypeName(e))
+		}
+		key, ok := entry["key"].(string)
+		if !ok {
+			return nil, fmt.Errorf("query: entry keys must be strings, got %s", parser.TypeName(entry["key"]))
+		}
+		obj.Set(key, entry["value"])
+	}
+	return obj, nil
+}
+
+func has(v, key interface{}) (interface{}, error) {
+	if _, members, ok := parser.Members(v); ok {
+		k, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot check whether object has a %s key", parser.TypeName(key))
+		}
+		_, found := members[k]
+		return found, nil
+	}
+	if arr, ok := v.(parser.JsonArray); ok {
+		n, ok := asNumber(key)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot check whether array has a %s key", parser.TypeName(key))
+		}
+		return n >= 0 && int(n) < len(arr), nil
+	}
+	return nil, fmt.Errorf("query: cannot check whether %s has a key", parser.TypeName(v))
+}
+
+func containsFunc(v, x interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		sub, ok := x.(string)
+		if !ok {
+			return nil, fmt.Errorf("query: string cannot contain %s", parser.TypeName(x))
+		}
+		return strings.Contains(s, sub), nil
+	}
+	if arr, ok := v.(parser.JsonArray); ok {
+		if sub, ok := x.(parser.JsonArray); ok {
+			for _, e := range sub {
+				if !contains(arr, e) {
+					return false, nil
+				}
+			}
+			return true, nil
+		}
+	}
+	return equal(v, x), nil
+}
+
+func join(v, sep interface{}) (interface{}, error) {
+	arr, ok := v.(parser.JsonArray)
+	s, ok2 := sep.(string)
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("query: cannot join %s with %s", parser.TypeName(v), parser.TypeName(sep))
+	}
+	parts := make([]string, len(arr))
+	for i, e := range arr {
+		if e == nil {
+			continue
+		}
+		str, err := tostring(e)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = str.(string)
+	}
+	return strings.Join(parts, s), nil
+}
+
+func trim(f func(s, fix string) string) func(v, arg interface{}) (interface{}, error) {
+	return func(v, arg interface{}) (interface{}, error) {
+		s, ok1 := v.(string)
+		fix, ok2 := arg.(string)
+		if !ok1 || !ok2 {
+			return v, nil
+		}
+		return f(s, fix), nil
+	}
+}
+
+func test(s, pattern string) (interface{}, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("query: invalid regular expression %q: %v", pattern, err)
+	}
+	return re.MatchString(s), nil
+}
+
+func mapFunc(input interface{}, args []expr) ([]interface{}, error) {
+	values, err := iterate(input)
+	if err != nil {
+		return nil, err
+	}
+	result := parser.JsonArray{}
+	for _, v := range values {
+		out, err := args[0].eval(v)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, out...)
+	}
+	return []interface{}{result}, nil
+}
+
+func mapValues(input interface{}, args []expr) ([]interface{}, error) {
+	if arr, ok := input.(parser.JsonArray); ok {
+		result := parser.JsonArray{}
+		for _, v := range arr {
+			out, err := args[0].eval(v)
+			if err != nil {
+				return nil, err
+			}
+			if len(out) > 0 {
+				result = append(result, out[0])
+			}
+		}
+		return []interface{}{result}, nil
+	}
+
+	k, members, ok := parser.Members(input)
+	if !ok {
+		return nil, fmt.Errorf("query: cannot map values of %s", parser.TypeName(input))
+	}
+	result := parser.NewOrderedObject()
+	for _, key := range k {
+		out, err := args[0].eval(members[key])
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > 0 {
+			result.Set(key, out[0])
+		}
+	}
+	return []interface{}{result}, nil
+}
+
+func selectFunc(input interface{}, args []expr) ([]interface{}, error) {
+	conds, err := args[0].eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, c := range conds {
+		if isTruthy(c) {
+			outputs = append(outputs, input)
+		}
+	}
+	return outputs, nil
+}
+
+func sortBy(input interface{}, args []expr) ([]interface{}, error) {
+	arr, ok := input.(parser.JsonArray)
+	if !ok {
+		return nil, fmt.Errorf("query: cannot sort %s", parser.TypeName(input))
+	}
+
+	sortKeys := make([]interface{}, len(arr))
+	for i, v := range arr {
+		out, err := args[0].eval(v)
+		if err != nil {
+			return nil, err
+		}
+		sortKeys[i] = append(parser.JsonArray{}, out...)
+	}
+
+	indexes := make([]int, len(arr))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool { return compare(sortKeys[indexes[i]], sortKeys[indexes[j]]) < 0 })
+
+	result := make(parser.JsonArray, len(arr))
+	for i, index := range indexes {
+		result[i] = arr[index]
+	}
+	return []interface{}{result}, nil
+}
+
+func rangeFunc(input interface{}, args []expr) ([]interface{}, error) {
+	limits, err := args[0].eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, l := range limits {
+		n, ok := asNumber(l)
+		if !ok {
+			return nil, fmt.Errorf("query: range limit must be a number, got %s", parser.TypeName(l))
+		}
+		for i := int64(0); float64(i) < n; i++ {
+			outputs = append(outputs, i)
+		}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,36 @@
+package query
+
+import "sync"
+
+// maxCachedQueries bounds the number of compiled queries kept by Compile, so
+// that programs compiling untrusted or generated expressions do not grow
+// without limit.
+const maxCachedQueries = 1024
+
+// cache holds the queries compiled by Compile, keyed by source.
+var cache = queryCache{queries: map[string]*Query{}}
+
+// queryCache is a bounded cache of compiled queries. When full, it is
+// emptied before a new query is added, which keeps hot expressions cached at
+// the cost of recompiling them once.
+type queryCache struct {
+	mu      sync.RWMutex
+	queries map[string]*Query
+}
+
+// get returns the cached query for src, or nil.
+func (c *queryCache) get(src string) *Query {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.queries[src]
+}
+
+// put adds q to the cache.
+func (c *queryCache) put(q *Query) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.queries) >= maxCachedQueries {
+		c.queries = map[string]*Query{}
+	}
+	c.queries[q.src] = q
+}
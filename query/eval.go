@@ -0,0 +1,595 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// expr is a node of a compiled expression. Evaluating it against an input
+// produces zero, one or several outputs.
+type expr interface {
+	eval(input interface{}) ([]interface{}, error)
+}
+
+// identityExpr is ".", which outputs its input.
+type identityExpr struct{}
+
+func (identityExpr) eval(input interface{}) ([]interface{}, error) {
+	return []interface{}{input}, nil
+}
+
+// recurseExpr is "..", which outputs its input and every value nested in it.
+type recurseExpr struct{}
+
+func (recurseExpr) eval(input interface{}) ([]interface{}, error) {
+	var outputs []interface{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		outputs = append(outputs, v)
+		if arr, ok := v.(parser.JsonArray); ok {
+			for _, e := range arr {
+				walk(e)
+			}
+		} else if keys, members, ok := parser.Members(v); ok {
+			for _, k := range keys {
+				walk(members[k])
+			}
+		}
+	}
+	walk(input)
+	return outputs, nil
+}
+
+// literalExpr outputs a constant value.
+type literalExpr struct {
+	value interface{}
+}
+
+func (e *literalExpr) eval(input interface{}) ([]interface{}, error) {
+	return []interface{}{e.value}, nil
+}
+
+// pipeExpr feeds every output of left to right.
+type pipeExpr struct {
+	left, right expr
+}
+
+func (e *pipeExpr) eval(input interface{}) ([]interface{}, error) {
+	values, err := e.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, v := range values {
+		out, err := e.right.eval(v)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out...)
+	}
+	return outputs, nil
+}
+
+// commaExpr outputs the outputs of left followed by those of right.
+type commaExpr struct {
+	left, right expr
+}
+
+func (e *commaExpr) eval(input interface{}) ([]interface{}, error) {
+	left, err := e.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.right.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	return append(left, right...), nil
+}
+
+// alternativeExpr is "a // b": the outputs of a that are neither false nor
+// null, or the outputs of b if there are none.
+type alternativeExpr struct {
+	left, right expr
+}
+
+func (e *alternativeExpr) eval(input interface{}) ([]interface{}, error) {
+	left, err := e.left.eval(input)
+	var outputs []interface{}
+	if err == nil {
+		for _, v := range left {
+			if isTruthy(v) {
+				outputs = append(outputs, v)
+			}
+		}
+	}
+	if len(outputs) > 0 {
+		return outputs, nil
+	}
+	return e.right.eval(input)
+}
+
+// tryExpr is "e?", which discards the errors raised by e.
+type tryExpr struct {
+	body expr
+}
+
+func (e *tryExpr) eval(input interface{}) ([]interface{}, error) {
+	outputs, err := e.body.eval(input)
+	if err != nil {
+		return nil, nil
+	}
+	return outputs, nil
+}
+
+// indexExpr is ".name", ".[index]" or "target[index]".
+type indexExpr struct {
+	target, index expr
+}
+
+func (e *indexExpr) eval(input interface{}) ([]interface{}, error) {
+	return cartesian(input, e.target, e.index, func(target, index interface{}) (interface{}, error) {
+		return indexValue(target, index)
+	})
+}
+
+// indexValue returns target[index], or null when the member or element does
+// not exist.
+func indexValue(target, index interface{}) (interface{}, error) {
+	if target == nil {
+		return nil, nil
+	}
+	if _, members, ok := parser.Members(target); ok {
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot index object with %s", parser.TypeName(index))
+		}
+		return members[key], nil
+	}
+	if arr, ok := target.(parser.JsonArray); ok {
+		f, ok := asNumber(index)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot index array with %s", parser.TypeName(index))
+		}
+		i := int(math.Floor(f))
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return nil, nil
+		}
+		return arr[i], nil
+	}
+	if s, ok := index.(string); ok {
+		return nil, fmt.Errorf("query: cannot index %s with %q", parser.TypeName(target), s)
+	}
+	return nil, fmt.Errorf("query: cannot index %s with %s", parser.TypeName(target), parser.TypeName(index))
+}
+
+// sliceExpr is "target[from:to]" on arrays and strings.
+type sliceExpr struct {
+	target, from, to expr
+}
+
+func (e *sliceExpr) eval(input interface{}) ([]interface{}, error) {
+	targets, err := e.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, target := range targets {
+		var length int
+		switch t := target.(type) {
+		case nil:
+			outputs = append(outputs, nil)
+			continue
+		case parser.JsonArray:
+			length = len(t)
+		case string:
+			length = len(t)
+		default:
+			return nil, fmt.Errorf("query: cannot slice %s", parser.TypeName(target))
+		}
+
+		from, err := e.bound(input, e.from, 0, length)
+		if err != nil {
+			return nil, err
+		}
+		to, err := e.bound(input, e.to, length, length)
+		if err != nil {
+			return nil, err
+		}
+		to = max(to, from)
+
+		if arr, ok := target.(parser.JsonArray); ok {
+			outputs = append(outputs, append(parser.JsonArray{}, arr[from:to]...))
+		} else {
+			outputs = append(outputs, target.(string)[from:to])
+		}
+	}
+	return outputs, nil
+}
+
+// bound evaluates a slice bound, defaulting to def and clamping negative
+// values relative to length.
+func (e *sliceExpr) bound(input interface{}, b expr, def, length int) (int, error) {
+	if b == nil {
+		return def, nil
+	}
+	values, err := b.eval(input)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) != 1 {
+		return 0, fmt.Errorf("query: slice bounds must produce a single value")
+	}
+	f, ok := asNumber(values[0])
+	if !ok {
+		return 0, fmt.Errorf("query: slice bounds must be numbers, got %s", parser.TypeName(values[0]))
+	}
+	i := int(math.Floor(f))
+	if i < 0 {
+		i += length
+	}
+	return min(max(i, 0), length), nil
+}
+
+// iterateExpr is "target[]", which outputs every element or member value.
+type iterateExpr struct {
+	target expr
+}
+
+func (e *iterateExpr) eval(input interface{}) ([]interface{}, error) {
+	targets, err := e.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, target := range targets {
+		values, err := iterate(target)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, values...)
+	}
+	return outputs, nil
+}
+
+// iterate returns the elements of an array or the member values of an object.
+func iterate(v interface{}) ([]interface{}, error) {
+	if arr, ok := v.(parser.JsonArray); ok {
+		return append([]interface{}(nil), arr...), nil
+	}
+	if keys, members, ok := parser.Members(v); ok {
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = members[k]
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("query: cannot iterate over %s", parser.TypeName(v))
+}
+
+// arrayExpr is "[body]", which collects the outputs of body into an array.
+type arrayExpr struct {
+	body expr
+}
+
+func (e *arrayExpr) eval(input interface{}) ([]interface{}, error) {
+	arr := parser.JsonArray{}
+	if e.body != nil {
+		values, err := e.body.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, values...)
+	}
+	return []interface{}{arr}, nil
+}
+
+// objectEntry is a "key: value" pair of an object construction.
+type objectEntry struct {
+	key, value expr
+}
+
+// objectExpr is "{k1: v1, ...}". When keys or values produce several outputs,
+// one object is output per combination.
+type objectExpr struct {
+	entries []objectEntry
+}
+
+func (e *objectExpr) eval(input interface{}) ([]interface{}, error) {
+	objects := []*parser.OrderedObject{parser.NewOrderedObject()}
+	for _, entry := range e.entries {
+		keys, err := entry.key.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		values, err := entry.value.eval(input)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []*parser.OrderedObject
+		for _, obj := range objects {
+			for _, k := range keys {
+				key, ok := k.(string)
+				if !ok {
+					return nil, fmt.Errorf("query: object keys must be strings, got %s", parser.TypeName(k))
+				}
+				for _, v := range values {
+					o := parser.NewOrderedObject()
+					for _, existing := range obj.Keys {
+						o.Set(existing, obj.Values[existing])
+					}
+					o.Set(key, v)
+					next = append(next, o)
+				}
+			}
+		}
+		objects = next
+	}
+
+	outputs := make([]interface{}, len(objects))
+	for i, o := range objects {
+		outputs[i] = o
+	}
+	return outputs, nil
+}
+
+// negateExpr is "-e".
+type negateExpr struct {
+	operand expr
+}
+
+func (e *negateExpr) eval(input interface{}) ([]interface{}, error) {
+	values, err := e.operand.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]interface{}, len(values))
+	for i, v := range values {
+		f, ok := asNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot negate %s", parser.TypeName(v))
+		}
+		if n, ok := integer(v); ok {
+			outputs[i] = -n
+		} else {
+			outputs[i] = -f
+		}
+	}
+	return outputs, nil
+}
+
+// logicalExpr is "a and b" or "a or b", evaluated with short-circuit.
+type logicalExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e *logicalExpr) eval(input interface{}) ([]interface{}, error) {
+	left, err := e.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, l := range left {
+		if e.op == "and" && !isTruthy(l) || e.op == "or" && isTruthy(l) {
+			outputs = append(outputs, isTruthy(l))
+			continue
+		}
+		right, err := e.right.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range right {
+			outputs = append(outputs, isTruthy(r))
+		}
+	}
+	return outputs, nil
+}
+
+// ifExpr is "if cond then a else b end". Without else, the input is output.
+type ifExpr struct {
+	cond, then, otherwise expr
+}
+
+func (e *ifExpr) eval(input interface{}) ([]interface{}, error) {
+	conds, err := e.cond.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, c := range conds {
+		branch := e.otherwise
+		if isTruthy(c) {
+			branch = e.then
+		}
+		if branch == nil {
+			outputs = append(outputs, input)
+			continue
+		}
+		values, err := branch.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, values...)
+	}
+	return outputs, nil
+}
+
+// binaryExpr is an arithmetic or comparison operator.
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e *binaryExpr) eval(input interface{}) ([]interface{}, error) {
+	return cartesian(input, e.left, e.right, func(l, r interface{}) (interface{}, error) {
+		return applyOperator(e.op, l, r)
+	})
+}
+
+// cartesian evaluates a and b against input and combines every pair of their
+// outputs with f.
+func cartesian(input interface{}, a, b expr, f func(x, y interface{}) (interface{}, error)) ([]interface{}, error) {
+	xs, err := a.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	ys, err := b.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	var outputs []interface{}
+	for _, x := range xs {
+		for _, y := range ys {
+			v, err := f(x, y)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, v)
+		}
+	}
+	return outputs, nil
+}
+
+// applyOperator computes "l op r".
+func applyOperator(op string, l, r interface{}) (interface{}, error) {
+	switch op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<":
+		return compare(l, r) < 0, nil
+	case "<=":
+		return compare(l, r) <= 0, nil
+	case ">":
+		return compare(l, r) > 0, nil
+	case ">=":
+		return compare(l, r) >= 0, nil
+	}
+
+	if fl, ok := asNumber(l); ok {
+		if fr, ok := asNumber(r); ok {
+			return arithmetic(op, l, r, fl, fr)
+		}
+	}
+
+	switch op {
+	case "+":
+		if l == nil {
+			return r, nil
+		}
+		if r == nil {
+			return l, nil
+		}
+		switch x := l.(type) {
+		case string:
+			if y, ok := r.(string); ok {
+				return x + y, nil
+			}
+		case parser.JsonArray:
+			if y, ok := r.(parser.JsonArray); ok {
+				return append(append(parser.JsonArray{}, x...), y...), nil
+			}
+		}
+		if lk, lm, ok := parser.Members(l); ok {
+			if rk, rm, ok := parser.Members(r); ok {
+				merged := parser.NewOrderedObject()
+				for _, k := range lk {
+					merged.Set(k, lm[k])
+				}
+				for _, k := range rk {
+					merged.Set(k, rm[k])
+				}
+				return merged, nil
+			}
+		}
+	case "-":
+		if x, ok := l.(parser.JsonArray); ok {
+			if y, ok := r.(parser.JsonArray); ok {
+				result := parser.JsonArray{}
+				for _, v := range x {
+					if !contains(y, v) {
+						result = append(result, v)
+					}
+				}
+				return result, nil
+			}
+		}
+	case "/":
+		if x, ok := l.(string); ok {
+			if y, ok := r.(string); ok {
+				return stringsArray(strings.Split(x, y)), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("query: %s and %s cannot be combined with %s", parser.TypeName(l), parser.TypeName(r), op)
+}
+
+// arithmetic applies an arithmetic operator to two numbers, keeping integers
+// when both operands are integers and the result is exact.
+func arithmetic(op string, l, r interface{}, fl, fr float64) (interface{}, error) {
+	li, lInt := integer(l)
+	ri, rInt := integer(r)
+	ints := lInt && rInt
+
+	switch op {
+	case "+":
+		if ints {
+			return li + ri, nil
+		}
+		return fl + fr, nil
+	case "-":
+		if ints {
+			return li - ri, nil
+		}
+		return fl - fr, nil
+	case "*":
+		if ints {
+			return li * ri, nil
+		}
+		return fl * fr, nil
+	case "/":
+		if fr == 0 {
+			return nil, fmt.Errorf("query: division by zero")
+		}
+		if ints && li%ri == 0 {
+			return li / ri, nil
+		}
+		return fl / fr, nil
+	case "%":
+		if int64(fr) == 0 {
+			return nil, fmt.Errorf("query: modulo by zero")
+		}
+		return int64(fl) % int64(fr), nil
+	}
+	return nil, fmt.Errorf("query: unknown operator %s", op)
+}
+
+// contains reports whether arr holds a value equal to v.
+func contains(arr parser.JsonArray, v interface{}) bool {
+	for _, e := range arr {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// callExpr is a call to a builtin function.
+type callExpr struct {
+	name string
+	fn   builtin
+	args []expr
+}
+
+func (e *callExpr) eval(input interface{}) ([]interface{}, error) {
+	return e.fn(input, e.args)
+}
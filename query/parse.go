@@ -0,0 +1,560 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/lexer"
+)
+
+// tokenKind distinguishes the tokens of an expression.
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokIdent            // function names and keywords
+	tokField            // .name or ."name"
+	tokDot              // .
+	tokDotDot           // ..
+	tokNumber           // 12, 1.5e3
+	tokString           // "text"
+	tokPunct            // operators and delimiters
+)
+
+// exprToken is a token of an expression with its offset, used in errors.
+type exprToken struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// punctuators lists the operators and delimiters, longest first.
+var punctuators = []string{"==", "!=", "<=", ">=", "//", "|", ",", "(", ")", "[", "]", "{", "}", ":", ";", "+", "-", "*", "/", "%", "<", ">", "?"}
+
+// tokenize splits an expression into tokens.
+func tokenize(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '.':
+			switch {
+			case i+1 < len(src) && src[i+1] == '.':
+				tokens = append(tokens, exprToken{tokDotDot, "..", i})
+				i += 2
+			case i+1 < len(src) && isIdentStart(src[i+1]):
+				j := i + 1
+				for j < len(src) && isIdentPart(src[j]) {
+					j++
+				}
+				tokens = append(tokens, exprToken{tokField, src[i+1 : j], i})
+				i = j
+			case i+1 < len(src) && src[i+1] == '"':
+				s, n, err := readString(src[i+1:])
+				if err != nil {
+					return nil, fmt.Errorf("query: %v at offset %d", err, i+1)
+				}
+				tokens = append(tokens, exprToken{tokField, s, i})
+				i += 1 + n
+			default:
+				tokens = append(tokens, exprToken{tokDot, ".", i})
+				i++
+			}
+		case c == '"':
+			s, n, err := readString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("query: %v at offset %d", err, i)
+			}
+			tokens = append(tokens, exprToken{tokString, s, i})
+			i += n
+		case '0' <= c && c <= '9':
+			j := i
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, exprToken{tokNumber, src[i:j], i})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && isIdentPart(src[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{tokIdent, src[i:j], i})
+			i = j
+		default:
+			matched := false
+			for _, p := range punctuators {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, exprToken{tokPunct, p, i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("query: unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, exprToken{tokEOF, "", len(src)}), nil
+}
+
+// readString reads the quoted string at the start of src and returns its
+// characters, decoded as those of a JSON string are, and its length in src.
+func readString(src string) (string, int, error) {
+	for i := 1; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '"':
+			s, ok := lexer.Unescape(src[1:i])
+			if !ok {
+				return "", 0, fmt.Errorf("invalid string %s", src[:i+1])
+			}
+			return s, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// exprParser builds the syntax tree of an expression by recursive descent.
+// From lowest to highest precedence, the operators are: |, ",", //, or, and,
+// comparisons, + and -, then *, / and %.
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+// cur returns the current token.
+func (p *exprParser) cur() exprToken {
+	return p.tokens[p.pos]
+}
+
+// next advances to the next token and returns the previous one.
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isPunct reports whether the current token is the punctuator s.
+func (p *exprParser) isPunct(s string) bool {
+	t := p.cur()
+	return t.kind == tokPunct && t.value == s
+}
+
+// isKeyword reports whether the current token is the keyword s.
+func (p *exprParser) isKeyword(s string) bool {
+	t := p.cur()
+	return t.kind == tokIdent && t.value == s
+}
+
+// expect consumes the punctuator or keyword s or reports an error.
+func (p *exprParser) expect(s string) error {
+	if !p.isPunct(s) && !p.isKeyword(s) {
+		return p.errorf("expected %q", s)
+	}
+	p.next()
+	return nil
+}
+
+// errorf returns a syntax error located at the current token.
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	t := p.cur()
+	found := t.value
+	if t.kind == tokEOF {
+		found = "end of expression"
+	}
+	return fmt.Errorf("query: "+format+" at offset %d, got %q", append(args, t.pos, found)...)
+}
+
+func (p *exprParser) parsePipe() (expr, error) {
+	left, err := p.parseComma()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("|") {
+		p.next()
+		right, err := p.parseComma()
+		if err != nil {
+			return nil, err
+		}
+		left = &pipeExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseComma() (expr, error) {
+	left, err := p.parseAlternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct(",") {
+		p.next()
+		right, err := p.parseAlternative()
+		if err != nil {
+			return nil, err
+		}
+		left = &commaExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAlternative() (expr, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("//") {
+		p.next()
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = &alternativeExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{"or", left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (expr, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{"and", left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseComparison() (expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.isPunct(op) {
+			p.next()
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryExpr{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdditive() (expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("+") || p.isPunct("-") {
+		op := p.next().value
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseMultiplicative() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("*") || p.isPunct("/") || p.isPunct("%") {
+		op := p.next().value
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op, left, right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (expr, error) {
+	if p.isPunct("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negateExpr{operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix parses a primary expression followed by any number of field
+// accesses, indexes, slices, iterations and "?" suffixes.
+func (p *exprParser) parsePostfix() (expr, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.cur().kind == tokField:
+			e = &indexExpr{target: e, index: &literalExpr{p.next().value}}
+		case p.cur().kind == tokDot && p.tokens[p.pos+1].kind == tokPunct && p.tokens[p.pos+1].value == "[":
+			p.next() // .[ is the same as [ after a term
+		case p.isPunct("["):
+			if e, err = p.parseBracketSuffix(e); err != nil {
+				return nil, err
+			}
+		case p.isPunct("?"):
+			p.next()
+			e = &tryExpr{e}
+		default:
+			return e, nil
+		}
+	}
+}
+
+// parseBracketSuffix parses [], [index] or [from:to] applied to target.
+func (p *exprParser) parseBracketSuffix(target expr) (expr, error) {
+	p.next() // [
+	if p.isPunct("]") {
+		p.next()
+		return &iterateExpr{target}, nil
+	}
+
+	var from, to expr
+	var err error
+	if !p.isPunct(":") {
+		if from, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isPunct(":") {
+		p.next()
+		if !p.isPunct("]") {
+			if to, err = p.parsePipe(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &sliceExpr{target, from, to}, nil
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return &indexExpr{target: target, index: from}, nil
+}
+
+func (p *exprParser) parsePrimary() (expr, error) {
+	t := p.cur()
+	switch t.kind {
+	case tokDot:
+		p.next()
+		return identityExpr{}, nil
+	case tokField:
+		p.next()
+		return &indexExpr{target: identityExpr{}, index: &literalExpr{t.value}}, nil
+	case tokDotDot:
+		p.next()
+		return recurseExpr{}, nil
+	case tokNumber:
+		p.next()
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("query: invalid number %q at offset %d", t.value, t.pos)
+		}
+		return &literalExpr{number(f)}, nil
+	case tokString:
+		p.next()
+		return &literalExpr{t.value}, nil
+	case tokIdent:
+		return p.parseIdent()
+	case tokPunct:
+		switch t.value {
+		case "(":
+			p.next()
+			e, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(")")
+		case "[":
+			p.next()
+			if p.isPunct("]") {
+				p.next()
+				return &arrayExpr{}, nil
+			}
+			e, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			return &arrayExpr{e}, p.expect("]")
+		case "{":
+			return p.parseObject()
+		}
+	}
+	return nil, p.errorf("unexpected token")
+}
+
+// parseIdent parses a keyword, a literal or a function call.
+func (p *exprParser) parseIdent() (expr, error) {
+	name := p.next().value
+	switch name {
+	case "true":
+		return &literalExpr{true}, nil
+	case "false":
+		return &literalExpr{false}, nil
+	case "null":
+		return &literalExpr{nil}, nil
+	case "if":
+		return p.parseIf()
+	}
+
+	var args []expr
+	if p.isPunct("(") {
+		p.next()
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.isPunct(";") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	fn, ok := builtins[builtinKey{name, len(args)}]
+	if !ok {
+		return nil, fmt.Errorf("query: unknown function %s/%d", name, len(args))
+	}
+	return &callExpr{name: name, fn: fn, args: args}, nil
+}
+
+// parseIf parses the rest of "if cond then a elif cond then b else c end".
+func (p *exprParser) parseIf() (expr, error) {
+	cond, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &ifExpr{cond: cond, then: then}
+	switch {
+	case p.isKeyword("elif"):
+		p.next()
+		e.otherwise, err = p.parseIf()
+		return e, err
+	case p.isKeyword("else"):
+		p.next()
+		if e.otherwise, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	return e, p.expect("end")
+}
+
+// parseObject parses an object construction such as {a, "b": .x, (.k): 1}.
+func (p *exprParser) parseObject() (expr, error) {
+	p.next() // {
+	obj := &objectExpr{}
+	for !p.isPunct("}") {
+		var entry objectEntry
+		t := p.cur()
+		switch {
+		case t.kind == tokIdent || t.kind == tokString:
+			p.next()
+			entry.key = &literalExpr{t.value}
+		case t.kind == tokField:
+			// {.a} is a shorthand for {a: .a}
+			p.next()
+			entry.key = &literalExpr{t.value}
+		case p.isPunct("("):
+			p.next()
+			key, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			entry.key = key
+		default:
+			return nil, p.errorf("expected an object key")
+		}
+
+		if p.isPunct(":") {
+			p.next()
+			value, err := p.parseAlternative()
+			if err != nil {
+				return nil, err
+			}
+			entry.value = value
+		} else if lit, ok := entry.key.(*literalExpr); ok {
+			entry.value = &indexExpr{target: identityExpr{}, index: lit}
+		} else {
+			return nil, p.errorf("expected ':' after a computed key")
+		}
+		obj.entries = append(obj.entries, entry)
+
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	return obj, p.expect("}")
+}
@@ -0,0 +1,82 @@
+// Package query implements a small jq-like expression language to select and
+// reshape values inside documents produced by the parser package.
+//
+// An expression is compiled once with Compile and can then be run against any
+// number of documents:
+//
+//	q, err := query.Compile(`.users[] | select(.age >= 18) | .name`)
+//	names, err := q.Run(doc)
+//
+// Supported syntax covers paths (., .name, ."key", [index], [from:to], [],
+// ..), pipes, commas, optional "?" suffixes, literals, array and object
+// construction, arithmetic, comparison and boolean operators, the "//"
+// alternative operator, if/elif/else/end and a set of builtin functions such
+// as length, keys, map, select, sort_by, has and to_entries.
+//
+// Arrays of objects can also be queried as tables with SQL-like statements,
+// compiled by CompileSQL:
+//
+//	s, err := query.CompileSQL(`SELECT name, age FROM $.users WHERE age > 30 ORDER BY age`)
+//	rows, err := s.Run(doc)
+package query
+
+import "fmt"
+
+// Query is a compiled expression. It is safe for concurrent use.
+type Query struct {
+	src  string
+	root expr
+}
+
+// Compile parses an expression. Compiled queries are cached by source, so
+// compiling the same expression again, for instance on a hot path, returns
+// the same Query without parsing it.
+func Compile(src string) (*Query, error) {
+	if q := cache.get(src); q != nil {
+		return q, nil
+	}
+	q, err := compile(src)
+	if err != nil {
+		return nil, err
+	}
+	cache.put(q)
+	return q, nil
+}
+
+// compile parses an expression.
+func compile(src string) (*Query, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return &Query{src: src, root: root}, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be parsed.
+func MustCompile(src string) *Query {
+	q, err := Compile(src)
+	if err != nil {
+		panic(fmt.Sprintf("query: Compile(%q): %v", src, err))
+	}
+	return q
+}
+
+// String returns the source of the expression.
+func (q *Query) String() string {
+	return q.src
+}
+
+// Run evaluates the expression against input and returns every value it
+// produces, in order. The input is never modified.
+func (q *Query) Run(input interface{}) ([]interface{}, error) {
+	return q.root.eval(input)
+}
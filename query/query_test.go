@@ -0,0 +1,381 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+const document = `{
+	"name": "gojson",
+	"version": 2,
+	"tags": ["json", "parser", "linter"],
+	"users": [
+		{"name": "alice", "age": 31, "admin": true},
+		{"name": "bob", "age": 17},
+		{"name": "carol", "age": 45, "admin": false}
+	],
+	"empty": null
+}`
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseOrdered()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+// run compiles and runs expression against the document and returns its
+// outputs as compact JSON separated by spaces.
+func run(t *testing.T, doc interface{}, expression string) string {
+	q, err := Compile(expression)
+	if err != nil {
+		t.Fatalf("Compile(%q): unexpected error: %v", expression, err)
+	}
+	outputs, err := q.Run(doc)
+	if err != nil {
+		t.Fatalf("Run(%q): unexpected error: %v", expression, err)
+	}
+	texts := make([]string, len(outputs))
+	for i, v := range outputs {
+		b, err := gojson.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal(%v): unexpected error: %v", v, err)
+		}
+		texts[i] = string(b)
+	}
+	return strings.Join(texts, " ")
+}
+
+func TestPaths(t *testing.T) {
+	doc := parse(t, document)
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{".", `{"name":"gojson","version":2,"tags":["json","parser","linter"],"users":[{"name":"alice","age":31,"admin":true},{"name":"bob","age":17},{"name":"carol","age":45,"admin":false}],"empty":null}`},
+		{".name", `"gojson"`},
+		{`."version"`, `2`},
+		{".missing", `null`},
+		{".tags[0]", `"json"`},
+		{".tags[-1]", `"linter"`},
+		{".tags[1:]", `["parser","linter"]`},
+		{".tags[:1]", `["json"]`},
+		{".tags[]", `"json" "parser" "linter"`},
+		{".users[].name", `"alice" "bob" "carol"`},
+		{".users[1].age", `17`},
+		{".name, .version", `"gojson" 2`},
+		{".users[0] | .name", `"alice"`},
+		{".name[0]?", ``},
+		{"[.. | numbers?]", `[2,31,17,45]`},
+	}
+
+	for _, tt := range tests {
+		if got := run(t, doc, tt.expression); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.expression, tt.expected, got)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	doc := parse(t, document)
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{".version + 1", `3`},
+		{".version * 2.5", `5`},
+		{"7 / 2", `3.5`},
+		{"7 % 2", `1`},
+		{"-.version", `-2`},
+		{`.name + "-" + "cli"`, `"gojson-cli"`},
+		{".tags + [\"cli\"]", `["json","parser","linter","cli"]`},
+		{".version == 2", `true`},
+		{".version != 2", `false`},
+		{".version < 3 and .name == \"gojson\"", `true`},
+		{".empty or false", `false`},
+		{".empty // \"default\"", `"default"`},
+		{".name // \"default\"", `"gojson"`},
+		{`if .version > 1 then "new" else "old" end`, `"new"`},
+		{`if .version > 5 then "big" elif .version > 1 then "medium" else "small" end`, `"medium"`},
+		{"[.users[].age] | [.[] | . > 18]", `[true,false,true]`},
+		{"{name, count: (.users | length)}", `{"name":"gojson","count":3}`},
+		{`{(.name): .version}`, `{"gojson":2}`},
+	}
+
+	for _, tt := range tests {
+		if got := run(t, doc, tt.expression); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.expression, tt.expected, got)
+		}
+	}
+}
+
+func TestEscapedKeysAndStrings(t *testing.T) {
+	doc := parse(t, `{"a\"b": "caf\u00e9", "x/y": [1], "t\u0061b": "a\tb"}`)
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{`."a\"b"`, `"café"`},
+		{`."a\u0022b" == "café"`, `true`},
+		{`."x\/y"[0]`, `1`},
+		{`.tab == "a\u0009b"`, `true`},
+		{`{"k\u0021": ."a\"b"}`, `{"k!":"café"}`},
+		{`keys`, `["a\"b","tab","x/y"]`},
+	}
+	for _, tt := range tests {
+		if got := run(t, doc, tt.expression); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.expression, tt.expected, got)
+		}
+	}
+
+	s, err := CompileSQL(`SELECT "a\"b" AS v FROM $.rows WHERE tab = 'a	b'`)
+	if err != nil {
+		t.Fatalf("CompileSQL: unexpected error: %v", err)
+	}
+	rows, err := s.Run(parse(t, `{"rows": [{"a\"b": "caf\u00e9", "tab": "a\tb"}, {"a\"b": 1, "tab": "a b"}]}`))
+	if b, _ := gojson.Marshal(rows); err != nil || string(b) != `[{"v":"café"}]` {
+		t.Errorf("expected the escaped column selected, got %s (%v)", b, err)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`{"big": 123456789012345678901, "bigger": 123456789012345678902, "n": [3, -1.5, 2], "i": 1}`))
+	p.UseNumber(true)
+	doc := p.ParseOrdered()
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{".big", `123456789012345678901`},
+		{".big | type", `"number"`},
+		{".big | tostring", `"123456789012345678901"`},
+		{".big == .bigger", `false`},
+		{".big < .bigger", `true`},
+		{".big == 123456789012345678901", `true`},
+		{".n | sort", `[-1.5,2,3]`},
+		{".n | max", `3`},
+		{".n[0] + .i", `4`},
+		{".n[0] / 2", `1.5`},
+		{"-.n[0]", `-3`},
+		{".n[1] | length", `1.5`},
+		{".n[.i]", `-1.5`},
+		{"[.n[] | numbers] | length", `3`},
+		{".i | tonumber", `1`},
+	}
+	for _, tt := range tests {
+		if got := run(t, doc, tt.expression); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.expression, tt.expected, got)
+		}
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	doc := parse(t, document)
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{".tags | length", `3`},
+		{".name | length", `6`},
+		{"keys", `["empty","name","tags","users","version"]`},
+		{"keys_unsorted", `["name","version","tags","users","empty"]`},
+		{".users[0] | has(\"admin\")", `true`},
+		{".users[1] | has(\"admin\")", `false`},
+		{".users | map(.age) | add", `93`},
+		{".users | map(select(.age >= 18)) | map(.name)", `["alice","carol"]`},
+		{".users[] | select(.admin) | .name", `"alice"`},
+		{".users | sort_by(.age) | map(.name)", `["bob","alice","carol"]`},
+		{".users | map(.age) | min, max", `17 45`},
+		{".tags | sort", `["json","linter","parser"]`},
+		{".tags | reverse | first", `"linter"`},
+		{".tags | join(\", \")", `"json, parser, linter"`},
+		{".name | split(\"j\")", `["go","son"]`},
+		{".name | startswith(\"go\"), endswith(\"json\")", `true true`},
+		{".name | ltrimstr(\"go\") | ascii_upcase", `"JSON"`},
+		{".name | test(\"^go[a-z]+$\")", `true`},
+		{".version | tostring", `"2"`},
+		{"\"1.5\" | tonumber", `1.5`},
+		{".users[0] | to_entries | map(.key)", `["name","age","admin"]`},
+		{".users[1] | to_entries | from_entries", `{"name":"bob","age":17}`},
+		{".users[1] | map_values(tostring)", `{"name":"bob","age":"17"}`},
+		{"[[1, [2]], 3] | flatten", `[1,2,3]`},
+		{"[1, 2, 1, 3] | unique", `[1,2,3]`},
+		{"[range(3)]", `[0,1,2]`},
+		{"[.users[] | .admin] | any, all", `true false`},
+		{".tags | contains([\"json\"])", `true`},
+		{"[empty]", `[]`},
+		{"[.empty, .name, .version, .tags, .users[0]] | map(type)", `["null","string","number","array","object"]`},
+	}
+
+	for _, tt := range tests {
+		if got := run(t, doc, tt.expression); got != tt.expected {
+			t.Errorf("%s: expected %s, got %s", tt.expression, tt.expected, got)
+		}
+	}
+}
+
+func TestRunDoesNotModifyInput(t *testing.T) {
+	doc := parse(t, `{"a": [3, 1, 2]}`)
+	run(t, doc, ".a | sort")
+	run(t, doc, ".a | reverse")
+	if got := run(t, doc, "."); got != `{"a":[3,1,2]}` {
+		t.Errorf("input was modified: %s", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []string{
+		"",
+		".[",
+		".a |",
+		"[1, 2",
+		"{a: }",
+		`"unterminated`,
+		`"\x41"`,
+		"if . then 1",
+		"unknown_function",
+		"map",
+		". )",
+	}
+
+	for _, expression := range tests {
+		if _, err := Compile(expression); err == nil {
+			t.Errorf("Compile(%q): expected an error", expression)
+		}
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	q1, err := Compile(".users[] | .name")
+	if err != nil {
+		t.Fatalf("Compile: unexpected error: %v", err)
+	}
+	q2, err := Compile(".users[] | .name")
+	if err != nil {
+		t.Fatalf("Compile: unexpected error: %v", err)
+	}
+	if q1 != q2 {
+		t.Errorf("Compile: expected the cached query to be returned")
+	}
+
+	for i := 0; i <= maxCachedQueries; i++ {
+		MustCompile(".[" + strconv.Itoa(i) + "]")
+	}
+	if n := len(cache.queries); n > maxCachedQueries {
+		t.Errorf("cache holds %d queries, expected at most %d", n, maxCachedQueries)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	doc := parse(t, document)
+	tests := []string{
+		".name[0]",
+		".tags.name",
+		".version + .name",
+		".name | keys",
+		"{(.version): 1}",
+		".users | length | sort",
+	}
+
+	for _, expression := range tests {
+		q, err := Compile(expression)
+		if err != nil {
+			t.Fatalf("Compile(%q): unexpected error: %v", expression, err)
+		}
+		if _, err := q.Run(doc); err == nil {
+			t.Errorf("Run(%q): expected an error", expression)
+		}
+	}
+}
+
+func TestSQL(t *testing.T) {
+	doc := parse(t, document)
+	tests := []struct {
+		statement string
+		expected  string
+	}{
+		{"SELECT name, age FROM $.users WHERE age > 30 ORDER BY age LIMIT 10", `[{"name":"alice","age":31},{"name":"carol","age":45}]`},
+		{"select name from $.users order by age desc limit 2", `[{"name":"carol"},{"name":"alice"}]`},
+		{"SELECT name AS who FROM $.users WHERE admin IS NULL OR NOT admin ORDER BY name", `[{"who":"bob"},{"who":"carol"}]`},
+		{"SELECT * FROM $.users WHERE name = 'bob'", `[{"name":"bob","age":17}]`},
+		{"SELECT name FROM $.users WHERE (age < 18 OR age >= 45) AND name <> 'carol'", `[{"name":"bob"}]`},
+		{"SELECT name, admin FROM $.users WHERE admin IS NOT NULL ORDER BY admin", `[{"name":"carol","admin":false},{"name":"alice","admin":true}]`},
+		{"SELECT name FROM $.users WHERE age != '31' LIMIT 1 OFFSET 2", `[{"name":"carol"}]`},
+		{"SELECT name FROM $.users WHERE admin = null", `[]`},
+		{"SELECT name FROM $.users OFFSET 5", `[]`},
+		{`SELECT "name", 1 AS one, -2.5 FROM $.users LIMIT 1`, `[{"name":"alice","one":1,"-2.5":-2.5}]`},
+		{"SELECT * FROM $.tags WHERE tags = 'x'", `[]`},
+	}
+
+	for _, test := range tests {
+		s, err := CompileSQL(test.statement)
+		if err != nil {
+			t.Fatalf("CompileSQL(%q): unexpected error: %v", test.statement, err)
+		}
+		rows, err := s.Run(doc)
+		if err != nil {
+			t.Fatalf("Run(%q): unexpected error: %v", test.statement, err)
+		}
+		b, err := gojson.Marshal(rows)
+		if err != nil {
+			t.Fatalf("Marshal(%v): unexpected error: %v", rows, err)
+		}
+		if got := string(b); got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.statement, test.expected, got)
+		}
+	}
+}
+
+func TestSQLNested(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`[
+		{"id": 1, "owner": {"login": "alice"}, "tags": ["a", "b"], "first name": "Alice"},
+		{"id": 2, "owner": {"login": "bob"}, "tags": []}
+	]`))
+	doc := p.ParseValue()
+	s := MustCompileSQL(`SELECT id, owner.login, tags[0], "first name" FROM $ WHERE owner.login = 'alice' OR tags[0] IS NULL`)
+	rows, err := s.Run(doc)
+	if err != nil {
+		t.Fatalf("Run: unexpected error: %v", err)
+	}
+	b, _ := gojson.Marshal(rows)
+	expected := `[{"id":1,"owner.login":"alice","tags[0]":"a","first name":"Alice"},{"id":2,"owner.login":"bob","tags[0]":null,"first name":null}]`
+	if got := string(b); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestSQLErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"SELECT FROM $.users",
+		"SELECT name $.users",
+		"SELECT name FROM users",
+		"SELECT name FROM $.users WHERE",
+		"SELECT name FROM $.users WHERE age >",
+		"SELECT name FROM $.users WHERE (age > 1",
+		"SELECT name FROM $.users WHERE name IS 'x'",
+		"SELECT name FROM $.users ORDER age",
+		"SELECT name FROM $.users LIMIT ten",
+		"SELECT name FROM $.users[x]",
+		"SELECT name AS FROM FROM $.users",
+		"SELECT name FROM $.users WHERE name = 'unterminated",
+		"SELECT name FROM $.users ;",
+	}
+	for _, statement := range tests {
+		if _, err := CompileSQL(statement); err == nil {
+			t.Errorf("CompileSQL(%q): expected an error", statement)
+		}
+	}
+
+	s := MustCompileSQL("SELECT name FROM $.name")
+	if _, err := s.Run(parse(t, document)); err == nil || err.Error() != "query: FROM $.name: string is not an array" {
+		t.Errorf("expected an error about the FROM clause, got %v", err)
+	}
+}
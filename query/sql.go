@@ -0,0 +1,633 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// SQL is a compiled SQL-like statement selecting rows from an array of
+// objects, for documents that are tables:
+//
+//	SELECT name, age FROM $.users WHERE age > 30 ORDER BY age DESC LIMIT 10
+//
+// The FROM clause is a path from the document, $, to the array whose
+// elements are the rows. Columns are member names, followed by .name and
+// [index] to reach nested values, double quotes enclosing names that are not
+// identifiers. The WHERE clause combines comparisons (=, != or <>, <, <=, >,
+// >=), IS [NOT] NULL, AND, OR, NOT and parentheses; strings are enclosed in
+// single quotes. ORDER BY sorts rows as Run orders values, null first, and
+// LIMIT and OFFSET select a page of them. Keywords are case insensitive.
+//
+// A SQL statement is safe for concurrent use.
+type SQL struct {
+	src     string
+	star    bool // SELECT *
+	columns []sqlColumn
+	from    sqlPath
+	where   sqlExpr // or nil
+	orderBy []sqlOrder
+	limit   int // or -1
+	offset  int
+}
+
+// sqlColumn is a selected column and the name of its member in result rows.
+type sqlColumn struct {
+	name  string
+	value sqlExpr
+}
+
+// sqlOrder is a sort key of the ORDER BY clause.
+type sqlOrder struct {
+	value      sqlExpr
+	descending bool
+}
+
+// sqlStep is a member name or an array index in a path.
+type sqlStep struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// sqlPath is a path to a value, missing values being null.
+type sqlPath []sqlStep
+
+// lookup returns the value at the end of the path from v, or nil.
+func (p sqlPath) lookup(v interface{}) interface{} {
+	for _, s := range p {
+		if s.isIndex {
+			arr, ok := v.(parser.JsonArray)
+			if !ok || s.index >= len(arr) {
+				return nil
+			}
+			v = arr[s.index]
+			continue
+		}
+		_, members, ok := parser.Members(v)
+		if !ok {
+			return nil
+		}
+		v = members[s.key]
+	}
+	return v
+}
+
+// sqlExpr is an expression evaluated against a row.
+type sqlExpr interface {
+	eval(row interface{}) interface{}
+}
+
+type sqlLiteral struct {
+	value interface{}
+}
+
+func (e sqlLiteral) eval(interface{}) interface{} {
+	return e.value
+}
+
+func (p sqlPath) eval(row interface{}) interface{} {
+	return p.lookup(row)
+}
+
+// sqlComparison compares two values. Null is not equal, nor unequal, to any
+// value, and values of different types are only unequal.
+type sqlComparison struct {
+	op          string
+	left, right sqlExpr
+}
+
+func (e *sqlComparison) eval(row interface{}) interface{} {
+	a, b := e.left.eval(row), e.right.eval(row)
+	if a == nil || b == nil {
+		return false
+	}
+	if parser.TypeName(a) != parser.TypeName(b) {
+		return e.op == "!="
+	}
+	c := compare(a, b)
+	switch e.op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// sqlIsNull tests whether a value is null, or missing.
+type sqlIsNull struct {
+	value  sqlExpr
+	negate bool
+}
+
+func (e *sqlIsNull) eval(row interface{}) interface{} {
+	return (e.value.eval(row) == nil) != e.negate
+}
+
+type sqlLogical struct {
+	op          string // AND or OR
+	left, right sqlExpr
+}
+
+func (e *sqlLogical) eval(row interface{}) interface{} {
+	left := isTruthy(e.left.eval(row))
+	if e.op == "AND" {
+		return left && isTruthy(e.right.eval(row))
+	}
+	return left || isTruthy(e.right.eval(row))
+}
+
+type sqlNot struct {
+	operand sqlExpr
+}
+
+func (e *sqlNot) eval(row interface{}) interface{} {
+	return !isTruthy(e.operand.eval(row))
+}
+
+// CompileSQL parses a SQL-like statement.
+func CompileSQL(src string) (*SQL, error) {
+	tokens, err := tokenizeSQL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &sqlParser{exprParser: exprParser{tokens: tokens}, src: src}
+	s, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	s.src = src
+	return s, nil
+}
+
+// MustCompileSQL is like CompileSQL but panics if the statement cannot be
+// parsed.
+func MustCompileSQL(src string) *SQL {
+	s, err := CompileSQL(src)
+	if err != nil {
+		panic(fmt.Sprintf("query: CompileSQL(%q): %v", src, err))
+	}
+	return s
+}
+
+// String returns the source of the statement.
+func (s *SQL) String() string {
+	return s.src
+}
+
+// Run evaluates the statement against input and returns the selected rows:
+// objects holding the selected columns in order, or the rows themselves for
+// SELECT *. The input is never modified, but the rows share their values
+// with it.
+func (s *SQL) Run(input interface{}) (parser.JsonArray, error) {
+	table, ok := s.from.lookup(input).(parser.JsonArray)
+	if !ok {
+		return nil, fmt.Errorf("query: FROM %s: %s is not an array", s.fromText(), parser.TypeName(s.from.lookup(input)))
+	}
+
+	var rows parser.JsonArray
+	for _, row := range table {
+		if s.where == nil || isTruthy(s.where.eval(row)) {
+			rows = append(rows, row)
+		}
+	}
+	if len(s.orderBy) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, o := range s.orderBy {
+				c := compare(o.value.eval(rows[i]), o.value.eval(rows[j]))
+				if o.descending {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+	rows = rows[min(s.offset, len(rows)):]
+	if s.limit >= 0 && s.limit < len(rows) {
+		rows = rows[:s.limit]
+	}
+
+	result := make(parser.JsonArray, len(rows))
+	for i, row := range rows {
+		if s.star {
+			result[i] = row
+			continue
+		}
+		obj := parser.NewOrderedObject()
+		for _, c := range s.columns {
+			obj.Set(c.name, c.value.eval(row))
+		}
+		result[i] = obj
+	}
+	return result, nil
+}
+
+// fromText returns the path of the FROM clause as written in errors.
+func (s *SQL) fromText() string {
+	return "$" + pathText(s.from)
+}
+
+// pathText returns the text of a path, member names being prefixed by dots.
+func pathText(p sqlPath) string {
+	var result strings.Builder
+	for _, s := range p {
+		if s.isIndex {
+			result.WriteString("[" + strconv.Itoa(s.index) + "]")
+		} else if isIdentifier(s.key) {
+			result.WriteString("." + s.key)
+		} else {
+			result.WriteString("." + strconv.Quote(s.key))
+		}
+	}
+	return result.String()
+}
+
+// isIdentifier reports whether s is a name that needs no quotes.
+func isIdentifier(s string) bool {
+	if s == "" || !isIdentStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentPart(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sqlPunctuators lists the operators and delimiters of statements, longest
+// first.
+var sqlPunctuators = []string{"<=", ">=", "<>", "!=", "=", "<", ">", ",", "(", ")", "[", "]", ".", "*", "$", "-"}
+
+// tokenizeSQL splits a statement into tokens. Quoted names are tokField
+// tokens.
+func tokenizeSQL(src string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			s, n, err := readString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("query: %v at offset %d", err, i)
+			}
+			tokens = append(tokens, exprToken{tokField, s, i})
+			i += n
+		case c == '\'':
+			s, n, err := readSQLString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("query: %v at offset %d", err, i)
+			}
+			tokens = append(tokens, exprToken{tokString, s, i})
+			i += n
+		case isDigit(c):
+			j := i
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.' || src[j] == 'e' || src[j] == 'E' ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, exprToken{tokNumber, src[i:j], i})
+			i = j
+		case isIdentStart(c):
+			j := i
+			for j < len(src) && isIdentPart(src[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{tokIdent, src[i:j], i})
+			i = j
+		default:
+			matched := false
+			for _, p := range sqlPunctuators {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, exprToken{tokPunct, p, i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("query: unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, exprToken{tokEOF, "", len(src)}), nil
+}
+
+// readSQLString reads the single-quoted string at the start of src, where
+// two quotes stand for one, and returns its value and its length in src.
+func readSQLString(src string) (string, int, error) {
+	var result strings.Builder
+	for i := 1; i < len(src); i++ {
+		if src[i] != '\'' {
+			result.WriteByte(src[i])
+			continue
+		}
+		if i+1 < len(src) && src[i+1] == '\'' {
+			result.WriteByte('\'')
+			i++
+			continue
+		}
+		return result.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// sqlKeywords are the words that cannot name columns unless quoted.
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "ORDER": true, "BY": true, "ASC": true, "DESC": true,
+	"LIMIT": true, "OFFSET": true, "AS": true, "AND": true, "OR": true, "NOT": true, "IS": true,
+	"NULL": true, "TRUE": true, "FALSE": true,
+}
+
+// sqlParser builds a statement by recursive descent. From lowest to highest
+// precedence, the operators are OR, AND, NOT, then comparisons.
+type sqlParser struct {
+	exprParser
+	src string
+}
+
+// isKeyword reports whether the current token is the keyword s, in any case.
+func (p *sqlParser) isKeyword(s string) bool {
+	t := p.cur()
+	return t.kind == tokIdent && strings.EqualFold(t.value, s)
+}
+
+// expect consumes the punctuator or keyword s or reports an error.
+func (p *sqlParser) expect(s string) error {
+	if !p.isPunct(s) && !p.isKeyword(s) {
+		return p.errorf("expected %s", s)
+	}
+	p.next()
+	return nil
+}
+
+func (p *sqlParser) parseStatement() (*SQL, error) {
+	s := &SQL{limit: -1}
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	if p.isPunct("*") {
+		p.next()
+		s.star = true
+	} else {
+		for {
+			start := p.pos
+			value, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			c := sqlColumn{value: value}
+			if path, ok := value.(sqlPath); ok && len(path) == 1 {
+				c.name = path[0].key
+			} else if ok {
+				c.name = strings.TrimPrefix(pathText(path), ".")
+			} else {
+				c.name = strings.TrimSpace(p.src[p.tokens[start].pos:p.cur().pos])
+			}
+			if p.isKeyword("AS") {
+				p.next()
+				if t := p.cur(); t.kind == tokField || t.kind == tokIdent && !sqlKeywords[strings.ToUpper(t.value)] {
+					c.name = p.next().value
+				} else {
+					return nil, p.errorf("expected a column name")
+				}
+			}
+			s.columns = append(s.columns, c)
+			if !p.isPunct(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	from, err := p.parseSteps(nil)
+	if err != nil {
+		return nil, err
+	}
+	s.from = from
+
+	if p.isKeyword("WHERE") {
+		p.next()
+		if s.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isKeyword("ORDER") {
+		p.next()
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			value, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			o := sqlOrder{value: value}
+			if p.isKeyword("DESC") {
+				p.next()
+				o.descending = true
+			} else if p.isKeyword("ASC") {
+				p.next()
+			}
+			s.orderBy = append(s.orderBy, o)
+			if !p.isPunct(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if p.isKeyword("LIMIT") {
+		p.next()
+		if s.limit, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isKeyword("OFFSET") {
+		p.next()
+		if s.offset, err = p.parseCount(); err != nil {
+			return nil, err
+		}
+	}
+	if p.cur().kind != tokEOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return s, nil
+}
+
+// parseCount parses the non-negative integer of LIMIT or OFFSET.
+func (p *sqlParser) parseCount() (int, error) {
+	if p.cur().kind != tokNumber {
+		return 0, p.errorf("expected a number")
+	}
+	n, err := strconv.Atoi(p.cur().value)
+	if err != nil {
+		return 0, p.errorf("invalid count")
+	}
+	p.next()
+	return n, nil
+}
+
+func (p *sqlParser) parseOr() (sqlExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlLogical{"OR", left, right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseAnd() (sqlExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &sqlLogical{"AND", left, right}
+	}
+	return left, nil
+}
+
+func (p *sqlParser) parseNot() (sqlExpr, error) {
+	if p.isKeyword("NOT") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &sqlNot{operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *sqlParser) parseComparison() (sqlExpr, error) {
+	if p.isPunct("(") {
+		p.next()
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.isKeyword("IS") {
+		p.next()
+		e := &sqlIsNull{value: left}
+		if p.isKeyword("NOT") {
+			p.next()
+			e.negate = true
+		}
+		return e, p.expect("NULL")
+	}
+	for _, op := range []string{"=", "!=", "<>", "<=", ">=", "<", ">"} {
+		if p.isPunct(op) {
+			p.next()
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			if op == "<>" {
+				op = "!="
+			}
+			return &sqlComparison{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+// parseOperand parses a literal or a column.
+func (p *sqlParser) parseOperand() (sqlExpr, error) {
+	t := p.cur()
+	switch {
+	case t.kind == tokNumber || t.kind == tokPunct && t.value == "-" && p.tokens[p.pos+1].kind == tokNumber:
+		text := p.next().value
+		if text == "-" {
+			text += p.next().value
+		}
+		n, err := parser.ParseNumber(text)
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("invalid number")
+		}
+		return sqlLiteral{n}, nil
+	case t.kind == tokString:
+		p.next()
+		return sqlLiteral{t.value}, nil
+	case p.isKeyword("TRUE"), p.isKeyword("FALSE"):
+		p.next()
+		return sqlLiteral{strings.EqualFold(t.value, "TRUE")}, nil
+	case p.isKeyword("NULL"):
+		p.next()
+		return sqlLiteral{nil}, nil
+	case t.kind == tokField, t.kind == tokIdent && !sqlKeywords[strings.ToUpper(t.value)]:
+		p.next()
+		return p.parseSteps(sqlPath{{key: t.value}})
+	}
+	return nil, p.errorf("expected a column or a value")
+}
+
+// parseSteps parses the .name, ."name" and [index] suffixes extending path.
+func (p *sqlParser) parseSteps(path sqlPath) (sqlPath, error) {
+	for {
+		switch {
+		case p.isPunct("."):
+			p.next()
+			if t := p.cur(); t.kind != tokIdent && t.kind != tokField {
+				return nil, p.errorf("expected a member name")
+			}
+			path = append(path, sqlStep{key: p.next().value})
+		case p.isPunct("["):
+			p.next()
+			if p.cur().kind != tokNumber {
+				return nil, p.errorf("expected an array index")
+			}
+			index, err := strconv.Atoi(p.cur().value)
+			if err != nil {
+				return nil, p.errorf("invalid array index")
+			}
+			p.next()
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, sqlStep{index: index, isIndex: true})
+		default:
+			return path, nil
+		}
+	}
+}
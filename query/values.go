@@ -0,0 +1,167 @@
+package query
+
+import (
+	"math"
+	"sort"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// asNumber converts a parsed number into a float64.
+func asNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case parser.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// integer returns v as an int64 when it is an integer that fits in one.
+func integer(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case parser.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// number returns f as an int64 when it is integral, so that integer arithmetic
+// keeps producing integers.
+func number(f float64) interface{} {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f)
+	}
+	return f
+}
+
+// isTruthy reports whether v counts as true: everything but false and null.
+func isTruthy(v interface{}) bool {
+	return v != nil && v != false
+}
+
+// typeRank orders JSON types the way jq sorts them.
+func typeRank(v interface{}) int {
+	switch x := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if !x {
+			return 1
+		}
+		return 2
+	case int64, float64, parser.Number:
+		return 3
+	case string:
+		return 4
+	case parser.JsonArray:
+		return 5
+	}
+	return 6
+}
+
+// compare returns -1, 0 or 1 depending on whether a sorts before, equal to or
+// after b. Values of different types are ordered null < false < true <
+// numbers < strings < arrays < objects.
+func compare(a, b interface{}) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+
+	switch x := a.(type) {
+	case int64, float64, parser.Number:
+		if c, ok := compareNumbers(x, b); ok {
+			return c
+		}
+		fa, _ := asNumber(x)
+		fb, _ := asNumber(b)
+		return compareOrdered(fa, fb)
+	case string:
+		return compareOrdered(x, b.(string))
+	case parser.JsonArray:
+		y := b.(parser.JsonArray)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return compareOrdered(len(x), len(y))
+	}
+
+	if ka, ma, ok := parser.Members(a); ok {
+		kb, mb, _ := parser.Members(b)
+		sa, sb := sortedCopy(ka), sortedCopy(kb)
+		if c := compare(stringsArray(sa), stringsArray(sb)); c != 0 {
+			return c
+		}
+		for _, k := range sa {
+			if c := compare(ma[k], mb[k]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// compareNumbers compares a and b exactly when both are Number values, which
+// may not fit in a float64.
+func compareNumbers(a, b interface{}) (int, bool) {
+	x, ok := a.(parser.Number)
+	y, ok2 := b.(parser.Number)
+	if !ok || !ok2 {
+		return 0, false
+	}
+	fx, err := x.BigFloat()
+	if err != nil {
+		return 0, false
+	}
+	fy, err := y.BigFloat()
+	if err != nil {
+		return 0, false
+	}
+	return fx.Cmp(fy), true
+}
+
+// compareOrdered compares two values of an ordered type.
+func compareOrdered[T int | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// sortedCopy returns a sorted copy of keys.
+func sortedCopy(keys []string) []string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// stringsArray converts a list of strings into a JSON array.
+func stringsArray(s []string) parser.JsonArray {
+	arr := make(parser.JsonArray, len(s))
+	for i, v := range s {
+		arr[i] = v
+	}
+	return arr
+}
+
+// equal reports whether a and b are the same JSON value, comparing numbers by
+// value regardless of their Go type.
+func equal(a, b interface{}) bool {
+	return typeRank(a) == typeRank(b) && compare(a, b) == 0
+}
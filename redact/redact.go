@@ -0,0 +1,244 @@
+// Package redact masks or removes the sensitive values of documents produced
+// by the parser package, so that payloads can be shared safely in bug reports
+// and logs. Values are designated by rules matching member keys, JSON
+// Pointer paths or the text of strings, or by the properties a JSON Schema
+// declares writeOnly or of the password format.
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Wildcard is a reference token matching any member or element in the paths
+// of rules, so that "/users/*/password" designates the password of every
+// user. It is pointer.Wildcard.
+const Wildcard = pointer.Wildcard
+
+// DefaultMask is the text replacing redacted values when Options.Mask is
+// empty.
+const DefaultMask = "[REDACTED]"
+
+// Rule designates values to redact. Exactly one of Key, Path and Value must
+// be set. Keys and strings are matched once decoded, as the parser stores
+// them.
+type Rule struct {
+	Key   *regexp.Regexp  // redacts the values of the members whose key matches
+	Path  pointer.Pointer // redacts the values designated by the path, which may hold wildcards
+	Value *regexp.Regexp  // redacts the parts of strings matching
+	// Remove removes the designated members and elements, or the whole
+	// strings holding a match of Value, instead of masking them.
+	Remove bool
+}
+
+// Options controls how Redact masks values.
+type Options struct {
+	Mask string // string replacing masked values, DefaultMask when empty
+}
+
+// Redact returns a copy of doc where the values designated by rules are
+// masked, that is replaced by the mask string, or removed. Rules apply in
+// turn, so a value removed by one of them is not masked by a later one. The
+// indexes of the paths are those of the input document, before elements are
+// removed.
+func Redact(doc interface{}, rules []Rule, opts Options) (interface{}, error) {
+	for i, r := range rules {
+		set := 0
+		for _, isSet := range []bool{r.Key != nil, r.Path != nil, r.Value != nil} {
+			if isSet {
+				set++
+			}
+		}
+		if set != 1 {
+			return nil, fmt.Errorf("redact: rule %d: exactly one of Key, Path and Value must be set", i)
+		}
+		if r.Path != nil && len(r.Path) == 0 {
+			return nil, fmt.Errorf("redact: rule %d: the path designates the whole document", i)
+		}
+	}
+	if opts.Mask == "" {
+		opts.Mask = DefaultMask
+	}
+
+	rd := &redactor{rules: rules, mask: opts.Mask}
+	v, _ := rd.value(pointer.Pointer{}, false, doc)
+	return v, nil
+}
+
+// redactor redacts the values of a document.
+type redactor struct {
+	rules []Rule
+	mask  string // the text replacing redacted values
+}
+
+// value returns a copy of v, found at path where it is an object member if
+// member is set, redacted, or false if it is removed.
+func (rd *redactor) value(path pointer.Pointer, member bool, v interface{}) (interface{}, bool) {
+	for _, r := range rd.rules {
+		switch {
+		case r.Key != nil && member && r.Key.MatchString(path[len(path)-1]),
+			r.Path != nil && r.Path.Matches(path):
+			if r.Remove {
+				return nil, false
+			}
+			return rd.mask, true
+		case r.Value != nil:
+			s, ok := v.(string)
+			if !ok || !r.Value.MatchString(s) {
+				continue
+			}
+			if r.Remove {
+				return nil, false
+			}
+			v = r.Value.ReplaceAllLiteralString(s, rd.mask)
+		}
+	}
+
+	switch x := v.(type) {
+	case parser.JsonArray:
+		arr := parser.JsonArray{}
+		for i, e := range x {
+			if e, ok := rd.value(path.Append(strconv.Itoa(i)), false, e); ok {
+				arr = append(arr, e)
+			}
+		}
+		return arr, true
+	case parser.JsonObject:
+		obj := parser.JsonObject{}
+		for k, e := range x {
+			if e, ok := rd.value(path.Append(k), true, e); ok {
+				obj[k] = e
+			}
+		}
+		return obj, true
+	case *parser.OrderedObject:
+		obj := parser.NewOrderedObject()
+		for _, k := range x.Keys {
+			if e, ok := rd.value(path.Append(k), true, x.Values[k]); ok {
+				obj.Set(k, e)
+			}
+		}
+		return obj, true
+	}
+	return v, true
+}
+
+// SchemaRules returns the rules masking the values of the properties that
+// the JSON Schema document schema declares sensitive, with "writeOnly": true
+// or "format": "password". The schema is walked through properties, items,
+// additionalProperties and $ref to locations inside the schema document. The
+// rules of additionalProperties use wildcards, which match the declared
+// properties too.
+func SchemaRules(schema interface{}) ([]Rule, error) {
+	w := &schemaWalker{root: schema, expanding: map[string]bool{}}
+	if err := w.walk(schema, pointer.Pointer{}); err != nil {
+		return nil, err
+	}
+	return w.rules, nil
+}
+
+// schemaWalker collects the sensitive paths of a schema.
+type schemaWalker struct {
+	root      interface{}
+	rules     []Rule
+	expanding map[string]bool // references being expanded, to stop on cycles
+}
+
+// walk collects the sensitive paths of the subschema s, describing the values
+// at path.
+func (w *schemaWalker) walk(s interface{}, path pointer.Pointer) error {
+	members, ok := s.(*parser.OrderedObject)
+	if !ok {
+		if obj, isObj := s.(parser.JsonObject); isObj {
+			members = parser.NewOrderedObject()
+			for k, v := range obj {
+				members.Set(k, v)
+			}
+		} else {
+			return nil // boolean schemas
+		}
+	}
+
+	if writeOnly, _ := members.Get("writeOnly"); writeOnly == true {
+		w.add(path)
+	} else if format, _ := members.Get("format"); format == "password" {
+		w.add(path)
+	}
+
+	if ref, ok := members.Get("$ref"); ok {
+		location, ok := ref.(string)
+		if !ok || !strings.HasPrefix(location, "#") {
+			return fmt.Errorf("redact: unsupported $ref %v, only references inside the schema are", ref)
+		}
+		if !w.expanding[location] {
+			target, err := w.resolve(location)
+			if err != nil {
+				return err
+			}
+			w.expanding[location] = true
+			err = w.walk(target, path)
+			delete(w.expanding, location)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if properties, ok := members.Get("properties"); ok {
+		switch p := properties.(type) {
+		case *parser.OrderedObject:
+			for _, k := range p.Keys {
+				if err := w.walk(p.Values[k], path.Append(k)); err != nil {
+					return err
+				}
+			}
+		case parser.JsonObject:
+			for k, v := range p {
+				if err := w.walk(v, path.Append(k)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, keyword := range []string{"items", "additionalProperties"} {
+		if sub, ok := members.Get(keyword); ok {
+			if err := w.walk(sub, path.Append(Wildcard)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// add adds a rule masking the values at path, unless it already exists or
+// path designates the whole document.
+func (w *schemaWalker) add(path pointer.Pointer) {
+	if len(path) == 0 {
+		return
+	}
+	for _, r := range w.rules {
+		if r.Path.String() == path.String() {
+			return
+		}
+	}
+	w.rules = append(w.rules, Rule{Path: append(pointer.Pointer{}, path...)})
+}
+
+// resolve returns the subschema designated by the $ref value location.
+func (w *schemaWalker) resolve(location string) (interface{}, error) {
+	p, err := pointer.Parse(location[1:])
+	if err != nil {
+		return nil, fmt.Errorf("redact: invalid $ref %q: %v", location, err)
+	}
+	target, err := p.Get(w.root)
+	if errors.Is(err, pointer.ErrNotFound) {
+		return nil, fmt.Errorf("redact: $ref %q does not resolve", location)
+	}
+	return target, err
+}
@@ -0,0 +1,132 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+func parse(t *testing.T, input string) interface{} {
+	t.Helper()
+	p := parser.NewParser(lexer.NewLexer(input))
+	v := p.ParseOrdered()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("parsing %s: %v", input, p.Errors())
+	}
+	return v
+}
+
+func compact(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+const payload = `{
+	"user": {"name": "John", "password": "hunter2", "apiToken": {"id": 1}},
+	"cards": [{"number": "4111-1111-1111-1111", "owner": "John"}, {"number": "5500-0000-0000-0004", "owner": "Jane"}],
+	"log": "paid with 4111-1111-1111-1111 today",
+	"ssn": "123-45-6789"
+}`
+
+func TestRedact(t *testing.T) {
+	doc := parse(t, payload)
+	rules := []Rule{
+		{Key: regexp.MustCompile(`(?i)password|token`)},
+		{Path: pointer.MustParse("/cards/*/owner"), Remove: true},
+		{Value: regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`)},
+		{Value: regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`), Remove: true},
+	}
+
+	got, err := Redact(doc, rules, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"user": {"name": "John", "password": "[REDACTED]", "apiToken": "[REDACTED]"}, "cards": [{"number": "[REDACTED]"}, {"number": "[REDACTED]"}], "log": "paid with [REDACTED] today"}`
+	if compact(got) != expected {
+		t.Errorf("got %s, want %s", compact(got), expected)
+	}
+	if compact(doc) == compact(got) {
+		t.Errorf("the input was modified")
+	}
+}
+
+func TestRedactMask(t *testing.T) {
+	doc := parse(t, `{"a": [1, 2, 3], "b": "secret"}`)
+	rules := []Rule{{Path: pointer.MustParse("/a/1")}, {Path: pointer.MustParse("/a/2"), Remove: true}, {Key: regexp.MustCompile(`^b$`)}}
+	got, err := Redact(doc, rules, Options{Mask: `"***"`})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := `{"a": [1, "\"***\""], "b": "\"***\""}`; compact(got) != expected {
+		t.Errorf("got %s, want %s", compact(got), expected)
+	}
+}
+
+func TestRedactEscaped(t *testing.T) {
+	doc := parse(t, `{"pass\u0077ord": "x", "log": "card 4111\u002d1111-1111-1111", "a\/b": {"k\"": 1, "n": "\u0031\u0032\u0033-45-6789"}}`)
+	rules := []Rule{
+		{Key: regexp.MustCompile(`^password$`)},
+		{Path: pointer.MustParse(`/a~1b/k"`)},
+		{Value: regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`)},
+		{Value: regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`), Remove: true},
+	}
+	got, err := Redact(doc, rules, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"password": "[REDACTED]", "log": "card [REDACTED]", "a/b": {"k\"": "[REDACTED]"}}`
+	if compact(got) != expected {
+		t.Errorf("got %s, want %s", compact(got), expected)
+	}
+}
+
+func TestRedactInvalidRules(t *testing.T) {
+	doc := parse(t, `{}`)
+	for _, rule := range []Rule{{}, {Key: regexp.MustCompile(`a`), Path: pointer.MustParse("/a")}, {Path: pointer.MustParse("")}} {
+		if _, err := Redact(doc, []Rule{rule}, Options{}); err == nil {
+			t.Errorf("expected an error for %+v", rule)
+		}
+	}
+}
+
+func TestSchemaRules(t *testing.T) {
+	schema := parse(t, `{
+		"type": "object",
+		"properties": {
+			"login": {"type": "string"},
+			"password": {"type": "string", "format": "password"},
+			"keys": {"type": "array", "items": {"$ref": "#/$defs/key"}},
+			"friends": {"type": "array", "items": {"$ref": "#"}}
+		},
+		"$defs": {"key": {"type": "object", "properties": {"secret": {"writeOnly": true}}}}
+	}`)
+	rules, err := SchemaRules(schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var paths []string
+	for _, r := range rules {
+		paths = append(paths, r.Path.String())
+	}
+	if got, expected := strings.Join(paths, " "), "/password /keys/*/secret /friends/*/password /friends/*/keys/*/secret"; got != expected {
+		t.Errorf("got paths %s, want %s", got, expected)
+	}
+
+	doc := parse(t, `{"login": "john", "password": "x", "keys": [{"secret": "k1", "name": "main"}], "friends": [{"password": "y"}]}`)
+	got, err := Redact(doc, rules, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"login": "john", "password": "[REDACTED]", "keys": [{"secret": "[REDACTED]", "name": "main"}], "friends": [{"password": "[REDACTED]"}]}`
+	if compact(got) != expected {
+		t.Errorf("got %s, want %s", compact(got), expected)
+	}
+
+	if _, err := SchemaRules(parse(t, `{"$ref": "other.json#/a"}`)); err == nil {
+		t.Errorf("expected an error for a remote reference")
+	}
+}
@@ -0,0 +1,377 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// GenerateOptions controls the documents produced by Generate.
+type GenerateOptions struct {
+	// Rand is the source of randomness, seeded from the current time when
+	// nil. A source with a fixed seed always produces the same documents.
+	Rand *rand.Rand
+	// MaxItems bounds the number of elements added to arrays beyond
+	// minItems when maxItems is not set, 3 when 0.
+	MaxItems int
+	// MaxDepth is the nesting beyond which optional properties are left out
+	// and arrays get no more elements than minItems, so that recursive
+	// schemas produce finite documents, 4 when 0.
+	MaxDepth int
+}
+
+// generateAttempts is the number of values generated for a subschema before
+// giving up on finding one that satisfies it.
+const generateAttempts = 32
+
+// maxReferences is the number of nested references followed before a schema
+// such as {"$ref": "#"}, which refers to itself without describing a value,
+// is reported.
+const maxReferences = 64
+
+// Generate returns a random document satisfying the schema: its types, enum,
+// const, required and optional properties, array and string lengths,
+// patterns, numeric bounds and multipleOf. Objects are *parser.OrderedObject
+// with their properties in sorted order. Values are generated, then checked
+// against the schema and generated again if they do not satisfy it, until an
+// attempt limit is reached for schemas that are hard or impossible to
+// satisfy, such as false.
+func (s *Schema) Generate(opts GenerateOptions) (interface{}, error) {
+	if opts.Rand == nil {
+		seed := uint64(time.Now().UnixNano())
+		opts.Rand = rand.New(rand.NewPCG(seed, seed>>32))
+	}
+	if opts.MaxItems == 0 {
+		opts.MaxItems = 3
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = 4
+	}
+	g := &generator{opts: opts, rand: opts.Rand}
+	return g.valid(s, pointer.Pointer{})
+}
+
+// generator generates the values of a document.
+type generator struct {
+	opts GenerateOptions
+	rand *rand.Rand
+	refs int // references being followed
+}
+
+// valid returns a value satisfying s, found at path.
+func (g *generator) valid(s *Schema, path pointer.Pointer) (interface{}, error) {
+	for i := 0; i < generateAttempts; i++ {
+		v, err := g.value(s, path)
+		if err != nil {
+			return nil, err
+		}
+		if s.Valid(v) {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("schema: %s: could not generate a value satisfying the schema", location(path))
+}
+
+// location returns how path appears in errors, the whole document being
+// designated by "/".
+func location(path pointer.Pointer) string {
+	if len(path) == 0 {
+		return "/"
+	}
+	return path.String()
+}
+
+// value returns a value of the shape s describes, which may not satisfy all
+// its constraints.
+func (g *generator) value(s *Schema, path pointer.Pointer) (interface{}, error) {
+	switch {
+	case s.always != nil && !*s.always:
+		return nil, fmt.Errorf("schema: %s: no value satisfies the false schema", location(path))
+	case s.always != nil:
+		return g.scalar(g.pick([]string{"null", "boolean", "integer", "string"})), nil
+	case s.ref != nil:
+		if g.refs++; g.refs > maxReferences {
+			return nil, fmt.Errorf("schema: %s: references do not lead to a value", location(path))
+		}
+		defer func() { g.refs-- }()
+		return g.value(s.ref, path)
+	case s.hasConst:
+		return gojson.Clone(s.constant), nil
+	case s.enum != nil:
+		if len(s.enum) == 0 {
+			return nil, fmt.Errorf("schema: %s: enum allows no value", location(path))
+		}
+		return gojson.Clone(s.enum[g.rand.IntN(len(s.enum))]), nil
+	}
+
+	switch g.typeOf(s) {
+	case "object":
+		return g.object(s, path)
+	case "array":
+		return g.array(s, path)
+	case "string":
+		return g.string(s), nil
+	case "integer":
+		return g.number(s, true), nil
+	case "number":
+		return g.number(s, false), nil
+	case "boolean":
+		return g.rand.IntN(2) == 1, nil
+	}
+	return nil, nil
+}
+
+// typeOf returns the type of the value to generate for s: one of its types,
+// or the type its keywords apply to.
+func (g *generator) typeOf(s *Schema) string {
+	switch {
+	case len(s.types) > 0:
+		return g.pick(s.types)
+	case s.properties != nil || s.required != nil || s.additionalProperties != nil:
+		return "object"
+	case s.items != nil || s.minItems > 0 || s.maxItems >= 0:
+		return "array"
+	case s.minLength > 0 || s.maxLength >= 0 || s.pattern != nil:
+		return "string"
+	case s.multipleOf != nil && *s.multipleOf == math.Trunc(*s.multipleOf):
+		return "integer"
+	case s.minimum != nil || s.maximum != nil || s.exclusiveMinimum != nil || s.exclusiveMaximum != nil || s.multipleOf != nil:
+		return "number"
+	}
+	return g.pick([]string{"null", "boolean", "integer", "string"})
+}
+
+// pick returns a random element of choices.
+func (g *generator) pick(choices []string) string {
+	return choices[g.rand.IntN(len(choices))]
+}
+
+// scalar returns a random value of the given scalar type.
+func (g *generator) scalar(typ string) interface{} {
+	return g.must(&Schema{types: []string{typ}, maxItems: -1, maxLength: -1})
+}
+
+// must returns a value of the shape of s, a schema without constraints that
+// could fail.
+func (g *generator) must(s *Schema) interface{} {
+	v, _ := g.value(s, nil)
+	return v
+}
+
+// object returns an object with the required properties of s and, unless
+// the maximum depth is reached, some of its optional ones.
+func (g *generator) object(s *Schema, path pointer.Pointer) (interface{}, error) {
+	names := make([]string, 0, len(s.properties)+len(s.required))
+	for name := range s.properties {
+		names = append(names, name)
+	}
+	for _, name := range s.required {
+		if _, ok := s.properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	obj := parser.NewOrderedObject()
+	for _, name := range names {
+		if !contains(s.required, name) && (len(path) >= g.opts.MaxDepth || g.rand.IntN(2) == 0) {
+			continue
+		}
+		sub, ok := s.properties[name]
+		if !ok {
+			sub = s.additionalProperties
+		}
+		if sub == nil {
+			obj.Set(name, g.scalar(g.pick([]string{"boolean", "integer", "string"})))
+			continue
+		}
+		v, err := g.valid(sub, path.Append(name))
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(name, v)
+	}
+	return obj, nil
+}
+
+// array returns an array of as many elements as s allows, within the limits
+// of the options.
+func (g *generator) array(s *Schema, path pointer.Pointer) (interface{}, error) {
+	n := s.minItems
+	if len(path) < g.opts.MaxDepth {
+		most := s.minItems + g.opts.MaxItems
+		if s.maxItems >= 0 && s.maxItems < most {
+			most = s.maxItems
+		}
+		if most > n {
+			n += g.rand.IntN(most - n + 1)
+		}
+	}
+
+	arr := make(parser.JsonArray, 0, n)
+	for i := 0; i < n; i++ {
+		if s.items == nil {
+			arr = append(arr, g.scalar(g.pick([]string{"boolean", "integer", "string"})))
+			continue
+		}
+		v, err := g.valid(s.items, path.Append(fmt.Sprint(i)))
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+// number returns a number within the bounds of s, a multiple of its
+// multipleOf, and integral if integer is set.
+func (g *generator) number(s *Schema, integer bool) interface{} {
+	low, high := math.Inf(-1), math.Inf(1)
+	if s.minimum != nil {
+		low = *s.minimum
+	}
+	if s.exclusiveMinimum != nil && *s.exclusiveMinimum >= low {
+		low = *s.exclusiveMinimum
+	}
+	if s.maximum != nil {
+		high = *s.maximum
+	}
+	if s.exclusiveMaximum != nil && *s.exclusiveMaximum <= high {
+		high = *s.exclusiveMaximum
+	}
+	switch {
+	case math.IsInf(low, -1) && math.IsInf(high, 1):
+		low, high = 0, 1000
+	case math.IsInf(low, -1):
+		low = high - 1000
+	case math.IsInf(high, 1):
+		high = low + 1000
+	}
+
+	step := 0.0
+	if s.multipleOf != nil {
+		step = *s.multipleOf
+	} else if integer {
+		step = 1
+	}
+	if step > 0 {
+		first, last := math.Ceil(low/step), math.Floor(high/step)
+		k := first
+		if last > first {
+			k += math.Floor(g.rand.Float64() * (last - first + 1))
+		}
+		if v := k * step; integer || v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return k * step
+	}
+
+	v := low + g.rand.Float64()*(high-low)
+	if rounded := math.Round(v*100) / 100; rounded >= low && rounded <= high {
+		v = rounded
+	}
+	return v
+}
+
+// string returns a string matching the pattern of s, or made of lowercase
+// letters, of a length within the bounds of s.
+func (g *generator) string(s *Schema) interface{} {
+	if s.pattern != nil {
+		if re, err := syntax.Parse(s.pattern.String(), syntax.Perl); err == nil {
+			var result strings.Builder
+			g.match(&result, re.Simplify())
+			return result.String()
+		}
+	}
+
+	most := s.minLength + 8
+	if s.maxLength >= 0 && s.maxLength < most {
+		most = s.maxLength
+	}
+	n := s.minLength
+	if most > n {
+		n += g.rand.IntN(most - n + 1)
+	}
+	var result strings.Builder
+	for i := 0; i < n; i++ {
+		result.WriteByte(byte('a' + g.rand.IntN(26)))
+	}
+	return result.String()
+}
+
+// match writes to result a random string matched by re.
+func (g *generator) match(result *strings.Builder, re *syntax.Regexp) {
+	repeat := func(min, max int) {
+		if max < 0 {
+			max = min + 3
+		}
+		n := min + g.rand.IntN(max-min+1)
+		for i := 0; i < n; i++ {
+			g.match(result, re.Sub[0])
+		}
+	}
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && g.rand.IntN(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			result.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		result.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		result.WriteByte(byte('a' + g.rand.IntN(26)))
+	case syntax.OpCapture:
+		g.match(result, re.Sub[0])
+	case syntax.OpStar:
+		repeat(0, 3)
+	case syntax.OpPlus:
+		repeat(1, 4)
+	case syntax.OpQuest:
+		repeat(0, 1)
+	case syntax.OpRepeat:
+		repeat(re.Min, re.Max)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.match(result, sub)
+		}
+	case syntax.OpAlternate:
+		g.match(result, re.Sub[g.rand.IntN(len(re.Sub))])
+	}
+}
+
+// classRune returns a random character of the class made of the given
+// ranges, printable ASCII characters being preferred.
+func (g *generator) classRune(ranges []rune) rune {
+	var printable []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		low, high := max(ranges[i], ' '), min(ranges[i+1], '~')
+		if low <= high {
+			printable = append(printable, low, high)
+		}
+	}
+	if len(printable) > 0 {
+		ranges = printable
+	}
+	if len(ranges) == 0 {
+		return 'a'
+	}
+	i := 2 * g.rand.IntN(len(ranges)/2)
+	r := ranges[i] + rune(g.rand.IntN(int(ranges[i+1]-ranges[i])+1))
+	if !utf8.ValidRune(r) {
+		return ranges[i]
+	}
+	return r
+}
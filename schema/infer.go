@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"github.com/oabrivard/gojson/parser"
+)
+
+// inferredTypes lists the types an inferred schema may allow, in the order
+// they are written in its type keyword.
+var inferredTypes = []string{"boolean", "integer", "number", "string", "array", "object", "null"}
+
+// Infer derives a schema from sample documents. The schema allows every type
+// observed at each location, a property being required when it is present in
+// every sampled object holding it. Integers and other numbers seen at the
+// same location merge into "number". The result is a schema document, ready
+// to be printed or passed to Compile; without samples it accepts everything.
+func Infer(samples ...interface{}) *parser.OrderedObject {
+	s := &shape{}
+	for _, v := range samples {
+		s.add(v)
+	}
+
+	doc := parser.NewOrderedObject()
+	doc.Set("$schema", "https://json-schema.org/draft/2020-12/schema")
+	s.write(doc)
+	return doc
+}
+
+// shape accumulates what has been observed at one location of the samples.
+type shape struct {
+	types map[string]bool
+
+	objects    int               // number of objects seen
+	properties map[string]*shape // members seen in those objects
+	order      []string          // member names in the order they were first seen
+	counts     map[string]int    // number of objects holding each member
+
+	items *shape // elements of the arrays seen, nil until a non-empty one is
+}
+
+// add records the sample value v.
+func (s *shape) add(v interface{}) {
+	if s.types == nil {
+		s.types = map[string]bool{}
+	}
+	t := typeName(v)
+	if _, ok := v.(float64); ok {
+		t = "number" // written with a fraction or exponent, even 2.0 suggests a number field
+	}
+	s.types[t] = true
+
+	switch x := v.(type) {
+	case parser.JsonArray:
+		for _, e := range x {
+			if s.items == nil {
+				s.items = &shape{}
+			}
+			s.items.add(e)
+		}
+	default:
+		keys, members, ok := parser.Members(v)
+		if !ok {
+			return
+		}
+		if s.properties == nil {
+			s.properties, s.counts = map[string]*shape{}, map[string]int{}
+		}
+		s.objects++
+		for _, k := range keys {
+			p, ok := s.properties[k]
+			if !ok {
+				p = &shape{}
+				s.properties[k] = p
+				s.order = append(s.order, k)
+			}
+			p.add(members[k])
+			s.counts[k]++
+		}
+	}
+}
+
+// write sets the keywords describing s in doc.
+func (s *shape) write(doc *parser.OrderedObject) {
+	if len(s.types) == 0 {
+		return
+	}
+
+	var types parser.JsonArray
+	for _, t := range inferredTypes {
+		if s.types[t] && !(t == "integer" && s.types["number"]) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 1 {
+		doc.Set("type", types[0])
+	} else {
+		doc.Set("type", types)
+	}
+
+	if s.types["object"] {
+		properties := parser.NewOrderedObject()
+		required := parser.JsonArray{}
+		for _, k := range s.order {
+			sub := parser.NewOrderedObject()
+			s.properties[k].write(sub)
+			properties.Set(k, sub)
+			if s.counts[k] == s.objects {
+				required = append(required, k)
+			}
+		}
+		doc.Set("properties", properties)
+		if len(required) > 0 {
+			doc.Set("required", required)
+		}
+	}
+
+	if s.items != nil {
+		items := parser.NewOrderedObject()
+		s.items.write(items)
+		doc.Set("items", items)
+	}
+}
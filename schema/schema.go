@@ -0,0 +1,483 @@
+// Package schema validates documents produced by the parser package against
+// JSON Schema. It implements a practical subset of draft 2020-12: type, enum,
+// const, properties, required, additionalProperties, items, minItems,
+// maxItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, multipleOf and $ref to locations inside
+// the schema document. Other keywords are ignored.
+package schema
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// typeNames lists the values allowed by the type keyword.
+var typeNames = []string{"null", "boolean", "object", "array", "number", "string", "integer"}
+
+// Schema is a compiled JSON Schema. It is safe for concurrent use.
+type Schema struct {
+	always *bool // set for the boolean schemas true and false
+
+	types    []string
+	enum     []interface{}
+	constant interface{}
+	hasConst bool
+	ref      *Schema
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+
+	items    *Schema
+	minItems int
+	maxItems int // -1 when unbounded
+
+	minLength int
+	maxLength int // -1 when unbounded
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+}
+
+// Violation is a constraint that a validated value does not satisfy.
+type Violation struct {
+	Path    pointer.Pointer // location of the offending value in the instance
+	Keyword string          // schema keyword that failed, such as "type" or "required"
+	Message string
+}
+
+// String returns the violation as "path: message", the whole instance being
+// designated by "/".
+func (v Violation) String() string {
+	path := v.Path.String()
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// Compile compiles a parsed schema document, resolving its local references.
+func Compile(doc interface{}) (*Schema, error) {
+	c := &compiler{root: doc, cache: map[string]*Schema{}}
+	return c.compile(doc, "")
+}
+
+// MustCompile is like Compile but panics if the schema is invalid.
+func MustCompile(doc interface{}) *Schema {
+	s, err := Compile(doc)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// compiler compiles the subschemas of a schema document. Subschemas are cached
+// by location, so that recursive references resolve to the same Schema.
+type compiler struct {
+	root  interface{}
+	cache map[string]*Schema
+}
+
+// compile compiles the subschema v found at location path.
+func (c *compiler) compile(v interface{}, path string) (*Schema, error) {
+	if s, ok := c.cache[path]; ok {
+		return s, nil
+	}
+	s := &Schema{maxItems: -1, maxLength: -1}
+	c.cache[path] = s
+
+	if b, ok := v.(bool); ok {
+		s.always = &b
+		return s, nil
+	}
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return nil, c.errorf(path, "a schema must be an object or a boolean, got %s", typeName(v))
+	}
+
+	for _, k := range keys {
+		value := members[k]
+		at := path + pointer.Pointer{k}.String()
+		var err error
+		switch k {
+		case "type":
+			s.types, err = c.typeList(value, at)
+		case "enum":
+			arr, ok := value.(parser.JsonArray)
+			if !ok {
+				return nil, c.errorf(at, "enum must be an array")
+			}
+			s.enum = arr
+		case "const":
+			s.constant, s.hasConst = value, true
+		case "$ref":
+			s.ref, err = c.resolve(value, at)
+		case "properties":
+			err = c.compileProperties(s, value, at)
+		case "required":
+			s.required, err = c.stringList(value, at)
+		case "additionalProperties":
+			s.additionalProperties, err = c.compile(value, at)
+		case "items":
+			s.items, err = c.compile(value, at)
+		case "minItems":
+			s.minItems, err = c.count(value, at)
+		case "maxItems":
+			s.maxItems, err = c.count(value, at)
+		case "minLength":
+			s.minLength, err = c.count(value, at)
+		case "maxLength":
+			s.maxLength, err = c.count(value, at)
+		case "pattern":
+			str, ok := value.(string)
+			if !ok {
+				return nil, c.errorf(at, "pattern must be a string")
+			}
+			if s.pattern, err = regexp.Compile(str); err != nil {
+				return nil, c.errorf(at, "invalid pattern: %v", err)
+			}
+		case "minimum":
+			s.minimum, err = c.bound(value, at)
+		case "maximum":
+			s.maximum, err = c.bound(value, at)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = c.bound(value, at)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = c.bound(value, at)
+		case "multipleOf":
+			if s.multipleOf, err = c.bound(value, at); err == nil && *s.multipleOf <= 0 {
+				err = c.errorf(at, "multipleOf must be strictly positive")
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// errorf returns a compilation error for the keyword at path.
+func (c *compiler) errorf(path string, format string, args ...interface{}) error {
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Errorf("schema: %s: "+format, append([]interface{}{path}, args...)...)
+}
+
+// typeList returns the types allowed by a type keyword.
+func (c *compiler) typeList(v interface{}, path string) ([]string, error) {
+	var types []string
+	if str, ok := v.(string); ok {
+		types = []string{str}
+	} else {
+		var err error
+		if types, err = c.stringList(v, path); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range types {
+		if !contains(typeNames, t) {
+			return nil, c.errorf(path, "unknown type %q", t)
+		}
+	}
+	return types, nil
+}
+
+// stringList returns the elements of an array of strings.
+func (c *compiler) stringList(v interface{}, path string) ([]string, error) {
+	arr, ok := v.(parser.JsonArray)
+	if !ok {
+		return nil, c.errorf(path, "expected an array of strings, got %s", typeName(v))
+	}
+	list := make([]string, len(arr))
+	for i, e := range arr {
+		if list[i], ok = e.(string); !ok {
+			return nil, c.errorf(path, "expected an array of strings, got %s element", typeName(e))
+		}
+	}
+	return list, nil
+}
+
+// count returns the value of a keyword holding a non-negative integer.
+func (c *compiler) count(v interface{}, path string) (int, error) {
+	f, ok := number(v)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return 0, c.errorf(path, "expected a non-negative integer, got %v", v)
+	}
+	return int(f), nil
+}
+
+// bound returns the value of a keyword holding a number.
+func (c *compiler) bound(v interface{}, path string) (*float64, error) {
+	f, ok := number(v)
+	if !ok {
+		return nil, c.errorf(path, "expected a number, got %s", typeName(v))
+	}
+	return &f, nil
+}
+
+// compileProperties compiles the subschemas of a properties keyword.
+func (c *compiler) compileProperties(s *Schema, v interface{}, path string) error {
+	keys, members, ok := parser.Members(v)
+	if !ok {
+		return c.errorf(path, "properties must be an object")
+	}
+	s.properties = make(map[string]*Schema, len(keys))
+	for _, k := range keys {
+		sub, err := c.compile(members[k], path+pointer.Pointer{k}.String())
+		if err != nil {
+			return err
+		}
+		s.properties[k] = sub
+	}
+	return nil
+}
+
+// resolve compiles the subschema designated by a $ref such as "#" or
+// "#/$defs/address". Only references inside the schema document are supported.
+func (c *compiler) resolve(v interface{}, path string) (*Schema, error) {
+	ref, ok := v.(string)
+	if !ok {
+		return nil, c.errorf(path, "$ref must be a string")
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return nil, c.errorf(path, "unsupported reference %q, only references inside the document are supported", ref)
+	}
+
+	p, err := pointer.Parse(ref[1:])
+	if err != nil {
+		return nil, c.errorf(path, "invalid reference %q: %v", ref, err)
+	}
+	target, err := p.Get(c.root)
+	if err != nil {
+		return nil, c.errorf(path, "unresolved reference %q", ref)
+	}
+	return c.compile(target, p.String())
+}
+
+// Validate checks v against the schema and returns the violations found, or
+// nil if v is valid. Violations follow the order of the members of v, plain
+// JsonObject maps being walked in sorted key order.
+func (s *Schema) Validate(v interface{}) []Violation {
+	var violations []Violation
+	s.validate(v, pointer.Pointer{}, &violations)
+	return violations
+}
+
+// Valid reports whether v satisfies the schema.
+func (s *Schema) Valid(v interface{}) bool {
+	return len(s.Validate(v)) == 0
+}
+
+// validate appends to violations the constraints of s that v, found at path,
+// does not satisfy.
+func (s *Schema) validate(v interface{}, path pointer.Pointer, violations *[]Violation) {
+	report := func(keyword, format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			report("false", "no value is allowed here")
+		}
+		return
+	}
+	if s.ref != nil {
+		s.ref.validate(v, path, violations)
+	}
+
+	if len(s.types) > 0 && !hasType(v, s.types) {
+		report("type", "expected %s, got %s", strings.Join(s.types, " or "), typeName(v))
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, v) {
+		report("enum", "value is not one of the allowed values")
+	}
+	if s.hasConst && !equal(s.constant, v) {
+		report("const", "value is not the expected constant")
+	}
+
+	switch x := v.(type) {
+	case string:
+		length := len([]rune(x))
+		if length < s.minLength {
+			report("minLength", "expected at least %d characters, got %d", s.minLength, length)
+		}
+		if s.maxLength >= 0 && length > s.maxLength {
+			report("maxLength", "expected at most %d characters, got %d", s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(x) {
+			report("pattern", "does not match pattern %q", s.pattern.String())
+		}
+	case int64, float64, parser.Number:
+		f, _ := number(x)
+		if s.minimum != nil && f < *s.minimum {
+			report("minimum", "expected a value >= %v, got %v", *s.minimum, x)
+		}
+		if s.maximum != nil && f > *s.maximum {
+			report("maximum", "expected a value <= %v, got %v", *s.maximum, x)
+		}
+		if s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum {
+			report("exclusiveMinimum", "expected a value > %v, got %v", *s.exclusiveMinimum, x)
+		}
+		if s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum {
+			report("exclusiveMaximum", "expected a value < %v, got %v", *s.exclusiveMaximum, x)
+		}
+		if s.multipleOf != nil {
+			if q := f / *s.multipleOf; q != math.Trunc(q) {
+				report("multipleOf", "expected a multiple of %v, got %v", *s.multipleOf, x)
+			}
+		}
+	case parser.JsonArray:
+		if len(x) < s.minItems {
+			report("minItems", "expected at least %d items, got %d", s.minItems, len(x))
+		}
+		if s.maxItems >= 0 && len(x) > s.maxItems {
+			report("maxItems", "expected at most %d items, got %d", s.maxItems, len(x))
+		}
+		if s.items != nil {
+			for i, e := range x {
+				s.items.validate(e, path.Append(fmt.Sprint(i)), violations)
+			}
+		}
+	default:
+		if keys, members, ok := parser.Members(v); ok {
+			s.validateObject(keys, members, path, violations, report)
+		}
+	}
+}
+
+// validateObject checks the members of an object.
+func (s *Schema) validateObject(keys []string, members map[string]interface{}, path pointer.Pointer, violations *[]Violation, report func(keyword, format string, args ...interface{})) {
+	for _, name := range s.required {
+		if _, ok := members[name]; !ok {
+			report("required", "missing required property %q", name)
+		}
+	}
+	for _, k := range keys {
+		if sub, ok := s.properties[k]; ok {
+			sub.validate(members[k], path.Append(k), violations)
+		} else if s.additionalProperties != nil {
+			s.additionalProperties.validate(members[k], path.Append(k), violations)
+		}
+	}
+}
+
+// hasType reports whether v is of one of the given JSON Schema types.
+func hasType(v interface{}, types []string) bool {
+	for _, t := range types {
+		switch name := typeName(v); {
+		case t == name:
+			return true
+		case t == "number" && name == "integer":
+			return true
+		}
+	}
+	return false
+}
+
+// typeName returns the JSON Schema type of v. Integral numbers are integers.
+func typeName(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		if x == math.Trunc(x) && !math.IsInf(x, 0) {
+			return "integer"
+		}
+		return "number"
+	case parser.Number:
+		if f, err := x.BigFloat(); err == nil && f.IsInt() {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case parser.JsonArray:
+		return "array"
+	case parser.JsonObject, *parser.OrderedObject:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// number converts a parsed number into a float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case parser.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equal reports whether two parsed values are equal as JSON values: numbers
+// compare by value and object member order is not significant.
+func equal(a, b interface{}) bool {
+	if fa, ok := number(a); ok {
+		fb, ok := number(b)
+		return ok && fa == fb
+	}
+	if ka, ma, ok := parser.Members(a); ok {
+		_, mb, ok := parser.Members(b)
+		if !ok || len(ka) != len(mb) {
+			return false
+		}
+		for _, k := range ka {
+			if v, ok := mb[k]; !ok || !equal(ma[k], v) {
+				return false
+			}
+		}
+		return true
+	}
+	if x, ok := a.(parser.JsonArray); ok {
+		y, ok := b.(parser.JsonArray)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// containsValue reports whether list holds a value equal to v.
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, e := range list {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether list holds s.
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
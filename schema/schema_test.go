@@ -0,0 +1,323 @@
+package schema
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+const personSchema = `{
+	"$defs": {
+		"address": {
+			"type": "object",
+			"properties": {
+				"street": {"type": "string", "minLength": 1},
+				"zip": {"type": "string", "pattern": "^\\d{5}$"}
+			},
+			"required": ["street"]
+		}
+	},
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "maxLength": 10},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"score": {"type": "number", "multipleOf": 0.5},
+		"role": {"enum": ["admin", "user"]},
+		"kind": {"const": "person"},
+		"nickname": {"type": ["string", "null"]},
+		"address": {"$ref": "#/$defs/address"},
+		"friends": {"type": "array", "items": {"$ref": "#"}, "maxItems": 2}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false
+}`
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func violations(t *testing.T, s *Schema, input string) []string {
+	var result []string
+	for _, v := range s.Validate(parse(t, input)) {
+		result = append(result, v.Keyword+" "+v.String())
+	}
+	return result
+}
+
+func TestValidDocuments(t *testing.T) {
+	s := MustCompile(parse(t, personSchema))
+	tests := []string{
+		`{"name": "alice", "age": 31}`,
+		`{"name": "bob", "age": 17.0, "score": 7.5, "role": "user", "kind": "person", "nickname": null}`,
+		`{"name": "carol", "age": 45, "address": {"street": "Main St", "zip": "12345"}}`,
+		`{"name": "dave", "age": 3, "friends": [{"name": "eve", "age": 4, "friends": []}]}`,
+	}
+
+	for _, input := range tests {
+		if got := violations(t, s, input); got != nil {
+			t.Errorf("%s: unexpected violations %v", input, got)
+		}
+	}
+}
+
+func TestViolations(t *testing.T) {
+	s := MustCompile(parse(t, personSchema))
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`[]`, []string{"type /: expected object, got array"}},
+		{`{"name": "alice"}`, []string{`required /: missing required property "age"`}},
+		{`{"name": "a very long name", "age": -1}`, []string{
+			"minimum /age: expected a value >= 0, got -1",
+			"maxLength /name: expected at most 10 characters, got 16",
+		}},
+		{`{"name": "a", "age": 150}`, []string{"exclusiveMaximum /age: expected a value < 150, got 150"}},
+		{`{"name": "a", "age": 1.5}`, []string{"type /age: expected integer, got number"}},
+		{`{"name": "a", "age": 1, "score": 0.3}`, []string{"multipleOf /score: expected a multiple of 0.5, got 0.3"}},
+		{`{"name": "a", "age": 1, "role": "root", "kind": "robot"}`, []string{
+			"const /kind: value is not the expected constant",
+			"enum /role: value is not one of the allowed values",
+		}},
+		{`{"name": "a", "age": 1, "nickname": 3}`, []string{"type /nickname: expected string or null, got integer"}},
+		{`{"name": "a", "age": 1, "email": "a@b.c"}`, []string{"false /email: no value is allowed here"}},
+		{`{"name": "a", "age": 1, "address": {"zip": "1234"}}`, []string{
+			`required /address: missing required property "street"`,
+			`pattern /address/zip: does not match pattern "^\\d{5}$"`,
+		}},
+		{`{"name": "a", "age": 1, "friends": [{"name": "b"}, {"name": "c", "age": 2, "friends": [{"age": "d"}]}, {}]}`, []string{
+			"maxItems /friends: expected at most 2 items, got 3",
+			`required /friends/0: missing required property "age"`,
+			`required /friends/1/friends/0: missing required property "name"`,
+			"type /friends/1/friends/0/age: expected integer, got string",
+			`required /friends/2: missing required property "name"`,
+			`required /friends/2: missing required property "age"`,
+		}},
+	}
+
+	for _, tt := range tests {
+		if got := violations(t, s, tt.input); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s:\nexpected %q\ngot      %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestBooleanSchemas(t *testing.T) {
+	if s := MustCompile(true); !s.Valid(parse(t, `{"anything": [1, 2]}`)) {
+		t.Errorf("the true schema should accept everything")
+	}
+	if s := MustCompile(false); s.Valid(nil) {
+		t.Errorf("the false schema should reject everything")
+	}
+}
+
+func TestEscapedNamesAndStrings(t *testing.T) {
+	s := MustCompile(parse(t, `{
+		"properties": {"a\"b": {"type": "string"}, "caf\u00e9": {"enum": ["x\ny", 1]}, "c": {"const": "\u0041\/"}},
+		"required": ["a\u0022b", "caf\u00e9"],
+		"additionalProperties": false
+	}`))
+	if got := violations(t, s, `{"a\u0022b": "ok", "café": "x\u000ay", "c": "A/"}`); got != nil {
+		t.Errorf("unexpected violations %v", got)
+	}
+	expected := []string{`type /a"b: expected string, got integer`, "const /c: value is not the expected constant", "enum /café: value is not one of the allowed values"}
+	if got := violations(t, s, `{"a\"b": 1, "caf\u00e9": "x\\ny", "c": "A\\/"}`); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := violations(t, s, `{"café": 1, "a\"b\"": "x"}`); len(got) != 2 {
+		t.Errorf("expected a missing required member and an additional one, got %v", got)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	s := MustCompile(parse(t, `{"items": {"type": "integer", "minimum": 0, "maximum": 1e30, "multipleOf": 2}}`))
+	p := parser.NewParser(lexer.NewLexer(`[4, 4.0, 1e3, 123456789012345678901234567890, 3, -2, 1.5, 1e31]`))
+	p.UseNumber(true)
+	expected := []string{
+		"multipleOf /4: expected a multiple of 2, got 3",
+		"minimum /5: expected a value >= 0, got -2",
+		"type /6: expected integer, got number",
+		"maximum /7: expected a value <= 1e+30, got 1e31",
+	}
+	var got []string
+	for _, v := range s.Validate(p.ParseValue()) {
+		got = append(got, v.Keyword+" "+v.String())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestLengthCountsCharacters(t *testing.T) {
+	s := MustCompile(parse(t, `{"minLength": 2, "maxLength": 2}`))
+	for _, input := range []string{`"éé"`, `"a\""`, `"\\n"`} {
+		if got := violations(t, s, input); got != nil {
+			t.Errorf("%s: unexpected violations %v", input, got)
+		}
+	}
+	if got := violations(t, s, `"été"`); len(got) != 1 {
+		t.Errorf(`"été": expected one violation, got %v`, got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []string{
+		`"string"`,
+		`{"type": "integr"}`,
+		`{"type": 3}`,
+		`{"required": "name"}`,
+		`{"minLength": -1}`,
+		`{"maxItems": 1.5}`,
+		`{"minimum": "0"}`,
+		`{"multipleOf": 0}`,
+		`{"pattern": "("}`,
+		`{"enum": "a"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"$ref": "other.json#/a"}`,
+		`{"properties": {"a": 1}}`,
+	}
+
+	for _, input := range tests {
+		if _, err := Compile(parse(t, input)); err == nil {
+			t.Errorf("Compile(%s): expected an error", input)
+		}
+	}
+}
+
+func TestInfer(t *testing.T) {
+	samples := []interface{}{
+		parse(t, `{"id": 1, "name": "alice", "score": 3, "tags": ["a"], "address": {"city": "Paris"}}`),
+		parse(t, `{"id": 2, "name": null, "score": 4.5, "tags": [], "address": {"city": "Rome", "zip": "00100"}}`),
+		parse(t, `{"id": 3, "name": "carol", "tags": [1, "b"], "address": null}`),
+	}
+
+	expected := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "address": {
+      "type": [
+        "object",
+        "null"
+      ],
+      "properties": {
+        "city": {
+          "type": "string"
+        },
+        "zip": {
+          "type": "string"
+        }
+      },
+      "required": [
+        "city"
+      ]
+    },
+    "id": {
+      "type": "integer"
+    },
+    "name": {
+      "type": [
+        "string",
+        "null"
+      ]
+    },
+    "score": {
+      "type": "number"
+    },
+    "tags": {
+      "type": "array",
+      "items": {
+        "type": [
+          "integer",
+          "string"
+        ]
+      }
+    }
+  },
+  "required": [
+    "address",
+    "id",
+    "name",
+    "tags"
+  ]
+}`
+	doc := Infer(samples...)
+	if got := linter.Format(doc, linter.DefaultOptions()); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+
+	s, err := Compile(doc)
+	if err != nil {
+		t.Fatalf("the inferred schema does not compile: %v", err)
+	}
+	for i, sample := range samples {
+		if v := s.Validate(sample); v != nil {
+			t.Errorf("sample %d does not match the inferred schema: %v", i, v)
+		}
+	}
+	if s.Valid(parse(t, `{"id": "4", "name": "dave", "tags": [], "address": null}`)) {
+		t.Errorf("a string id should not match the inferred schema")
+	}
+}
+
+func TestInferWithoutSamples(t *testing.T) {
+	s := MustCompile(Infer())
+	if !s.Valid(parse(t, `[1, {"a": null}]`)) {
+		t.Errorf("a schema inferred without samples should accept everything")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s := MustCompile(parse(t, personSchema))
+	compact := linter.Options{InlineWidth: 1 << 30}
+	for seed := uint64(0); seed < 50; seed++ {
+		doc, err := s.Generate(GenerateOptions{Rand: rand.New(rand.NewPCG(seed, 0))})
+		if err != nil {
+			t.Fatalf("seed %d: unexpected error: %v", seed, err)
+		}
+		if v := s.Validate(doc); len(v) != 0 {
+			t.Errorf("seed %d: generated %s, which has violations %v", seed, linter.Format(doc, compact), v)
+		}
+		again, _ := s.Generate(GenerateOptions{Rand: rand.New(rand.NewPCG(seed, 0))})
+		if linter.Format(again, compact) != linter.Format(doc, compact) {
+			t.Errorf("seed %d: the same seed generated different documents", seed)
+		}
+	}
+
+	bounds := MustCompile(parse(t, `{"type": "array", "minItems": 2, "maxItems": 2, "items": {"type": "integer", "exclusiveMinimum": 6, "maximum": 7}}`))
+	doc, err := bounds.Generate(GenerateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := linter.Format(doc, compact); got != "[7, 7]" {
+		t.Errorf("got %s, want [7, 7]", got)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		schema   string
+		expected string
+	}{
+		{`false`, "schema: /: no value satisfies the false schema"},
+		{`{"properties": {"a": {"enum": []}}, "required": ["a"]}`, "schema: /a: enum allows no value"},
+		{`{"type": "integer", "minimum": 5, "maximum": 4}`, "schema: /: could not generate a value satisfying the schema"},
+		{`{"$ref": "#"}`, "schema: /: references do not lead to a value"},
+	}
+
+	for _, test := range tests {
+		_, err := MustCompile(parse(t, test.schema)).Generate(GenerateOptions{})
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%s: got error %v, want %q", test.schema, err, test.expected)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+)
+
+// ArrayReader reads the elements of a document holding an array one at a
+// time, each being parsed in full while the elements that follow it are not
+// read yet, and those that precede it are not kept.
+type ArrayReader struct {
+	in      *Reader
+	started bool
+	index   int   // index of the next element
+	err     error // the error that stopped reading, if any
+}
+
+// StreamArray returns an ArrayReader of the array held by r, so that exports
+// of any number of records can be processed record by record:
+//
+//	elements := stream.StreamArray(f)
+//	for {
+//		v, err := elements.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+func StreamArray(r io.Reader) *ArrayReader {
+	return &ArrayReader{in: NewReader(r)}
+}
+
+// SetMaxDepth limits the nesting of the document, as Reader.SetMaxDepth does.
+func (a *ArrayReader) SetMaxDepth(depth int) {
+	a.in.SetMaxDepth(depth)
+}
+
+// Next returns the next element of the array, objects being built as
+// *parser.OrderedObject values. It returns io.EOF after the last element, and
+// an error if the document is not an array or is invalid, after which every
+// call returns the same error.
+func (a *ArrayReader) Next() (interface{}, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	v, err := a.next()
+	if err != nil {
+		a.err = err
+	}
+	return v, err
+}
+
+// next reads the next element.
+func (a *ArrayReader) next() (interface{}, error) {
+	if !a.started {
+		e, err := a.in.Next()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if e.Kind != ArrayStart {
+			return nil, fmt.Errorf("stream: expected an array at line %d, column %d", e.Line, e.Column)
+		}
+		a.started = true
+	}
+
+	e, err := a.in.Next()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if e.Kind == ArrayEnd {
+		if _, err := a.in.Next(); err != io.EOF {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	v, err := a.in.ReadValue(e)
+	if err != nil {
+		return nil, err
+	}
+	a.index++
+	return v, nil
+}
+
+// Count returns the number of elements read so far.
+func (a *ArrayReader) Count() int {
+	return a.index
+}
@@ -0,0 +1,71 @@
+package stream
+
+import "io"
+
+// Handler holds the callbacks a Reader invokes, in document order, as it
+// reads the events of a document with Dispatch, so that documents can be
+// processed without building their values, even as events. Nil callbacks are
+// skipped. Keys and string values are decoded, as in events. A callback
+// returning an error stops reading, the error being returned by Dispatch.
+type Handler struct {
+	OnObjectStart func() error
+	OnObjectEnd   func() error
+	OnArrayStart  func() error
+	OnArrayEnd    func() error
+	OnKey         func(key string) error
+	// OnValue is invoked for scalar values: nil, bool, int64, float64 or
+	// string.
+	OnValue func(value interface{}) error
+}
+
+// Parse reads the document held by r, invoking the callbacks of h for its
+// events. It is NewReader(r).Dispatch(h).
+func Parse(r io.Reader, h Handler) error {
+	return NewReader(r).Dispatch(h)
+}
+
+// Dispatch reads the remaining events of the document, invoking the callbacks
+// of h for each of them. It returns nil once the whole document has been
+// read, a *SyntaxError if it is invalid, or the first error returned by a
+// callback.
+func (r *Reader) Dispatch(h Handler) error {
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := h.handle(e); err != nil {
+			return err
+		}
+	}
+}
+
+// handle invokes the callback of h for e.
+func (h *Handler) handle(e Event) error {
+	var callback func() error
+	switch e.Kind {
+	case ObjectStart:
+		callback = h.OnObjectStart
+	case ObjectEnd:
+		callback = h.OnObjectEnd
+	case ArrayStart:
+		callback = h.OnArrayStart
+	case ArrayEnd:
+		callback = h.OnArrayEnd
+	case Key:
+		if h.OnKey != nil {
+			return h.OnKey(e.Value.(string))
+		}
+	case Scalar:
+		if h.OnValue != nil {
+			return h.OnValue(e.Value)
+		}
+	}
+	if callback != nil {
+		return callback()
+	}
+	return nil
+}
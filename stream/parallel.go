@@ -0,0 +1,238 @@
+package stream
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// parallelBatch is the number of consecutive elements a worker of
+// ParseArrayParallel parses at a time.
+const parallelBatch = 64
+
+// element is the location of an element of the array parsed by
+// ParseArrayParallel.
+type element struct {
+	start, end   int // offsets of the text of the element in the document
+	line, column int // position of its first character
+}
+
+// ParseArrayParallel parses the document held by data, which must be an
+// array, on workers goroutines, runtime.GOMAXPROCS(0) of them when workers
+// is not positive: the elements are delimited by a quick scan of the
+// document, then parsed concurrently and returned in order, objects being
+// JsonObject maps as parser.ParseValue builds them. It suits large arrays of
+// records held in memory or mapped with the mmap package; the strings of the
+// values reference data, which must not be modified while they are in use.
+// Errors are *SyntaxError values, the first in the document being reported.
+func ParseArrayParallel(data []byte, workers int) (parser.JsonArray, error) {
+	elements, err := splitArray(data)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	result := make(parser.JsonArray, len(elements))
+	errs := make([]error, len(elements))
+	batches := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for first := range batches {
+				for i := first; i < min(first+parallelBatch, len(elements)); i++ {
+					result[i], errs[i] = parseElement(data, elements[i])
+				}
+			}
+		}()
+	}
+	for first := 0; first < len(elements); first += parallelBatch {
+		batches <- first
+	}
+	close(batches)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// parseElement parses the element e of data. The positions of errors are
+// made relative to the document.
+func parseElement(data []byte, e element) (interface{}, error) {
+	p := parser.NewParser(lexer.NewBytesLexer(data[e.start:e.end]))
+	v := p.ParseValue()
+	if len(p.Errors()) == 0 {
+		return v, nil
+	}
+
+	first := p.Errors()[0]
+	column := first.Column
+	if first.Line == 1 {
+		column += e.column - 1
+	}
+	return nil, &SyntaxError{Msg: first.Message, Line: e.line + first.Line - 1, Column: column}
+}
+
+// splitArray returns the elements of the array held by data, checking the
+// brackets and commas around them. The elements themselves are only scanned
+// for their end: their brackets and strings.
+func splitArray(data []byte) ([]element, error) {
+	s := &arraySplitter{data: data, line: 1, column: 1}
+	s.skipSpace()
+	if s.pos == len(data) || data[s.pos] != '[' {
+		return nil, s.errorf("expected an array")
+	}
+	s.advance(1)
+	s.skipSpace()
+
+	var elements []element
+	if s.pos < len(data) && data[s.pos] == ']' {
+		s.advance(1)
+	} else {
+		for {
+			if s.pos == len(data) {
+				return nil, s.errorf("unexpected end of input")
+			}
+			if data[s.pos] == ',' || data[s.pos] == ']' {
+				return nil, s.errorf("expected a value")
+			}
+			e := element{start: s.pos, line: s.line, column: s.column}
+			if err := s.skipValue(); err != nil {
+				return nil, err
+			}
+			e.end = s.pos
+			elements = append(elements, e)
+
+			s.skipSpace()
+			if s.pos == len(data) {
+				return nil, s.errorf("unexpected end of input")
+			}
+			c := data[s.pos]
+			s.advance(1)
+			if c == ']' {
+				break
+			}
+			if c != ',' {
+				s.advance(-1)
+				return nil, s.errorf("expected ',' or ']'")
+			}
+			s.skipSpace()
+		}
+	}
+
+	s.skipSpace()
+	if s.pos != len(data) {
+		return nil, s.errorf("unexpected data after the end of the document")
+	}
+	return elements, nil
+}
+
+// arraySplitter scans the document split by splitArray.
+type arraySplitter struct {
+	data         []byte
+	pos          int
+	line, column int // position of data[pos]
+}
+
+// advance moves n bytes forward, or backward when n is negative, on a line
+// without newlines.
+func (s *arraySplitter) advance(n int) {
+	s.pos += n
+	s.column += n
+}
+
+// skipSpace moves past white space.
+func (s *arraySplitter) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\n':
+			s.pos++
+			s.line, s.column = s.line+1, 1
+		case ' ', '\t', '\r':
+			s.advance(1)
+		default:
+			return
+		}
+	}
+}
+
+// skipValue moves past the value starting at the current position: a
+// container up to its matching bracket, or a scalar up to the next white
+// space, comma or bracket.
+func (s *arraySplitter) skipValue() error {
+	depth := 0
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '"':
+			s.skipString()
+			if depth == 0 {
+				return nil
+			}
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return nil
+			}
+			depth--
+			if depth == 0 {
+				s.advance(1)
+				return nil
+			}
+		case ',', ' ', '\t', '\r':
+			if depth == 0 {
+				return nil
+			}
+		case '\n':
+			if depth == 0 {
+				return nil
+			}
+			s.pos++
+			s.line, s.column = s.line+1, 1
+			continue
+		}
+		s.advance(1)
+	}
+	if depth > 0 {
+		return s.errorf("unexpected end of input")
+	}
+	return nil
+}
+
+// skipString moves past the string starting at the current position, or to
+// the end of the input if it is not terminated.
+func (s *arraySplitter) skipString() {
+	s.advance(1)
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '"':
+			s.advance(1)
+			return
+		case '\\':
+			s.advance(1)
+			if s.pos == len(s.data) || s.data[s.pos] == '\n' {
+				continue // an escaped character never ends the string
+			}
+		case '\n':
+			s.pos++
+			s.line, s.column = s.line+1, 1
+			continue
+		}
+		s.advance(1)
+	}
+}
+
+// errorf returns a syntax error located at the current position.
+func (s *arraySplitter) errorf(msg string) error {
+	return &SyntaxError{Msg: msg, Line: s.line, Column: s.column}
+}
@@ -0,0 +1,254 @@
+// Package stream processes JSON documents as sequences of events, the starts
+// and ends of objects and arrays, keys and scalar values, so that documents
+// far larger than memory can be read, rewritten and written in constant
+// memory. Reader produces the events of a document, or invokes the callbacks
+// of a Handler for them, Writer encodes events back to JSON and Transformer
+// rewrites them on the way from one to the other.
+package stream
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/token"
+)
+
+// Kind is the kind of an Event.
+type Kind int
+
+const (
+	ObjectStart Kind = iota // {
+	ObjectEnd               // }
+	ArrayStart              // [
+	ArrayEnd                // ]
+	Key                     // an object key, always followed by the events of its value
+	Scalar                  // a string, number, boolean or null value
+)
+
+// Event is an element of the structure of a document.
+type Event struct {
+	Kind Kind
+	// Value is the key of Key events and the value of Scalar events, in the
+	// form the parser produces: nil, bool, int64, float64 or string, strings
+	// being decoded.
+	Value  interface{}
+	Line   int // line of the token the event was read from, if any
+	Column int // column of the token the event was read from, if any
+}
+
+// readerState is what a Reader expects to read next.
+type readerState int
+
+const (
+	expectValue        readerState = iota // a value
+	expectFirstElement                    // a value or the end of an empty array
+	expectFirstKey                        // a key or the end of an empty object
+	expectKey                             // a key
+	expectSeparator                       // a value separator or the end of the current container
+	expectEOF                             // the end of the input
+)
+
+// Reader reads the events of a JSON document from an io.Reader. Only the
+// current token and the nesting of the document are kept in memory.
+type Reader struct {
+	lexer *lexer.Lexer      // the lexer producing the tokens of the document
+	stack []token.TokenType // BEGIN_OBJECT or BEGIN_ARRAY for each open container
+	state readerState       // what is expected next
+	err   error             // the error that stopped reading, if any
+
+	maxDepth int // maximum number of nested containers, unlimited if 0
+}
+
+// NewReader returns a Reader of the document held by r, which may be any JSON
+// value. Documents are read as RFC 8259 defines them: strings holding
+// control characters or invalid escape sequences, unterminated strings and
+// numbers such as 01 or 1. are syntax errors.
+func NewReader(r io.Reader) *Reader {
+	l := lexer.NewReaderLexer(r)
+	l.DecodeStrings(false) // decoded by the reader once checked
+	return &Reader{lexer: l}
+}
+
+// SetMaxDepth limits the number of containers nested inside each other, so
+// that a document holding an object itself holding an array has a depth of 2.
+// Deeper documents are reported as syntax errors. A depth of 0, the default,
+// means no limit.
+func (r *Reader) SetMaxDepth(depth int) {
+	r.maxDepth = depth
+}
+
+// Next returns the next event of the document. It returns io.EOF once the
+// whole document has been read, and a *SyntaxError if the document is
+// invalid, after which every call returns the same error.
+func (r *Reader) Next() (Event, error) {
+	if r.err != nil {
+		return Event{}, r.err
+	}
+	e, err := r.next()
+	if err != nil {
+		r.err = err
+	}
+	return e, err
+}
+
+// next reads the next event.
+func (r *Reader) next() (Event, error) {
+	for {
+		tok := r.lexer.NextToken()
+		if err := r.lexer.Err(); err != nil {
+			return Event{}, err
+		}
+		switch r.state {
+		case expectEOF:
+			if tok.Type != token.EOF {
+				return Event{}, errorf(tok, "unexpected token '%s' after the end of the document", tok.Value)
+			}
+			return Event{}, io.EOF
+
+		case expectFirstElement:
+			if tok.Type == token.END_ARRAY {
+				return r.end(ArrayEnd, tok), nil
+			}
+			return r.value(tok)
+
+		case expectValue:
+			return r.value(tok)
+
+		case expectFirstKey, expectKey:
+			if tok.Type == token.END_OBJECT && r.state == expectFirstKey {
+				return r.end(ObjectEnd, tok), nil
+			}
+			if tok.Type != token.STRING && !unterminated(tok) {
+				return Event{}, errorf(tok, "expected string for key, got '%s'", tok.Value)
+			}
+			key, err := decode(tok)
+			if err != nil {
+				return Event{}, err
+			}
+			if sep := r.lexer.NextToken(); sep.Type != token.NAME_SEPARATOR {
+				return Event{}, errorf(sep, "expected ':', got '%s'", sep.Value)
+			}
+			r.state = expectValue
+			return Event{Kind: Key, Value: key, Line: tok.Line, Column: tok.Column}, nil
+
+		case expectSeparator:
+			container := r.stack[len(r.stack)-1]
+			switch {
+			case tok.Type == token.VALUE_SEPARATOR && container == token.BEGIN_OBJECT:
+				r.state = expectKey
+			case tok.Type == token.VALUE_SEPARATOR:
+				r.state = expectValue
+			case tok.Type == token.END_OBJECT && container == token.BEGIN_OBJECT:
+				return r.end(ObjectEnd, tok), nil
+			case tok.Type == token.END_ARRAY && container == token.BEGIN_ARRAY:
+				return r.end(ArrayEnd, tok), nil
+			case container == token.BEGIN_OBJECT:
+				return Event{}, errorf(tok, "expected ',' or '}', got '%s'", tok.Value)
+			default:
+				return Event{}, errorf(tok, "expected ',' or ']', got '%s'", tok.Value)
+			}
+		}
+	}
+}
+
+// value returns the event of the value starting with tok.
+func (r *Reader) value(tok token.Token) (Event, error) {
+	e := Event{Kind: Scalar, Line: tok.Line, Column: tok.Column}
+	if (tok.Type == token.BEGIN_OBJECT || tok.Type == token.BEGIN_ARRAY) && r.maxDepth > 0 && len(r.stack) == r.maxDepth {
+		return Event{}, errorf(tok, "document nested deeper than %d levels", r.maxDepth)
+	}
+	switch tok.Type {
+	case token.BEGIN_OBJECT:
+		r.stack = append(r.stack, tok.Type)
+		r.state = expectFirstKey
+		e.Kind = ObjectStart
+		return e, nil
+	case token.BEGIN_ARRAY:
+		r.stack = append(r.stack, tok.Type)
+		r.state = expectFirstElement
+		e.Kind = ArrayStart
+		return e, nil
+	case token.STRING:
+		s, err := decode(tok)
+		if err != nil {
+			return Event{}, err
+		}
+		e.Value = s
+	case token.NUMBER:
+		if !parser.Number(tok.Value).Valid() {
+			return Event{}, errorf(tok, "invalid number '%s'", tok.Value)
+		}
+		n, err := parser.ParseNumber(tok.Value)
+		if err != nil {
+			return Event{}, errorf(tok, "%v", err)
+		}
+		e.Value = n
+	case token.TRUE, token.FALSE:
+		e.Value = tok.Type == token.TRUE
+	case token.NULL:
+	default:
+		if unterminated(tok) {
+			return Event{}, errorf(tok, "unterminated string")
+		}
+		return Event{}, errorf(tok, "unexpected token '%s'", tok.Value)
+	}
+	r.afterValue()
+	return e, nil
+}
+
+// unterminated reports whether tok is a string running to the end of the
+// input, which the lexer returns as an ILLEGAL token starting with its quote.
+func unterminated(tok token.Token) bool {
+	return tok.Type == token.ILLEGAL && strings.HasPrefix(tok.Value, `"`)
+}
+
+// decode returns the characters of the string token tok, read with escape
+// sequences as written, or the syntax error making it invalid.
+func decode(tok token.Token) (string, error) {
+	if unterminated(tok) {
+		return "", errorf(tok, "unterminated string")
+	}
+	if i := strings.IndexFunc(tok.Value, func(c rune) bool { return c < 0x20 }); i >= 0 {
+		return "", errorf(tok, "invalid control character %q in string", tok.Value[i])
+	}
+	s, ok := lexer.Unescape(tok.Value)
+	if !ok {
+		return "", errorf(tok, "invalid escape sequence in string")
+	}
+	return s, nil
+}
+
+// end closes the innermost container.
+func (r *Reader) end(kind Kind, tok token.Token) Event {
+	r.stack = r.stack[:len(r.stack)-1]
+	r.afterValue()
+	return Event{Kind: kind, Line: tok.Line, Column: tok.Column}
+}
+
+// afterValue updates the state once a whole value has been read.
+func (r *Reader) afterValue() {
+	if len(r.stack) == 0 {
+		r.state = expectEOF
+	} else {
+		r.state = expectSeparator
+	}
+}
+
+// SyntaxError is a parsing error of a Reader.
+type SyntaxError struct {
+	Msg    string // description of the error
+	Line   int    // line of the offending token
+	Column int    // column of the offending token
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("stream: %s at line %d, column %d", e.Msg, e.Line, e.Column)
+}
+
+// errorf returns a parsing error located at tok.
+func errorf(tok token.Token, format string, args ...interface{}) error {
+	return &SyntaxError{Msg: fmt.Sprintf(format, args...), Line: tok.Line, Column: tok.Column}
+}
@@ -0,0 +1,193 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SplitOptions controls how Split distributes the elements of an array
+// across shards. Exactly one of its fields must be set.
+type SplitOptions struct {
+	Shards  int   // number of shards receiving the elements in turn
+	MaxSize int64 // size in bytes beyond which a shard is not grown, unless it holds a single element
+}
+
+// Split reads a document holding an array from r and writes its elements,
+// compacted, to shards holding arrays themselves, so that they can be
+// processed in parallel. Only one element at a time is held in memory.
+//
+// Shards are obtained from create, numbered from 0, when they receive their
+// first element, and closed by Split, which returns how many were created.
+// With SplitOptions.Shards, elements are dealt out in turn, so that shards all
+// remain open until the end of the input; with SplitOptions.MaxSize, each
+// shard receives consecutive elements and is closed once full.
+func Split(r io.Reader, create func(shard int) (io.WriteCloser, error), opts SplitOptions) (int, error) {
+	if (opts.Shards > 0) == (opts.MaxSize > 0) {
+		return 0, errors.New("stream: exactly one of Shards and MaxSize must be positive")
+	}
+	s := &splitter{in: NewReader(r), create: create, opts: opts}
+	err := s.split()
+	if closeErr := s.close(); err == nil {
+		err = closeErr
+	}
+	return len(s.shards), err
+}
+
+// splitter is the state of a running Split.
+type splitter struct {
+	in      *Reader
+	create  func(shard int) (io.WriteCloser, error)
+	opts    SplitOptions
+	shards  []*shard      // the shards created so far
+	element bytes.Buffer  // the element being copied
+	encoder *bufio.Writer // the buffered writer of element given to stream writers
+}
+
+// shard is an output of Split.
+type shard struct {
+	w        io.WriteCloser
+	size     int64 // number of bytes written so far
+	elements int   // number of elements written so far
+	closed   bool
+}
+
+// split copies the elements of the input to the shards.
+func (s *splitter) split() error {
+	e, err := s.in.Next()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if e.Kind != ArrayStart {
+		return fmt.Errorf("stream: expected an array at line %d, column %d", e.Line, e.Column)
+	}
+
+	for i := 0; ; i++ {
+		if e, err = s.in.Next(); err != nil {
+			return unexpectedEOF(err)
+		}
+		if e.Kind == ArrayEnd {
+			break
+		}
+		if err := s.readElement(e); err != nil {
+			return err
+		}
+		sh, err := s.target(i)
+		if err != nil {
+			return err
+		}
+		if err := sh.write(s.element.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.in.Next(); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// readElement encodes the element starting with e into s.element.
+func (s *splitter) readElement(e Event) error {
+	s.element.Reset()
+	if s.encoder == nil {
+		s.encoder = bufio.NewWriter(&s.element)
+	}
+	// The writer reuses s.encoder rather than allocating its own buffer.
+	w := NewWriter(s.encoder)
+	for depth := 0; ; {
+		if err := w.WriteEvent(e); err != nil {
+			return err
+		}
+		switch e.Kind {
+		case ObjectStart, ArrayStart:
+			depth++
+		case ObjectEnd, ArrayEnd:
+			depth--
+		}
+		if depth == 0 {
+			return w.Flush()
+		}
+		var err error
+		if e, err = s.in.Next(); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+}
+
+// target returns the shard receiving the i-th element, held by s.element,
+// creating it if needed.
+func (s *splitter) target(i int) (*shard, error) {
+	if s.opts.Shards > 0 {
+		if i < s.opts.Shards {
+			return s.newShard()
+		}
+		return s.shards[i%s.opts.Shards], nil
+	}
+
+	if len(s.shards) > 0 {
+		last := s.shards[len(s.shards)-1]
+		// The element, a comma, the closing bracket and the newline must fit.
+		if last.elements == 0 || last.size+int64(s.element.Len())+3 <= s.opts.MaxSize {
+			return last, nil
+		}
+		if err := last.close(); err != nil {
+			return nil, err
+		}
+	}
+	return s.newShard()
+}
+
+// newShard creates the next shard.
+func (s *splitter) newShard() (*shard, error) {
+	w, err := s.create(len(s.shards))
+	if err != nil {
+		return nil, err
+	}
+	sh := &shard{w: w}
+	s.shards = append(s.shards, sh)
+	return sh, nil
+}
+
+// close terminates and closes the shards still open.
+func (s *splitter) close() error {
+	var first error
+	for _, sh := range s.shards {
+		if err := sh.close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// write appends an element to the array held by the shard.
+func (sh *shard) write(element []byte) error {
+	sep := ","
+	if sh.elements == 0 {
+		sep = "["
+	}
+	n, err := io.WriteString(sh.w, sep)
+	sh.size += int64(n)
+	if err != nil {
+		return err
+	}
+	n, err = sh.w.Write(element)
+	sh.size += int64(n)
+	sh.elements++
+	return err
+}
+
+// close terminates the array held by the shard and closes it.
+func (sh *shard) close() error {
+	if sh.closed {
+		return nil
+	}
+	sh.closed = true
+	_, err := io.WriteString(sh.w, "]\n")
+	if closeErr := sh.w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,608 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+func TestReader(t *testing.T) {
+	r := NewReader(strings.NewReader(`{"a": [1, 2.5, "x\n"], "b": {}, "c": [], "d": true, "e": null}`))
+	expected := []Event{
+		{Kind: ObjectStart},
+		{Kind: Key, Value: "a"},
+		{Kind: ArrayStart},
+		{Kind: Scalar, Value: int64(1)},
+		{Kind: Scalar, Value: 2.5},
+		{Kind: Scalar, Value: "x\n"},
+		{Kind: ArrayEnd},
+		{Kind: Key, Value: "b"},
+		{Kind: ObjectStart},
+		{Kind: ObjectEnd},
+		{Kind: Key, Value: "c"},
+		{Kind: ArrayStart},
+		{Kind: ArrayEnd},
+		{Kind: Key, Value: "d"},
+		{Kind: Scalar, Value: true},
+		{Kind: Key, Value: "e"},
+		{Kind: Scalar, Value: nil},
+		{Kind: ObjectEnd},
+	}
+
+	for i, want := range expected {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("event %d: unexpected error: %v", i, err)
+		}
+		if got.Kind != want.Kind || got.Value != want.Value {
+			t.Fatalf("event %d: got %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF at the end of the document, got %v", err)
+	}
+}
+
+func TestReaderPositions(t *testing.T) {
+	r := NewReader(strings.NewReader("[\n  1,\n  {}\n]"))
+	r.Next()
+	r.Next()
+	e, _ := r.Next()
+	if e.Kind != ObjectStart || e.Line != 3 || e.Column != 3 {
+		t.Errorf("got %+v, want an ObjectStart event at line 3, column 3", e)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"{",
+		`{"a" 1}`,
+		`{"a": 1,}`,
+		`{1: 2}`,
+		"[1 2]",
+		"[1,]",
+		"[}",
+		`{"a": 1]`,
+		"1 2",
+	}
+
+	for _, input := range tests {
+		r := NewReader(strings.NewReader(input))
+		var err error
+		for err == nil {
+			_, err = r.Next()
+		}
+		if err == io.EOF {
+			t.Errorf("%q: expected a parsing error", input)
+		}
+		if _, again := r.Next(); again != err {
+			t.Errorf("%q: expected the error to be returned again, got %v", input, again)
+		}
+	}
+}
+
+func TestReaderIsStrict(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": "\q"}`, "stream: invalid escape sequence in string at line 1, column 10"},
+		{`{"a\q": 1}`, "stream: invalid escape sequence in string at line 1, column 6"},
+		{`["\u12"]`, "stream: invalid escape sequence in string at line 1, column 7"},
+		{`"\uzzzz"`, "stream: invalid escape sequence in string at line 1, column 8"},
+		{"[\"a\x01b\"]", "stream: invalid control character '\\x01' in string at line 1, column 6"},
+		{"{\"a\tb\": 1}", "stream: invalid control character '\\t' in string at line 1, column 6"},
+		{`["abc`, "stream: unterminated string at line 1, column 6"},
+		{`{"abc`, "stream: unterminated string at line 1, column 6"},
+		{`[01]`, "stream: invalid number '01' at line 1, column 4"},
+		{`[1.]`, "stream: invalid number '1.' at line 1, column 4"},
+		{`-`, "stream: invalid number '-' at line 1, column 2"},
+		{`[1e+]`, "stream: invalid number '1e+' at line 1, column 5"},
+		{`[.5]`, "stream: unexpected token '.' at line 1, column 2"},
+	}
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(tt.input))
+		e, err := r.Next()
+		if err == nil {
+			_, err = r.ReadValue(e)
+		}
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || err.Error() != tt.expected {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+
+	r := NewReader(strings.NewReader(`{"a\"b": ["\u00e9\n", -0.5e-3, 0]}`))
+	e, _ := r.Next()
+	v, err := r.ReadValue(e)
+	var out strings.Builder
+	w := NewWriter(&out)
+	w.WriteValue(v)
+	w.Flush()
+	if expected := `{"a\"b":["é\n",-0.0005,0]}`; err != nil || out.String() != expected {
+		t.Errorf("expected %s, got %s (%v)", expected, out.String(), err)
+	}
+}
+
+func TestReaderReadError(t *testing.T) {
+	failure := errors.New("connection reset")
+	r := NewReader(io.MultiReader(strings.NewReader(`[1, 2`), iotest.ErrReader(failure)))
+	for i := 0; i < 2; i++ {
+		if _, err := r.Next(); err != nil {
+			t.Fatalf("events[%d] - unexpected error %v", i, err)
+		}
+	}
+	if _, err := r.Next(); err != failure {
+		t.Errorf("expected the read error instead of a syntax error, got %v", err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var out strings.Builder
+	w := NewWriter(&out)
+	doc := parser.NewOrderedObject()
+	doc.Set("b", parser.JsonArray{int64(1), 2.5, parser.JsonObject{}})
+	doc.Set("a", parser.JsonObject{"y": nil, "x": false})
+	if err := w.WriteValue(doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Flush()
+
+	expected := `{"b":[1,2.5,{}],"a":{"x":false,"y":null}}`
+	if out.String() != expected {
+		t.Errorf("got %s, want %s", out.String(), expected)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	tests := [][]Event{
+		{{Kind: Key, Value: "a"}},
+		{{Kind: ArrayStart}, {Kind: Key, Value: "a"}},
+		{{Kind: ObjectStart}, {Kind: Scalar, Value: int64(1)}},
+		{{Kind: ObjectStart}, {Kind: Key, Value: "a"}, {Kind: ObjectEnd}},
+		{{Kind: ArrayStart}, {Kind: ObjectEnd}},
+		{{Kind: Scalar, Value: int64(1)}, {Kind: Scalar, Value: int64(2)}},
+		{{Kind: Scalar, Value: 1}},
+	}
+
+	for i, events := range tests {
+		w := NewWriter(io.Discard)
+		var err error
+		for _, e := range events {
+			if err = w.WriteEvent(e); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Errorf("test %d: expected an error", i)
+		}
+	}
+}
+
+func transform(t *testing.T, tr *Transformer, input string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := tr.Transform(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Transform(%s): unexpected error: %v", input, err)
+	}
+	return out.String()
+}
+
+func TestTransformCopies(t *testing.T) {
+	input := `{"b": [1, {"c": "d"}, []], "a": {}}`
+	if got := transform(t, NewTransformer(), input); got != `{"b":[1,{"c":"d"},[]],"a":{}}` {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	input := `{
+		"users": [
+			{"name": "John", "password": "secret", "id": 1},
+			{"name": "Jane", "password": "hunter2", "id": 2, "tags": ["a"]}
+		],
+		"debug": {"trace": [1, 2, 3]},
+		"items": [10, 20, 30]
+	}`
+	tr := NewTransformer().
+		RenameKey("name", "login").
+		Drop(pointer.MustParse("/debug")).
+		Drop(pointer.MustParse("/items/1")).
+		Drop(pointer.MustParse("/users/*/tags")).
+		Redact(pointer.MustParse("/users/*/password"), "***").
+		Redact(pointer.MustParse("/items/2"), parser.JsonArray{})
+
+	expected := `{"users":[{"login":"John","password":"***","id":1},{"login":"Jane","password":"***","id":2}],"items":[10,[]]}`
+	if got := transform(t, tr, input); got != expected {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
+
+func TestTransformRedactsContainers(t *testing.T) {
+	tr := NewTransformer().Redact(pointer.MustParse("/secret"), nil)
+	if got := transform(t, tr, `{"secret": {"a": [1, {"b": 2}]}, "c": 3}`); got != `{"secret":null,"c":3}` {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	tr := NewTransformer().Drop(pointer.MustParse("/a"))
+	for _, input := range []string{`{"a": [1, 2`, `{"a": 1, "b": }`, `[1] 2`} {
+		var out strings.Builder
+		if err := tr.Transform(strings.NewReader(input), &out); err == nil {
+			t.Errorf("Transform(%s): expected an error", input)
+		}
+	}
+}
+
+// shards collects the output of Split.
+type shards struct {
+	outputs []*strings.Builder
+	closed  int
+}
+
+func (s *shards) create(shard int) (io.WriteCloser, error) {
+	if shard != len(s.outputs) {
+		return nil, errors.New("shards created out of order")
+	}
+	out := &strings.Builder{}
+	s.outputs = append(s.outputs, out)
+	return closer{out, s}, nil
+}
+
+type closer struct {
+	io.Writer
+	s *shards
+}
+
+func (c closer) Close() error {
+	c.s.closed++
+	return nil
+}
+
+func (s *shards) contents() []string {
+	var result []string
+	for _, out := range s.outputs {
+		result = append(result, out.String())
+	}
+	return result
+}
+
+func TestSplitShards(t *testing.T) {
+	var s shards
+	n, err := Split(strings.NewReader(`[1, {"a": [2]}, "x", [], null]`), s.create, SplitOptions{Shards: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"[1,\"x\",null]\n", "[{\"a\":[2]},[]]\n"}
+	if got := s.contents(); n != 2 || s.closed != 2 || strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("got %d shards %q, %d closed, want %q", n, got, s.closed, expected)
+	}
+}
+
+func TestSplitFewerElementsThanShards(t *testing.T) {
+	var s shards
+	if n, err := Split(strings.NewReader(`[1]`), s.create, SplitOptions{Shards: 4}); n != 1 || err != nil {
+		t.Errorf("got %d shards, error %v, want 1 shard", n, err)
+	}
+	if n, err := Split(strings.NewReader(`[]`), s.create, SplitOptions{Shards: 4}); n != 0 || err != nil {
+		t.Errorf("got %d shards, error %v, want none", n, err)
+	}
+}
+
+func TestSplitMaxSize(t *testing.T) {
+	var s shards
+	n, err := Split(strings.NewReader(`[1, 22, 333, "a long element", 4]`), s.create, SplitOptions{MaxSize: 9})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"[1,22]\n", "[333]\n", "[\"a long element\"]\n", "[4]\n"}
+	if got := s.contents(); n != 4 || s.closed != 4 || strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("got %d shards %q, %d closed, want %q", n, got, s.closed, expected)
+	}
+	for i, out := range expected[:2] {
+		if len(out) > 9 {
+			t.Errorf("shard %d exceeds the maximum size: %q", i, out)
+		}
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  SplitOptions
+	}{
+		{`[1]`, SplitOptions{}},
+		{`[1]`, SplitOptions{Shards: 2, MaxSize: 10}},
+		{`{"a": 1}`, SplitOptions{Shards: 2}},
+		{`[1, 2`, SplitOptions{Shards: 2}},
+		{`[1, {]`, SplitOptions{Shards: 2}},
+		{`[1] 2`, SplitOptions{Shards: 2}},
+	}
+
+	for _, test := range tests {
+		var s shards
+		if _, err := Split(strings.NewReader(test.input), s.create, test.opts); err == nil {
+			t.Errorf("Split(%s, %+v): expected an error", test.input, test.opts)
+		}
+		if s.closed != len(s.outputs) {
+			t.Errorf("Split(%s, %+v): %d shards created, %d closed", test.input, test.opts, len(s.outputs), s.closed)
+		}
+	}
+}
+
+func TestReadValue(t *testing.T) {
+	r := NewReader(strings.NewReader(`[{"b": [1, {}], "a": "x"}, 2, []]`))
+	r.Next()
+
+	var got []string
+	for {
+		e, err := r.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if e.Kind == ArrayEnd {
+			break
+		}
+		v, err := r.ReadValue(e)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out strings.Builder
+		w := NewWriter(&out)
+		w.WriteValue(v)
+		w.Flush()
+		got = append(got, out.String())
+	}
+
+	expected := []string{`{"b":[1,{}],"a":"x"}`, "2", "[]"}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+func TestReadValueErrors(t *testing.T) {
+	r := NewReader(strings.NewReader(`{"a": [1, 2`))
+	e, _ := r.Next()
+	var syntaxErr *SyntaxError
+	if _, err := r.ReadValue(e); !errors.As(err, &syntaxErr) {
+		t.Errorf("expected a syntax error for a truncated value, got %v", err)
+	}
+
+	r = NewReader(strings.NewReader(`{"a": 1}`))
+	r.Next()
+	e, _ = r.Next()
+	if _, err := r.ReadValue(e); err == nil {
+		t.Errorf("expected an error for a key event")
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	r := NewReader(strings.NewReader("[1,\n  }"))
+	var err error
+	for err == nil {
+		_, err = r.Next()
+	}
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 2 || syntaxErr.Column != 3 {
+		t.Errorf("expected a syntax error at line 2, column 3, got %v", err)
+	}
+}
+
+func TestReaderMaxDepth(t *testing.T) {
+	tests := []struct {
+		input string
+		depth int
+		valid bool
+	}{
+		{`{"a": [1]}`, 2, true},
+		{`{"a": [[1]]}`, 2, false},
+		{`[[], {}, [[]]]`, 2, false},
+		{`[[], {}]`, 2, true},
+		{`1`, 1, true},
+		{`[[[[[[]]]]]]`, 0, true},
+	}
+
+	for _, test := range tests {
+		r := NewReader(strings.NewReader(test.input))
+		r.SetMaxDepth(test.depth)
+		var err error
+		for err == nil {
+			_, err = r.Next()
+		}
+		if valid := err == io.EOF; valid != test.valid {
+			t.Errorf("%s with depth %d: got error %v", test.input, test.depth, err)
+		}
+	}
+}
+
+func TestStreamArray(t *testing.T) {
+	elements := StreamArray(strings.NewReader(` [{"id": 1, "tags": ["a"]}, 2, "x", [], null] `))
+	var got []string
+	for {
+		v, err := elements.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out strings.Builder
+		w := NewWriter(&out)
+		w.WriteValue(v)
+		w.Flush()
+		got = append(got, out.String())
+	}
+	expected := []string{`{"id":1,"tags":["a"]}`, "2", `"x"`, "[]", "null"}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+	if elements.Count() != 5 {
+		t.Errorf("expected 5 elements, got %d", elements.Count())
+	}
+	if _, err := elements.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF again, got %v", err)
+	}
+
+	empty := StreamArray(strings.NewReader(`[]`))
+	if _, err := empty.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF for an empty array, got %v", err)
+	}
+}
+
+func TestStreamArrayErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		elements int // read before the error
+		expected string
+	}{
+		{`{"a": 1}`, 0, "stream: expected an array at line 1, column 1"},
+		{``, 0, "stream: unexpected token '' at line 1, column 1"},
+		{`[1, 2`, 2, "stream: expected ',' or ']', got '' at line 1, column 6"},
+		{`[1, {"a": }]`, 1, "stream: unexpected token '}' at line 1, column 11"},
+		{`[1] 2`, 1, "stream: unexpected token '2' after the end of the document at line 1, column 6"},
+	}
+	for _, test := range tests {
+		elements := StreamArray(strings.NewReader(test.input))
+		for i := 0; i < test.elements; i++ {
+			if _, err := elements.Next(); err != nil {
+				t.Fatalf("%q: unexpected error reading element %d: %v", test.input, i, err)
+			}
+		}
+		_, err := elements.Next()
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%q: expected error %q, got %v", test.input, test.expected, err)
+		}
+		if _, again := elements.Next(); again != err {
+			t.Errorf("%q: expected the same error again, got %v", test.input, again)
+		}
+	}
+}
+
+func TestParseArrayParallel(t *testing.T) {
+	var doc strings.Builder
+	doc.WriteString("[\n")
+	for i := 0; i < 500; i++ {
+		if i > 0 {
+			doc.WriteString(",\n")
+		}
+		switch i % 5 {
+		case 0:
+			doc.WriteString(`{"id": ` + strconv.Itoa(i) + `, "tags": ["a]", "b\"}"], "nested": {"x": [[], {}]}}`)
+		case 1:
+			doc.WriteString(`"text with , and ] and \\"`)
+		case 2:
+			doc.WriteString(`-1.5e3`)
+		case 3:
+			doc.WriteString("[true,\n\tnull]")
+		case 4:
+			doc.WriteString(`false`)
+		}
+	}
+	doc.WriteString("\n] ")
+
+	p := parser.NewParser(lexer.NewLexer(doc.String()))
+	expected := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	for _, workers := range []int{0, 1, 7} {
+		got, err := ParseArrayParallel([]byte(doc.String()), workers)
+		if err != nil {
+			t.Fatalf("ParseArrayParallel(%d workers): unexpected error: %v", workers, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("ParseArrayParallel(%d workers): the result differs from ParseValue", workers)
+		}
+	}
+
+	if got, err := ParseArrayParallel([]byte(" [ ] "), 2); err != nil || len(got) != 0 {
+		t.Errorf("ParseArrayParallel([]): expected an empty array, got %v, %v", got, err)
+	}
+}
+
+func TestParseArrayParallelErrors(t *testing.T) {
+	tests := []struct {
+		input, expected string
+	}{
+		{`{"a": 1}`, "stream: expected an array at line 1, column 1"},
+		{`[1,]`, "stream: expected a value at line 1, column 4"},
+		{`[1 2]`, "stream: expected ',' or ']' at line 1, column 4"},
+		{"[1,\n  [2, 3", "stream: unexpected end of input at line 2, column 8"},
+		{`[1] [2]`, "stream: unexpected data after the end of the document at line 1, column 5"},
+		// element errors are located as the parser locates them in the whole
+		// document
+		{"[1,\n  {\"a\": [1, }], 2]", "stream: unexpected token '}' at line 2, column 13"},
+		{`[1, tru]`, "stream: unexpected token 'tru' at line 1, column 8"},
+	}
+	for _, tt := range tests {
+		_, err := ParseArrayParallel([]byte(tt.input), 2)
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) || err.Error() != tt.expected {
+			t.Errorf("ParseArrayParallel(%q): expected error %q, got %v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestParseHandler(t *testing.T) {
+	var calls []string
+	record := func(name string) func() error {
+		return func() error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	h := Handler{
+		OnObjectStart: record("{"),
+		OnObjectEnd:   record("}"),
+		OnArrayStart:  record("["),
+		OnArrayEnd:    record("]"),
+		OnKey: func(key string) error {
+			calls = append(calls, "key "+key)
+			return nil
+		},
+		OnValue: func(value interface{}) error {
+			calls = append(calls, fmt.Sprintf("%T %v", value, value))
+			return nil
+		},
+	}
+	if err := Parse(strings.NewReader(`{"a": [1, 2.5, "x\n"], "b": {}, "c": null}`), h); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"{", "key a", "[", "int64 1", "float64 2.5", "string x\n", "]", "key b", "{", "}", "key c", "<nil> <nil>", "}"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %q, got %q", expected, calls)
+	}
+
+	calls = nil
+	if err := Parse(strings.NewReader(`[1, {"a": true}]`), Handler{OnKey: h.OnKey}); err != nil || !reflect.DeepEqual(calls, []string{"key a"}) {
+		t.Errorf("expected only the keys to be handled, got %q (%v)", calls, err)
+	}
+
+	var syntax *SyntaxError
+	if err := Parse(strings.NewReader(`[1 2]`), h); !errors.As(err, &syntax) {
+		t.Errorf("expected a *SyntaxError, got %v", err)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err := Parse(strings.NewReader(`[1, 2, 3]`), Handler{OnValue: func(interface{}) error {
+		if n++; n == 2 {
+			return stop
+		}
+		return nil
+	}})
+	if err != stop || n != 2 {
+		t.Errorf("expected the callback to stop reading after 2 values, got %v after %d", err, n)
+	}
+}
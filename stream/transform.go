@@ -0,0 +1,216 @@
+package stream
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Wildcard is a reference token matching any member or element in the paths
+// given to a Transformer, so that "/users/*/password" designates the password
+// of every user. It is pointer.Wildcard.
+const Wildcard = pointer.Wildcard
+
+// Transformer rewrites documents event by event: members are renamed, and
+// values designated by paths are dropped or redacted, without holding the
+// documents in memory. Paths are JSON Pointers into the input document and
+// are matched against its decoded keys, before renaming. They never match the
+// whole document.
+type Transformer struct {
+	renames map[string]string
+	rules   []rule
+}
+
+// action is what a rule does to the values it matches.
+type action int
+
+const (
+	drop   action = iota // remove the value, and its key in objects
+	redact               // replace the value
+)
+
+// rule is a rewrite of the values at a path.
+type rule struct {
+	path        pointer.Pointer
+	action      action
+	replacement interface{}
+}
+
+// NewTransformer returns a Transformer copying documents unchanged until
+// rewrites are registered.
+func NewTransformer() *Transformer {
+	return &Transformer{renames: map[string]string{}}
+}
+
+// RenameKey renames the members with key from to key to, at any depth, and
+// returns the transformer.
+func (t *Transformer) RenameKey(from, to string) *Transformer {
+	t.renames[from] = to
+	return t
+}
+
+// Drop removes the values designated by p and returns the transformer.
+func (t *Transformer) Drop(p pointer.Pointer) *Transformer {
+	t.rules = append(t.rules, rule{path: p, action: drop})
+	return t
+}
+
+// Redact replaces the values designated by p with replacement, a parsed
+// value, and returns the transformer.
+func (t *Transformer) Redact(p pointer.Pointer, replacement interface{}) *Transformer {
+	t.rules = append(t.rules, rule{path: p, action: redact, replacement: replacement})
+	return t
+}
+
+// Transform reads a document from r and writes it to w, compacted and
+// rewritten. Since the output is produced while the input is being read,
+// part of it may already have been written when a syntax error is found.
+func (t *Transformer) Transform(r io.Reader, w io.Writer) error {
+	run := &transformation{Transformer: t, in: NewReader(r), out: NewWriter(w)}
+	err := run.transform()
+	if flushErr := run.out.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// transformation is the state of a running Transform.
+type transformation struct {
+	*Transformer
+	in     *Reader
+	out    *Writer
+	path   pointer.Pointer // path of the innermost open container
+	arrays []bool          // for each open container, whether it is an array
+	next   []int           // for each open container, the index of its next element
+	key    string          // the key of the next value in an object
+}
+
+// transform copies the events of the input to the output, rewriting them.
+func (t *transformation) transform() error {
+	for {
+		e, err := t.in.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch e.Kind {
+		case Key:
+			t.key = e.Value.(string)
+			r := t.match(t.path.Append(t.key))
+			if r != nil && r.action == drop {
+				if err := t.skipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+			if to, ok := t.renames[t.key]; ok {
+				e.Value = to
+			}
+			if err := t.out.WriteEvent(e); err != nil {
+				return err
+			}
+			if r != nil {
+				if err := t.skipValue(); err != nil {
+					return err
+				}
+				if err := t.out.WriteValue(r.replacement); err != nil {
+					return err
+				}
+			}
+			continue
+
+		case ObjectEnd, ArrayEnd:
+			if len(t.arrays) > 1 {
+				t.path = t.path[:len(t.path)-1]
+			}
+			t.arrays = t.arrays[:len(t.arrays)-1]
+			t.next = t.next[:len(t.next)-1]
+			if err := t.out.WriteEvent(e); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// The start of a value: a member whose key has been handled, an
+		// element or the whole document.
+		token, root := t.key, len(t.arrays) == 0
+		if !root && t.arrays[len(t.arrays)-1] {
+			token = strconv.Itoa(t.next[len(t.next)-1])
+			t.next[len(t.next)-1]++
+			if r := t.match(t.path.Append(token)); r != nil {
+				if err := t.skip(e); err != nil {
+					return err
+				}
+				if r.action == redact {
+					if err := t.out.WriteValue(r.replacement); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+		}
+		if e.Kind == ObjectStart || e.Kind == ArrayStart {
+			if !root {
+				t.path = t.path.Append(token)
+			}
+			t.arrays = append(t.arrays, e.Kind == ArrayStart)
+			t.next = append(t.next, 0)
+		}
+		if err := t.out.WriteEvent(e); err != nil {
+			return err
+		}
+	}
+}
+
+// match returns the first rule matching path, or nil.
+func (t *transformation) match(path pointer.Pointer) *rule {
+	for i, r := range t.rules {
+		if len(path) > 0 && r.path.Matches(path) {
+			return &t.rules[i]
+		}
+	}
+	return nil
+}
+
+// skipValue reads the events of the next value without writing them.
+func (t *transformation) skipValue() error {
+	e, err := t.in.Next()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	return t.skip(e)
+}
+
+// skip reads the remaining events of the value starting with e without
+// writing them.
+func (t *transformation) skip(e Event) error {
+	depth := 0
+	for {
+		switch e.Kind {
+		case ObjectStart, ArrayStart:
+			depth++
+		case ObjectEnd, ArrayEnd:
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if e, err = t.in.Next(); err != nil {
+			return unexpectedEOF(err)
+		}
+	}
+}
+
+// unexpectedEOF turns io.EOF, which the Reader never returns inside a value,
+// into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package stream
+
+import (
+	"errors"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+// ReadValue returns the value starting with start, an event just returned by
+// Next, reading the events of the rest of the value. Objects are built as
+// *parser.OrderedObject values, keeping the order of their keys. It lets
+// large documents be processed one part at a time, such as the elements of a
+// top-level array.
+func (r *Reader) ReadValue(start Event) (interface{}, error) {
+	switch start.Kind {
+	case Scalar:
+		return start.Value, nil
+	case ObjectStart:
+		obj := parser.NewOrderedObject()
+		for {
+			e, err := r.Next()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if e.Kind == ObjectEnd {
+				return obj, nil
+			}
+			key := e.Value.(string) // only keys and values alternate in objects
+			if e, err = r.Next(); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			v, err := r.ReadValue(e)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, v)
+		}
+	case ArrayStart:
+		arr := parser.JsonArray{}
+		for {
+			e, err := r.Next()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if e.Kind == ArrayEnd {
+				return arr, nil
+			}
+			v, err := r.ReadValue(e)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	}
+	return nil, errors.New("stream: ReadValue called with an event not starting a value")
+}
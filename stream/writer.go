@@ -0,0 +1,177 @@
+package stream
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Writer encodes events as compact JSON. Output is buffered, so Flush must be
+// called once the document has been written.
+type Writer struct {
+	out      *bufio.Writer // the buffered destination of the document
+	stack    []Kind        // ObjectStart or ArrayStart for each open container
+	counts   []int         // number of members or elements written in each open container
+	afterKey bool          // whether a key has been written and its value not yet
+	done     bool          // whether a whole document has been written
+}
+
+// NewWriter returns a Writer of a single document to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{out: bufio.NewWriter(w)}
+}
+
+// WriteEvent writes the next event of the document. Events that would not
+// produce valid JSON, such as a key inside an array, are reported as errors
+// and not written.
+func (w *Writer) WriteEvent(e Event) error {
+	if w.done {
+		return errors.New("stream: event after the end of the document")
+	}
+	inObject := len(w.stack) > 0 && w.stack[len(w.stack)-1] == ObjectStart
+
+	switch e.Kind {
+	case Key:
+		key, ok := e.Value.(string)
+		if !ok || !inObject || w.afterKey {
+			return errors.New("stream: unexpected key")
+		}
+		w.separate()
+		w.out.WriteString("\"" + lexer.Escape(key) + "\":")
+		w.afterKey = true
+		return nil
+
+	case ObjectEnd, ArrayEnd:
+		open := ObjectStart
+		if e.Kind == ArrayEnd {
+			open = ArrayStart
+		}
+		if len(w.stack) == 0 || w.afterKey || w.stack[len(w.stack)-1] != open {
+			return errors.New("stream: unexpected end of container")
+		}
+		w.stack = w.stack[:len(w.stack)-1]
+		w.counts = w.counts[:len(w.counts)-1]
+		if e.Kind == ObjectEnd {
+			w.out.WriteByte('}')
+		} else {
+			w.out.WriteByte(']')
+		}
+		w.done = len(w.stack) == 0
+		return nil
+	}
+
+	if inObject && !w.afterKey {
+		return errors.New("stream: value without a key")
+	}
+	text, err := valueText(e)
+	if err != nil {
+		return err
+	}
+	if !inObject {
+		w.separate()
+	}
+	w.afterKey = false
+
+	switch e.Kind {
+	case ObjectStart, ArrayStart:
+		w.stack = append(w.stack, e.Kind)
+		w.counts = append(w.counts, 0)
+	default:
+		w.done = len(w.stack) == 0
+	}
+	w.out.WriteString(text)
+	return nil
+}
+
+// separate writes the value separator preceding a member or an element, if
+// any, and counts it.
+func (w *Writer) separate() {
+	if len(w.counts) == 0 {
+		return
+	}
+	if w.counts[len(w.counts)-1] > 0 {
+		w.out.WriteByte(',')
+	}
+	w.counts[len(w.counts)-1]++
+}
+
+// valueText returns the text of a value event.
+func valueText(e Event) (string, error) {
+	switch e.Kind {
+	case ObjectStart:
+		return "{", nil
+	case ArrayStart:
+		return "[", nil
+	case Scalar:
+		switch v := e.Value.(type) {
+		case nil:
+			return "null", nil
+		case bool:
+			if v {
+				return "true", nil
+			}
+			return "false", nil
+		case string:
+			return "\"" + lexer.Escape(v) + "\"", nil
+		case int64, float64:
+			return fmt.Sprintf("%v", v), nil
+		case parser.Number:
+			return string(v), nil
+		}
+		return "", fmt.Errorf("stream: unsupported value of type %T", e.Value)
+	}
+	return "", fmt.Errorf("stream: unknown event kind %d", e.Kind)
+}
+
+// WriteValue writes the events of a whole parsed value. Plain JsonObject
+// maps are written with sorted keys.
+func (w *Writer) WriteValue(v interface{}) error {
+	switch x := v.(type) {
+	case parser.JsonArray:
+		if err := w.WriteEvent(Event{Kind: ArrayStart}); err != nil {
+			return err
+		}
+		for _, e := range x {
+			if err := w.WriteValue(e); err != nil {
+				return err
+			}
+		}
+		return w.WriteEvent(Event{Kind: ArrayEnd})
+	case *parser.OrderedObject:
+		return w.writeObject(x.Keys, x.Values)
+	case parser.JsonObject:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return w.writeObject(keys, x)
+	}
+	return w.WriteEvent(Event{Kind: Scalar, Value: v})
+}
+
+// writeObject writes the events of an object holding the given members.
+func (w *Writer) writeObject(keys []string, values map[string]interface{}) error {
+	if err := w.WriteEvent(Event{Kind: ObjectStart}); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := w.WriteEvent(Event{Kind: Key, Value: k}); err != nil {
+			return err
+		}
+		if err := w.WriteValue(values[k]); err != nil {
+			return err
+		}
+	}
+	return w.WriteEvent(Event{Kind: ObjectEnd})
+}
+
+// Flush writes any buffered output to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.out.Flush()
+}
@@ -0,0 +1,477 @@
+// Package tape parses JSON into a compact, read-only representation: a flat
+// array of fixed-size nodes whose strings and numbers are spans of the input,
+// instead of a tree of maps, slices and strings. It allocates a few slices
+// per document rather than a few values per member, which suits read-mostly
+// workloads holding many or large documents. Values are converted to the
+// types of the parser package on demand.
+//
+//	t, err := tape.Parse(input)
+//	name, ok := t.Root().Get("name")
+//	user := t.Root().Value() // a parser.JsonObject
+package tape
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+// Kind is the JSON type of a value.
+type Kind uint8
+
+const (
+	Null Kind = iota
+	Bool
+	Number
+	String
+	Array
+	Object
+)
+
+var kindNames = [...]string{"null", "boolean", "number", "string", "array", "object"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// node is a value of a tape. The members of an object are its key and value
+// nodes in turn, following it like the elements of an array.
+type node struct {
+	kind       Kind
+	truth      bool   // the value of a boolean
+	start, end uint32 // offsets of the text of scalars, the body of strings
+	next       uint32 // index of the node following the value and its members
+	count      uint32 // number of members or elements
+}
+
+// Tape is a parsed document. It holds the input, which it shares, and is safe
+// for concurrent use. Inputs are limited to 4 GiB.
+type Tape struct {
+	src   string
+	nodes []node
+}
+
+// Parse parses the JSON text src, which must hold exactly one value.
+func Parse(src string) (*Tape, error) {
+	if uint64(len(src)) > 1<<32-1 {
+		return nil, fmt.Errorf("tape: input of %d bytes is too large", len(src))
+	}
+	p := &tapeParser{src: src, t: &Tape{src: src, nodes: make([]node, 0, len(src)/8+1)}}
+	p.skipSpace()
+	if err := p.parseValue(); err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(src) {
+		return nil, p.errorf("unexpected %q after the end of the document", src[p.pos])
+	}
+	return p.t, nil
+}
+
+// Len returns the number of values in the document, keys included.
+func (t *Tape) Len() int {
+	return len(t.nodes)
+}
+
+// Root returns the whole document.
+func (t *Tape) Root() Value {
+	return Value{t: t, i: 0}
+}
+
+// Value is a value of a tape. The zero Value is not valid.
+type Value struct {
+	t *Tape
+	i int
+}
+
+func (v Value) node() *node {
+	return &v.t.nodes[v.i]
+}
+
+// Kind returns the JSON type of the value.
+func (v Value) Kind() Kind {
+	return v.node().kind
+}
+
+// Len returns the number of members of an object or elements of an array, and
+// 0 for other values.
+func (v Value) Len() int {
+	return int(v.node().count)
+}
+
+// Index returns the element i of an array, or false if v is not an array or
+// has no such element. Finding an element skips over the previous ones
+// without looking at their members.
+func (v Value) Index(i int) (Value, bool) {
+	n := v.node()
+	if n.kind != Array || i < 0 || i >= int(n.count) {
+		return Value{}, false
+	}
+	j := v.i + 1
+	for ; i > 0; i-- {
+		j = int(v.t.nodes[j].next)
+	}
+	return Value{t: v.t, i: j}, true
+}
+
+// Member returns the decoded key and the value of the member i of an object, or false if v is not an object or has no such member.
+func (v Value) Member(i int) (string, Value, bool) {
+	n := v.node()
+	if n.kind != Object || i < 0 || i >= int(n.count) {
+		return "", Value{}, false
+	}
+	j := v.i + 1
+	for ; i > 0; i-- {
+		j = int(v.t.nodes[j+1].next)
+	}
+	return v.t.decoded(j), Value{t: v.t, i: j + 1}, true
+}
+
+// Get returns the value of the member key of an object, or false if there is
+// none. Keys are compared once decoded, as the parser stores them; when keys
+// are duplicated, the last one wins, as with the parser.
+func (v Value) Get(key string) (Value, bool) {
+	n := v.node()
+	if n.kind != Object {
+		return Value{}, false
+	}
+	found := -1
+	for j, k := v.i+1, 0; k < int(n.count); k++ {
+		if v.t.decoded(j) == key {
+			found = j + 1
+		}
+		j = int(v.t.nodes[j+1].next)
+	}
+	if found < 0 {
+		return Value{}, false
+	}
+	return Value{t: v.t, i: found}, true
+}
+
+// Raw returns the text of a string, in its escaped form without the quotes,
+// or of a number, and the empty string for other values.
+func (v Value) Raw() string {
+	switch v.node().kind {
+	case String, Number:
+		return v.t.text(v.i)
+	}
+	return ""
+}
+
+// Bool returns the value of a boolean, or false if v is not one.
+func (v Value) Bool() (bool, bool) {
+	n := v.node()
+	return n.truth, n.kind == Bool
+}
+
+// Number returns the value of a number as the parser does, an int64 or a
+// float64, or false if v is not a number.
+func (v Value) Number() (interface{}, bool) {
+	if v.node().kind != Number {
+		return nil, false
+	}
+	n, err := parser.ParseNumber(v.t.text(v.i))
+	return n, err == nil
+}
+
+// Value converts v to the types of the parser package: a parser.JsonObject,
+// parser.JsonArray, string, int64, float64, bool or nil.
+func (v Value) Value() interface{} {
+	result, _ := v.t.convert(v.i, false)
+	return result
+}
+
+// Ordered is like Value, but converts objects to *parser.OrderedObject values
+// keeping the order of their members.
+func (v Value) Ordered() interface{} {
+	result, _ := v.t.convert(v.i, true)
+	return result
+}
+
+// convert returns the parsed value of the node i and the index of the node
+// following it.
+func (t *Tape) convert(i int, ordered bool) (interface{}, int) {
+	n := &t.nodes[i]
+	switch n.kind {
+	case Bool:
+		return n.truth, i + 1
+	case Number:
+		v, _ := parser.ParseNumber(t.text(i))
+		return v, i + 1
+	case String:
+		return t.decoded(i), i + 1
+	case Array:
+		arr := make(parser.JsonArray, 0, n.count)
+		for j := i + 1; j < int(n.next); {
+			var e interface{}
+			e, j = t.convert(j, ordered)
+			arr = append(arr, e)
+		}
+		return arr, int(n.next)
+	case Object:
+		var obj *parser.OrderedObject
+		var m parser.JsonObject
+		if ordered {
+			obj = parser.NewOrderedObject()
+		} else {
+			m = make(parser.JsonObject, n.count)
+		}
+		for j := i + 1; j < int(n.next); {
+			key := t.decoded(j)
+			var e interface{}
+			e, j = t.convert(j+1, ordered)
+			if ordered {
+				obj.Set(key, e)
+			} else {
+				m[key] = e
+			}
+		}
+		if ordered {
+			return obj, int(n.next)
+		}
+		return m, int(n.next)
+	}
+	return nil, i + 1
+}
+
+// text returns the text of the node i.
+func (t *Tape) text(i int) string {
+	n := &t.nodes[i]
+	return t.src[n.start:n.end]
+}
+
+// decoded returns the characters of the string node i, whose escape
+// sequences have been checked while parsing.
+func (t *Tape) decoded(i int) string {
+	s, _ := lexer.Unescape(t.text(i))
+	return s
+}
+
+// tapeParser appends the nodes of a document to a tape.
+type tapeParser struct {
+	src string
+	pos int
+	t   *Tape
+}
+
+// errorf returns a syntax error located at the current offset.
+func (p *tapeParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("tape: offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips the whitespace allowed between tokens.
+func (p *tapeParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// add appends a node and returns its index.
+func (p *tapeParser) add(n node) int {
+	p.t.nodes = append(p.t.nodes, n)
+	return len(p.t.nodes) - 1
+}
+
+// parseValue parses the value at the current offset.
+func (p *tapeParser) parseValue() error {
+	if p.pos == len(p.src) {
+		return p.errorf("unexpected end of input")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.parseObject()
+	case c == '[':
+		return p.parseArray()
+	case c == '"':
+		return p.parseString()
+	case c == '-' || '0' <= c && c <= '9':
+		return p.parseNumber()
+	}
+	for _, literal := range []struct {
+		text string
+		n    node
+	}{
+		{"true", node{kind: Bool, truth: true}},
+		{"false", node{kind: Bool}},
+		{"null", node{kind: Null}},
+	} {
+		if len(p.src)-p.pos >= len(literal.text) && p.src[p.pos:p.pos+len(literal.text)] == literal.text {
+			n := literal.n
+			n.start, n.end = uint32(p.pos), uint32(p.pos+len(literal.text))
+			n.next = uint32(len(p.t.nodes) + 1)
+			p.add(n)
+			p.pos += len(literal.text)
+			return nil
+		}
+	}
+	return p.errorf("unexpected %q", p.src[p.pos])
+}
+
+// parseObject parses an object, the current byte being its opening brace.
+func (p *tapeParser) parseObject() error {
+	i := p.add(node{kind: Object, start: uint32(p.pos)})
+	p.pos++
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '}' {
+		p.pos++
+		p.close(i)
+		return nil
+	}
+	for {
+		if p.pos == len(p.src) || p.src[p.pos] != '"' {
+			return p.found("a string for key")
+		}
+		if err := p.parseString(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if p.pos == len(p.src) || p.src[p.pos] != ':' {
+			return p.found("':' after key")
+		}
+		p.pos++
+		p.skipSpace()
+		if err := p.parseValue(); err != nil {
+			return err
+		}
+		p.t.nodes[i].count++
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '}' {
+			p.pos++
+			p.close(i)
+			return nil
+		}
+		if p.pos == len(p.src) || p.src[p.pos] != ',' {
+			return p.found("',' or '}'")
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+// parseArray parses an array, the current byte being its opening bracket.
+func (p *tapeParser) parseArray() error {
+	i := p.add(node{kind: Array, start: uint32(p.pos)})
+	p.pos++
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == ']' {
+		p.pos++
+		p.close(i)
+		return nil
+	}
+	for {
+		if err := p.parseValue(); err != nil {
+			return err
+		}
+		p.t.nodes[i].count++
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			p.close(i)
+			return nil
+		}
+		if p.pos == len(p.src) || p.src[p.pos] != ',' {
+			return p.found("',' or ']'")
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+// close records the end of the container i, which the parser just left.
+func (p *tapeParser) close(i int) {
+	p.t.nodes[i].end = uint32(p.pos)
+	p.t.nodes[i].next = uint32(len(p.t.nodes))
+}
+
+// found returns the error of a missing token.
+func (p *tapeParser) found(expected string) error {
+	if p.pos == len(p.src) {
+		return p.errorf("expected %s, got end of input", expected)
+	}
+	return p.errorf("expected %s, got %q", expected, p.src[p.pos])
+}
+
+// parseString parses a string, the current byte being its opening quote,
+// checking its escape sequences.
+func (p *tapeParser) parseString() error {
+	start := p.pos + 1
+	for i := start; i < len(p.src); i++ {
+		switch c := p.src[i]; {
+		case c == '"':
+			p.add(node{kind: String, start: uint32(start), end: uint32(i), next: uint32(len(p.t.nodes) + 1)})
+			p.pos = i + 1
+			return nil
+		case c == '\\':
+			if i+1 == len(p.src) {
+				break
+			}
+			switch p.src[i+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				i++
+			case 'u':
+				if i+6 > len(p.src) {
+					p.pos = i
+					return p.errorf("invalid escape sequence")
+				}
+				if _, err := strconv.ParseUint(p.src[i+2:i+6], 16, 16); err != nil {
+					p.pos = i
+					return p.errorf("invalid escape sequence %q", p.src[i:i+6])
+				}
+				i += 5
+			default:
+				p.pos = i
+				return p.errorf("invalid escape sequence %q", p.src[i:i+2])
+			}
+		case c < 0x20:
+			p.pos = i
+			return p.errorf("control character %q in string", c)
+		}
+	}
+	return p.errorf("unterminated string")
+}
+
+// parseNumber parses a number, following the grammar of RFC 8259.
+func (p *tapeParser) parseNumber() error {
+	start := p.pos
+	digits := func() int {
+		n := 0
+		for p.pos < len(p.src) && '0' <= p.src[p.pos] && p.src[p.pos] <= '9' {
+			p.pos++
+			n++
+		}
+		return n
+	}
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	switch {
+	case p.pos < len(p.src) && p.src[p.pos] == '0':
+		p.pos++
+	case digits() == 0:
+		return p.found("a digit")
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		if digits() == 0 {
+			return p.found("a digit after '.'")
+		}
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		if digits() == 0 {
+			return p.found("a digit in the exponent")
+		}
+	}
+	p.add(node{kind: Number, start: uint32(start), end: uint32(p.pos), next: uint32(len(p.t.nodes) + 1)})
+	return nil
+}
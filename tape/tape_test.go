@@ -0,0 +1,137 @@
+package tape
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+)
+
+const document = `{
+	"name": "gojson",
+	"version": 2,
+	"ratio": -1.5e2,
+	"tags": ["json", "a\"b", []],
+	"owner": {"login": "alice", "admin": true, "manager": null},
+	"empty": {},
+	"name": "last"
+}`
+
+func TestParse(t *testing.T) {
+	tape, err := Parse(document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	root := tape.Root()
+	if root.Kind() != Object || root.Len() != 7 {
+		t.Fatalf("expected an object of 7 members, got %s of %d", root.Kind(), root.Len())
+	}
+
+	if name, ok := root.Get("name"); !ok || name.Raw() != "last" {
+		t.Errorf("expected the last duplicate to win, got %q", name.Raw())
+	}
+	if _, ok := root.Get("missing"); ok {
+		t.Errorf("expected no member missing")
+	}
+	if version, _ := root.Get("version"); !reflect.DeepEqual(first(version.Number()), int64(2)) {
+		t.Errorf("expected version 2, got %v", first(version.Number()))
+	}
+	if ratio, _ := root.Get("ratio"); ratio.Raw() != "-1.5e2" || first(ratio.Number()) != -150.0 {
+		t.Errorf("expected ratio -1.5e2, got %q", ratio.Raw())
+	}
+
+	tags, _ := root.Get("tags")
+	if e, ok := tags.Index(1); !ok || e.Kind() != String || e.Raw() != `a\"b` {
+		t.Errorf("expected the escaped string a\\\"b, got %q", e.Raw())
+	}
+	if e, ok := tags.Index(2); !ok || e.Kind() != Array || e.Len() != 0 {
+		t.Errorf("expected an empty array")
+	}
+	if _, ok := tags.Index(3); ok {
+		t.Errorf("expected no element 3")
+	}
+
+	owner, _ := root.Get("owner")
+	key, admin, ok := owner.Member(1)
+	if b, isBool := admin.Bool(); !ok || key != "admin" || !b || !isBool {
+		t.Errorf("expected member admin: true, got %s: %v", key, admin.Value())
+	}
+	if _, manager, _ := owner.Member(2); manager.Kind() != Null || manager.Value() != nil {
+		t.Errorf("expected a null manager")
+	}
+	if key, v, ok := root.Member(6); !ok || key != "name" || v.Raw() != "last" {
+		t.Errorf("expected the last member to be name, got %s", key)
+	}
+	if _, _, ok := root.Member(7); ok {
+		t.Errorf("expected no member 7")
+	}
+	escaped, _ := Parse(`{"k\u0022": "v\n"}`)
+	if v, ok := escaped.Root().Get(`k"`); !ok || v.Value() != "v\n" || v.Raw() != `v\n` {
+		t.Errorf("expected the member found by its decoded key, got %v", v.Value())
+	}
+	if key, _, _ := escaped.Root().Member(0); key != `k"` {
+		t.Errorf("expected the decoded key, got %q", key)
+	}
+	if _, ok := owner.Index(0); ok {
+		t.Errorf("expected Index to fail on objects")
+	}
+	if tape.Len() != 24 {
+		t.Errorf("expected 24 nodes, got %d", tape.Len())
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tape, err := Parse(document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := parser.NewParser(lexer.NewLexer(document))
+	expected := p.ParseValue()
+	if got := tape.Root().Value(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	compact := linter.Options{InlineWidth: 1 << 30}
+	owner, _ := tape.Root().Get("owner")
+	if got, expected := linter.Format(owner.Ordered(), compact), `{"login": "alice", "admin": true, "manager": null}`; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	scalar, err := Parse(" 12 ")
+	if err != nil || scalar.Root().Value() != int64(12) {
+		t.Errorf("expected the scalar document 12, got %v (%v)", scalar.Root().Value(), err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{``, "tape: offset 0: unexpected end of input"},
+		{`{"a" 1}`, `tape: offset 5: expected ':' after key, got '1'`},
+		{`{"a": 1,}`, `tape: offset 8: expected a string for key, got '}'`},
+		{`[1 2]`, `tape: offset 3: expected ',' or ']', got '2'`},
+		{`[1`, `tape: offset 2: expected ',' or ']', got end of input`},
+		{`"abc`, "tape: offset 0: unterminated string"},
+		{`"a\x"`, `tape: offset 2: invalid escape sequence "\\x"`},
+		{`"\u12g4"`, `tape: offset 1: invalid escape sequence "\\u12g4"`},
+		{"\"a\nb\"", `tape: offset 2: control character '\n' in string`},
+		{`01`, `tape: offset 1: unexpected '1' after the end of the document`},
+		{`-`, "tape: offset 1: expected a digit, got end of input"},
+		{`1.`, "tape: offset 2: expected a digit after '.', got end of input"},
+		{`1e+`, "tape: offset 3: expected a digit in the exponent, got end of input"},
+		{`tru`, `tape: offset 0: unexpected 't'`},
+		{`{} {}`, `tape: offset 3: unexpected '{' after the end of the document`},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.input); err == nil || err.Error() != test.expected {
+			t.Errorf("%q: expected error %q, got %v", test.input, test.expected, err)
+		}
+	}
+}
+
+func first(v interface{}, _ bool) interface{} {
+	return v
+}
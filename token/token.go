@@ -24,6 +24,8 @@ const (
 	TRUE   // Represents the boolean value "true"
 	FALSE  // Represents the boolean value "false"
 	NULL   // Represents the "null" value
+
+	IDENTIFIER // Represents an unquoted object key, in JSON5
 )
 
 type Token struct {
@@ -31,6 +33,7 @@ type Token struct {
 	Value  string
 	Line   int
 	Column int
+	Offset int // offset in bytes of the first character of the token in the input
 }
 
 func NewToken(tokenType TokenType, ch byte, l int, c int) Token {
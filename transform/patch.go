@@ -0,0 +1,112 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Patch returns the transform applying a JSON Patch document (RFC 6902), an
+// array of "add", "remove", "replace", "move", "copy" and "test" operations,
+// to the whole document. The paths of the operations designate values of the
+// document as the preceding transforms left it. A failing operation, such as
+// a test that does not hold, fails the transform.
+func Patch(patch interface{}) Transform {
+	return Func(func(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+		if len(path) > 0 {
+			return v, true, nil
+		}
+		ops, ok := patch.(parser.JsonArray)
+		if !ok {
+			return nil, false, errors.New("transform: a patch must be an array of operations")
+		}
+		for i, op := range ops {
+			var err error
+			if v, err = applyOperation(v, op); err != nil {
+				return nil, false, errorf("patch operation %d: %v", i, err)
+			}
+		}
+		return v, true, nil
+	})
+}
+
+// applyOperation applies a JSON Patch operation to doc and returns the
+// result.
+func applyOperation(doc, op interface{}) (interface{}, error) {
+	_, members, ok := parser.Members(op)
+	if !ok {
+		return nil, errors.New("an operation must be an object")
+	}
+	pointerAt := func(key string) (pointer.Pointer, error) {
+		s, ok := members[key].(string)
+		if !ok {
+			return nil, fmt.Errorf("missing %q", key)
+		}
+		return pointer.Parse(s)
+	}
+	path, err := pointerAt("path")
+	if err != nil {
+		return nil, err
+	}
+	value, hasValue := members["value"]
+	if name := members["op"]; !hasValue && (name == "add" || name == "replace" || name == "test") {
+		return nil, errors.New("missing \"value\"")
+	}
+
+	switch members["op"] {
+	case "add":
+		return path.Add(doc, gojson.Clone(value))
+	case "remove":
+		return path.Delete(doc)
+	case "replace":
+		if _, err := path.Get(doc); err != nil {
+			return nil, err
+		}
+		return path.Set(doc, gojson.Clone(value))
+	case "move", "copy":
+		from, err := pointerAt("from")
+		if err != nil {
+			return nil, err
+		}
+		v, err := from.Get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if members["op"] == "copy" {
+			return path.Add(doc, gojson.Clone(v))
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, errors.New("cannot move a value into itself")
+		}
+		if doc, err = from.Delete(doc); err != nil {
+			return nil, err
+		}
+		return path.Add(doc, v)
+	case "test":
+		v, err := path.Get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !gojson.Equal(v, value) {
+			return nil, fmt.Errorf("test failed: %s differs", path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %v", members["op"])
+}
+
+// isPrefix reports whether prefix designates path or one of its parents.
+func isPrefix(prefix, path pointer.Pointer) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
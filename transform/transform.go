@@ -0,0 +1,233 @@
+// Package transform rewrites documents produced by the parser package with
+// operations, such as sorting keys, dropping paths, renaming keys, converting
+// numbers and applying JSON Patch documents, that implement a common
+// interface. Chained operations are applied in a single traversal of the
+// document.
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Wildcard is a reference token matching any member or element in the paths
+// given to DropPaths. It is pointer.Wildcard.
+const Wildcard = pointer.Wildcard
+
+// Transform is an operation on the values of a document.
+type Transform interface {
+	// Apply returns the value replacing v, found at path in the input
+	// document, or false to remove it. The members and elements of v have
+	// already been transformed.
+	Apply(path pointer.Pointer, v interface{}) (interface{}, bool, error)
+}
+
+// Func adapts a function to the Transform interface.
+type Func func(path pointer.Pointer, v interface{}) (interface{}, bool, error)
+
+// Apply calls f.
+func (f Func) Apply(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+	return f(path, v)
+}
+
+// Pipeline is a sequence of transforms applied in turn to each value. It is a
+// Transform itself, so pipelines can be nested.
+type Pipeline []Transform
+
+// Chain returns the pipeline applying transforms in order.
+func Chain(transforms ...Transform) Pipeline {
+	return Pipeline(transforms)
+}
+
+// Apply applies the transforms of the pipeline to v in turn, stopping when
+// one of them removes it.
+func (p Pipeline) Apply(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+	for _, t := range p {
+		var keep bool
+		var err error
+		if v, keep, err = t.Apply(path, v); err != nil || !keep {
+			return nil, false, err
+		}
+	}
+	return v, true, nil
+}
+
+// Run transforms doc in a single traversal and returns the result, leaving
+// doc unmodified. Values are transformed after their members and elements, so
+// the whole document is transformed last: operations on the whole document,
+// such as Patch, see the effects of the preceding transforms on every part of
+// it.
+func (p Pipeline) Run(doc interface{}) (interface{}, error) {
+	return Run(doc, p...)
+}
+
+// Run transforms doc with the chain of transforms, as Pipeline.Run does.
+func Run(doc interface{}, transforms ...Transform) (interface{}, error) {
+	v, keep, err := walk(Pipeline(transforms), pointer.Pointer{}, doc)
+	if err != nil {
+		return nil, err
+	}
+	if !keep {
+		return nil, errors.New("transform: the whole document was removed")
+	}
+	return v, nil
+}
+
+// walk returns the transformed copy of v, found at path, or false if it is
+// removed.
+func walk(t Transform, path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+	switch x := v.(type) {
+	case parser.JsonObject:
+		obj := make(parser.JsonObject, len(x))
+		for k, e := range x {
+			e, keep, err := walk(t, path.Append(k), e)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				obj[k] = e
+			}
+		}
+		v = obj
+	case *parser.OrderedObject:
+		obj := parser.NewOrderedObject()
+		for _, k := range x.Keys {
+			e, keep, err := walk(t, path.Append(k), x.Values[k])
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				obj.Set(k, e)
+			}
+		}
+		v = obj
+	case parser.JsonArray:
+		arr := make(parser.JsonArray, 0, len(x))
+		for i, e := range x {
+			e, keep, err := walk(t, path.Append(strconv.Itoa(i)), e)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				arr = append(arr, e)
+			}
+		}
+		v = arr
+	}
+	return t.Apply(path, v)
+}
+
+// SortKeys returns the transform turning objects into *parser.OrderedObject
+// values whose keys are sorted.
+func SortKeys() Transform {
+	return Func(func(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+		keys, members, ok := parser.Members(v)
+		if !ok {
+			return v, true, nil
+		}
+		obj := parser.NewOrderedObject()
+		for _, k := range sortedCopy(keys) {
+			obj.Set(k, members[k])
+		}
+		return obj, true, nil
+	})
+}
+
+// DropPaths returns the transform removing the values designated by paths,
+// which may hold wildcards. Paths are those of the input document, indexes
+// included, before any value is removed or renamed.
+func DropPaths(paths ...pointer.Pointer) Transform {
+	return Func(func(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+		for _, p := range paths {
+			if p.Matches(path) {
+				return nil, false, nil
+			}
+		}
+		return v, true, nil
+	})
+}
+
+// RenameKeys returns the transform renaming the members of every object to
+// the key rename returns for their current key. Keys are given and returned
+// decoded, as the parser stores them. A member renamed to the key of another
+// one replaces it.
+func RenameKeys(rename func(key string) string) Transform {
+	return Func(func(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+		switch x := v.(type) {
+		case parser.JsonObject:
+			obj := make(parser.JsonObject, len(x))
+			for _, k := range sortedCopy(mapKeys(x)) {
+				obj[rename(k)] = x[k]
+			}
+			return obj, true, nil
+		case *parser.OrderedObject:
+			obj := parser.NewOrderedObject()
+			for _, k := range x.Keys {
+				obj.Set(rename(k), x.Values[k])
+			}
+			return obj, true, nil
+		}
+		return v, true, nil
+	})
+}
+
+// NumberFormat is the representation ConvertNumbers gives numbers.
+type NumberFormat int
+
+const (
+	Float   NumberFormat = iota // every number becomes a float64
+	Integer                     // floats holding integers that an int64 represents exactly become int64
+	String                      // numbers become strings holding their shortest decimal text
+)
+
+// ConvertNumbers returns the transform converting numbers to format.
+func ConvertNumbers(format NumberFormat) Transform {
+	return Func(func(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+		switch n := v.(type) {
+		case int64:
+			switch format {
+			case Float:
+				return float64(n), true, nil
+			case String:
+				return strconv.FormatInt(n, 10), true, nil
+			}
+		case float64:
+			switch format {
+			case Integer:
+				if n >= -(1<<63) && n < 1<<63 && n == math.Trunc(n) {
+					return int64(n), true, nil
+				}
+			case String:
+				return strconv.FormatFloat(n, 'g', -1, 64), true, nil
+			}
+		}
+		return v, true, nil
+	})
+}
+
+// mapKeys returns the keys of obj, in no particular order.
+func mapKeys(obj parser.JsonObject) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// sortedCopy returns a sorted copy of keys.
+func sortedCopy(keys []string) []string {
+	sorted := append([]string{}, keys...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// errorf returns an error prefixed by the name of the package.
+func errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("transform: "+format, args...)
+}
@@ -0,0 +1,138 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/linter"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+const document = `{
+	"name": "gojson",
+	"version": 2.0,
+	"owner": {"login": "alice", "password": "secret"},
+	"releases": [
+		{"tag": "v1", "size": 1.5, "password": "x"},
+		{"tag": "v2", "size": 3, "password": "y"}
+	]
+}`
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseValue()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func format(v interface{}) string {
+	return linter.Format(v, linter.Options{InlineWidth: 1 << 30})
+}
+
+func TestRun(t *testing.T) {
+	doc := parse(t, document)
+	before := format(doc)
+
+	result, err := Run(doc,
+		DropPaths(pointer.MustParse("/owner/password"), pointer.MustParse("/releases/*/password")),
+		RenameKeys(strings.ToUpper),
+		ConvertNumbers(Integer),
+		SortKeys(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"NAME": "gojson", "OWNER": {"LOGIN": "alice"}, "RELEASES": [{"SIZE": 1.5, "TAG": "v1"}, {"SIZE": 3, "TAG": "v2"}], "VERSION": 2}`
+	if got := format(result); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	if _, ok := result.(*parser.OrderedObject).Values["VERSION"].(int64); !ok {
+		t.Errorf("expected the version to become an int64")
+	}
+	if after := format(doc); after != before {
+		t.Errorf("expected the input to be unmodified, got %s", after)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	strings := Chain(ConvertNumbers(Float), ConvertNumbers(String))
+	result, err := Chain(strings, SortKeys()).Run(parse(t, `{"b": 1, "a": [2, 0.5]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, expected := format(result), `{"a": ["2", "0.5"], "b": "1"}`; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	if _, err := Run(parse(t, `[1]`), DropPaths(pointer.Pointer{})); err == nil || err.Error() != "transform: the whole document was removed" {
+		t.Errorf("expected the removal of the whole document to fail, got %v", err)
+	}
+
+	failing := Func(func(path pointer.Pointer, v interface{}) (interface{}, bool, error) {
+		if path.String() == "/a/1" {
+			return nil, false, errorf("cannot transform %s", path)
+		}
+		return v, true, nil
+	})
+	if _, err := Run(parse(t, `{"a": [1, 2]}`), failing); err == nil || err.Error() != "transform: cannot transform /a/1" {
+		t.Errorf("expected the error of the transform, got %v", err)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	patch := parse(t, `[
+		{"op": "test", "path": "/owner/login", "value": "alice"},
+		{"op": "replace", "path": "/name", "value": "gojson2"},
+		{"op": "add", "path": "/releases/0", "value": {"tag": "v0"}},
+		{"op": "remove", "path": "/version"},
+		{"op": "move", "from": "/owner/login", "path": "/login"},
+		{"op": "copy", "from": "/releases/2/tag", "path": "/latest"}
+	]`)
+	result, err := Run(parse(t, document),
+		DropPaths(pointer.MustParse("/owner"), pointer.MustParse("/releases/*/password"), pointer.MustParse("/releases/*/size")),
+		Patch(patch),
+	)
+	if err == nil {
+		t.Fatalf("expected the test of a dropped member to fail, got %s", format(result))
+	}
+	if expected := "transform: patch operation 0: "; !strings.HasPrefix(err.Error(), expected) {
+		t.Errorf("expected an error starting with %q, got %v", expected, err)
+	}
+
+	result, err = Run(parse(t, document),
+		DropPaths(pointer.MustParse("/owner/password"), pointer.MustParse("/releases/*/password"), pointer.MustParse("/releases/*/size")),
+		Patch(patch),
+		SortKeys(),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"latest": "v2", "login": "alice", "name": "gojson2", "owner": {}, "releases": [{"tag": "v0"}, {"tag": "v1"}, {"tag": "v2"}]}`
+	if got := format(result); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	for _, test := range []struct {
+		patch    string
+		expected string
+	}{
+		{`{}`, "transform: a patch must be an array of operations"},
+		{`[1]`, "transform: patch operation 0: an operation must be an object"},
+		{`[{"op": "add", "value": 1}]`, `transform: patch operation 0: missing "path"`},
+		{`[{"op": "add", "path": "/b"}]`, `transform: patch operation 0: missing "value"`},
+		{`[{"op": "test", "path": "/a", "value": 2}]`, "transform: patch operation 0: test failed: /a differs"},
+		{`[{"op": "move", "from": "/a", "path": "/a/b"}]`, "transform: patch operation 0: cannot move a value into itself"},
+		{`[{"op": "append", "path": "/a"}]`, "transform: patch operation 0: unknown op append"},
+	} {
+		_, err := Run(parse(t, `{"a": {"b": 1}}`), Patch(parse(t, test.patch)))
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%s: expected error %q, got %v", test.patch, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package gojson
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// InvalidUnmarshalError describes an invalid argument passed to Unmarshal:
+// values must be stored through a non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "gojson: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Pointer {
+		return "gojson: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "gojson: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// Unmarshal parses the JSON document held by data and stores it in the value
+// v points to, like encoding/json's Unmarshal. Values are converted as As
+// converts them: object members fill the struct fields Marshal encodes under
+// their key, including the promoted fields of embedded structs, whose nil
+// pointers are allocated as needed; members without a field are ignored, and
+// fields without a member are left as they are. Pointers are allocated, and
+// set to nil by null values. Numbers are parsed as parser.Number values, so
+// integers beyond the range of int64, such as large uint64 values, and
+// big.Int and big.Float fields receive all of their digits, while interface
+// values hold int64 and float64 numbers as the parser builds them by default.
+// Fields with the string option take a JSON string holding their value.
+//
+// Invalid documents return an error listing the syntax errors, and values
+// that do not fit the Go type they are stored into a *ConversionError
+// locating them in the document, in which case v may be partially filled.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+
+	p := parser.NewParser(lexer.NewLexer(string(data)))
+	p.UseNumber(true)
+	value := p.ParseValue()
+	if errs := p.Errors(); len(errs) > 0 {
+		return fmt.Errorf("gojson: invalid JSON: %w", parser.ErrorList(errs))
+	}
+	return converter{plainNumbers: true}.convertValue(pointer.Pointer{}, value, rv.Elem())
+}
+
+// quotedValue returns the scalar held by the JSON string v, the value of a
+// field with the string option found at path, for a field of type t.
+func (c converter) quotedValue(path pointer.Pointer, v interface{}, t reflect.Type) (interface{}, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, &ConversionError{Path: path, Found: parser.TypeName(v), Value: v, Type: t, Reason: "the string option requires a JSON string"}
+	}
+	p := parser.NewParser(lexer.NewLexer(s))
+	p.UseNumber(c.plainNumbers)
+	value := p.ParseValue()
+	switch value.(type) {
+	case parser.JsonObject, parser.JsonArray:
+		ok = false
+	}
+	if !ok || len(p.Errors()) > 0 {
+		return nil, &ConversionError{Path: path, Found: "string", Value: v, Type: t, Reason: "invalid value for the string option"}
+	}
+	return value, nil
+}
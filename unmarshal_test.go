@@ -0,0 +1,162 @@
+package gojson
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oabrivard/gojson/parser"
+)
+
+func TestUnmarshal(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  *int   `json:"zip,omitempty"`
+	}
+	type user struct {
+		*Base
+		Mid
+		Name     string            `json:"name"`
+		Age      uint8             `json:"age"`
+		Ratio    float32           `json:"ratio"`
+		Count    int               `json:"count,string"`
+		Address  *address          `json:"address"`
+		Previous []address         `json:"previous"`
+		Labels   map[string]string `json:"labels"`
+		Extra    interface{}       `json:"extra"`
+		Kept     string            `json:"kept"`
+		Ignored  string            `json:"-"`
+	}
+	doc := `{
+		"id": 7, "kind": "user", "Depth": 2, "name": "Ana \"A\"", "age": 31, "ratio": 0.5,
+		"count": "12", "address": {"city": "Lyon", "zip": 69000}, "previous": [{"city": "Paris"}],
+		"labels": {"a\nb": "c"}, "extra": [1, "x"], "unknown": true, "-": "no"
+	}`
+
+	var got, expected user
+	got.Kept, expected.Kept = "before", "before"
+	if err := Unmarshal([]byte(doc), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := json.Unmarshal([]byte(doc), &expected); err != nil {
+		t.Fatal(err)
+	}
+	// interface{} values are stored as the parser builds them.
+	expected.Extra = parser.JsonArray{int64(1), "x"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+	if got.Base == nil || got.Base.ID != 7 || got.Base.Kind != "user" {
+		t.Errorf("expected the embedded pointer to be allocated, got %#v", got.Base)
+	}
+
+	var n int
+	if err := Unmarshal([]byte(" 42 "), &n); err != nil || n != 42 {
+		t.Errorf("expected 42, got %d (%v)", n, err)
+	}
+	ptr := &n
+	if err := Unmarshal([]byte("null"), &ptr); err != nil || ptr != nil {
+		t.Errorf("expected null to clear the pointer, got %v (%v)", ptr, err)
+	}
+}
+
+func TestUnmarshalNumbers(t *testing.T) {
+	var numbers struct {
+		Max      uint64        `json:"max"`
+		Quoted   uint64        `json:"quoted,string"`
+		Min      int64         `json:"min"`
+		Big      *big.Int      `json:"big"`
+		Precise  big.Float     `json:"precise"`
+		Text     parser.Number `json:"text"`
+		Float    float64       `json:"float"`
+		Any      interface{}   `json:"any"`
+		Overflow interface{}   `json:"overflow"`
+	}
+	doc := `{
+		"max": 18446744073709551615, "quoted": "18446744073709551615", "min": -9223372036854775808,
+		"big": 123456789012345678901234567890, "precise": 0.1000000000000000000001, "text": 1.50,
+		"float": 1e-400, "any": [1, 2.5, {"a": 3}], "overflow": 18446744073709551616
+	}`
+	if err := Unmarshal([]byte(doc), &numbers); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if numbers.Max != math.MaxUint64 || numbers.Quoted != math.MaxUint64 || numbers.Min != math.MinInt64 {
+		t.Errorf("expected the limits of the integer types, got %d, %d and %d", numbers.Max, numbers.Quoted, numbers.Min)
+	}
+	if numbers.Big.String() != "123456789012345678901234567890" {
+		t.Errorf("expected the exact integer, got %v", numbers.Big)
+	}
+	if got := numbers.Precise.Text('g', 22); got != "0.1000000000000000000001" {
+		t.Errorf("expected the exact decimal, got %s", got)
+	}
+	if numbers.Text != "1.50" || numbers.Float != 0 {
+		t.Errorf("expected 1.50 and 0, got %q and %v", numbers.Text, numbers.Float)
+	}
+	// interface{} values are stored as the parser builds them by default.
+	if expected := (parser.JsonArray{int64(1), 2.5, parser.JsonObject{"a": int64(3)}}); !reflect.DeepEqual(numbers.Any, expected) {
+		t.Errorf("expected %#v, got %#v", expected, numbers.Any)
+	}
+	if numbers.Overflow != float64(1<<64) {
+		t.Errorf("expected an integer beyond int64 to become a float64, got %#v", numbers.Overflow)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type item struct {
+		Count int `json:"count"`
+		Code  int `json:"code,string"`
+	}
+	type order struct {
+		Items []item        `json:"items"`
+		Max   uint32        `json:"max"`
+		Any   []interface{} `json:"any"`
+	}
+
+	tests := []struct {
+		input string
+		path  string
+		found string
+		msg   string
+	}{
+		{`{"items": [{"count": 1}, {"count": "2"}]}`, "/items/1/count", "string", `gojson: cannot convert string at /items/1/count to int`},
+		{`{"items": [{"count": 1.5}]}`, "/items/0/count", "number", `gojson: cannot convert number 1.5 at /items/0/count to int: not an integer`},
+		{`{"items": {}}`, "/items", "object", `gojson: cannot convert object at /items to []gojson.item`},
+		{`{"items": [{"code": 3}]}`, "/items/0/code", "number", `gojson: cannot convert number 3 at /items/0/code to int: the string option requires a JSON string`},
+		{`{"items": [{"code": "x"}]}`, "/items/0/code", "string", `gojson: cannot convert string at /items/0/code to int: invalid value for the string option`},
+		{`{"items": [{"count": 9223372036854775808}]}`, "/items/0/count", "number", `gojson: cannot convert number 9223372036854775808 at /items/0/count to int: out of range`},
+		{`{"items": [{"count": 1e400}]}`, "/items/0/count", "number", `gojson: cannot convert number 1e400 at /items/0/count to int: out of range`},
+		{`{"max": 4294967296}`, "/max", "number", `gojson: cannot convert number 4294967296 at /max to uint32: out of range`},
+		{`{"max": -1}`, "/max", "number", `gojson: cannot convert number -1 at /max to uint32: out of range`},
+		{`{"any": [1, 1e400]}`, "/any/1", "number", `gojson: cannot convert number 1e400 at /any/1 to []interface {}: out of range`},
+	}
+	for _, test := range tests {
+		var o order
+		err := Unmarshal([]byte(test.input), &o)
+		var conversion *ConversionError
+		if !errors.As(err, &conversion) {
+			t.Errorf("%s: expected a *ConversionError, got %v", test.input, err)
+			continue
+		}
+		if conversion.Path.String() != test.path || conversion.Found != test.found || err.Error() != test.msg {
+			t.Errorf("%s: expected %q, got %q", test.input, test.msg, err)
+		}
+	}
+
+	var o order
+	if err := Unmarshal([]byte(`{"items": [}`), &o); err == nil || !strings.HasPrefix(err.Error(), "gojson: invalid JSON: ") {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+
+	var p *order
+	for _, v := range []interface{}{nil, o, p} {
+		err := Unmarshal([]byte(`{}`), v)
+		var invalid *InvalidUnmarshalError
+		if !errors.As(err, &invalid) {
+			t.Errorf("%T: expected an *InvalidUnmarshalError, got %v", v, err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package gojson
+
+import "unicode/utf8"
+
+// Valid reports whether data holds exactly one JSON value (RFC 8259),
+// surrounded by optional white space. It scans data in place without
+// building tokens or values, and does not allocate unless containers are
+// nested more than 512 levels deep, so that services can check payloads
+// before forwarding them at little cost.
+func Valid(data []byte) bool {
+	var s validator
+	return s.valid(data)
+}
+
+// validator holds the containers enclosing the value being scanned by Valid,
+// one bit per level: 1 for an object, 0 for an array. The first 512 levels
+// are in words, the following ones in more.
+type validator struct {
+	words [8]uint64
+	more  []uint64
+	depth int
+}
+
+// word returns the word holding the bit of level d.
+func (s *validator) word(d int) *uint64 {
+	w := d / 64
+	if w < len(s.words) {
+		return &s.words[w]
+	}
+	for w-len(s.words) >= len(s.more) {
+		s.more = append(s.more, 0)
+	}
+	return &s.more[w-len(s.words)]
+}
+
+// push enters a container.
+func (s *validator) push(object bool) {
+	w := s.word(s.depth)
+	if object {
+		*w |= 1 << (s.depth % 64)
+	} else {
+		*w &^= 1 << (s.depth % 64)
+	}
+	s.depth++
+}
+
+// inObject reports whether the innermost container is an object.
+func (s *validator) inObject() bool {
+	d := s.depth - 1
+	return *s.word(d)&(1<<(d%64)) != 0
+}
+
+func (s *validator) valid(data []byte) bool {
+	i := skipSpace(data, 0)
+	for {
+		// a value starts at i
+		if i == len(data) {
+			return false
+		}
+		switch c := data[i]; {
+		case c == '{' || c == '[':
+			i = skipSpace(data, i+1)
+			if i < len(data) && data[i] == c+2 { // } and ] follow { and [ by 2
+				i++
+				break
+			}
+			s.push(c == '{')
+			if c == '{' {
+				if i = memberValue(data, i); i < 0 {
+					return false
+				}
+			}
+			continue
+		case c == '"':
+			if i = stringEnd(data, i); i < 0 {
+				return false
+			}
+		case c == '-' || '0' <= c && c <= '9':
+			if i = numberEnd(data, i); i < 0 {
+				return false
+			}
+		case c == 't':
+			if i = literalEnd(data, i, "true"); i < 0 {
+				return false
+			}
+		case c == 'f':
+			if i = literalEnd(data, i, "false"); i < 0 {
+				return false
+			}
+		case c == 'n':
+			if i = literalEnd(data, i, "null"); i < 0 {
+				return false
+			}
+		default:
+			return false
+		}
+
+		// a value ends at i: close the containers it ends
+		for {
+			i = skipSpace(data, i)
+			if s.depth == 0 {
+				return i == len(data)
+			}
+			if i == len(data) {
+				return false
+			}
+			object := s.inObject()
+			switch data[i] {
+			case ',':
+				i = skipSpace(data, i+1)
+				if object {
+					if i = memberValue(data, i); i < 0 {
+						return false
+					}
+				}
+			case '}', ']':
+				if object != (data[i] == '}') {
+					return false
+				}
+				s.depth--
+				i++
+				continue
+			default:
+				return false
+			}
+			break
+		}
+	}
+}
+
+// memberValue returns the position of the value of the object member starting at
+// i, or -1 if there is no valid key and colon there.
+func memberValue(data []byte, i int) int {
+	if i == len(data) || data[i] != '"' {
+		return -1
+	}
+	if i = stringEnd(data, i); i < 0 {
+		return -1
+	}
+	i = skipSpace(data, i)
+	if i == len(data) || data[i] != ':' {
+		return -1
+	}
+	return skipSpace(data, i+1)
+}
+
+// skipSpace returns the position of the first byte of data from i on that is
+// not JSON white space.
+func skipSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// stringEnd returns the position following the string starting with the
+// quotation mark at i, or -1 if it is invalid.
+func stringEnd(data []byte, i int) int {
+	for i++; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			return i + 1
+		case c == '\\':
+			if i+1 == len(data) {
+				return -1
+			}
+			switch data[i+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				i += 2
+			case 'u':
+				if i+6 > len(data) {
+					return -1
+				}
+				for _, h := range data[i+2 : i+6] {
+					if !('0' <= h && h <= '9' || 'a' <= h && h <= 'f' || 'A' <= h && h <= 'F') {
+						return -1
+					}
+				}
+				i += 6
+			default:
+				return -1
+			}
+		case c < 0x20:
+			return -1
+		case c < utf8.RuneSelf:
+			i++
+		default:
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 {
+				return -1
+			}
+			i += size
+		}
+	}
+	return -1
+}
+
+// numberEnd returns the position following the number starting at i, or -1
+// if it is invalid.
+func numberEnd(data []byte, i int) int {
+	if data[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(data) && data[i] == '0':
+		i++
+	case i < len(data) && '1' <= data[i] && data[i] <= '9':
+		i = digitsEnd(data, i)
+	default:
+		return -1
+	}
+	if i < len(data) && data[i] == '.' {
+		end := digitsEnd(data, i+1)
+		if end == i+1 {
+			return -1
+		}
+		i = end
+	}
+	if i < len(data) && (data[i] == 'e' || data[i] == 'E') {
+		i++
+		if i < len(data) && (data[i] == '+' || data[i] == '-') {
+			i++
+		}
+		end := digitsEnd(data, i)
+		if end == i {
+			return -1
+		}
+		i = end
+	}
+	return i
+}
+
+// digitsEnd returns the position of the first byte of data from i on that is
+// not a digit.
+func digitsEnd(data []byte, i int) int {
+	for i < len(data) && '0' <= data[i] && data[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+// literalEnd returns the position following literal, expected at i, or -1
+// if data does not hold it there.
+func literalEnd(data []byte, i int, literal string) int {
+	if len(data)-i < len(literal) || string(data[i:i+len(literal)]) != literal {
+		return -1
+	}
+	return i + len(literal)
+}
@@ -0,0 +1,34 @@
+package gojson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	deep := strings.Repeat(`[{"a":`, 600) + "1" + strings.Repeat("}]", 600)
+	valid := []string{
+		`{}`, `[]`, ` [ ] `, `0`, `-0`, `-12.5e+3`, `1E-2`, `"éé\n\""`, `true`, `false`, `null`,
+		`{"a": [1, {"b": null}, "c"], "d": {}}`, "\t{\n\"a\"\r: 1 }\n", deep,
+	}
+	invalid := []string{
+		``, ` `, `{`, `]`, `[1,]`, `[1 2]`, `{"a"}`, `{"a":}`, `{"a": 1,}`, `{1: 2}`, `{"a": 1]`, `[1}`,
+		`01`, `-`, `1.`, `.5`, `1e`, `+1`, `"abc`, `"\x"`, `"\u12"`, "\"a\tb\"", "\"\xff\"",
+		`tru`, `nul`, `truex`, `{} {}`, `[1] 2`, `'a'`, deep[:len(deep)-1],
+	}
+	for _, input := range valid {
+		if !Valid([]byte(input)) {
+			t.Errorf("Valid(%q): expected true", input)
+		}
+	}
+	for _, input := range invalid {
+		if Valid([]byte(input)) {
+			t.Errorf("Valid(%q): expected false", input)
+		}
+	}
+
+	doc := []byte(`{"users": [{"name": "alice", "tags": ["a", "b"], "age": 31.5}, {"name": "bobé", "admin": true, "x": null}]}`)
+	if allocs := testing.AllocsPerRun(100, func() { Valid(doc) }); allocs != 0 {
+		t.Errorf("Valid: expected no allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,352 @@
+// Package validate checks documents produced by the parser package against
+// rules written in Go, a lighter alternative to JSON Schema for services
+// validating their inputs:
+//
+//	violations := validate.Validate(doc,
+//		validate.Required("name", "items"),
+//		validate.Field("name", validate.String().MinLen(1)),
+//		validate.Field("age", validate.Int().Min(0)),
+//		validate.Each("/items", validate.Required("id")),
+//	)
+//
+// Violations are addressed by JSON Pointers to the offending values.
+package validate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/oabrivard/gojson"
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// Violation is a rule that a validated value does not satisfy.
+type Violation struct {
+	Path    pointer.Pointer // location of the offending value in the document
+	Message string
+}
+
+// String returns the violation as "path: message", the whole document being
+// designated by "/".
+func (v Violation) String() string {
+	path := v.Path.String()
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// Rule is a constraint on the values of documents.
+type Rule interface {
+	// Check returns the violations of the rule by v, found at path, or nil.
+	Check(path pointer.Pointer, v interface{}) []Violation
+}
+
+// Func adapts a function checking a value to the Rule interface: the error it
+// returns, if any, is the message of the violation.
+type Func func(v interface{}) error
+
+// Check calls f.
+func (f Func) Check(path pointer.Pointer, v interface{}) []Violation {
+	if err := f(v); err != nil {
+		return []Violation{{Path: path, Message: err.Error()}}
+	}
+	return nil
+}
+
+// Validate checks doc against rules and returns the violations found, in the
+// order of the rules, or nil if doc is valid.
+func Validate(doc interface{}, rules ...Rule) []Violation {
+	return All(rules...).Check(pointer.Pointer{}, doc)
+}
+
+// All returns the rule satisfied by the values satisfying every rule.
+func All(rules ...Rule) Rule {
+	return all(rules)
+}
+
+type all []Rule
+
+func (rules all) Check(path pointer.Pointer, v interface{}) []Violation {
+	var violations []Violation
+	for _, r := range rules {
+		violations = append(violations, r.Check(path, v)...)
+	}
+	return violations
+}
+
+// Required returns the rule satisfied by objects holding the members named
+// keys.
+func Required(keys ...string) Rule {
+	return ruleFunc(func(path pointer.Pointer, v interface{}) []Violation {
+		_, members, ok := parser.Members(v)
+		if !ok {
+			return violation(path, "expected object, got %s", parser.TypeName(v))
+		}
+		var violations []Violation
+		for _, k := range keys {
+			if _, ok := members[k]; !ok {
+				violations = append(violations, Violation{Path: path, Message: fmt.Sprintf("missing required member %q", k)})
+			}
+		}
+		return violations
+	})
+}
+
+// Field returns the rule checking the member key, when an object has it,
+// against rules. Values that are not objects satisfy it; Object or Required
+// reject them.
+func Field(key string, rules ...Rule) Rule {
+	return ruleFunc(func(path pointer.Pointer, v interface{}) []Violation {
+		_, members, ok := parser.Members(v)
+		if !ok {
+			return nil
+		}
+		member, ok := members[key]
+		if !ok {
+			return nil
+		}
+		return all(rules).Check(path.Append(key), member)
+	})
+}
+
+// At returns the rule checking the value designated by the JSON Pointer ptr,
+// relative to the checked value, against rules when it exists. It panics if
+// ptr is invalid, as it is a programming error.
+func At(ptr string, rules ...Rule) Rule {
+	p := pointer.MustParse(ptr)
+	return ruleFunc(func(path pointer.Pointer, v interface{}) []Violation {
+		target, err := p.Get(v)
+		if err != nil {
+			return nil
+		}
+		return all(rules).Check(path.Append(p...), target)
+	})
+}
+
+// Each returns the rule checking every element of the array designated by
+// the JSON Pointer ptr, relative to the checked value, against rules. A
+// missing array satisfies it, while another value is a violation. It panics
+// if ptr is invalid, as it is a programming error.
+func Each(ptr string, rules ...Rule) Rule {
+	p := pointer.MustParse(ptr)
+	return ruleFunc(func(path pointer.Pointer, v interface{}) []Violation {
+		target, err := p.Get(v)
+		if err != nil {
+			return nil
+		}
+		path = path.Append(p...)
+		arr, ok := target.(parser.JsonArray)
+		if !ok {
+			return violation(path, "expected array, got %s", parser.TypeName(target))
+		}
+		var violations []Violation
+		for i, e := range arr {
+			violations = append(violations, all(rules).Check(path.Append(strconv.Itoa(i)), e)...)
+		}
+		return violations
+	})
+}
+
+// Object returns the rule satisfied by objects.
+func Object() Rule {
+	return ruleFunc(func(path pointer.Pointer, v interface{}) []Violation {
+		switch v.(type) {
+		case parser.JsonObject, *parser.OrderedObject:
+			return nil
+		}
+		return violation(path, "expected object, got %s", parser.TypeName(v))
+	})
+}
+
+// Bool returns the rule satisfied by booleans.
+func Bool() Rule {
+	return ruleFunc(func(path pointer.Pointer, v interface{}) []Violation {
+		if _, ok := v.(bool); !ok {
+			return violation(path, "expected boolean, got %s", parser.TypeName(v))
+		}
+		return nil
+	})
+}
+
+// NumberRule is the rule satisfied by numbers within bounds.
+type NumberRule struct {
+	integer  bool
+	min, max float64
+}
+
+// Number returns the rule satisfied by numbers.
+func Number() NumberRule {
+	return NumberRule{min: math.Inf(-1), max: math.Inf(1)}
+}
+
+// Int returns the rule satisfied by integers, such as 3 or 3.0.
+func Int() NumberRule {
+	return NumberRule{integer: true, min: math.Inf(-1), max: math.Inf(1)}
+}
+
+// Min returns the rule also requiring numbers to be at least min.
+func (r NumberRule) Min(min float64) NumberRule {
+	r.min = min
+	return r
+}
+
+// Max returns the rule also requiring numbers to be at most max.
+func (r NumberRule) Max(max float64) NumberRule {
+	r.max = max
+	return r
+}
+
+// Check implements Rule.
+func (r NumberRule) Check(path pointer.Pointer, v interface{}) []Violation {
+	var n float64
+	switch x := v.(type) {
+	case int64:
+		n = float64(x)
+	case float64:
+		n = x
+	case parser.Number:
+		n, _ = x.Float64() // infinite when out of range
+	default:
+		if r.integer {
+			return violation(path, "expected integer, got %s", parser.TypeName(v))
+		}
+		return violation(path, "expected number, got %s", parser.TypeName(v))
+	}
+	switch {
+	case r.integer && n != math.Trunc(n):
+		return violation(path, "expected integer, got %v", v)
+	case n < r.min:
+		return violation(path, "%v is less than the minimum of %v", v, r.min)
+	case n > r.max:
+		return violation(path, "%v is greater than the maximum of %v", v, r.max)
+	}
+	return nil
+}
+
+// StringRule is the rule satisfied by strings of some length, matching a
+// pattern or among allowed values. Lengths count characters, and patterns and
+// values apply to the unescaped strings.
+type StringRule struct {
+	minLen, maxLen int
+	pattern        *regexp.Regexp
+	values         []string
+}
+
+// String returns the rule satisfied by strings.
+func String() StringRule {
+	return StringRule{maxLen: -1}
+}
+
+// MinLen returns the rule also requiring strings to be at least n characters
+// long.
+func (r StringRule) MinLen(n int) StringRule {
+	r.minLen = n
+	return r
+}
+
+// MaxLen returns the rule also requiring strings to be at most n characters
+// long.
+func (r StringRule) MaxLen(n int) StringRule {
+	r.maxLen = n
+	return r
+}
+
+// Pattern returns the rule also requiring strings to match the regular
+// expression pattern, anywhere unless it is anchored. It panics if pattern
+// does not compile, as it is a programming error.
+func (r StringRule) Pattern(pattern string) StringRule {
+	r.pattern = regexp.MustCompile(pattern)
+	return r
+}
+
+// OneOf returns the rule also requiring strings to be one of values.
+func (r StringRule) OneOf(values ...string) StringRule {
+	r.values = values
+	return r
+}
+
+// Check implements Rule.
+func (r StringRule) Check(path pointer.Pointer, v interface{}) []Violation {
+	raw, ok := v.(string)
+	if !ok {
+		return violation(path, "expected string, got %s", parser.TypeName(v))
+	}
+	s, _ := gojson.As[string](raw)
+	n := utf8.RuneCountInString(s)
+	switch {
+	case n < r.minLen:
+		return violation(path, "string is shorter than the minimum length of %d", r.minLen)
+	case r.maxLen >= 0 && n > r.maxLen:
+		return violation(path, "string is longer than the maximum length of %d", r.maxLen)
+	case r.pattern != nil && !r.pattern.MatchString(s):
+		return violation(path, "string does not match the pattern %q", r.pattern)
+	case r.values != nil && !slices.Contains(r.values, s):
+		return violation(path, "%q is not one of %s", s, strings.Join(quoted(r.values), ", "))
+	}
+	return nil
+}
+
+// ArrayRule is the rule satisfied by arrays of some length.
+type ArrayRule struct {
+	minItems, maxItems int
+}
+
+// Array returns the rule satisfied by arrays. Each checks their elements.
+func Array() ArrayRule {
+	return ArrayRule{maxItems: -1}
+}
+
+// MinItems returns the rule also requiring arrays to hold at least n
+// elements.
+func (r ArrayRule) MinItems(n int) ArrayRule {
+	r.minItems = n
+	return r
+}
+
+// MaxItems returns the rule also requiring arrays to hold at most n elements.
+func (r ArrayRule) MaxItems(n int) ArrayRule {
+	r.maxItems = n
+	return r
+}
+
+// Check implements Rule.
+func (r ArrayRule) Check(path pointer.Pointer, v interface{}) []Violation {
+	arr, ok := v.(parser.JsonArray)
+	switch {
+	case !ok:
+		return violation(path, "expected array, got %s", parser.TypeName(v))
+	case len(arr) < r.minItems:
+		return violation(path, "array has fewer than %d elements", r.minItems)
+	case r.maxItems >= 0 && len(arr) > r.maxItems:
+		return violation(path, "array has more than %d elements", r.maxItems)
+	}
+	return nil
+}
+
+// ruleFunc adapts a function to the Rule interface.
+type ruleFunc func(path pointer.Pointer, v interface{}) []Violation
+
+func (f ruleFunc) Check(path pointer.Pointer, v interface{}) []Violation {
+	return f(path, v)
+}
+
+// violation returns the single violation at path with the formatted message.
+func violation(path pointer.Pointer, format string, args ...interface{}) []Violation {
+	return []Violation{{Path: path, Message: fmt.Sprintf(format, args...)}}
+}
+
+// quoted returns values in double quotes.
+func quoted(values []string) []string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = strconv.Quote(v)
+	}
+	return result
+}
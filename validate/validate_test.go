@@ -0,0 +1,128 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/lexer"
+	"github.com/oabrivard/gojson/parser"
+)
+
+const document = `{
+	"name": "",
+	"age": -1,
+	"email": "alice@example",
+	"role": "owner",
+	"items": [
+		{"id": 1, "quantity": 2.5},
+		{"quantity": 1},
+		"x"
+	],
+	"tags": ["a", "b", "c"],
+	"address": {"city": 7}
+}`
+
+func parse(t *testing.T, input string) interface{} {
+	p := parser.NewParser(lexer.NewLexer(input))
+	doc := p.ParseOrdered()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("unexpected parsing errors: %v", p.Errors())
+	}
+	return doc
+}
+
+func messages(violations []Violation) []string {
+	result := []string{}
+	for _, v := range violations {
+		result = append(result, v.String())
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	violations := Validate(parse(t, document),
+		Required("name", "id", "items"),
+		Field("name", String().MinLen(1)),
+		Field("age", Int().Min(0)),
+		Field("email", String().Pattern(`^[^@]+@[^@]+\.[a-z]+$`)),
+		Field("role", String().OneOf("admin", "user")),
+		Each("/items", Object(), Required("id"), Field("quantity", Int().Max(10))),
+		Field("tags", Array().MaxItems(2)),
+		At("/address/city", String()),
+		Field("missing", Bool()),
+		Each("/absent", Bool()),
+	)
+	expected := []string{
+		`/: missing required member "id"`,
+		"/name: string is shorter than the minimum length of 1",
+		"/age: -1 is less than the minimum of 0",
+		`/email: string does not match the pattern "^[^@]+@[^@]+\\.[a-z]+$"`,
+		`/role: "owner" is not one of "admin", "user"`,
+		"/items/0/quantity: expected integer, got 2.5",
+		`/items/1: missing required member "id"`,
+		"/items/2: expected object, got string",
+		"/items/2: expected object, got string",
+		"/tags: array has more than 2 elements",
+		"/address/city: expected string, got number",
+	}
+	if got := messages(violations); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	doc := parse(t, `{"name": "café", "count": 3.0, "ok": true, "list": [1, 2], "nested": {"x": null}}`)
+	violations := Validate(doc,
+		Object(),
+		Required("name", "count"),
+		Field("name", String().MinLen(4).MaxLen(4).OneOf("café")),
+		Field("count", Int().Min(1).Max(3), Number()),
+		Field("ok", Bool()),
+		Field("list", Array().MinItems(2).MaxItems(2)),
+		Each("/list", Int()),
+		At("/nested", Required("x")),
+	)
+	if violations != nil {
+		t.Errorf("expected no violations, got %q", messages(violations))
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	p := parser.NewParser(lexer.NewLexer(`{"count": 3.0, "ratio": 2.5, "big": 1e400, "name": "x"}`))
+	p.UseNumber(true)
+	violations := Validate(p.ParseOrdered(),
+		Field("count", Int().Min(1).Max(3)),
+		Field("ratio", Int()),
+		Field("big", Number().Max(1e300)),
+		Field("name", Number()),
+	)
+	expected := []string{
+		"/ratio: expected integer, got 2.5",
+		"/big: 1e400 is greater than the maximum of 1e+300",
+		"/name: expected number, got string",
+	}
+	if got := messages(violations); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestFunc(t *testing.T) {
+	even := Func(func(v interface{}) error {
+		if n, ok := v.(int64); !ok || n%2 != 0 {
+			return errors.New("expected an even integer")
+		}
+		return nil
+	})
+	violations := Validate(parse(t, `{"values": [2, 3]}`), Each("/values", even))
+	if got, expected := messages(violations), []string{"/values/1: expected an even integer"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	if got := messages(Validate(parse(t, `{"values": 1}`), Each("/values", even))); !reflect.DeepEqual(got, []string{"/values: expected array, got number"}) {
+		t.Errorf("expected a violation for a value that is not an array, got %q", got)
+	}
+	if got := messages(Validate(parse(t, `{"values": []}`), Field("values", Required("x")))); !reflect.DeepEqual(got, []string{"/values: expected object, got array"}) {
+		t.Errorf("expected Required to reject arrays, got %q", got)
+	}
+}
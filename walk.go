@@ -0,0 +1,109 @@
+package gojson
+
+import (
+	"iter"
+	"sort"
+	"strconv"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// All returns an iterator over v and all the values it contains, depth
+// first: every value comes with the pointer designating it in v, the empty
+// pointer for v itself, and is followed by its members or elements before its
+// siblings. The members of ordered objects come in their order, those of
+// JsonObject maps sorted by key. Breaking out of the loop stops the
+// traversal, and the pointers can be kept as every one is a new slice.
+//
+//	for path, value := range gojson.All(doc) {
+//		if s, ok := value.(string); ok && strings.HasPrefix(s, "http:") {
+//			fmt.Println(path, "is not secure")
+//		}
+//	}
+func All(v interface{}) iter.Seq2[pointer.Pointer, interface{}] {
+	return func(yield func(pointer.Pointer, interface{}) bool) {
+		walk(pointer.Pointer{}, v, yield)
+	}
+}
+
+// walk yields v, found at path, and the values it contains, depth first. It
+// returns false once yield has.
+func walk(path pointer.Pointer, v interface{}, yield func(pointer.Pointer, interface{}) bool) bool {
+	if !yield(path, v) {
+		return false
+	}
+	return eachChild(v, func(token string, child interface{}) bool {
+		return walk(path.Append(token), child, yield)
+	})
+}
+
+// BreadthFirst returns an iterator over v and all the values it contains,
+// like All, but level by level: v, then its members or elements, then theirs,
+// and so on.
+func BreadthFirst(v interface{}) iter.Seq2[pointer.Pointer, interface{}] {
+	type entry struct {
+		path  pointer.Pointer
+		value interface{}
+	}
+	return func(yield func(pointer.Pointer, interface{}) bool) {
+		queue := []entry{{pointer.Pointer{}, v}}
+		for len(queue) > 0 {
+			e := queue[0]
+			queue = queue[1:]
+			if !yield(e.path, e.value) {
+				return
+			}
+			eachChild(e.value, func(token string, child interface{}) bool {
+				queue = append(queue, entry{e.path.Append(token), child})
+				return true
+			})
+		}
+	}
+}
+
+// eachChild calls fn with the token and the value of every member or element
+// of v, in order, until it returns false. It returns false if fn did.
+func eachChild(v interface{}, fn func(token string, child interface{}) bool) bool {
+	switch x := v.(type) {
+	case *parser.OrderedObject:
+		if x == nil {
+			return true
+		}
+		for _, k := range x.Keys {
+			if !fn(k, x.Values[k]) {
+				return false
+			}
+		}
+	case parser.JsonObject:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !fn(k, x[k]) {
+				return false
+			}
+		}
+	case parser.JsonArray:
+		for i, e := range x {
+			if !fn(strconv.Itoa(i), e) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// All returns an iterator over the values of the document, depth first, as
+// the All function does. Like Value, they must not be modified.
+func (d *Document) All() iter.Seq2[pointer.Pointer, interface{}] {
+	return All(d.root)
+}
+
+// BreadthFirst returns an iterator over the values of the document, level by
+// level, as the BreadthFirst function does.
+func (d *Document) BreadthFirst() iter.Seq2[pointer.Pointer, interface{}] {
+	return BreadthFirst(d.root)
+}
@@ -0,0 +1,70 @@
+package gojson
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/oabrivard/gojson/parser"
+	"github.com/oabrivard/gojson/pointer"
+)
+
+// walkDoc is the document walked by the tests, with an ordered object whose
+// keys are not sorted.
+func walkDoc() interface{} {
+	ordered := parser.NewOrderedObject()
+	ordered.Set("z", int64(1))
+	ordered.Set("a", parser.JsonArray{true})
+	return parser.JsonObject{
+		"b": parser.JsonArray{"x", ordered},
+		"a": nil,
+	}
+}
+
+// collect returns the paths yielded by seq, stopping after max of them.
+func collect(seq func(func(pointer.Pointer, interface{}) bool), max int) []string {
+	var paths []string
+	for path := range seq {
+		paths = append(paths, path.String())
+		if len(paths) == max {
+			break
+		}
+	}
+	return paths
+}
+
+func TestAll(t *testing.T) {
+	expected := []string{"", "/a", "/b", "/b/0", "/b/1", "/b/1/z", "/b/1/a", "/b/1/a/0"}
+	if paths := collect(All(walkDoc()), -1); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected paths %q, got %q", expected, paths)
+	}
+	if paths := collect(NewDocument(walkDoc()).All(), 4); !reflect.DeepEqual(paths, expected[:4]) {
+		t.Errorf("expected the traversal to stop after %q, got %q", expected[:4], paths)
+	}
+
+	// values come with their pointer, which can be kept
+	var kept []pointer.Pointer
+	for path, value := range All(walkDoc()) {
+		if got, err := path.Get(walkDoc()); err != nil || !reflect.DeepEqual(got, value) {
+			t.Errorf("%s: expected %v, got %v, %v", path, value, got, err)
+		}
+		kept = append(kept, path)
+	}
+	if kept[5].String() != "/b/1/z" {
+		t.Errorf("expected the kept pointers to be unchanged, got %s", kept[5])
+	}
+
+	if paths := collect(All("scalar"), -1); !reflect.DeepEqual(paths, []string{""}) {
+		t.Errorf("expected only the root for a scalar, got %q", paths)
+	}
+}
+
+func TestBreadthFirst(t *testing.T) {
+	doc := parser.JsonArray{parser.JsonArray{parser.JsonArray{int64(1)}}, walkDoc()}
+	expected := []string{"", "/0", "/1", "/0/0", "/1/a", "/1/b", "/0/0/0", "/1/b/0", "/1/b/1", "/1/b/1/z", "/1/b/1/a", "/1/b/1/a/0"}
+	if paths := collect(BreadthFirst(doc), -1); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected paths %q, got %q", expected, paths)
+	}
+	if paths := collect(NewDocument(doc).BreadthFirst(), 3); !reflect.DeepEqual(paths, expected[:3]) {
+		t.Errorf("expected the traversal to stop after %q, got %q", expected[:3], paths)
+	}
+}